@@ -1,20 +1,86 @@
 package main
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net"
 	"os"
+	"path/filepath"
+	goruntime "runtime"
+	"runtime/debug"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
-	"serial-assistant/pkg/jlink"   // 引入刚才创建的包
+	"serial-assistant/pkg/anchor"
+	"serial-assistant/pkg/apperr"
+	"serial-assistant/pkg/bench"
+	"serial-assistant/pkg/burst"
+	"serial-assistant/pkg/checksum"
+	"serial-assistant/pkg/classify"
+	"serial-assistant/pkg/commands"
+	"serial-assistant/pkg/compare"
+	"serial-assistant/pkg/connspec"
+	"serial-assistant/pkg/console"
+	"serial-assistant/pkg/devid"
+	"serial-assistant/pkg/diag"
+	"serial-assistant/pkg/dropwatch"
+	"serial-assistant/pkg/events"
+	"serial-assistant/pkg/format"
+	"serial-assistant/pkg/frame"
+	"serial-assistant/pkg/fsname"
+	"serial-assistant/pkg/history"
+	"serial-assistant/pkg/htmlexport"
+	"serial-assistant/pkg/httpclient"
+	"serial-assistant/pkg/initpayload"
+	"serial-assistant/pkg/input"
+	"serial-assistant/pkg/jlink" // 引入刚才创建的包
+	"serial-assistant/pkg/jsonl"
+	"serial-assistant/pkg/linetime"
+	"serial-assistant/pkg/logfile"
+	"serial-assistant/pkg/loghdr"
+	"serial-assistant/pkg/macro"
+	"serial-assistant/pkg/membudget"
+	"serial-assistant/pkg/modbus"
+	"serial-assistant/pkg/nametmpl"
+	"serial-assistant/pkg/netprobe"
+	"serial-assistant/pkg/onboarding"
+	"serial-assistant/pkg/openguard"
+	"serial-assistant/pkg/outbox"
+	"serial-assistant/pkg/pcap"
+	"serial-assistant/pkg/pipecfg"
+	"serial-assistant/pkg/plot"
+	"serial-assistant/pkg/portlock"
+	"serial-assistant/pkg/power"
+	"serial-assistant/pkg/recording"
+	"serial-assistant/pkg/rxcheck"
+	"serial-assistant/pkg/safemode"
+	"serial-assistant/pkg/schedule"
+	"serial-assistant/pkg/serialport"
+	"serial-assistant/pkg/session"
+	"serial-assistant/pkg/settings"
+	"serial-assistant/pkg/shutdown"
+	"serial-assistant/pkg/slcan"
+	"serial-assistant/pkg/stream"
+	"serial-assistant/pkg/textenc"
+	"serial-assistant/pkg/tmpl"
+	"serial-assistant/pkg/transport"
+	"serial-assistant/pkg/txlane"
 	"serial-assistant/pkg/updater" // 引入更新模块
+	"serial-assistant/pkg/vpipe"
+	"serial-assistant/pkg/watchdog"
+	"serial-assistant/pkg/wsbin"
+	"serial-assistant/pkg/zmodem"
 
 	"github.com/wailsapp/wails/v2/pkg/runtime"
 	"go.bug.st/serial"
+	"go.bug.st/serial/enumerator"
 )
 
 // ConnectionType 定义连接类型
@@ -25,9 +91,32 @@ const (
 	TypeTcpClient ConnectionType = "TCP_CLIENT"
 	TypeTcpServer ConnectionType = "TCP_SERVER"
 	TypeUdp       ConnectionType = "UDP"
-	TypeJLink     ConnectionType = "JLINK" // 新增 JLink 类型
+	TypeJLink     ConnectionType = "JLINK"   // 新增 JLink 类型
+	TypeSlcan     ConnectionType = "SLCAN"   // 基于串口的 SLCAN (CAN) 适配器
+	TypeVirtual   ConnectionType = "VIRTUAL" // 进程内虚拟连接对的 A 端
 )
 
+// kind 返回连接类型对应的连接描述类型
+func (t ConnectionType) kind() connspec.Kind {
+	switch t {
+	case TypeSerial:
+		return connspec.Serial
+	case TypeTcpClient:
+		return connspec.TcpClient
+	case TypeTcpServer:
+		return connspec.TcpServer
+	case TypeUdp:
+		return connspec.Udp
+	case TypeJLink:
+		return connspec.JLink
+	case TypeSlcan:
+		return connspec.Slcan
+	case TypeVirtual:
+		return connspec.Virtual
+	}
+	return ""
+}
+
 // App struct
 type App struct {
 	ctx          context.Context
@@ -38,24 +127,625 @@ type App struct {
 
 	// 串口资源
 	serialPort serial.Port
+	portLock   *portlock.Lock // 防止其他实例同时打开同一串口
+	portName   string
+
+	// 只读模式 (由 a.mutex 保护)：所有发送路径返回 READ_ONLY 错误，跨连接保持
+	readOnly bool
+
+	// 发送前的设备存在检查 (由 a.mutex 保护)，linkWarned 表示本次失联已提示过
+	linkCheck  serialport.LinkCheck
+	linkWarned bool
+
+	// 串口 DTR/RTS 最近一次设置的电平 (由 a.mutex 保护)，见 SetSerialLine；keep 时按驱动默认的有效电平记录
+	dtrHigh bool
+	rtsHigh bool
 
 	// 网络资源
-	netConn     net.Conn       // 用于 TCP Client, active TCP Server conn
-	netListener net.Listener   // 用于 TCP Server
-	udpConn     net.PacketConn // 用于 UDP
-	udpRemote   net.Addr       // UDP 远程地址 (用于发送)
+	netConn     net.Conn             // 用于 TCP Client
+	netListener net.Listener         // 用于 TCP Server
+	tcpClients  *transport.ClientSet // TCP Server 当前接入的客户端
+	udpConn     net.PacketConn       // 用于 UDP
+	udpSocket   *transport.UDPSocket // 绑定了多个本地端口时包含全部套接字 (udpConn 为第一个)
+	udpPeer     transport.UDPPeer    // UDP 的发送目标与回复套接字
+
+	// TCP Server 最后一个客户端断开后的处理方式 (由 SetClientLostPolicy 设置，a.mutex 保护)，跨连接保持
+	clientLost transport.ClientLostPolicy
+
+	// UDP 选项 (由 SetUdpOptions 设置)：udpConnected 由 a.mutex 保护，udpShowSource 由 streamMutex 保护
+	udpConnected  bool
+	udpShowSource bool
+
+	// TCP Client 连接失败后的诊断探测 (由 a.mutex 保护)，默认开启；probeAltPort 为额外探测的端口 (例如设备的 Web 界面)
+	skipTcpProbe bool
+	probeAltPort int
+
+	// 串口参数与发送容量检查 (由 a.mutex 保护)，仅串口连接时 txMonitor 不为 nil
+	// serialMode 为最近一次打开或 SetSerialMode 后读回比较的结果
+	serialParams serialParams
+	txMonitor    *serialport.TxMonitor
+	serialMode   *serialport.ModeCheck
+
+	// 7 位数据的最高位处理：sevenBitMode 为 SetSevenBitMode 的设置 (a.mutex 保护，跨连接保持)，
+	// sevenBit 为接收处理链中实际生效的过滤器 (自带锁)，非串口连接时为 off
+	sevenBitMode serialport.SevenBit
+	sevenBit     *serialport.SevenBitFilter
+
+	// TCP Client 收到对端 FIN 后仍保持可发送 (由 a.mutex 保护)；tcpCloseOnEOF 为 true 时恢复读到 EOF 即关闭连接
+	remoteHalfClosed bool
+	tcpCloseOnEOF    bool
+
+	// TCP Server 的客户端归属 (由 streamMutex 保护)：serverMode 时数据事件总是附带客户端地址，
+	// clientFilter 非空时只发送该客户端的数据事件 (历史缓冲区仍记录所有客户端)
+	serverMode   bool
+	clientFilter string
+
+	// 虚拟连接对的 B 端及其事件通道 (A 端使用 netConn)
+	virtualPeer net.Conn
+	peerChannel string
 
 	// RTT 资源
-	jlinkConn *jlink.JLinkWrapper
+	jlinkConn    *jlink.JLinkWrapper
+	rttStatus    JLinkStatus // 由 jlinkReadLoop 每秒更新
+	jlinkLogFile *os.File    // DLL 日志文件，由 SetJLinkLogLevel 开启 (由 a.mutex 保护)
+	jlinkLogSink *jlink.LogSink
+
+	// 接收历史（按序号索引，用于前端补齐丢失的事件）
+	history *history.Buffer
+
+	// 控制台模式的本地回显抑制
+	echo            *console.EchoSuppressor
+	echoSuppression bool
+
+	// 持久化设置
+	settings *settings.Store
+
+	// 写超时，防止卡死的 USB 串口使发送永久阻塞
+	writeTimeout time.Duration
+
+	// 打开超时 (由 a.mutex 保护)，防止无响应的驱动 (例如失联的蓝牙串口) 长时间占用 a.mutex；
+	// openGuard 记录正在进行的打开操作，CancelOpen 不需要 a.mutex 即可取消
+	openTimeout time.Duration
+	openGuard   openguard.Guard
+
+	// 发送大小限制：UDP 数据报上限及超限策略，串口/TCP 分块发送阈值
+	udpLimit           int
+	udpPolicy          transport.DatagramPolicy
+	largeSendThreshold int
+
+	// 前端传入的发送数据的校验规则 (大小上限、不合法 UTF-8 的处理)
+	inputLimits input.Limits
+
+	// 发送带宽上限 (字节/秒，由 a.mutex 保护)，0 表示不限速，跨连接保持；txBucket 在每次建立连接时重新创建
+	txRate   int
+	txBucket *transport.Bucket
+
+	// 接收处理选项，由读取循环访问，使用独立的锁避免与发送争用 a.mutex
+	streamMutex   sync.Mutex
+	formatEnabled bool
+	formatOpts    format.Options
+	hexDumper     *format.Dumper // 非 nil 时 Hex 使用多行转储格式
+
+	// 接收数据的编码 (由 SetRxEncoding 设置) 及当前连接的解码器，同样由 streamMutex 保护
+	rxEncoding textenc.Mode
+	rxDecoder  *textenc.Decoder
+
+	// 接收行分类规则 (由 SetClassifierRules 设置) 及当前连接跨数据块的行状态，同样由 streamMutex 保护
+	classifier  *classify.Classifier
+	lineTracker classify.Tracker
+
+	// TCP Server 各客户端跨数据块的接收状态，以客户端地址为键，同样由 streamMutex 保护；
+	// 每个客户端使用自己的解码器与行状态，不同客户端的半行与 UTF-16 码元不会拼接在一起
+	clientRx map[string]*clientRx
+
+	// 接收行的耗时计算 (由 SetLineTiming 开启，nil 表示关闭) 及当前连接的计时标记，同样由 streamMutex 保护；
+	// timeMarks 为连接开始与 SetTimeMark 的时间，ExportLines 按历史记录重新计算耗时时使用
+	lineTimer *linetime.Timer
+	timeMarks []time.Time
+
+	// 接收处理阶段的崩溃检测 (见 safemode 包)；safeMode 非 nil 时处于安全模式，
+	// 用户配置的阶段因崩溃未启用，直到 ApplyPipelineConfig 重新应用 (由 a.mutex 保护)
+	health   *safemode.Monitor
+	safeMode *SafeModeEvent
+
+	// 当前连接的会话统计，由 markConnected 创建，同样由 streamMutex 保护；
+	// lastSession 为上一次连接断开时的摘要 (由 a.mutex 保护)
+	sessionStats *session.Stats
+	lastSession  *session.Summary
+
+	// 触发捕获 (由 ArmBurstCapture 开启，nil 表示未开启)，同样由 streamMutex 保护；
+	// burstTimer 在没有新数据到达时按时结束进行中的捕获
+	burst      *burst.Recorder
+	burstTimer *time.Timer
+
+	// 后端绘图解析与 CSV 导出，同样由 streamMutex 保护
+	plotParser *plot.Parser
+	plotCsv    *plot.CSVWriter
+	plotFile   *logfile.File
+
+	// 长度前缀帧解码 (由 SetFrameDecoder 设置)，同样由 streamMutex 保护；
+	// frameDecoder 在每次建立连接时按 frameCfg 重新创建
+	frameCfg     *frame.Config
+	frameEmitBad bool
+	frameSchema  *frame.Schema // 字段表 (由 SetFrameSchema 设置)，与帧格式分开保存
+	frameDecoder *frame.Decoder
+
+	// JSON Lines 解析 (由 SetJsonLines 开启)，同样由 streamMutex 保护；每次建立连接时按相同选项重新创建
+	// jsonPlotKeys 非空时每条记录中这些键的值作为 plot-sample 发送并写入 CSV 导出
+	jsonDecoder  *jsonl.Decoder
+	jsonPlotKeys []string
+
+	// 双连接逐行比较 (由 StartCompare 开启)，同样由 streamMutex 保护；
+	// compareChannels 为 A、B 两侧的通道 ID，compareStop 停止 time 模式的过期检查
+	comparer        *compare.Comparer
+	compareChannels [2]string
+	compareStop     chan struct{}
+
+	// 网络连接的 PCAP 抓包，由 streamMutex 保护
+	pcapWriter *pcap.Writer
+	pcapFile   *logfile.File
+
+	// 会话录制 (由 streamMutex 保护)，见 StartRecording；recordSync 为 fsync 间隔，跨连接保持
+	recorder   *recording.Writer
+	recordSync time.Duration
+
+	// 导出/抓包文件是否 gzip 压缩 (由 streamMutex 保护)
+	captureCompress bool
+
+	// ZMODEM 自动接收，由 streamMutex 保护；zmodemFeed 非空时接收数据转交给接收状态机
+	zmodemAuto   bool
+	zmodemDir    string
+	zmodemDetect zmodem.Detector
+	zmodemFeed   *io.PipeWriter
+	zmodemIdle   *time.Timer
+
+	// 以相同参数重新打开当前连接，由各 Open* 方法在成功时设置 (由 a.mutex 保护)
+	reopen func() string
+
+	// 系统睡眠处理 (由 a.mutex 保护)：sleepReopen 为睡眠前关闭的连接的重新打开函数，
+	// resuming 表示唤醒后的重连正在进行；stopPower 停止监听睡眠/唤醒通知
+	sleepReopen func() string
+	resuming    bool
+	stopPower   func()
+
+	// 自动重连期间的发送队列 (由 SetSendQueue 开启，a.mutex 保护)，为 nil 时不排队，跨连接保持；
+	// reconnecting 表示看门狗正在关闭并重新打开连接
+	sendQueue    *outbox.Queue
+	reconnecting bool
+
+	// 当前连接的连接字符串 (见 connspec 包，由 a.mutex 保护)，由各 Open* 方法设置，用于日志文件头
+	connSpec string
+
+	// 连接标签与颜色 (由 a.mutex 保护)，应用于当前连接及之后打开的连接 (包括自动重连)
+	connLabel string
+	connColor string
+
+	// 接收静默看门狗配置 (由 a.mutex 保护)，watchdog 为当前连接的实例 (由 streamMutex 保护)
+	rxWatchdog RxWatchdogConfig
+	watchdog   *watchdog.Watchdog
+
+	// 定时发送任务，随应用运行，与连接无关
+	scheduler *schedule.Scheduler
+
+	// 发送优先级：交互发送在获取 a.mutex 之前登记，批量发送 (sendBulk) 在帧边界为其让出
+	txLanes *txlane.Gate
+
+	// 退出清理步骤，窗口关闭与 QuitApp 共用
+	cleanup shutdown.Orchestrator
+
+	// 发送模板的计数器，每个连接重新从 1 开始
+	templates *tmpl.Expander
+
+	// 粘贴模式 (由 a.mutex 保护)，pasteCancel 非空时有分块发送正在进行
+	paste       pasteConfig
+	pasteCancel chan struct{}
+
+	// 目录监视自动发送 (由 a.mutex 保护)，见 WatchSendDirectory
+	sendWatch *dropwatch.Watcher
+
+	// 锚点截取 (由 a.mutex 保护)，见 StartAnchorCapture；anchorScan 在停止后保留供 GetAnchorStats 查询
+	anchorScan *anchor.Scanner
+	anchorStop chan string
+
+	// Modbus 轮询 (由 a.mutex 保护)，见 StartModbusPoller
+	modbusPoller *modbus.Poller
+
+	// 接收数据的临时订阅 (Transact 等)，transactMutex 使并发的 Transact 排队执行
+	// rawHub 为接收处理链 (7 位处理、UTF-16 解码等) 之前的原始数据，见 AnalyzeRx
+	rxHub         *stream.Hub
+	rawHub        *stream.Hub
+	transactMutex sync.Mutex
+
+	// 首次启动时待发送的 first-run 事件，前端加载完成后发送 (由 a.mutex 保护)
+	firstRun *FirstRun
+
+	// lastUpdate the most recent CheckForUpdates result, used for the expected asset size (guarded by a.mutex)
+	lastUpdate updater.UpdateInfo
+
+	// 全局内存预算，rxPending 为所有接收队列中等待处理的字节数
+	memory     *membudget.Accountant
+	stopMemory func()
+	rxPending  atomic.Int64
+
+	// 当前连接的接收处理链，由 markConnected 按连接创建 (由 streamMutex 保护)
+	pipeline *stream.Pipeline
+
+	// 连接事件路由，channel 为当前连接的通道 ID (由 streamMutex 保护)
+	router  *events.Router
+	channel string
+
+	// 二进制数据传输，开启时数据块经回环 WebSocket 发送 (由 streamMutex 保护)，见 EnableBinaryTransport
+	binTransport *wsbin.Server
+
+	// sys-msg / serial-error 去重限流，防止错误风暴冻结消息区
+	limiter *events.Limiter
+}
+
+// defaultWriteTimeout 默认写超时
+const defaultWriteTimeout = 5 * time.Second
+
+// DataMeta 数据事件的元信息，作为 serial-data 事件的第二个参数发送
+// 第一个参数仍然是原始字节，旧版前端可以忽略该参数
+type DataMeta struct {
+	Seq    uint64 `json:"seq"`              // 每个连接内单调递增的序号，从 1 开始
+	Replay bool   `json:"replay,omitempty"` // 由 RequestReplay 重新发送的数据
+	Source string `json:"source,omitempty"` // UDP 数据报的来源地址 (SetUdpOptions 开启时) 或 TCP Server 的客户端地址
+	// UDP 绑定多个本地端口时，接收该数据报的本地端口
+	LocalPort int `json:"localPort,omitempty"`
+
+	// 由 SetDataFormatting 开启的预格式化表示
+	Hex       string `json:"hex,omitempty"`
+	Text      string `json:"text,omitempty"`
+	Truncated bool   `json:"truncated,omitempty"` // 格式化字符串超过单事件上限被截断
+
+	// 本数据块中结束的、被 SetClassifierRules 的规则匹配到的行
+	Lines []classify.LineClass `json:"lines,omitempty"`
+	// 本数据块中结束的每一行距上一行与距计时标记的耗时 (由 SetLineTiming 开启)
+	Timing []linetime.Line `json:"timing,omitempty"`
+}
+
+// JLinkStatus JLink 连接的运行统计
+type JLinkStatus struct {
+	jlink.RTTStats
+	BytesPerSec float64 `json:"bytesPerSec"` // 最近一秒的接收速率
+	TotalBytes  uint64  `json:"totalBytes"`
+}
+
+// ConnectionStatus GetConnectionStatus 的返回结果
+type ConnectionStatus struct {
+	Connected bool           `json:"connected"`
+	Type      ConnectionType `json:"type"`
+	Channel   string         `json:"channel,omitempty"` // 事件通道 ID，见 SubscribeChannel
+	Label     string         `json:"label,omitempty"`   // 见 SetConnectionLabel
+	Color     string         `json:"color,omitempty"`
+
+	ReadOnly  bool                   `json:"readOnly"`            // 见 SetReadOnly
+	Lines     *serialport.ModemLines `json:"lines,omitempty"`     // 仅串口：当前的输入状态线
+	LinkCheck serialport.LinkCheck   `json:"linkCheck,omitempty"` // 仅串口：见 SetLinkCheck
+	SilenceMs int64                  `json:"silenceMs"`           // 距最近一次接收的时间 (毫秒)，仅在看门狗开启时有效
+	TxLimit   int                    `json:"txLimit"`             // 发送带宽上限 (字节/秒)，0 表示不限速，见 SetTxBandwidthLimit
+	TxUsage   float64                `json:"txUsage"`             // 最近一秒发送量占带宽上限的比例，不限速时为 0
+	JLink     *JLinkStatus           `json:"jlink,omitempty"`     // 仅 JLink 连接
+	UdpPorts  []int                  `json:"udpPorts,omitempty"`  // 仅 UDP：已绑定的本地端口
+	// 仅 TCP Client：对端已关闭写方向 (收到 FIN)，不再接收数据但仍可发送
+	HalfClosed bool `json:"halfClosed,omitempty"`
+	// 仅串口：请求的参数与读回的实际参数，见 OpenSerial
+	SerialMode *serialport.ModeCheck `json:"serialMode,omitempty"`
+	// 仅串口：线路错误统计，见 SetSevenBitMode
+	LineErrors *LineErrors `json:"lineErrors,omitempty"`
+}
+
+// LineErrors 串口接收的线路错误统计
+type LineErrors struct {
+	SevenBit serialport.SevenBit `json:"sevenBit"` // 生效的 7 位处理方式
+	Parity   uint64              `json:"parity"`   // even/odd 模式下校验失败的字节数
+}
+
+// RxWatchdogConfig 接收静默看门狗配置
+type RxWatchdogConfig struct {
+	Enabled bool          `json:"enabled"`
+	Timeout time.Duration `json:"timeout"`
+	Action  string        `json:"action"` // "event"、"send" 或 "reconnect"
+	Probe   []byte        `json:"probe"`  // action 为 "send" 时发送的数据
+}
+
+// VirtualPair OpenVirtualPair 返回的两端事件通道 ID
+type VirtualPair struct {
+	A string `json:"a"`
+	B string `json:"b"`
+}
+
+// AnnotationEvent annotation 事件的数据
+type AnnotationEvent struct {
+	Seq    uint64 `json:"seq"`              // 与数据事件共用的序号
+	Time   int64  `json:"time"`             // Unix 毫秒
+	Text   string `json:"text"`             // 原始标注文本
+	Line   string `json:"line"`             // 文本日志中的表示 ("#" 开头)
+	Replay bool   `json:"replay,omitempty"` // 由 RequestReplay 重新发送
+}
+
+// TransactResult Transact 的返回结果
+type TransactResult struct {
+	Data     []byte `json:"data"`
+	Hex      string `json:"hex"`
+	Complete bool   `json:"complete"` // 在超时前收到了结束符
+}
+
+// ReliableResult SendReliable 的返回结果
+type ReliableResult struct {
+	Acked     bool   `json:"acked"`
+	Outcome   string `json:"outcome"`  // 最后一次尝试的结果："ack"、"nak" 或 "timeout"
+	Attempts  int    `json:"attempts"` // 实际发送次数
+	ElapsedMs int64  `json:"elapsedMs"`
+}
+
+// ModbusPollError modbus-error 事件，Modbus 轮询中一次读取失败时发送
+type ModbusPollError struct {
+	modbus.SlaveStats
+	Error string `json:"error"`
+}
+
+// ReliableAttempt reliable-attempt 事件，每次尝试结束时发送
+type ReliableAttempt struct {
+	Attempt     int    `json:"attempt"` // 从 1 开始
+	MaxAttempts int    `json:"maxAttempts"`
+	Outcome     string `json:"outcome"` // "ack"、"nak"、"timeout"
+	ElapsedMs   int64  `json:"elapsedMs"`
+}
+
+// ReplayResult RequestReplay 的返回结果
+type ReplayResult struct {
+	FirstSeq  uint64 `json:"firstSeq"`  // 实际重发的第一个序号
+	LastSeq   uint64 `json:"lastSeq"`   // 实际重发的最后一个序号
+	Count     int    `json:"count"`     // 重发的事件数量
+	Truncated bool   `json:"truncated"` // 请求的序号已被淘汰，从最早可用的数据开始重发
 }
 
 // NewApp creates a new App application struct
 func NewApp() *App {
-	return &App{}
+	a := &App{
+		history:  history.New(history.DefaultLimit),
+		echo:     console.NewEchoSuppressor(500 * time.Millisecond),
+		settings: openSettings(),
+
+		writeTimeout:       defaultWriteTimeout,
+		openTimeout:        openguard.DefaultTimeout,
+		udpLimit:           transport.MaxUDPPayload,
+		udpPolicy:          transport.DatagramReject,
+		clientLost:         transport.ClientLostWait,
+		largeSendThreshold: transport.DefaultLargeSendThreshold,
+		formatOpts:         format.DefaultOptions,
+		rxEncoding:         textenc.Raw,
+		inputLimits:        input.Limits{UTF8: input.UTF8Reject},
+		linkCheck:          serialport.LinkCheckOff,
+		recordSync:         recording.DefaultSyncInterval,
+		sevenBitMode:       serialport.SevenBitAuto,
+		sevenBit:           serialport.NewSevenBitFilter(),
+		paste:              newPasteConfig(false, 0, 0, 0, "", 0),
+	}
+	a.limiter = events.NewLimiter(func(name, msg string) {
+		runtime.EventsEmit(a.ctx, name, msg)
+	})
+	a.router = events.NewRouter(a.emit)
+	a.pipeline = a.newPipeline()
+	a.rxHub = stream.NewHub()
+	a.rawHub = stream.NewHub()
+	a.templates = tmpl.NewExpander()
+	a.scheduler = schedule.New(a.fireSchedule)
+	a.txLanes = txlane.New(txlane.Config{})
+	a.loadSchedules()
+	a.loadPipelineHealth()
+	a.loadHTTPTransport()
+	a.runOnboarding()
+	a.startMemoryBudget()
+	a.scheduler.Start()
+	a.registerCleanup()
+	return a
+}
+
+// FirstRun first-run 事件的数据
+type FirstRun struct {
+	Version  int      `json:"version"`  // 引导内容版本
+	Profiles []string `json:"profiles"` // 本次安装的示例配置
+}
+
+// runOnboarding 引导内容尚未安装 (或有新版本) 时安装示例配置；
+// 首次启动 (设置文件尚不存在) 时在前端加载完成后发送 first-run 事件，前端可据此开始引导
+func (a *App) runOnboarding() {
+	prev := a.settings.Get().OnboardingVersion
+	if prev >= onboarding.Version {
+		return
+	}
+	added, err := a.installOnboarding()
+	if err != nil {
+		fmt.Printf("Onboarding skipped: %v\n", err)
+		return
+	}
+	if prev == 0 && a.settings.Fresh() {
+		a.firstRun = &FirstRun{Version: onboarding.Version, Profiles: added}
+	}
+}
+
+// installOnboarding 补装缺少的示例配置并记录引导版本，已存在的同名配置保持不变
+func (a *App) installOnboarding() ([]string, error) {
+	added := []string{}
+	var installErr error
+	err := a.settings.Update(func(s *settings.Settings) {
+		var names []string
+		if s.Profiles, names, installErr = onboarding.Install(s.Profiles); installErr == nil {
+			added = append(added, names...)
+			s.OnboardingVersion = onboarding.Version
+		}
+	})
+	if installErr != nil {
+		return nil, installErr
+	}
+	return added, err
+}
+
+// ResetOnboarding 重新运行首次启动引导：补装被删除的示例配置 (不覆盖同名配置) 并立即发送 first-run 事件
+func (a *App) ResetOnboarding() string {
+	added, err := a.installOnboarding()
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	}
+	a.emit("first-run", FirstRun{Version: onboarding.Version, Profiles: added})
+	return "Success"
+}
+
+// LoadDemoSession 将内置的演示会话载入历史缓冲区 (替换现有历史)，之后可用 RequestReplay 回放或导出
+// 连接期间不可用，避免与实时数据混在一起；下次建立连接时历史照常清空
+func (a *App) LoadDemoSession() (history.Range, error) {
+	chunks, err := onboarding.Demo(time.Now())
+	if err != nil {
+		return history.Range{}, err
+	}
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	if a.isConnected {
+		return history.Range{}, fmt.Errorf("close the connection before loading the demo session")
+	}
+	a.history.Reset()
+	for _, c := range chunks {
+		if c.Annotation != "" {
+			a.history.AppendAnnotation(c.Time, c.Annotation)
+		} else {
+			a.history.Append(c.Time, c.Data)
+		}
+	}
+	r := a.history.Range()
+	a.emit("backend-has-history", r)
+	return r, nil
+}
+
+// registerCleanup 注册退出清理步骤，按顺序执行：先关闭连接 (停止读取循环与看门狗)，再关闭导出文件
+// 设置在每次修改时已经保存，无需额外处理
+func (a *App) registerCleanup() {
+	a.cleanup.Add("stop scheduler", func(context.Context) error {
+		a.scheduler.Stop()
+		return nil
+	})
+	a.cleanup.Add("stop memory budget", func(context.Context) error {
+		a.stopMemory()
+		return nil
+	})
+	a.cleanup.Add("stop power watch", func(context.Context) error {
+		a.mutex.Lock()
+		stop := a.stopPower
+		a.mutex.Unlock()
+		if stop != nil {
+			stop()
+		}
+		return nil
+	})
+	a.cleanup.Add("close connection", func(context.Context) error {
+		if result := a.Close(); result != "Success" && result != "Not connected" {
+			return fmt.Errorf("%s", result)
+		}
+		return nil
+	})
+	a.cleanup.Add("stop binary transport", func(context.Context) error {
+		_, err := a.EnableBinaryTransport(false)
+		return err
+	})
+	a.cleanup.Add("stop plot csv export", func(context.Context) error {
+		if result := a.StopPlotCsv(); strings.HasPrefix(result, "Error") {
+			return fmt.Errorf("%s", result)
+		}
+		return nil
+	})
+	a.cleanup.Add("stop pcap capture", func(context.Context) error {
+		if result := a.StopPcapCapture(); strings.HasPrefix(result, "Error") {
+			return fmt.Errorf("%s", result)
+		}
+		return nil
+	})
+}
+
+// runCleanup 执行退出清理，最多等待 shutdown.DefaultTimeout，卡住的连接不会阻止退出
+func (a *App) runCleanup() {
+	report := a.cleanup.Run(shutdown.DefaultTimeout)
+	for _, step := range report.Steps {
+		if step.Error != "" {
+			fmt.Printf("Shutdown step %q failed: %s\n", step.Name, step.Error)
+		}
+	}
+	if report.TimedOut {
+		fmt.Printf("Shutdown timed out, skipped: %s\n", strings.Join(report.Skipped, ", "))
+	}
+}
+
+// beforeClose 窗口关闭前完成清理，然后允许关闭
+func (a *App) beforeClose(ctx context.Context) (prevent bool) {
+	a.runCleanup()
+	return false
+}
+
+// shutdown 应用退出时调用，清理已执行过时立即返回
+func (a *App) shutdown(ctx context.Context) {
+	a.runCleanup()
+}
+
+// openSettings 加载用户设置，失败时退回到仅内存的设置存储
+func openSettings() *settings.Store {
+	path, err := settings.DefaultPath()
+	if err == nil {
+		var store *settings.Store
+		if store, err = settings.Open(path); err == nil {
+			if store.Backup() != "" {
+				fmt.Printf("Settings migrated from version %d, original saved to %s\n", store.FileVersion(), store.Backup())
+			}
+			return store
+		}
+	}
+	fmt.Printf("Settings unavailable, using defaults: %v\n", err)
+	return settings.NewMemoryStore()
 }
 
 func (a *App) startup(ctx context.Context) {
 	a.ctx = ctx
+	stop := power.Watch(power.Handler{Suspend: a.onSuspend, Resume: a.onResume})
+	a.mutex.Lock()
+	a.stopPower = stop
+	a.mutex.Unlock()
+}
+
+// domReady 前端每次加载完成 (包括重新加载) 时调用，告知前端后端保留了多少接收历史，
+// 前端可据此调用 GetRecentData 恢复接收区；设置文件为只读时每次都发送 settings-read-only
+func (a *App) domReady(ctx context.Context) {
+	a.emit("backend-has-history", a.history.Range())
+
+	a.mutex.Lock()
+	first := a.firstRun
+	a.firstRun = nil
+	a.mutex.Unlock()
+	if first != nil {
+		a.emit("first-run", *first)
+	}
+	a.mutex.Lock()
+	safeMode := a.safeMode
+	a.mutex.Unlock()
+	if safeMode != nil && safeMode.Startup {
+		a.emit("safe-mode", *safeMode)
+		a.emit("sys-msg", fmt.Sprintf("Safe mode: previous run crashed: %s; not loaded %s", safeMode.Crash, strings.Join(safeMode.Disabled, ", ")))
+	}
+	if a.settings.ReadOnly() {
+		a.emit("settings-read-only", SettingsReadOnly{
+			Path:             a.settings.Path(),
+			FileVersion:      a.settings.FileVersion(),
+			SupportedVersion: settings.SchemaVersion,
+		})
+	}
+}
+
+// SettingsReadOnly settings-read-only 事件的数据：设置文件由更新版本的程序写入，
+// 本次运行照常使用其中认识的设置，但不保存任何修改，前端应提示用户更新程序
+type SettingsReadOnly struct {
+	Path             string `json:"path"`
+	FileVersion      int    `json:"fileVersion"`
+	SupportedVersion int    `json:"supportedVersion"`
 }
 
 // 1. 获取串口列表
@@ -70,70 +760,147 @@ func (a *App) GetSerialPorts() ([]string, error) {
 	return ports, nil
 }
 
-// --- 连接逻辑封装 ---
-
-// OpenSerial 打开串口
-func (a *App) OpenSerial(portName string, baudRate int, dataBits int, stopBits int, parityName string) string {
-	a.mutex.Lock()
-	defer a.mutex.Unlock()
+// SerialDevice 枚举到的串口及其设备指纹 (见 devid 包)
+type SerialDevice struct {
+	devid.Port
+	Fingerprint devid.Fingerprint `json:"fingerprint,omitempty"` // 非 USB 串口为空
+	Unique      bool              `json:"unique"`                // 指纹包含序列号
+	Alias       string            `json:"alias,omitempty"`
+}
 
-	if a.isConnected {
-		return "Already connected"
+// listDevicePorts 枚举串口及 USB 信息
+func listDevicePorts() ([]devid.Port, error) {
+	details, err := enumerator.GetDetailedPortsList()
+	if err != nil {
+		return nil, err
+	}
+	ports := make([]devid.Port, 0, len(details))
+	for _, d := range details {
+		ports = append(ports, devid.Port{Name: d.Name, IsUSB: d.IsUSB, VID: d.VID, PID: d.PID, SerialNumber: d.SerialNumber, Product: d.Product})
 	}
+	return ports, nil
+}
 
-	var parity serial.Parity
-	switch parityName {
-	case "None":
-		parity = serial.NoParity
-	case "Odd":
-		parity = serial.OddParity
-	case "Even":
-		parity = serial.EvenParity
-	case "Mark":
-		parity = serial.MarkParity
-	case "Space":
-		parity = serial.SpaceParity
-	default:
-		parity = serial.NoParity
+// portFingerprint 返回端口上当前设备的指纹，无法识别或枚举失败时为空
+func portFingerprint(portName string) devid.Fingerprint {
+	ports, err := listDevicePorts()
+	if err != nil {
+		return ""
 	}
+	p, _ := devid.Find(portName, ports)
+	return devid.Of(p)
+}
 
-	var stop serial.StopBits
-	switch stopBits {
-	case 1:
-		stop = serial.OneStopBit
-	case 15:
-		stop = serial.OnePointFiveStopBits
-	case 2:
-		stop = serial.TwoStopBits
-	default:
-		stop = serial.OneStopBit
+// ListSerialDevices 返回串口及其设备指纹与别名
+func (a *App) ListSerialDevices() ([]SerialDevice, error) {
+	ports, err := listDevicePorts()
+	if err != nil {
+		return nil, err
 	}
+	aliases := a.settings.Get().DeviceAliases
+	list := make([]SerialDevice, 0, len(ports))
+	for _, p := range ports {
+		fp := devid.Of(p)
+		list = append(list, SerialDevice{Port: p, Fingerprint: fp, Unique: fp.Unique(), Alias: aliases[string(fp)]})
+	}
+	return list, nil
+}
 
-	mode := &serial.Mode{
-		BaudRate: baudRate,
-		DataBits: dataBits,
-		Parity:   parity,
-		StopBits: stop,
+// DeviceResolution ResolveDevice 的结果：Port 为唯一匹配的端口；
+// 多个插入的设备共用指纹 (没有序列号) 时 Port 为空，Candidates 列出可选的端口
+type DeviceResolution struct {
+	Fingerprint devid.Fingerprint `json:"fingerprint"`
+	Port        string            `json:"port,omitempty"`
+	Candidates  []string          `json:"candidates,omitempty"`
+}
+
+// ResolveDevice 查找设备指纹当前对应的端口名，设备未插入时返回错误
+func (a *App) ResolveDevice(fingerprint string) (DeviceResolution, error) {
+	fp, err := devid.Parse(fingerprint)
+	if err != nil {
+		return DeviceResolution{}, err
+	}
+	ports, err := listDevicePorts()
+	if err != nil {
+		return DeviceResolution{}, fmt.Errorf("failed to enumerate serial ports: %w", err)
+	}
+	res := DeviceResolution{Fingerprint: fp}
+	port, err := devid.Resolve(fp, ports)
+	var amb *devid.AmbiguousError
+	switch {
+	case errors.As(err, &amb):
+		res.Candidates = amb.Candidates
+	case err != nil:
+		return res, err
+	default:
+		res.Port = port
 	}
+	return res, nil
+}
 
-	port, err := serial.Open(portName, mode)
+// SetDeviceAlias 设置设备的别名，alias 为空时删除
+func (a *App) SetDeviceAlias(fingerprint string, alias string) string {
+	fp, err := devid.Parse(fingerprint)
 	if err != nil {
 		return fmt.Sprintf("Error: %v", err)
 	}
+	alias = strings.TrimSpace(alias)
+	if len([]rune(alias)) > commands.MaxNameLen {
+		return fmt.Sprintf("Error: alias must be at most %d characters", commands.MaxNameLen)
+	}
+	if err := a.settings.Update(func(s *settings.Settings) {
+		if alias == "" {
+			delete(s.DeviceAliases, string(fp))
+			return
+		}
+		if s.DeviceAliases == nil {
+			s.DeviceAliases = make(map[string]string)
+		}
+		s.DeviceAliases[string(fp)] = alias
+	}); err != nil {
+		return fmt.Sprintf("Error saving settings: %v", err)
+	}
+	return "Success"
+}
 
-	port.SetMode(mode)
-	port.SetDTR(true)
-	port.SetRTS(true)
-
-	a.serialPort = port
-	a.connType = TypeSerial
-	a.startReadLoop(port) // 启动通用读取循环
+// adoptDevice 把按端口名保存的设置迁移到刚打开的设备 (见 settings.AdoptDevice)
+func (a *App) adoptDevice(portName string, fp devid.Fingerprint) {
+	if !fp.Unique() {
+		return
+	}
+	if _, ok := a.settings.Get().SerialLines[portName]; !ok && !a.hasUnboundProfile() {
+		return
+	}
+	changed := 0
+	if err := a.settings.Update(func(s *settings.Settings) {
+		changed = s.AdoptDevice(portName, string(fp))
+	}); err != nil {
+		a.emit("sys-msg", fmt.Sprintf("保存设备设置失败: %v", err))
+		return
+	}
+	if changed > 0 {
+		a.emit("sys-msg", fmt.Sprintf("Settings for %s now follow device %s", portName, fp))
+	}
+}
 
-	return "Success"
+// hasUnboundProfile 是否有未绑定设备的连接配置，没有时迁移无需写入设置
+func (a *App) hasUnboundProfile() bool {
+	for _, p := range a.settings.Get().Profiles {
+		if p.Device == "" {
+			return true
+		}
+	}
+	return false
 }
 
-// OpenJLink 连接 RTT
-func (a *App) OpenJLink(chip string, speed int, iface string) string {
+// --- 连接逻辑封装 ---
+
+// OpenSerial 打开串口
+// initialDtr/initialRts 为 "high"、"low"、"keep" (不修改) 或空字符串 (使用该端口保存的设置，默认 high)，
+// 显式指定的状态会保存为该端口的默认值
+// openRetry 为端口不存在时的重试次数 (设备刚插入时驱动可能尚未就绪)，权限不足等错误不会重试
+// 打开后读回驱动实际生效的参数 (见 serial-mode 事件)，驱动改写了请求的参数时结果为 "Success (mode mismatch: ...)"
+func (a *App) OpenSerial(portName string, baudRate int, dataBits int, stopBits int, parityName string, initialDtr string, initialRts string, openRetry int) string {
 	a.mutex.Lock()
 	defer a.mutex.Unlock()
 
@@ -141,108 +908,287 @@ func (a *App) OpenJLink(chip string, speed int, iface string) string {
 		return "Already connected"
 	}
 
-	// 定义日志回调函数，将日志发送到前端 RX Monitor
-	logCallback := func(message string) {
-		// 将日志消息作为字符串发送到前端
-		logData := []byte(message + "\n")
-		runtime.EventsEmit(a.ctx, "serial-data", logData)
+	dtr, err := serialport.ParseLineState(initialDtr)
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	}
+	rts, err := serialport.ParseLineState(initialRts)
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err)
 	}
 
-	// 1. 加载驱动
-	jl, err := jlink.NewJLinkWrapper(logCallback)
+	mode := serialport.ParseMode(baudRate, dataBits, stopBits, parityName)
+
+	lock, err := a.claimPort(portName)
 	if err != nil {
-		return err.Error()
+		return fmt.Sprintf("Error: %v", err)
 	}
+	opened := false
+	defer func() {
+		if !opened {
+			lock.Release()
+		}
+	}()
 
-	// 2. 连接芯片
-	err = jl.Connect(chip, speed, iface)
+	// 超时作用于每次尝试，重试间隔中取消时下一次尝试立即返回
+	ctx, end := a.openGuard.Begin()
+	defer end()
+	port, err := serialport.OpenWithRetry(func() (serial.Port, error) {
+		return openSerialPort(ctx, a.openTimeout, portName, mode)
+	}, openRetry, serialport.DefaultRetryDelay)
 	if err != nil {
-		// 连接失败需要释放资源
-		jl.Close()
-		return err.Error()
+		return "Error: " + serialOpenError(portName, err).Error()
 	}
 
-	a.jlinkConn = jl
-	a.connType = TypeJLink
-	a.isConnected = true
-	a.readStopChan = make(chan struct{})
+	port.SetMode(mode)
 
-	// 3. 启动 RTT 专用读取循环 (因为它的 API 不是 io.Reader 风格，而是轮询)
-	go a.jlinkReadLoop()
+	fp := portFingerprint(portName)
+	current := a.settings.Get()
+	saved, _ := current.LinesFor(string(fp), portName) // 旧版本按端口名保存的设置同样生效
+	dtr = dtr.Resolve(serialport.LineState(saved.DTR))
+	rts = rts.Resolve(serialport.LineState(saved.RTS))
+	if err := serialport.ApplyLines(port, dtr, rts); err != nil {
+		port.Close()
+		return fmt.Sprintf("Error: %v", err)
+	}
+	a.adoptDevice(portName, fp)
+	if initialDtr != "" || initialRts != "" {
+		a.saveSerialLines(portName, fp, dtr, rts)
+	}
+	a.dtrHigh, a.rtsHigh = dtr != serialport.LineLow, rts != serialport.LineLow
 
-	return "Success"
+	opened = true
+	a.serialPort = port
+	a.portLock = lock
+	a.portName = portName
+	a.connType = TypeSerial
+	a.connSpec = connspec.Spec{Kind: connspec.Serial, Port: portName, Baud: baudRate, DataBits: dataBits, Parity: parityName, StopBits: stopBits}.String()
+	a.sevenBit.SetMode(a.sevenBitMode.Resolve(dataBits)) // 在读取循环启动前生效
+	a.startReadLoop(port)                                // 启动通用读取循环
+	a.serialParams = serialParams{baudRate: baudRate, dataBits: dataBits, stopBits: stopBits, parity: parityName}
+	a.txMonitor = serialport.NewTxMonitor(*mode)
+	result := a.checkSerialModeLocked(*mode)
+
+	// 重新打开时使用最近一次 SetSerialMode 设置的参数
+	a.reopen = func() string {
+		a.mutex.Lock()
+		p := a.serialParams
+		a.mutex.Unlock()
+		return a.OpenSerial(portName, p.baudRate, p.dataBits, p.stopBits, p.parity, initialDtr, initialRts, openRetry)
+	}
+	return result
 }
 
-// jlinkReadLoop 专用的 RTT 轮询循环
-func (a *App) jlinkReadLoop() {
-	ticker := time.NewTicker(10 * time.Millisecond) // 10ms 轮询一次
-	defer ticker.Stop()
+// serialParams 当前串口连接的参数，格式同 OpenSerial
+type serialParams struct {
+	baudRate, dataBits, stopBits int
+	parity                       string
+}
 
-	consecutiveErrors := 0
-	// 连续错误次数阈值：允许少量偶发错误，避免瞬时故障导致断连
-	// 但在持续错误时及时断开连接，防止无效轮询占用资源
-	const maxConsecutiveErrors = 10
+// openSerialPort 在 timeout 内打开串口，超时或取消后才打开成功的端口被关闭
+func openSerialPort(ctx context.Context, timeout time.Duration, portName string, mode *serial.Mode) (serial.Port, error) {
+	return openguard.Run(ctx, timeout, "open "+portName, func(context.Context) (serial.Port, error) {
+		return serial.Open(portName, mode)
+	}, func(p serial.Port) { p.Close() })
+}
 
-	for {
-		select {
-		case <-a.readStopChan:
-			return
-		case <-ticker.C:
-			// 检查连接是否还在 (需要加锁读取 jlinkConn，或者假设 stopChan 会处理)
-			// 注意：这里为了性能，简单处理，如果 closed 会置为 nil，所以要小心
-			a.mutex.Lock()
-			jl := a.jlinkConn
-			a.mutex.Unlock()
+// serialOpenError 为打开串口的错误附加错误码 (端口被占用、不存在、权限不足等)，
+// 返回文本以错误码开头，前端可以据此区分处理
+// 端口被占用或拒绝访问时查找占用端口的进程，附加在信息中 (例如 "held by putty.exe (pid 4321)")
+func serialOpenError(portName string, err error) error {
+	if openguard.Aborted(err) {
+		return err
+	}
+	code := serialport.TranslateError(err).Code
+	if code == apperr.PortBusy || code == apperr.PermissionDenied {
+		if report, werr := serialport.WhoHasPort(portName); werr == nil {
+			return apperr.Wrap(code, err, "open %s (%s)", portName, report.Summary())
+		}
+	}
+	return apperr.Wrap(code, err, "open %s", portName)
+}
 
-			if jl == nil {
-				return
-			}
+// WhoHasPort 查找打开了串口的进程 (Linux 扫描 /proc，Windows 枚举句柄表)
+// 平台不支持或权限不足导致结果不完整时，Supported/Complete 为 false 并在 Note 中说明
+func (a *App) WhoHasPort(portName string) (serialport.HolderReport, error) {
+	return serialport.WhoHasPort(portName)
+}
 
-			data, err := jl.ReadRTT()
-			if err != nil {
-				consecutiveErrors++
+// SetSerialMode 修改已打开串口的波特率、数据位、停止位与校验 (参数格式同 OpenSerial)，不重新打开端口
+// 与 OpenSerial 相同，设置后读回驱动实际生效的参数，不一致时结果为 "Success (mode mismatch: ...)"
+// 发送容量检查 (见 tx-overcommitted 事件) 随之按新参数计算；之后的重新连接同样使用新参数
+func (a *App) SetSerialMode(baudRate int, dataBits int, stopBits int, parityName string) string {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
 
-				// 检测是否是偏移量错误（STM32 复位导致）
-				errMsg := err.Error()
-				if consecutiveErrors == 1 && (strings.Contains(errMsg, "offset out of bounds") ||
-					strings.Contains(errMsg, "偏移量超出范围")) {
-					runtime.EventsEmit(a.ctx, "sys-msg", "[RTT] 检测到目标设备可能已复位，尝试重新连接...")
-					// 尝试重新初始化 RTT
-					if reinitErr := jl.ReinitSoftRTT(); reinitErr == nil {
-						runtime.EventsEmit(a.ctx, "sys-msg", "[RTT] RTT 重新初始化成功")
-						consecutiveErrors = 0
-						continue
-					} else {
-						runtime.EventsEmit(a.ctx, "sys-msg", fmt.Sprintf("[RTT] RTT 重新初始化失败: %v", reinitErr))
-					}
-				}
+	if !a.isConnected || a.connType != TypeSerial || a.serialPort == nil {
+		return "Error: serial port not connected"
+	}
+	if baudRate <= 0 {
+		return fmt.Sprintf("Error: invalid baud rate %d", baudRate)
+	}
+	mode := serialport.ParseMode(baudRate, dataBits, stopBits, parityName)
+	if err := a.serialPort.SetMode(mode); err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	}
+	a.serialParams = serialParams{baudRate: baudRate, dataBits: dataBits, stopBits: stopBits, parity: parityName}
+	a.connSpec = connspec.Spec{Kind: connspec.Serial, Port: a.portName, Baud: baudRate, DataBits: dataBits, Parity: parityName, StopBits: stopBits}.String()
+	a.sevenBit.SetMode(a.sevenBitMode.Resolve(dataBits))
+	if a.txMonitor != nil {
+		a.txMonitor.SetMode(*mode)
+	}
+	a.emit("sys-msg", fmt.Sprintf("Serial mode changed: %d baud, %.0f B/s line capacity", baudRate, serialport.Capacity(*mode)))
+	return a.checkSerialModeLocked(*mode)
+}
 
-				// 增加容错机制：只有连续多次错误才关闭连接
-				// 这样可以避免偶发错误导致断连，同时确保持续错误时能及时断开
-				if consecutiveErrors >= maxConsecutiveErrors {
-					runtime.EventsEmit(a.ctx, "serial-error", fmt.Sprintf("[RTT] 错误 (连续 %d 次): %v", consecutiveErrors, err))
-					a.Close()
-					return
-				}
-				// 首次或少量错误时，仅记录日志，继续尝试
-				if consecutiveErrors == 1 {
-					runtime.EventsEmit(a.ctx, "sys-msg", fmt.Sprintf("[RTT] 读取警告: %v", err))
-				}
-				continue
-			}
+// SetSerialLine 设置串口控制线：line 为 "dtr" 或 "rts"，state 为 "high"、"low" 或 "toggle" (翻转最近一次设置的电平)
+// 只影响当前连接，不修改保存的初始状态
+func (a *App) SetSerialLine(line string, state string) string {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
 
-			// 成功读取，重置错误计数
-			consecutiveErrors = 0
+	if !a.isConnected || a.connType != TypeSerial || a.serialPort == nil {
+		return "Error: serial port not connected"
+	}
+	level := &a.dtrHigh
+	set := a.serialPort.SetDTR
+	switch line {
+	case "dtr":
+	case "rts":
+		level, set = &a.rtsHigh, a.serialPort.SetRTS
+	default:
+		return fmt.Sprintf("Error: unknown line %q (expected dtr or rts)", line)
+	}
+	high := *level
+	switch state {
+	case "high":
+		high = true
+	case "low":
+		high = false
+	case "toggle":
+		high = !high
+	default:
+		return fmt.Sprintf("Error: unknown line state %q (expected high, low or toggle)", state)
+	}
+	if err := set(high); err != nil {
+		return fmt.Sprintf("Error: failed to set %s: %v", strings.ToUpper(line), err)
+	}
+	*level = high
+	if high {
+		return "Success (" + strings.ToUpper(line) + " high)"
+	}
+	return "Success (" + strings.ToUpper(line) + " low)"
+}
 
-			if len(data) > 0 {
-				runtime.EventsEmit(a.ctx, "serial-data", data)
-			}
+// checkSerialModeLocked 读回串口实际生效的参数 (Linux 为 TCGETS2，Windows 为 GetCommState，其他平台为 unverified)
+// 并与请求的参数比较，结果以 serial-mode 事件发送并记录在 GetConnectionStatus 中；
+// 驱动改写了参数时发送 sys-msg 警告，返回 "Success (mode mismatch: ...)"。调用方必须持有 a.mutex
+func (a *App) checkSerialModeLocked(mode serial.Mode) string {
+	check := serialport.CheckMode(a.serialPort, mode)
+	a.serialMode = &check
+	a.emitConn("serial-mode", check)
+	if !check.Mismatch {
+		return "Success"
+	}
+	a.emit("sys-msg", "Warning: "+check.Summary())
+	return "Success (" + check.Summary() + ")"
+}
+
+// trackTxLocked 记录串口发送量，持续发送需求超过线路容量的 90% 时发送一次 tx-overcommitted 警告，
+// 之后在超额期间每秒发送一次 tx-stats (含估计的积压字节数)；网络连接不做检查。调用方必须持有 a.mutex
+func (a *App) trackTxLocked(n int) {
+	if a.connType != TypeSerial || a.txMonitor == nil {
+		return
+	}
+	report, ok, first := a.txMonitor.Add(time.Now(), n)
+	if first {
+		a.emitConn("tx-overcommitted", report)
+		a.emit("sys-msg", "Warning: "+report.String()+". Data will queue up and may be lost; lower the send rate or raise the baud rate")
+	}
+	if ok {
+		a.emitConn("tx-stats", report)
+	}
+}
+
+// saveSerialLines 保存端口的控制线初始状态
+func (a *App) saveSerialLines(portName string, fp devid.Fingerprint, dtr, rts serialport.LineState) {
+	// 带序列号的设备按指纹保存，跟随设备而不是端口名
+	key := portName
+	if fp.Unique() {
+		key = string(fp)
+	}
+	if err := a.settings.Update(func(s *settings.Settings) {
+		if s.SerialLines == nil {
+			s.SerialLines = make(map[string]settings.SerialLines)
 		}
+		s.SerialLines[key] = settings.SerialLines{DTR: string(dtr), RTS: string(rts)}
+	}); err != nil {
+		a.emit("sys-msg", fmt.Sprintf("保存 DTR/RTS 设置失败: %v", err))
 	}
 }
 
-// OpenTcpClient 连接 TCP 服务端
-func (a *App) OpenTcpClient(ip string, port string) string {
+// portLockDir 返回端口锁文件目录
+func portLockDir() (string, error) {
+	dir, err := settings.ConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "locks"), nil
+}
+
+// claimPort 在打开串口前获取端口锁，端口被另一个存活的实例占用时返回 PORT_CLAIMED_BY_OTHER_INSTANCE
+// 无法确定配置目录时不加锁 (返回 nil 锁)
+func (a *App) claimPort(portName string) (*portlock.Lock, error) {
+	dir, err := portLockDir()
+	if err != nil {
+		return nil, nil
+	}
+	lock, err := portlock.Acquire(dir, portName)
+	var claimed *portlock.ClaimedError
+	if errors.As(err, &claimed) {
+		return nil, apperr.New(apperr.PortClaimed, "%s; use ReleasePortClaim to override", claimed.Error())
+	}
+	if err != nil {
+		// 锁文件不可用不应阻止打开串口
+		fmt.Printf("Port lock unavailable: %v\n", err)
+		return nil, nil
+	}
+	return lock, nil
+}
+
+// ReleasePortClaim 强制删除端口的锁文件，用于确认另一个实例的占用可以忽略
+// 之后可以重新调用 OpenSerial/OpenSlcan
+func (a *App) ReleasePortClaim(portName string) string {
+	dir, err := portLockDir()
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	}
+	if err := portlock.ForceRelease(dir, portName); err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	}
+	return "Success"
+}
+
+// SetLowLatencyMode 为当前串口调整适配器的批量延迟 (例如 FTDI 的 16ms 延迟定时器)
+// 返回实际生效的调整；平台或驱动不支持时 Supported 为 false 并附带说明
+func (a *App) SetLowLatencyMode(enabled bool) (serialport.LatencyResult, error) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	if !a.isConnected || (a.connType != TypeSerial && a.connType != TypeSlcan) {
+		return serialport.LatencyResult{}, fmt.Errorf("no serial port open")
+	}
+	return serialport.SetLowLatency(a.portName, enabled)
+}
+
+// CanFrameEvent can-frame 事件的数据
+type CanFrameEvent struct {
+	slcan.Frame
+	Time int64 `json:"time"` // 主机接收时间 (Unix 毫秒)
+}
+
+// OpenSlcan 打开 SLCAN 适配器并以指定速率代码 (0-8 对应 10k-1M) 打开 CAN 通道
+func (a *App) OpenSlcan(portName string, bitrateCode int) string {
 	a.mutex.Lock()
 	defer a.mutex.Unlock()
 
@@ -250,21 +1196,155 @@ func (a *App) OpenTcpClient(ip string, port string) string {
 		return "Already connected"
 	}
 
-	address := net.JoinHostPort(ip, port)
-	conn, err := net.DialTimeout("tcp", address, 3*time.Second)
+	setup := slcan.SetupCommands
+	if a.readOnly {
+		// 只读模式下以只听模式打开，适配器不会在总线上应答 ACK
+		setup = slcan.ListenOnlyCommands
+	}
+	cmds, err := setup(bitrateCode)
 	if err != nil {
-		return fmt.Sprintf("Connect error: %v", err)
+		return fmt.Sprintf("Error: %v", err)
 	}
 
-	a.netConn = conn
-	a.connType = TypeTcpClient
-	a.startReadLoop(conn)
+	lock, err := a.claimPort(portName)
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	}
+	opened := false
+	defer func() {
+		if !opened {
+			lock.Release()
+		}
+	}()
+
+	// USB CDC 适配器忽略波特率，这里使用常见的 115200 8N1
+	ctx, end := a.openGuard.Begin()
+	defer end()
+	port, err := openSerialPort(ctx, a.openTimeout, portName, &serial.Mode{BaudRate: 115200, DataBits: 8})
+	if err != nil {
+		return "Error: " + serialOpenError(portName, err).Error()
+	}
 
+	for _, cmd := range cmds {
+		if _, err := port.Write([]byte(cmd)); err != nil {
+			port.Close()
+			return fmt.Sprintf("SLCAN setup error: %v", err)
+		}
+	}
+
+	opened = true
+	a.serialPort = port
+	a.portLock = lock
+	a.portName = portName
+	a.connType = TypeSlcan
+	a.connSpec = connspec.Spec{Kind: connspec.Slcan, Port: portName, Bitrate: bitrateCode}.String()
+	a.adoptDevice(portName, portFingerprint(portName))
+	a.markConnected()
+	go a.slcanReadLoop(port)
+
+	a.reopen = func() string { return a.OpenSlcan(portName, bitrateCode) }
 	return "Success"
 }
 
-// OpenTcpServer 开启 TCP 服务端
-func (a *App) OpenTcpServer(port string) string {
+// slcanReadLoop 读取 SLCAN 适配器数据，原始数据照常发送到监视器，解析出的帧额外发送 can-frame 事件
+func (a *App) slcanReadLoop(port serial.Port) {
+	var splitter slcan.LineSplitter
+	framer := stream.New(stream.Lines(splitter.Feed))
+	stop := a.readStopChan
+	q := a.newRxQueue(stop, func(data []byte, _ interface{}) {
+		a.emitData(data)
+
+		now := time.Now().UnixMilli()
+		for _, chunk := range framer.Process(data) {
+			line := string(chunk)
+			frame, err := slcan.Parse(line)
+			if err == nil {
+				a.emitConn("can-frame", CanFrameEvent{Frame: frame, Time: now})
+				continue
+			}
+			if line == string(slcan.BEL) {
+				a.emit("sys-msg", "[SLCAN] 适配器返回错误 (BEL)")
+			} else if err != slcan.ErrNotFrame {
+				a.emit("sys-msg", fmt.Sprintf("[SLCAN] 无法解析: %v", err))
+			}
+		}
+	})
+	defer q.stop()
+
+	buff := make([]byte, 4096)
+	for {
+		select {
+		case <-stop:
+			return
+		default:
+			n, err := port.Read(buff)
+			if err != nil {
+				ev, expected := serialport.ReadError(err)
+				if a.isConnected && !expected {
+					q.flush()
+					a.emitConnError(err.Error(), ev)
+					a.Close()
+				}
+				return
+			}
+			if n == 0 {
+				continue
+			}
+
+			dataToSend := make([]byte, n)
+			copy(dataToSend, buff[:n])
+			q.Push(dataToSend, nil)
+		}
+	}
+}
+
+// SendCanFrame 通过 SLCAN 适配器发送一帧 CAN 数据，dataHex 为十六进制字符串 (可含空格)
+func (a *App) SendCanFrame(id uint32, dataHex string, extended bool) string {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	if !a.isConnected || a.connType != TypeSlcan || a.serialPort == nil {
+		return "Error: SLCAN not connected"
+	}
+
+	data, err := input.ParseHex(dataHex)
+	if err != nil {
+		return fmt.Sprintf("Error: invalid hex data: %v", err)
+	}
+
+	cmd, err := slcan.Encode(id, data, extended, false)
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	}
+
+	// 与其他发送路径相同：只读检查、写入超时、带宽限制、发送计数与录制
+	return a.sendLocked([]byte(cmd))
+}
+
+// jlinkPresetOverrides 返回用户在设置中覆盖的芯片预设
+func (a *App) jlinkPresetOverrides() map[string]jlink.Preset {
+	saved := a.settings.Get().JLinkPresets
+	if len(saved) == 0 {
+		return nil
+	}
+	out := make(map[string]jlink.Preset, len(saved))
+	for chip, p := range saved {
+		out[chip] = jlink.Preset{
+			Speed:          p.Speed,
+			Interface:      p.Interface,
+			RTTSearchStart: p.RTTSearchStart,
+			RTTSearchSize:  p.RTTSearchSize,
+		}
+	}
+	return out
+}
+
+// OpenJLink 连接 RTT
+// resetStrategy 可选 "normal"、"under-reset"、"attach-no-reset"，为空时使用该芯片上次成功连接时的策略
+// iface 为空或 speed < 0 时使用芯片预设，speed 为 0 表示自动速度
+// mode 为 "rtt" (默认) 或 "swo"；SWO 模式接收 ITM 刺激端口 0 的输出，需要指定目标内核时钟 cpuFreqHz，
+// swoFreqHz 为 0 时使用 jlink.DefaultSWOFreqHz，RTT 模式下这两个参数被忽略
+func (a *App) OpenJLink(chip string, speed int, iface string, resetStrategy string, mode string, cpuFreqHz int, swoFreqHz int) string {
 	a.mutex.Lock()
 	defer a.mutex.Unlock()
 
@@ -272,260 +1352,6010 @@ func (a *App) OpenTcpServer(port string) string {
 		return "Already connected"
 	}
 
-	listener, err := net.Listen("tcp", ":"+port)
+	if resetStrategy == "" {
+		resetStrategy = a.settings.Get().JLinkResetStrategies[chip]
+	}
+	strategy, err := jlink.ParseResetStrategy(resetStrategy)
 	if err != nil {
-		return fmt.Sprintf("Listen error: %v", err)
+		return err.Error()
+	}
+	jlMode, err := jlink.ParseMode(mode)
+	if err != nil {
+		return err.Error()
+	}
+	if jlMode == jlink.ModeSWO && (cpuFreqHz <= 0 || swoFreqHz < 0) {
+		return "SWO 模式需要指定有效的内核时钟频率 (cpuFreqHz) 与 SWO 频率 (swoFreqHz)"
 	}
 
-	a.netListener = listener
-	a.connType = TypeTcpServer
-	a.isConnected = true
-	a.readStopChan = make(chan struct{})
+	// 已知芯片使用预设补全未指定的参数 (iface 为空、speed < 0)
+	opts := jlink.ConnectOptions{ResetStrategy: strategy, Mode: jlMode}
+	if jlMode == jlink.ModeSWO {
+		opts.CPUFreqHz = uint32(cpuFreqHz)
+		opts.SWOFreqHz = uint32(swoFreqHz)
+	}
+	if preset, ok := jlink.LookupPreset(chip, a.jlinkPresetOverrides()); ok {
+		if iface == "" {
+			iface = preset.Interface
+		}
+		if speed < 0 {
+			speed = preset.Speed
+		}
+		opts.RTTSearchStart = preset.RTTSearchStart
+		opts.RTTSearchSize = preset.RTTSearchSize
+		a.emit("sys-msg", fmt.Sprintf("[RTT] 使用芯片预设: %s", chip))
+	}
+	if iface == "" {
+		iface = jlink.InterfaceSWD
+	}
+	if speed < 0 {
+		speed = jlink.SpeedAuto
+	}
+	if iface, err = jlink.ParseInterface(iface); err != nil {
+		return err.Error()
+	}
+	if speed, err = jlink.NormalizeSpeed(speed); err != nil {
+		return err.Error()
+	}
 
-	go func() {
-		for {
-			select {
-			case <-a.readStopChan:
-				return
-			default:
-				conn, err := listener.Accept()
-				if err != nil {
-					return
-				}
+	// 驱动日志通过 jlink-log 事件发送，serial-data 只包含从目标读取的字节；
+	// 合并模式下日志额外以 jlink-log-text 事件发送到连接的事件通道，由前端显示在接收区，
+	// 不占用 seq，也不会进入历史与捕获文件
+	sink := jlink.NewLogSink(func(e jlink.LogEntry) {
+		a.emit("jlink-log", e)
+		runtime.LogInfo(a.ctx, e.Message)
+	}, func(text []byte) {
+		a.emitConn("jlink-log-text", string(text))
+	})
+	sink.SetMerge(a.settings.Get().JLinkLogMerge)
+	a.jlinkLogSink = sink
 
-				a.mutex.Lock()
-				if a.netConn != nil {
-					a.netConn.Close()
-				}
-				a.netConn = conn
-				a.mutex.Unlock()
+	// 1. 加载驱动
+	jl, err := jlink.NewJLinkWrapper(sink.Callback())
+	if err != nil {
+		return err.Error()
+	}
+	// 在连接之前开启 DLL 日志，这样连接失败的原因也能被记录下来
+	a.applyJLinkLogLocked(jl)
 
-				runtime.EventsEmit(a.ctx, "sys-msg", fmt.Sprintf("Client connected: %s", conn.RemoteAddr().String()))
-				go a.handleTcpConnection(conn)
+	// 2. 校验芯片名称（设备数据库不可用时跳过）
+	if devices := jl.Devices(); len(devices) > 0 {
+		if _, ok := jlink.FindDevice(devices, chip); !ok {
+			jl.Close()
+			a.closeJLinkLogLocked()
+			msg := fmt.Sprintf("未知的芯片型号 %q", chip)
+			if suggestions := jlink.SuggestDevices(devices, chip, 5); len(suggestions) > 0 {
+				msg += fmt.Sprintf("，您是否想要: %s", strings.Join(suggestions, ", "))
 			}
+			return msg
 		}
-	}()
+	}
+
+	// 3. 连接芯片
+	a.emit("sys-msg", fmt.Sprintf("[RTT] 复位策略: %s, 接口: %s, 速度: %s kHz, 模式: %s", strategy, iface, jlink.SpeedString(speed), jlMode))
+	// 连接 (包括等待芯片稳定与软件 RTT 重试) 可以被超时与 CancelOpen 中断；
+	// 中断后才完成的连接在后台关闭
+	ctx, end := a.openGuard.Begin()
+	defer end()
+	_, err = openguard.Run(ctx, a.openTimeout, "connect to "+chip, func(ctx context.Context) (*jlink.JLinkWrapper, error) {
+		if err := jl.ConnectContext(ctx, chip, speed, iface, opts); err != nil {
+			// 连接失败需要释放资源
+			jl.Close()
+			return nil, err
+		}
+		return jl, nil
+	}, (*jlink.JLinkWrapper).Close)
+	if err != nil {
+		a.closeJLinkLogLocked()
+		return err.Error()
+	}
+
+	// 记住该芯片可用的复位策略，下次无需重新选择
+	if err := a.settings.Update(func(s *settings.Settings) {
+		if s.JLinkResetStrategies == nil {
+			s.JLinkResetStrategies = make(map[string]string)
+		}
+		s.JLinkResetStrategies[chip] = string(strategy)
+	}); err != nil {
+		a.emit("sys-msg", fmt.Sprintf("[RTT] 保存复位策略失败: %v", err))
+	}
+
+	a.jlinkConn = jl
+	a.connType = TypeJLink
+	a.connSpec = connspec.Spec{Kind: connspec.JLink, Chip: chip, Speed: speed, Interface: iface}.String()
+	if jlMode == jlink.ModeSWO {
+		a.connSpec += fmt.Sprintf(" (swo, cpu %d Hz, swo %d Hz)", cpuFreqHz, swoFreqHz)
+	}
+	a.rttStatus = JLinkStatus{RTTStats: jl.Stats()}
+	a.markConnected()
+
+	// 4. 启动 RTT 专用读取循环 (因为它的 API 不是 io.Reader 风格，而是轮询)
+	go a.jlinkReadLoop()
+
+	a.reopen = func() string { return a.OpenJLink(chip, speed, iface, resetStrategy, mode, cpuFreqHz, swoFreqHz) }
+	return "Success"
+}
+
+// SetJLinkLogLevel 设置 J-Link DLL 日志的转发级别："off" (默认)、"errors"、"info" (错误和警告) 或 "debug" (完整日志)
+// DLL 日志以 "[J-Link]" 前缀显示在接收区，每秒最多 jlink.DLLLogMaxPerSec 条；
+// toFile 为 true 时同时完整追加到配置目录下的 logs/jlink.log (不限流，诊断包会一并收集)
+// 设置会被保存，当前已连接 J-Link 时立即生效；旧版 DLL 缺少的日志函数会被跳过
+func (a *App) SetJLinkLogLevel(level string, toFile bool) string {
+	parsed, err := jlink.ParseLogLevel(level)
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	}
+	if err := a.settings.Update(func(s *settings.Settings) {
+		s.JLinkLogLevel = string(parsed)
+		s.JLinkLogToFile = toFile
+	}); err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	}
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	if parsed == jlink.LogOff || !toFile {
+		a.closeJLinkLogLocked()
+	}
+	if a.isConnected && a.connType == TypeJLink && a.jlinkConn != nil {
+		a.applyJLinkLogLocked(a.jlinkConn)
+	}
+	return "Success"
+}
+
+// SetJLinkLogMerge 兼容旧版行为：开启后驱动日志除 jlink-log 事件外也以 jlink-log-text 事件 (文本，
+// 按连接的事件通道发送) 显示在接收区。合并的日志只用于显示，没有 seq，不会写入接收历史、录制或捕获文件；
+// 设置会被保存并对当前连接立即生效
+func (a *App) SetJLinkLogMerge(enabled bool) string {
+	if err := a.settings.Update(func(s *settings.Settings) {
+		s.JLinkLogMerge = enabled
+	}); err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	}
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	if a.jlinkLogSink != nil {
+		a.jlinkLogSink.SetMerge(enabled)
+	}
+	return "Success"
+}
+
+// applyJLinkLogLocked 按保存的设置为 jl 开启 DLL 日志，失败只提示不影响连接；调用方必须持有 a.mutex
+func (a *App) applyJLinkLogLocked(jl *jlink.JLinkWrapper) {
+	s := a.settings.Get()
+	level, err := jlink.ParseLogLevel(s.JLinkLogLevel)
+	if err != nil {
+		level = jlink.LogOff
+	}
+
+	var file io.Writer
+	if level != jlink.LogOff && s.JLinkLogToFile {
+		if a.jlinkLogFile == nil {
+			f, err := openJLinkLog()
+			if err != nil {
+				a.emit("sys-msg", fmt.Sprintf("[RTT] 无法打开 J-Link 日志文件: %v", err))
+			}
+			a.jlinkLogFile = f
+		}
+		if a.jlinkLogFile != nil {
+			file = a.jlinkLogFile
+		}
+	}
+	if err := jl.SetLogLevel(level, file); err != nil {
+		a.emit("sys-msg", fmt.Sprintf("[RTT] 无法捕获 J-Link DLL 日志: %v", err))
+	}
+}
+
+// closeJLinkLogLocked 关闭 J-Link 日志文件；调用方必须持有 a.mutex
+func (a *App) closeJLinkLogLocked() {
+	if a.jlinkLogFile != nil {
+		a.jlinkLogFile.Close()
+		a.jlinkLogFile = nil
+	}
+}
+
+// openJLinkLog 以追加方式打开配置目录下的 logs/jlink.log
+func openJLinkLog() (*os.File, error) {
+	configDir, err := settings.ConfigDir()
+	if err != nil {
+		return nil, err
+	}
+	dir := filepath.Join(configDir, "logs")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return os.OpenFile(filepath.Join(dir, "jlink.log"), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+}
+
+// GetSupportedChips 从 J-Link 设备数据库中查找名称包含 filter 的芯片，最多返回 limit 个
+// 设备列表在首次成功加载后缓存，之后的查询无需再次访问 DLL
+func (a *App) GetSupportedChips(filter string, limit int) ([]jlink.DeviceInfo, error) {
+	a.mutex.Lock()
+	jl := a.jlinkConn
+	a.mutex.Unlock()
+
+	if jl == nil {
+		// 未连接时临时加载驱动读取设备列表
+		tmp, err := jlink.NewJLinkWrapper(nil)
+		if err != nil {
+			return nil, err
+		}
+		defer tmp.Close()
+		jl = tmp
+	}
+
+	devices := jl.Devices()
+	if len(devices) == 0 {
+		return nil, fmt.Errorf("J-Link 设备数据库不可用")
+	}
+	return jlink.FilterDevices(devices, filter, limit), nil
+}
+
+// JLinkWatchInfo 内存监视项信息
+type JLinkWatchInfo struct {
+	ID         string `json:"id"`
+	Addr       string `json:"addr"`
+	Size       int    `json:"size"`
+	IntervalMs int    `json:"intervalMs"`
+	Format     string `json:"format"`
+}
+
+// JLinkWatchAdd 添加目标内存监视，按 intervalMs 轮询 addr 处的 size 字节
+// 值变化时发送 "jlink-watch" 事件，format 可选 u8、u16、u32、i32、float、hex
+// 相同 id 的监视项会被替换
+func (a *App) JLinkWatchAdd(id string, addr string, size int, intervalMs int, format string) string {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	if !a.isConnected || a.connType != TypeJLink || a.jlinkConn == nil {
+		return "Error: J-Link not connected"
+	}
+	address, err := jlink.ParseAddress(addr)
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	}
+	w := jlink.Watch{
+		ID:       id,
+		Addr:     address,
+		Size:     size,
+		Interval: time.Duration(intervalMs) * time.Millisecond,
+		Format:   jlink.WatchFormat(format),
+	}
+	if err := a.jlinkConn.AddWatch(w, func(e jlink.WatchEvent) {
+		a.emitConn("jlink-watch", e)
+	}); err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	}
+	return "Success"
+}
+
+// JLinkWatchRemove 移除内存监视
+func (a *App) JLinkWatchRemove(id string) string {
+	a.mutex.Lock()
+	jl := a.jlinkConn
+	a.mutex.Unlock()
+
+	if jl == nil || !jl.RemoveWatch(id) {
+		return fmt.Sprintf("Error: watch %q not found", id)
+	}
+	return "Success"
+}
+
+// JLinkWatchList 返回当前的内存监视项
+func (a *App) JLinkWatchList() []JLinkWatchInfo {
+	a.mutex.Lock()
+	jl := a.jlinkConn
+	a.mutex.Unlock()
+
+	list := []JLinkWatchInfo{}
+	if jl == nil {
+		return list
+	}
+	for _, w := range jl.Watches() {
+		list = append(list, JLinkWatchInfo{
+			ID:         w.ID,
+			Addr:       fmt.Sprintf("0x%08X", w.Addr),
+			Size:       w.Size,
+			IntervalMs: int(w.Interval / time.Millisecond),
+			Format:     string(w.Format),
+		})
+	}
+	return list
+}
+
+// JLinkReset 复位目标芯片并恢复运行，RTT 会话保持连接
+func (a *App) JLinkReset() string {
+	a.mutex.Lock()
+	jl := a.jlinkConn
+	a.mutex.Unlock()
+
+	if jl == nil {
+		return "Error: J-Link not connected"
+	}
+	if err := jl.Reset(); err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	}
+	a.emit("sys-msg", "[RTT] 目标已复位")
+	return "Success"
+}
+
+// jlinkReadLoop 专用的 RTT 轮询循环
+func (a *App) jlinkReadLoop() {
+	ticker := time.NewTicker(10 * time.Millisecond) // 10ms 轮询一次
+	defer ticker.Stop()
+
+	consecutiveErrors := 0
+	// 连续错误次数阈值：允许少量偶发错误，避免瞬时故障导致断连
+	// 但在持续错误时及时断开连接，防止无效轮询占用资源
+	const maxConsecutiveErrors = 10
+
+	// 速率统计：每秒汇总一次，同时检测缓冲区是否持续接近溢出
+	var windowBytes, totalBytes uint64
+	windowStart := time.Now()
+	saturated := false
+	var drops int64 // 已提示过的丢包次数 (SWO 溢出包)
+
+	stop := a.readStopChan
+	q := a.newRxQueue(stop, func(data []byte, _ interface{}) {
+		a.emitData(data)
+	})
+	defer q.stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			// 检查连接是否还在 (需要加锁读取 jlinkConn，或者假设 stopChan 会处理)
+			// 注意：这里为了性能，简单处理，如果 closed 会置为 nil，所以要小心
+			a.mutex.Lock()
+			jl := a.jlinkConn
+			a.mutex.Unlock()
+
+			if jl == nil {
+				return
+			}
+
+			data, err := jl.ReadRTT()
+			if errors.Is(err, jlink.ErrClosed) {
+				// 连接正在关闭
+				return
+			}
+			if err != nil {
+				consecutiveErrors++
+
+				// 检测是否是偏移量错误（STM32 复位导致）
+				if consecutiveErrors == 1 && errors.Is(err, jlink.ErrOffsetOutOfBounds) {
+					a.emit("sys-msg", "[RTT] 检测到目标设备可能已复位，尝试重新连接...")
+					// 尝试重新初始化 RTT
+					if reinitErr := jl.ReinitSoftRTT(); reinitErr == nil {
+						a.emit("sys-msg", "[RTT] RTT 重新初始化成功")
+						consecutiveErrors = 0
+						continue
+					} else {
+						a.emit("sys-msg", fmt.Sprintf("[RTT] RTT 重新初始化失败: %v", reinitErr))
+					}
+				}
+
+				// 增加容错机制：只有连续多次错误才关闭连接
+				// 这样可以避免偶发错误导致断连，同时确保持续错误时能及时断开
+				if consecutiveErrors >= maxConsecutiveErrors {
+					q.flush()
+					a.emitConnError(fmt.Sprintf("[RTT] 错误 (连续 %d 次): %v", consecutiveErrors, err), jlink.TranslateError(err))
+					a.Close()
+					return
+				}
+				// 首次或少量错误时，仅记录日志，继续尝试
+				if consecutiveErrors == 1 {
+					a.emit("sys-msg", fmt.Sprintf("[RTT] 读取警告: %v", err))
+				}
+				continue
+			}
+
+			// 成功读取，重置错误计数
+			consecutiveErrors = 0
+
+			stats := jl.Stats()
+			if stats.Saturated && !saturated {
+				a.emit("sys-msg", fmt.Sprintf("[RTT] 警告：目标端上行缓冲区占用率持续超过 %.0f%% (大小 %d 字节，溢出模式 %s)，数据可能丢失。请提高轮询频率或增大固件中的 RTT 缓冲区",
+					jlink.OccupancyWarnThreshold*100, stats.BufferSize, stats.OverflowMode))
+			}
+			saturated = stats.Saturated
+			if stats.Drops > drops {
+				a.emit("sys-msg", fmt.Sprintf("[RTT] 警告：SWO 数据溢出，目标端已丢弃数据 (累计 %d 次)。请降低输出量或提高 SWO 频率", stats.Drops))
+				drops = stats.Drops
+			}
+
+			if len(data) > 0 {
+				windowBytes += uint64(len(data))
+				totalBytes += uint64(len(data))
+				q.Push(data, nil)
+			}
+
+			if elapsed := time.Since(windowStart); elapsed >= time.Second {
+				a.mutex.Lock()
+				a.rttStatus = JLinkStatus{
+					RTTStats:    stats,
+					BytesPerSec: float64(windowBytes) / elapsed.Seconds(),
+					TotalBytes:  totalBytes,
+				}
+				a.mutex.Unlock()
+				windowBytes = 0
+				windowStart = time.Now()
+			}
+		}
+	}
+}
+
+// Reconnect 以上一次成功打开时的参数重新打开连接，用于 app-error 的 "reconnect" 建议操作
+// 当前已连接时先关闭；还没有打开过连接时返回错误
+func (a *App) Reconnect() string {
+	a.mutex.Lock()
+	reopen := a.reopen
+	connected := a.isConnected
+	a.mutex.Unlock()
+
+	if reopen == nil {
+		return "Error: no previous connection to reopen"
+	}
+	if connected {
+		a.Close()
+	}
+	return reopen()
+}
+
+// OpenFromString 按连接字符串打开连接 (语法见 pkg/connspec)，例如
+// "serial:COM7@115200,8N1"、"tcp://192.168.1.50:4001"、"jlink:STM32F103C8@4000/SWD"
+// 返回解析后的参数，其中 Inferred/Defaulted 列出推断和使用默认值的字段
+func (a *App) OpenFromString(spec string) (connspec.Spec, error) {
+	cs, err := connspec.Parse(spec)
+	if err != nil {
+		return connspec.Spec{}, err
+	}
+	if cs.Inferred == nil {
+		cs.Inferred = []string{}
+	}
+	if cs.Defaulted == nil {
+		cs.Defaulted = []string{}
+	}
+
+	var result string
+	switch cs.Kind {
+	case connspec.Serial:
+		result = a.OpenSerial(cs.Port, cs.Baud, cs.DataBits, cs.StopBits, cs.Parity, "", "", 0)
+	case connspec.Slcan:
+		result = a.OpenSlcan(cs.Port, cs.Bitrate)
+	case connspec.JLink:
+		result = a.OpenJLink(cs.Chip, cs.Speed, cs.Interface, "", "", 0, 0)
+	case connspec.TcpClient:
+		result = a.OpenTcpClient(cs.Host, cs.NetPort)
+	case connspec.TcpServer:
+		result = a.OpenTcpServer(cs.NetPort)
+	case connspec.Udp:
+		result = a.OpenUdp(cs.LocalPort, cs.Host, cs.NetPort)
+	case connspec.Virtual:
+		result = "Success"
+		if _, err := a.OpenVirtualPair(0, 0, 0); err != nil {
+			result = err.Error()
+		}
+	}
+	if !openSucceeded(result) {
+		return *cs, errors.New(result)
+	}
+	return *cs, nil
+}
+
+// ListProfiles 返回保存的连接配置
+func (a *App) ListProfiles() []settings.Profile {
+	list := a.settings.Get().Profiles
+	if list == nil {
+		return []settings.Profile{}
+	}
+	return list
+}
+
+// SaveProfile 新增或按名称覆盖一条连接配置，Spec 必须是有效的连接字符串
+// 覆盖示例配置后清除其示例标记，之后重新运行引导不会再改动它
+func (a *App) SaveProfile(p settings.Profile) string {
+	p.Name = strings.TrimSpace(p.Name)
+	if p.Name == "" || len([]rune(p.Name)) > commands.MaxNameLen {
+		return fmt.Sprintf("Error: profile name must be 1 to %d characters", commands.MaxNameLen)
+	}
+	if _, err := connspec.Parse(p.Spec); err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	}
+	// 引用的快捷指令在保存时检查，不留到连接时才发现
+	current := a.settings.Get()
+	if err := p.ValidateActions(&current); err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	}
+	p.Sample = false
+	if err := a.settings.Update(func(s *settings.Settings) {
+		for i := range s.Profiles {
+			if s.Profiles[i].Name == p.Name {
+				s.Profiles[i] = p
+				return
+			}
+		}
+		s.Profiles = append(s.Profiles, p)
+	}); err != nil {
+		return fmt.Sprintf("Error saving settings: %v", err)
+	}
+	return "Success"
+}
+
+// DeleteProfile 删除连接配置
+func (a *App) DeleteProfile(name string) string {
+	found := false
+	if err := a.settings.Update(func(s *settings.Settings) {
+		for i, p := range s.Profiles {
+			if p.Name == name {
+				s.Profiles = append(s.Profiles[:i:i], s.Profiles[i+1:]...)
+				found = true
+				return
+			}
+		}
+	}); err != nil {
+		return fmt.Sprintf("Error saving settings: %v", err)
+	}
+	if !found {
+		return fmt.Sprintf("Error: profile %q not found", name)
+	}
+	return "Success"
+}
+
+// OpenProfile 按保存的连接配置打开连接，同 OpenFromString
+// 打开成功后按顺序执行配置的 OnConnect 动作，每个动作以 profile-action 事件报告并作为标注记录；
+// 遇到第一个失败的动作时停止执行其余动作，但不关闭连接，返回值仍表示打开成功
+func (a *App) OpenProfile(name string) (connspec.Spec, error) {
+	for _, p := range a.settings.Get().Profiles {
+		if p.Name == name {
+			specStr, err := a.profileSpec(p)
+			if err != nil {
+				return connspec.Spec{}, err
+			}
+			spec, err := a.OpenFromString(specStr)
+			if err == nil && len(p.OnConnect) > 0 {
+				a.runProfileActions(p)
+			}
+			return spec, err
+		}
+	}
+	return connspec.Spec{}, fmt.Errorf("profile %q not found", name)
+}
+
+// profileSpec 返回打开连接配置使用的连接字符串：绑定了设备时端口名替换为设备当前的端口，
+// 设备未插入或多个设备共用指纹时返回错误 (后者的错误信息列出候选端口)
+func (a *App) profileSpec(p settings.Profile) (string, error) {
+	if p.Device == "" {
+		return p.Spec, nil
+	}
+	cs, err := connspec.Parse(p.Spec)
+	if err != nil {
+		return "", err
+	}
+	if cs.Kind != connspec.Serial && cs.Kind != connspec.Slcan {
+		return p.Spec, nil
+	}
+	res, err := a.ResolveDevice(p.Device)
+	if errors.Is(err, devid.ErrNotPresent) {
+		return "", apperr.Wrap(apperr.PortNotFound, err, "profile %q", p.Name)
+	}
+	if err != nil {
+		return "", err
+	}
+	if res.Port == "" {
+		return "", apperr.New(apperr.DeviceAmbiguous, "%d devices match %s for profile %q, choose a port: %s",
+			len(res.Candidates), p.Device, p.Name, strings.Join(res.Candidates, ", "))
+	}
+	cs.Port = res.Port
+	return cs.String(), nil
+}
+
+// ProfileActionEvent profile-action 事件的数据
+type ProfileActionEvent struct {
+	Profile     string `json:"profile"`
+	Index       int    `json:"index"` // 从 1 开始
+	Total       int    `json:"total"`
+	Type        string `json:"type"`
+	Description string `json:"description"`
+	Status      string `json:"status"`           // "ok"、"failed" 或 "skipped" (之前的动作失败)
+	Detail      string `json:"detail,omitempty"` // 结果 (例如抓包文件路径) 或错误信息
+}
+
+// runProfileActions 依次执行连接配置的 OnConnect 动作
+func (a *App) runProfileActions(p settings.Profile) {
+	total := len(p.OnConnect)
+	for i, act := range p.OnConnect {
+		ev := ProfileActionEvent{Profile: p.Name, Index: i + 1, Total: total, Type: act.Type, Description: act.Describe()}
+		detail, err := a.runProfileAction(act)
+		if err != nil {
+			ev.Status, ev.Detail = "failed", err.Error()
+			a.emit("profile-action", ev)
+			a.annotate(time.Now(), fmt.Sprintf("Profile %q: %s failed: %v", p.Name, ev.Description, err))
+			a.emitError(fmt.Sprintf("Profile %q action %d (%s) failed: %v", p.Name, i+1, act.Type, err), apperr.NewEvent(apperr.ProfileActionFailed, err.Error()))
+			for j := i + 1; j < total; j++ {
+				rest := p.OnConnect[j]
+				a.emit("profile-action", ProfileActionEvent{Profile: p.Name, Index: j + 1, Total: total, Type: rest.Type, Description: rest.Describe(), Status: "skipped"})
+			}
+			return
+		}
+		ev.Status, ev.Detail = "ok", detail
+		a.emit("profile-action", ev)
+		a.annotate(time.Now(), fmt.Sprintf("Profile %q: %s", p.Name, ev.Description))
+	}
+}
+
+// runProfileAction 执行一个动作，返回结果描述
+func (a *App) runProfileAction(act settings.ProfileAction) (string, error) {
+	result := ""
+	switch act.Type {
+	case settings.ActionPcap:
+		return a.StartPcapCapture(act.Path)
+	case settings.ActionPlotCsv:
+		return a.StartPlotCsv(act.Path, act.Columns)
+	case settings.ActionFrameDecoder:
+		if act.Frame == nil {
+			return "", fmt.Errorf("frame format is required")
+		}
+		if act.Schema != nil {
+			// 字段表随帧格式一起替换，旧字段表不必适合新格式
+			a.SetFrameSchema("")
+		}
+		result = a.SetFrameDecoder(*act.Frame, false)
+		if result == "Success" && act.Schema != nil {
+			result = a.applyFrameSchema(act.Schema)
+		}
+	case settings.ActionRxEncoding:
+		result = a.SetRxEncoding(act.Value)
+	case settings.ActionCommand:
+		if result = a.SendCommand(act.Name); strings.HasPrefix(result, "Sent") {
+			return result, nil
+		}
+		return "", fmt.Errorf("%s", result)
+	case settings.ActionPeriodic:
+		return "", a.startPeriodicCommand(act.Name, time.Duration(act.IntervalMs)*time.Millisecond)
+	default:
+		return "", fmt.Errorf("unknown action type %q", act.Type)
+	}
+	if result != "Success" {
+		return "", fmt.Errorf("%s", strings.TrimPrefix(result, "Error: "))
+	}
+	return "", nil
+}
+
+// startPeriodicCommand 每隔 interval 发送一次快捷指令，直到当前连接关闭；发送失败时停止并报告
+func (a *App) startPeriodicCommand(name string, interval time.Duration) error {
+	if interval < settings.MinPeriodicInterval {
+		return fmt.Errorf("interval must be at least %d ms", settings.MinPeriodicInterval.Milliseconds())
+	}
+	a.mutex.Lock()
+	stop := a.readStopChan
+	connected := a.isConnected
+	a.mutex.Unlock()
+	if !connected || stop == nil {
+		return fmt.Errorf("not connected")
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+			}
+			// 定时发送走批量优先级，手动按下的快捷指令可以插在两次发送之间
+			var result string
+			if payload, err := a.commandPayload(name); err != nil {
+				result = fmt.Sprintf("Send error: %v", err)
+			} else {
+				result = a.sendBulk(payload, stop)
+			}
+			if !strings.HasPrefix(result, "Sent") {
+				select {
+				case <-stop:
+					// 连接已关闭，不是发送失败
+				default:
+					a.emitError(fmt.Sprintf("Periodic command %q stopped: %s", name, result), apperr.NewEvent(apperr.IOError, result))
+				}
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+// RegisterAction 新增或按 ID 覆盖一个用户动作并保存到设置，前端的快捷键等只需调用 InvokeAction(id)
+// actionType 为 "send"、"command"、"set-line"、"start-recording"、"stop-recording" 或 "open-profile"，
+// 参数在注册时检查 (包括引用的快捷指令与连接配置是否存在)，参数含义见 settings.UserAction
+func (a *App) RegisterAction(id string, actionType string, params settings.ActionParams) string {
+	act := settings.UserAction{ID: id, Type: actionType, Params: params}
+	current := a.settings.Get()
+	if err := act.Validate(&current); err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	}
+	full := false
+	if err := a.settings.Update(func(s *settings.Settings) {
+		for i := range s.UserActions {
+			if s.UserActions[i].ID == id {
+				s.UserActions[i] = act
+				return
+			}
+		}
+		if len(s.UserActions) >= settings.MaxUserActions {
+			full = true
+			return
+		}
+		s.UserActions = append(s.UserActions, act)
+	}); err != nil {
+		return fmt.Sprintf("Error saving settings: %v", err)
+	}
+	if full {
+		return fmt.Sprintf("Error: too many actions (max %d)", settings.MaxUserActions)
+	}
+	return "Success"
+}
+
+// DeleteAction 删除用户动作
+func (a *App) DeleteAction(id string) string {
+	found := false
+	if err := a.settings.Update(func(s *settings.Settings) {
+		for i, act := range s.UserActions {
+			if act.ID == id {
+				s.UserActions = append(s.UserActions[:i:i], s.UserActions[i+1:]...)
+				found = true
+				return
+			}
+		}
+	}); err != nil {
+		return fmt.Sprintf("Error saving settings: %v", err)
+	}
+	if !found {
+		return fmt.Sprintf("Error: action %q not found", id)
+	}
+	return "Success"
+}
+
+// ListActions 返回注册的用户动作
+func (a *App) ListActions() []settings.UserAction {
+	list := a.settings.Get().UserActions
+	if list == nil {
+		return []settings.UserAction{}
+	}
+	return list
+}
+
+// ActionAudit action-invoked 事件的数据，每次 InvokeAction 发送一次
+type ActionAudit struct {
+	ID          string `json:"id"`
+	Type        string `json:"type,omitempty"`
+	Description string `json:"description,omitempty"`
+	Time        int64  `json:"time"`             // Unix 毫秒
+	Status      string `json:"status"`           // "ok" 或 "failed"
+	Detail      string `json:"detail,omitempty"` // 结果 (例如录制文件路径) 或错误信息
+}
+
+// InvokeAction 执行注册的用户动作，返回 "Success" (可附带结果) 或错误；结果以 action-invoked 事件记录
+// 执行前按当前设置重新检查参数，注册后被删除的快捷指令或连接配置在这里报告
+func (a *App) InvokeAction(id string) string {
+	ev := ActionAudit{ID: id, Time: time.Now().UnixMilli()}
+	current := a.settings.Get()
+	var detail string
+	err := fmt.Errorf("action %q not found", id)
+	for _, act := range current.UserActions {
+		if act.ID != id {
+			continue
+		}
+		ev.Type, ev.Description = act.Type, act.Describe()
+		if err = act.Validate(&current); err == nil {
+			detail, err = a.runUserAction(act)
+		}
+		break
+	}
+	if err != nil {
+		ev.Status, ev.Detail = "failed", err.Error()
+		a.emit("action-invoked", ev)
+		return fmt.Sprintf("Error: %v", err)
+	}
+	ev.Status, ev.Detail = "ok", detail
+	a.emit("action-invoked", ev)
+	if detail != "" {
+		return "Success (" + detail + ")"
+	}
+	return "Success"
+}
+
+// runUserAction 执行一个已检查的用户动作，返回结果描述
+func (a *App) runUserAction(act settings.UserAction) (string, error) {
+	p := act.Params
+	result := ""
+	switch act.Type {
+	case settings.UserSend:
+		payload, err := input.Decode(p.Payload, p.Hex)
+		if err != nil {
+			return "", err
+		}
+		a.mutex.Lock()
+		result = a.checkLinkLocked(a.sendLocked(payload))
+		a.mutex.Unlock()
+	case settings.UserCommand:
+		result = a.SendCommand(p.Name)
+	case settings.UserSetLine:
+		result = a.SetSerialLine(p.Line, p.Value)
+	case settings.UserStartRecording:
+		return a.StartRecording(p.Path)
+	case settings.UserStopRecording:
+		result = a.StopRecording()
+	case settings.UserOpenProfile:
+		spec, err := a.OpenProfile(p.Name)
+		if err != nil {
+			return "", err
+		}
+		return spec.String(), nil
+	default:
+		return "", fmt.Errorf("unknown action type %q", act.Type)
+	}
+	switch {
+	case result == "Sent", result == "Success":
+		return "", nil
+	case strings.HasPrefix(result, "Sent ("), strings.HasPrefix(result, "Success ("):
+		return result[strings.Index(result, "(")+1 : len(result)-1], nil
+	}
+	return "", fmt.Errorf("%s", strings.TrimPrefix(result, "Error: "))
+}
+
+// OpenTcpClient 连接 TCP 服务端
+// 连接失败后的诊断探测 (见 SetTcpProbe) 在释放 a.mutex 后进行，同样受打开超时与 CancelOpen 限制
+func (a *App) OpenTcpClient(ip string, port string) string {
+	a.mutex.Lock()
+	ctx, end := a.openGuard.Begin()
+	defer end()
+	start := time.Now()
+	result, dialErr := a.openTcpClientLocked(ctx, ip, port)
+	probe := dialErr != nil && !a.skipTcpProbe
+	altPort, timeout := a.probeAltPort, a.openTimeout
+	a.mutex.Unlock()
+	if !probe {
+		return result
+	}
+
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, start.Add(timeout))
+		defer cancel()
+	}
+	findings := netprobe.DefaultProber().Run(ctx, ip, port, altPort, dialErr)
+	if diagnosis := netprobe.Diagnose(findings); diagnosis != "" {
+		return fmt.Sprintf("%s (%s)", result, diagnosis)
+	}
+	return result
+}
+
+// openTcpClientLocked 建立 TCP 连接，连接失败 (不包括超时与取消) 时同时返回连接错误以便诊断
+// 调用方必须持有 a.mutex
+func (a *App) openTcpClientLocked(ctx context.Context, ip string, port string) (string, error) {
+	if a.isConnected {
+		return "Already connected", nil
+	}
+
+	// 连接超时仍为 3 秒，打开超时另外限制域名解析等阶段的总时长
+	address := net.JoinHostPort(ip, port)
+	conn, err := openguard.Run(ctx, a.openTimeout, "connect to "+address, func(ctx context.Context) (net.Conn, error) {
+		d := net.Dialer{Timeout: 3 * time.Second}
+		return d.DialContext(ctx, "tcp", address)
+	}, func(c net.Conn) { c.Close() })
+	if err != nil {
+		if openguard.Aborted(err) {
+			return "Error: " + err.Error(), nil
+		}
+		return fmt.Sprintf("Connect error: %v", err), err
+	}
+
+	a.netConn = conn
+	a.connType = TypeTcpClient
+	a.connSpec = connspec.Spec{Kind: connspec.TcpClient, Host: ip, NetPort: port}.String()
+	a.startReadLoop(conn)
+
+	a.reopen = func() string { return a.OpenTcpClient(ip, port) }
+	return "Success", nil
+}
+
+// SetTcpProbe 设置 TCP Client 连接失败后的诊断探测 (默认开启)：失败后在约 2 秒内做 ping、
+// 备用端口 altPort (0 表示不探测，例如设备 Web 界面的 80) 与本地子网 ARP 检查，并在错误信息后附上诊断结论
+// ping 需要原始套接字权限，没有权限时自动跳过；连接被拒绝或域名无法解析时不做额外探测
+func (a *App) SetTcpProbe(enabled bool, altPort int) string {
+	if altPort < 0 || altPort > 65535 {
+		return fmt.Sprintf("Error: invalid port %d", altPort)
+	}
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.skipTcpProbe = !enabled
+	a.probeAltPort = altPort
+	return "Success"
+}
+
+// OpenTcpServer 开启 TCP 服务端
+func (a *App) OpenTcpServer(port string) string {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	if a.isConnected {
+		return "Already connected"
+	}
+
+	listener, err := net.Listen("tcp", ":"+port)
+	if err != nil {
+		return fmt.Sprintf("Listen error: %v", err)
+	}
+
+	clients := transport.NewClientSet()
+	a.netListener = listener
+	a.tcpClients = clients
+	a.connType = TypeTcpServer
+	a.connSpec = connspec.Spec{Kind: connspec.TcpServer, NetPort: port}.String()
+	a.markConnected()
+
+	go func() {
+		for {
+			select {
+			case <-a.readStopChan:
+				return
+			default:
+				conn, err := listener.Accept()
+				if err != nil {
+					return
+				}
+
+				client := clients.Add(conn)
+				a.emit("sys-msg", fmt.Sprintf("Client connected: %s", client.Addr))
+				go a.handleTcpConnection(clients, client)
+
+				a.mutex.Lock()
+				if a.isConnected {
+					a.scheduleInitPayload(client)
+				}
+				a.mutex.Unlock()
+			}
+		}
+	}()
+
+	a.reopen = func() string { return a.OpenTcpServer(port) }
+	return "Success"
+}
+
+// handleTcpConnection TCP Server 中单个客户端的读取循环，数据以客户端地址为来源发送
+func (a *App) handleTcpConnection(clients *transport.ClientSet, client *transport.Client) {
+	conn := client.Conn
+	q := a.newRxQueue(a.readStopChan, func(data []byte, _ interface{}) {
+		a.capturePacket(conn.LocalAddr(), conn.RemoteAddr(), false, data)
+		a.emitDataFrom(data, client.Addr)
+	})
+	defer q.stop()
+
+	buff := make([]byte, 4096)
+	for {
+		n, err := conn.Read(buff)
+		if err != nil {
+			conn.Close()
+			// 服务端关闭时 CloseAll 已移除全部客户端，不再提示
+			if clients.Remove(client) {
+				q.flush()
+				a.forgetClientRx(client.Addr)
+				a.emit("sys-msg", fmt.Sprintf("Client disconnected: %s", client.Addr))
+				a.onClientLost(clients, client, err)
+			}
+			return
+		}
+		if n > 0 {
+			dataToSend := make([]byte, n)
+			copy(dataToSend, buff[:n])
+			client.CountRx(n)
+			q.Push(dataToSend, nil)
+		}
+	}
+}
+
+// onClientLost 服务端的最后一个客户端断开后按 SetClientLostPolicy 的策略发送 client-lost 事件或关闭服务端
+// 仍有其他客户端接入、或 clients 已不属于当前连接时不做处理
+func (a *App) onClientLost(clients *transport.ClientSet, client *transport.Client, err error) {
+	if clients.Len() > 0 {
+		return
+	}
+	a.mutex.Lock()
+	policy := a.clientLost
+	current := a.isConnected && a.tcpClients == clients
+	a.mutex.Unlock()
+	if !current || policy == transport.ClientLostWait {
+		return
+	}
+
+	a.emitConn("client-lost", transport.NewClientLost(client, err, time.Now(), policy))
+	if policy == transport.ClientLostClose {
+		a.emit("sys-msg", "Last client disconnected, closing TCP server")
+		a.Close()
+	}
+}
+
+// SetClientLostPolicy 设置 TCP Server 最后一个客户端断开后的处理方式：
+// "wait" (默认) 继续监听；"notify" 继续监听并发送 client-lost 事件 (地址、原因与连接时长)；
+// "close" 发送 client-lost 事件后关闭服务端，便于外部脚本察觉。立即生效并保持到之后的连接
+func (a *App) SetClientLostPolicy(policy string) string {
+	p, err := transport.ParseClientLostPolicy(policy)
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	}
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.clientLost = p
+	return "Success"
+}
+
+// GetTcpClients 返回 TCP Server 当前接入的客户端及各自的收发字节数，其他连接类型返回空列表
+func (a *App) GetTcpClients() []transport.ClientInfo {
+	a.mutex.Lock()
+	clients := a.tcpClients
+	a.mutex.Unlock()
+
+	if clients == nil {
+		return []transport.ClientInfo{}
+	}
+	return clients.List()
+}
+
+// SetServerClientFilter 只发送来自 addr (GetTcpClients 返回的地址) 的数据事件，空字符串表示不过滤
+// 过滤只影响事件发送，历史缓冲区仍记录所有客户端的数据；重新连接时清除
+func (a *App) SetServerClientFilter(addr string) string {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	if !a.isConnected || a.connType != TypeTcpServer {
+		return "Error: client filter is only available in TCP server mode"
+	}
+
+	a.streamMutex.Lock()
+	a.clientFilter = addr
+	a.streamMutex.Unlock()
+	return "Success"
+}
+
+// OpenVirtualPair 创建一对进程内背靠背连接，用于在没有硬件时调试协议
+// A 端作为当前连接 (SendData 发送)，B 端通过 SendVirtualPeer 发送；
+// 连接对存在期间有两个通道，不再发送旧版事件名，前端需通过 SubscribeChannel 订阅两端
+// dropPercent/latencyMs/bytesPerSec 为有损模式参数，全部为 0 表示理想链路；Close 同时关闭两端
+func (a *App) OpenVirtualPair(dropPercent float64, latencyMs int, bytesPerSec int) (VirtualPair, error) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	if a.isConnected {
+		return VirtualPair{}, fmt.Errorf("already connected")
+	}
+
+	opts := vpipe.Options{
+		DropPercent: dropPercent,
+		Latency:     time.Duration(latencyMs) * time.Millisecond,
+		BytesPerSec: bytesPerSec,
+	}
+	if err := opts.Validate(); err != nil {
+		return VirtualPair{}, err
+	}
+
+	endA, endB := vpipe.Pair(opts)
+	a.netConn = endA
+	a.virtualPeer = endB
+	a.connType = TypeVirtual
+	a.connSpec = fmt.Sprintf("virtual-pair (drop %.1f%%, latency %d ms, %d B/s)", dropPercent, latencyMs, bytesPerSec)
+	a.startReadLoop(endA)
+
+	a.streamMutex.Lock()
+	pair := VirtualPair{A: a.channel, B: a.router.AddConnection()}
+	a.peerChannel = pair.B
+	a.streamMutex.Unlock()
+
+	go a.virtualPeerLoop(endB, pair.B)
+
+	a.emit("sys-msg", fmt.Sprintf("Virtual pair opened: A=%s, B=%s", pair.A, pair.B))
+	return pair, nil
+}
+
+// virtualPeerLoop 读取 B 端数据并发送到 B 通道，B 端关闭时关闭整个连接对
+func (a *App) virtualPeerLoop(peer net.Conn, channel string) {
+	buff := make([]byte, 4096)
+	for {
+		n, err := peer.Read(buff)
+		if err != nil {
+			if a.isConnected {
+				a.router.Emit(channel, "serial-error", err.Error())
+				a.router.Emit(channel, "app-error", transport.TranslateError(err))
+				a.Close()
+			}
+			return
+		}
+		if n > 0 {
+			data := make([]byte, n)
+			copy(data, buff[:n])
+			a.router.Emit(channel, "serial-data", data)
+			a.feedCompare(channel, time.Now(), data)
+		}
+	}
+}
+
+// SendVirtualPeer 从虚拟连接对的 B 端发送数据，A 端将收到这些数据
+func (a *App) SendVirtualPeer(data string) string {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	if !a.isConnected || a.connType != TypeVirtual || a.virtualPeer == nil {
+		return "Error: No virtual pair open"
+	}
+	if _, err := transport.Write(a.virtualPeer, []byte(data), a.writeTimeout); err != nil {
+		return fmt.Sprintf("Send error: %v", err)
+	}
+	return "Sent"
+}
+
+// OpenUdp 开启 UDP
+func (a *App) OpenUdp(localPort string, remoteIp string, remotePort string) string {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	if a.isConnected {
+		return "Already connected"
+	}
+
+	var rAddr *net.UDPAddr
+	var err error
+	if remoteIp != "" && remotePort != "" {
+		rAddr, err = net.ResolveUDPAddr("udp", net.JoinHostPort(remoteIp, remotePort))
+		if err != nil {
+			return fmt.Sprintf("Remote Addr error: %v", err)
+		}
+	}
+
+	sock, err := transport.OpenUDP(localPort, rAddr, a.udpConnected)
+	if err != nil {
+		return fmt.Sprintf("UDP error: %v", err)
+	}
+
+	a.udpConn = sock.Conns[0]
+	a.udpSocket = sock
+	a.udpPeer = transport.UDPPeer{Dialed: sock.Dialed}
+	if rAddr != nil {
+		a.udpPeer.Remote = rAddr
+	}
+	a.connType = TypeUdp
+	a.connSpec = connspec.Spec{Kind: connspec.Udp, Host: remoteIp, NetPort: remotePort, LocalPort: localPort}.String()
+	a.markConnected()
+
+	for _, conn := range sock.Conns {
+		go a.udpReadLoop(conn, len(sock.Conns) > 1)
+	}
+
+	a.reopen = func() string { return a.OpenUdp(localPort, remoteIp, remotePort) }
+	if len(sock.Failures) > 0 {
+		return fmt.Sprintf("Success (listening on %s; failed: %s)", udpPortList(sock.Conns), strings.Join(sock.Failures, "; "))
+	}
+	return "Success"
+}
+
+// udpReadLoop 读取一个 UDP 套接字，sweep 为 true 时 (绑定了多个端口) 数据事件附带本地端口
+func (a *App) udpReadLoop(conn net.PacketConn, sweep bool) {
+	stop := a.readStopChan
+	q := a.newRxQueue(stop, func(data []byte, meta interface{}) {
+		d := meta.(transport.Datagram)
+		a.capturePacket(conn.LocalAddr(), d.Source, false, data)
+		a.emitDatagram(data, d.Source.String(), d.LocalPort)
+	})
+	defer q.stop()
+
+	err := transport.ReadDatagrams(conn, stop, sweep, func(d transport.Datagram) {
+		// 对端跟踪：未指定远端时以第一个发送者作为回复地址 (connected 模式下远端固定)
+		a.mutex.Lock()
+		if a.udpPeer.Observe(conn, d.Source) {
+			a.emit("sys-msg", fmt.Sprintf("Remote set to: %s", d.Source.String()))
+		}
+		a.mutex.Unlock()
+
+		if len(d.Data) > 0 {
+			q.Push(d.Data, d)
+		}
+	})
+	if err != nil && a.isConnected {
+		q.flush()
+		a.emitConnError(err.Error(), transport.TranslateError(err))
+	}
+}
+
+// openSucceeded 打开连接的结果是否表示连接已建立 (包括 "Success (...)" 形式的部分成功)
+func openSucceeded(result string) bool {
+	return result == "Success" || strings.HasPrefix(result, "Success (")
+}
+
+// udpPortList 以逗号分隔列出套接字的本地端口
+func udpPortList(conns []net.PacketConn) string {
+	ports := make([]string, len(conns))
+	for i, c := range conns {
+		ports[i] = strconv.Itoa(transport.LocalPort(c))
+	}
+	return strings.Join(ports, ",")
+}
+
+// SetUdpOptions 设置 UDP 选项
+// showSource 为 true 时 serial-data 事件的元信息附带数据报来源地址，可随时切换；
+// connected 为 true 时使用 connected 套接字 (net.DialUDP)，只接收来自远端地址的数据报，
+// 需要重新打开 UDP 才能切换，连接期间修改会返回错误
+func (a *App) SetUdpOptions(showSource bool, connected bool) string {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	if a.isConnected && a.connType == TypeUdp && connected != a.udpPeer.Dialed {
+		return "Error: close and reopen the UDP socket to switch connected mode"
+	}
+	a.udpConnected = connected
+
+	a.streamMutex.Lock()
+	a.udpShowSource = showSource
+	a.streamMutex.Unlock()
+	return "Success"
+}
+
+// --- 通用方法 ---
+
+// markConnected 标记连接已建立，并为新连接重置序号与历史
+// 调用方必须持有 a.mutex
+func (a *App) markConnected() {
+	a.isConnected = true
+	a.readStopChan = make(chan struct{})
+	a.reopen = nil
+	a.linkWarned = false
+	a.remoteHalfClosed = false
+	a.txMonitor = nil
+	a.serialMode = nil
+	if a.connType != TypeSerial {
+		a.sevenBit.SetMode(serialport.SevenBitOff) // 串口连接已在 OpenSerial 中按数据位设置
+	}
+	a.sevenBit.Reset()
+	a.history.Reset()
+	a.templates.Reset()
+	a.txBucket = transport.NewBucket(a.txRate)
+
+	a.streamMutex.Lock()
+	a.channel = a.router.AddConnection()
+	a.router.SetIdentity(a.channel, a.connLabel, a.connColor)
+	a.serverMode = a.connType == TypeTcpServer
+	a.pipeline = a.newPipeline()
+	a.clientFilter = ""
+	if a.hexDumper != nil {
+		a.hexDumper.Reset()
+	}
+	a.lineTracker.Reset()
+	a.clientRx = nil
+	start := time.Now()
+	a.timeMarks = append(a.timeMarks[:0], start)
+	if a.lineTimer != nil {
+		a.lineTimer.Reset(start)
+	}
+	a.sessionStats = session.New(start)
+	if a.frameCfg != nil {
+		a.frameDecoder, _ = frame.NewDecoder(*a.frameCfg)
+	}
+	if a.jsonDecoder != nil {
+		a.jsonDecoder = jsonl.New(a.jsonDecoder.Options())
+	}
+	stages := a.customStagesLocked()
+	a.streamMutex.Unlock()
+	a.health.Applied(stages)
+	a.startWatchdogLocked()
+
+	// 连接说明头只进入接收区与历史，不会发送到连接
+	now := time.Now()
+	header := a.logHeaderLocked(now)
+	a.emit("sys-msg", header)
+	a.annotate(now, header)
+
+	// TCP Server 在客户端接入时发送；SLCAN 的串口由 CAN 协议占用
+	if a.connType != TypeTcpServer && a.connType != TypeSlcan {
+		a.scheduleInitPayload(nil)
+	}
+}
+
+// scheduleInitPayload 按设置在连接建立后发送初始化数据，只用于设置中选择的连接类型
+// client 非 nil 时只发送给这个新接入的 TCP Server 客户端，已接入的客户端不会重复收到
+// 调用方必须持有 a.mutex；发送在 Open* 返回、读取循环启动之后进行
+func (a *App) scheduleInitPayload(client *transport.Client) {
+	cfg := a.settings.Get().InitPayload
+	payload, err := initpayload.Plan(cfg, a.connType.kind())
+	if err != nil || payload == nil {
+		return
+	}
+
+	stop := a.readStopChan
+	initpayload.Schedule(stop, time.Duration(cfg.DelayMs)*time.Millisecond, func() {
+		a.mutex.Lock()
+		defer a.mutex.Unlock()
+
+		if !a.isConnected || a.readStopChan != stop {
+			return
+		}
+		if client != nil {
+			a.sendInitToClientLocked(client, payload)
+			return
+		}
+		// 与普通发送走同一路径；失败时连接保持打开，只发出警告
+		if result := a.sendLocked(payload); result != "Sent" {
+			a.emit("init-payload-error", result)
+		}
+	})
+}
+
+// sendInitToClientLocked 将初始化数据只写入 TCP Server 的一个客户端，客户端已断开时不发送
+// 检查与统计与 sendLocked 相同；失败时只发出警告，不影响其他客户端
+func (a *App) sendInitToClientLocked(client *transport.Client, payload []byte) {
+	if a.connType != TypeTcpServer || a.tcpClients == nil || a.tcpClients.Get(client.Addr) != client {
+		return
+	}
+	if a.readOnly {
+		a.emit("init-payload-error", "Send error: "+errReadOnly.Error())
+		return
+	}
+	if err := a.inputLimits.Check(payload); err != nil {
+		a.emit("init-payload-error", fmt.Sprintf("Send error: %v", err))
+		return
+	}
+	if err := a.writeClientLocked(client, payload); err != nil {
+		if errors.Is(err, transport.ErrTimeout) {
+			err = apperr.Wrap(apperr.WriteTimeout, err, "write did not complete within %v", a.writeTimeout)
+		}
+		a.emit("init-payload-error", fmt.Sprintf("Send error: %v", err))
+		return
+	}
+	a.countTxLocked(payload)
+}
+
+// Transact 发送 data 并收集之后收到的数据，直到出现 terminator 或超过 timeoutMs
+// hexMode 时 data 与 terminator 均为十六进制字符串；terminator 为空时收集整个超时时间内的数据
+// 并发调用按顺序排队执行；收到的数据仍照常以 serial-data 事件发送
+func (a *App) Transact(data string, hexMode bool, timeoutMs int, terminator string) (TransactResult, error) {
+	if timeoutMs <= 0 || timeoutMs > 60000 {
+		return TransactResult{}, fmt.Errorf("timeout must be between 1 and 60000 ms, got %d", timeoutMs)
+	}
+	payload, err := input.Decode(data, hexMode)
+	if err != nil {
+		return TransactResult{}, fmt.Errorf("invalid hex payload: %w", err)
+	}
+	term, err := input.Decode(terminator, hexMode)
+	if err != nil {
+		return TransactResult{}, fmt.Errorf("invalid hex terminator: %w", err)
+	}
+
+	a.transactMutex.Lock()
+	defer a.transactMutex.Unlock()
+
+	// 先订阅再发送，避免丢失快速到达的回复
+	collector := stream.NewCollector(term)
+	unsubscribe := a.rxHub.Subscribe(collector.Write)
+	defer unsubscribe()
+
+	release := a.txLanes.Interactive()
+	a.mutex.Lock()
+	result := a.sendLocked(payload)
+	a.mutex.Unlock()
+	release()
+	if result != "Sent" {
+		return TransactResult{}, fmt.Errorf("%s", result)
+	}
+
+	resp, complete := collector.Wait(time.Duration(timeoutMs) * time.Millisecond)
+	hexStr, _ := format.Hex(resp, format.DefaultOptions)
+	return TransactResult{Data: resp, Hex: hexStr, Complete: complete}, nil
+}
+
+// SendReliable 发送一帧并等待应答：收到 ackPattern 视为成功，收到 nakPattern 或超过 timeoutMs
+// 则重发，最多重发 maxRetries 次。hexMode 时 data 与两个模式均为十六进制字符串，nakPattern 可为空
+// 应答只在接收流中匹配，不会被截留，其他数据仍照常显示；每次尝试结束时发送 reliable-attempt 事件
+// 与 Transact 共用队列，并发调用按顺序执行
+func (a *App) SendReliable(data string, hexMode bool, ackPattern string, nakPattern string, timeoutMs int, maxRetries int) (ReliableResult, error) {
+	if timeoutMs <= 0 || timeoutMs > 60000 {
+		return ReliableResult{}, fmt.Errorf("timeout must be between 1 and 60000 ms, got %d", timeoutMs)
+	}
+	if maxRetries < 0 || maxRetries > 100 {
+		return ReliableResult{}, fmt.Errorf("retries must be between 0 and 100, got %d", maxRetries)
+	}
+	payload, err := input.Decode(data, hexMode)
+	if err != nil {
+		return ReliableResult{}, fmt.Errorf("invalid hex payload: %w", err)
+	}
+	ack, err := input.Decode(ackPattern, hexMode)
+	if err != nil {
+		return ReliableResult{}, fmt.Errorf("invalid hex ACK pattern: %w", err)
+	}
+	if len(ack) == 0 {
+		return ReliableResult{}, fmt.Errorf("ACK pattern must not be empty")
+	}
+	nak, err := input.Decode(nakPattern, hexMode)
+	if err != nil {
+		return ReliableResult{}, fmt.Errorf("invalid hex NAK pattern: %w", err)
+	}
+
+	a.transactMutex.Lock()
+	defer a.transactMutex.Unlock()
+
+	start := time.Now()
+	maxAttempts := maxRetries + 1
+	res := ReliableResult{}
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		// 每次尝试重新订阅，上一帧迟到的应答不会被算作本次的结果
+		matcher := stream.NewMatcher(ack, nak)
+		unsubscribe := a.rxHub.Subscribe(matcher.Write)
+
+		release := a.txLanes.Interactive()
+		a.mutex.Lock()
+		result := a.sendLocked(payload)
+		a.mutex.Unlock()
+		release()
+		if result != "Sent" {
+			unsubscribe()
+			return res, fmt.Errorf("%s", result)
+		}
+		res.Attempts = attempt
+
+		switch matcher.Wait(time.Duration(timeoutMs) * time.Millisecond) {
+		case 0:
+			res.Outcome = "ack"
+		case 1:
+			res.Outcome = "nak"
+		default:
+			res.Outcome = "timeout"
+		}
+		unsubscribe()
+
+		a.emitConn("reliable-attempt", ReliableAttempt{
+			Attempt:     attempt,
+			MaxAttempts: maxAttempts,
+			Outcome:     res.Outcome,
+			ElapsedMs:   time.Since(start).Milliseconds(),
+		})
+		if res.Outcome == "ack" {
+			res.Acked = true
+			break
+		}
+	}
+	res.ElapsedMs = time.Since(start).Milliseconds()
+	return res, nil
+}
+
+// StartModbusPoller 在当前连接上循环轮询多个 Modbus RTU 从站，替换正在进行的轮询
+// 每轮按顺序读取各从站 (请求之间保持串口波特率对应的帧间静默)，相邻两轮的起始间隔为 intervalMs
+// 成功的读取以 modbus-data 事件发送 (按寄存器表命名并换算)，失败以 modbus-error 事件发送 (附带该从站的累计统计)；
+// 连续失败 3 次的从站进入退避，退避时间从 intervalMs 开始加倍，最长 1 分钟
+// 每轮结束时所有寄存器的数值 (按配置顺序，未读到的为空) 作为 plot-sample 发送并写入正在进行的 CSV 导出
+// 与 Transact 共用队列；StopModbusPoller 或关闭连接时结束，正在等待的应答被放弃
+func (a *App) StartModbusPoller(slaves []modbus.SlaveConfig, intervalMs int) string {
+	if intervalMs < 10 || intervalMs > 3600000 {
+		return fmt.Sprintf("Error: interval must be between 10 and 3600000 ms, got %d", intervalMs)
+	}
+	cfg := modbus.Config{Slaves: slaves, Interval: time.Duration(intervalMs) * time.Millisecond}
+	if err := cfg.Validate(); err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	}
+
+	a.mutex.Lock()
+	if !a.isConnected {
+		a.mutex.Unlock()
+		return "Error: Not connected"
+	}
+	if a.connType == TypeSerial {
+		cfg.Gap = modbus.FrameGap(a.serialParams.baudRate)
+	}
+	old := a.modbusPoller
+	a.modbusPoller = modbus.New(cfg, a.modbusExchange, modbus.Hooks{
+		Data: func(r modbus.Reading) {
+			a.emitConn("modbus-data", r)
+		},
+		Error: func(st modbus.SlaveStats, err error) {
+			a.emitConn("modbus-error", ModbusPollError{SlaveStats: st, Error: err.Error()})
+		},
+		Cycle: func(t time.Time, values []float64) {
+			a.streamMutex.Lock()
+			a.emitPlotSampleLocked(plot.Sample{Time: t, Values: values})
+			a.streamMutex.Unlock()
+		},
+	})
+	a.modbusPoller.Start()
+	a.mutex.Unlock()
+
+	// 旧的轮询可能正在等待 a.mutex，在锁外停止
+	if old != nil {
+		old.Stop()
+	}
+	return "Success"
+}
+
+// StopModbusPoller 结束 Modbus 轮询，正在进行的请求不再等待应答
+func (a *App) StopModbusPoller() string {
+	a.mutex.Lock()
+	p := a.modbusPoller
+	a.modbusPoller = nil
+	a.mutex.Unlock()
+
+	if p == nil {
+		return "Error: Modbus poller is not running"
+	}
+	p.Stop()
+	return "Success"
+}
+
+// GetModbusPollerStats 返回正在进行的 Modbus 轮询中各从站的统计，没有轮询时返回 nil
+func (a *App) GetModbusPollerStats() []modbus.SlaveStats {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	if a.modbusPoller == nil {
+		return nil
+	}
+	return a.modbusPoller.Stats()
+}
+
+// modbusExchange 发送一个 Modbus 请求并等待应答，与 Transact 共用队列
+func (a *App) modbusExchange(ctx context.Context, req []byte, fn modbus.Function, want int, timeout time.Duration) ([]byte, error) {
+	a.transactMutex.Lock()
+	defer a.transactMutex.Unlock()
+
+	collector := modbus.NewCollector(fn, want)
+	unsubscribe := a.rxHub.Subscribe(collector.Write)
+	defer unsubscribe()
+
+	a.mutex.Lock()
+	if err := ctx.Err(); err != nil {
+		// 等待锁期间轮询已被停止 (例如连接已关闭)
+		a.mutex.Unlock()
+		return nil, err
+	}
+	result := a.sendLocked(req)
+	a.mutex.Unlock()
+	if result != "Sent" {
+		return nil, fmt.Errorf("%s", result)
+	}
+	return collector.Wait(ctx, timeout)
+}
+
+// AnalyzeRx 采样 durationMs 内收到的原始数据 (接收处理链之前)，分析显示乱码的可能原因：
+// 可打印比例、最高位置位比例、帧错误形态的字节、行结束符，以及推测的原因与建议
+// (波特率不匹配、7 位数据按 8 位接收、UTF-16 文本、二进制协议)。结果只是启发式的推测
+func (a *App) AnalyzeRx(durationMs int) (rxcheck.Report, error) {
+	if durationMs < 100 || durationMs > 10000 {
+		return rxcheck.Report{}, fmt.Errorf("duration must be between 100 and 10000 ms, got %d", durationMs)
+	}
+	a.mutex.Lock()
+	connected, connType, baud := a.isConnected, a.connType, a.serialParams.baudRate
+	a.mutex.Unlock()
+	if !connected {
+		return rxcheck.Report{}, fmt.Errorf("not connected")
+	}
+
+	collector := stream.NewCollector(nil)
+	unsubscribe := a.rawHub.Subscribe(collector.Write)
+	sample, _ := collector.Wait(time.Duration(durationMs) * time.Millisecond)
+	unsubscribe()
+
+	report := rxcheck.Analyze(sample)
+	if report.Cause == rxcheck.CauseBaud && connType == TypeSerial {
+		report.Suggestion += fmt.Sprintf(" (currently %d baud)", baud)
+	}
+	return report, nil
+}
+
+// SetRxWatchdog 设置接收静默看门狗：超过 timeoutSec 秒没有收到任何数据时执行 action
+//   - "event": 发送 rx-silent 事件，参数为静默时长 (毫秒)
+//   - "send": 发送 SetRxWatchdogProbe 配置的探测数据
+//   - "reconnect": 关闭连接并以相同参数重新打开
+//
+// 静默期间每隔 timeoutSec 重复执行，直到再次收到数据；连接关闭时自动停止
+func (a *App) SetRxWatchdog(enabled bool, timeoutSec int, action string) string {
+	switch action {
+	case "event", "send", "reconnect":
+	default:
+		return fmt.Sprintf("Error: unknown watchdog action %q (expected event, send or reconnect)", action)
+	}
+	if enabled && (timeoutSec < 1 || timeoutSec > 3600) {
+		return fmt.Sprintf("Error: watchdog timeout must be between 1 and 3600 seconds, got %d", timeoutSec)
+	}
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	if enabled && action == "send" && len(a.rxWatchdog.Probe) == 0 {
+		return "Error: no probe payload configured, call SetRxWatchdogProbe first"
+	}
+	a.rxWatchdog.Enabled = enabled
+	a.rxWatchdog.Timeout = time.Duration(timeoutSec) * time.Second
+	a.rxWatchdog.Action = action
+
+	// 立即作用于当前连接
+	a.stopWatchdogLocked()
+	if a.isConnected {
+		a.startWatchdogLocked()
+	}
+	return "Success"
+}
+
+// SetRxWatchdogProbe 设置看门狗 "send" 动作发送的探测数据
+func (a *App) SetRxWatchdogProbe(data string, isHex bool) string {
+	probe, err := input.Decode(data, isHex)
+	if err != nil {
+		return fmt.Sprintf("Error: invalid hex payload: %v", err)
+	}
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	a.rxWatchdog.Probe = probe
+	return "Success"
+}
+
+// startWatchdogLocked 按配置为当前连接启动看门狗，调用方必须持有 a.mutex
+func (a *App) startWatchdogLocked() {
+	if !a.rxWatchdog.Enabled {
+		return
+	}
+	cfg := a.rxWatchdog
+	wd := watchdog.New(cfg.Timeout, func(silence time.Duration) {
+		a.onRxSilent(cfg, silence)
+	})
+
+	a.streamMutex.Lock()
+	a.watchdog = wd
+	a.streamMutex.Unlock()
+	wd.Start()
+}
+
+// stopWatchdogLocked 停止当前连接的看门狗，调用方必须持有 a.mutex
+func (a *App) stopWatchdogLocked() {
+	a.streamMutex.Lock()
+	defer a.streamMutex.Unlock()
+
+	if a.watchdog != nil {
+		a.watchdog.Stop()
+		a.watchdog = nil
+	}
+}
+
+// onRxSilent 在看门狗的 goroutine 中执行静默动作
+func (a *App) onRxSilent(cfg RxWatchdogConfig, silence time.Duration) {
+	a.streamMutex.Lock()
+	if a.sessionStats != nil {
+		a.sessionStats.AddTrigger()
+	}
+	a.streamMutex.Unlock()
+
+	switch cfg.Action {
+	case "event":
+		a.emitConn("rx-silent", silence.Milliseconds())
+	case "send":
+		a.mutex.Lock()
+		result := a.sendLocked(cfg.Probe)
+		a.mutex.Unlock()
+		if result != "Sent" {
+			a.emit("sys-msg", fmt.Sprintf("Watchdog probe failed: %s", result))
+		}
+	case "reconnect":
+		a.mutex.Lock()
+		reopen := a.reopen
+		a.mutex.Unlock()
+		if reopen == nil {
+			a.emit("sys-msg", "Watchdog: this connection type cannot be reopened automatically")
+			return
+		}
+		a.emit("sys-msg", fmt.Sprintf("No data for %v, reconnecting", silence.Round(time.Second)))
+		a.mutex.Lock()
+		a.reconnecting = true
+		a.mutex.Unlock()
+		a.Close()
+		result := reopen()
+		if !openSucceeded(result) {
+			a.emitError(fmt.Sprintf("Watchdog reconnect failed: %s", result), apperr.NewEvent(apperr.ReconnectFailed, result))
+		}
+		a.finishReconnect(openSucceeded(result))
+	}
+}
+
+// resumeRetryDelays 唤醒后重新打开连接的重试间隔 (USB 串口需要时间重新枚举，网络需要时间重新获取地址)
+var resumeRetryDelays = []time.Duration{0, time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 16 * time.Second}
+
+// ResumeResult resumed-after-sleep 事件的数据
+type ResumeResult struct {
+	Success  bool   `json:"success"`
+	Result   string `json:"result"` // 最后一次打开的结果
+	Attempts int    `json:"attempts"`
+}
+
+// SetSleepHandling 设置是否在系统睡眠前关闭连接并在唤醒后自动重新打开 (默认开启)，并保存到设置
+// 适配器能够在睡眠后正常工作的用户可以关闭
+func (a *App) SetSleepHandling(enabled bool) string {
+	if err := a.settings.Update(func(s *settings.Settings) {
+		s.IgnoreSleep = !enabled
+	}); err != nil {
+		return fmt.Sprintf("Error saving settings: %v", err)
+	}
+	return "Success"
+}
+
+// onSuspend 系统即将睡眠：关闭物理句柄并记住如何重新打开
+func (a *App) onSuspend() {
+	if a.settings.Get().IgnoreSleep {
+		return
+	}
+	a.mutex.Lock()
+	reopen := a.reopen
+	connected := a.isConnected
+	a.mutex.Unlock()
+	if !connected || reopen == nil {
+		return
+	}
+
+	// 先记录重新打开函数，使 Close 把唤醒前的间隔视为重连中 (保留发送队列)
+	a.mutex.Lock()
+	a.sleepReopen = reopen
+	a.mutex.Unlock()
+	a.Close()
+	a.emit("sys-msg", "System is going to sleep, connection closed until resume")
+}
+
+// onResume 系统已唤醒：重新打开睡眠前关闭的连接
+// 没有收到睡眠通知 (仅靠时钟检测发现唤醒) 时，当前连接的句柄可能已失效，同样关闭后重新打开
+func (a *App) onResume() {
+	if a.settings.Get().IgnoreSleep {
+		return
+	}
+	a.mutex.Lock()
+	if a.resuming {
+		a.mutex.Unlock()
+		return
+	}
+	reopen := a.sleepReopen
+	a.sleepReopen = nil
+	stale := reopen == nil && a.isConnected && a.reopen != nil
+	if stale {
+		reopen = a.reopen
+	}
+	if reopen == nil {
+		a.mutex.Unlock()
+		return
+	}
+	a.resuming = true
+	a.mutex.Unlock()
+
+	if stale {
+		a.Close()
+	}
+	go a.resumeConnection(reopen)
+}
+
+// resumeConnection 按 resumeRetryDelays 重试重新打开连接，用户期间手动打开了连接时停止
+func (a *App) resumeConnection(reopen func() string) {
+	defer func() {
+		a.mutex.Lock()
+		a.resuming = false
+		a.mutex.Unlock()
+	}()
+
+	var res ResumeResult
+	for _, delay := range resumeRetryDelays {
+		time.Sleep(delay)
+		a.mutex.Lock()
+		connected := a.isConnected
+		a.mutex.Unlock()
+		if connected {
+			a.finishReconnect(true)
+			return
+		}
+
+		res.Attempts++
+		res.Result = reopen()
+		if openSucceeded(res.Result) {
+			res.Success = true
+			break
+		}
+	}
+
+	if res.Success {
+		a.emit("sys-msg", "Resumed after sleep, connection reopened")
+	} else {
+		a.emitError(fmt.Sprintf("Resumed after sleep, reopen failed after %d attempts: %s", res.Attempts, res.Result),
+			apperr.NewEvent(apperr.ReconnectFailed, res.Result))
+	}
+	a.emit("resumed-after-sleep", res)
+	a.finishReconnect(res.Success)
+}
+
+// SetInitPayload 设置连接建立后自动发送的初始化数据 (例如 "ATE0\r\n")，并保存到设置
+// data 为空表示不发送；isHex 时 data 为十六进制字符串；delayMs 为连接建立后的等待时间；
+// kinds 为发送的连接类型 ("serial"、"tcp"、"tcp-server"、"udp" 等)，为空时只用于串口
+// 发送失败不会关闭连接，而是发出 init-payload-error 事件
+func (a *App) SetInitPayload(data string, isHex bool, delayMs int, kinds []string) string {
+	parsed, err := initpayload.ParseKinds(kinds)
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	}
+	cfg := &settings.InitPayload{Data: data, Hex: isHex, DelayMs: delayMs, Kinds: parsed}
+	if err := initpayload.Validate(*cfg); err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	}
+
+	if data == "" {
+		cfg = nil
+	}
+	if err := a.settings.Update(func(s *settings.Settings) {
+		s.InitPayload = cfg
+	}); err != nil {
+		return fmt.Sprintf("Error saving settings: %v", err)
+	}
+	return "Success"
+}
+
+// ScheduleInfo 定时发送任务及其下一次触发时间
+type ScheduleInfo struct {
+	ID        string `json:"id"`
+	Expr      string `json:"expr"`
+	Data      string `json:"data"`
+	Hex       bool   `json:"hex"`
+	Reconnect bool   `json:"reconnect"`
+	NextFire  int64  `json:"nextFire"` // Unix 毫秒
+}
+
+// ScheduleSend 添加或替换定时发送任务并保存到设置
+// expr 为每日时刻 ("02:00,04:00") 或固定间隔 ("@every 30m")，按本地时间计算
+// 触发时未连接：reconnect 为 true 时先以相同参数重新打开上一次的连接，否则跳过并发出 sys-msg 警告
+func (a *App) ScheduleSend(id string, expr string, data string, hexMode bool, reconnect bool) string {
+	if _, err := schedule.Parse(expr); err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	}
+	if _, err := input.Decode(data, hexMode); err != nil {
+		return fmt.Sprintf("Error: invalid hex payload: %v", err)
+	}
+
+	job := settings.ScheduledSend{ID: id, Expr: expr, Data: data, Hex: hexMode, Reconnect: reconnect}
+	if err := a.scheduler.Add(schedule.Job{ID: id, Expr: expr, Data: job}); err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	}
+	if err := a.settings.Update(func(s *settings.Settings) {
+		s.Schedules = replaceSchedule(s.Schedules, id, &job)
+	}); err != nil {
+		return fmt.Sprintf("Error saving settings: %v", err)
+	}
+	return "Success"
+}
+
+// RemoveSchedule 删除定时发送任务
+func (a *App) RemoveSchedule(id string) string {
+	if !a.scheduler.Remove(id) {
+		return fmt.Sprintf("Error: schedule %q not found", id)
+	}
+	if err := a.settings.Update(func(s *settings.Settings) {
+		s.Schedules = replaceSchedule(s.Schedules, id, nil)
+	}); err != nil {
+		return fmt.Sprintf("Error saving settings: %v", err)
+	}
+	return "Success"
+}
+
+// ListSchedules 返回所有定时发送任务，按下一次触发时间排序
+func (a *App) ListSchedules() []ScheduleInfo {
+	entries := a.scheduler.List()
+	out := make([]ScheduleInfo, 0, len(entries))
+	for _, e := range entries {
+		job := e.Data.(settings.ScheduledSend)
+		out = append(out, ScheduleInfo{
+			ID:        job.ID,
+			Expr:      job.Expr,
+			Data:      job.Data,
+			Hex:       job.Hex,
+			Reconnect: job.Reconnect,
+			NextFire:  e.Next.UnixMilli(),
+		})
+	}
+	return out
+}
+
+// replaceSchedule 在列表中替换 (job 为 nil 时删除) 指定 ID 的任务，不存在时追加
+func replaceSchedule(list []settings.ScheduledSend, id string, job *settings.ScheduledSend) []settings.ScheduledSend {
+	out := make([]settings.ScheduledSend, 0, len(list)+1)
+	for _, s := range list {
+		if s.ID != id {
+			out = append(out, s)
+		}
+	}
+	if job != nil {
+		out = append(out, *job)
+	}
+	return out
+}
+
+// loadSchedules 从设置恢复定时发送任务，无效的任务跳过
+func (a *App) loadSchedules() {
+	for _, job := range a.settings.Get().Schedules {
+		if err := a.scheduler.Add(schedule.Job{ID: job.ID, Expr: job.Expr, Data: job}); err != nil {
+			fmt.Printf("Skipping invalid schedule %q: %v\n", job.ID, err)
+		}
+	}
+}
+
+// fireSchedule 在调度器 goroutine 中执行一次定时发送
+func (a *App) fireSchedule(j schedule.Job, scheduled time.Time) {
+	job := j.Data.(settings.ScheduledSend)
+	payload, err := input.Decode(job.Data, job.Hex)
+	if err != nil {
+		a.emitError(fmt.Sprintf("Schedule %q: invalid payload: %v", job.ID, err), apperr.NewEvent(apperr.InvalidPayload, err.Error()))
+		return
+	}
+
+	a.mutex.Lock()
+	connected, reopen := a.isConnected, a.reopen
+	a.mutex.Unlock()
+
+	if !connected {
+		if !job.Reconnect || reopen == nil {
+			a.emit("sys-msg", fmt.Sprintf("Schedule %q skipped at %s: not connected", job.ID, scheduled.Format("15:04:05")))
+			return
+		}
+		if result := reopen(); !openSucceeded(result) {
+			a.emitError(fmt.Sprintf("Schedule %q: reconnect failed: %s", job.ID, result), apperr.NewEvent(apperr.ReconnectFailed, result))
+			return
+		}
+	}
+
+	result := a.sendBulk(payload, nil)
+	if result != "Sent" {
+		a.emitError(fmt.Sprintf("Schedule %q: %s", job.ID, result), apperr.NewEvent(apperr.IOError, result))
+		return
+	}
+	a.emit("sys-msg", fmt.Sprintf("Schedule %q sent %d bytes", job.ID, len(payload)))
+}
+
+// ListCommands 返回保存的快捷指令
+func (a *App) ListCommands() []commands.Command {
+	list := a.settings.Get().Commands
+	if list == nil {
+		return []commands.Command{}
+	}
+	return list
+}
+
+// SaveCommand 新增或按名称覆盖一条快捷指令，并记录修改时间 (用于导入合并时比较新旧)
+func (a *App) SaveCommand(cmd commands.Command) string {
+	cmd.Name = strings.TrimSpace(cmd.Name)
+	if err := cmd.Validate(); err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	}
+	now := time.Now().UTC().Truncate(time.Second)
+	cmd.Updated = &now
+	if err := a.settings.Update(func(s *settings.Settings) {
+		s.Commands, _ = commands.Merge(s.Commands, []commands.Command{cmd}, commands.PolicyNewest)
+	}); err != nil {
+		return fmt.Sprintf("Error saving settings: %v", err)
+	}
+	return "Success"
+}
+
+// DeleteCommand 删除快捷指令
+func (a *App) DeleteCommand(name string) string {
+	found := false
+	if err := a.settings.Update(func(s *settings.Settings) {
+		for i, c := range s.Commands {
+			if c.Name == name {
+				s.Commands = append(s.Commands[:i:i], s.Commands[i+1:]...)
+				found = true
+				return
+			}
+		}
+	}); err != nil {
+		return fmt.Sprintf("Error saving settings: %v", err)
+	}
+	if !found {
+		return fmt.Sprintf("Error: command %q not found", name)
+	}
+	return "Success"
+}
+
+// SendCommand 发送保存的快捷指令 (已追加其行尾)
+func (a *App) SendCommand(name string) string {
+	payload, err := a.commandPayload(name)
+	if err != nil {
+		return fmt.Sprintf("Send error: %v", err)
+	}
+	defer a.txLanes.Interactive()()
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	return a.sendLocked(payload)
+}
+
+// commandPayload 返回快捷指令要发送的字节
+func (a *App) commandPayload(name string) ([]byte, error) {
+	for _, c := range a.settings.Get().Commands {
+		if c.Name == name {
+			return c.Bytes()
+		}
+	}
+	return nil, fmt.Errorf("command %q not found", name)
+}
+
+// ExportCommands 将快捷指令导出为 JSON 文件 (格式见 commands 包)，便于分享给其他用户
+// path 可以是文件名模板 (见 nametmpl 包)，为空时使用 commands 的默认模板；返回实际写入的文件路径
+func (a *App) ExportCommands(path string) (string, error) {
+	path, err := a.outputPath(FileCommands, path)
+	if err != nil {
+		return "", err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return "", err
+	}
+	if err := commands.Encode(f, a.settings.Get().Commands); err != nil {
+		f.Close()
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// ImportResult ImportCommands 的结果
+type ImportResult struct {
+	commands.MergeStats
+	Errors []commands.EntryError `json:"errors,omitempty"` // 被跳过的无效指令
+}
+
+// ImportCommands 从 JSON 文件导入快捷指令，无效的指令逐条报告在 Errors 中，其余照常导入
+// merge 为 false 时用文件中的有效指令替换全部已有指令；为 true 时按名称合并，
+// policy 为 "newest" (默认，保留修改时间较新的一条) 或 "skip" (保留已有的指令)
+func (a *App) ImportCommands(path string, merge bool, policy string) (ImportResult, error) {
+	p, err := commands.ParsePolicy(policy)
+	if err != nil {
+		return ImportResult{}, err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return ImportResult{}, err
+	}
+	defer f.Close()
+
+	imported, entryErrs, err := commands.Decode(f)
+	if err != nil {
+		return ImportResult{}, err
+	}
+
+	result := ImportResult{Errors: entryErrs}
+	if err := a.settings.Update(func(s *settings.Settings) {
+		if !merge {
+			s.Commands = nil
+		}
+		s.Commands, result.MergeStats = commands.Merge(s.Commands, imported, p)
+	}); err != nil {
+		return ImportResult{}, fmt.Errorf("failed to save settings: %w", err)
+	}
+	return result, nil
+}
+
+// SetClassifierRules 设置接收行分类规则，立即生效并保存；规则为空时关闭分类
+// 每条规则的 pattern 为行首前缀或正则表达式 (regex 为 true)，按 priority 从高到低匹配第一条；
+// 匹配结果以 DataMeta.Lines 随 serial-data 事件发送，前端只需按类别设置样式
+func (a *App) SetClassifierRules(rules []classify.Rule) string {
+	c, err := classify.Compile(rules)
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	}
+	if err := a.settings.Update(func(s *settings.Settings) {
+		s.ClassifierRules = append([]classify.Rule(nil), rules...)
+	}); err != nil {
+		return fmt.Sprintf("Error saving settings: %v", err)
+	}
+
+	a.streamMutex.Lock()
+	a.classifier = c
+	a.streamMutex.Unlock()
+	a.health.Applied(a.customStages())
+	return "Success"
+}
+
+// GetClassifierRules 返回保存的接收行分类规则
+func (a *App) GetClassifierRules() []classify.Rule {
+	rules := a.settings.Get().ClassifierRules
+	if rules == nil {
+		return []classify.Rule{}
+	}
+	return rules
+}
+
+// loadClassifierRules 从设置恢复分类规则，规则无效时不启用分类
+func (a *App) loadClassifierRules() {
+	rules := a.settings.Get().ClassifierRules
+	if len(rules) == 0 {
+		return
+	}
+	c, err := classify.Compile(rules)
+	if err != nil {
+		fmt.Printf("Skipping invalid classifier rules: %v\n", err)
+		return
+	}
+	a.classifier = c
+}
+
+// SetLineTiming 开启或关闭接收行的耗时计算并保存：开启时 serial-data 事件的 DataMeta.Timing
+// 附带每行距上一行与距计时标记的耗时 (微秒，按读取数据的时间计算，而不是事件发送的时间)；
+// markPatterns 中的正则表达式匹配某一行时自动在该行设置计时标记 (例如 "^BOOT")，关闭时同样用于 ExportLines
+func (a *App) SetLineTiming(enabled bool, markPatterns []string) string {
+	timer, err := linetime.New(markPatterns)
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	}
+	if err := a.settings.Update(func(s *settings.Settings) {
+		s.LineTiming = &settings.LineTiming{Enabled: enabled, MarkPatterns: append([]string(nil), markPatterns...)}
+	}); err != nil {
+		return fmt.Sprintf("Error saving settings: %v", err)
+	}
+
+	a.streamMutex.Lock()
+	defer a.streamMutex.Unlock()
+	if !enabled {
+		a.lineTimer = nil
+		a.health.Applied(a.customStagesLocked())
+		return "Success"
+	}
+	// 连接中开启时从最近的计时标记开始
+	if n := len(a.timeMarks); n > 0 {
+		timer.Reset(a.timeMarks[n-1])
+	}
+	a.lineTimer = timer
+	a.health.Applied(a.customStagesLocked())
+	return "Success"
+}
+
+// GetLineTiming 返回接收行的耗时设置
+func (a *App) GetLineTiming() settings.LineTiming {
+	lt := a.settings.Get().LineTiming
+	if lt == nil {
+		return settings.LineTiming{MarkPatterns: []string{}}
+	}
+	if lt.MarkPatterns == nil {
+		lt.MarkPatterns = []string{}
+	}
+	return *lt
+}
+
+// loadLineTiming 从设置恢复耗时计算，触发规则无效时不开启
+func (a *App) loadLineTiming() {
+	lt := a.settings.Get().LineTiming
+	if lt == nil || !lt.Enabled {
+		return
+	}
+	timer, err := linetime.New(lt.MarkPatterns)
+	if err != nil {
+		fmt.Printf("Skipping invalid line timing triggers: %v\n", err)
+		return
+	}
+	a.lineTimer = timer
+}
+
+// maxTimeMarks 每个连接保留的计时标记数，超出时丢弃最早的
+const maxTimeMarks = 4096
+
+// SetTimeMark 将计时标记设为当前时间，之后的行的距标记耗时从 0 开始计算；同时记录一条标注
+func (a *App) SetTimeMark() string {
+	a.mutex.Lock()
+	connected := a.isConnected
+	a.mutex.Unlock()
+	if !connected {
+		return "Not connected"
+	}
+
+	now := time.Now()
+	a.streamMutex.Lock()
+	if a.lineTimer != nil {
+		a.lineTimer.Mark(now)
+	}
+	if len(a.timeMarks) >= maxTimeMarks {
+		a.timeMarks = append(a.timeMarks[:0], a.timeMarks[1:]...)
+	}
+	a.timeMarks = append(a.timeMarks, now)
+	a.streamMutex.Unlock()
+
+	a.annotate(now, "Time mark")
+	return "Success"
+}
+
+// ExportLines 将历史记录中 [fromTs, toTs] (Unix 毫秒) 范围内的接收行与标注导出为 CSV 或文本 (exportFormat 为 "csv" 或 "text")，
+// 每行附带距上一行与距计时标记的耗时 (微秒)。耗时按历史记录的接收时间、SetTimeMark 设置的标记与
+// SetLineTiming 的触发规则重新计算，与实时事件一致；最早的历史已被淘汰时，保留的第一行从最近的标记开始计算。
+// fromTs 为 0 时从最早的历史记录开始，toTs 为 0 时到当前时间为止。
+// path 可以是文件名模板 (见 nametmpl 包)，为空时使用 lines 的默认模板；返回实际写入的文件路径
+func (a *App) ExportLines(path string, fromTs int64, toTs int64, exportFormat string) (string, error) {
+	f, err := linetime.ParseFormat(exportFormat)
+	if err != nil {
+		return "", err
+	}
+	from, to := time.Time{}, time.Now()
+	if fromTs > 0 {
+		from = time.UnixMilli(fromTs)
+	}
+	if toTs > 0 {
+		to = time.UnixMilli(toTs)
+	}
+	if from.After(to) {
+		return "", fmt.Errorf("invalid time range: start is after end")
+	}
+	timer, err := linetime.New(a.GetLineTiming().MarkPatterns)
+	if err != nil {
+		return "", err
+	}
+
+	a.mutex.Lock()
+	header := a.logHeaderLocked(time.Now())
+	path, err = a.outputPathLocked(FileLines, path)
+	a.mutex.Unlock()
+	if err != nil {
+		return "", err
+	}
+	a.streamMutex.Lock()
+	marks := append([]time.Time(nil), a.timeMarks...)
+	a.streamMutex.Unlock()
+
+	out, err := logfile.Create(path, a.captureCompress)
+	if err != nil {
+		return "", fmt.Errorf("failed to create file: %w", err)
+	}
+	w, err := linetime.NewWriter(out, f, loghdr.Lines(header))
+	if err == nil {
+		entries, _ := a.history.From(0)
+		for _, e := range entries {
+			for len(marks) > 0 && !marks[0].After(e.Time) {
+				timer.Mark(marks[0])
+				marks = marks[1:]
+			}
+			inRange := !e.Time.Before(from) && !e.Time.After(to)
+			if e.Annotation != "" {
+				if inRange {
+					err = w.WriteAnnotation(e.Time, e.Annotation)
+				}
+			} else {
+				// 范围之前的数据同样需要计算，保证第一行的耗时正确
+				timer.FeedFuncFrom(e.Source, e.Data, e.Time, func(line []byte, l linetime.Line) {
+					if inRange && err == nil {
+						err = w.WriteLine(e.Time, line, l)
+					}
+				})
+			}
+			if err != nil {
+				break
+			}
+		}
+	}
+	if err == nil {
+		err = w.Close()
+	}
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(out.Path())
+		return "", fmt.Errorf("failed to write lines: %w", err)
+	}
+	return out.Path(), nil
+}
+
+// newPipeline 创建连接的接收处理链，调用方必须持有 a.streamMutex (NewApp 中除外)
+func (a *App) newPipeline() *stream.Pipeline {
+	a.rxDecoder = textenc.NewDecoder(a.rxEncoding, func(mode textenc.Mode, reason string) {
+		if mode == textenc.Raw {
+			a.emit("sys-msg", fmt.Sprintf("RX encoding: keeping raw bytes (%s)", reason))
+		} else {
+			a.emit("sys-msg", fmt.Sprintf("RX encoding: decoding as %s (%s)", mode, reason))
+		}
+	})
+	stages := []stream.Stage{
+		stream.Map(a.sevenBit.Process), // 7 位数据的最高位处理 (仅串口)
+		stream.Map(a.echo.Filter),      // 控制台模式的本地回显抑制
+	}
+	// TCP Server 的每个客户端使用各自的解码器，在 emitChunks 中解码 (见 clientRxLocked)
+	if !a.serverMode {
+		stages = append(stages, stream.Map(a.rxDecoder.Decode)) // UTF-16 解码
+	}
+	return stream.New(stages...)
+}
+
+// clientRx TCP Server 一个客户端跨数据块的接收状态
+type clientRx struct {
+	decoder *textenc.Decoder
+	lines   classify.Tracker
+}
+
+// decode 用客户端的解码器转换处理链输出的数据块，丢弃解码后为空的块
+func (c *clientRx) decode(chunks [][]byte) [][]byte {
+	out := chunks[:0]
+	for _, chunk := range chunks {
+		if d := c.decoder.Decode(chunk); len(d) > 0 {
+			out = append(out, d)
+		}
+	}
+	return out
+}
+
+// clientRxLocked 返回客户端 addr 的接收状态，第一次收到该客户端的数据时创建；调用方必须持有 a.streamMutex
+func (a *App) clientRxLocked(addr string) *clientRx {
+	if c, ok := a.clientRx[addr]; ok {
+		return c
+	}
+	if a.clientRx == nil {
+		a.clientRx = make(map[string]*clientRx)
+	}
+	c := &clientRx{decoder: textenc.NewDecoder(a.rxEncoding, func(mode textenc.Mode, reason string) {
+		if mode == textenc.Raw {
+			a.emit("sys-msg", fmt.Sprintf("RX encoding for %s: keeping raw bytes (%s)", addr, reason))
+		} else {
+			a.emit("sys-msg", fmt.Sprintf("RX encoding for %s: decoding as %s (%s)", addr, mode, reason))
+		}
+	})}
+	a.clientRx[addr] = c
+	return c
+}
+
+// forgetClientRx 客户端断开后丢弃其接收状态与未结束的行
+func (a *App) forgetClientRx(addr string) {
+	a.streamMutex.Lock()
+	defer a.streamMutex.Unlock()
+
+	delete(a.clientRx, addr)
+	if a.lineTimer != nil {
+		a.lineTimer.Forget(addr)
+	}
+}
+
+// SetRxEncoding 设置接收数据的编码："raw" (默认，原样输出)、"utf-16le"、"utf-16be" 或 "auto"
+// auto 在连接开始后的前 256 字节内根据 BOM 或交替出现的 NUL 字节识别 UTF-16，判定结果通过 sys-msg 报告；
+// 立即作用于当前连接 (覆盖自动检测的结果) 并保持到之后的连接
+func (a *App) SetRxEncoding(name string) string {
+	mode, err := textenc.ParseMode(name)
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	}
+
+	a.streamMutex.Lock()
+	defer a.streamMutex.Unlock()
+
+	a.rxEncoding = mode
+	a.rxDecoder.SetMode(mode)
+	for _, c := range a.clientRx {
+		c.decoder.SetMode(mode)
+	}
+	return "Success"
+}
+
+// emit 发送事件到前端
+func (a *App) emit(name string, data ...interface{}) {
+	if len(data) == 1 && isMessageEvent(name) {
+		if msg, ok := data[0].(string); ok {
+			a.limiter.Emit(name, msg)
+			return
+		}
+	}
+	runtime.EventsEmit(a.ctx, name, data...)
+}
+
+// isMessageEvent 判断是否为需要限流的消息类事件 (包括按通道命名的 serial-error)，数据事件不受影响
+func isMessageEvent(name string) bool {
+	return name == "sys-msg" || name == "serial-error" || strings.HasPrefix(name, "serial-error:")
+}
+
+// emitConn 发送当前连接的事件，经由路由器分发到订阅了该连接通道的窗口
+func (a *App) emitConn(name string, data ...interface{}) {
+	a.streamMutex.Lock()
+	channel := a.channel
+	if name == "serial-error" && a.sessionStats != nil {
+		a.sessionStats.AddError(fmt.Sprint(data...))
+	}
+	a.streamMutex.Unlock()
+
+	a.router.Emit(channel, name, data...)
+}
+
+// emitConnError 发送当前连接的错误：legacy 为原有的 serial-error 文本 (前端迁移前继续发送)，
+// ev 以 app-error 事件发送，包含错误码、原始错误与建议的恢复操作
+func (a *App) emitConnError(legacy string, ev apperr.Event) {
+	a.emitConn("serial-error", legacy)
+	a.emitConn("app-error", ev)
+}
+
+// emitError 同 emitConnError，用于不属于某个连接的错误 (自动重连、定时任务等)
+func (a *App) emitError(legacy string, ev apperr.Event) {
+	a.emit("serial-error", legacy)
+	a.emit("app-error", ev)
+}
+
+// SetConnectionLabel 设置连接的标签与颜色 ("#RGB" 或 "#RRGGBB")，均为空时清除
+// id 为事件通道 ID；为空或为当前连接的通道时同时作为之后打开的连接 (包括自动重连) 的标签，
+// 未连接时只对之后的连接生效。设置后该连接的每个事件在参数末尾追加 events.Identity
+func (a *App) SetConnectionLabel(id string, label string, color string) string {
+	label, color, err := events.NormalizeIdentity(label, color)
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	}
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	a.streamMutex.Lock()
+	current := a.channel
+	a.streamMutex.Unlock()
+
+	if id == "" || id == current {
+		a.connLabel, a.connColor = label, color
+		if !a.isConnected {
+			return "Success"
+		}
+		id = current
+	}
+	if err := a.router.SetIdentity(id, label, color); err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	}
+	return "Success"
+}
+
+// SuggestFileName 返回导出文件的建议文件名 "<prefix>_<标签>_<日期-时间>.<ext>"，标签与前缀经过文件名清理，
+// 未设置标签时省略该部分；供前端的保存对话框使用，避免并行会话的文件混淆
+func (a *App) SuggestFileName(prefix string, ext string) string {
+	a.mutex.Lock()
+	label := a.connLabel
+	a.mutex.Unlock()
+
+	var parts []string
+	for _, p := range []string{prefix, label} {
+		if p = fsname.Sanitize(p); p != "" {
+			parts = append(parts, p)
+		}
+	}
+	parts = append(parts, time.Now().Format("20060102-150405"))
+
+	name := strings.Join(parts, "_")
+	if ext = fsname.Sanitize(strings.TrimPrefix(ext, ".")); ext != "" {
+		name += "." + ext
+	}
+	return name
+}
+
+// RegisterWindow 注册一个前端窗口，返回用于 SubscribeChannel 的窗口 ID
+func (a *App) RegisterWindow() string {
+	return a.router.RegisterWindow()
+}
+
+// UnregisterWindow 注销窗口并取消其全部订阅
+func (a *App) UnregisterWindow(windowID string) {
+	a.router.UnregisterWindow(windowID)
+}
+
+// SubscribeChannel 订阅连接通道的事件，事件名为 "<事件名>:<通道 ID>" (例如 "serial-data:conn-1")
+// 只有一个连接时原始事件名 (例如 "serial-data") 仍会发送，旧版前端无需订阅
+func (a *App) SubscribeChannel(windowID string, channel string) string {
+	if err := a.router.Subscribe(windowID, channel); err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	}
+	return "Success"
+}
+
+// UnsubscribeChannel 取消订阅连接通道
+func (a *App) UnsubscribeChannel(windowID string, channel string) {
+	a.router.Unsubscribe(windowID, channel)
+}
+
+// emitData 将接收到的数据送入处理链，为输出的每个数据块分配序号、记录到历史缓冲区并发送 serial-data 事件
+func (a *App) emitData(data []byte) {
+	a.emitDataFrom(data, "")
+}
+
+// emitDataFrom 同 emitData，source 为数据来源地址 (UDP 数据报来源或 TCP Server 的客户端)，总是记录到历史；
+// UDP 仅在开启来源显示时附加到元信息，TCP Server 总是附加
+func (a *App) emitDataFrom(data []byte, source string) {
+	a.emitDatagram(data, source, 0)
+}
+
+// emitDatagram 同 emitDataFrom，localPort 非 0 时在事件元信息中附带接收数据的本地端口
+func (a *App) emitDatagram(data []byte, source string, localPort int) {
+	a.streamMutex.Lock()
+	pipeline := a.pipeline
+	if a.watchdog != nil {
+		a.watchdog.Kick()
+	}
+	origin := rxOrigin{source: source, localPort: localPort}
+	if a.udpShowSource || a.serverMode {
+		origin.shown = source
+	}
+	origin.muted = a.clientFilter != "" && source != a.clientFilter
+	if a.serverMode && source != "" {
+		origin.rx = a.clientRxLocked(source)
+	}
+	feed := a.zmodemFeed
+	var before []byte
+	if feed == nil && a.zmodemAuto {
+		if i := a.zmodemDetect.Feed(data); i >= 0 {
+			before, data = data[:i], data[i:]
+			feed = a.startZmodemLocked()
+		}
+	}
+	a.streamMutex.Unlock()
+
+	if feed == nil {
+		a.emitChunks(pipeline, data, origin)
+		return
+	}
+	if len(before) > 0 {
+		a.emitChunks(pipeline, before, origin)
+	}
+	a.feedZmodem(feed, data)
+}
+
+// rxOrigin 接收数据的来源：source 记录到历史，shown 附加到事件元信息，muted 时不发送数据事件；
+// rx 为 TCP Server 客户端各自的接收状态，其他连接为 nil
+type rxOrigin struct {
+	source    string
+	shown     string
+	muted     bool
+	localPort int
+	rx        *clientRx
+}
+
+// emitChunks 经过接收处理链后记录并发送数据事件
+func (a *App) emitChunks(pipeline *stream.Pipeline, data []byte, origin rxOrigin) {
+	a.rawHub.Publish(data)
+	var chunks [][]byte
+	if !a.health.Run(stageRx, data, func() {
+		chunks = pipeline.Process(data)
+		if origin.rx != nil {
+			chunks = origin.rx.decode(chunks)
+		}
+	}) {
+		chunks = [][]byte{data}
+	}
+	for _, chunk := range chunks {
+		now := time.Now()
+		seq := a.history.AppendFrom(now, chunk, origin.source)
+		a.streamMutex.Lock()
+		a.feedBurstLocked(seq, now, chunk)
+		tracker := &a.lineTracker
+		if origin.rx != nil {
+			tracker = &origin.rx.lines
+		}
+		var lines []classify.LineClass
+		a.health.Run(stageClassifier, chunk, func() { lines = tracker.Feed(a.classifier, chunk) })
+		var timing []linetime.Line
+		if a.lineTimer != nil {
+			a.health.Run(stageLineTiming, chunk, func() { timing = a.lineTimer.FeedFrom(origin.source, chunk, now) })
+		}
+		if a.sessionStats != nil {
+			a.sessionStats.AddRx(now, len(chunk))
+		}
+		a.recordLocked(recording.KindRx, now, chunk)
+		bin, channel := a.binTransport, a.channel
+		a.streamMutex.Unlock()
+		if !origin.muted {
+			meta := a.dataMeta(seq, chunk)
+			meta.Source = origin.shown
+			meta.LocalPort = origin.localPort
+			meta.Lines = lines
+			meta.Timing = timing
+			a.emitChunk(bin, wsbin.Frame{Channel: channel, Seq: seq, Time: now, Data: chunk}, meta)
+		}
+		a.rxHub.Publish(chunk)
+		a.health.Run(stagePlot, chunk, func() { a.feedPlot(now, chunk) })
+		a.health.Run(stageFrames, chunk, func() { a.feedFrames(now, chunk) })
+		a.health.Run(stageJsonLines, chunk, func() { a.feedJsonLines(now, chunk) })
+		a.health.Run(stageCompare, chunk, func() { a.feedCompare(channel, now, chunk) })
+	}
+}
+
+// 接收处理阶段的名称，用于崩溃记录与 safe-mode 事件
+const (
+	stageRx         = "rx-pipeline" // 内置阶段：7 位处理、回显抑制、接收编码
+	stageClassifier = "classifier-rules"
+	stageLineTiming = "line-timing"
+	stagePlot       = "plot-parser"
+	stageFrames     = "frame-decoder"
+	stageJsonLines  = "json-lines"
+	stageCompare    = "compare"
+)
+
+// SafeModeEvent safe-mode 事件的数据：接收处理阶段崩溃后，用户配置的阶段不再生效
+type SafeModeEvent struct {
+	safemode.Crash
+	Startup  bool     `json:"startup"`  // 上一次运行中崩溃，本次启动时未加载这些阶段；否则为本次运行中崩溃
+	Disabled []string `json:"disabled"` // 未启用的阶段，可通过 ApplyPipelineConfig 等逐个重新启用
+}
+
+// healthPath 返回处理链状态文件的路径，无法确定配置目录时为空 (只在内存中记录)
+func healthPath() string {
+	dir, err := settings.ConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "pipeline-health.json")
+}
+
+// loadPipelineHealth 读取处理链状态并恢复保存的处理阶段；上一次运行中阶段崩溃且没有恢复正常时进入安全模式，
+// 不加载保存的分类规则与行耗时设置 (设置本身保留)，safe-mode 事件在前端加载后发送
+func (a *App) loadPipelineHealth() {
+	health, crash := safemode.Open(healthPath(), 0)
+	a.health = health
+	a.health.OnCrash(a.pipelineCrashed)
+	if crash == nil {
+		a.loadClassifierRules()
+		a.loadLineTiming()
+		return
+	}
+
+	ev := &SafeModeEvent{Crash: *crash, Startup: true, Disabled: []string{}}
+	s := a.settings.Get()
+	if len(s.ClassifierRules) > 0 {
+		ev.Disabled = append(ev.Disabled, stageClassifier)
+	}
+	if s.LineTiming != nil && s.LineTiming.Enabled {
+		ev.Disabled = append(ev.Disabled, stageLineTiming)
+	}
+	a.safeMode = ev
+}
+
+// customStagesLocked 返回当前生效的用户配置阶段，调用方必须持有 a.streamMutex
+func (a *App) customStagesLocked() []string {
+	var stages []string
+	if !a.classifier.Empty() {
+		stages = append(stages, stageClassifier)
+	}
+	if a.lineTimer != nil {
+		stages = append(stages, stageLineTiming)
+	}
+	if a.plotParser != nil {
+		stages = append(stages, stagePlot)
+	}
+	if a.frameCfg != nil {
+		stages = append(stages, stageFrames)
+	}
+	if a.jsonDecoder != nil {
+		stages = append(stages, stageJsonLines)
+	}
+	return stages
+}
+
+// customStages 同 customStagesLocked，自行获取 a.streamMutex
+func (a *App) customStages() []string {
+	a.streamMutex.Lock()
+	defer a.streamMutex.Unlock()
+	return a.customStagesLocked()
+}
+
+// pipelineCrashed 接收处理阶段崩溃 (panic 已恢复并写入崩溃记录)：本次应用后的第一次崩溃关闭全部用户配置的阶段，
+// 发送 safe-mode 事件；之后的崩溃 (内置阶段或尚未重新应用) 只发送 sys-msg
+func (a *App) pipelineCrashed(c safemode.Crash, first bool) {
+	if !first {
+		a.emit("sys-msg", fmt.Sprintf("Pipeline %s", c))
+		return
+	}
+
+	a.streamMutex.Lock()
+	disabled := a.customStagesLocked()
+	a.classifier = nil
+	a.lineTimer = nil
+	a.plotParser = nil
+	a.frameCfg, a.frameSchema, a.frameDecoder = nil, nil, nil
+	a.jsonDecoder, a.jsonPlotKeys = nil, nil
+	a.streamMutex.Unlock()
+	if disabled == nil {
+		disabled = []string{}
+	}
+
+	ev := SafeModeEvent{Crash: c, Disabled: disabled}
+	a.mutex.Lock()
+	a.safeMode = &ev
+	a.mutex.Unlock()
+	a.emit("safe-mode", ev)
+	a.emit("sys-msg", fmt.Sprintf("Safe mode: receive %s; disabled %s", c, strings.Join(disabled, ", ")))
+}
+
+// GetSafeMode 返回安全模式的原因，不在安全模式时返回 nil
+func (a *App) GetSafeMode() *SafeModeEvent {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	return a.safeMode
+}
+
+// emitChunk 发送一个数据块：开启二进制传输时数据经 WebSocket 发送，事件通道只发送 serial-data-meta
+// (与数据帧按 seq 对应)；否则照常发送 serial-data
+func (a *App) emitChunk(bin *wsbin.Server, f wsbin.Frame, meta DataMeta) {
+	if bin == nil {
+		a.emitConn("serial-data", f.Data, meta)
+		return
+	}
+	bin.Send(f)
+	a.emitConn("serial-data-meta", meta)
+}
+
+// dataMeta 构造数据事件的元信息，按需附加预格式化的十六进制与文本表示
+func (a *App) dataMeta(seq uint64, data []byte) DataMeta {
+	meta := DataMeta{Seq: seq}
+
+	a.streamMutex.Lock()
+	enabled, opts := a.formatEnabled, a.formatOpts
+	var hexTrunc bool
+	if enabled && a.hexDumper != nil {
+		// 转储器带有连续偏移状态，需在锁内调用
+		meta.Hex, hexTrunc = a.hexDumper.Dump(data)
+	}
+	a.streamMutex.Unlock()
+
+	if enabled {
+		var textTrunc bool
+		if meta.Hex == "" {
+			meta.Hex, hexTrunc = format.Hex(data, opts)
+		}
+		meta.Text, textTrunc = format.Printable(data)
+		meta.Truncated = hexTrunc || textTrunc
+	}
+	return meta
+}
+
+// SetDataFormatting 开启后，每个 serial-data 事件的元信息中附带后端预先格式化的十六进制字符串
+// 与可打印文本 (不可打印字符显示为 '.')；关闭时事件仍为原始字节
+// groupSize 为每组字节数 (<= 0 表示不分组)
+func (a *App) SetDataFormatting(enabled bool, groupSize int, uppercase bool) {
+	a.streamMutex.Lock()
+	defer a.streamMutex.Unlock()
+
+	a.formatEnabled = enabled
+	a.formatOpts = format.Options{GroupSize: groupSize, Uppercase: uppercase}
+}
+
+// SetHexFormat 设置数据事件中 Hex 字段的多行转储格式 (偏移 + 十六进制 + 可选 ASCII 栏)
+// 需同时通过 SetDataFormatting 开启格式化；BytesPerRow 为 0 时恢复单行分组格式
+// 连续偏移在每次建立连接时归零；诊断包中的 history.hex 使用相同的格式
+func (a *App) SetHexFormat(opts format.DumpOptions) string {
+	if opts.BytesPerRow != 0 {
+		if err := opts.Validate(); err != nil {
+			return "Error: " + err.Error()
+		}
+	}
+
+	a.streamMutex.Lock()
+	defer a.streamMutex.Unlock()
+
+	if opts.BytesPerRow == 0 {
+		a.hexDumper = nil
+	} else {
+		a.hexDumper = format.NewDumper(opts)
+	}
+	return "Success"
+}
+
+// hexDumpOptions 返回当前转储格式，未设置时使用 16 字节/行带 ASCII 栏
+func (a *App) hexDumpOptions() format.DumpOptions {
+	a.streamMutex.Lock()
+	defer a.streamMutex.Unlock()
+
+	if a.hexDumper != nil {
+		return a.hexDumper.Options()
+	}
+	return format.DumpOptions{BytesPerRow: 16, ASCII: true, Uppercase: true}
+}
+
+// SetPlotParser 开启后端绘图解析，protocol 为 "string"、"hex" 或 "both"，空字符串表示关闭
+// 每个解析出的采样点以 plot-sample 事件发送
+func (a *App) SetPlotParser(protocol string) string {
+	a.streamMutex.Lock()
+	defer a.streamMutex.Unlock()
+
+	if protocol == "" {
+		a.plotParser = nil
+		a.health.Applied(a.customStagesLocked())
+		return "Success"
+	}
+	p, err := plot.ParseProtocol(protocol)
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	}
+	a.plotParser = plot.NewParser(p)
+	a.health.Applied(a.customStagesLocked())
+	return "Success"
+}
+
+// feedPlot 将接收数据送入绘图解析器，并把采样点写入正在进行的 CSV 导出
+func (a *App) feedPlot(t time.Time, data []byte) {
+	a.streamMutex.Lock()
+	defer a.streamMutex.Unlock()
+
+	if a.plotParser == nil {
+		return
+	}
+	for _, sample := range a.plotParser.Feed(t, data) {
+		a.emitPlotSampleLocked(sample)
+	}
+}
+
+// emitPlotSampleLocked 发送 plot-sample 事件并写入正在进行的 CSV 导出，调用方必须持有 a.streamMutex
+func (a *App) emitPlotSampleLocked(sample plot.Sample) {
+	a.router.Emit(a.channel, "plot-sample", sample)
+	if a.plotCsv == nil {
+		return
+	}
+	if err := a.writePlotSampleLocked(sample); err != nil {
+		// 写入失败只停止导出，不影响解析
+		a.closePlotCsvLocked()
+		a.emit("plot-csv-error", err.Error())
+	}
+}
+
+// JsonRecordEvent json-record 事件的数据
+type JsonRecordEvent struct {
+	jsonl.Record
+	Time int64 `json:"time"` // 主机接收时间 (Unix 毫秒)
+}
+
+// SetJsonLines 开启或关闭 JSON Lines 解析：接收数据照常发送，每行 JSON 对象额外以 json-record 事件发送
+// (解析后的字段与原始行)。flatten 为 true 时嵌套对象展开为 "a.b" 形式的键。
+// 非 JSON 的行与解析失败的行只计数，不影响之后的行；出现过的键见 GetJsonSchema。
+// 立即作用于当前连接 (清空已有的键集合与统计) 并保持到之后的连接
+func (a *App) SetJsonLines(enabled bool, flatten bool) string {
+	a.streamMutex.Lock()
+	defer a.streamMutex.Unlock()
+
+	if !enabled {
+		a.jsonDecoder = nil
+		a.health.Applied(a.customStagesLocked())
+		return "Success"
+	}
+	a.jsonDecoder = jsonl.New(jsonl.Options{Flatten: flatten})
+	a.health.Applied(a.customStagesLocked())
+	return "Success"
+}
+
+// GetJsonSchema 返回当前连接中 JSON 记录出现过的键 (按首次出现的顺序) 及其类型，以及记录数与失败数
+func (a *App) GetJsonSchema() (jsonl.Schema, error) {
+	a.streamMutex.Lock()
+	defer a.streamMutex.Unlock()
+
+	if a.jsonDecoder == nil {
+		return jsonl.Schema{}, fmt.Errorf("JSON lines decoding is not enabled")
+	}
+	return a.jsonDecoder.Schema(), nil
+}
+
+// SetJsonPlotKeys 选择作为绘图通道的 JSON 键 (第 i 个键对应第 i 个通道，可用 "a.b" 访问嵌套对象)：
+// 每条含其中任一键的记录发送一个 plot-sample 并写入 CSV 导出，缺失或非数值的键在 CSV 中留空。
+// 与 SetPlotParser 的协议解析相互独立；keys 为空时停止。StartPlotCsv 未给出列名时使用这些键
+func (a *App) SetJsonPlotKeys(keys []string) string {
+	clean := make([]string, 0, len(keys))
+	for _, k := range keys {
+		if k = strings.TrimSpace(k); k == "" {
+			return "Error: JSON plot keys must not be empty"
+		}
+		clean = append(clean, k)
+	}
+
+	a.streamMutex.Lock()
+	defer a.streamMutex.Unlock()
+	if len(clean) == 0 {
+		a.jsonPlotKeys = nil
+	} else {
+		a.jsonPlotKeys = clean
+	}
+	return "Success"
+}
+
+// feedJsonLines 将接收数据送入 JSON Lines 解析器
+func (a *App) feedJsonLines(t time.Time, data []byte) {
+	a.streamMutex.Lock()
+	defer a.streamMutex.Unlock()
+
+	if a.jsonDecoder == nil {
+		return
+	}
+	for _, rec := range a.jsonDecoder.Feed(data) {
+		a.router.Emit(a.channel, "json-record", JsonRecordEvent{Record: rec, Time: t.UnixMilli()})
+		if len(a.jsonPlotKeys) == 0 {
+			continue
+		}
+		if values, ok := jsonl.Values(rec.Fields, a.jsonPlotKeys); ok {
+			a.emitPlotSampleLocked(plot.Sample{Time: t, Values: values})
+		}
+	}
+}
+
+// CompareSummary compare-summary 事件的数据，比较结束时发送
+type CompareSummary struct {
+	compare.Summary
+	A      string `json:"a"`      // A 侧通道 ID
+	B      string `json:"b"`      // B 侧通道 ID
+	Reason string `json:"reason"` // "stopped" 或 "connection closed"
+}
+
+// StartCompare 开始逐行比较两个连接 (通道 ID，见 SubscribeChannel) 的接收数据，用于对比两个固件版本的行为：
+// mode 为 "lockstep" 时第 N 行与第 N 行配对，为 "time" 时与另一侧 500 ms 内时间最接近的行配对。
+// 每个配对以 compare-row 事件发送 (附带累计的匹配/不匹配数)，无法配对的行以单侧行发送。
+// StopCompare 或任一连接关闭时结束，并以 compare-summary 事件发送最终结果
+func (a *App) StartCompare(connA string, connB string, mode string) string {
+	m, err := compare.ParseMode(mode)
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	}
+	if connA == connB {
+		return "Error: compare needs two different connections"
+	}
+	for _, ch := range []string{connA, connB} {
+		if !a.router.Has(ch) {
+			return fmt.Sprintf("Error: unknown connection %q", ch)
+		}
+	}
+
+	a.streamMutex.Lock()
+	defer a.streamMutex.Unlock()
+
+	if a.comparer != nil {
+		a.stopCompareLocked("stopped")
+	}
+	a.comparer = compare.New(compare.Config{Mode: m})
+	a.compareChannels = [2]string{connA, connB}
+	if m == compare.Time {
+		a.compareStop = make(chan struct{})
+		go a.compareExpireLoop(a.comparer, a.compareStop)
+	}
+	return "Success"
+}
+
+// StopCompare 结束比较，返回最终结果的说明
+func (a *App) StopCompare() string {
+	a.streamMutex.Lock()
+	defer a.streamMutex.Unlock()
+
+	if a.comparer == nil {
+		return "Error: compare is not running"
+	}
+	sum := a.stopCompareLocked("stopped")
+	return fmt.Sprintf("Success (%d matched, %d mismatched, %d only in A, %d only in B)",
+		sum.Matched, sum.Mismatched, sum.OnlyA, sum.OnlyB)
+}
+
+// stopCompareLocked 输出剩余的行并发送 compare-summary，调用方持有 streamMutex 且比较正在进行
+func (a *App) stopCompareLocked(reason string) compare.Summary {
+	if a.compareStop != nil {
+		close(a.compareStop)
+		a.compareStop = nil
+	}
+	rows, sum := a.comparer.Finish(time.Now())
+	for _, r := range rows {
+		a.emit("compare-row", r)
+	}
+	a.emit("compare-summary", CompareSummary{Summary: sum, A: a.compareChannels[0], B: a.compareChannels[1], Reason: reason})
+	a.comparer = nil
+	a.compareChannels = [2]string{}
+	return sum
+}
+
+// closeCompareLocked 在连接关闭 (移除通道) 时结束使用该通道的比较，调用方持有 streamMutex
+func (a *App) closeCompareLocked(channel string) {
+	if a.comparer != nil && channel != "" && (a.compareChannels[0] == channel || a.compareChannels[1] == channel) {
+		a.stopCompareLocked("connection closed")
+	}
+}
+
+// feedCompare 将 channel 接收的数据送入比较
+func (a *App) feedCompare(channel string, t time.Time, data []byte) {
+	a.streamMutex.Lock()
+	defer a.streamMutex.Unlock()
+
+	if a.comparer == nil {
+		return
+	}
+	side := compare.A
+	switch channel {
+	case a.compareChannels[0]:
+	case a.compareChannels[1]:
+		side = compare.B
+	default:
+		return
+	}
+	for _, r := range a.comparer.Feed(side, t, data) {
+		a.emit("compare-row", r)
+	}
+}
+
+// compareExpireLoop 在 time 模式下定期输出窗口已过的单侧行，c 被替换或 stop 关闭时退出
+func (a *App) compareExpireLoop(c *compare.Comparer, stop chan struct{}) {
+	ticker := time.NewTicker(c.Config().Window / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case now := <-ticker.C:
+			a.streamMutex.Lock()
+			if a.comparer == c {
+				for _, r := range c.Expire(now) {
+					a.emit("compare-row", r)
+				}
+			}
+			a.streamMutex.Unlock()
+		}
+	}
+}
+
+// FrameEvent frame 事件的数据
+type FrameEvent struct {
+	frame.Frame
+	Time   int64              `json:"time"`             // 主机接收时间 (Unix 毫秒)
+	Fields []frame.FieldValue `json:"fields,omitempty"` // 按 SetFrameSchema 的字段表解码的字段
+}
+
+// SetFrameDecoder 开启长度前缀帧解码：接收数据照常以 serial-data 发送，解出的每一帧额外发送 frame 事件
+// cfg.Checksum 为负载的校验算法 (见 checksum 包，例如 "crc8-maxim"、"fletcher16")；
+// 校验失败的帧计入统计，emitBad 为 true 时仍以 badChecksum 标记发送，便于调试
+// 立即作用于当前连接 (丢弃未完成的帧) 并保持到之后的连接
+// 已设置字段表时，字段表必须放得下新格式的最大负载
+func (a *App) SetFrameDecoder(cfg frame.Config, emitBad bool) string {
+	d, err := frame.NewDecoder(cfg)
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	}
+
+	a.streamMutex.Lock()
+	defer a.streamMutex.Unlock()
+
+	if a.frameSchema != nil {
+		if err := a.frameSchema.Validate(cfg.PayloadLimit()); err != nil {
+			return fmt.Sprintf("Error: frame schema does not fit this format: %v", err)
+		}
+	}
+	a.frameCfg = &cfg
+	a.frameEmitBad = emitBad
+	a.frameDecoder = d
+	a.health.Applied(a.customStagesLocked())
+	return "Success"
+}
+
+// SetFrameSchema 设置帧负载的字段表 (JSON，格式见 frame.Schema)：之后每个 frame 事件额外携带按名称解码的字段，
+// 字段类型为 u8/i8、u16le/u16be/i16le/i16be、u32le/u32be/i32le/i32be、f32le/f32be/f64le/f64be、
+// cstring 与 bytes (需要 length)，数值乘以 scale 并附带 unit。plot 为 true 的数值字段按顺序作为绘图通道
+// 发送 plot-sample 并写入 CSV 导出 (StartPlotCsv 未给出列名时以字段名为列名)
+// 重名、重叠或超出当前帧格式最大负载的字段在设置时报错；立即作用于之后的帧，不需要重新连接。
+// schemaJSON 为空时清除字段表。随连接配置保存时使用 frame-decoder 动作的 schema 字段
+func (a *App) SetFrameSchema(schemaJSON string) string {
+	if strings.TrimSpace(schemaJSON) == "" {
+		a.streamMutex.Lock()
+		a.frameSchema = nil
+		a.streamMutex.Unlock()
+		return "Success"
+	}
+	var schema frame.Schema
+	dec := json.NewDecoder(strings.NewReader(schemaJSON))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&schema); err != nil {
+		return fmt.Sprintf("Error: invalid frame schema: %v", err)
+	}
+	return a.applyFrameSchema(&schema)
+}
+
+// applyFrameSchema 按当前帧格式校验并设置字段表
+func (a *App) applyFrameSchema(schema *frame.Schema) string {
+	a.streamMutex.Lock()
+	defer a.streamMutex.Unlock()
+
+	limit := 0
+	if a.frameCfg != nil {
+		limit = a.frameCfg.PayloadLimit()
+	}
+	if err := schema.Validate(limit); err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	}
+	a.frameSchema = schema
+	return "Success"
+}
+
+// GetFrameSchema 返回当前的字段表，未设置时字段列表为空
+func (a *App) GetFrameSchema() frame.Schema {
+	a.streamMutex.Lock()
+	defer a.streamMutex.Unlock()
+
+	if a.frameSchema == nil {
+		return frame.Schema{Fields: []frame.Field{}}
+	}
+	return *a.frameSchema
+}
+
+// DisableFrameDecoder 关闭长度前缀帧解码
+func (a *App) DisableFrameDecoder() {
+	a.streamMutex.Lock()
+	defer a.streamMutex.Unlock()
+
+	a.frameCfg = nil
+	a.frameDecoder = nil
+	a.health.Applied(a.customStagesLocked())
+}
+
+// GetFrameStats 返回当前连接的帧解码统计，未开启时为零值
+func (a *App) GetFrameStats() frame.Stats {
+	a.streamMutex.Lock()
+	defer a.streamMutex.Unlock()
+
+	if a.frameDecoder == nil {
+		return frame.Stats{}
+	}
+	return a.frameDecoder.Stats()
+}
+
+// feedFrames 将接收数据送入帧解码器并发送解出的帧
+func (a *App) feedFrames(t time.Time, data []byte) {
+	a.streamMutex.Lock()
+	defer a.streamMutex.Unlock()
+
+	if a.frameDecoder == nil {
+		return
+	}
+	for _, f := range a.frameDecoder.Feed(data) {
+		if f.BadChecksum && !a.frameEmitBad {
+			continue
+		}
+		ev := FrameEvent{Frame: f, Time: t.UnixMilli()}
+		if a.frameSchema != nil {
+			ev.Fields = a.frameSchema.Decode(f.Payload)
+		}
+		a.router.Emit(a.channel, "frame", ev)
+		if a.frameSchema == nil || f.BadChecksum {
+			continue
+		}
+		if values, ok := a.frameSchema.PlotValues(f.Payload); ok {
+			a.emitPlotSampleLocked(plot.Sample{Time: t, Values: values})
+		}
+	}
+}
+
+// SendFrame 按 SetFrameDecoder 设置的帧格式 (同步头、长度前缀与校验值) 封装后发送 data
+// hexMode 时 data 为十六进制字符串
+func (a *App) SendFrame(data string, hexMode bool) string {
+	defer a.txLanes.Interactive()()
+	payload, err := input.Decode(data, hexMode)
+	if err != nil {
+		return fmt.Sprintf("Error: invalid hex data: %v", err)
+	}
+	a.streamMutex.Lock()
+	cfg := a.frameCfg
+	a.streamMutex.Unlock()
+	if cfg == nil {
+		return "Error: frame format not configured"
+	}
+	framed, err := cfg.Encode(payload)
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	}
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	if result, queued := a.queueSendLocked(framed); queued {
+		return result
+	}
+	return a.checkLinkLocked(a.sendLocked(framed))
+}
+
+// SendWithChecksum 在 data 之后追加 algorithm 的校验值后发送，不加长度前缀
+// algorithm 见 checksum 包，hexMode 时 data 为十六进制字符串
+func (a *App) SendWithChecksum(data string, hexMode bool, algorithm string) string {
+	defer a.txLanes.Interactive()()
+	alg, err := checksum.Parse(algorithm)
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	}
+	payload, err := input.Decode(data, hexMode)
+	if err != nil {
+		return fmt.Sprintf("Error: invalid hex data: %v", err)
+	}
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	payload = alg.Append(payload)
+	if result, queued := a.queueSendLocked(payload); queued {
+		return result
+	}
+	return a.checkLinkLocked(a.sendLocked(payload))
+}
+
+// writePlotSampleLocked 写入 CSV 并按间隔刷新压缩流，调用方必须持有 a.streamMutex
+func (a *App) writePlotSampleLocked(sample plot.Sample) error {
+	if err := a.plotCsv.WriteSample(sample); err != nil {
+		return err
+	}
+	return a.plotFile.Flush()
+}
+
+// AddAnnotation 在接收数据流中插入一条带时间戳的标注 (例如 "=== power cycled DUT ===")
+// 标注记录到历史缓冲区与正在进行的 CSV 导出，并发送 annotation 事件，不会发送到连接
+func (a *App) AddAnnotation(text string) string {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return "Error: annotation text is empty"
+	}
+	a.annotate(time.Now(), text)
+	return "Success"
+}
+
+// annotate 记录标注并发送 annotation 事件
+func (a *App) annotate(now time.Time, text string) {
+	seq := a.history.AppendAnnotation(now, text)
+
+	a.streamMutex.Lock()
+	if a.plotCsv != nil {
+		if err := a.plotCsv.WriteAnnotation(now, text); err != nil {
+			a.closePlotCsvLocked()
+			a.emit("plot-csv-error", err.Error())
+		}
+	}
+	a.recordLocked(recording.KindNote, now, []byte(text))
+	a.streamMutex.Unlock()
+
+	a.emit("annotation", newAnnotationEvent(seq, now, text))
+}
+
+// emitSessionSummaryLocked 生成当前连接的会话摘要：发送 session-summary 事件，
+// 作为标注写入历史缓冲区与正在进行的 CSV 导出，并保存供诊断包使用
+// 调用方必须持有 a.mutex，且连接资源尚未释放
+func (a *App) emitSessionSummaryLocked() {
+	a.streamMutex.Lock()
+	stats := a.sessionStats
+	a.sessionStats = nil
+	a.streamMutex.Unlock()
+	if stats == nil {
+		return
+	}
+
+	now := time.Now()
+	sum := stats.Summary(now)
+	sum.Type = string(a.connType)
+	sum.Drops = a.history.Range().EvictedBytes
+	a.lastSession = &sum
+
+	a.emitConn("session-summary", sum)
+	a.annotate(now, sum.String())
+}
+
+// GetLastSession 返回上一次连接断开时的会话摘要，尚无时返回 nil
+func (a *App) GetLastSession() *session.Summary {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	return a.lastSession
+}
+
+func newAnnotationEvent(seq uint64, t time.Time, text string) AnnotationEvent {
+	return AnnotationEvent{
+		Seq:  seq,
+		Time: t.UnixMilli(),
+		Text: text,
+		Line: format.AnnotationLine(t, text),
+	}
+}
+
+// SetLogHeaderTemplate 设置日志文件头模板，支持与发送模板相同的 {{...}} 占位符 ({{ts_iso}}、{{ts_ms}}、{{rand:N}})
+// 以及 {{app}}、{{version}}、{{connection}}、{{pipeline}}，详见 loghdr 包；为空时恢复默认模板
+// 文件头写在之后开始的 CSV 导出与 PCAP 抓包的开头，并在建立连接时作为标注显示，不会发送到连接
+func (a *App) SetLogHeaderTemplate(tmpl string) string {
+	if len(tmpl) > loghdr.MaxTemplateLen {
+		return fmt.Sprintf("Error: template exceeds %d bytes", loghdr.MaxTemplateLen)
+	}
+	if _, err := loghdr.Render(tmpl, loghdr.Info{Time: time.Now()}); err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	}
+	if err := a.settings.Update(func(s *settings.Settings) {
+		s.LogHeaderTemplate = tmpl
+	}); err != nil {
+		return fmt.Sprintf("Error saving settings: %v", err)
+	}
+	return "Success"
+}
+
+// GetLogHeader 按当前模板生成文件头，用于预览
+func (a *App) GetLogHeader() string {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	return a.logHeaderLocked(time.Now())
+}
+
+// logHeaderLocked 按模板生成文件头，调用方必须持有 a.mutex (不能持有 streamMutex)
+func (a *App) logHeaderLocked(now time.Time) string {
+	info := loghdr.Info{
+		App:        appName,
+		Version:    Version,
+		Time:       now,
+		Connection: a.connSpec,
+		Pipeline:   a.pipelineConfigLocked().Summary(),
+	}
+	header, err := loghdr.Render(a.settings.Get().LogHeaderTemplate, info)
+	if err != nil {
+		// 模板在保存时已校验，这里只可能是配置文件被手动修改
+		header, _ = loghdr.Render("", info)
+	}
+	return header
+}
+
+// 使用文件名模板的功能，SetFileNameTemplate 按这些名称保存默认模板
+const (
+	FilePlotCSV     = "plot-csv"
+	FilePcap        = "pcap"
+	FileCommands    = "commands"
+	FileDiagnostics = "diagnostics"
+	FileHTML        = "html"
+	FileRecording   = "recording"
+	FileLines       = "lines"
+	FileBurst       = "burst"
+)
+
+var fileFeatures = map[string]bool{FilePlotCSV: true, FilePcap: true, FileCommands: true, FileDiagnostics: true, FileHTML: true, FileRecording: true, FileLines: true, FileBurst: true}
+
+// outputPathLocked 将 path (可含 {{port}}、{{date}}、{{time}}、{{n}} 占位符，见 nametmpl 包) 展开为实际路径并创建目录
+// path 为空时使用该功能保存的默认模板；调用方必须持有 a.mutex
+func (a *App) outputPathLocked(feature, path string) (string, error) {
+	path, vars, err := a.fileTemplateLocked(feature, path)
+	if err != nil {
+		return "", err
+	}
+	return nametmpl.Resolve(path, vars)
+}
+
+// fileTemplateLocked 返回 path (为空时为该功能保存的默认模板) 及当前连接的占位符取值，调用方必须持有 a.mutex
+func (a *App) fileTemplateLocked(feature, path string) (string, nametmpl.Vars, error) {
+	names := a.settings.Get().FileNames
+	if names == nil {
+		names = &settings.FileNames{}
+	}
+	if path == "" {
+		path = names.Templates[feature]
+	}
+	if path == "" {
+		return "", nametmpl.Vars{}, fmt.Errorf("no file name given and no default template for %s", feature)
+	}
+	port := a.connSpec
+	if cs, err := connspec.Parse(a.connSpec); err == nil {
+		port = cs.Endpoint()
+	}
+	return path, nametmpl.Vars{
+		Port:       port,
+		Time:       time.Now(),
+		DateLayout: names.DateLayout,
+		TimeLayout: names.TimeLayout,
+	}, nil
+}
+
+// outputPath 同 outputPathLocked，自行获取 a.mutex
+func (a *App) outputPath(feature, path string) (string, error) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	return a.outputPathLocked(feature, path)
+}
+
+// BurstCaptureEvent burst-capture 事件的数据，每次捕获开始 (status 为 "started") 与结束 ("done" 或 "failed") 时发送
+type BurstCaptureEvent struct {
+	burst.Report
+	Armed bool `json:"armed"` // 之后仍会触发；达到捕获数上限后为 false
+}
+
+// ArmBurstCapture 开启触发捕获：接收数据中出现 pattern (正则表达式) 时，把触发前 preMs 内的数据 (取自历史缓冲区)
+// 与触发后 postMs 内收到的原始数据写入一个文件，之后重新等待触发，最多 burst.DefaultMaxCaptures 次。
+// 捕获进行中再次触发时延长到该次触发后 postMs，而不是开始新的文件。
+// path 可以是文件名模板 (见 nametmpl 包)，为空时使用 burst 的默认模板；不含占位符时自动加上
+// "_{{date}}_{{time}}_{{n}}"，每次捕获写入带时间戳的新文件。再次调用替换之前的设置，进行中的捕获先结束
+func (a *App) ArmBurstCapture(pattern string, preMs int, postMs int, path string) string {
+	cfg := burst.Config{Pattern: pattern, Pre: time.Duration(preMs) * time.Millisecond, Post: time.Duration(postMs) * time.Millisecond}
+	if err := cfg.Validate(); err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	}
+
+	a.mutex.Lock()
+	tmpl, vars, err := a.fileTemplateLocked(FileBurst, path)
+	a.mutex.Unlock()
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	}
+	if !strings.Contains(tmpl, "{{") {
+		ext := filepath.Ext(tmpl)
+		tmpl = strings.TrimSuffix(tmpl, ext) + "_{{date}}_{{time}}_{{n}}" + ext
+	}
+	if err := nametmpl.Validate(tmpl); err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	}
+
+	// 在读取路径中调用，此时持有 a.streamMutex
+	rec, err := burst.New(cfg, func(index int, t time.Time) (io.WriteCloser, string, error) {
+		v := vars
+		v.Time = t
+		name, err := nametmpl.Resolve(tmpl, v)
+		if err != nil {
+			return nil, "", err
+		}
+		f, err := logfile.Create(name, a.captureCompress)
+		if err != nil {
+			return nil, "", err
+		}
+		return f, f.Path(), nil
+	})
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	}
+
+	a.streamMutex.Lock()
+	defer a.streamMutex.Unlock()
+	a.disarmBurstLocked()
+	a.burst = rec
+	return "Success"
+}
+
+// DisarmBurstCapture 关闭触发捕获，进行中的捕获立即结束
+func (a *App) DisarmBurstCapture() string {
+	a.streamMutex.Lock()
+	defer a.streamMutex.Unlock()
+
+	if a.burst == nil {
+		return "Not armed"
+	}
+	a.disarmBurstLocked()
+	return "Success"
+}
+
+// disarmBurstLocked 结束进行中的捕获并关闭触发，调用方必须持有 a.streamMutex
+func (a *App) disarmBurstLocked() {
+	if a.burst == nil {
+		return
+	}
+	if a.burstTimer != nil {
+		a.burstTimer.Stop()
+		a.burstTimer = nil
+	}
+	rep, ok := a.burst.Close()
+	a.burst = nil
+	if ok {
+		a.emit("burst-capture", BurstCaptureEvent{Report: rep})
+	}
+}
+
+// feedBurstLocked 把接收数据送入触发捕获，seq 为该数据块在历史缓冲区中的序号，调用方必须持有 a.streamMutex
+func (a *App) feedBurstLocked(seq uint64, t time.Time, chunk []byte) {
+	if a.burst == nil {
+		return
+	}
+	reports := a.burst.Feed(t, chunk, func(from time.Time) [][]byte {
+		entries, _ := a.history.From(0)
+		var pre [][]byte
+		for _, e := range entries {
+			if e.Seq < seq && e.Annotation == "" && !e.Time.Before(from) {
+				pre = append(pre, e.Data)
+			}
+		}
+		return pre
+	})
+	a.burstReportsLocked(reports)
+}
+
+// burstReportsLocked 发送捕获的状态变化，达到上限时关闭触发，否则按进行中捕获的结束时间设置计时器
+// 调用方必须持有 a.streamMutex
+func (a *App) burstReportsLocked(reports []burst.Report) {
+	rec := a.burst
+	armed := !rec.Exhausted()
+	for _, rep := range reports {
+		a.emit("burst-capture", BurstCaptureEvent{Report: rep, Armed: armed})
+	}
+	if !armed {
+		a.disarmBurstLocked()
+		return
+	}
+	deadline, ok := rec.Deadline()
+	if !ok {
+		return
+	}
+	wait := time.Until(deadline) + time.Millisecond
+	if a.burstTimer == nil {
+		a.burstTimer = time.AfterFunc(wait, func() { a.burstTick(rec) })
+	} else {
+		a.burstTimer.Reset(wait)
+	}
+}
+
+// burstTick 没有新数据时按时结束进行中的捕获
+func (a *App) burstTick(rec *burst.Recorder) {
+	a.streamMutex.Lock()
+	defer a.streamMutex.Unlock()
+
+	if a.burst != rec {
+		return
+	}
+	if rep, ok := rec.Tick(time.Now()); ok {
+		a.burstReportsLocked([]burst.Report{rep})
+		return
+	}
+	a.burstReportsLocked(nil)
+}
+
+// GetFileNameSettings 返回各功能的默认文件名模板与日期/时间格式
+func (a *App) GetFileNameSettings() settings.FileNames {
+	out := settings.FileNames{Templates: map[string]string{}, DateLayout: nametmpl.DefaultDateLayout, TimeLayout: nametmpl.DefaultTimeLayout}
+	if names := a.settings.Get().FileNames; names != nil {
+		for k, v := range names.Templates {
+			out.Templates[k] = v
+		}
+		if names.DateLayout != "" {
+			out.DateLayout = names.DateLayout
+		}
+		if names.TimeLayout != "" {
+			out.TimeLayout = names.TimeLayout
+		}
+	}
+	return out
+}
+
+// SetFileNameTemplate 设置功能 (plot-csv、pcap、commands、diagnostics、html、recording、lines、burst) 的默认文件名模板，为空时清除
+// 对应的导出方法以空路径调用时使用该模板
+func (a *App) SetFileNameTemplate(feature string, tmpl string) string {
+	if !fileFeatures[feature] {
+		return fmt.Sprintf("Error: unknown feature %q (expected plot-csv, pcap, commands, diagnostics, html, recording, lines or burst)", feature)
+	}
+	if tmpl != "" {
+		if err := nametmpl.Validate(tmpl); err != nil {
+			return fmt.Sprintf("Error: %v", err)
+		}
+	}
+	if err := a.settings.Update(func(s *settings.Settings) {
+		if s.FileNames == nil {
+			s.FileNames = &settings.FileNames{}
+		}
+		if tmpl == "" {
+			delete(s.FileNames.Templates, feature)
+			return
+		}
+		if s.FileNames.Templates == nil {
+			s.FileNames.Templates = map[string]string{}
+		}
+		s.FileNames.Templates[feature] = tmpl
+	}); err != nil {
+		return fmt.Sprintf("Error saving settings: %v", err)
+	}
+	return "Success"
+}
+
+// SetFileNameLayouts 设置 {{date}} 与 {{time}} 的格式 (Go 时间格式，例如 "20060102")，为空时恢复默认值
+func (a *App) SetFileNameLayouts(dateLayout string, timeLayout string) string {
+	if err := a.settings.Update(func(s *settings.Settings) {
+		if s.FileNames == nil {
+			s.FileNames = &settings.FileNames{}
+		}
+		s.FileNames.DateLayout = dateLayout
+		s.FileNames.TimeLayout = timeLayout
+	}); err != nil {
+		return fmt.Sprintf("Error saving settings: %v", err)
+	}
+	return "Success"
+}
+
+// StartPlotCsv 开始将 plot-sample 采样点导出到 CSV 文件
+// columns 为通道列名，第 i 列对应第 i 个通道；导出期间列集合不可更改，
+// 已有导出进行时调用会返回错误，需先 StopPlotCsv 再以新的列开始新文件
+// path 可以是文件名模板 (见 nametmpl 包)，为空时使用 plot-csv 的默认模板；返回实际写入的文件路径
+// columns 为空时使用 SetJsonPlotKeys 选择的 JSON 键，没有时使用 SetFrameSchema 中的绘图字段名
+func (a *App) StartPlotCsv(path string, columns []string) (string, error) {
+	if len(columns) == 0 {
+		a.streamMutex.Lock()
+		columns = append([]string(nil), a.jsonPlotKeys...)
+		if len(columns) == 0 && a.frameSchema != nil {
+			columns = a.frameSchema.PlotFields()
+		}
+		a.streamMutex.Unlock()
+	}
+	if len(columns) == 0 {
+		return "", fmt.Errorf("at least one column is required")
+	}
+	a.mutex.Lock()
+	header := a.logHeaderLocked(time.Now())
+	path, err := a.outputPathLocked(FilePlotCSV, path)
+	a.mutex.Unlock()
+	if err != nil {
+		return "", err
+	}
+
+	a.streamMutex.Lock()
+	defer a.streamMutex.Unlock()
+
+	if a.plotCsv != nil {
+		return "", fmt.Errorf("CSV export already running, stop it before changing columns")
+	}
+	f, err := logfile.Create(path, a.captureCompress)
+	if err != nil {
+		return "", fmt.Errorf("error creating CSV file: %w", err)
+	}
+	cw, err := plot.NewCommentedCSVWriter(f, columns, loghdr.Lines(header))
+	if err != nil {
+		f.Close()
+		return "", fmt.Errorf("error writing CSV header: %w", err)
+	}
+	a.plotCsv = cw
+	a.plotFile = f
+	if f.Compressed() {
+		a.emit("sys-msg", fmt.Sprintf("Writing compressed CSV to %s", f.Path()))
+	}
+	return f.Path(), nil
+}
+
+// StopPlotCsv 结束 CSV 导出，写入剩余数据并关闭文件
+func (a *App) StopPlotCsv() string {
+	a.streamMutex.Lock()
+	defer a.streamMutex.Unlock()
+
+	if a.plotCsv == nil {
+		return "Not exporting"
+	}
+	if err := a.closePlotCsvLocked(); err != nil {
+		return fmt.Sprintf("Error closing CSV file: %v", err)
+	}
+	return "Success"
+}
+
+// closePlotCsvLocked 刷新并关闭 CSV 文件，调用方必须持有 a.streamMutex
+func (a *App) closePlotCsvLocked() error {
+	err := a.plotCsv.Flush()
+	if closeErr := a.plotFile.Close(); err == nil {
+		err = closeErr
+	}
+	a.plotCsv = nil
+	a.plotFile = nil
+	return err
+}
+
+// StartPcapCapture 将当前 TCP/UDP 连接的收发数据保存为 pcapng 文件，可直接用 Wireshark 打开
+// 以太网/IP/TCP/UDP 头部根据两端地址合成，每次 Read/Write 为一条记录 (不是真实的 TCP 报文边界)，
+// TCP 序号连续，Wireshark 可以正常重组数据流。断开连接时自动结束抓包
+// path 可以是文件名模板 (见 nametmpl 包)，为空时使用 pcap 的默认模板；返回实际写入的文件路径
+func (a *App) StartPcapCapture(path string) (string, error) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	if !a.isConnected {
+		return "", fmt.Errorf("not connected")
+	}
+	switch a.connType {
+	case TypeTcpClient, TypeTcpServer, TypeUdp:
+	default:
+		return "", fmt.Errorf("PCAP capture is not available for %s connections", a.connType)
+	}
+
+	header := a.logHeaderLocked(time.Now())
+
+	a.streamMutex.Lock()
+	defer a.streamMutex.Unlock()
+
+	if a.pcapWriter != nil {
+		return "", fmt.Errorf("PCAP capture already running")
+	}
+	path, err := a.outputPathLocked(FilePcap, path)
+	if err != nil {
+		return "", err
+	}
+	f, err := logfile.Create(path, a.captureCompress)
+	if err != nil {
+		return "", fmt.Errorf("error creating PCAP file: %w", err)
+	}
+	w, err := pcap.NewCommentedWriter(f, a.router.Identity(a.channel).Label, header)
+	if err != nil {
+		f.Close()
+		return "", fmt.Errorf("error writing PCAP header: %w", err)
+	}
+	a.pcapWriter = w
+	a.pcapFile = f
+	if f.Compressed() {
+		a.emit("sys-msg", fmt.Sprintf("Writing compressed capture to %s", f.Path()))
+	}
+	return f.Path(), nil
+}
+
+// SetCaptureCompression 开启后，之后开始的 CSV 导出与 PCAP 抓包以 gzip 压缩写入 (文件名追加 ".gz")
+// 压缩流至少每秒刷新一次，程序崩溃时已刷新的部分仍可解压；正在进行的导出不受影响
+func (a *App) SetCaptureCompression(enabled bool) {
+	a.streamMutex.Lock()
+	defer a.streamMutex.Unlock()
+
+	a.captureCompress = enabled
+}
+
+// StopPcapCapture 结束抓包并关闭文件
+func (a *App) StopPcapCapture() string {
+	a.streamMutex.Lock()
+	defer a.streamMutex.Unlock()
+
+	if a.pcapWriter == nil {
+		return "Not capturing"
+	}
+	if err := a.closePcapLocked(); err != nil {
+		return fmt.Sprintf("Error closing PCAP file: %v", err)
+	}
+	return "Success"
+}
+
+// capturePacket 记录一次网络收发，写入失败时停止抓包
+func (a *App) capturePacket(local, remote net.Addr, outbound bool, data []byte) {
+	if len(data) == 0 {
+		return
+	}
+	a.streamMutex.Lock()
+	defer a.streamMutex.Unlock()
+
+	if a.pcapWriter == nil {
+		return
+	}
+	err := a.pcapWriter.WritePacket(time.Now(), local, remote, outbound, data)
+	if err == nil {
+		err = a.pcapWriter.Flush()
+	}
+	if err == nil {
+		err = a.pcapFile.Flush()
+	}
+	if err != nil {
+		a.closePcapLocked()
+		a.emit("sys-msg", fmt.Sprintf("PCAP capture stopped: %v", err))
+	}
+}
+
+// closePcapLocked 刷新并关闭抓包文件，调用方必须持有 a.streamMutex
+func (a *App) closePcapLocked() error {
+	err := a.pcapWriter.Flush()
+	if closeErr := a.pcapFile.Close(); err == nil {
+		err = closeErr
+	}
+	a.pcapWriter = nil
+	a.pcapFile = nil
+	return err
+}
+
+// StartRecording 将当前连接的收发数据与标注写入会话录制文件 (格式见 recording 包)
+// 每条记录带 CRC 并以一次写入落盘，程序崩溃时至多丢失最后一条记录；按 SetRecordingSyncInterval 的间隔 fsync
+// 断开连接时自动结束。path 可以是文件名模板 (见 nametmpl 包)，为空时使用 recording 的默认模板；返回实际写入的文件路径
+func (a *App) StartRecording(path string) (string, error) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	if !a.isConnected {
+		return "", fmt.Errorf("not connected")
+	}
+
+	a.streamMutex.Lock()
+	defer a.streamMutex.Unlock()
+
+	if a.recorder != nil {
+		return "", fmt.Errorf("recording already running")
+	}
+	path, err := a.outputPathLocked(FileRecording, path)
+	if err != nil {
+		return "", err
+	}
+	interval := a.recordSync
+	if interval == 0 {
+		interval = -1 // 每条记录都 fsync
+	}
+	w, err := recording.Create(path, recording.Options{SyncInterval: interval})
+	if err != nil {
+		return "", fmt.Errorf("error creating recording: %w", err)
+	}
+	a.recorder = w
+	return path, nil
+}
+
+// StopRecording 结束录制，写入最终索引并关闭文件
+func (a *App) StopRecording() string {
+	a.streamMutex.Lock()
+	defer a.streamMutex.Unlock()
+
+	if a.recorder == nil {
+		return "Not recording"
+	}
+	n := a.recorder.Count()
+	if err := a.closeRecordingLocked(); err != nil {
+		return fmt.Sprintf("Error closing recording: %v", err)
+	}
+	return fmt.Sprintf("Success (%d records)", n)
+}
+
+// SetRecordingSyncInterval 设置录制文件的 fsync 间隔 (毫秒)，0 表示每条记录都 fsync，默认 1000
+// 间隔越长写入开销越小，但操作系统崩溃或断电时丢失的数据越多；之后开始的录制生效
+func (a *App) SetRecordingSyncInterval(ms int) string {
+	if ms < 0 || ms > 60000 {
+		return fmt.Sprintf("Error: interval must be between 0 and 60000 ms, got %d", ms)
+	}
+	a.streamMutex.Lock()
+	defer a.streamMutex.Unlock()
+
+	a.recordSync = time.Duration(ms) * time.Millisecond
+	return "Success"
+}
+
+// VerifyRecording 校验录制文件：跳过损坏与末尾不完整的记录，返回恢复的记录数、
+// 周期索引给出的记录数、丢失数以及录制是否正常结束
+func (a *App) VerifyRecording(path string) (recording.Integrity, error) {
+	return recording.Verify(path)
+}
+
+// RecordingLoad LoadRecording 的返回结果
+type RecordingLoad struct {
+	Range     history.Range       `json:"range"`
+	Integrity recording.Integrity `json:"integrity"`
+}
+
+// LoadRecording 校验录制文件并将其中的接收数据与标注载入历史缓冲区 (替换现有历史)，之后可用 RequestReplay 回放或导出
+// 发送记录不载入 (历史缓冲区只保存接收数据)；文件有损坏时仍载入恢复的记录，并以 sys-msg 说明完整性结果
+// 连接期间不可用，同 LoadDemoSession
+func (a *App) LoadRecording(path string) (RecordingLoad, error) {
+	integrity, err := a.VerifyRecording(path)
+	if err != nil {
+		return RecordingLoad{}, err
+	}
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	if a.isConnected {
+		return RecordingLoad{}, fmt.Errorf("close the connection before loading a recording")
+	}
+	a.history.Reset()
+	if _, err := recording.ReadFile(path, func(r recording.Record) error {
+		switch r.Kind {
+		case recording.KindRx:
+			a.history.Append(r.Time, r.Data)
+		case recording.KindNote:
+			a.history.AppendAnnotation(r.Time, string(r.Data))
+		}
+		return nil
+	}); err != nil {
+		return RecordingLoad{}, err
+	}
+	if !integrity.OK {
+		a.emit("sys-msg", "Warning: "+integrity.Summary())
+	}
+	res := RecordingLoad{Range: a.history.Range(), Integrity: integrity}
+	a.emit("backend-has-history", res.Range)
+	return res, nil
+}
+
+// recordLocked 写入一条录制记录，写入失败时停止录制并发送 recording-error；调用方必须持有 a.streamMutex
+func (a *App) recordLocked(kind recording.Kind, t time.Time, data []byte) {
+	if a.recorder == nil {
+		return
+	}
+	if err := a.recorder.Write(kind, t, data); err != nil {
+		a.closeRecordingLocked()
+		a.emit("recording-error", err.Error())
+	}
+}
+
+// closeRecordingLocked 关闭录制文件，调用方必须持有 a.streamMutex
+func (a *App) closeRecordingLocked() error {
+	err := a.recorder.Close()
+	a.recorder = nil
+	return err
+}
+
+func (a *App) startReadLoop(reader io.Reader) {
+	a.markConnected()
+	translate := transport.TranslateError
+	closedByUs := func(error) bool { return false }
+	if a.connType == TypeSerial {
+		translate = serialport.TranslateError
+		closedByUs = serialport.IsClosed
+	}
+
+	stop := a.readStopChan
+	halfClose := a.connType == TypeTcpClient && !a.tcpCloseOnEOF
+	conn, isNet := reader.(net.Conn)
+	q := a.newRxQueue(stop, func(data []byte, _ interface{}) {
+		if isNet {
+			a.capturePacket(conn.LocalAddr(), conn.RemoteAddr(), false, data)
+		}
+		a.emitData(data)
+	})
+	go func() {
+		defer q.stop()
+		buff := make([]byte, 4096)
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				n, err := reader.Read(buff)
+				if err != nil {
+					if halfClose && transport.IsHalfClose(err) {
+						q.flush()
+						a.onRemoteHalfClose(stop)
+						return
+					}
+					if closedByUs(err) {
+						// Close 与阻塞中的 Read 竞争，属于正常断开
+						q.flush()
+						return
+					}
+					if a.isConnected {
+						fmt.Printf("Read Error: %v\n", err)
+						q.flush()
+						a.emitConnError(err.Error(), translate(err))
+						a.Close()
+					}
+					return
+				}
+				if n == 0 {
+					continue
+				}
+
+				dataToSend := make([]byte, n)
+				copy(dataToSend, buff[:n])
+				q.Push(dataToSend, nil)
+			}
+		}
+	}()
+}
+
+// onRemoteHalfClose TCP Client 读到 EOF (对端关闭了写方向) 时停止读取，但保持连接可发送，
+// 直到用户关闭或之后的发送失败；stop 用于确认连接未在此期间被关闭或替换
+func (a *App) onRemoteHalfClose(stop chan struct{}) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	if !a.isConnected || a.readStopChan != stop {
+		return
+	}
+	a.remoteHalfClosed = true
+	a.emitConn("remote-half-closed")
+	a.emit("sys-msg", "Remote closed its side of the connection (FIN received); sending is still possible until you close")
+}
+
+// SetTcpCloseOnEOF 为 true 时 TCP Client 读到 EOF 立即关闭连接 (旧行为)；
+// 默认 false：对端半关闭后发送 remote-half-closed 事件并保持可发送
+func (a *App) SetTcpCloseOnEOF(enabled bool) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.tcpCloseOnEOF = enabled
+}
+
+// rxQueue 读取循环的接收队列：读取 goroutine 只做 Read 与 Push，
+// 处理链、格式化与事件发送在队列的处理 goroutine 中进行，避免处理耗时导致操作系统缓冲区溢出
+type rxQueue struct {
+	*stream.Queue
+	drain bool // 连接关闭时处理完已读取的数据
+}
+
+// newRxQueue 按设置创建接收队列，stop 关闭后阻塞中的 Push 立即返回
+// 队列满而丢弃数据时 (drop 策略) 在处理 goroutine 中提示累计丢弃的块数
+func (a *App) newRxQueue(stop <-chan struct{}, handle func(data []byte, meta interface{})) *rxQueue {
+	cfg := settings.RxQueue{}
+	if s := a.settings.Get().RxQueue; s != nil {
+		cfg = *s
+	}
+	policy, err := stream.ParsePolicy(cfg.Policy)
+	if err != nil {
+		policy = stream.PolicyBlock
+	}
+
+	q := &rxQueue{drain: cfg.DrainOnClose}
+	var reported int64
+	q.Queue = stream.NewQueue(stream.QueueConfig{Depth: cfg.Depth, Policy: policy, Cancel: stop, Pending: &a.rxPending}, func(data []byte, meta interface{}) {
+		if dropped := q.Dropped(); dropped > reported {
+			reported = dropped
+			a.emit("sys-msg", fmt.Sprintf("[RX] 警告：接收队列已满，累计丢弃 %d 个数据块。请降低数据量或在设置中增大接收队列", dropped))
+		}
+		handle(data, meta)
+	})
+	return q
+}
+
+// stop 读取循环退出时调用，按设置处理完或丢弃队列中剩余的数据，不等待
+func (q *rxQueue) stop() {
+	q.Close(q.drain)
+}
+
+// flush 处理完队列中已读取的数据并等待处理 goroutine 退出，
+// 用于读取出错时保证错误事件在最后一批数据之后发送
+func (q *rxQueue) flush() {
+	q.Close(true)
+	<-q.Done()
+}
+
+// SetRxQueue 设置接收队列：depth 为可容纳的数据块数 (0 表示默认 256)，
+// policy 为队列满时的策略，"block" 让读取等待 (不丢数据，但可能让系统缓冲区溢出)，
+// "drop" 丢弃新数据并提示；drainOnClose 为 true 时关闭连接会处理完已读取的数据
+// 新设置在下次建立连接时生效
+func (a *App) SetRxQueue(policy string, depth int, drainOnClose bool) string {
+	p, err := stream.ParsePolicy(policy)
+	if err != nil {
+		return "Error: " + err.Error()
+	}
+	if depth < 0 || depth > stream.MaxQueueDepth {
+		return fmt.Sprintf("Error: queue depth must be between 0 and %d, got %d", stream.MaxQueueDepth, depth)
+	}
+	if err := a.settings.Update(func(s *settings.Settings) {
+		s.RxQueue = &settings.RxQueue{Depth: depth, Policy: string(p), DrainOnClose: drainOnClose}
+	}); err != nil {
+		return fmt.Sprintf("Error saving settings: %v", err)
+	}
+	return "Success"
+}
+
+// GetRxQueue 返回当前的接收队列设置
+func (a *App) GetRxQueue() settings.RxQueue {
+	cfg := settings.RxQueue{Policy: string(stream.PolicyBlock), Depth: stream.DefaultQueueDepth}
+	if s := a.settings.Get().RxQueue; s != nil {
+		cfg.DrainOnClose = s.DrainOnClose
+		if s.Policy != "" {
+			cfg.Policy = s.Policy
+		}
+		if s.Depth > 0 {
+			cfg.Depth = s.Depth
+		}
+	}
+	return cfg
+}
+
+// RequestReplay 重新发送历史缓冲区中从 fromSeq 开始的数据
+// 如果 fromSeq 已被淘汰，则从最早可用的序号开始重发，并在结果中标记 Truncated
+func (a *App) RequestReplay(fromSeq uint64) ReplayResult {
+	entries, truncated := a.history.From(fromSeq)
+	result := ReplayResult{Count: len(entries), Truncated: truncated}
+	if len(entries) > 0 {
+		result.FirstSeq = entries[0].Seq
+		result.LastSeq = entries[len(entries)-1].Seq
+	}
+	// 重发使用独立的行状态，不影响实时数据的分类
+	var tracker classify.Tracker
+	a.streamMutex.Lock()
+	classifier := a.classifier
+	a.streamMutex.Unlock()
+	for _, e := range entries {
+		if e.Annotation != "" {
+			ev := newAnnotationEvent(e.Seq, e.Time, e.Annotation)
+			ev.Replay = true
+			a.emitConn("annotation", ev)
+			continue
+		}
+		a.streamMutex.Lock()
+		filter, show := a.clientFilter, a.udpShowSource || a.serverMode
+		a.streamMutex.Unlock()
+		if filter != "" && e.Source != filter {
+			continue
+		}
+		meta := a.dataMeta(e.Seq, e.Data)
+		meta.Replay = true
+		meta.Lines = tracker.Feed(classifier, e.Data)
+		if show {
+			meta.Source = e.Source
+		}
+		a.streamMutex.Lock()
+		bin, channel := a.binTransport, a.channel
+		a.streamMutex.Unlock()
+		a.emitChunk(bin, wsbin.Frame{Channel: channel, Seq: e.Seq, Time: e.Time, Replay: true, Data: e.Data}, meta)
+	}
+	return result
+}
+
+// BinaryTransportInfo EnableBinaryTransport 的返回值
+type BinaryTransportInfo struct {
+	Enabled   bool   `json:"enabled"`
+	URL       string `json:"url,omitempty"` // WebSocket 地址，重连时附加 "&from=<下一个序号>" 补发断开期间的数据
+	Version   int    `json:"version"`       // 帧格式版本
+	HeaderLen int    `json:"headerLen"`     // 固定头部长度，之后为连接 ID 与数据
+	wsbin.Stats
+}
+
+// EnableBinaryTransport 开启或关闭二进制数据传输。开启后接收数据经本机回环 WebSocket 以二进制帧发送
+// (帧格式见 pkg/wsbin)，serial-data 事件改为只含元信息的 serial-data-meta 事件，其余事件不变；
+// 关闭后恢复为 serial-data 事件。重复开启返回现有服务的地址与统计
+func (a *App) EnableBinaryTransport(enabled bool) (BinaryTransportInfo, error) {
+	info := BinaryTransportInfo{Version: wsbin.Version, HeaderLen: wsbin.HeaderLen}
+
+	a.streamMutex.Lock()
+	bin := a.binTransport
+	if !enabled {
+		a.binTransport = nil
+	}
+	a.streamMutex.Unlock()
+
+	if !enabled {
+		if bin != nil {
+			return info, bin.Close()
+		}
+		return info, nil
+	}
+	if bin == nil {
+		var err error
+		if bin, err = wsbin.Start(a.binaryReplay); err != nil {
+			return info, fmt.Errorf("failed to start binary transport: %w", err)
+		}
+		a.streamMutex.Lock()
+		if a.binTransport != nil {
+			// 并发开启，保留先完成的一个
+			bin.Close()
+			bin = a.binTransport
+		} else {
+			a.binTransport = bin
+		}
+		a.streamMutex.Unlock()
+	}
+	info.Enabled = true
+	info.URL = bin.URL()
+	info.Stats = bin.Stats()
+	return info, nil
+}
+
+// binaryReplay 返回历史缓冲区中从 fromSeq 开始的数据帧，用于二进制传输的客户端重连
+// 与 RequestReplay 一样按 SetClientFilter 过滤，标注不通过二进制传输发送
+func (a *App) binaryReplay(fromSeq uint64) []wsbin.Frame {
+	entries, _ := a.history.From(fromSeq)
+	a.streamMutex.Lock()
+	filter, channel := a.clientFilter, a.channel
+	a.streamMutex.Unlock()
+
+	frames := make([]wsbin.Frame, 0, len(entries))
+	for _, e := range entries {
+		if e.Annotation != "" || (filter != "" && e.Source != filter) {
+			continue
+		}
+		frames = append(frames, wsbin.Frame{Channel: channel, Seq: e.Seq, Time: e.Time, Data: e.Data})
+	}
+	return frames
+}
+
+// GetHistoryRange 返回历史缓冲区当前保留的序号范围及淘汰统计
+func (a *App) GetHistoryRange() history.Range {
+	return a.history.Range()
+}
+
+// RecentEntry GetRecentData 返回的一条记录
+type RecentEntry struct {
+	Seq        uint64 `json:"seq"`
+	Time       int64  `json:"time"`      // Unix 毫秒
+	Direction  string `json:"direction"` // "rx" 接收数据，"note" 用户标注 (历史缓冲区不记录发送数据)
+	Data       []byte `json:"data,omitempty"`
+	Source     string `json:"source,omitempty"`
+	Annotation string `json:"annotation,omitempty"`
+}
+
+// RecentData 最近接收数据的快照
+type RecentData struct {
+	Entries   []RecentEntry `json:"entries"`
+	Truncated bool          `json:"truncated"` // 更早的数据因超出 maxBytes 或已被淘汰而未返回
+	NextSeq   uint64        `json:"nextSeq"`   // 之后的 serial-data 事件从该序号开始，可用于衔接实时数据
+}
+
+// defaultRecentBytes GetRecentData 的默认字节上限
+const defaultRecentBytes = 256 * 1024
+
+// GetRecentData 返回历史缓冲区末尾不超过 maxBytes 字节的完整记录 (maxBytes <= 0 时为 256KB)，
+// 供前端重新加载 (开发热重载或 WebView 崩溃) 后恢复接收区
+// 记录不会被截断，开启分帧时不会出现半帧；复制期间读取循环不会被阻塞
+func (a *App) GetRecentData(maxBytes int) RecentData {
+	if maxBytes <= 0 {
+		maxBytes = defaultRecentBytes
+	}
+	entries, truncated := a.history.Tail(maxBytes)
+
+	out := RecentData{Entries: make([]RecentEntry, 0, len(entries)), Truncated: truncated}
+	for _, e := range entries {
+		entry := RecentEntry{Seq: e.Seq, Time: e.Time.UnixMilli(), Direction: "rx", Data: e.Data, Source: e.Source}
+		if e.Annotation != "" {
+			entry.Direction = "note"
+			entry.Annotation = e.Annotation
+		}
+		out.Entries = append(out.Entries, entry)
+	}
+	if n := len(entries); n > 0 {
+		out.NextSeq = entries[n-1].Seq + 1
+	} else {
+		out.NextSeq = a.history.Range().NextSeq
+	}
+	return out
+}
+
+// HtmlExportOptions ExportHtml 的选项
+type HtmlExportOptions struct {
+	Title       string `json:"title"`       // 页首标题，为空时使用连接标签或 "serial-mate session"
+	LastMinutes int    `json:"lastMinutes"` // 未给出时间范围时导出最近的分钟数，0 表示 10
+	Hex         bool   `json:"hex"`         // 所有数据以十六进制/ASCII 转储显示 (格式同 SetHexFormat)；否则只有二进制数据如此显示
+}
+
+// defaultHtmlMinutes 未给出时间范围时 ExportHtml 导出的分钟数
+const defaultHtmlMinutes = 10
+
+// ExportHtml 将历史记录中 [fromTs, toTs] (Unix 毫秒) 范围内的接收数据与标注导出为单个自包含的 HTML 文件，
+// 包括时间戳、方向着色、分类规则的高亮与转储视图，适合作为报告附件；数据逐条写入文件，不在内存中构建整个文档。
+// toTs 为 0 时到当前时间为止，fromTs 为 0 时从 toTs 之前 LastMinutes 分钟开始。
+// path 可以是文件名模板 (见 nametmpl 包)，为空时使用 html 的默认模板；返回实际写入的文件路径
+func (a *App) ExportHtml(path string, fromTs int64, toTs int64, options HtmlExportOptions) (string, error) {
+	to := time.Now()
+	if toTs > 0 {
+		to = time.UnixMilli(toTs)
+	}
+	minutes := options.LastMinutes
+	if minutes <= 0 {
+		minutes = defaultHtmlMinutes
+	}
+	from := to.Add(-time.Duration(minutes) * time.Minute)
+	if fromTs > 0 {
+		from = time.UnixMilli(fromTs)
+	}
+	if from.After(to) {
+		return "", fmt.Errorf("invalid time range: start is after end")
+	}
+
+	path, err := a.outputPath(FileHTML, path)
+	if err != nil {
+		return "", err
+	}
+	a.mutex.Lock()
+	title := options.Title
+	if title == "" {
+		title = a.connLabel
+	}
+	a.mutex.Unlock()
+	a.streamMutex.Lock()
+	classifier := a.classifier
+	a.streamMutex.Unlock()
+
+	f, err := os.Create(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to create file: %w", err)
+	}
+	w, err := htmlexport.NewWriter(f, htmlexport.Options{
+		Title:      title,
+		From:       from,
+		To:         to,
+		Hex:        options.Hex,
+		Dump:       a.hexDumpOptions(),
+		Classifier: classifier,
+	})
+	if err == nil {
+		entries, _ := a.history.From(0)
+		for _, e := range entries {
+			if e.Time.Before(from) || e.Time.After(to) {
+				continue
+			}
+			if err = w.Write(e); err != nil {
+				break
+			}
+		}
+	}
+	if err == nil {
+		err = w.Close()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(path)
+		return "", fmt.Errorf("failed to write HTML: %w", err)
+	}
+	return path, nil
+}
+
+// GetConnectionStatus 返回当前连接的状态及统计信息
+func (a *App) GetConnectionStatus() ConnectionStatus {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	status := ConnectionStatus{
+		Connected: a.isConnected,
+		Type:      a.connType,
+		ReadOnly:  a.readOnly,
+		TxLimit:   a.txRate,
+	}
+	if a.isConnected && a.txBucket != nil {
+		status.TxUsage = a.txBucket.Utilization()
+	}
+	if a.isConnected {
+		a.streamMutex.Lock()
+		status.Channel = a.channel
+		id := a.router.Identity(a.channel)
+		status.Label, status.Color = id.Label, id.Color
+		if a.watchdog != nil {
+			status.SilenceMs = a.watchdog.Silence().Milliseconds()
+		}
+		a.streamMutex.Unlock()
+	}
+	if a.isConnected && a.connType == TypeUdp && a.udpSocket != nil {
+		for _, c := range a.udpSocket.Conns {
+			status.UdpPorts = append(status.UdpPorts, transport.LocalPort(c))
+		}
+	}
+	status.HalfClosed = a.isConnected && a.remoteHalfClosed
+	if a.isConnected && a.connType == TypeJLink {
+		rtt := a.rttStatus
+		status.JLink = &rtt
+	}
+	if a.isConnected && a.connType == TypeSerial && a.serialPort != nil {
+		if lines, err := serialport.ReadLines(a.serialPort); err == nil {
+			status.Lines = &lines
+		}
+		status.LinkCheck = a.linkCheck
+		status.SerialMode = a.serialMode
+		status.LineErrors = &LineErrors{SevenBit: a.sevenBit.Mode(), Parity: a.sevenBit.ParityErrors()}
+	}
+	return status
+}
+
+// SetHistoryLimit 设置历史缓冲区的内存上限 (MB)，立即生效，必要时淘汰最旧的数据
+func (a *App) SetHistoryLimit(megabytes int) string {
+	if megabytes < 1 || megabytes > 1024 {
+		return fmt.Sprintf("Error: history limit must be between 1 and 1024 MB, got %d", megabytes)
+	}
+	a.history.SetLimit(megabytes * 1024 * 1024)
+	return "Success"
+}
+
+// memoryCheckInterval 检查全局内存预算的间隔
+const memoryCheckInterval = time.Second
+
+// startMemoryBudget 按设置创建全局内存预算并登记各缓冲功能，超出预算时按以下顺序释放：
+//  1. 接收历史：淘汰最旧的记录 (不改变 SetHistoryLimit 的上限)
+//  2. CSV 导出缓冲：提前刷新到文件
+//
+// 接收队列中尚未处理的数据只计入用量，不会被丢弃；PCAP 抓包每次写入后立即刷新，
+// 日志文件的压缩流由 gzip 自行缓冲，均不登记
+func (a *App) startMemoryBudget() {
+	budget := int64(a.settings.Get().MemoryBudgetMB) << 20
+	a.memory = membudget.New(budget, func(p membudget.Pressure) {
+		a.emit("memory-pressure", p)
+	})
+	a.memory.Register(membudget.Consumer{
+		Name:     "history",
+		Priority: membudget.PriorityHistory,
+		Usage:    func() int64 { return int64(a.history.Bytes()) },
+		Release:  func(excess int64) { a.history.Trim(int(excess)) },
+	})
+	a.memory.Register(membudget.Consumer{
+		Name:     "plot-csv",
+		Priority: membudget.PriorityRecording,
+		Usage: func() int64 {
+			a.streamMutex.Lock()
+			defer a.streamMutex.Unlock()
+			if a.plotCsv == nil {
+				return 0
+			}
+			return int64(a.plotCsv.Buffered())
+		},
+		Release: func(int64) {
+			a.streamMutex.Lock()
+			defer a.streamMutex.Unlock()
+			if a.plotCsv == nil {
+				return
+			}
+			if err := a.plotCsv.Flush(); err != nil {
+				a.closePlotCsvLocked()
+				a.emit("plot-csv-error", err.Error())
+			}
+		},
+	})
+	a.memory.Register(membudget.Consumer{
+		Name:  "rx-queue",
+		Usage: func() int64 { return a.rxPending.Load() },
+	})
+	a.stopMemory = a.memory.Start(memoryCheckInterval)
+}
+
+// SetMemoryBudget 设置历史、导出缓冲与接收队列共享的内存预算 (MB)，0 表示默认 64MB
+// 立即检查一次，超出时按 startMemoryBudget 中的顺序释放并发送 memory-pressure 事件
+func (a *App) SetMemoryBudget(megabytes int) string {
+	if megabytes != 0 && (megabytes < membudget.MinBudget>>20 || megabytes > membudget.MaxBudget>>20) {
+		return fmt.Sprintf("Error: memory budget must be between %d and %d MB, got %d",
+			membudget.MinBudget>>20, membudget.MaxBudget>>20, megabytes)
+	}
+	if err := a.settings.Update(func(s *settings.Settings) {
+		s.MemoryBudgetMB = megabytes
+	}); err != nil {
+		return fmt.Sprintf("Error saving settings: %v", err)
+	}
+	a.memory.SetBudget(int64(megabytes) << 20)
+	a.memory.Check()
+	return "Success"
+}
+
+// GetMemoryUsage 返回内存预算及各功能的当前用量与累计释放量
+func (a *App) GetMemoryUsage() membudget.Usage {
+	return a.memory.Usage()
+}
+
+// Close 关闭连接
+func (a *App) Close() string {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	// 自动重连关闭旧连接时保留发送队列，其他情况 (包括重连间隔中用户关闭) 丢弃
+	if a.sendQueue != nil && a.sendQueue.Len() > 0 && !(a.isConnected && a.reconnectPendingLocked()) {
+		a.discardSendQueueLocked("closed")
+	}
+	if !a.isConnected {
+		return "Not connected"
+	}
+
+	// 摘要在释放任何资源之前生成，事件仍发往当前连接的通道
+	a.emitSessionSummaryLocked()
+
+	a.isConnected = false
+	a.connSpec = ""
+	a.templates.ClearEnv()
+	if a.sendWatch != nil {
+		// 处理函数可能正在等待 a.mutex，在后台停止
+		go a.sendWatch.Stop()
+		a.sendWatch = nil
+	}
+	if a.modbusPoller != nil {
+		// 轮询可能正在等待 a.mutex，在后台停止
+		go a.modbusPoller.Stop()
+		a.modbusPoller = nil
+	}
+	a.stopAnchorLocked(AnchorStopClosed)
+	if a.readStopChan != nil {
+		close(a.readStopChan)
+	}
+
+	a.streamMutex.Lock()
+	a.closeCompareLocked(a.channel)
+	a.router.RemoveConnection(a.channel)
+	if a.watchdog != nil {
+		a.watchdog.Stop()
+		a.watchdog = nil
+	}
+	if a.pcapWriter != nil {
+		a.closePcapLocked()
+	}
+	if a.recorder != nil {
+		a.closeRecordingLocked()
+	}
+	if a.zmodemFeed != nil {
+		a.zmodemFeed.CloseWithError(errZmodemClosed)
+		a.zmodemFeed = nil
+		a.zmodemIdle.Stop()
+		a.zmodemIdle = nil
+	}
+	a.zmodemDetect.Reset()
+	a.streamMutex.Unlock()
+
+	var err error
+
+	switch a.connType {
+	case TypeSerial:
+		if a.serialPort != nil {
+			err = a.serialPort.Close()
+			a.serialPort = nil
+		}
+	case TypeSlcan:
+		if a.serialPort != nil {
+			// 释放串口前先关闭 CAN 通道
+			a.serialPort.Write([]byte(slcan.CloseCommand))
+			err = a.serialPort.Close()
+			a.serialPort = nil
+		}
+	case TypeJLink:
+		if a.jlinkConn != nil {
+			a.jlinkConn.Close()
+			a.jlinkConn = nil
+		}
+		a.closeJLinkLogLocked()
+	case TypeTcpClient:
+		if a.netConn != nil {
+			err = a.netConn.Close()
+			a.netConn = nil
+		}
+	case TypeTcpServer:
+		if a.netListener != nil {
+			err = a.netListener.Close()
+			a.netListener = nil
+		}
+		if a.tcpClients != nil {
+			a.tcpClients.CloseAll()
+			a.tcpClients = nil
+		}
+	case TypeUdp:
+		if a.udpConn != nil {
+			err = a.udpSocket.Close()
+			a.udpConn = nil
+			a.udpSocket = nil
+			a.udpPeer = transport.UDPPeer{}
+		}
+	case TypeVirtual:
+		// 关闭任意一端即关闭整个连接对
+		if a.netConn != nil {
+			err = a.netConn.Close()
+			a.netConn = nil
+		}
+		if a.virtualPeer != nil {
+			a.virtualPeer.Close()
+			a.virtualPeer = nil
+		}
+		a.streamMutex.Lock()
+		a.closeCompareLocked(a.peerChannel)
+		a.router.RemoveConnection(a.peerChannel)
+		a.peerChannel = ""
+		a.streamMutex.Unlock()
+	}
+
+	// 端口关闭后再释放锁
+	a.portLock.Release()
+	a.portLock = nil
+
+	if err != nil {
+		return fmt.Sprintf("Error closing: %v", err)
+	}
+	return "Success"
+}
+
+// SendData 发送数据
+// 开启粘贴模式且数据超过阈值时分块发送 (见 SetPasteMode)，可通过 CancelSend 中途取消
+func (a *App) SendData(data string) string {
+	defer a.txLanes.Interactive()()
+	a.mutex.Lock()
+	payload, err := a.inputLimits.Text(data)
+	if err != nil {
+		a.mutex.Unlock()
+		return fmt.Sprintf("Send error: %v", err)
+	}
+	if result, queued := a.queueSendLocked(payload); queued {
+		a.mutex.Unlock()
+		return result
+	}
+	cfg := a.paste
+	if !cfg.enabled || len(payload) <= cfg.threshold {
+		defer a.mutex.Unlock()
+		return a.checkLinkLocked(a.sendLocked(payload))
+	}
+	if a.pasteCancel != nil {
+		a.mutex.Unlock()
+		return "Error: another paste send is in progress"
+	}
+	cancel := make(chan struct{})
+	a.pasteCancel = cancel
+	a.mutex.Unlock()
+
+	result := a.sendPaste(payload, cfg, cancel)
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	if a.pasteCancel == cancel {
+		a.pasteCancel = nil
+	}
+	return a.checkLinkLocked(result)
+}
+
+// SendQueueStatus GetSendQueue 的返回值
+type SendQueueStatus struct {
+	Enabled bool          `json:"enabled"`
+	Config  outbox.Config `json:"config"`
+	outbox.Stats
+	Pending bool `json:"pending"` // 自动重连正在进行，SendData 的数据会排队
+	// Lanes 发送优先级的排队情况 (交互发送与批量发送)，与是否开启发送队列无关
+	Lanes txlane.Depth `json:"lanes"`
+}
+
+// SendQueueDiscarded send-queue-discarded 事件的数据
+type SendQueueDiscarded struct {
+	outbox.Stats
+	Reason string `json:"reason"` // "closed"、"disabled"、"reconnect failed" 或 "flush failed"
+}
+
+// SendQueueFlushed send-queue-flushed 事件的数据
+type SendQueueFlushed struct {
+	Sent  int    `json:"sent"`
+	Error string `json:"error,omitempty"` // 发出过程中的错误，此时剩余的数据已被丢弃
+}
+
+// SetSendQueue 开启或关闭自动重连期间的发送队列 (store-and-forward)：看门狗重连或睡眠唤醒重连进行中时，
+// SendData (以及 SendHexDump、SendFrame、SendWithChecksum) 的数据排队并返回 "Queued (...)"，连接恢复后按顺序发出 (send-queue-flushed 事件)；
+// 重连失败或显式 Close 时丢弃队列并发送 send-queue-discarded 事件 (含丢弃的条数与字节数)
+// maxCount/maxBytes 为队列上限 (0 表示 100 条/64KB)，overflow 为队列已满时的处理方式：
+// "drop-oldest" (默认) 丢弃最早的数据，"drop-newest" 丢弃新数据；丢弃数见 GetSendQueue。
+// 关闭时丢弃已排队的数据；重新设置时保留已排队的数据，超出新上限的部分按新策略丢弃
+func (a *App) SetSendQueue(enabled bool, maxCount int, maxBytes int, overflow string) string {
+	cfg := outbox.Config{MaxCount: maxCount, MaxBytes: maxBytes, Overflow: outbox.Overflow(overflow)}
+	if err := cfg.Validate(); err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	}
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	if !enabled {
+		if a.sendQueue != nil && a.sendQueue.Len() > 0 {
+			a.discardSendQueueLocked("disabled")
+		}
+		a.sendQueue = nil
+		return "Success"
+	}
+	q := outbox.New(cfg)
+	if a.sendQueue != nil {
+		a.sendQueue.Flush(func(p []byte) error {
+			q.Push(p)
+			return nil
+		})
+	}
+	a.sendQueue = q
+	return "Success"
+}
+
+// GetSendQueue 返回发送队列的设置与状态
+func (a *App) GetSendQueue() SendQueueStatus {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	status := SendQueueStatus{Pending: a.reconnectPendingLocked(), Lanes: a.txLanes.Depth()}
+	if a.sendQueue != nil {
+		status.Enabled = true
+		status.Config = a.sendQueue.Config()
+		status.Stats = a.sendQueue.Stats()
+	}
+	return status
+}
+
+// reconnectPendingLocked 自动重连是否正在进行 (看门狗重连、睡眠期间或唤醒后的重试)，调用方必须持有 a.mutex
+func (a *App) reconnectPendingLocked() bool {
+	return a.reconnecting || a.resuming || a.sleepReopen != nil
+}
+
+// queueSendLocked 自动重连进行中且开启了发送队列时将 payload 排队，queued 为 false 时调用方照常发送
+// 调用方必须持有 a.mutex
+func (a *App) queueSendLocked(payload []byte) (result string, queued bool) {
+	if a.sendQueue == nil || a.isConnected || !a.reconnectPendingLocked() {
+		return "", false
+	}
+	if _, err := a.sendQueue.Push(payload); err != nil {
+		return fmt.Sprintf("Error: %v", err), true
+	}
+	return fmt.Sprintf("Queued (%d pending until the connection is restored)", a.sendQueue.Len()), true
+}
+
+// finishReconnect 自动重连结束：成功时按顺序发出排队的数据，失败时丢弃队列
+func (a *App) finishReconnect(ok bool) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	a.reconnecting = false
+	if a.sendQueue == nil || a.sendQueue.Len() == 0 {
+		return
+	}
+	if !ok || !a.isConnected {
+		a.discardSendQueueLocked("reconnect failed")
+		return
+	}
+	var ev SendQueueFlushed
+	var err error
+	ev.Sent, err = a.sendQueue.Flush(func(p []byte) error {
+		if result := a.sendLocked(p); !strings.HasPrefix(result, "Sent") {
+			return errors.New(result)
+		}
+		return nil
+	})
+	if err != nil {
+		// 剩余的数据不再保留，避免之后直接发送的数据越过它们
+		ev.Error = err.Error()
+		a.discardSendQueueLocked("flush failed")
+	}
+	a.emitConn("send-queue-flushed", ev)
+}
+
+// discardSendQueueLocked 清空发送队列并发送 send-queue-discarded 事件，调用方必须持有 a.mutex
+func (a *App) discardSendQueueLocked(reason string) {
+	stats := a.sendQueue.Discard()
+	a.emit("send-queue-discarded", SendQueueDiscarded{Stats: stats, Reason: reason})
+	a.emit("sys-msg", fmt.Sprintf("Send queue discarded (%s): %d messages, %d bytes", reason, stats.Queued, stats.Bytes))
+}
+
+// ParseHexDump 将粘贴的十六进制转储 (hexdump -C、xxd、Wireshark 十六进制流或转储、空格/冒号分隔、
+// C 数组 {0x01, 0x02}) 还原为字节，用于发送前预览；格式混杂时错误信息指出第一个不符合的行
+func (a *App) ParseHexDump(text string) (input.HexDump, error) {
+	a.mutex.Lock()
+	limits := a.inputLimits
+	a.mutex.Unlock()
+	return limits.HexDump(text)
+}
+
+// SendHexDump 按 ParseHexDump 解析后发送，返回与 SendData 相同格式的结果
+func (a *App) SendHexDump(text string) string {
+	defer a.txLanes.Interactive()()
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	dump, err := a.inputLimits.HexDump(text)
+	if err != nil {
+		return fmt.Sprintf("Send error: %v", err)
+	}
+	if result, queued := a.queueSendLocked(dump.Data); queued {
+		return result
+	}
+	return a.checkLinkLocked(a.sendLocked(dump.Data))
+}
+
+var errReadOnly = apperr.New(apperr.ReadOnly, "connection is in read-only mode")
+
+// SetReadOnly 开启只读模式：所有发送路径 (SendData、模板、粘贴、定时发送、看门狗探测、初始化数据、ZMODEM 应答、
+// CAN 帧) 返回 READ_ONLY 错误且不写入连接；设置跨连接保持，可在打开连接前设置
+// 只读时 SLCAN 以只听模式打开；dropLines 为 true 且当前为串口时同时将 DTR/RTS 置为无效
+func (a *App) SetReadOnly(enabled bool, dropLines bool) string {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	a.readOnly = enabled
+	if enabled && dropLines && a.isConnected && a.connType == TypeSerial && a.serialPort != nil {
+		if err := serialport.ApplyLines(a.serialPort, serialport.LineLow, serialport.LineLow); err != nil {
+			return fmt.Sprintf("Error: read-only enabled but %v", err)
+		}
+		a.dtrHigh, a.rtsHigh = false, false
+	}
+	if enabled {
+		a.emit("sys-msg", "Read-only mode enabled, nothing will be transmitted")
+	} else {
+		a.emit("sys-msg", "Read-only mode disabled")
+	}
+	return "Success"
+}
+
+// NoDeviceEvent no-device-detected 事件的数据
+type NoDeviceEvent struct {
+	Check serialport.LinkCheck  `json:"check"`
+	Lines serialport.ModemLines `json:"lines"`
+}
+
+// SetLinkCheck 设置串口发送时的设备存在检查："off" (默认)、"dsr"、"dcd" 或 "any" (DSR 或 DCD)
+// 开启后 SendData 发送成功但所选状态线无效时，结果附带警告，并在每次失联时发送一次 no-device-detected 事件
+// 三线制线缆不连接这些线，此时应保持关闭
+func (a *App) SetLinkCheck(lines string) string {
+	check, err := serialport.ParseLinkCheck(lines)
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	}
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	a.linkCheck = check
+	a.linkWarned = false
+	return "Success"
+}
+
+// SetSevenBitMode 设置 7 位数据的最高位处理："auto" (默认，数据位为 7 时按 mask 处理)、"off"、
+// "mask" (接收时清除最高位)、"even" 或 "odd" (最高位视为校验位，校验后清除并统计失败数，见 GetConnectionStatus)
+// 处理在分帧与事件发送之前进行；生效时发送的数据同样只保留低 7 位。设置跨连接保持，串口已连接时立即生效
+func (a *App) SetSevenBitMode(mode string) string {
+	m, err := serialport.ParseSevenBit(mode)
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	}
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	a.sevenBitMode = m
+	if a.isConnected && a.connType == TypeSerial {
+		a.sevenBit.SetMode(m.Resolve(a.serialParams.dataBits))
+	}
+	return "Success"
+}
+
+// checkLinkLocked 在串口发送成功后检查状态线，设备不存在时为 result 附加警告
+// 数据仍然照常发送；调用方必须持有 a.mutex
+func (a *App) checkLinkLocked(result string) string {
+	if result != "Sent" || a.connType != TypeSerial || a.serialPort == nil {
+		return result
+	}
+	if a.linkCheck == "" || a.linkCheck == serialport.LinkCheckOff {
+		return result
+	}
+
+	lines, err := serialport.ReadLines(a.serialPort)
+	if err != nil {
+		return result
+	}
+	if a.linkCheck.DevicePresent(lines) {
+		a.linkWarned = false
+		return result
+	}
+	if !a.linkWarned {
+		a.linkWarned = true
+		a.emitConn("no-device-detected", NoDeviceEvent{Check: a.linkCheck, Lines: lines})
+	}
+	return fmt.Sprintf("Sent (warning: %s not asserted, no device may be listening)", a.linkCheck.Lines())
+}
+
+// pasteConfig 粘贴模式参数
+type pasteConfig struct {
+	enabled       bool
+	threshold     int
+	chunkSize     int
+	delay         time.Duration
+	prompt        []byte
+	promptTimeout time.Duration
+}
+
+// 粘贴模式默认值
+const (
+	defaultPasteThreshold     = 1024
+	defaultPasteChunkSize     = 256
+	defaultPastePromptTimeout = 5 * time.Second
+)
+
+// SetPasteMode 设置粘贴模式：SendData 的数据超过 threshold 字节时按 chunkSize 分块发送 (不拆分 UTF-8 字符)，
+// 块之间等待 delayMs 毫秒；prompt 非空时改为等待收到 prompt 后再发送下一块，超过 promptTimeoutMs 未收到则中止
+// threshold/chunkSize/promptTimeoutMs 为 0 时分别使用 1024、256 字节与 5000 毫秒
+func (a *App) SetPasteMode(enabled bool, threshold int, chunkSize int, delayMs int, prompt string, promptTimeoutMs int) string {
+	if threshold < 0 || chunkSize < 0 || delayMs < 0 || promptTimeoutMs < 0 {
+		return "Error: paste parameters must not be negative"
+	}
+	if delayMs > 60000 || promptTimeoutMs > 60000 {
+		return "Error: paste delay and prompt timeout must not exceed 60000 ms"
+	}
+
+	cfg := newPasteConfig(enabled, threshold, chunkSize, delayMs, prompt, promptTimeoutMs)
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	a.paste = cfg
+	return "Success"
+}
+
+// newPasteConfig 创建粘贴模式参数，为 0 的 threshold/chunkSize/promptTimeoutMs 使用默认值
+func newPasteConfig(enabled bool, threshold int, chunkSize int, delayMs int, prompt string, promptTimeoutMs int) pasteConfig {
+	cfg := pasteConfig{
+		enabled:       enabled,
+		threshold:     threshold,
+		chunkSize:     chunkSize,
+		delay:         time.Duration(delayMs) * time.Millisecond,
+		prompt:        []byte(prompt),
+		promptTimeout: time.Duration(promptTimeoutMs) * time.Millisecond,
+	}
+	if cfg.threshold == 0 {
+		cfg.threshold = defaultPasteThreshold
+	}
+	if cfg.chunkSize == 0 {
+		cfg.chunkSize = defaultPasteChunkSize
+	}
+	if cfg.promptTimeout == 0 {
+		cfg.promptTimeout = defaultPastePromptTimeout
+	}
+	return cfg
+}
+
+// 目录监视的发送方式
+const (
+	WatchSendLines = "lines" // 逐行发送，跳过空行与 '#' 开头的注释行
+	WatchSendRaw   = "raw"   // 按原样发送整个文件
+)
+
+// watchSendChunk raw 模式下每次发送的最大字节数
+const watchSendChunk = 4096
+
+// WatchSendEvent watch-send 事件的数据，每个文件处理后发送一次
+type WatchSendEvent struct {
+	dropwatch.Result
+	Mode  string `json:"mode"`
+	Sends int    `json:"sends"` // 实际发送的行数或数据块数
+}
+
+// WatchSendDirectory 监视目录 path，文件名匹配 pattern (通配符，为空时匹配全部) 的文件出现或被修改时
+// 自动发送其内容：mode 为 "lines" 时逐行发送 (保留原有的行结束符)，为 "raw" 时按原样发送；
+// moveSent 为 true 时发送成功的文件移动到 path/sent。文件大小在 1 秒内不再变化后才发送，
+// 多个文件按名称顺序逐个发送，不会在线路上交错。每个文件的结果以 watch-send 事件报告。
+// 开始监视时已存在的文件不会发送；断开连接或 StopWatchSendDirectory 时停止
+func (a *App) WatchSendDirectory(path string, pattern string, mode string, moveSent bool) string {
+	switch mode {
+	case "":
+		mode = WatchSendLines
+	case WatchSendLines, WatchSendRaw:
+	default:
+		return fmt.Sprintf("Error: unknown mode %q (expected lines or raw)", mode)
+	}
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	if !a.isConnected {
+		return "Error: Not connected"
+	}
+	if a.sendWatch != nil {
+		return "Error: a directory is already being watched, stop it first"
+	}
+
+	sends := make(map[string]int)
+	var sendsMu sync.Mutex
+	handle := func(file string, data []byte, stop <-chan struct{}) error {
+		n, err := a.sendWatchedFile(mode, data, stop)
+		sendsMu.Lock()
+		sends[file] = n
+		sendsMu.Unlock()
+		return err
+	}
+	report := func(res dropwatch.Result) {
+		sendsMu.Lock()
+		n := sends[res.Path]
+		delete(sends, res.Path)
+		sendsMu.Unlock()
+		a.emit("watch-send", WatchSendEvent{Result: res, Mode: mode, Sends: n})
+	}
+	w, err := dropwatch.Start(dropwatch.Config{Dir: path, Pattern: pattern, MoveSent: moveSent}, handle, report)
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	}
+	a.sendWatch = w
+	return "Success"
+}
+
+// StopWatchSendDirectory 停止目录监视，正在发送的文件在当前行或数据块后中止
+func (a *App) StopWatchSendDirectory() string {
+	a.mutex.Lock()
+	w := a.sendWatch
+	a.sendWatch = nil
+	a.mutex.Unlock()
+
+	if w == nil {
+		return "Not watching"
+	}
+	w.Stop()
+	return "Success"
+}
+
+// sendWatchedFile 发送一个被监视的文件，每行或每块单独持有 a.mutex，返回成功发送的次数
+func (a *App) sendWatchedFile(mode string, data []byte, stop <-chan struct{}) (int, error) {
+	var parts [][]byte
+	if mode == WatchSendLines {
+		for _, line := range bytes.SplitAfter(data, []byte("\n")) {
+			text := bytes.TrimSpace(line)
+			if len(text) == 0 || text[0] == '#' {
+				continue
+			}
+			parts = append(parts, line)
+		}
+	} else {
+		for len(data) > 0 {
+			n := min(len(data), watchSendChunk)
+			parts = append(parts, data[:n])
+			data = data[n:]
+		}
+	}
+
+	for i, p := range parts {
+		select {
+		case <-stop:
+			return i, fmt.Errorf("stopped after %d of %d sends", i, len(parts))
+		default:
+		}
+		res := a.sendBulk(p, stop)
+		if !strings.HasPrefix(res, "Sent") {
+			return i, fmt.Errorf("send %d of %d: %s", i+1, len(parts), res)
+		}
+	}
+	return len(parts), nil
+}
+
+// 锚点截取结束的原因
+const (
+	AnchorStopMax    = "max"     // 达到 maxCaptures
+	AnchorStopUser   = "stopped" // StopAnchorCapture
+	AnchorStopClosed = "closed"  // 连接关闭
+)
+
+// AnchorCaptureEvent anchor-capture 事件的数据，每次截取发送一次
+type AnchorCaptureEvent struct {
+	Index  int    `json:"index"`
+	Offset uint64 `json:"offset"` // 锚点在本次截取开始后接收数据中的偏移
+	Hex    string `json:"hex"`    // 锚点之后的窗口数据
+}
+
+// AnchorStats GetAnchorStats 的返回值
+type AnchorStats struct {
+	Anchor  string `json:"anchor"`
+	Window  int    `json:"window"`
+	Running bool   `json:"running"`
+	anchor.Stats
+}
+
+// StartAnchorCapture 在接收数据中查找锚点 anchorHex (十六进制)，截取其后的 windowBytes 个字节，
+// 每次截取以 anchor-capture 事件发送，并累计每个字节位置的取值分布 (GetAnchorStats)。
+// 锚点可以跨越数据块；窗口内再次出现的锚点视为窗口数据，截取之间不重叠。
+// maxCaptures <= 0 时不限次数；达到上限、StopAnchorCapture 或断开连接时停止，
+// 并发送 anchor-capture-done 事件 (结束原因与汇总)
+func (a *App) StartAnchorCapture(anchorHex string, windowBytes int, maxCaptures int) string {
+	pattern, err := input.ParseHex(anchorHex)
+	if err != nil {
+		return fmt.Sprintf("Error: invalid hex anchor: %v", err)
+	}
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	if !a.isConnected {
+		return "Error: Not connected"
+	}
+	if a.anchorStop != nil {
+		return "Error: an anchor capture is already running, stop it first"
+	}
+
+	scan, err := anchor.New(pattern, windowBytes, maxCaptures, func(c anchor.Capture) {
+		hexStr, _ := format.Hex(c.Data, format.DefaultOptions)
+		a.emitConn("anchor-capture", AnchorCaptureEvent{Index: c.Index, Offset: c.Offset, Hex: hexStr})
+	})
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	}
+	stop := make(chan string, 1)
+	unsubscribe := a.rxHub.Subscribe(scan.Write)
+	a.anchorScan = scan
+	a.anchorStop = stop
+
+	go func() {
+		var reason string
+		select {
+		case <-scan.Done():
+			reason = AnchorStopMax
+			a.mutex.Lock()
+			if a.anchorStop == stop {
+				a.anchorStop = nil
+			}
+			a.mutex.Unlock()
+		case reason = <-stop:
+		}
+		unsubscribe()
+		a.emit("anchor-capture-done", map[string]interface{}{
+			"reason": reason,
+			"stats":  scan.Stats(),
+		})
+	}()
+	return "Success"
+}
+
+// StopAnchorCapture 停止锚点截取，已有的统计仍可通过 GetAnchorStats 查询
+func (a *App) StopAnchorCapture() string {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	if a.anchorStop == nil {
+		return "Not capturing"
+	}
+	a.stopAnchorLocked(AnchorStopUser)
+	return "Success"
+}
+
+func (a *App) stopAnchorLocked(reason string) {
+	if a.anchorStop != nil {
+		a.anchorStop <- reason
+		a.anchorStop = nil
+	}
+}
+
+// GetAnchorStats 返回最近一次锚点截取的汇总：截取次数与每个字节位置的取值直方图，
+// constant 为 true 的位置在所有截取中取值相同
+func (a *App) GetAnchorStats() (AnchorStats, error) {
+	a.mutex.Lock()
+	scan, running := a.anchorScan, a.anchorStop != nil
+	a.mutex.Unlock()
+
+	if scan == nil {
+		return AnchorStats{}, fmt.Errorf("no anchor capture has been started")
+	}
+	anchorHex, _ := format.Hex(scan.Anchor(), format.DefaultOptions)
+	return AnchorStats{
+		Anchor:  anchorHex,
+		Window:  scan.Window(),
+		Running: running,
+		Stats:   scan.Stats(),
+	}, nil
+}
+
+// CancelSend 取消正在进行的粘贴模式发送，已发送的字节数由 SendData 的结果报告
+func (a *App) CancelSend() string {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	if a.pasteCancel == nil {
+		return "Error: no paste send in progress"
+	}
+	close(a.pasteCancel)
+	a.pasteCancel = nil
+	return "Success"
+}
+
+// sendPaste 分块发送，每块单独持有 a.mutex，块之间允许其他发送插入
+// 每块发送后发出 send-progress 事件
+func (a *App) sendPaste(payload []byte, cfg pasteConfig, cancel chan struct{}) string {
+	chunks := transport.TextChunks(payload, cfg.chunkSize)
+	total, sent := len(payload), 0
+	progress := func(result string) string {
+		return fmt.Sprintf("%s (%d of %d bytes sent)", result, sent, total)
+	}
+
+	unsubscribe := func() {}
+	defer func() { unsubscribe() }()
+
+	for i, chunk := range chunks {
+		select {
+		case <-cancel:
+			return progress("Send canceled")
+		default:
+		}
+
+		// 先订阅再发送，避免丢失快速到达的提示符
+		last := i == len(chunks)-1
+		var collector *stream.Collector
+		unsubscribe()
+		if len(cfg.prompt) > 0 && !last {
+			collector = stream.NewCollector(cfg.prompt)
+			unsubscribe = a.rxHub.Subscribe(collector.Write)
+		}
+
+		a.mutex.Lock()
+		result := a.sendLocked(chunk)
+		a.mutex.Unlock()
+		if result != "Sent" {
+			return progress(result)
+		}
+		sent += len(chunk)
+		a.emitConn("send-progress", SendProgress{Sent: sent, Total: total})
+		if last {
+			break
+		}
+
+		if collector != nil {
+			timer := time.NewTimer(cfg.promptTimeout)
+			select {
+			case <-collector.Done():
+				timer.Stop()
+			case <-cancel:
+				timer.Stop()
+				return progress("Send canceled")
+			case <-timer.C:
+				return progress(fmt.Sprintf("Send error: prompt %q not received within %v", cfg.prompt, cfg.promptTimeout))
+			}
+		} else if cfg.delay > 0 {
+			timer := time.NewTimer(cfg.delay)
+			select {
+			case <-timer.C:
+			case <-cancel:
+				timer.Stop()
+				return progress("Send canceled")
+			}
+		}
+	}
+	return "Sent"
+}
+
+// SendTemplate 在发送时展开模板中的占位符 ({{seq}}、{{seq:04x}}、{{ts_ms}}、{{ts_iso}}、{{rand:N}}、{{env.KEY}}) 后发送
+// hexMode 时模板展开后按十六进制解码；模板错误在写入之前返回
+func (a *App) SendTemplate(template string, hexMode bool) string {
+	defer a.txLanes.Interactive()()
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	if !a.isConnected {
+		return "Error: Not connected"
+	}
+	payload, err := a.templates.Expand(template, hexMode)
+	if err != nil {
+		return fmt.Sprintf("Template error: %v", err)
+	}
+	return a.sendLocked(payload)
+}
+
+// ResetTemplateCounters 将当前连接的模板计数重置为从 1 开始
+func (a *App) ResetTemplateCounters() {
+	a.templates.Reset()
+}
+
+// GetSessionEnv 返回当前连接的会话环境 (宏捕获或手动设置的变量)，模板中以 {{env.KEY}} 引用
+func (a *App) GetSessionEnv() map[string]string {
+	return a.templates.Env()
+}
+
+// SetSessionEnv 设置会话环境变量，value 为空时删除；会话环境在关闭连接时清空
+func (a *App) SetSessionEnv(key, value string) string {
+	if err := a.templates.SetEnv(key, value); err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	}
+	return "Success"
+}
+
+// MacroStepEvent macro-step 事件的数据
+type MacroStepEvent struct {
+	Total int `json:"total"`
+	macro.StepResult
+}
+
+// RunMacro 依次执行宏的步骤 (见 macro 包)：发送模板、等待正则表达式匹配并把分组捕获到会话环境，
+// 每个步骤结束时发送 macro-step 事件。连接关闭时中止；与 Transact 共用队列，并发调用按顺序执行
+func (a *App) RunMacro(steps []macro.Step) (macro.Result, error) {
+	if err := macro.Validate(steps); err != nil {
+		return macro.Result{}, err
+	}
+
+	a.transactMutex.Lock()
+	defer a.transactMutex.Unlock()
+
+	a.mutex.Lock()
+	stop := a.readStopChan
+	connected := a.isConnected
+	a.mutex.Unlock()
+	if !connected || stop == nil {
+		return macro.Result{}, fmt.Errorf("not connected")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	res := macro.Run(ctx, macroSession{a}, steps, func(r macro.StepResult) {
+		a.emit("macro-step", MacroStepEvent{Total: len(steps), StepResult: r})
+	})
+	return res, nil
+}
+
+// macroSession 让宏在当前连接上运行，变量保存在模板展开器的会话环境中
+type macroSession struct {
+	a *App
+}
+
+func (s macroSession) Expand(template string, hex bool) ([]byte, error) {
+	return s.a.templates.Expand(template, hex)
+}
+
+func (s macroSession) Send(payload []byte) error {
+	defer s.a.txLanes.Interactive()()
+	s.a.mutex.Lock()
+	defer s.a.mutex.Unlock()
+
+	if !s.a.isConnected {
+		return errors.New("not connected")
+	}
+	if res := s.a.sendLocked(payload); !strings.HasPrefix(res, "Sent") {
+		return errors.New(res)
+	}
+	return nil
+}
+
+func (s macroSession) Subscribe(fn func([]byte)) func() {
+	return s.a.rxHub.Subscribe(fn)
+}
+
+func (s macroSession) SetEnv(key, value string) error {
+	return s.a.templates.SetEnv(key, value)
+}
+
+// sendBulk 以批量优先级发送一帧 (文件发送、定时发送)：有交互发送等待时先让其发出，
+// stop 关闭时放弃等待。调用方不得持有 a.mutex
+func (a *App) sendBulk(payload []byte, stop <-chan struct{}) string {
+	done, ok := a.txLanes.Bulk(stop)
+	if !ok {
+		return "Send canceled"
+	}
+	defer done()
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	return a.sendLocked(payload)
+}
+
+// sendLocked 将 payload 写入当前连接，返回与 SendData 相同格式的结果
+// 调用方必须持有 a.mutex
+func (a *App) sendLocked(payload []byte) string {
+	if !a.isConnected {
+		return "Error: Not connected"
+	}
+	if a.readOnly {
+		return "Send error: " + errReadOnly.Error()
+	}
+	// 所有发送路径 (模板、快捷指令、定时发送等) 共用的大小与空数据检查
+	if err := a.inputLimits.Check(payload); err != nil {
+		return fmt.Sprintf("Send error: %v", err)
+	}
+
+	var err error
+	timeout := a.writeTimeout
+	result := "Sent"
+
+	switch a.connType {
+	case TypeSerial, TypeSlcan:
+		if a.serialPort != nil {
+			if a.connType == TypeSerial && a.sevenBit.Mode().Active() {
+				var masked int
+				if payload, masked = serialport.MaskTx(payload); masked > 0 {
+					result = fmt.Sprintf("Sent (%d bytes masked to 7 bits)", masked)
+				}
+			}
+			_, err = a.writeStreamLocked(a.serialPort, payload)
+		}
+	case TypeJLink:
+		if a.jlinkConn != nil {
+			a.paceLocked(len(payload))
+			_, err = a.jlinkConn.WriteRTTTimeout(payload, timeout)
+		}
+	case TypeTcpClient:
+		if a.netConn != nil {
+			var n int
+			n, err = a.writeStreamLocked(a.netConn, payload)
+			a.capturePacket(a.netConn.LocalAddr(), a.netConn.RemoteAddr(), true, payload[:n])
+		}
+	case TypeTcpServer:
+		// 发送给所有接入的客户端，任一客户端写入失败时返回第一个错误
+		clients := a.tcpClients.Clients()
+		if len(clients) == 0 {
+			return "Error: " + a.tcpClients.NoClientError(time.Now()).Error()
+		}
+		for _, c := range clients {
+			if werr := a.writeClientLocked(c, payload); werr != nil && err == nil {
+				err = werr
+			}
+		}
+	case TypeVirtual:
+		if a.netConn != nil {
+			_, err = a.writeStreamLocked(a.netConn, payload)
+		}
+	case TypeUdp:
+		if a.udpConn == nil || !a.udpPeer.Ready() {
+			return "Error: No remote address set"
+		}
+		datagrams, derr := transport.Datagrams(payload, a.udpLimit, a.udpPolicy)
+		if derr != nil {
+			return fmt.Sprintf("Send error: %v (policy %q; set the UDP policy to %q to send multiple datagrams)",
+				derr, a.udpPolicy, transport.DatagramSplit)
+		}
+		// 绑定多个端口时，从收到对端数据报的套接字回复，使对端看到的源端口一致
+		conn := a.udpConn
+		if a.udpPeer.Reply != nil {
+			conn = a.udpPeer.Reply
+		}
+		for _, d := range datagrams {
+			var n int
+			a.paceLocked(len(d))
+			if a.udpPeer.Dialed {
+				// connected 套接字不能使用 WriteTo
+				n, err = transport.Write(a.udpConn.(net.Conn), d, timeout)
+			} else {
+				n, err = transport.WriteTo(conn, d, a.udpPeer.Remote, timeout)
+			}
+			a.capturePacket(conn.LocalAddr(), a.udpPeer.Remote, true, d[:n])
+			if err != nil {
+				break
+			}
+		}
+		if len(datagrams) > 1 {
+			result = fmt.Sprintf("Sent (split into %d datagrams)", len(datagrams))
+		}
+	}
+
+	if err != nil && err != transport.ErrTimeout && a.connType == TypeTcpClient && a.remoteHalfClosed {
+		// 对端已完全关闭：读取循环已停止，由发送失败结束连接 (Close 需要 a.mutex，异步执行)
+		a.emitConnError(err.Error(), transport.TranslateError(err))
+		go a.Close()
+	}
+	if err == transport.ErrTimeout {
+		// 连接保持原状，用户仍然可以正常 Close
+		err = apperr.Wrap(apperr.WriteTimeout, err, "write did not complete within %v", timeout)
+		a.emitConnError(err.Error(), apperr.EventOf(err))
+	}
+	if err != nil {
+		return fmt.Sprintf("Send error: %v", err)
+	}
+	a.countTxLocked(payload)
+	return result
+}
+
+// writeClientLocked 写入 TCP Server 的一个客户端并计入其发送字节数，错误带客户端地址
+func (a *App) writeClientLocked(c *transport.Client, payload []byte) error {
+	n, err := a.writeStreamLocked(c.Conn, payload)
+	c.CountTx(n)
+	a.capturePacket(c.Conn.LocalAddr(), c.Conn.RemoteAddr(), true, payload[:n])
+	if err != nil {
+		return fmt.Errorf("%s: %w", c.Addr, err)
+	}
+	return nil
+}
+
+// countTxLocked 将发送成功的数据计入会话统计、录制与发送速率
+func (a *App) countTxLocked(payload []byte) {
+	a.streamMutex.Lock()
+	if a.sessionStats != nil {
+		a.sessionStats.AddTx(time.Now(), len(payload))
+	}
+	a.recordLocked(recording.KindTx, time.Now(), payload)
+	a.streamMutex.Unlock()
+	a.trackTxLocked(len(payload))
+}
+
+// SendProgress 分块发送进度事件
+type SendProgress struct {
+	Sent    int     `json:"sent"`
+	Total   int     `json:"total"`
+	TxUsage float64 `json:"txUsage,omitempty"` // 限速发送时的带宽利用率，见 SetTxBandwidthLimit
+}
+
+// writeStreamLocked 写入串口/TCP 等字节流连接
+// 超过分块阈值的负载按块写入并发送 "send-progress" 事件，避免一次长时间阻塞的 Write
+func (a *App) writeStreamLocked(w io.Writer, payload []byte) (int, error) {
+	if bucket := a.txBucket; bucket != nil && bucket.Rate() > 0 {
+		chunk := bucket.ChunkSize()
+		return transport.WritePaced(w, payload, bucket, a.writeTimeout, nil, func(sent, total int) {
+			if total > chunk {
+				a.emitConn("send-progress", SendProgress{Sent: sent, Total: total, TxUsage: bucket.Utilization()})
+			}
+		})
+	}
+	if a.largeSendThreshold <= 0 || len(payload) <= a.largeSendThreshold {
+		return transport.Write(w, payload, a.writeTimeout)
+	}
+	return transport.WriteChunked(w, payload, transport.DefaultChunkSize, a.writeTimeout, func(sent, total int) {
+		a.emitConn("send-progress", SendProgress{Sent: sent, Total: total})
+	})
+}
+
+// paceLocked 按发送带宽上限等待 n 字节的令牌，用于无法拆分的 UDP 数据报与 RTT 写入
+// 只在令牌桶外等待，不占用桶的锁；调用方必须持有 a.mutex
+func (a *App) paceLocked(n int) {
+	if a.txBucket == nil {
+		return
+	}
+	if d := a.txBucket.Reserve(n); d > 0 {
+		time.Sleep(d)
+	}
+}
+
+// SetTxBandwidthLimit 设置发送带宽上限 (字节/秒)，0 表示不限速；适用于 LoRa 等低速无线透传
+// 超出上限的数据不会被拒绝，而是拆成小块平滑发送 (最多允许 250ms 的突发)，覆盖所有发送路径
+// (SendData、模板、粘贴、快捷指令、定时发送等)；设置跨连接保持，每个连接使用独立的令牌桶
+// 限速发送期间与分块发送一样占用连接，发送结束前其他发送会排队等待
+func (a *App) SetTxBandwidthLimit(bytesPerSec int) string {
+	if bytesPerSec < 0 {
+		return "Error: bandwidth limit must not be negative"
+	}
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.txRate = bytesPerSec
+	if a.txBucket != nil {
+		a.txBucket.SetRate(bytesPerSec)
+	}
+	return "Success"
+}
+
+// SetSendLimits 设置发送大小限制
+// udpLimit 为单个 UDP 数据报的最大负载 (0 表示 65507，可设为 1472 等更小值)，
+// udpPolicy 为超限时的处理方式 ("reject" 或 "split")，
+// largeSendThreshold 为串口/TCP 分块发送的阈值 (字节)，0 表示始终一次写入
+func (a *App) SetSendLimits(udpLimit int, udpPolicy string, largeSendThreshold int) string {
+	if udpLimit < 0 || udpLimit > transport.MaxUDPPayload {
+		return fmt.Sprintf("Error: UDP limit must be between 0 and %d", transport.MaxUDPPayload)
+	}
+	if largeSendThreshold < 0 {
+		return "Error: large send threshold must not be negative"
+	}
+	policy, err := transport.ParseDatagramPolicy(udpPolicy)
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	}
+	if udpLimit == 0 {
+		udpLimit = transport.MaxUDPPayload
+	}
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	a.udpLimit = udpLimit
+	a.udpPolicy = policy
+	a.largeSendThreshold = largeSendThreshold
+	return "Success"
+}
+
+// SetPayloadLimits 设置前端传入的发送数据的校验规则
+// maxBytes 为单次发送的大小上限 (0 表示 4MB)，invalidUTF8 为文本模式下遇到不合法 UTF-8
+// (包括孤立的 UTF-16 代理项) 时的处理方式："reject" (默认) 或 "replace" (替换为 U+FFFD)
+func (a *App) SetPayloadLimits(maxBytes int, invalidUTF8 string) string {
+	if maxBytes < 0 {
+		return "Error: payload limit must not be negative"
+	}
+	policy, err := input.ParseUTF8Policy(invalidUTF8)
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	}
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	a.inputLimits = input.Limits{MaxBytes: maxBytes, UTF8: policy}
+	return "Success"
+}
+
+// GetPipelineConfig 返回所有收发处理设置的快照，可直接传给 ApplyPipelineConfig 恢复
+func (a *App) GetPipelineConfig() pipecfg.Config {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	return a.pipelineConfigLocked()
+}
+
+// pipelineConfigLocked 生成设置快照，调用方必须持有 a.mutex (不能持有 streamMutex)
+func (a *App) pipelineConfigLocked() pipecfg.Config {
+	rules := a.settings.Get().ClassifierRules
+	if rules == nil {
+		rules = []classify.Rule{}
+	}
+
+	cfg := pipecfg.Config{
+		ClassifierRules: rules,
+		EchoSuppression: a.echoSuppression,
+		Watchdog: pipecfg.Watchdog{
+			Enabled:    a.rxWatchdog.Enabled,
+			TimeoutSec: int(a.rxWatchdog.Timeout / time.Second),
+			Action:     a.rxWatchdog.Action,
+			Probe:      a.rxWatchdog.Probe,
+		},
+		TxRate:             a.txRate,
+		WriteTimeoutMs:     int(a.writeTimeout / time.Millisecond),
+		UDPLimit:           a.udpLimit,
+		UDPPolicy:          a.udpPolicy,
+		LargeSendThreshold: a.largeSendThreshold,
+		Payload:            a.inputLimits,
+		Paste: pipecfg.Paste{
+			Enabled:         a.paste.enabled,
+			Threshold:       a.paste.threshold,
+			ChunkSize:       a.paste.chunkSize,
+			DelayMs:         int(a.paste.delay / time.Millisecond),
+			Prompt:          string(a.paste.prompt),
+			PromptTimeoutMs: int(a.paste.promptTimeout / time.Millisecond),
+		},
+		LinkCheck: a.linkCheck,
+	}
+
+	a.streamMutex.Lock()
+	defer a.streamMutex.Unlock()
+
+	cfg.Formatting = a.formatEnabled
+	cfg.Format = a.formatOpts
+	if a.hexDumper != nil {
+		cfg.HexDump = a.hexDumper.Options()
+	}
+	cfg.RxEncoding = a.rxEncoding
+	if a.plotParser != nil {
+		cfg.PlotParser = string(a.plotParser.Protocol())
+	}
+	cfg.Zmodem = pipecfg.Zmodem{Auto: a.zmodemAuto, Dir: a.zmodemDir}
+	if cfg.Zmodem.Dir == "" {
+		cfg.Zmodem.Dir, _ = defaultDownloadDir()
+	}
+	return cfg
+}
+
+// TestPipeline 不建立连接，用 configJSON 描述的阶段 (接收编码、行分类规则、帧格式、绘图协议、应答模式，见 bench.Config)
+// 试运行 sample，返回输出块、解出的帧、采样点、匹配结果与统计，供界面在编辑设置时实时预览
+// 各阶段与实时接收路径使用相同的实现；每次调用独立创建状态，不影响当前连接，可随时调用
+func (a *App) TestPipeline(configJSON, sample string) (bench.Result, error) {
+	var cfg bench.Config
+	if strings.TrimSpace(configJSON) != "" {
+		dec := json.NewDecoder(strings.NewReader(configJSON))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&cfg); err != nil {
+			return bench.Result{}, fmt.Errorf("invalid test bench config: %w", err)
+		}
+	}
+	return bench.Run(cfg, sample)
+}
+
+// ApplyPipelineConfig 整体应用 GetPipelineConfig 返回的设置：先校验全部字段，任何字段无效时不做任何修改，
+// 并在结果中列出所有无效字段；校验通过后在同一次加锁内替换全部设置，立即作用于当前连接
+// 安全模式 (见 GetSafeMode) 下用于逐个重新启用未生效的阶段，调用后退出安全模式
+func (a *App) ApplyPipelineConfig(cfg pipecfg.Config) string {
+	if err := cfg.Validate(); err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	}
+
+	// 以下转换在校验通过后不会失败
+	classifier, _ := classify.Compile(cfg.ClassifierRules)
+	encoding, _ := textenc.ParseMode(string(cfg.RxEncoding))
+	udpPolicy, _ := transport.ParseDatagramPolicy(string(cfg.UDPPolicy))
+	utf8Policy, _ := input.ParseUTF8Policy(string(cfg.Payload.UTF8))
+	linkCheck, _ := serialport.ParseLinkCheck(string(cfg.LinkCheck))
+	var plotParser *plot.Parser
+	if cfg.PlotParser != "" {
+		protocol, _ := plot.ParseProtocol(cfg.PlotParser)
+		plotParser = plot.NewParser(protocol)
+	}
+	var hexDumper *format.Dumper
+	if cfg.HexDump.BytesPerRow != 0 {
+		hexDumper = format.NewDumper(cfg.HexDump)
+	}
+	udpLimit := cfg.UDPLimit
+	if udpLimit == 0 {
+		udpLimit = transport.MaxUDPPayload
+	}
+	zmodemDir := cfg.Zmodem.Dir
+	if zmodemDir == "" {
+		var err error
+		if zmodemDir, err = defaultDownloadDir(); err != nil {
+			return fmt.Sprintf("Error: %v", err)
+		}
+	}
+
+	// 分类规则与 SetClassifierRules 一样需要保存，保存失败时同样不做任何修改
+	if err := a.settings.Update(func(s *settings.Settings) {
+		s.ClassifierRules = append([]classify.Rule(nil), cfg.ClassifierRules...)
+	}); err != nil {
+		return fmt.Sprintf("Error saving settings: %v", err)
+	}
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	a.echoSuppression = cfg.EchoSuppression
+	a.rxWatchdog = RxWatchdogConfig{
+		Enabled: cfg.Watchdog.Enabled,
+		Timeout: time.Duration(cfg.Watchdog.TimeoutSec) * time.Second,
+		Action:  cfg.Watchdog.Action,
+		Probe:   append([]byte(nil), cfg.Watchdog.Probe...),
+	}
+	a.txRate = cfg.TxRate
+	if a.txBucket != nil {
+		a.txBucket.SetRate(cfg.TxRate)
+	}
+	a.writeTimeout = time.Duration(cfg.WriteTimeoutMs) * time.Millisecond
+	a.udpLimit = udpLimit
+	a.udpPolicy = udpPolicy
+	a.largeSendThreshold = cfg.LargeSendThreshold
+	a.inputLimits = input.Limits{MaxBytes: cfg.Payload.MaxBytes, UTF8: utf8Policy}
+	p := cfg.Paste
+	a.paste = newPasteConfig(p.Enabled, p.Threshold, p.ChunkSize, p.DelayMs, p.Prompt, p.PromptTimeoutMs)
+	a.linkCheck = linkCheck
+	a.linkWarned = false
+
+	a.streamMutex.Lock()
+	a.formatEnabled = cfg.Formatting
+	a.formatOpts = cfg.Format
+	a.hexDumper = hexDumper
+	a.rxEncoding = encoding
+	a.rxDecoder.SetMode(encoding)
+	a.classifier = classifier
+	a.plotParser = plotParser
+	a.zmodemAuto = cfg.Zmodem.Auto
+	a.zmodemDir = zmodemDir
+	a.zmodemDetect.Reset()
+	stages := a.customStagesLocked()
+	a.streamMutex.Unlock()
+
+	// 重新应用的阶段再次计时，之后没有崩溃则处理链恢复正常
+	a.safeMode = nil
+	a.health.Applied(stages)
+
+	a.stopWatchdogLocked()
+	if a.isConnected {
+		a.startWatchdogLocked()
+	}
+	return "Success"
+}
+
+// SetOpenTimeout 设置打开串口、SLCAN 适配器、TCP 连接 (包括域名解析) 与 J-Link 连接的超时 (毫秒)，
+// 0 表示恢复默认的 5 秒。超时后立即返回 OPEN_TIMEOUT 错误，之后才完成的打开结果被关闭
+func (a *App) SetOpenTimeout(ms int) string {
+	if ms < 0 {
+		return "Error: open timeout must not be negative"
+	}
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
 
+	a.openTimeout = time.Duration(ms) * time.Millisecond
+	if ms == 0 {
+		a.openTimeout = openguard.DefaultTimeout
+	}
 	return "Success"
 }
 
-func (a *App) handleTcpConnection(conn net.Conn) {
-	buff := make([]byte, 4096)
-	for {
-		n, err := conn.Read(buff)
-		if err != nil {
-			a.mutex.Lock()
-			if a.netConn == conn {
-				a.netConn = nil
-			}
-			a.mutex.Unlock()
-			return
-		}
-		if n > 0 {
-			dataToSend := make([]byte, n)
-			copy(dataToSend, buff[:n])
-			runtime.EventsEmit(a.ctx, "serial-data", dataToSend)
-		}
+// CancelOpen 取消正在进行的打开操作，该 Open* 调用返回 OPEN_CANCELED 错误；不需要等待 a.mutex
+func (a *App) CancelOpen() string {
+	if !a.openGuard.Cancel() {
+		return "Error: no connection is being opened"
+	}
+	return "Success"
+}
+
+// SetWriteTimeout 设置所有连接类型的写超时 (毫秒)，0 表示不限时
+func (a *App) SetWriteTimeout(ms int) string {
+	if ms < 0 {
+		return "Error: write timeout must not be negative"
 	}
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	a.writeTimeout = time.Duration(ms) * time.Millisecond
+	return "Success"
 }
 
-// OpenUdp 开启 UDP
-func (a *App) OpenUdp(localPort string, remoteIp string, remotePort string) string {
+// --- 控制台输入模式 ---
+
+// SendRawKeys 立即发送按键字节，不追加行尾、不做任何转义处理
+func (a *App) SendRawKeys(data []byte) string {
+	defer a.txLanes.Interactive()()
 	a.mutex.Lock()
 	defer a.mutex.Unlock()
 
-	if a.isConnected {
-		return "Already connected"
+	if len(data) == 0 {
+		return "Sent"
 	}
+	result := a.sendLocked(data)
+	if result == "Sent" && a.echoSuppression {
+		a.echo.Expect(data)
+	}
+	return result
+}
 
-	lAddrStr := ":" + localPort
-	conn, err := net.ListenPacket("udp", lAddrStr)
+// SendCtrl 发送 Ctrl+key 控制字符，例如 SendCtrl("C") 发送 0x03
+func (a *App) SendCtrl(key string) string {
+	b, err := console.CtrlByte(key)
 	if err != nil {
-		return fmt.Sprintf("UDP Listen error: %v", err)
+		return fmt.Sprintf("Error: %v", err)
 	}
+	return a.SendRawKeys([]byte{b})
+}
 
-	var rAddr net.Addr
-	if remoteIp != "" && remotePort != "" {
-		rAddr, err = net.ResolveUDPAddr("udp", net.JoinHostPort(remoteIp, remotePort))
-		if err != nil {
-			conn.Close()
-			return fmt.Sprintf("Remote Addr error: %v", err)
-		}
+// SendEscapeSequence 发送命名按键的转义序列，例如 "up"、"delete"、"f1"
+func (a *App) SendEscapeSequence(name string) string {
+	seq, err := console.EscapeSequence(name)
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err)
 	}
+	return a.SendRawKeys(seq)
+}
 
-	a.udpConn = conn
-	a.udpRemote = rAddr
-	a.connType = TypeUdp
-	a.isConnected = true
-	a.readStopChan = make(chan struct{})
+// SetEchoSuppression 开启后，通过 SendRawKeys 发送的字符被远端回显时不再重复显示
+func (a *App) SetEchoSuppression(enabled bool) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
 
-	go func() {
-		buff := make([]byte, 4096)
-		for {
-			select {
-			case <-a.readStopChan:
-				return
-			default:
-				conn.SetReadDeadline(time.Now().Add(500 * time.Millisecond))
-				n, addr, err := conn.ReadFrom(buff)
-				if err != nil {
-					if nerr, ok := err.(net.Error); ok && nerr.Timeout() {
-						continue
-					}
-					if a.isConnected {
-						runtime.EventsEmit(a.ctx, "serial-error", err.Error())
-					}
-					return
-				}
+	a.echoSuppression = enabled
+}
 
-				a.mutex.Lock()
-				if a.udpRemote == nil {
-					a.udpRemote = addr
-					runtime.EventsEmit(a.ctx, "sys-msg", fmt.Sprintf("Remote set to: %s", addr.String()))
-				}
-				a.mutex.Unlock()
+// --- ZMODEM ---
 
-				if n > 0 {
-					dataToSend := make([]byte, n)
-					copy(dataToSend, buff[:n])
-					runtime.EventsEmit(a.ctx, "serial-data", dataToSend)
-				}
-			}
+// zmodemIdleTimeout 传输过程中超过该时间没有收到数据则放弃
+const zmodemIdleTimeout = 10 * time.Second
+
+var (
+	errZmodemClosed  = errors.New("connection closed")
+	errZmodemTimeout = fmt.Errorf("no data for %v", zmodemIdleTimeout)
+)
+
+// ZmodemResult "zmodem-done" 事件数据
+type ZmodemResult struct {
+	Files []string `json:"files"`
+	Error string   `json:"error,omitempty"`
+}
+
+// SetZmodemOptions 开启后在接收数据中检测 ZMODEM 发送请求 (设备端执行 sz)，
+// 自动接收文件到 downloadDir (为空时使用用户的 Downloads 目录)
+// 传输期间暂停 serial-data 事件，通过 "zmodem-progress" 报告进度，结束时发送 "zmodem-done"
+func (a *App) SetZmodemOptions(autoDetect bool, downloadDir string) string {
+	if downloadDir == "" {
+		var err error
+		if downloadDir, err = defaultDownloadDir(); err != nil {
+			return fmt.Sprintf("Error: %v", err)
 		}
-	}()
+	}
+
+	a.streamMutex.Lock()
+	defer a.streamMutex.Unlock()
 
+	a.zmodemAuto = autoDetect
+	a.zmodemDir = downloadDir
+	a.zmodemDetect.Reset()
 	return "Success"
 }
 
-// --- 通用方法 ---
+// defaultDownloadDir 返回用户的 Downloads 目录
+func defaultDownloadDir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, "Downloads"), nil
+}
 
-func (a *App) startReadLoop(reader io.Reader) {
-	a.isConnected = true
-	a.readStopChan = make(chan struct{})
+// startZmodemLocked 启动接收状态机，返回向其转交数据的管道
+// 调用方必须持有 streamMutex
+func (a *App) startZmodemLocked() *io.PipeWriter {
+	pr, pw := io.Pipe()
+	a.zmodemFeed = pw
+	a.zmodemIdle = time.AfterFunc(zmodemIdleTimeout, func() {
+		pw.CloseWithError(errZmodemTimeout)
+	})
+	dir := a.zmodemDir
+	go a.runZmodem(pr, pw, dir)
+	return pw
+}
 
-	go func() {
-		buff := make([]byte, 4096)
-		for {
-			select {
-			case <-a.readStopChan:
-				return
-			default:
-				n, err := reader.Read(buff)
-				if err != nil {
-					if a.isConnected {
-						fmt.Printf("Read Error: %v\n", err)
-						runtime.EventsEmit(a.ctx, "serial-error", err.Error())
-						a.Close()
-					}
-					return
-				}
-				if n == 0 {
-					continue
-				}
+// feedZmodem 将接收数据转交给接收状态机，状态机已结束时丢弃
+func (a *App) feedZmodem(feed *io.PipeWriter, data []byte) {
+	a.streamMutex.Lock()
+	if a.zmodemIdle != nil {
+		a.zmodemIdle.Reset(zmodemIdleTimeout)
+	}
+	a.streamMutex.Unlock()
 
-				fmt.Printf("[DEBUG] Recv %d bytes\n", n)
-				dataToSend := make([]byte, n)
-				copy(dataToSend, buff[:n])
-				runtime.EventsEmit(a.ctx, "serial-data", dataToSend)
-			}
+	feed.Write(data)
+}
+
+func (a *App) runZmodem(pr *io.PipeReader, pw *io.PipeWriter, dir string) {
+	a.emit("sys-msg", fmt.Sprintf("[ZMODEM] 开始接收文件到 %s", dir))
+
+	files, err := zmodem.Receive(pr, zmodemWriter{a}, dir, func(p zmodem.Progress) {
+		a.emitConn("zmodem-progress", p)
+	})
+	// 结束读取，之后的数据恢复正常显示
+	pr.Close()
+
+	a.streamMutex.Lock()
+	if a.zmodemFeed == pw {
+		a.zmodemFeed = nil
+		a.zmodemIdle.Stop()
+		a.zmodemIdle = nil
+	}
+	a.zmodemDetect.Reset()
+	a.streamMutex.Unlock()
+
+	result := ZmodemResult{Files: files}
+	if result.Files == nil {
+		result.Files = []string{}
+	}
+	if err != nil {
+		result.Error = err.Error()
+		if !errors.Is(err, zmodem.ErrAborted) && !errors.Is(err, errZmodemClosed) {
+			// 通知发送端停止发送
+			zmodemWriter{a}.Write(zmodem.CancelSequence)
 		}
-	}()
+		a.emit("sys-msg", fmt.Sprintf("[ZMODEM] 传输失败: %v", err))
+	} else {
+		a.emit("sys-msg", fmt.Sprintf("[ZMODEM] 已接收 %d 个文件", len(files)))
+	}
+	a.emitConn("zmodem-done", result)
 }
 
-// Close 关闭连接
-func (a *App) Close() string {
-	a.mutex.Lock()
-	defer a.mutex.Unlock()
+// zmodemWriter 将接收端的应答写入当前连接
+type zmodemWriter struct {
+	a *App
+}
 
-	if !a.isConnected {
-		return "Not connected"
+func (w zmodemWriter) Write(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
 	}
+	w.a.mutex.Lock()
+	defer w.a.mutex.Unlock()
 
-	a.isConnected = false
-	if a.readStopChan != nil {
-		close(a.readStopChan)
+	if res := w.a.sendLocked(p); !strings.HasPrefix(res, "Sent") {
+		return 0, errors.New(res)
 	}
+	return len(p), nil
+}
 
-	var err error
+// --- 诊断 ---
 
-	switch a.connType {
-	case TypeSerial:
-		if a.serialPort != nil {
-			err = a.serialPort.Close()
-			a.serialPort = nil
-		}
-	case TypeJLink:
-		if a.jlinkConn != nil {
-			a.jlinkConn.Close()
-			a.jlinkConn = nil
-		}
-	case TypeTcpClient:
-		if a.netConn != nil {
-			err = a.netConn.Close()
-			a.netConn = nil
-		}
-	case TypeTcpServer:
-		if a.netListener != nil {
-			err = a.netListener.Close()
-			a.netListener = nil
-		}
-		if a.netConn != nil {
-			a.netConn.Close()
-			a.netConn = nil
-		}
-	case TypeUdp:
-		if a.udpConn != nil {
-			err = a.udpConn.Close()
-			a.udpConn = nil
-			a.udpRemote = nil
-		}
+// RunDiagnostics 执行环境自检，返回结构化报告（报告中的 Text 字段可直接粘贴到 issue）
+// 所有检查都不会打开用户未选择的端口，单项检查最长耗时 diag.CheckTimeout
+func (a *App) RunDiagnostics() diag.Report {
+	configDir, _ := settings.ConfigDir()
+	return diag.Run(diag.DefaultProbes(Version, configDir), diag.CheckTimeout)
+}
+
+// ExportDiagnosticsBundle 将诊断报告、最近的日志、脱敏后的设置、构建信息及上一次连接的会话摘要打包为 zip 写入 path
+// historyKB > 0 时附带接收历史的最后 historyKB KB (需用户确认，可能包含设备数据)
+// 未连接或部分来源缺失时仍会生成，缺失项记录在包内的 MANIFEST.txt 中
+// path 可以是文件名模板 (见 nametmpl 包)，为空时使用 diagnostics 的默认模板；返回实际写入的文件路径
+func (a *App) ExportDiagnosticsBundle(path string, historyKB int) (string, error) {
+	path, err := a.outputPath(FileDiagnostics, path)
+	if err != nil {
+		return "", err
+	}
+	configDir, _ := settings.ConfigDir()
+	report := a.RunDiagnostics()
+
+	files := []diag.BundleFile{
+		{Name: "diagnostics.txt", Read: func() ([]byte, error) { return []byte(report.Text), nil }},
+		{Name: "build-info.txt", Read: buildInfo},
+		{Name: "connection.json", Read: func() ([]byte, error) {
+			return json.MarshalIndent(a.GetConnectionStatus(), "", "  ")
+		}},
+		{Name: "settings.json", Read: func() ([]byte, error) {
+			data, err := json.Marshal(a.settings.Get())
+			if err != nil {
+				return nil, err
+			}
+			return diag.RedactJSON(data)
+		}},
+	}
+	if last := a.GetLastSession(); last != nil {
+		files = append(files, diag.BundleFile{Name: "last-session.json", Read: func() ([]byte, error) {
+			return json.MarshalIndent(last, "", "  ")
+		}})
+	}
+	if configDir != "" {
+		files = append(files, diag.LogFiles(filepath.Join(configDir, "logs"))...)
+	}
+	if historyKB > 0 {
+		files = append(files, diag.BundleFile{Name: "history.bin", Read: func() ([]byte, error) {
+			return a.historyTail(historyKB * 1024)
+		}}, diag.BundleFile{Name: "history.hex", Read: func() ([]byte, error) {
+			data, err := a.historyTail(historyKB * 1024)
+			if err != nil {
+				return nil, err
+			}
+			return hexDumpAll(a.hexDumpOptions(), data), nil
+		}})
 	}
 
+	f, err := os.Create(path)
 	if err != nil {
-		return fmt.Sprintf("Error closing: %v", err)
+		return "", fmt.Errorf("failed to create bundle: %w", err)
 	}
-	return "Success"
+	if err := diag.WriteBundle(f, files); err != nil {
+		f.Close()
+		os.Remove(path)
+		return "", fmt.Errorf("failed to write bundle: %w", err)
+	}
+	return path, f.Close()
 }
 
-// SendData 发送数据
-func (a *App) SendData(data string) string {
-	a.mutex.Lock()
-	defer a.mutex.Unlock()
+// historyTail 返回接收历史中最后 limit 字节的数据
+func (a *App) historyTail(limit int) ([]byte, error) {
+	entries, _ := a.history.From(0)
+	total := 0
+	start := len(entries)
+	for start > 0 && total < limit {
+		start--
+		total += len(entries[start].Data)
+	}
+	if total == 0 {
+		return nil, fmt.Errorf("history is empty")
+	}
 
-	if !a.isConnected {
-		return "Error: Not connected"
+	out := make([]byte, 0, total)
+	for _, e := range entries[start:] {
+		out = append(out, e.Data...)
+	}
+	if len(out) > limit {
+		out = out[len(out)-limit:]
 	}
+	return out, nil
+}
 
-	payload := []byte(data)
-	var err error
+// hexDumpAll 以连续偏移转储全部数据，分块调用以避开单次转储的长度上限
+func hexDumpAll(opts format.DumpOptions, data []byte) []byte {
+	opts.Continuous = true
+	d := format.NewDumper(opts)
 
-	switch a.connType {
-	case TypeSerial:
-		if a.serialPort != nil {
-			_, err = a.serialPort.Write(payload)
-		}
-	case TypeJLink:
-		if a.jlinkConn != nil {
-			_, err = a.jlinkConn.WriteRTT(payload)
-		}
-	case TypeTcpClient, TypeTcpServer:
-		if a.netConn != nil {
-			_, err = a.netConn.Write(payload)
-		} else if a.connType == TypeTcpServer {
-			return "Error: No client connected"
-		}
-	case TypeUdp:
-		if a.udpConn != nil && a.udpRemote != nil {
-			_, err = a.udpConn.WriteTo(payload, a.udpRemote)
-		} else {
-			return "Error: No remote address set"
+	const block = 4096
+	var out strings.Builder
+	for len(data) > 0 {
+		n := block
+		if n > len(data) {
+			n = len(data)
 		}
+		s, _ := d.Dump(data[:n])
+		out.WriteString(s)
+		data = data[n:]
 	}
+	return []byte(out.String())
+}
 
-	if err != nil {
-		return fmt.Sprintf("Send error: %v", err)
+// buildInfo 返回版本、平台及 Go 模块信息
+func buildInfo() ([]byte, error) {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "version: %s\nplatform: %s/%s\ngo: %s\n", Version, goruntime.GOOS, goruntime.GOARCH, goruntime.Version())
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		sb.WriteString("\n")
+		sb.WriteString(bi.String())
 	}
-	return "Sent"
+	return []byte(sb.String()), nil
 }
 
 // --- Update Methods ---
@@ -535,26 +7365,113 @@ func (a *App) GetVersion() string {
 	return Version
 }
 
-// CheckForUpdates checks if a new version is available
+// SetCAFile sets an extra CA certificate file (PEM) trusted by all outbound HTTPS requests,
+// for networks that intercept TLS with an internal CA; an empty path trusts only the system roots.
+// The file is loaded and validated here, so a bad file is reported now rather than on the first request
+func (a *App) SetCAFile(path string) string {
+	t, err := httpclient.NewTransport(httpclient.Config{CAFile: path})
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	}
+	if err := a.settings.Update(func(s *settings.Settings) {
+		s.CAFile = path
+	}); err != nil {
+		return fmt.Sprintf("Error saving settings: %v", err)
+	}
+	updater.SetTransport(t)
+	return "Success"
+}
+
+// GetCAFile returns the configured extra CA certificate file
+func (a *App) GetCAFile() string {
+	return a.settings.Get().CAFile
+}
+
+// loadHTTPTransport applies the saved proxy and CA settings at startup;
+// if the CA file is no longer valid the system roots are used and a warning is printed
+func (a *App) loadHTTPTransport() {
+	t, err := httpclient.NewTransport(httpclient.Config{CAFile: a.settings.Get().CAFile})
+	if err != nil {
+		fmt.Printf("Ignoring CA file: %v\n", err)
+		t, _ = httpclient.NewTransport(httpclient.Config{})
+	}
+	updater.SetTransport(t)
+}
+
+// GetUpdatePolicy returns the update policy in force and where it came from.
+// The system policy file and SERIAL_MATE_UPDATE_POLICY override the user setting; see updater.ResolvePolicy
+func (a *App) GetUpdatePolicy() updater.EffectivePolicy {
+	return updater.LoadPolicy(updater.SystemPolicyPath(), a.settings.Get().UpdatePolicy)
+}
+
+// SetUpdatePolicy saves the user's update policy ("full", "notify-only" or "disabled").
+// Fails when the policy is forced by the system policy file or the environment
+func (a *App) SetUpdatePolicy(policy string) string {
+	p, err := updater.ParsePolicy(policy)
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	}
+	if eff := a.GetUpdatePolicy(); eff.Forced {
+		return fmt.Sprintf("Error: update policy is managed by the %s (%s) and cannot be changed", policySourceName(eff.Source), eff.Policy)
+	}
+	if err := a.settings.Update(func(s *settings.Settings) {
+		s.UpdatePolicy = string(p)
+	}); err != nil {
+		return fmt.Sprintf("Error saving settings: %v", err)
+	}
+	return "Success"
+}
+
+// policySourceName describes a policy source for error messages
+func policySourceName(source string) string {
+	switch source {
+	case updater.SourceSystem:
+		return "system policy file " + updater.SystemPolicyPath()
+	case updater.SourceEnv:
+		return "environment variable " + updater.PolicyEnvVar
+	default:
+		return "user settings"
+	}
+}
+
+// CheckForUpdates checks if a new version is available; fails with POLICY_DISABLED when updates are disabled
 func (a *App) CheckForUpdates() (updater.UpdateInfo, error) {
+	if eff := a.GetUpdatePolicy(); !eff.Policy.AllowsCheck() {
+		return updater.UpdateInfo{}, apperr.New(apperr.PolicyDisabled, "update checks are disabled by the %s", policySourceName(eff.Source))
+	}
 	info, err := updater.CheckForUpdates(Version)
 	if err != nil {
 		return updater.UpdateInfo{}, err
 	}
+	a.mutex.Lock()
+	a.lastUpdate = *info
+	a.mutex.Unlock()
 	return *info, nil
 }
 
-// DownloadAndInstallUpdate downloads and installs the update
+// DownloadAndInstallUpdate downloads and installs the update.
+// Under the notify-only and disabled policies it fails with POLICY_DISABLED and points to the release page
 func (a *App) DownloadAndInstallUpdate(downloadURL string) error {
-	// Download with progress reporting
-	tempFile, err := updater.DownloadUpdate(downloadURL, func(downloaded, total int64) {
-		// Emit progress event to frontend
-		progress := float64(downloaded) / float64(total) * 100
-		runtime.EventsEmit(a.ctx, "update-progress", map[string]interface{}{
-			"downloaded": downloaded,
-			"total":      total,
-			"progress":   progress,
-		})
+	if eff := a.GetUpdatePolicy(); !eff.Policy.AllowsInstall() {
+		return apperr.New(apperr.PolicyDisabled, "self-update is disabled by the %s; download the new version from %s",
+			policySourceName(eff.Source), updater.ChangelogURL())
+	}
+
+	// Check the install location before downloading; a read-only share cannot be updated in place
+	if err := updater.CheckInstallable(); err != nil {
+		return notWritableError(err)
+	}
+
+	// Download with progress reporting; a patch from the last check is preferred, with the
+	// full download as fallback when it is missing or fails to apply
+	a.mutex.Lock()
+	info := a.lastUpdate
+	a.mutex.Unlock()
+	if info.DownloadURL != downloadURL {
+		info = updater.UpdateInfo{DownloadURL: downloadURL}
+	}
+	tempFile, err := updater.FetchUpdate(info, a.emitUpdateProgress, func(err error) {
+		a.emit("update-patch-fallback", map[string]interface{}{"error": err.Error()})
 	})
 	if err != nil {
 		return fmt.Errorf("download failed: %w", err)
@@ -562,7 +7479,8 @@ func (a *App) DownloadAndInstallUpdate(downloadURL string) error {
 
 	// Install the update
 	if err := updater.InstallUpdate(tempFile); err != nil {
-		return fmt.Errorf("installation failed: %w", err)
+		os.Remove(tempFile)
+		return notWritableError(fmt.Errorf("installation failed: %w", err))
 	}
 
 	// Clean up temp file
@@ -583,10 +7501,66 @@ func (a *App) DownloadAndInstallUpdate(downloadURL string) error {
 	return nil // Unreachable, but kept for API consistency
 }
 
+// DownloadUpdateTo downloads the update found by the last CheckForUpdates into dir without installing it,
+// for installs that fail with NOT_WRITABLE; returns the path of the downloaded file
+func (a *App) DownloadUpdateTo(dir string) (string, error) {
+	if eff := a.GetUpdatePolicy(); !eff.Policy.AllowsInstall() {
+		return "", apperr.New(apperr.PolicyDisabled, "update downloads are disabled by the %s; download the new version from %s",
+			policySourceName(eff.Source), updater.ChangelogURL())
+	}
+	if dir == "" {
+		return "", fmt.Errorf("no download directory given")
+	}
+	a.mutex.Lock()
+	downloadURL := a.lastUpdate.DownloadURL
+	a.mutex.Unlock()
+	if downloadURL == "" {
+		return "", fmt.Errorf("no update available; check for updates first")
+	}
+
+	path, err := updater.DownloadUpdateTo(downloadURL, dir, a.expectedUpdateSize(downloadURL), a.emitUpdateProgress)
+	if err != nil {
+		return "", fmt.Errorf("download failed: %w", err)
+	}
+	return path, nil
+}
+
+// expectedUpdateSize returns the asset size from the last check when it is for downloadURL,
+// which lets the download reject truncated files and error pages
+func (a *App) expectedUpdateSize(downloadURL string) int64 {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	if a.lastUpdate.DownloadURL == downloadURL {
+		return a.lastUpdate.AssetSize
+	}
+	return 0
+}
+
+// emitUpdateProgress sends update-progress events; without Content-Length only the byte count is known
+func (a *App) emitUpdateProgress(downloaded, total int64) {
+	data := map[string]interface{}{
+		"downloaded": downloaded,
+		"total":      total,
+	}
+	if total > 0 {
+		data["progress"] = float64(downloaded) / float64(total) * 100
+	}
+	a.emit("update-progress", data)
+}
+
+// notWritableError tags an install location failure with NOT_WRITABLE so the frontend can offer DownloadUpdateTo
+func notWritableError(err error) error {
+	var nw *updater.NotWritableError
+	if errors.As(err, &nw) {
+		return apperr.Wrap(apperr.NotWritable, err, "cannot update in place; download the update to another folder instead")
+	}
+	return err
+}
+
 // QuitApp quits the application (user can manually restart it)
 func (a *App) QuitApp() {
-	// Close all connections first
-	a.Close()
+	// Close connections and flush files first
+	a.runCleanup()
 
 	// Quit the application
 	runtime.Quit(a.ctx)
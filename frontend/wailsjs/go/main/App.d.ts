@@ -12,9 +12,9 @@ export function GetSerialPorts():Promise<Array<string>>;
 
 export function GetVersion():Promise<string>;
 
-export function OpenJLink(arg1:string,arg2:number,arg3:string):Promise<string>;
+export function OpenJLink(arg1:string,arg2:number,arg3:string,arg4:string,arg5:string,arg6:number,arg7:number):Promise<string>;
 
-export function OpenSerial(arg1:string,arg2:number,arg3:number,arg4:number,arg5:string):Promise<string>;
+export function OpenSerial(arg1:string,arg2:number,arg3:number,arg4:number,arg5:string,arg6:string,arg7:string,arg8:number):Promise<string>;
 
 export function OpenTcpClient(arg1:string,arg2:string):Promise<string>;
 
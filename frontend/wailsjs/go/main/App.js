@@ -22,12 +22,12 @@ export function GetVersion() {
   return window['go']['main']['App']['GetVersion']();
 }
 
-export function OpenJLink(arg1, arg2, arg3) {
-  return window['go']['main']['App']['OpenJLink'](arg1, arg2, arg3);
+export function OpenJLink(arg1, arg2, arg3, arg4, arg5, arg6, arg7) {
+  return window['go']['main']['App']['OpenJLink'](arg1, arg2, arg3, arg4, arg5, arg6, arg7);
 }
 
-export function OpenSerial(arg1, arg2, arg3, arg4, arg5) {
-  return window['go']['main']['App']['OpenSerial'](arg1, arg2, arg3, arg4, arg5);
+export function OpenSerial(arg1, arg2, arg3, arg4, arg5, arg6, arg7, arg8) {
+  return window['go']['main']['App']['OpenSerial'](arg1, arg2, arg3, arg4, arg5, arg6, arg7, arg8);
 }
 
 export function OpenTcpClient(arg1, arg2) {
@@ -6,11 +6,15 @@ import (
 	"github.com/wailsapp/wails/v2"
 	"github.com/wailsapp/wails/v2/pkg/options"
 	"github.com/wailsapp/wails/v2/pkg/options/assetserver"
+	"github.com/wailsapp/wails/v2/pkg/options/windows"
 )
 
 // Version is the current application version
 const Version = "v1.3.7"
 
+// appName is the application name shown in the window title and log headers
+const appName = "serial-mate"
+
 //go:embed all:frontend/dist
 var assets embed.FS
 
@@ -20,7 +24,7 @@ func main() {
 
 	// Create application with options
 	err := wails.Run(&options.App{
-		Title:  "serial-mate",
+		Title:  appName,
 		Width:  1024,
 		Height: 768,
 		AssetServer: &assetserver.Options{
@@ -28,6 +32,13 @@ func main() {
 		},
 		BackgroundColour: &options.RGBA{R: 27, G: 38, B: 54, A: 1},
 		OnStartup:        app.startup,
+		OnDomReady:       app.domReady,
+		OnBeforeClose:    app.beforeClose,
+		OnShutdown:       app.shutdown,
+		Windows: &windows.Options{
+			OnSuspend: app.onSuspend,
+			OnResume:  app.onResume,
+		},
 		Bind: []interface{}{
 			app,
 		},
@@ -0,0 +1,203 @@
+// Package anchor 在接收数据中查找同步字 (锚点)，截取其后固定长度的窗口并统计每个位置的取值分布
+//
+// 用于分析未知的二进制协议：对齐到帧头后，哪些字节始终不变、哪些字节在变化一目了然。
+//
+// 重叠策略：窗口内出现的锚点视为窗口数据，不会开始新的截取；一个窗口收满后，从其后的第一个字节
+// 继续查找锚点。因此截取之间互不重叠，统计中的每个字节只被计入一次。
+package anchor
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// 参数上限
+const (
+	MaxAnchorLen = 64
+	MaxWindow    = 4096
+)
+
+// Capture 一次截取
+type Capture struct {
+	Index  int    // 从 1 开始的序号
+	Offset uint64 // 锚点第一个字节在数据流中的偏移
+	Data   []byte // 锚点之后的 window 个字节
+}
+
+// ValueCount 某个位置上一个取值出现的次数
+type ValueCount struct {
+	Value byte `json:"value"`
+	Count int  `json:"count"`
+}
+
+// Position 窗口中一个字节位置的统计
+type Position struct {
+	Offset   int          `json:"offset"` // 相对锚点之后第一个字节的偏移
+	Distinct int          `json:"distinct"`
+	Constant bool         `json:"constant"` // 所有截取中取值相同
+	Min      byte         `json:"min"`
+	Max      byte         `json:"max"`
+	Values   []ValueCount `json:"values"` // 直方图，按次数从多到少排列，次数相同时按取值排列
+}
+
+// Stats 截取的汇总
+type Stats struct {
+	Captures  int        `json:"captures"`
+	Max       int        `json:"max"` // 0 表示不限
+	Done      bool       `json:"done"`
+	Positions []Position `json:"positions"`
+}
+
+// Scanner 锚点截取器，Write 可作为 stream.Hub 的订阅回调
+type Scanner struct {
+	anchor    []byte
+	window    int
+	max       int
+	onCapture func(Capture)
+
+	mu        sync.Mutex
+	seen      uint64 // 已写入的字节数
+	tail      []byte // 查找锚点时上次未匹配的尾部 (最多 len(anchor)-1 字节)
+	capturing bool
+	start     uint64 // 当前窗口对应锚点的偏移
+	cur       []byte
+	captures  int
+	hist      [][256]int
+	done      bool
+	doneCh    chan struct{}
+}
+
+// New 创建截取器。max <= 0 时不限次数；onCapture 在每次截取完成后调用 (不持有内部锁)，可为 nil
+func New(anchor []byte, window, max int, onCapture func(Capture)) (*Scanner, error) {
+	if len(anchor) == 0 || len(anchor) > MaxAnchorLen {
+		return nil, fmt.Errorf("anchor must be 1 to %d bytes, got %d", MaxAnchorLen, len(anchor))
+	}
+	if window < 1 || window > MaxWindow {
+		return nil, fmt.Errorf("window must be between 1 and %d bytes, got %d", MaxWindow, window)
+	}
+	if max < 0 {
+		max = 0
+	}
+	return &Scanner{
+		anchor:    append([]byte(nil), anchor...),
+		window:    window,
+		max:       max,
+		onCapture: onCapture,
+		hist:      make([][256]int, window),
+		doneCh:    make(chan struct{}),
+	}, nil
+}
+
+// Write 处理一段接收数据，锚点与窗口都可以跨越多次 Write
+func (s *Scanner) Write(chunk []byte) {
+	s.mu.Lock()
+	captured := s.feed(chunk)
+	s.mu.Unlock()
+
+	if s.onCapture != nil {
+		for _, c := range captured {
+			s.onCapture(c)
+		}
+	}
+}
+
+func (s *Scanner) feed(chunk []byte) []Capture {
+	if s.done {
+		return nil
+	}
+	at := s.seen // chunk[0] 在数据流中的偏移
+	s.seen += uint64(len(chunk))
+
+	var captured []Capture
+	for len(chunk) > 0 {
+		if s.capturing {
+			n := min(len(chunk), s.window-len(s.cur))
+			s.cur = append(s.cur, chunk[:n]...)
+			chunk, at = chunk[n:], at+uint64(n)
+			if len(s.cur) < s.window {
+				break
+			}
+			captured = append(captured, s.finish())
+			if s.done {
+				break
+			}
+			continue
+		}
+
+		buf := chunk
+		if len(s.tail) > 0 {
+			buf = append(s.tail, chunk...)
+		}
+		idx := bytes.Index(buf, s.anchor)
+		if idx < 0 {
+			keep := min(len(buf), len(s.anchor)-1)
+			s.tail = append([]byte(nil), buf[len(buf)-keep:]...)
+			break
+		}
+		s.capturing = true
+		s.start = at - uint64(len(s.tail)) + uint64(idx)
+		skip := idx + len(s.anchor) - len(s.tail) // 锚点结束位置在 chunk 中的下标
+		s.tail = nil
+		chunk, at = chunk[skip:], at+uint64(skip)
+	}
+	return captured
+}
+
+// finish 结束当前窗口，更新统计
+func (s *Scanner) finish() Capture {
+	s.captures++
+	for i, b := range s.cur {
+		s.hist[i][b]++
+	}
+	c := Capture{Index: s.captures, Offset: s.start, Data: s.cur}
+	s.cur = nil
+	s.capturing = false
+	if s.max > 0 && s.captures >= s.max {
+		s.done = true
+		close(s.doneCh)
+	}
+	return c
+}
+
+// Anchor 返回锚点
+func (s *Scanner) Anchor() []byte {
+	return s.anchor
+}
+
+// Window 返回窗口长度
+func (s *Scanner) Window() int {
+	return s.window
+}
+
+// Done 返回达到截取次数上限时关闭的通道
+func (s *Scanner) Done() <-chan struct{} {
+	return s.doneCh
+}
+
+// Stats 返回截取次数与每个位置的取值分布
+func (s *Scanner) Stats() Stats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	st := Stats{Captures: s.captures, Max: s.max, Done: s.done, Positions: make([]Position, s.window)}
+	for i := range s.hist {
+		p := Position{Offset: i}
+		for v, n := range s.hist[i] {
+			if n == 0 {
+				continue
+			}
+			if p.Distinct == 0 {
+				p.Min = byte(v)
+			}
+			p.Max = byte(v)
+			p.Distinct++
+			p.Values = append(p.Values, ValueCount{Value: byte(v), Count: n})
+		}
+		sort.SliceStable(p.Values, func(a, b int) bool { return p.Values[a].Count > p.Values[b].Count })
+		p.Constant = p.Distinct == 1
+		st.Positions[i] = p
+	}
+	return st
+}
@@ -0,0 +1,97 @@
+package anchor
+
+import (
+	"bytes"
+	"testing"
+)
+
+func collect(t *testing.T, anchor []byte, window, max int) (*Scanner, *[]Capture) {
+	t.Helper()
+	var got []Capture
+	s, err := New(anchor, window, max, func(c Capture) {
+		c.Data = append([]byte(nil), c.Data...)
+		got = append(got, c)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s, &got
+}
+
+func TestCaptureAcrossChunkBoundaries(t *testing.T) {
+	stream := []byte{0x00, 0xAA, 0x55, 0x01, 0x02, 0x03, 0xFF, 0xAA, 0x55, 0x01, 0x07, 0x03}
+	// 逐字节写入与整块写入结果相同
+	for _, step := range []int{1, 2, 5, len(stream)} {
+		s, got := collect(t, []byte{0xAA, 0x55}, 3, 0)
+		for i := 0; i < len(stream); i += step {
+			s.Write(stream[i:min(i+step, len(stream))])
+		}
+		if len(*got) != 2 {
+			t.Fatalf("step %d: %d captures", step, len(*got))
+		}
+		c := (*got)[1]
+		if c.Index != 2 || c.Offset != 7 || !bytes.Equal(c.Data, []byte{0x01, 0x07, 0x03}) {
+			t.Errorf("step %d: capture = %+v", step, c)
+		}
+	}
+}
+
+func TestAnchorInsideWindowIsData(t *testing.T) {
+	s, got := collect(t, []byte{0x7E}, 4, 0)
+	s.Write([]byte{0x7E, 0x01, 0x7E, 0x02, 0x03, 0x7E, 0x09, 0x09, 0x09, 0x09})
+	if len(*got) != 2 {
+		t.Fatalf("%d captures", len(*got))
+	}
+	if !bytes.Equal((*got)[0].Data, []byte{0x01, 0x7E, 0x02, 0x03}) || (*got)[1].Offset != 5 {
+		t.Errorf("captures = %+v", *got)
+	}
+}
+
+func TestMaxCapturesAndStats(t *testing.T) {
+	s, got := collect(t, []byte("$"), 3, 3)
+	s.Write([]byte("$A1x$A2y$A3z$A4w"))
+	if len(*got) != 3 {
+		t.Fatalf("%d captures", len(*got))
+	}
+	select {
+	case <-s.Done():
+	default:
+		t.Fatal("Done not closed after max captures")
+	}
+	s.Write([]byte("$A5v"))
+	if len(*got) != 3 {
+		t.Error("captured after reaching max")
+	}
+
+	st := s.Stats()
+	if st.Captures != 3 || !st.Done || len(st.Positions) != 3 {
+		t.Fatalf("stats = %+v", st)
+	}
+	if p := st.Positions[0]; !p.Constant || p.Values[0] != (ValueCount{'A', 3}) {
+		t.Errorf("position 0 = %+v", p)
+	}
+	if p := st.Positions[1]; p.Constant || p.Distinct != 3 || p.Min != '1' || p.Max != '3' {
+		t.Errorf("position 1 = %+v", p)
+	}
+}
+
+func TestHistogramOrder(t *testing.T) {
+	s, _ := collect(t, []byte{0xAA}, 1, 0)
+	s.Write([]byte{0xAA, 5, 0xAA, 9, 0xAA, 9, 0xAA, 1})
+	vals := s.Stats().Positions[0].Values
+	if len(vals) != 3 || vals[0] != (ValueCount{9, 2}) || vals[1].Value != 1 || vals[2].Value != 5 {
+		t.Errorf("histogram = %+v", vals)
+	}
+}
+
+func TestNewValidates(t *testing.T) {
+	if _, err := New(nil, 4, 0, nil); err == nil {
+		t.Error("expected an error for an empty anchor")
+	}
+	if _, err := New([]byte{1}, 0, 0, nil); err == nil {
+		t.Error("expected an error for a zero window")
+	}
+	if _, err := New([]byte{1}, MaxWindow+1, 0, nil); err == nil {
+		t.Error("expected an error for an oversized window")
+	}
+}
@@ -0,0 +1,104 @@
+// Package apperr 定义带错误码的应用错误，便于前端按错误码区分处理
+package apperr
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Code 错误码
+type Code string
+
+const (
+	// WriteTimeout 写入在超时时间内未完成
+	WriteTimeout Code = "WRITE_TIMEOUT"
+	// PortClaimed 端口已被另一个 serial-mate 实例占用
+	PortClaimed Code = "PORT_CLAIMED_BY_OTHER_INSTANCE"
+	// ReadOnly 只读模式下拒绝发送
+	ReadOnly Code = "READ_ONLY"
+	// PayloadTooLarge 发送数据超过大小上限
+	PayloadTooLarge Code = "PAYLOAD_TOO_LARGE"
+	// InvalidPayload 发送数据无法解析 (例如不合法的 UTF-8 或十六进制)
+	InvalidPayload Code = "INVALID_PAYLOAD"
+	// EmptyPayload 发送数据为空
+	EmptyPayload Code = "EMPTY_PAYLOAD"
+	// DeviceRemoved 设备被拔出或驱动报告设备已不存在
+	DeviceRemoved Code = "DEVICE_REMOVED"
+	// RemoteClosed 对端正常关闭了连接
+	RemoteClosed Code = "REMOTE_CLOSED"
+	// ConnectionReset 连接被对端或中间设备重置
+	ConnectionReset Code = "CONNECTION_RESET"
+	// NetworkUnreachable 本机网络或目标主机不可达
+	NetworkUnreachable Code = "NETWORK_UNREACHABLE"
+	// PortBusy 端口被其他程序占用
+	PortBusy Code = "PORT_BUSY"
+	// PortNotFound 端口不存在
+	PortNotFound Code = "PORT_NOT_FOUND"
+	// InvalidPort 设备不是串口
+	InvalidPort Code = "INVALID_PORT"
+	// UnsupportedSettings 驱动不支持所选的波特率、数据位、校验或停止位
+	UnsupportedSettings Code = "UNSUPPORTED_SETTINGS"
+	// PortClosed 端口在操作进行中被关闭
+	PortClosed Code = "PORT_CLOSED"
+	// PermissionDenied 操作系统拒绝访问设备或套接字
+	PermissionDenied Code = "PERMISSION_DENIED"
+	// RTTOffsetCorrupt RTT 控制块中的读写偏移量无效，通常是目标复位导致
+	RTTOffsetCorrupt Code = "RTT_OFFSET_CORRUPT"
+	// ProbeLost 无法通过调试探针访问目标内存
+	ProbeLost Code = "PROBE_LOST"
+	// ReconnectFailed 自动重新打开连接失败 (看门狗、睡眠唤醒、定时任务)
+	ReconnectFailed Code = "RECONNECT_FAILED"
+	// IOError 未能归类的读写错误
+	IOError Code = "IO_ERROR"
+	// PolicyDisabled 操作被管理员设置的策略禁止 (例如受管安装中的自动更新)
+	PolicyDisabled Code = "POLICY_DISABLED"
+	// ProfileActionFailed 连接配置打开后的自动动作失败，连接保持打开
+	ProfileActionFailed Code = "PROFILE_ACTION_FAILED"
+	// NoClient TCP 服务端没有接入的客户端，发送无处可去
+	NoClient Code = "NO_CLIENT"
+	// NotWritable 程序所在目录不可写 (只读共享、其他用户的安装目录)，无法自动更新
+	NotWritable Code = "NOT_WRITABLE"
+	// OpenTimeout 打开连接超时 (驱动或探针无响应、DNS 解析过慢)
+	OpenTimeout Code = "OPEN_TIMEOUT"
+	// OpenCanceled 用户取消了正在进行的打开操作 (App.CancelOpen)
+	OpenCanceled Code = "OPEN_CANCELED"
+	// DeviceAmbiguous 多个插入的设备共用连接配置绑定的设备指纹 (没有序列号)，需要用户选择端口
+	DeviceAmbiguous Code = "DEVICE_AMBIGUOUS"
+)
+
+// Error 带错误码的错误
+type Error struct {
+	Code    Code
+	Message string
+	Err     error // 原始错误，可为 nil
+}
+
+// New 创建带错误码的错误
+func New(code Code, format string, args ...interface{}) *Error {
+	return &Error{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// Wrap 为已有错误附加错误码
+func Wrap(code Code, err error, format string, args ...interface{}) *Error {
+	return &Error{Code: code, Message: fmt.Sprintf(format, args...), Err: err}
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%s: %s: %v", e.Code, e.Message, e.Err)
+	}
+	return fmt.Sprintf("%s: %s", e.Code, e.Message)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// CodeOf 返回错误链中第一个 *Error 的错误码，没有时返回空字符串
+func CodeOf(err error) Code {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Code
+	}
+	return ""
+}
@@ -0,0 +1,81 @@
+package apperr
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestErrorFormatting(t *testing.T) {
+	err := New(WriteTimeout, "write did not complete within %dms", 500)
+	if got := err.Error(); got != "WRITE_TIMEOUT: write did not complete within 500ms" {
+		t.Errorf("Unexpected message: %q", got)
+	}
+
+	cause := errors.New("i/o timeout")
+	wrapped := Wrap(WriteTimeout, cause, "serial write")
+	if got := wrapped.Error(); got != "WRITE_TIMEOUT: serial write: i/o timeout" {
+		t.Errorf("Unexpected wrapped message: %q", got)
+	}
+	if !errors.Is(wrapped, cause) {
+		t.Error("Wrapped error should unwrap to its cause")
+	}
+}
+
+func TestCodeOf(t *testing.T) {
+	err := fmt.Errorf("send failed: %w", New(WriteTimeout, "timeout"))
+	if code := CodeOf(err); code != WriteTimeout {
+		t.Errorf("CodeOf() = %q, expected %q", code, WriteTimeout)
+	}
+	if code := CodeOf(errors.New("plain")); code != "" {
+		t.Errorf("CodeOf(plain) = %q, expected empty", code)
+	}
+	if code := CodeOf(nil); code != "" {
+		t.Errorf("CodeOf(nil) = %q, expected empty", code)
+	}
+}
+
+func TestNewEvent(t *testing.T) {
+	ev := NewEvent(DeviceRemoved, "read /dev/ttyUSB0: input/output error")
+	if ev.Code != DeviceRemoved || ev.Message == "" || ev.Detail != "read /dev/ttyUSB0: input/output error" {
+		t.Errorf("Unexpected event: %+v", ev)
+	}
+	if len(ev.SuggestedActions) == 0 || ev.SuggestedActions[0] != ActionCheckCable {
+		t.Errorf("Unexpected actions: %v", ev.SuggestedActions)
+	}
+
+	// 修改返回的操作列表不影响之后的事件
+	ev.SuggestedActions[0] = "changed"
+	if NewEvent(DeviceRemoved, "").SuggestedActions[0] != ActionCheckCable {
+		t.Error("NewEvent shares its action slice")
+	}
+
+	if ev := NewEvent(EmptyPayload, ""); ev.SuggestedActions == nil {
+		t.Error("SuggestedActions should be an empty list, not nil")
+	}
+	if ev := NewEvent("SOMETHING_NEW", "x"); ev.Message != "SOMETHING_NEW" || len(ev.SuggestedActions) != 0 {
+		t.Errorf("Unexpected event for unknown code: %+v", ev)
+	}
+}
+
+func TestEveryCodeHasCatalogEntry(t *testing.T) {
+	for _, code := range []Code{WriteTimeout, PortClaimed, ReadOnly, PayloadTooLarge, InvalidPayload, EmptyPayload,
+		DeviceRemoved, RemoteClosed, ConnectionReset, NetworkUnreachable, PortBusy, PortNotFound, InvalidPort,
+		UnsupportedSettings, PortClosed, PermissionDenied, RTTOffsetCorrupt,
+		ProbeLost, ReconnectFailed, IOError, PolicyDisabled, ProfileActionFailed, NoClient, NotWritable,
+		OpenTimeout, OpenCanceled} {
+		if _, ok := catalog[code]; !ok {
+			t.Errorf("No catalog entry for %s", code)
+		}
+	}
+}
+
+func TestEventOf(t *testing.T) {
+	err := fmt.Errorf("send: %w", Wrap(WriteTimeout, errors.New("i/o timeout"), "serial write"))
+	if ev := EventOf(err); ev.Code != WriteTimeout || ev.Detail != err.Error() {
+		t.Errorf("Unexpected event: %+v", ev)
+	}
+	if ev := EventOf(errors.New("boom")); ev.Code != IOError {
+		t.Errorf("Plain error mapped to %s", ev.Code)
+	}
+}
@@ -0,0 +1,90 @@
+package apperr
+
+import "errors"
+
+// Action 前端可以为错误提供的恢复操作，每个操作对应已有的 App 方法
+type Action string
+
+const (
+	// ActionReconnect 以相同参数重新打开连接 (App.Reconnect)
+	ActionReconnect Action = "reconnect"
+	// ActionCheckCable 提示检查线缆与设备供电，没有对应的方法
+	ActionCheckCable Action = "check-cable"
+	// ActionRunDiagnostics 运行环境诊断 (App.RunDiagnostics)
+	ActionRunDiagnostics Action = "run-diagnostics"
+	// ActionCheckPermissions 提示检查设备权限 (例如 Linux 的 dialout 组)，诊断报告中包含相关检查
+	ActionCheckPermissions Action = "check-permissions"
+	// ActionReleasePortClaim 解除其他实例对端口的占用 (App.ReleasePortClaim)
+	ActionReleasePortClaim Action = "release-port-claim"
+	// ActionDisableReadOnly 关闭只读模式 (App.SetReadOnly)
+	ActionDisableReadOnly Action = "disable-read-only"
+	// ActionIncreaseWriteTimeout 增大写入超时 (App.SetWriteTimeout)
+	ActionIncreaseWriteTimeout Action = "increase-write-timeout"
+	// ActionDownloadUpdateTo 将更新下载到用户选择的目录 (App.DownloadUpdateTo)
+	ActionDownloadUpdateTo Action = "download-update-to"
+)
+
+// Event app-error 事件的数据
+type Event struct {
+	Code             Code     `json:"code"`
+	Message          string   `json:"message"`          // 面向用户的简短说明
+	Detail           string   `json:"detail,omitempty"` // 原始错误文本
+	SuggestedActions []Action `json:"suggestedActions"`
+}
+
+type catalogEntry struct {
+	message string
+	actions []Action
+}
+
+// catalog 每个错误码的说明与建议操作
+var catalog = map[Code]catalogEntry{
+	WriteTimeout:        {"The device did not accept data in time", []Action{ActionCheckCable, ActionIncreaseWriteTimeout}},
+	PortClaimed:         {"The port is in use by another serial-mate window", []Action{ActionReleasePortClaim}},
+	ReadOnly:            {"The connection is read-only", []Action{ActionDisableReadOnly}},
+	PayloadTooLarge:     {"The data is larger than the send limit", nil},
+	InvalidPayload:      {"The data could not be parsed", nil},
+	EmptyPayload:        {"There is nothing to send", nil},
+	DeviceRemoved:       {"The device was disconnected", []Action{ActionCheckCable, ActionReconnect}},
+	RemoteClosed:        {"The remote side closed the connection", []Action{ActionReconnect}},
+	ConnectionReset:     {"The connection was reset", []Action{ActionReconnect, ActionRunDiagnostics}},
+	NetworkUnreachable:  {"The network or host is unreachable", []Action{ActionRunDiagnostics, ActionReconnect}},
+	PortBusy:            {"The port is in use by another program", []Action{ActionRunDiagnostics}},
+	PortNotFound:        {"The port does not exist", []Action{ActionCheckCable, ActionRunDiagnostics}},
+	InvalidPort:         {"The device is not a serial port", []Action{ActionRunDiagnostics}},
+	UnsupportedSettings: {"The driver does not support the selected port settings", nil},
+	PortClosed:          {"The port was closed", []Action{ActionReconnect}},
+	PermissionDenied:    {"Access to the device was denied", []Action{ActionCheckPermissions, ActionRunDiagnostics}},
+	RTTOffsetCorrupt:    {"The RTT buffer state is invalid, the target was probably reset", []Action{ActionReconnect}},
+	ProbeLost:           {"The debug probe cannot access the target", []Action{ActionCheckCable, ActionReconnect}},
+	ReconnectFailed:     {"The connection could not be reopened", []Action{ActionReconnect, ActionCheckCable, ActionRunDiagnostics}},
+	IOError:             {"A read or write error occurred", []Action{ActionReconnect, ActionRunDiagnostics}},
+	PolicyDisabled:      {"This action is disabled by an administrator policy", nil},
+	ProfileActionFailed: {"A profile's on-connect action failed; the connection is still open", nil},
+	NoClient:            {"No client is connected to the TCP server", []Action{ActionCheckCable}},
+	NotWritable:         {"serial-mate cannot update itself in its current location", []Action{ActionDownloadUpdateTo}},
+	OpenTimeout:         {"The device did not respond while opening the connection", []Action{ActionCheckCable, ActionRunDiagnostics}},
+	OpenCanceled:        {"Opening the connection was canceled", nil},
+}
+
+// NewEvent 按错误码生成事件，detail 为原始错误文本；未知的错误码没有建议操作
+func NewEvent(code Code, detail string) Event {
+	entry, ok := catalog[code]
+	if !ok {
+		entry = catalogEntry{message: string(code)}
+	}
+	actions := append([]Action{}, entry.actions...)
+	return Event{Code: code, Message: entry.message, Detail: detail, SuggestedActions: actions}
+}
+
+// EventOf 为带错误码的错误生成事件，其他错误归为 IOError
+func EventOf(err error) Event {
+	if err == nil {
+		return NewEvent(IOError, "")
+	}
+	var e *Error
+	if errors.As(err, &e) {
+		return NewEvent(e.Code, err.Error())
+	}
+	return NewEvent(IOError, err.Error())
+}
@@ -0,0 +1,214 @@
+// Package bench 在不建立连接的情况下试运行接收处理设置
+//
+// 界面编辑分帧、分类或绘图设置时，把一段样本输入交给 Run，即可预览解出的帧、分类结果与统计；
+// 各阶段使用与实时接收路径相同的实现 (textenc、classify、frame、plot 与 stream.Matcher)，
+// 每次调用都新建全部状态，不读取也不修改任何连接的状态，可在连接进行中随时调用。
+package bench
+
+import (
+	"fmt"
+	"time"
+
+	"serial-assistant/pkg/classify"
+	"serial-assistant/pkg/frame"
+	"serial-assistant/pkg/input"
+	"serial-assistant/pkg/plot"
+	"serial-assistant/pkg/stream"
+	"serial-assistant/pkg/textenc"
+)
+
+// MaxInput 样本输入的最大字节数，预览不需要更多数据
+const MaxInput = 64 << 10
+
+// Config 要试运行的阶段，未设置的阶段不参与
+type Config struct {
+	RxEncoding      textenc.Mode    `json:"rxEncoding,omitempty"`      // 接收编码，同 SetRxEncoding，为空时原样输出
+	ClassifierRules []classify.Rule `json:"classifierRules,omitempty"` // 行分类规则，同 SetClassifierRules
+	Frame           *frame.Config   `json:"frame,omitempty"`           // 长度前缀帧格式，同 SetFrameDecoder
+	PlotParser      string          `json:"plotParser,omitempty"`      // 绘图协议，同 SetPlotParser
+	Match           []string        `json:"match,omitempty"`           // 应答模式，与 SendReliable 的 ACK/NAK 使用相同的匹配器
+	HexMatch        bool            `json:"hexMatch,omitempty"`        // Match 为十六进制字符串
+
+	HexInput  bool `json:"hexInput,omitempty"`  // 输入为十六进制 (支持 ParseHexDump 识别的各种转储格式)
+	ChunkSize int  `json:"chunkSize,omitempty"` // 按该大小切分输入以模拟多次读取，检查跨块的处理；0 表示一次输入
+}
+
+// Chunk 处理链末端输出的一个数据块，对应实时路径中的一个 serial-data 事件
+type Chunk struct {
+	Data  []byte               `json:"data"`
+	Lines []classify.LineClass `json:"lines,omitempty"`
+}
+
+// Match 应答模式的匹配结果
+type Match struct {
+	Pattern int `json:"pattern"` // 在 Config.Match 中的下标
+	Chunk   int `json:"chunk"`   // 匹配完成时所在的输出块下标
+}
+
+// Counters 试运行统计
+type Counters struct {
+	InputBytes   int            `json:"inputBytes"`
+	Reads        int            `json:"reads"`        // 按 ChunkSize 切分后的输入次数
+	Chunks       int            `json:"chunks"`       // 输出块数
+	OutputBytes  int            `json:"outputBytes"`  // 输出字节数 (经过编码转换)
+	Lines        int            `json:"lines"`        // 匹配到类别的行数
+	Classes      map[string]int `json:"classes"`      // 各类别的行数
+	Frame        frame.Stats    `json:"frame"`        // 帧解码统计
+	FramePending int            `json:"framePending"` // 输入结束时尚未组成完整帧的字节数
+	Samples      int            `json:"samples"`      // 绘图采样点数
+}
+
+// Result 试运行结果
+type Result struct {
+	Chunks   []Chunk       `json:"chunks"`
+	Frames   []frame.Frame `json:"frames"`
+	Samples  []plot.Sample `json:"samples"`
+	Match    *Match        `json:"match,omitempty"` // 没有配置模式或没有匹配时为空
+	Encoding textenc.Mode  `json:"encoding"`        // 输入结束时生效的编码 (auto 时为检测结果)
+	Notes    []string      `json:"notes,omitempty"` // 编码检测等过程中的提示
+	Counters Counters      `json:"counters"`
+}
+
+// stages 一次试运行的全部阶段状态
+type stages struct {
+	pipeline   *stream.Pipeline
+	decoder    *textenc.Decoder
+	classifier *classify.Classifier
+	tracker    classify.Tracker
+	frames     *frame.Decoder
+	plot       *plot.Parser
+	matcher    *stream.Matcher
+}
+
+// newStages 按 cfg 创建阶段，notes 收集编码检测的结论
+func newStages(cfg Config, notes *[]string) (*stages, error) {
+	s := &stages{}
+
+	mode := cfg.RxEncoding
+	if mode == "" {
+		mode = textenc.Raw
+	}
+	if _, err := textenc.ParseMode(string(mode)); err != nil {
+		return nil, fmt.Errorf("rxEncoding: %v", err)
+	}
+	s.decoder = textenc.NewDecoder(mode, func(m textenc.Mode, reason string) {
+		*notes = append(*notes, fmt.Sprintf("RX encoding: %s (%s)", m, reason))
+	})
+	s.pipeline = stream.New(stream.Map(s.decoder.Decode))
+
+	c, err := classify.Compile(cfg.ClassifierRules)
+	if err != nil {
+		return nil, fmt.Errorf("classifierRules: %v", err)
+	}
+	s.classifier = c
+
+	if cfg.Frame != nil {
+		d, err := frame.NewDecoder(*cfg.Frame)
+		if err != nil {
+			return nil, fmt.Errorf("frame: %v", err)
+		}
+		s.frames = d
+	}
+
+	if cfg.PlotParser != "" {
+		p, err := plot.ParseProtocol(cfg.PlotParser)
+		if err != nil {
+			return nil, fmt.Errorf("plotParser: %v", err)
+		}
+		s.plot = plot.NewParser(p)
+	}
+
+	if len(cfg.Match) > 0 {
+		patterns := make([][]byte, len(cfg.Match))
+		for i, m := range cfg.Match {
+			p := []byte(m)
+			if cfg.HexMatch {
+				if p, err = input.ParseHex(m); err != nil {
+					return nil, fmt.Errorf("match %d: %v", i, err)
+				}
+			}
+			patterns[i] = p
+		}
+		s.matcher = stream.NewMatcher(patterns...)
+	}
+	return s, nil
+}
+
+// Run 按 cfg 创建各阶段并处理 in，返回各阶段的输出与统计
+// HexInput 时 in 按 input.ParseHexDump 解析，否则按原样作为字节
+func Run(cfg Config, in string) (Result, error) {
+	if cfg.ChunkSize < 0 {
+		return Result{}, fmt.Errorf("chunkSize must not be negative, got %d", cfg.ChunkSize)
+	}
+	data := []byte(in)
+	if cfg.HexInput {
+		dump, err := input.ParseHexDump(in)
+		if err != nil {
+			return Result{}, err
+		}
+		data = dump.Data
+	}
+	if len(data) > MaxInput {
+		return Result{}, fmt.Errorf("sample input of %d bytes exceeds the %d byte limit", len(data), MaxInput)
+	}
+
+	res := Result{Chunks: []Chunk{}, Frames: []frame.Frame{}, Samples: []plot.Sample{}}
+	s, err := newStages(cfg, &res.Notes)
+	if err != nil {
+		return Result{}, err
+	}
+
+	n := &res.Counters
+	n.InputBytes = len(data)
+	n.Classes = map[string]int{}
+	// 采样点时间以开始时间为基准，按读取次数递增 1ms，使预览中的顺序稳定
+	start := time.Now()
+	for len(data) > 0 {
+		read := data
+		if cfg.ChunkSize > 0 && len(read) > cfg.ChunkSize {
+			read = read[:cfg.ChunkSize]
+		}
+		data = data[len(read):]
+		t := start.Add(time.Duration(n.Reads) * time.Millisecond)
+		n.Reads++
+
+		for _, out := range s.pipeline.Process(read) {
+			s.feed(&res, t, out)
+		}
+	}
+
+	res.Encoding = s.decoder.Mode()
+	if s.frames != nil {
+		n.Frame = s.frames.Stats()
+		n.FramePending = s.frames.Pending()
+	}
+	return res, nil
+}
+
+// feed 让一个输出块经过处理链之后的各阶段，顺序与实时路径的 emitChunks 相同
+func (s *stages) feed(res *Result, t time.Time, chunk []byte) {
+	n := &res.Counters
+	c := Chunk{Data: chunk, Lines: s.tracker.Feed(s.classifier, chunk)}
+	for _, l := range c.Lines {
+		n.Lines++
+		n.Classes[l.Class]++
+	}
+	res.Chunks = append(res.Chunks, c)
+	n.Chunks++
+	n.OutputBytes += len(chunk)
+
+	if s.matcher != nil && res.Match == nil {
+		s.matcher.Write(chunk)
+		if i := s.matcher.Matched(); i >= 0 {
+			res.Match = &Match{Pattern: i, Chunk: n.Chunks - 1}
+		}
+	}
+	if s.plot != nil {
+		samples := s.plot.Feed(t, chunk)
+		res.Samples = append(res.Samples, samples...)
+		n.Samples += len(samples)
+	}
+	if s.frames != nil {
+		res.Frames = append(res.Frames, s.frames.Feed(chunk)...)
+	}
+}
@@ -0,0 +1,130 @@
+package bench
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"serial-assistant/pkg/classify"
+	"serial-assistant/pkg/frame"
+)
+
+func TestRunClassifiesLinesAcrossReads(t *testing.T) {
+	cfg := Config{
+		ClassifierRules: []classify.Rule{
+			{Pattern: "ERR", Class: "error"},
+			{Pattern: `^W\d+`, Regex: true, Class: "warn"},
+		},
+		ChunkSize: 3,
+	}
+	res, err := Run(cfg, "boot ok\nERROR: x\nW12 low\nERR again\n")
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	c := res.Counters
+	if c.Reads != 12 || c.Chunks != 12 || c.InputBytes != 35 || c.OutputBytes != 35 {
+		t.Errorf("counters = %+v", c)
+	}
+	if c.Lines != 3 || c.Classes["error"] != 2 || c.Classes["warn"] != 1 {
+		t.Errorf("classes = %v (%d lines)", c.Classes, c.Lines)
+	}
+}
+
+func TestRunFrames(t *testing.T) {
+	fc := frame.Config{Sync: []byte{0xAA}, LenBytes: 1, Checksum: "crc8-maxim"}
+	good, _ := fc.Encode([]byte{1, 2, 3})
+	bad, _ := fc.Encode([]byte{4, 5})
+	bad[len(bad)-1] ^= 0xFF
+	var buf bytes.Buffer
+	buf.WriteString("\x00\x01")
+	buf.Write(good)
+	buf.Write(bad)
+	buf.Write(good[:3])
+
+	res, err := Run(Config{Frame: &fc, HexInput: true, ChunkSize: 2}, fmt.Sprintf("% x", buf.Bytes()))
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if len(res.Frames) != 2 || !bytes.Equal(res.Frames[0].Payload, []byte{1, 2, 3}) || !res.Frames[1].BadChecksum {
+		t.Errorf("frames = %+v", res.Frames)
+	}
+	c := res.Counters
+	if c.Frame.Frames != 1 || c.Frame.BadChecksum != 1 || c.Frame.Resyncs != 2 || c.FramePending != 3 {
+		t.Errorf("frame counters = %+v, pending %d", c.Frame, c.FramePending)
+	}
+}
+
+func TestRunPlotAndMatch(t *testing.T) {
+	cfg := Config{PlotParser: "string", Match: []string{"OK", "FAIL"}, ChunkSize: 4}
+	res, err := Run(cfg, "&DRAW,1,2#&DRAW,3.5,-1#...FAIL...OK")
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if res.Counters.Samples != 2 || len(res.Samples) != 2 || res.Samples[1].Values[0] != 3.5 {
+		t.Errorf("samples = %+v", res.Samples)
+	}
+	if res.Match == nil || res.Match.Pattern != 1 {
+		t.Fatalf("match = %+v, want FAIL", res.Match)
+	}
+	if got := res.Chunks[res.Match.Chunk].Data; !bytes.Contains(got, []byte("IL")) {
+		t.Errorf("match chunk = %q", got)
+	}
+}
+
+func TestRunUTF16Detection(t *testing.T) {
+	in := "\xff\xfeh\x00e\x00l\x00l\x00o\x00\n\x00"
+	res, err := Run(Config{RxEncoding: "auto"}, in)
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if res.Encoding != "utf-16le" || len(res.Notes) != 1 {
+		t.Errorf("encoding = %s, notes = %v", res.Encoding, res.Notes)
+	}
+	var out []byte
+	for _, c := range res.Chunks {
+		out = append(out, c.Data...)
+	}
+	if string(out) != "hello\n" {
+		t.Errorf("output = %q", out)
+	}
+}
+
+func TestRunIsolated(t *testing.T) {
+	cfg := Config{ClassifierRules: []classify.Rule{{Pattern: "E", Class: "error"}}}
+	// 未结束的行不会带到下一次试运行
+	if _, err := Run(cfg, "E"); err != nil {
+		t.Fatal(err)
+	}
+	res, err := Run(cfg, "\nx\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Counters.Lines != 0 {
+		t.Errorf("state leaked between runs: %+v", res.Counters)
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  Config
+		in   string
+		msg  string
+	}{
+		{"bad encoding", Config{RxEncoding: "latin1"}, "x", "rxEncoding"},
+		{"bad rule", Config{ClassifierRules: []classify.Rule{{Pattern: "(", Regex: true, Class: "e"}}}, "x", "classifierRules"},
+		{"bad frame", Config{Frame: &frame.Config{LenBytes: 3}}, "x", "frame"},
+		{"bad plot", Config{PlotParser: "csv"}, "x", "plotParser"},
+		{"bad hex match", Config{Match: []string{"zz"}, HexMatch: true}, "x", "match 0"},
+		{"bad hex input", Config{HexInput: true}, "0x01 02", ""},
+		{"negative chunk", Config{ChunkSize: -1}, "x", "chunkSize"},
+		{"too large", Config{}, strings.Repeat("x", MaxInput+1), "limit"},
+	}
+	for _, tt := range tests {
+		_, err := Run(tt.cfg, tt.in)
+		if err == nil || !strings.Contains(err.Error(), tt.msg) {
+			t.Errorf("%s: err = %v, want mention of %q", tt.name, err, tt.msg)
+		}
+	}
+}
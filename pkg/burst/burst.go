@@ -0,0 +1,236 @@
+// Package burst 实现示波器式的触发捕获：接收数据中出现触发模式时，把触发前 Pre 内的数据
+// (取自历史缓冲区) 与触发后 Post 内收到的数据写入一个文件，之后重新等待触发，直到捕获数达到上限
+//
+// 捕获进行中再次出现触发时，结束时间延长为该次触发后 Post，而不是截断或开始新的文件。
+// 所有时间都是调用方传入的接收时间，不读取系统时钟，便于用脚本化的数据流测试。
+package burst
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"time"
+)
+
+const (
+	// DefaultMaxCaptures MaxCaptures 为 0 时的捕获数上限
+	DefaultMaxCaptures = 10
+	// MaxPre/MaxPost 触发前后窗口的上限
+	MaxPre  = time.Minute
+	MaxPost = 10 * time.Minute
+	// maxSpan 跨数据块匹配触发模式时保留的上一块末尾字节数
+	maxSpan = 1024
+)
+
+// Config 触发捕获设置
+type Config struct {
+	Pattern     string        // 触发模式 (正则表达式)
+	Pre         time.Duration // 触发前保留的时间
+	Post        time.Duration // 最后一次触发后继续捕获的时间
+	MaxCaptures int           // 捕获文件数上限，0 表示 DefaultMaxCaptures
+}
+
+// Validate 检查设置
+func (c Config) Validate() error {
+	if c.Pattern == "" {
+		return errors.New("trigger pattern is empty")
+	}
+	if _, err := regexp.Compile(c.Pattern); err != nil {
+		return fmt.Errorf("invalid trigger pattern: %w", err)
+	}
+	if c.Pre < 0 || c.Pre > MaxPre {
+		return fmt.Errorf("pre-trigger window must be between 0 and %d ms", MaxPre.Milliseconds())
+	}
+	if c.Post <= 0 || c.Post > MaxPost {
+		return fmt.Errorf("post-trigger window must be between 1 and %d ms", MaxPost.Milliseconds())
+	}
+	if c.MaxCaptures < 0 {
+		return errors.New("max captures must not be negative")
+	}
+	return nil
+}
+
+// Status 捕获的状态
+type Status string
+
+const (
+	StatusStarted Status = "started"
+	StatusDone    Status = "done"
+	StatusFailed  Status = "failed"
+)
+
+// Report 一次捕获的状态变化
+type Report struct {
+	Index     int       `json:"index"` // 从 1 开始
+	Status    Status    `json:"status"`
+	Path      string    `json:"path,omitempty"`
+	Trigger   time.Time `json:"trigger"`   // 第一次触发的接收时间
+	Until     time.Time `json:"until"`     // 捕获的结束时间 (最后一次触发后 Post)
+	Triggers  int       `json:"triggers"`  // 包括延长捕获的触发次数
+	PreBytes  int64     `json:"preBytes"`  // 触发所在数据块之前的字节数
+	PostBytes int64     `json:"postBytes"` // 触发所在数据块及之后的字节数
+	Remaining int       `json:"remaining"` // 之后还能开始的捕获数
+	Error     string    `json:"error,omitempty"`
+}
+
+// CreateFunc 为第 index 次捕获创建文件，t 为触发时间，返回写入器与实际路径
+type CreateFunc func(index int, t time.Time) (io.WriteCloser, string, error)
+
+// PreFunc 返回 from 之后、当前数据块之前接收的数据块，按接收顺序
+type PreFunc func(from time.Time) [][]byte
+
+// Recorder 触发捕获的状态，不是线程安全的
+type Recorder struct {
+	cfg    Config
+	re     *regexp.Regexp
+	create CreateFunc
+
+	tail    []byte
+	started int
+	cur     *Report
+	w       io.WriteCloser
+}
+
+// New 创建触发捕获，cfg 应已通过 Validate
+func New(cfg Config, create CreateFunc) (*Recorder, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	if cfg.MaxCaptures == 0 {
+		cfg.MaxCaptures = DefaultMaxCaptures
+	}
+	return &Recorder{cfg: cfg, re: regexp.MustCompile(cfg.Pattern), create: create}, nil
+}
+
+// Config 返回设置 (MaxCaptures 已填入默认值)
+func (r *Recorder) Config() Config {
+	return r.cfg
+}
+
+// Feed 处理接收时间为 t 的数据块，返回本次开始或结束的捕获
+func (r *Recorder) Feed(t time.Time, chunk []byte, pre PreFunc) []Report {
+	var out []Report
+	if r.cur != nil && t.After(r.cur.Until) {
+		out = append(out, r.finish(nil))
+	}
+
+	triggered := r.match(chunk)
+	if r.cur == nil {
+		if !triggered || r.started >= r.cfg.MaxCaptures {
+			return out
+		}
+		rep, ok := r.start(t, pre)
+		out = append(out, rep)
+		if !ok {
+			return out
+		}
+	} else if triggered {
+		r.cur.Triggers++
+		r.cur.Until = t.Add(r.cfg.Post)
+	}
+
+	if err := r.write(chunk, &r.cur.PostBytes); err != nil {
+		out = append(out, r.finish(err))
+	}
+	return out
+}
+
+// Tick 在没有新数据时结束已到时间的捕获，返回结束的捕获
+func (r *Recorder) Tick(now time.Time) (Report, bool) {
+	if r.cur == nil || !now.After(r.cur.Until) {
+		return Report{}, false
+	}
+	return r.finish(nil), true
+}
+
+// Deadline 返回进行中捕获的结束时间
+func (r *Recorder) Deadline() (time.Time, bool) {
+	if r.cur == nil {
+		return time.Time{}, false
+	}
+	return r.cur.Until, true
+}
+
+// Exhausted 已达到捕获数上限且没有进行中的捕获
+func (r *Recorder) Exhausted() bool {
+	return r.cur == nil && r.started >= r.cfg.MaxCaptures
+}
+
+// Remaining 返回还能开始的捕获数
+func (r *Recorder) Remaining() int {
+	return r.cfg.MaxCaptures - r.started
+}
+
+// Close 结束进行中的捕获 (撤销触发时)
+func (r *Recorder) Close() (Report, bool) {
+	if r.cur == nil {
+		return Report{}, false
+	}
+	return r.finish(nil), true
+}
+
+// match 在上一块末尾与本块中查找触发模式，只计入结束于本块的匹配
+func (r *Recorder) match(chunk []byte) bool {
+	window := append(r.tail, chunk...)
+	found := false
+	for _, loc := range r.re.FindAllIndex(window, -1) {
+		if loc[1] > len(r.tail) {
+			found = true
+			break
+		}
+	}
+	if len(window) > maxSpan {
+		window = window[len(window)-maxSpan:]
+	}
+	r.tail = append(r.tail[:0], window...)
+	return found
+}
+
+func (r *Recorder) start(t time.Time, pre PreFunc) (Report, bool) {
+	r.started++
+	rep := Report{Index: r.started, Trigger: t, Until: t.Add(r.cfg.Post), Triggers: 1, Remaining: r.Remaining()}
+	w, path, err := r.create(rep.Index, t)
+	if err != nil {
+		rep.Status, rep.Error = StatusFailed, err.Error()
+		return rep, false
+	}
+	rep.Path = path
+	r.cur, r.w = &rep, w
+
+	var preErr error
+	if pre != nil && r.cfg.Pre > 0 {
+		for _, data := range pre(t.Add(-r.cfg.Pre)) {
+			if preErr = r.write(data, &r.cur.PreBytes); preErr != nil {
+				break
+			}
+		}
+	}
+	if preErr != nil {
+		return r.finish(preErr), false
+	}
+	started := *r.cur
+	started.Status = StatusStarted
+	return started, true
+}
+
+func (r *Recorder) write(data []byte, n *int64) error {
+	written, err := r.w.Write(data)
+	*n += int64(written)
+	return err
+}
+
+// finish 关闭文件并结束进行中的捕获，err 非 nil 时为失败
+func (r *Recorder) finish(err error) Report {
+	rep := *r.cur
+	if cerr := r.w.Close(); err == nil {
+		err = cerr
+	}
+	rep.Status = StatusDone
+	if err != nil {
+		rep.Status, rep.Error = StatusFailed, err.Error()
+	}
+	rep.Remaining = r.Remaining()
+	r.cur, r.w = nil, nil
+	return rep
+}
@@ -0,0 +1,184 @@
+package burst
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+)
+
+// file 内存中的捕获文件
+type file struct {
+	bytes.Buffer
+	closed bool
+}
+
+func (f *file) Close() error {
+	f.closed = true
+	return nil
+}
+
+// fakeStream 脚本化的数据流：按时间逐块送入 Recorder，并像历史缓冲区一样保留已收到的数据块
+type fakeStream struct {
+	t0      time.Time
+	history []chunk
+	files   []*file
+	reports []Report
+	rec     *Recorder
+}
+
+type chunk struct {
+	t    time.Time
+	data []byte
+}
+
+func newFakeStream(t *testing.T, cfg Config) *fakeStream {
+	s := &fakeStream{t0: time.Date(2026, 10, 15, 9, 0, 0, 0, time.UTC)}
+	rec, err := New(cfg, func(index int, at time.Time) (io.WriteCloser, string, error) {
+		f := &file{}
+		s.files = append(s.files, f)
+		return f, fmt.Sprintf("burst-%d-%s.bin", index, at.Format("150405.000")), nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.rec = rec
+	return s
+}
+
+// at 在 t0 之后 ms 毫秒收到 data
+func (s *fakeStream) at(ms int, data string) {
+	t := s.t0.Add(time.Duration(ms) * time.Millisecond)
+	s.reports = append(s.reports, s.rec.Feed(t, []byte(data), func(from time.Time) [][]byte {
+		var out [][]byte
+		for _, c := range s.history {
+			if !c.t.Before(from) {
+				out = append(out, c.data)
+			}
+		}
+		return out
+	})...)
+	s.history = append(s.history, chunk{t, []byte(data)})
+}
+
+func (s *fakeStream) tick(ms int) {
+	if rep, ok := s.rec.Tick(s.t0.Add(time.Duration(ms) * time.Millisecond)); ok {
+		s.reports = append(s.reports, rep)
+	}
+}
+
+func TestPrePostWindow(t *testing.T) {
+	s := newFakeStream(t, Config{Pattern: "ERROR", Pre: 2 * time.Second, Post: 5 * time.Second})
+	s.at(0, "[too old]")
+	s.at(2500, "[pre1]")
+	s.at(4000, "[pre2]")
+	s.at(4500, "[ERROR here]")
+	s.at(7000, "[post1]")
+	s.at(9500, "[post2]")  // 触发后正好 5 秒，仍在窗口内
+	s.at(9501, "[after]")  // 超出窗口，结束捕获
+	s.at(10000, "[quiet]") // 不会再写入
+
+	if len(s.files) != 1 {
+		t.Fatalf("Expected 1 capture file, got %d", len(s.files))
+	}
+	want := "[pre1][pre2][ERROR here][post1][post2]"
+	if got := s.files[0].String(); got != want || !s.files[0].closed {
+		t.Errorf("Capture = %q (closed %v), expected %q", got, s.files[0].closed, want)
+	}
+	if len(s.reports) != 2 || s.reports[0].Status != StatusStarted || s.reports[1].Status != StatusDone {
+		t.Fatalf("Reports = %+v", s.reports)
+	}
+	done := s.reports[1]
+	if done.PreBytes != 12 || done.PostBytes != int64(len("[ERROR here][post1][post2]")) {
+		t.Errorf("Byte counts pre %d post %d", done.PreBytes, done.PostBytes)
+	}
+	if done.Path != "burst-1-090004.500.bin" || done.Triggers != 1 || done.Remaining != DefaultMaxCaptures-1 {
+		t.Errorf("Done = %+v", done)
+	}
+}
+
+func TestOverlappingTriggerExtends(t *testing.T) {
+	s := newFakeStream(t, Config{Pattern: "ERROR", Pre: time.Second, Post: 5 * time.Second})
+	s.at(0, "ERROR 1|")
+	s.at(4000, "ERROR 2|") // 窗口内再次触发，延长到 9000
+	s.at(8000, "tail|")
+	s.at(9000, "end|")
+	s.tick(9001)
+	s.at(9500, "idle|")
+
+	if len(s.files) != 1 {
+		t.Fatalf("Overlapping trigger must not start a new file, got %d files", len(s.files))
+	}
+	if got := s.files[0].String(); got != "ERROR 1|ERROR 2|tail|end|" {
+		t.Errorf("Capture = %q", got)
+	}
+	last := s.reports[len(s.reports)-1]
+	if last.Status != StatusDone || last.Triggers != 2 || !last.Until.Equal(s.t0.Add(9*time.Second)) {
+		t.Errorf("Done = %+v", last)
+	}
+}
+
+func TestRearmAndLimit(t *testing.T) {
+	s := newFakeStream(t, Config{Pattern: `ERR(OR)?`, Post: 100 * time.Millisecond, MaxCaptures: 2})
+	s.at(0, "ERROR a")
+	s.at(1000, "ERROR b") // 上一次捕获已结束，重新触发
+	s.at(2000, "ERROR c") // 已达到上限
+	s.tick(3000)
+
+	if len(s.files) != 2 {
+		t.Fatalf("Expected 2 capture files, got %d", len(s.files))
+	}
+	if s.files[0].String() != "ERROR a" || s.files[1].String() != "ERROR b" {
+		t.Errorf("Captures = %q, %q", s.files[0].String(), s.files[1].String())
+	}
+	if !s.rec.Exhausted() || s.rec.Remaining() != 0 {
+		t.Error("Recorder should be exhausted")
+	}
+}
+
+func TestTriggerAcrossChunks(t *testing.T) {
+	s := newFakeStream(t, Config{Pattern: "ERROR", Pre: time.Second, Post: time.Second})
+	s.at(0, "boot ok ER")
+	if len(s.files) != 0 {
+		t.Fatal("Partial pattern must not trigger")
+	}
+	s.at(10, "ROR!")
+	if len(s.files) != 1 || s.files[0].String() != "boot ok ERROR!" {
+		t.Fatalf("Expected a capture starting with the pre window, got %d files", len(s.files))
+	}
+	// 已计入的匹配留在末尾，不会再次触发
+	s.at(2000, "x")
+	s.at(2010, "y")
+	if len(s.files) != 1 {
+		t.Errorf("Old match triggered again, %d files", len(s.files))
+	}
+}
+
+func TestCreateFailure(t *testing.T) {
+	rec, _ := New(Config{Pattern: "E", Post: time.Second, MaxCaptures: 1}, func(int, time.Time) (io.WriteCloser, string, error) {
+		return nil, "", errors.New("disk full")
+	})
+	reps := rec.Feed(time.Now(), []byte("E"), nil)
+	if len(reps) != 1 || reps[0].Status != StatusFailed || reps[0].Error != "disk full" || !rec.Exhausted() {
+		t.Errorf("Reports = %+v", reps)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	bad := []Config{
+		{Post: time.Second},
+		{Pattern: "(", Post: time.Second},
+		{Pattern: "x"},
+		{Pattern: "x", Post: MaxPost + 1},
+		{Pattern: "x", Pre: -1, Post: time.Second},
+		{Pattern: "x", Pre: MaxPre + 1, Post: time.Second},
+		{Pattern: "x", Post: time.Second, MaxCaptures: -1},
+	}
+	for i, c := range bad {
+		if err := c.Validate(); err == nil {
+			t.Errorf("case %d: expected an error", i)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+// Package checksum 实现帧校验常用的校验算法，用于发送时追加校验值与接收时校验
+//
+// 多字节校验值按各协议的惯例排列：CRC-16/MODBUS 为小端，其余为大端
+package checksum
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"strings"
+)
+
+// Algorithm 校验算法名称
+type Algorithm string
+
+const (
+	None        Algorithm = ""           // 不校验
+	Sum8        Algorithm = "sum8"       // 字节累加和取低 8 位
+	XOR8        Algorithm = "xor8"       // 字节异或
+	CRC8Maxim   Algorithm = "crc8-maxim" // CRC-8/MAXIM (Dallas 1-Wire)：多项式 0x31，输入输出反转
+	CRC8SMBus   Algorithm = "crc8-smbus" // CRC-8/SMBUS：多项式 0x07，初值 0
+	CRC8CCITT   Algorithm = "crc8-ccitt" // CRC-8/I-432-1 (ITU-T I.432.1，常称 CCITT)：多项式 0x07，结果异或 0x55
+	CRC16Modbus Algorithm = "crc16-modbus"
+	CRC32       Algorithm = "crc32" // IEEE 802.3
+	Fletcher16  Algorithm = "fletcher16"
+)
+
+// Algorithms 全部支持的算法，按 UI 中的显示顺序
+var Algorithms = []Algorithm{Sum8, XOR8, CRC8Maxim, CRC8SMBus, CRC8CCITT, CRC16Modbus, CRC32, Fletcher16}
+
+// Parse 解析算法名称 (不区分大小写)，空字符串或 "none" 表示不校验
+func Parse(name string) (Algorithm, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" || name == "none" {
+		return None, nil
+	}
+	for _, alg := range Algorithms {
+		if Algorithm(name) == alg {
+			return alg, nil
+		}
+	}
+	return None, fmt.Errorf("unknown checksum algorithm %q", name)
+}
+
+// Size 返回校验值的字节数，None 为 0
+func (alg Algorithm) Size() int {
+	switch alg {
+	case None:
+		return 0
+	case CRC16Modbus, Fletcher16:
+		return 2
+	case CRC32:
+		return 4
+	default:
+		return 1
+	}
+}
+
+// Sum 计算 data 的校验值，返回 Size() 字节
+func (alg Algorithm) Sum(data []byte) []byte {
+	switch alg {
+	case Sum8:
+		var s byte
+		for _, b := range data {
+			s += b
+		}
+		return []byte{s}
+	case XOR8:
+		var x byte
+		for _, b := range data {
+			x ^= b
+		}
+		return []byte{x}
+	case CRC8Maxim:
+		return []byte{crc8Maxim.sum(data)}
+	case CRC8SMBus:
+		return []byte{crc8SMBus.sum(data)}
+	case CRC8CCITT:
+		return []byte{crc8SMBus.sum(data) ^ 0x55}
+	case CRC16Modbus:
+		return binary.LittleEndian.AppendUint16(nil, crc16Modbus(data))
+	case CRC32:
+		return binary.BigEndian.AppendUint32(nil, crc32.ChecksumIEEE(data))
+	case Fletcher16:
+		return binary.BigEndian.AppendUint16(nil, fletcher16(data))
+	default:
+		return nil
+	}
+}
+
+// Append 返回在 data 之后追加校验值的新切片
+func (alg Algorithm) Append(data []byte) []byte {
+	out := make([]byte, 0, len(data)+alg.Size())
+	out = append(out, data...)
+	return append(out, alg.Sum(data)...)
+}
+
+// Verify 检查 sum 是否为 data 的校验值
+func (alg Algorithm) Verify(data, sum []byte) bool {
+	return string(alg.Sum(data)) == string(sum)
+}
+
+// crc8Table 按字节查表的 CRC-8，reflected 时使用反转后的多项式按 LSB 优先计算
+type crc8Table [256]byte
+
+func newCRC8Table(poly byte, reflected bool) *crc8Table {
+	var t crc8Table
+	for i := range t {
+		c := byte(i)
+		for bit := 0; bit < 8; bit++ {
+			switch {
+			case reflected && c&0x01 != 0:
+				c = c>>1 ^ poly
+			case reflected:
+				c >>= 1
+			case c&0x80 != 0:
+				c = c<<1 ^ poly
+			default:
+				c <<= 1
+			}
+		}
+		t[i] = c
+	}
+	return &t
+}
+
+func (t *crc8Table) sum(data []byte) byte {
+	var c byte
+	for _, b := range data {
+		c = t[c^b]
+	}
+	return c
+}
+
+var (
+	crc8Maxim = newCRC8Table(0x8C, true) // 0x31 反转
+	crc8SMBus = newCRC8Table(0x07, false)
+)
+
+// crc16Modbus CRC-16/MODBUS：多项式 0xA001 (0x8005 反转)，初值 0xFFFF
+func crc16Modbus(data []byte) uint16 {
+	crc := uint16(0xFFFF)
+	for _, b := range data {
+		crc ^= uint16(b)
+		for bit := 0; bit < 8; bit++ {
+			if crc&1 != 0 {
+				crc = crc>>1 ^ 0xA001
+			} else {
+				crc >>= 1
+			}
+		}
+	}
+	return crc
+}
+
+// fletcher16 两个模 255 的累加和，高字节为 sum2
+func fletcher16(data []byte) uint16 {
+	var sum1, sum2 uint16
+	for _, b := range data {
+		sum1 = (sum1 + uint16(b)) % 255
+		sum2 = (sum2 + sum1) % 255
+	}
+	return sum2<<8 | sum1
+}
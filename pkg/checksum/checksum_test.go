@@ -0,0 +1,77 @@
+package checksum
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+// 各算法对 "123456789" 的标准校验值 (CRC RevEng 目录中的 check 值)，
+// Fletcher-16 使用维基百科给出的示例
+func TestCheckValues(t *testing.T) {
+	check := []byte("123456789")
+	tests := []struct {
+		alg  Algorithm
+		data []byte
+		want string
+	}{
+		{Sum8, check, "dd"},
+		{XOR8, check, "31"},
+		{CRC8Maxim, check, "a1"},
+		{CRC8SMBus, check, "f4"},
+		{CRC8CCITT, check, "a1"},
+		{CRC16Modbus, check, "374b"}, // 0x4B37，小端
+		{CRC32, check, "cbf43926"},
+		{Fletcher16, []byte("abcde"), "c8f0"},
+		{Fletcher16, []byte("abcdef"), "2057"},
+		{Fletcher16, []byte("abcdefgh"), "0627"},
+	}
+	for _, tt := range tests {
+		got := hex.EncodeToString(tt.alg.Sum(tt.data))
+		if got != tt.want {
+			t.Errorf("%s(%q) = %s, want %s", tt.alg, tt.data, got, tt.want)
+		}
+		if len(tt.alg.Sum(tt.data)) != tt.alg.Size() {
+			t.Errorf("%s: Size %d does not match the sum length", tt.alg, tt.alg.Size())
+		}
+	}
+}
+
+// DS18B20 暂存器 (85°C 上电值) 的最后一个字节为前 8 字节的 CRC-8/MAXIM
+func TestCRC8MaximDS18B20(t *testing.T) {
+	scratchpad := []byte{0x50, 0x05, 0x4B, 0x46, 0x7F, 0xFF, 0x0C, 0x10, 0x1C}
+	if !CRC8Maxim.Verify(scratchpad[:8], scratchpad[8:]) {
+		t.Errorf("CRC8Maxim = %x, want 1c", CRC8Maxim.Sum(scratchpad[:8]))
+	}
+}
+
+func TestAppendAndVerify(t *testing.T) {
+	for _, alg := range Algorithms {
+		payload := []byte{0x01, 0x02, 0xFE}
+		framed := alg.Append(payload)
+		if !bytes.Equal(framed[:len(payload)], payload) || len(framed) != len(payload)+alg.Size() {
+			t.Errorf("%s: Append = %x", alg, framed)
+		}
+		if !alg.Verify(payload, framed[len(payload):]) {
+			t.Errorf("%s: Verify failed on its own sum", alg)
+		}
+		framed[0] ^= 0x10
+		if alg.Verify(framed[:len(payload)], framed[len(payload):]) {
+			t.Errorf("%s: Verify accepted a corrupted payload", alg)
+		}
+	}
+	if out := None.Append([]byte("x")); string(out) != "x" {
+		t.Errorf("None.Append = %q", out)
+	}
+}
+
+func TestParse(t *testing.T) {
+	for name, want := range map[string]Algorithm{"": None, "none": None, "CRC8-MAXIM": CRC8Maxim, " fletcher16 ": Fletcher16} {
+		if got, err := Parse(name); err != nil || got != want {
+			t.Errorf("Parse(%q) = %q, %v", name, got, err)
+		}
+	}
+	if _, err := Parse("md5"); err == nil {
+		t.Error("Parse(md5) should fail")
+	}
+}
@@ -0,0 +1,137 @@
+// Package classify 按规则对接收到的文本行分类 (例如 "error"、"warn")，
+// 前端只需根据类别设置样式，无需在 JS 中逐行执行正则匹配
+package classify
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"sort"
+)
+
+// MaxLineLen 跟踪未结束行时保留的最大字节数，超出部分不参与匹配
+const MaxLineLen = 4096
+
+// Rule 一条分类规则
+type Rule struct {
+	Pattern  string `json:"pattern"`
+	Regex    bool   `json:"regex,omitempty"` // 为 false 时 Pattern 是行首前缀
+	Class    string `json:"class"`           // 类别名称，例如 "error"、"warn"、"info" 或自定义名称
+	Priority int    `json:"priority,omitempty"`
+}
+
+// classNameRe 类别名称需要能直接用作 CSS 类名
+var classNameRe = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_-]{0,31}$`)
+
+type compiled struct {
+	Rule
+	re     *regexp.Regexp
+	prefix []byte
+}
+
+// Classifier 编译后的规则集，创建后只读，可被多个 goroutine 同时使用
+type Classifier struct {
+	rules []compiled
+}
+
+// Compile 校验并编译规则，按 Priority 从高到低匹配，优先级相同时按原顺序
+func Compile(rules []Rule) (*Classifier, error) {
+	c := &Classifier{rules: make([]compiled, 0, len(rules))}
+	for i, r := range rules {
+		if !classNameRe.MatchString(r.Class) {
+			return nil, fmt.Errorf("rule %d: invalid class name %q", i, r.Class)
+		}
+		if r.Pattern == "" {
+			return nil, fmt.Errorf("rule %d: pattern is empty", i)
+		}
+		cr := compiled{Rule: r}
+		if r.Regex {
+			re, err := regexp.Compile(r.Pattern)
+			if err != nil {
+				return nil, fmt.Errorf("rule %d: %v", i, err)
+			}
+			cr.re = re
+		} else {
+			cr.prefix = []byte(r.Pattern)
+		}
+		c.rules = append(c.rules, cr)
+	}
+	sort.SliceStable(c.rules, func(i, j int) bool { return c.rules[i].Priority > c.rules[j].Priority })
+	return c, nil
+}
+
+// Empty 规则集为空
+func (c *Classifier) Empty() bool {
+	return c == nil || len(c.rules) == 0
+}
+
+// Classify 返回第一条匹配 line (不含行尾) 的规则的类别，没有匹配时返回空字符串
+func (c *Classifier) Classify(line []byte) string {
+	if c == nil {
+		return ""
+	}
+	for _, r := range c.rules {
+		if r.re != nil {
+			if r.re.Match(line) {
+				return r.Class
+			}
+		} else if bytes.HasPrefix(line, r.prefix) {
+			return r.Class
+		}
+	}
+	return ""
+}
+
+// LineClass 数据块中一行的分类结果
+type LineClass struct {
+	End   int    `json:"end"` // 该行在当前数据块中的结束位置 (不含，包括 '\n')
+	Class string `json:"class"`
+}
+
+// Tracker 跟踪跨数据块的行，每条连接一个，不是线程安全的
+type Tracker struct {
+	partial []byte
+}
+
+// Feed 对 chunk 中结束的每一行分类，返回匹配到类别的行；
+// 跨块的行使用完整内容匹配，未结束的部分保留到下一次调用
+func (t *Tracker) Feed(c *Classifier, chunk []byte) []LineClass {
+	if c.Empty() {
+		t.partial = t.partial[:0]
+		return nil
+	}
+
+	var out []LineClass
+	start := 0
+	for {
+		i := bytes.IndexByte(chunk[start:], '\n')
+		if i < 0 {
+			break
+		}
+		end := start + i + 1
+		line := chunk[start : end-1]
+		if len(t.partial) > 0 {
+			line = append(t.partial, line...)
+			t.partial = line[:0]
+		}
+		if class := c.Classify(bytes.TrimSuffix(line, []byte{'\r'})); class != "" {
+			out = append(out, LineClass{End: end, Class: class})
+		}
+		start = end
+	}
+
+	if rest := chunk[start:]; len(rest) > 0 {
+		if room := MaxLineLen - len(t.partial); room > 0 {
+			if len(rest) > room {
+				rest = rest[:room]
+			}
+			t.partial = append(t.partial, rest...)
+		}
+	}
+	return out
+}
+
+// Reset 丢弃未结束的行
+func (t *Tracker) Reset() {
+	t.partial = t.partial[:0]
+}
@@ -0,0 +1,110 @@
+package classify
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+var testRules = []Rule{
+	{Pattern: "[I]", Class: "info"},
+	{Pattern: `(?i)\berror\b`, Regex: true, Class: "error", Priority: 10},
+	{Pattern: `warn`, Regex: true, Class: "warn", Priority: 5},
+	{Pattern: "[I] boot", Class: "boot", Priority: 1},
+}
+
+func TestClassify(t *testing.T) {
+	c, err := Compile(testRules)
+	if err != nil {
+		t.Fatalf("Compile failed: %v", err)
+	}
+	tests := map[string]string{
+		"[I] sensor ready":         "info",
+		"[I] boot complete":        "boot",
+		"[I] ERROR: flash timeout": "error",
+		"low battery warning":      "warn",
+		"warn and error":           "error",
+		"terrors":                  "",
+		"plain line":               "",
+	}
+	for line, expected := range tests {
+		if got := c.Classify([]byte(line)); got != expected {
+			t.Errorf("Classify(%q) = %q, expected %q", line, got, expected)
+		}
+	}
+}
+
+func TestCompileRejectsInvalidRules(t *testing.T) {
+	for _, rules := range [][]Rule{
+		{{Pattern: "(", Regex: true, Class: "error"}},
+		{{Pattern: "x", Class: "bad class"}},
+		{{Pattern: "x", Class: ""}},
+		{{Pattern: "", Class: "info"}},
+	} {
+		if _, err := Compile(rules); err == nil {
+			t.Errorf("Expected error for %+v", rules)
+		}
+	}
+	if c, err := Compile(nil); err != nil || !c.Empty() {
+		t.Errorf("Expected empty classifier, got %v", err)
+	}
+}
+
+func TestTrackerAcrossChunks(t *testing.T) {
+	c, _ := Compile(testRules)
+	var tr Tracker
+
+	got := tr.Feed(c, []byte("ok\r\n[I] ready\r\nfatal err"))
+	want := []LineClass{{End: 15, Class: "info"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("First chunk: got %+v, want %+v", got, want)
+	}
+
+	// "fatal error" 跨两个数据块，按完整行匹配
+	got = tr.Feed(c, []byte("or\nwarn\n"))
+	want = []LineClass{{End: 3, Class: "error"}, {End: 8, Class: "warn"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Second chunk: got %+v, want %+v", got, want)
+	}
+
+	tr.Feed(c, []byte("[I] partial"))
+	tr.Reset()
+	if got := tr.Feed(c, []byte(" line\n")); got != nil {
+		t.Errorf("Expected partial line to be dropped after Reset, got %+v", got)
+	}
+}
+
+func TestTrackerBoundsPartialLine(t *testing.T) {
+	c, _ := Compile([]Rule{{Pattern: "x", Class: "x"}})
+	var tr Tracker
+	for i := 0; i < 10; i++ {
+		tr.Feed(c, bytes.Repeat([]byte{'x'}, 1000))
+	}
+	if len(tr.partial) != MaxLineLen {
+		t.Errorf("Expected partial line capped at %d bytes, got %d", MaxLineLen, len(tr.partial))
+	}
+	if got := tr.Feed(c, []byte("\n")); len(got) != 1 {
+		t.Errorf("Expected long line to be classified, got %+v", got)
+	}
+}
+
+func BenchmarkTracker(b *testing.B) {
+	rules := append([]Rule(nil), testRules...)
+	for i := 0; i < 16; i++ {
+		rules = append(rules, Rule{Pattern: fmt.Sprintf(`^\[mod%d\].*(fail|timeout)`, i), Regex: true, Class: "custom"})
+	}
+	c, _ := Compile(rules)
+	var chunk []byte
+	for i := 0; i < 100; i++ {
+		chunk = append(chunk, fmt.Sprintf("[I] %08d temperature=23.%d humidity=41 status=ok\r\n", i, i%10)...)
+	}
+
+	var tr Tracker
+	b.SetBytes(int64(len(chunk)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		tr.Feed(c, chunk)
+	}
+	b.ReportMetric(float64(b.N*100)/b.Elapsed().Seconds(), "lines/s")
+}
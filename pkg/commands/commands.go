@@ -0,0 +1,202 @@
+// Package commands 定义快捷指令及其导入/导出文件格式
+//
+// 导出文件是 UTF-8 JSON：
+//
+//	{
+//	  "version": 1,
+//	  "commands": [
+//	    {
+//	      "name": "读取版本",          // 必填，唯一，最长 64 个字符
+//	      "payload": "AT+GMR",         // 发送内容；hex 为 true 时为十六进制字符串 (允许空格分隔)
+//	      "hex": false,
+//	      "lineEnding": "crlf",        // 可选: "" (不追加)、"cr"、"lf"、"crlf"
+//	      "description": "查询固件版本", // 可选
+//	      "category": "AT",            // 可选，用于分组显示
+//	      "updated": "2024-05-01T08:00:00Z" // 可选，合并时用于比较新旧
+//	    }
+//	  ]
+//	}
+package commands
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"serial-assistant/pkg/input"
+)
+
+// SchemaVersion 当前导出文件的格式版本
+const SchemaVersion = 1
+
+// MaxNameLen 指令名称的最大字符数
+const MaxNameLen = 64
+
+// Command 一条快捷指令
+type Command struct {
+	Name        string     `json:"name"`
+	Payload     string     `json:"payload"`
+	Hex         bool       `json:"hex,omitempty"`
+	LineEnding  string     `json:"lineEnding,omitempty"`
+	Description string     `json:"description,omitempty"`
+	Category    string     `json:"category,omitempty"`
+	Updated     *time.Time `json:"updated,omitempty"`
+}
+
+// lineEndings 支持的行尾
+var lineEndings = map[string]string{"": "", "cr": "\r", "lf": "\n", "crlf": "\r\n"}
+
+// Validate 检查指令是否合法
+func (c Command) Validate() error {
+	name := strings.TrimSpace(c.Name)
+	switch {
+	case name == "":
+		return errors.New("name is required")
+	case utf8.RuneCountInString(name) > MaxNameLen:
+		return fmt.Errorf("name is longer than %d characters", MaxNameLen)
+	}
+	if _, ok := lineEndings[c.LineEnding]; !ok {
+		return fmt.Errorf("unknown line ending %q (expected cr, lf or crlf)", c.LineEnding)
+	}
+	if c.Hex {
+		if _, err := input.ParseHex(c.Payload); err != nil {
+			return fmt.Errorf("invalid hex payload: %v", err)
+		}
+	}
+	return nil
+}
+
+// Bytes 返回实际发送的字节 (已追加行尾)
+func (c Command) Bytes() ([]byte, error) {
+	var data []byte
+	if c.Hex {
+		var err error
+		if data, err = input.ParseHex(c.Payload); err != nil {
+			return nil, err
+		}
+	} else {
+		data = []byte(c.Payload)
+	}
+	return append(data, lineEndings[c.LineEnding]...), nil
+}
+
+// file 导出文件的顶层结构
+type file struct {
+	Version  int               `json:"version"`
+	Commands []json.RawMessage `json:"commands"`
+}
+
+// Encode 以导出格式写出指令
+func Encode(w io.Writer, cmds []Command) error {
+	out := struct {
+		Version  int       `json:"version"`
+		Commands []Command `json:"commands"`
+	}{SchemaVersion, cmds}
+	if out.Commands == nil {
+		out.Commands = []Command{}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
+
+// EntryError 导入时单条指令的错误
+type EntryError struct {
+	Index int    `json:"index"` // 在文件 commands 数组中的下标，从 0 开始
+	Name  string `json:"name,omitempty"`
+	Error string `json:"error"`
+}
+
+// Decode 读取导出文件，逐条校验；不合法的指令记录在 errs 中并跳过，不影响其他指令
+// 只有文件本身无法解析或版本不受支持时才返回 err
+func Decode(r io.Reader) (cmds []Command, errs []EntryError, err error) {
+	var f file
+	if err := json.NewDecoder(r).Decode(&f); err != nil {
+		return nil, nil, fmt.Errorf("invalid command file: %w", err)
+	}
+	if f.Version > SchemaVersion {
+		return nil, nil, fmt.Errorf("unsupported command file version %d (max %d)", f.Version, SchemaVersion)
+	}
+
+	seen := make(map[string]bool)
+	for i, raw := range f.Commands {
+		var c Command
+		if err := json.Unmarshal(raw, &c); err != nil {
+			errs = append(errs, EntryError{Index: i, Error: err.Error()})
+			continue
+		}
+		c.Name = strings.TrimSpace(c.Name)
+		if err := c.Validate(); err != nil {
+			errs = append(errs, EntryError{Index: i, Name: c.Name, Error: err.Error()})
+			continue
+		}
+		if seen[c.Name] {
+			errs = append(errs, EntryError{Index: i, Name: c.Name, Error: "duplicate name in file"})
+			continue
+		}
+		seen[c.Name] = true
+		cmds = append(cmds, c)
+	}
+	return cmds, errs, nil
+}
+
+// Policy 合并时同名指令的处理方式
+type Policy string
+
+const (
+	// PolicyNewest 保留 Updated 较新的一条，时间相同或缺失时使用导入的指令
+	PolicyNewest Policy = "newest"
+	// PolicySkip 保留已有的指令，跳过导入的同名指令
+	PolicySkip Policy = "skip"
+)
+
+// ParsePolicy 解析合并策略，空字符串视为 PolicyNewest
+func ParsePolicy(name string) (Policy, error) {
+	switch Policy(name) {
+	case "", PolicyNewest:
+		return PolicyNewest, nil
+	case PolicySkip:
+		return PolicySkip, nil
+	default:
+		return "", fmt.Errorf("unknown merge policy %q (expected newest or skip)", name)
+	}
+}
+
+// MergeStats 合并结果统计
+type MergeStats struct {
+	Added    int `json:"added"`
+	Replaced int `json:"replaced"`
+	Skipped  int `json:"skipped"`
+}
+
+// Merge 按名称将 imported 合并到 existing，返回新的列表；已有指令保持原顺序，新增的追加在末尾
+func Merge(existing, imported []Command, policy Policy) ([]Command, MergeStats) {
+	var stats MergeStats
+	out := append([]Command(nil), existing...)
+	index := make(map[string]int, len(out))
+	for i, c := range out {
+		index[c.Name] = i
+	}
+
+	for _, c := range imported {
+		i, ok := index[c.Name]
+		switch {
+		case !ok:
+			index[c.Name] = len(out)
+			out = append(out, c)
+			stats.Added++
+		case policy == PolicySkip:
+			stats.Skipped++
+		case out[i].Updated != nil && c.Updated != nil && out[i].Updated.After(*c.Updated):
+			stats.Skipped++
+		default:
+			out[i] = c
+			stats.Replaced++
+		}
+	}
+	return out, stats
+}
@@ -0,0 +1,143 @@
+package commands
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRoundTrip(t *testing.T) {
+	updated := time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC)
+	cmds := []Command{
+		{Name: "读取版本", Payload: "AT+GMR", LineEnding: "crlf", Description: "查询固件版本", Category: "AT", Updated: &updated},
+		{Name: "reset", Payload: "AA 55 01", Hex: true},
+	}
+
+	var buf bytes.Buffer
+	if err := Encode(&buf, cmds); err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	if !strings.Contains(buf.String(), `"version": 1`) || strings.Count(buf.String(), `"updated"`) != 1 {
+		t.Errorf("Expected schema version in output: %s", buf.String())
+	}
+
+	got, errs, err := Decode(&buf)
+	if err != nil || len(errs) != 0 {
+		t.Fatalf("Decode failed: %v %+v", err, errs)
+	}
+	if !reflect.DeepEqual(got, cmds) {
+		t.Errorf("Round trip mismatch:\n got %+v\nwant %+v", got, cmds)
+	}
+}
+
+func TestEncodeEmpty(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Encode(&buf, nil); err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	if !strings.Contains(buf.String(), `"commands": []`) {
+		t.Errorf("Expected empty commands array, got %s", buf.String())
+	}
+}
+
+func TestDecodeReportsEntryErrors(t *testing.T) {
+	input := `{
+  "version": 1,
+  "commands": [
+    {"name": "ok", "payload": "PING", "lineEnding": "lf"},
+    {"name": "", "payload": "x"},
+    {"name": "bad hex", "payload": "ZZ", "hex": true},
+    {"name": "bad ending", "payload": "x", "lineEnding": "\r\n"},
+    {"name": 42},
+    {"name": "ok", "payload": "again"},
+    {"name": "  spaced  ", "payload": "01 02", "hex": true}
+  ]
+}`
+	cmds, errs, err := Decode(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	if len(cmds) != 2 || cmds[0].Name != "ok" || cmds[1].Name != "spaced" {
+		t.Errorf("Unexpected valid commands: %+v", cmds)
+	}
+
+	wantIdx := []int{1, 2, 3, 4, 5}
+	if len(errs) != len(wantIdx) {
+		t.Fatalf("Expected %d entry errors, got %+v", len(wantIdx), errs)
+	}
+	for i, e := range errs {
+		if e.Index != wantIdx[i] || e.Error == "" {
+			t.Errorf("Unexpected entry error %+v", e)
+		}
+	}
+	if errs[1].Name != "bad hex" || !strings.Contains(errs[4].Error, "duplicate") {
+		t.Errorf("Unexpected error details: %+v", errs)
+	}
+}
+
+func TestDecodeMalformedFile(t *testing.T) {
+	for _, input := range []string{
+		`{"version": 1, "commands": [`,
+		`not json`,
+		`{"version": 1, "commands": {"name": "x"}}`,
+		`{"version": 99, "commands": []}`,
+	} {
+		if _, _, err := Decode(strings.NewReader(input)); err == nil {
+			t.Errorf("Expected error for %q", input)
+		}
+	}
+}
+
+func TestBytes(t *testing.T) {
+	data, err := Command{Name: "a", Payload: "AT", LineEnding: "crlf"}.Bytes()
+	if err != nil || string(data) != "AT\r\n" {
+		t.Errorf("Unexpected text payload %q, %v", data, err)
+	}
+	data, err = Command{Name: "b", Payload: "aa 55", Hex: true, LineEnding: "lf"}.Bytes()
+	if err != nil || !bytes.Equal(data, []byte{0xAA, 0x55, '\n'}) {
+		t.Errorf("Unexpected hex payload % X, %v", data, err)
+	}
+}
+
+func TestMerge(t *testing.T) {
+	old := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	newer := old.Add(time.Hour)
+	existing := []Command{
+		{Name: "a", Payload: "1", Updated: &newer},
+		{Name: "b", Payload: "1", Updated: &old},
+		{Name: "c", Payload: "1"},
+	}
+	imported := []Command{
+		{Name: "a", Payload: "2", Updated: &old},
+		{Name: "b", Payload: "2", Updated: &newer},
+		{Name: "c", Payload: "2"},
+		{Name: "d", Payload: "2"},
+	}
+
+	out, stats := Merge(existing, imported, PolicyNewest)
+	payloads := ""
+	for _, c := range out {
+		payloads += c.Name + c.Payload
+	}
+	if payloads != "a1b2c2d2" || stats != (MergeStats{Added: 1, Replaced: 2, Skipped: 1}) {
+		t.Errorf("Newest policy: got %s %+v", payloads, stats)
+	}
+
+	out, stats = Merge(existing, imported, PolicySkip)
+	payloads = ""
+	for _, c := range out {
+		payloads += c.Name + c.Payload
+	}
+	if payloads != "a1b1c1d2" || stats != (MergeStats{Added: 1, Skipped: 3}) {
+		t.Errorf("Skip policy: got %s %+v", payloads, stats)
+	}
+	if existing[1].Payload != "1" {
+		t.Error("Merge must not modify the existing slice")
+	}
+
+	if _, err := ParsePolicy("overwrite"); err == nil {
+		t.Error("Expected error for unknown policy")
+	}
+}
@@ -0,0 +1,284 @@
+// Package compare 对齐两个连接的按行输出并逐行比较，用于对比两个固件版本等场景
+//
+// lockstep 模式按行号配对 (A 的第 N 行对 B 的第 N 行)；time 模式在时间窗口内按最接近的时间戳配对，
+// 窗口内找不到对应行的行以单侧行输出。Comparer 非线程安全，由调用方加锁。
+package compare
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Mode 配对方式
+type Mode string
+
+const (
+	// Lockstep 按行号配对
+	Lockstep Mode = "lockstep"
+	// Time 按时间戳配对，两行的时间差不超过 Window
+	Time Mode = "time"
+)
+
+// ParseMode 解析配对方式，空字符串视为 Lockstep
+func ParseMode(name string) (Mode, error) {
+	switch Mode(name) {
+	case "", Lockstep:
+		return Lockstep, nil
+	case Time:
+		return Time, nil
+	default:
+		return "", fmt.Errorf("unknown compare mode %q (expected lockstep or time)", name)
+	}
+}
+
+const (
+	// DefaultWindow Window 为 0 时 time 模式的配对窗口
+	DefaultWindow = 500 * time.Millisecond
+	// DefaultMaxPending MaxPending 为 0 时每侧最多等待配对的行数
+	DefaultMaxPending = 1000
+	// MaxLineLen 未遇到换行符时强制断行的字节数
+	MaxLineLen = 4096
+)
+
+// Config 比较设置
+type Config struct {
+	Mode       Mode
+	Window     time.Duration // time 模式的配对窗口，0 表示 DefaultWindow
+	MaxPending int           // 每侧等待配对的行数上限，超出时最早的行以单侧行输出；0 表示 DefaultMaxPending
+}
+
+// Side 行所属的连接
+type Side int
+
+const (
+	A Side = iota
+	B
+)
+
+// Line 一行输出
+type Line struct {
+	Num  int    `json:"num"`  // 该侧的行号，从 1 开始
+	Text string `json:"text"` // 不含行尾的 \r\n
+	Time int64  `json:"time"` // 接收时间 (Unix 毫秒)
+
+	at time.Time
+}
+
+// Row 比较结果的一行，单侧行的另一侧为 nil
+type Row struct {
+	Index int     `json:"index"` // 从 1 开始
+	A     *Line   `json:"a"`
+	B     *Line   `json:"b"`
+	Match bool    `json:"match"` // 两侧都有且文本相同
+	Stats Summary `json:"stats"` // 包括本行在内的累计结果
+}
+
+// Summary 累计比较结果
+type Summary struct {
+	Rows       int     `json:"rows"`
+	Matched    int     `json:"matched"`
+	Mismatched int     `json:"mismatched"` // 两侧都有但文本不同
+	OnlyA      int     `json:"onlyA"`
+	OnlyB      int     `json:"onlyB"`
+	Similarity float64 `json:"similarity"` // Matched / Rows，没有行时为 0
+}
+
+// Comparer 两侧的行缓冲与配对状态
+type Comparer struct {
+	cfg     Config
+	partial [2][]byte
+	lines   [2]int
+	pending [2][]*Line
+	stats   Summary
+}
+
+// New 创建比较器
+func New(cfg Config) *Comparer {
+	if cfg.Mode == "" {
+		cfg.Mode = Lockstep
+	}
+	if cfg.Window <= 0 {
+		cfg.Window = DefaultWindow
+	}
+	if cfg.MaxPending <= 0 {
+		cfg.MaxPending = DefaultMaxPending
+	}
+	return &Comparer{cfg: cfg}
+}
+
+// Config 返回生效的设置 (已填入默认值)
+func (c *Comparer) Config() Config {
+	return c.cfg
+}
+
+// Feed 送入 side 在 now 收到的数据，返回因此产生的比较行；不完整的行留待之后的数据
+func (c *Comparer) Feed(side Side, now time.Time, data []byte) []Row {
+	var rows []Row
+	buf := append(c.partial[side], data...)
+	for {
+		i := bytes.IndexByte(buf, '\n')
+		if i < 0 {
+			if len(buf) < MaxLineLen {
+				break
+			}
+			i = MaxLineLen
+		}
+		text := buf[:i]
+		if i < len(buf) && buf[i] == '\n' {
+			i++
+		}
+		rows = append(rows, c.addLine(side, now, text)...)
+		buf = buf[i:]
+	}
+	c.partial[side] = append(c.partial[side][:0], buf...)
+	return rows
+}
+
+// Expire 在 time 模式下输出窗口已过、不可能再配对的行 (按时间顺序)，由调用方定期调用
+func (c *Comparer) Expire(now time.Time) []Row {
+	if c.cfg.Mode != Time {
+		return nil
+	}
+	var rows []Row
+	deadline := now.Add(-c.cfg.Window)
+	for {
+		side, ok := c.oldestPending()
+		if !ok || !c.pending[side][0].at.Before(deadline) {
+			return rows
+		}
+		rows = append(rows, c.popOneSided(side))
+	}
+}
+
+// Finish 输出未结束的行与全部等待中的行，返回最终结果
+func (c *Comparer) Finish(now time.Time) ([]Row, Summary) {
+	var rows []Row
+	for _, side := range []Side{A, B} {
+		if len(c.partial[side]) > 0 {
+			rows = append(rows, c.addLine(side, now, c.partial[side])...)
+			c.partial[side] = nil
+		}
+	}
+	if c.cfg.Mode == Lockstep {
+		rows = append(rows, c.pairLockstep()...)
+	}
+	for {
+		side, ok := c.oldestPending()
+		if !ok {
+			break
+		}
+		rows = append(rows, c.popOneSided(side))
+	}
+	return rows, c.stats
+}
+
+// Summary 返回累计结果
+func (c *Comparer) Summary() Summary {
+	return c.stats
+}
+
+func (c *Comparer) addLine(side Side, now time.Time, text []byte) []Row {
+	c.lines[side]++
+	s := strings.ToValidUTF8(string(bytes.TrimSuffix(text, []byte{'\r'})), "\uFFFD")
+	line := &Line{Num: c.lines[side], Text: s, Time: now.UnixMilli(), at: now}
+
+	if c.cfg.Mode == Lockstep {
+		c.pending[side] = append(c.pending[side], line)
+		rows := c.pairLockstep()
+		if len(c.pending[side]) > c.cfg.MaxPending {
+			rows = append(rows, c.popOneSided(side))
+		}
+		return rows
+	}
+
+	// time 模式：先输出已过窗口的行，再与另一侧时间最接近的等待行配对；
+	// 另一侧更早的等待行已被跳过，以单侧行输出
+	rows := c.Expire(now)
+	other := 1 - side
+	best := -1
+	for i, p := range c.pending[other] {
+		if d := absDuration(now.Sub(p.at)); d <= c.cfg.Window && (best < 0 || d <= absDuration(now.Sub(c.pending[other][best].at))) {
+			best = i
+		}
+	}
+	if best < 0 {
+		c.pending[side] = append(c.pending[side], line)
+		if len(c.pending[side]) > c.cfg.MaxPending {
+			rows = append(rows, c.popOneSided(side))
+		}
+		return rows
+	}
+	for i := 0; i < best; i++ {
+		rows = append(rows, c.popOneSided(other))
+	}
+	match := c.pending[other][0]
+	c.pending[other] = c.pending[other][1:]
+	if side == A {
+		return append(rows, c.row(line, match))
+	}
+	return append(rows, c.row(match, line))
+}
+
+// pairLockstep 两侧都有等待行时按顺序配对
+func (c *Comparer) pairLockstep() []Row {
+	var rows []Row
+	for len(c.pending[A]) > 0 && len(c.pending[B]) > 0 {
+		a, b := c.pending[A][0], c.pending[B][0]
+		c.pending[A], c.pending[B] = c.pending[A][1:], c.pending[B][1:]
+		rows = append(rows, c.row(a, b))
+	}
+	return rows
+}
+
+// oldestPending 返回最早的等待行所在的一侧
+func (c *Comparer) oldestPending() (Side, bool) {
+	switch {
+	case len(c.pending[A]) == 0 && len(c.pending[B]) == 0:
+		return A, false
+	case len(c.pending[B]) == 0:
+		return A, true
+	case len(c.pending[A]) == 0:
+		return B, true
+	case c.pending[B][0].at.Before(c.pending[A][0].at):
+		return B, true
+	default:
+		return A, true
+	}
+}
+
+func (c *Comparer) popOneSided(side Side) Row {
+	line := c.pending[side][0]
+	c.pending[side] = c.pending[side][1:]
+	if side == A {
+		return c.row(line, nil)
+	}
+	return c.row(nil, line)
+}
+
+func (c *Comparer) row(a, b *Line) Row {
+	c.stats.Rows++
+	r := Row{Index: c.stats.Rows, A: a, B: b}
+	switch {
+	case a == nil:
+		c.stats.OnlyB++
+	case b == nil:
+		c.stats.OnlyA++
+	case a.Text == b.Text:
+		r.Match = true
+		c.stats.Matched++
+	default:
+		c.stats.Mismatched++
+	}
+	c.stats.Similarity = float64(c.stats.Matched) / float64(c.stats.Rows)
+	r.Stats = c.stats
+	return r
+}
+
+func absDuration(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
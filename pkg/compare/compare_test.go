@@ -0,0 +1,107 @@
+package compare
+
+import (
+	"testing"
+	"time"
+)
+
+// describe 将比较行写成 "A|B|match" 形式，单侧为空
+func describe(rows []Row) []string {
+	var out []string
+	for _, r := range rows {
+		s := ""
+		if r.A != nil {
+			s += r.A.Text
+		}
+		s += "|"
+		if r.B != nil {
+			s += r.B.Text
+		}
+		if r.Match {
+			s += "|="
+		}
+		out = append(out, s)
+	}
+	return out
+}
+
+func equal(t *testing.T, got []Row, want ...string) {
+	t.Helper()
+	d := describe(got)
+	if len(d) != len(want) {
+		t.Fatalf("rows %q, want %q", d, want)
+	}
+	for i := range d {
+		if d[i] != want[i] {
+			t.Fatalf("rows %q, want %q", d, want)
+		}
+	}
+}
+
+func TestLockstep(t *testing.T) {
+	c := New(Config{})
+	now := time.Unix(100, 0)
+
+	// 行可以跨多次送入，\r\n 与 \n 等价
+	equal(t, c.Feed(A, now, []byte("boot v1\r\nre")))
+	equal(t, c.Feed(B, now, []byte("boot v2\nready\nextra\n")), "boot v1|boot v2")
+	equal(t, c.Feed(A, now, []byte("ady\n")), "ready|ready|=")
+
+	rows, sum := c.Finish(now)
+	equal(t, rows, "|extra")
+	if sum.Rows != 3 || sum.Matched != 1 || sum.Mismatched != 1 || sum.OnlyB != 1 || sum.OnlyA != 0 {
+		t.Errorf("summary %+v", sum)
+	}
+	if rows[0].B.Num != 3 || rows[0].Index != 3 || rows[0].Stats != sum {
+		t.Errorf("last row %+v", rows[0])
+	}
+}
+
+func TestLockstepMaxPending(t *testing.T) {
+	c := New(Config{MaxPending: 2})
+	now := time.Unix(100, 0)
+	equal(t, c.Feed(A, now, []byte("1\n2\n3\n")), "1|")
+	equal(t, c.Feed(B, now, []byte("2\n")), "2|2|=")
+}
+
+func TestTimeMode(t *testing.T) {
+	c := New(Config{Mode: Time, Window: 100 * time.Millisecond})
+	t0 := time.Unix(100, 0)
+	at := func(ms int) time.Time { return t0.Add(time.Duration(ms) * time.Millisecond) }
+
+	equal(t, c.Feed(A, at(0), []byte("temp=20\n")))
+	equal(t, c.Feed(B, at(30), []byte("temp=21\n")), "temp=20|temp=21")
+
+	// B 多出的一行在窗口过后以单侧行输出
+	equal(t, c.Feed(B, at(200), []byte("warning\n")))
+	equal(t, c.Expire(at(250)))
+	equal(t, c.Expire(at(301)), "|warning")
+
+	// 另一侧有多行等待时与时间最接近的一行配对，更早的行以单侧行输出
+	c.Feed(A, at(1000), []byte("x\n"))
+	c.Feed(A, at(1080), []byte("y\n"))
+	equal(t, c.Feed(B, at(1090), []byte("y\n")), "x|", "y|y|=")
+
+	rows, sum := c.Finish(at(2000))
+	equal(t, rows)
+	if sum.Rows != 4 || sum.Matched != 1 || sum.Mismatched != 1 || sum.OnlyA != 1 || sum.OnlyB != 1 || sum.Similarity != 0.25 {
+		t.Errorf("summary %+v", sum)
+	}
+}
+
+func TestFinishFlushesPartialLines(t *testing.T) {
+	c := New(Config{Mode: Time})
+	now := time.Unix(100, 0)
+	c.Feed(A, now, []byte("no newline"))
+	rows, _ := c.Finish(now)
+	equal(t, rows, "no newline|")
+}
+
+func TestParseMode(t *testing.T) {
+	if m, err := ParseMode(""); m != Lockstep || err != nil {
+		t.Errorf("ParseMode(\"\") = %q, %v", m, err)
+	}
+	if _, err := ParseMode("fuzzy"); err == nil {
+		t.Error("Expected error for unknown mode")
+	}
+}
@@ -0,0 +1,434 @@
+// Package connspec 解析单行连接字符串，用于快速打开连接
+//
+// 语法：
+//
+//	serial:PORT[@BAUD][,FRAME]      serial:COM7@115200,8N1  serial:/dev/ttyUSB0@9600,7E2
+//	                                FRAME = 数据位(5-8) 校验位(N/E/O/M/S) 停止位(1/1.5/2)
+//	slcan:PORT[@RATE]               slcan:COM3@S6  slcan:COM3@500k  (RATE 为 S0-S8 或对应的位速率)
+//	jlink:CHIP[@SPEED][/IFACE]      jlink:STM32F103C8@4000/SWD  jlink:nRF52840_xxAA@auto
+//	tcp://HOST:PORT                 tcp://192.168.1.50:4001  tcp://[fe80::1]:23
+//	tcp-server://[HOST]:PORT        tcp-server://:4001 (HOST 被忽略，监听所有地址)
+//	udp://HOST:PORT[?local=PORT]    udp://192.168.1.50:4001?local=5000
+//	virtual:                        进程内背靠背连接对 (理想链路)，无需硬件
+//
+// 类型名不区分大小写；省略的参数使用默认值，并在 Spec.Defaulted 中列出
+package connspec
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"serial-assistant/pkg/jlink"
+	"serial-assistant/pkg/slcan"
+)
+
+// Kind 连接类型
+type Kind string
+
+const (
+	Serial    Kind = "serial"
+	Slcan     Kind = "slcan"
+	JLink     Kind = "jlink"
+	TcpClient Kind = "tcp"
+	TcpServer Kind = "tcp-server"
+	Udp       Kind = "udp"
+	Virtual   Kind = "virtual"
+)
+
+// 默认值
+const (
+	DefaultBaud  = 115200
+	DefaultFrame = "8N1"
+	// DefaultSlcanRate 默认 CAN 位速率代码 (S6 = 500k)
+	DefaultSlcanRate = 6
+)
+
+// Spec 解析后的连接参数
+type Spec struct {
+	Kind Kind `json:"kind"`
+
+	// 串口 / SLCAN
+	Port     string `json:"port,omitempty"`
+	Baud     int    `json:"baud,omitempty"`
+	DataBits int    `json:"dataBits,omitempty"`
+	Parity   string `json:"parity,omitempty"`   // None/Odd/Even/Mark/Space，与 OpenSerial 一致
+	StopBits int    `json:"stopBits,omitempty"` // 1、2 或 15 (1.5)，与 OpenSerial 一致
+	Bitrate  int    `json:"bitrate,omitempty"`  // SLCAN 位速率代码 0-8
+
+	// J-Link：Speed < 0 或 Interface 为空表示使用芯片预设
+	Chip      string `json:"chip,omitempty"`
+	Speed     int    `json:"speed,omitempty"`
+	Interface string `json:"interface,omitempty"`
+
+	// 网络
+	Host      string `json:"host,omitempty"`
+	NetPort   string `json:"netPort,omitempty"`
+	LocalPort string `json:"localPort,omitempty"`
+
+	// Inferred 由其他参数推断得出的字段 (例如芯片预设)，Defaulted 使用固定默认值的字段
+	Inferred  []string `json:"inferred"`
+	Defaulted []string `json:"defaulted"`
+}
+
+// Error 解析错误，Pos 为出错记号在输入中的字节偏移
+type Error struct {
+	Spec  string
+	Pos   int
+	Token string
+	Msg   string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s at position %d (%q)", e.Msg, e.Pos, e.Token)
+}
+
+// Parse 解析连接字符串
+func Parse(spec string) (*Spec, error) {
+	lead := len(spec) - len(strings.TrimLeft(spec, " \t"))
+	s := strings.TrimSpace(spec)
+	p := &parser{spec: spec, base: lead}
+	if s == "" {
+		return nil, p.errorf(0, spec, "empty connection spec")
+	}
+
+	var kind, rest string
+	var restPos int
+	if i := strings.Index(s, "://"); i >= 0 {
+		kind, rest, restPos = strings.ToLower(s[:i]), s[i+3:], i+3
+	} else if i := strings.IndexByte(s, ':'); i >= 0 {
+		kind, rest, restPos = strings.ToLower(s[:i]), s[i+1:], i+1
+	} else {
+		return nil, p.errorf(0, s, "missing connection type (expected serial:, slcan:, jlink:, tcp://, tcp-server://, udp:// or virtual:)")
+	}
+
+	switch Kind(kind) {
+	case Serial:
+		return p.parseSerial(rest, restPos)
+	case Slcan:
+		return p.parseSlcan(rest, restPos)
+	case JLink:
+		return p.parseJLink(rest, restPos)
+	case TcpClient, TcpServer, Udp:
+		return p.parseNet(Kind(kind), rest, restPos)
+	case Virtual:
+		if rest != "" {
+			return nil, p.errorf(restPos, rest, "virtual connections take no parameters")
+		}
+		return &Spec{Kind: Virtual}, nil
+	default:
+		return nil, p.errorf(0, s[:restPos], "unknown connection type %q", kind)
+	}
+}
+
+type parser struct {
+	spec string
+	base int // 去除的前导空白长度
+}
+
+func (p *parser) errorf(pos int, token string, format string, args ...interface{}) error {
+	return &Error{Spec: p.spec, Pos: p.base + pos, Token: token, Msg: fmt.Sprintf(format, args...)}
+}
+
+// split 在第一个 sep 处拆分，返回后半部分的偏移
+func split(s string, pos int, sep string) (head, tail string, tailPos int, ok bool) {
+	i := strings.Index(s, sep)
+	if i < 0 {
+		return s, "", 0, false
+	}
+	return s[:i], s[i+len(sep):], pos + i + len(sep), true
+}
+
+func (p *parser) parseSerial(rest string, pos int) (*Spec, error) {
+	out := &Spec{Kind: Serial}
+
+	body, frame, framePos, hasFrame := split(rest, pos, ",")
+	port, baud, baudPos, hasBaud := split(body, pos, "@")
+	if port == "" {
+		return nil, p.errorf(pos, rest, "missing serial port name")
+	}
+	out.Port = port
+
+	if hasBaud {
+		n, err := strconv.Atoi(baud)
+		if err != nil || n <= 0 {
+			return nil, p.errorf(baudPos, baud, "invalid baud rate")
+		}
+		out.Baud = n
+	} else {
+		out.Baud = DefaultBaud
+		out.Defaulted = append(out.Defaulted, fmt.Sprintf("baud=%d", DefaultBaud))
+	}
+
+	if !hasFrame {
+		frame, framePos = DefaultFrame, -1
+		out.Defaulted = append(out.Defaulted, "frame="+DefaultFrame)
+	}
+	if err := p.parseFrame(out, frame, framePos); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// parseFrame 解析 8N1 形式的帧格式
+func (p *parser) parseFrame(out *Spec, frame string, pos int) error {
+	f := strings.ToUpper(frame)
+	if len(f) < 3 {
+		return p.errorf(pos, frame, "invalid frame format (expected e.g. 8N1)")
+	}
+	if f[0] < '5' || f[0] > '8' {
+		return p.errorf(pos, frame[:1], "invalid data bits (expected 5-8)")
+	}
+	out.DataBits = int(f[0] - '0')
+
+	switch f[1] {
+	case 'N':
+		out.Parity = "None"
+	case 'E':
+		out.Parity = "Even"
+	case 'O':
+		out.Parity = "Odd"
+	case 'M':
+		out.Parity = "Mark"
+	case 'S':
+		out.Parity = "Space"
+	default:
+		return p.errorf(pos+1, frame[1:2], "invalid parity (expected N, E, O, M or S)")
+	}
+
+	switch f[2:] {
+	case "1":
+		out.StopBits = 1
+	case "1.5":
+		out.StopBits = 15
+	case "2":
+		out.StopBits = 2
+	default:
+		return p.errorf(pos+2, frame[2:], "invalid stop bits (expected 1, 1.5 or 2)")
+	}
+	return nil
+}
+
+func (p *parser) parseSlcan(rest string, pos int) (*Spec, error) {
+	out := &Spec{Kind: Slcan}
+
+	port, rate, ratePos, hasRate := split(rest, pos, "@")
+	if port == "" {
+		return nil, p.errorf(pos, rest, "missing serial port name")
+	}
+	out.Port = port
+
+	if !hasRate {
+		out.Bitrate = DefaultSlcanRate
+		out.Defaulted = append(out.Defaulted, fmt.Sprintf("bitrate=S%d", DefaultSlcanRate))
+		return out, nil
+	}
+
+	code, ok := slcanRate(rate)
+	if !ok {
+		return nil, p.errorf(ratePos, rate, "invalid CAN bitrate (expected S0-S8 or e.g. 500k)")
+	}
+	out.Bitrate = code
+	return out, nil
+}
+
+// slcanRate 将 S6、6、500k、500000 等形式转换为位速率代码
+func slcanRate(s string) (int, bool) {
+	u := strings.ToUpper(s)
+	if strings.HasPrefix(u, "S") && len(u) == 2 {
+		u = u[1:]
+	}
+	if len(u) == 1 && u[0] >= '0' && u[0] <= '8' {
+		return int(u[0] - '0'), true
+	}
+
+	mult := 1
+	switch {
+	case strings.HasSuffix(u, "K"):
+		mult, u = 1000, strings.TrimSuffix(u, "K")
+	case strings.HasSuffix(u, "M"):
+		mult, u = 1000000, strings.TrimSuffix(u, "M")
+	}
+	n, err := strconv.Atoi(u)
+	if err != nil {
+		return 0, false
+	}
+	for code, rate := range slcan.Bitrates {
+		if rate == n*mult {
+			return code, true
+		}
+	}
+	return 0, false
+}
+
+func (p *parser) parseJLink(rest string, pos int) (*Spec, error) {
+	out := &Spec{Kind: JLink, Speed: -1}
+
+	body, iface, ifacePos, hasIface := split(rest, pos, "/")
+	chip, speed, speedPos, hasSpeed := split(body, pos, "@")
+	if chip == "" {
+		return nil, p.errorf(pos, rest, "missing chip name")
+	}
+	out.Chip = chip
+	preset, hasPreset := jlink.LookupPreset(chip, nil)
+
+	if hasSpeed {
+		if strings.EqualFold(speed, "auto") {
+			out.Speed = jlink.SpeedAuto
+		} else {
+			n, err := strconv.Atoi(strings.TrimSuffix(strings.ToLower(speed), "khz"))
+			if err != nil || n <= 0 {
+				return nil, p.errorf(speedPos, speed, "invalid speed (expected kHz or auto)")
+			}
+			if out.Speed, err = jlink.NormalizeSpeed(n); err != nil {
+				return nil, p.errorf(speedPos, speed, "%v", err)
+			}
+		}
+	} else if hasPreset {
+		out.Inferred = append(out.Inferred, fmt.Sprintf("speed=%s (chip preset)", jlink.SpeedString(preset.Speed)))
+	} else {
+		out.Defaulted = append(out.Defaulted, "speed=auto")
+	}
+
+	if hasIface {
+		v, err := jlink.ParseInterface(iface)
+		if err != nil {
+			return nil, p.errorf(ifacePos, iface, "invalid interface (expected SWD or JTAG)")
+		}
+		out.Interface = v
+	} else if hasPreset {
+		out.Inferred = append(out.Inferred, fmt.Sprintf("interface=%s (chip preset)", preset.Interface))
+	} else {
+		out.Defaulted = append(out.Defaulted, "interface="+jlink.InterfaceSWD)
+	}
+	return out, nil
+}
+
+func (p *parser) parseNet(kind Kind, rest string, pos int) (*Spec, error) {
+	out := &Spec{Kind: kind}
+
+	addr, query, queryPos, hasQuery := split(rest, pos, "?")
+	if hasQuery && kind != Udp {
+		return nil, p.errorf(queryPos-1, "?"+query, "options are only supported for udp://")
+	}
+
+	// 主机与端口以最后一个冒号分隔，IPv6 地址需要使用方括号
+	i := strings.LastIndexByte(addr, ':')
+	if i < 0 {
+		if kind == TcpServer && addr != "" {
+			i = -1 // tcp-server://4001
+		} else {
+			return nil, p.errorf(pos, addr, "missing port (expected HOST:PORT)")
+		}
+	}
+	host, port, portPos := "", addr, pos
+	if i >= 0 {
+		host, port, portPos = addr[:i], addr[i+1:], pos+i+1
+	}
+	if strings.HasPrefix(host, "[") {
+		if !strings.HasSuffix(host, "]") {
+			return nil, p.errorf(pos, host, "unterminated IPv6 address")
+		}
+		host = host[1 : len(host)-1]
+	} else if strings.Contains(host, ":") {
+		return nil, p.errorf(pos, host, "IPv6 addresses must be enclosed in brackets")
+	}
+	if err := checkPort(port); err != nil {
+		return nil, p.errorf(portPos, port, "%v", err)
+	}
+	out.NetPort = port
+
+	switch kind {
+	case TcpClient, Udp:
+		if host == "" {
+			return nil, p.errorf(pos, addr, "missing host")
+		}
+		out.Host = host
+	case TcpServer:
+		out.Host = host
+	}
+
+	if kind == Udp {
+		if !hasQuery {
+			out.Defaulted = append(out.Defaulted, "local=ephemeral")
+			return out, nil
+		}
+		key, value, valuePos, ok := split(query, queryPos, "=")
+		if !ok || key != "local" {
+			return nil, p.errorf(queryPos, query, "unknown option (expected local=PORT)")
+		}
+		if err := checkPort(value); err != nil {
+			return nil, p.errorf(valuePos, value, "%v", err)
+		}
+		out.LocalPort = value
+	}
+	return out, nil
+}
+
+func checkPort(s string) error {
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 1 || n > 65535 {
+		return fmt.Errorf("invalid port (expected 1-65535)")
+	}
+	return nil
+}
+
+// String 将参数格式化为连接字符串，结果可以再次被 Parse 解析
+// J-Link 使用芯片预设的字段 (Speed < 0、Interface 为空) 会被省略
+func (s Spec) String() string {
+	switch s.Kind {
+	case Serial:
+		stop := strconv.Itoa(s.StopBits)
+		if s.StopBits == 15 {
+			stop = "1.5"
+		}
+		parity := "N"
+		if s.Parity != "" {
+			parity = strings.ToUpper(s.Parity[:1])
+		}
+		return fmt.Sprintf("serial:%s@%d,%d%s%s", s.Port, s.Baud, s.DataBits, parity, stop)
+	case Slcan:
+		return fmt.Sprintf("slcan:%s@S%d", s.Port, s.Bitrate)
+	case JLink:
+		out := "jlink:" + s.Chip
+		switch {
+		case s.Speed == jlink.SpeedAuto:
+			out += "@auto"
+		case s.Speed > 0:
+			out += "@" + strconv.Itoa(s.Speed)
+		}
+		if s.Interface != "" {
+			out += "/" + s.Interface
+		}
+		return out
+	case TcpServer:
+		return "tcp-server://" + net.JoinHostPort(s.Host, s.NetPort)
+	case Virtual:
+		return "virtual:"
+	case Udp:
+		out := "udp://" + net.JoinHostPort(s.Host, s.NetPort)
+		if s.LocalPort != "" {
+			out += "?local=" + s.LocalPort
+		}
+		return out
+	default:
+		return string(s.Kind) + "://" + net.JoinHostPort(s.Host, s.NetPort)
+	}
+}
+
+// Endpoint 返回连接的端点 (端口名、芯片、主机:端口)，用于文件名等简短标识
+func (s Spec) Endpoint() string {
+	switch s.Kind {
+	case Serial, Slcan:
+		return s.Port
+	case JLink:
+		return s.Chip
+	case TcpServer:
+		return "server-" + s.NetPort
+	case Virtual:
+		return "virtual"
+	default:
+		return net.JoinHostPort(s.Host, s.NetPort)
+	}
+}
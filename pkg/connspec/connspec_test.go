@@ -0,0 +1,157 @@
+package connspec
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParseValid(t *testing.T) {
+	tests := []struct {
+		spec     string
+		expected Spec
+	}{
+		{"serial:COM7@115200,8N1", Spec{Kind: Serial, Port: "COM7", Baud: 115200, DataBits: 8, Parity: "None", StopBits: 1}},
+		{"serial:/dev/ttyUSB0@9600,7e2", Spec{Kind: Serial, Port: "/dev/ttyUSB0", Baud: 9600, DataBits: 7, Parity: "Even", StopBits: 2}},
+		{"SERIAL:COM3@57600,8O1.5", Spec{Kind: Serial, Port: "COM3", Baud: 57600, DataBits: 8, Parity: "Odd", StopBits: 15}},
+		{"serial:COM3,5M1", Spec{Kind: Serial, Port: "COM3", Baud: DefaultBaud, DataBits: 5, Parity: "Mark", StopBits: 1,
+			Defaulted: []string{"baud=115200"}}},
+		{"serial:COM3@9600,8S1", Spec{Kind: Serial, Port: "COM3", Baud: 9600, DataBits: 8, Parity: "Space", StopBits: 1}},
+		{"  serial:COM1  ", Spec{Kind: Serial, Port: "COM1", Baud: DefaultBaud, DataBits: 8, Parity: "None", StopBits: 1,
+			Defaulted: []string{"baud=115200", "frame=8N1"}}},
+		{"serial://COM9@921600", Spec{Kind: Serial, Port: "COM9", Baud: 921600, DataBits: 8, Parity: "None", StopBits: 1,
+			Defaulted: []string{"frame=8N1"}}},
+
+		{"slcan:COM4@S8", Spec{Kind: Slcan, Port: "COM4", Bitrate: 8}},
+		{"slcan:COM4@500k", Spec{Kind: Slcan, Port: "COM4", Bitrate: 6}},
+		{"slcan:COM4@1M", Spec{Kind: Slcan, Port: "COM4", Bitrate: 8}},
+		{"slcan:COM4@125000", Spec{Kind: Slcan, Port: "COM4", Bitrate: 4}},
+		{"slcan:COM4@3", Spec{Kind: Slcan, Port: "COM4", Bitrate: 3}},
+		{"slcan:COM4", Spec{Kind: Slcan, Port: "COM4", Bitrate: DefaultSlcanRate, Defaulted: []string{"bitrate=S6"}}},
+
+		{"jlink:STM32F103C8@4000/SWD", Spec{Kind: JLink, Chip: "STM32F103C8", Speed: 4000, Interface: "SWD"}},
+		{"jlink:STM32F103C8@4000kHz/jtag", Spec{Kind: JLink, Chip: "STM32F103C8", Speed: 4000, Interface: "JTAG"}},
+		{"jlink:MyChip@auto", Spec{Kind: JLink, Chip: "MyChip", Speed: 0, Defaulted: []string{"interface=SWD"}}},
+		{"jlink:MyChip@100000/SWD", Spec{Kind: JLink, Chip: "MyChip", Speed: 50000, Interface: "SWD"}},
+		{"jlink:MyChip", Spec{Kind: JLink, Chip: "MyChip", Speed: -1, Defaulted: []string{"speed=auto", "interface=SWD"}}},
+		{"jlink:STM32H743ZI", Spec{Kind: JLink, Chip: "STM32H743ZI", Speed: -1,
+			Inferred: []string{"speed=12000 (chip preset)", "interface=SWD (chip preset)"}}},
+
+		{"tcp://192.168.1.50:4001", Spec{Kind: TcpClient, Host: "192.168.1.50", NetPort: "4001"}},
+		{"TCP://example.com:23", Spec{Kind: TcpClient, Host: "example.com", NetPort: "23"}},
+		{"tcp://[fe80::1]:23", Spec{Kind: TcpClient, Host: "fe80::1", NetPort: "23"}},
+		{"tcp-server://:4001", Spec{Kind: TcpServer, NetPort: "4001"}},
+		{"tcp-server://4001", Spec{Kind: TcpServer, NetPort: "4001"}},
+		{"tcp-server://0.0.0.0:4001", Spec{Kind: TcpServer, Host: "0.0.0.0", NetPort: "4001"}},
+		{"udp://192.168.1.50:4001?local=5000", Spec{Kind: Udp, Host: "192.168.1.50", NetPort: "4001", LocalPort: "5000"}},
+		{"udp://10.0.0.1:9", Spec{Kind: Udp, Host: "10.0.0.1", NetPort: "9", Defaulted: []string{"local=ephemeral"}}},
+		{"Virtual:", Spec{Kind: Virtual}},
+	}
+
+	for _, tt := range tests {
+		got, err := Parse(tt.spec)
+		if err != nil {
+			t.Errorf("Parse(%q) failed: %v", tt.spec, err)
+			continue
+		}
+		if !reflect.DeepEqual(*got, tt.expected) {
+			t.Errorf("Parse(%q) = %+v\nexpected %+v", tt.spec, *got, tt.expected)
+		}
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	tests := []struct {
+		spec  string
+		pos   int
+		token string
+	}{
+		{"", 0, ""},
+		{"COM7", 0, "COM7"},
+		{"modem:COM7", 0, "modem:"},
+		{"serial:", 7, ""},
+		{"serial:@9600", 7, "@9600"},
+		{"serial:COM7@fast", 12, "fast"},
+		{"serial:COM7@-1", 12, "-1"},
+		{"serial:COM7@9600,9N1", 17, "9"},
+		{"serial:COM7@9600,8X1", 18, "X"},
+		{"serial:COM7@9600,8N3", 19, "3"},
+		{"serial:COM7@9600,8N", 17, "8N"},
+		{"  serial:COM7@fast", 14, "fast"},
+		{"slcan:COM4@S9", 11, "S9"},
+		{"slcan:COM4@300k", 11, "300k"},
+		{"slcan:", 6, ""},
+		{"jlink:", 6, ""},
+		{"jlink:STM32@fast/SWD", 12, "fast"},
+		{"jlink:STM32@4000/UART", 17, "UART"},
+		{"tcp://192.168.1.50", 6, "192.168.1.50"},
+		{"tcp://:4001", 6, ":4001"},
+		{"tcp://host:0", 11, "0"},
+		{"tcp://host:70000", 11, "70000"},
+		{"tcp://host:port", 11, "port"},
+		{"tcp://fe80::1:23", 6, "fe80::1"},
+		{"tcp://[fe80::1:23", 6, "[fe80::1"},
+		{"tcp://host:23?local=1", 13, "?local=1"},
+		{"udp://host:23?remote=1", 14, "remote=1"},
+		{"udp://host:23?local=x", 20, "x"},
+		{"tcp-server://", 13, ""},
+		{"virtual:COM1", 8, "COM1"},
+	}
+
+	for _, tt := range tests {
+		_, err := Parse(tt.spec)
+		var perr *Error
+		if !errors.As(err, &perr) {
+			t.Errorf("Parse(%q): expected *Error, got %v", tt.spec, err)
+			continue
+		}
+		if perr.Pos != tt.pos || perr.Token != tt.token {
+			t.Errorf("Parse(%q): error at %d %q, expected %d %q (%v)", tt.spec, perr.Pos, perr.Token, tt.pos, tt.token, err)
+		}
+	}
+}
+
+func TestStringRoundTrip(t *testing.T) {
+	for _, spec := range []string{
+		"serial:COM7@115200,8N1",
+		"serial:/dev/ttyUSB0@9600,7E1.5",
+		"slcan:COM3@S6",
+		"jlink:STM32F103C8@4000/SWD",
+		"jlink:nRF52840_xxAA@auto",
+		"jlink:MyChip",
+		"tcp://192.168.1.50:4001",
+		"tcp://[fe80::1]:23",
+		"tcp-server://:4001",
+		"udp://192.168.1.50:4001?local=5000",
+		"virtual:",
+	} {
+		parsed, err := Parse(spec)
+		if err != nil {
+			t.Fatalf("Parse(%q) failed: %v", spec, err)
+		}
+		if got := parsed.String(); got != spec {
+			t.Errorf("String() = %q, want %q", got, spec)
+		}
+	}
+}
+
+func TestEndpoint(t *testing.T) {
+	tests := map[string]string{
+		"serial:/dev/ttyUSB0@9600":   "/dev/ttyUSB0",
+		"slcan:COM3":                 "COM3",
+		"jlink:STM32F103C8@4000/SWD": "STM32F103C8",
+		"tcp://192.168.1.50:4001":    "192.168.1.50:4001",
+		"tcp-server://:4001":         "server-4001",
+		"udp://10.0.0.2:7?local=8":   "10.0.0.2:7",
+		"virtual:":                   "virtual",
+	}
+	for spec, want := range tests {
+		parsed, err := Parse(spec)
+		if err != nil {
+			t.Fatalf("Parse(%q) failed: %v", spec, err)
+		}
+		if got := parsed.Endpoint(); got != want {
+			t.Errorf("Endpoint(%q) = %q, want %q", spec, got, want)
+		}
+	}
+}
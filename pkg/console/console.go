@@ -0,0 +1,124 @@
+// Package console 提供交互式终端 (U-Boot、Zephyr shell 等) 所需的按键编码与本地回显抑制
+package console
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CtrlByte 返回 Ctrl+<key> 对应的控制字符，例如 'C' -> 0x03
+// 支持 A-Z 以及 @ [ \ ] ^ _ （即 0x00-0x1F 全部控制字符）
+func CtrlByte(key string) (byte, error) {
+	if len(key) != 1 {
+		return 0, fmt.Errorf("invalid control key %q", key)
+	}
+	c := key[0]
+	if c >= 'a' && c <= 'z' {
+		c -= 'a' - 'A'
+	}
+	if c < '@' || c > '_' {
+		return 0, fmt.Errorf("invalid control key %q", key)
+	}
+	return c - '@', nil
+}
+
+// escapeSequences 常用按键对应的 VT100/xterm 转义序列
+var escapeSequences = map[string]string{
+	"up":        "\x1b[A",
+	"down":      "\x1b[B",
+	"right":     "\x1b[C",
+	"left":      "\x1b[D",
+	"home":      "\x1b[H",
+	"end":       "\x1b[F",
+	"insert":    "\x1b[2~",
+	"delete":    "\x1b[3~",
+	"pageup":    "\x1b[5~",
+	"pagedown":  "\x1b[6~",
+	"esc":       "\x1b",
+	"tab":       "\t",
+	"enter":     "\r",
+	"backspace": "\x7f",
+	"f1":        "\x1bOP",
+	"f2":        "\x1bOQ",
+	"f3":        "\x1bOR",
+	"f4":        "\x1bOS",
+	"f5":        "\x1b[15~",
+	"f6":        "\x1b[17~",
+	"f7":        "\x1b[18~",
+	"f8":        "\x1b[19~",
+	"f9":        "\x1b[20~",
+	"f10":       "\x1b[21~",
+	"f11":       "\x1b[23~",
+	"f12":       "\x1b[24~",
+}
+
+// EscapeSequence 返回按键名对应的字节序列（名称不区分大小写）
+func EscapeSequence(name string) ([]byte, error) {
+	seq, ok := escapeSequences[strings.ToLower(name)]
+	if !ok {
+		return nil, fmt.Errorf("unknown key %q", name)
+	}
+	return []byte(seq), nil
+}
+
+// EchoSuppressor 记录最近发送的按键，并从接收数据中去掉远端回显的相同字节
+// 适用于前端已经本地回显输入字符、而远端 shell 也会回显的场景
+type EchoSuppressor struct {
+	mu      sync.Mutex
+	pending []byte
+	expires time.Time
+	window  time.Duration
+	now     func() time.Time
+}
+
+// maxPendingEcho 等待回显的最大字节数，超出时丢弃最旧的部分
+const maxPendingEcho = 256
+
+// NewEchoSuppressor 创建回显抑制器，window 为等待远端回显的最长时间
+func NewEchoSuppressor(window time.Duration) *EchoSuppressor {
+	return &EchoSuppressor{window: window, now: time.Now}
+}
+
+// Expect 登记已发送、预期会被回显的字节
+func (e *EchoSuppressor) Expect(sent []byte) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.now().After(e.expires) {
+		e.pending = e.pending[:0]
+	}
+	e.pending = append(e.pending, sent...)
+	if len(e.pending) > maxPendingEcho {
+		e.pending = e.pending[len(e.pending)-maxPendingEcho:]
+	}
+	e.expires = e.now().Add(e.window)
+}
+
+// Filter 去掉 rx 开头与待回显字节一致的部分，返回剩余数据
+// 一旦出现不匹配的字节，认为远端没有回显，清空等待列表并原样返回
+func (e *EchoSuppressor) Filter(rx []byte) []byte {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if len(e.pending) == 0 {
+		return rx
+	}
+	if e.now().After(e.expires) {
+		e.pending = e.pending[:0]
+		return rx
+	}
+
+	n := 0
+	for n < len(rx) && n < len(e.pending) && rx[n] == e.pending[n] {
+		n++
+	}
+	if n == 0 {
+		e.pending = e.pending[:0]
+		return rx
+	}
+	e.pending = e.pending[:copy(e.pending, e.pending[n:])]
+	return bytes.Clone(rx[n:])
+}
@@ -0,0 +1,88 @@
+package console
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestCtrlByte(t *testing.T) {
+	tests := []struct {
+		key      string
+		expected byte
+	}{
+		{"C", 0x03},
+		{"c", 0x03},
+		{"A", 0x01},
+		{"Z", 0x1A},
+		{"@", 0x00},
+		{"[", 0x1B},
+		{"_", 0x1F},
+	}
+	for _, tt := range tests {
+		b, err := CtrlByte(tt.key)
+		if err != nil {
+			t.Errorf("CtrlByte(%q) returned error: %v", tt.key, err)
+			continue
+		}
+		if b != tt.expected {
+			t.Errorf("CtrlByte(%q) = 0x%02X, expected 0x%02X", tt.key, b, tt.expected)
+		}
+	}
+
+	for _, key := range []string{"", "CC", "1", "~"} {
+		if _, err := CtrlByte(key); err == nil {
+			t.Errorf("CtrlByte(%q) expected error", key)
+		}
+	}
+}
+
+func TestEscapeSequence(t *testing.T) {
+	seq, err := EscapeSequence("Up")
+	if err != nil || string(seq) != "\x1b[A" {
+		t.Errorf("EscapeSequence(Up) = %q, %v", seq, err)
+	}
+	if _, err := EscapeSequence("nonexistent"); err == nil {
+		t.Error("Expected error for unknown key")
+	}
+}
+
+func TestEchoSuppressorStripsEcho(t *testing.T) {
+	now := time.Now()
+	e := NewEchoSuppressor(time.Second)
+	e.now = func() time.Time { return now }
+
+	e.Expect([]byte("ls"))
+	// 回显被拆分到两次接收中
+	if out := e.Filter([]byte("l")); len(out) != 0 {
+		t.Errorf("Expected first echo byte suppressed, got %q", out)
+	}
+	if out := e.Filter([]byte("s\r\nfile.txt")); !bytes.Equal(out, []byte("\r\nfile.txt")) {
+		t.Errorf("Expected remaining output after echo, got %q", out)
+	}
+	// 回显消费完毕后数据原样通过
+	if out := e.Filter([]byte("ls")); !bytes.Equal(out, []byte("ls")) {
+		t.Errorf("Expected passthrough when nothing pending, got %q", out)
+	}
+}
+
+func TestEchoSuppressorMismatchAndExpiry(t *testing.T) {
+	now := time.Now()
+	e := NewEchoSuppressor(time.Second)
+	e.now = func() time.Time { return now }
+
+	e.Expect([]byte("abc"))
+	if out := e.Filter([]byte("xyz")); !bytes.Equal(out, []byte("xyz")) {
+		t.Errorf("Mismatch should pass data through, got %q", out)
+	}
+	// 不匹配后等待列表被清空
+	if out := e.Filter([]byte("abc")); !bytes.Equal(out, []byte("abc")) {
+		t.Errorf("Pending echo should be cleared after mismatch, got %q", out)
+	}
+
+	e.Expect([]byte("q"))
+	now = now.Add(2 * time.Second)
+	if out := e.Filter([]byte("q")); !bytes.Equal(out, []byte("q")) {
+		t.Errorf("Expired echo should not be suppressed, got %q", out)
+	}
+}
@@ -0,0 +1,119 @@
+// Package devid 按 USB VID:PID 与序列号识别串口设备，使保存的配置跟随物理设备，
+// 而不是随插拔顺序变化的端口名 (COM5 变成 COM9、ttyACM0 变成 ttyACM1)
+//
+// 指纹格式为 "usb:vvvv:pppp:SERIAL"，没有序列号的设备为 "usb:vvvv:pppp"。没有序列号时
+// 同型号的设备共用一个指纹，Resolve 在多个设备同时插入时返回候选端口而不是猜测其中一个。
+package devid
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Fingerprint 设备指纹，空字符串表示无法识别 (非 USB 串口)
+type Fingerprint string
+
+// Port 枚举到的串口，字段与 enumerator.PortDetails 一致
+type Port struct {
+	Name         string `json:"name"`
+	IsUSB        bool   `json:"isUsb"`
+	VID          string `json:"vid,omitempty"`
+	PID          string `json:"pid,omitempty"`
+	SerialNumber string `json:"serialNumber,omitempty"`
+	Product      string `json:"product,omitempty"`
+}
+
+// Of 返回端口的指纹，非 USB 串口或缺少 VID/PID 时返回空字符串
+func Of(p Port) Fingerprint {
+	vid, pid := strings.ToLower(strings.TrimSpace(p.VID)), strings.ToLower(strings.TrimSpace(p.PID))
+	if !p.IsUSB || vid == "" || pid == "" {
+		return ""
+	}
+	fp := "usb:" + vid + ":" + pid
+	if sn := strings.TrimSpace(p.SerialNumber); sn != "" {
+		fp += ":" + sn
+	}
+	return Fingerprint(fp)
+}
+
+// Parse 检查指纹的格式，VID/PID 统一为小写
+func Parse(s string) (Fingerprint, error) {
+	parts := strings.SplitN(strings.TrimSpace(s), ":", 4)
+	if len(parts) < 3 || parts[0] != "usb" {
+		return "", fmt.Errorf("invalid device fingerprint %q (expected usb:VID:PID[:SERIAL])", s)
+	}
+	for _, id := range parts[1:3] {
+		if !isHexID(id) {
+			return "", fmt.Errorf("invalid device fingerprint %q: %q is not a 4-digit hex id", s, id)
+		}
+	}
+	fp := "usb:" + strings.ToLower(parts[1]) + ":" + strings.ToLower(parts[2])
+	if len(parts) == 4 {
+		if parts[3] == "" {
+			return "", fmt.Errorf("invalid device fingerprint %q: empty serial number", s)
+		}
+		fp += ":" + parts[3]
+	}
+	return Fingerprint(fp), nil
+}
+
+func isHexID(s string) bool {
+	if len(s) != 4 {
+		return false
+	}
+	for _, c := range s {
+		if !strings.ContainsRune("0123456789abcdefABCDEF", c) {
+			return false
+		}
+	}
+	return true
+}
+
+// Unique 指纹包含序列号，可以区分同型号的多个设备
+func (f Fingerprint) Unique() bool {
+	return strings.Count(string(f), ":") >= 3
+}
+
+// ErrNotPresent 没有插入指纹对应的设备
+var ErrNotPresent = errors.New("device not present")
+
+// AmbiguousError 多个插入的设备共用一个指纹 (没有序列号)，由用户从 Candidates 中选择
+type AmbiguousError struct {
+	Fingerprint Fingerprint
+	Candidates  []string // 端口名，已排序
+}
+
+func (e *AmbiguousError) Error() string {
+	return fmt.Sprintf("%d devices match %s: %s", len(e.Candidates), e.Fingerprint, strings.Join(e.Candidates, ", "))
+}
+
+// Resolve 在当前枚举到的端口中查找指纹对应的端口名；
+// 没有匹配时返回 ErrNotPresent，多个匹配时返回 *AmbiguousError
+func Resolve(fp Fingerprint, ports []Port) (string, error) {
+	var matches []string
+	for _, p := range ports {
+		if fp != "" && Of(p) == fp {
+			matches = append(matches, p.Name)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return "", fmt.Errorf("%w: %s", ErrNotPresent, fp)
+	case 1:
+		return matches[0], nil
+	}
+	sort.Strings(matches)
+	return "", &AmbiguousError{Fingerprint: fp, Candidates: matches}
+}
+
+// Find 返回端口名对应的端口信息
+func Find(name string, ports []Port) (Port, bool) {
+	for _, p := range ports {
+		if p.Name == name {
+			return p, true
+		}
+	}
+	return Port{}, false
+}
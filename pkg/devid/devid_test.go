@@ -0,0 +1,71 @@
+package devid
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestOf(t *testing.T) {
+	tests := []struct {
+		port Port
+		want Fingerprint
+	}{
+		{Port{Name: "COM5", IsUSB: true, VID: "0483", PID: "5740", SerialNumber: "3671359A3133"}, "usb:0483:5740:3671359A3133"},
+		{Port{Name: "COM6", IsUSB: true, VID: "1A86", PID: "7523"}, "usb:1a86:7523"},
+		{Port{Name: "COM1"}, ""},
+		{Port{Name: "COM7", IsUSB: true}, ""},
+	}
+	for _, tt := range tests {
+		if got := Of(tt.port); got != tt.want {
+			t.Errorf("Of(%s) = %q, expected %q", tt.port.Name, got, tt.want)
+		}
+	}
+	if !Fingerprint("usb:0483:5740:3671359A3133").Unique() || Fingerprint("usb:1a86:7523").Unique() {
+		t.Error("Unique should depend on the serial number")
+	}
+}
+
+func TestParse(t *testing.T) {
+	fp, err := Parse(" usb:0483:5740:AB:CD ")
+	if err != nil || fp != "usb:0483:5740:AB:CD" {
+		t.Errorf("Parse = %q, %v", fp, err)
+	}
+	if fp, _ := Parse("usb:1A86:7523"); fp != "usb:1a86:7523" {
+		t.Errorf("Expected lower-case ids, got %q", fp)
+	}
+	for _, s := range []string{"", "COM5", "usb:0483", "usb:483:5740", "usb:0483:57x0", "usb:0483:5740:", "pci:0483:5740"} {
+		if _, err := Parse(s); err == nil {
+			t.Errorf("Parse(%q): expected an error", s)
+		}
+	}
+}
+
+func TestResolve(t *testing.T) {
+	ports := []Port{
+		{Name: "COM9", IsUSB: true, VID: "0483", PID: "5740", SerialNumber: "A1"},
+		{Name: "COM4", IsUSB: true, VID: "1a86", PID: "7523"},
+		{Name: "COM3", IsUSB: true, VID: "1a86", PID: "7523"},
+		{Name: "COM1"},
+	}
+
+	// 板子从 COM5 换到了 COM9
+	if name, err := Resolve("usb:0483:5740:A1", ports); err != nil || name != "COM9" {
+		t.Errorf("Resolve = %q, %v", name, err)
+	}
+
+	_, err := Resolve("usb:1a86:7523", ports)
+	var amb *AmbiguousError
+	if !errors.As(err, &amb) {
+		t.Fatalf("Expected AmbiguousError, got %v", err)
+	}
+	if len(amb.Candidates) != 2 || amb.Candidates[0] != "COM3" || amb.Candidates[1] != "COM4" {
+		t.Errorf("Candidates = %v", amb.Candidates)
+	}
+
+	if _, err := Resolve("usb:0483:5740:B2", ports); !errors.Is(err, ErrNotPresent) {
+		t.Errorf("Expected ErrNotPresent, got %v", err)
+	}
+	if _, err := Resolve("", ports); !errors.Is(err, ErrNotPresent) {
+		t.Errorf("Empty fingerprint must not match non-USB ports, got %v", err)
+	}
+}
@@ -0,0 +1,209 @@
+// Package diag 实现启动自检与环境诊断，生成可直接粘贴到 issue 中的报告
+package diag
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"serial-assistant/pkg/jlink"
+
+	"go.bug.st/serial/enumerator"
+)
+
+// Status 检查结果状态
+type Status string
+
+const (
+	StatusOK   Status = "ok"
+	StatusWarn Status = "warn"
+	StatusFail Status = "fail"
+)
+
+// CheckTimeout 单项检查的最长耗时
+const CheckTimeout = 2 * time.Second
+
+// minFreeDiskBytes 日志目录可用空间低于该值时给出警告
+const minFreeDiskBytes = 100 * 1024 * 1024
+
+// Check 单项检查结果
+type Check struct {
+	ID      string   `json:"id"`
+	Status  Status   `json:"status"`
+	Message string   `json:"message"`
+	Details []string `json:"details,omitempty"`
+}
+
+// Report 诊断报告
+type Report struct {
+	Time   time.Time `json:"time"`
+	Checks []Check   `json:"checks"`
+	Text   string    `json:"text"` // 纯文本格式，便于粘贴到 issue
+}
+
+// Probe 一项待执行的检查
+type Probe struct {
+	ID  string
+	Run func() Check
+}
+
+// Run 并行执行所有检查，超过 timeout 的检查记为警告
+// 结果顺序与 probes 一致
+func Run(probes []Probe, timeout time.Duration) Report {
+	checks := make([]Check, len(probes))
+
+	var wg sync.WaitGroup
+	for i, p := range probes {
+		wg.Add(1)
+		go func(i int, p Probe) {
+			defer wg.Done()
+
+			done := make(chan Check, 1)
+			go func() {
+				defer func() {
+					if r := recover(); r != nil {
+						done <- Check{Status: StatusFail, Message: fmt.Sprintf("check panicked: %v", r)}
+					}
+				}()
+				done <- p.Run()
+			}()
+
+			timer := time.NewTimer(timeout)
+			defer timer.Stop()
+			select {
+			case c := <-done:
+				c.ID = p.ID
+				checks[i] = c
+			case <-timer.C:
+				checks[i] = Check{ID: p.ID, Status: StatusWarn, Message: fmt.Sprintf("check timed out after %v", timeout)}
+			}
+		}(i, p)
+	}
+	wg.Wait()
+
+	r := Report{Time: time.Now(), Checks: checks}
+	r.Text = r.format()
+	return r
+}
+
+// format 将报告格式化为纯文本
+func (r Report) format() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "serial-mate diagnostics (%s)\n", r.Time.Format(time.RFC3339))
+	for _, c := range r.Checks {
+		fmt.Fprintf(&sb, "[%s] %s: %s\n", strings.ToUpper(string(c.Status)), c.ID, c.Message)
+		for _, d := range c.Details {
+			fmt.Fprintf(&sb, "    %s\n", d)
+		}
+	}
+	return sb.String()
+}
+
+// DefaultProbes 返回标准检查列表，configDir 为配置 (及日志) 目录
+func DefaultProbes(version, configDir string) []Probe {
+	return []Probe{
+		{ID: "system", Run: func() Check { return SystemCheck(version) }},
+		{ID: "serial-ports", Run: SerialPortsCheck},
+		{ID: "jlink-library", Run: JLinkCheck},
+		{ID: "config-dir", Run: func() Check { return ConfigDirCheck(configDir) }},
+		{ID: "disk-space", Run: func() Check { return DiskSpaceCheck(configDir) }},
+		{ID: "network", Run: NetworkCheck},
+	}
+}
+
+// SystemCheck 报告操作系统与架构
+func SystemCheck(version string) Check {
+	return Check{
+		Status:  StatusOK,
+		Message: fmt.Sprintf("serial-mate %s on %s/%s (%s)", version, runtime.GOOS, runtime.GOARCH, runtime.Version()),
+	}
+}
+
+// SerialPortsCheck 枚举串口及 USB 信息（只枚举，不打开任何端口）
+func SerialPortsCheck() Check {
+	ports, err := enumerator.GetDetailedPortsList()
+	if err != nil {
+		return Check{Status: StatusFail, Message: fmt.Sprintf("failed to enumerate serial ports: %v", err)}
+	}
+	if len(ports) == 0 {
+		return Check{Status: StatusWarn, Message: "no serial ports found (is the USB driver installed?)"}
+	}
+
+	c := Check{Status: StatusOK, Message: fmt.Sprintf("%d serial port(s) found", len(ports))}
+	for _, p := range ports {
+		if p.IsUSB {
+			c.Details = append(c.Details, fmt.Sprintf("%s USB %s:%s serial=%q product=%q", p.Name, p.VID, p.PID, p.SerialNumber, p.Product))
+		} else {
+			c.Details = append(c.Details, p.Name)
+		}
+	}
+	return c
+}
+
+// JLinkCheck 检查 J-Link 驱动库能否加载
+func JLinkCheck() Check {
+	probe := jlink.ProbeLibrary()
+	if !probe.Loaded {
+		return Check{
+			Status:  StatusWarn,
+			Message: "J-Link library not loaded (only needed for RTT)",
+			Details: []string{"path tried: " + probe.Path, "error: " + probe.Error},
+		}
+	}
+	version := probe.Version
+	if version == "" {
+		version = "unknown version"
+	}
+	return Check{Status: StatusOK, Message: fmt.Sprintf("J-Link library %s loaded from %s", version, probe.Path)}
+}
+
+// ConfigDirCheck 检查配置目录是否可写（创建并删除一个探测文件）
+func ConfigDirCheck(dir string) Check {
+	if dir == "" {
+		return Check{Status: StatusFail, Message: "config directory could not be determined"}
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return Check{Status: StatusFail, Message: fmt.Sprintf("cannot create %s: %v", dir, err)}
+	}
+	probe := filepath.Join(dir, fmt.Sprintf(".write-probe-%d", os.Getpid()))
+	if err := os.WriteFile(probe, nil, 0644); err != nil {
+		return Check{Status: StatusFail, Message: fmt.Sprintf("%s is not writable: %v", dir, err)}
+	}
+	os.Remove(probe)
+	return Check{Status: StatusOK, Message: fmt.Sprintf("%s is writable", dir)}
+}
+
+// DiskSpaceCheck 检查目录所在磁盘的可用空间
+func DiskSpaceCheck(dir string) Check {
+	free, err := freeDiskBytes(dir)
+	if err != nil {
+		return Check{Status: StatusWarn, Message: fmt.Sprintf("cannot determine free disk space: %v", err)}
+	}
+	msg := fmt.Sprintf("%.1f GB free for logs", float64(free)/(1<<30))
+	if free < minFreeDiskBytes {
+		return Check{Status: StatusWarn, Message: msg + " (low)"}
+	}
+	return Check{Status: StatusOK, Message: msg}
+}
+
+// NetworkCheck 检查能否在回环地址上绑定临时 TCP/UDP 端口
+func NetworkCheck() Check {
+	tcp, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return Check{Status: StatusFail, Message: fmt.Sprintf("cannot bind TCP port: %v", err)}
+	}
+	tcp.Close()
+
+	udp, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		return Check{Status: StatusFail, Message: fmt.Sprintf("cannot bind UDP port: %v", err)}
+	}
+	udp.Close()
+
+	return Check{Status: StatusOK, Message: "TCP and UDP sockets can be bound"}
+}
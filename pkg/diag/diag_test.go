@@ -0,0 +1,89 @@
+package diag
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunPreservesOrderAndTimesOut(t *testing.T) {
+	block := make(chan struct{})
+	defer close(block)
+
+	probes := []Probe{
+		{ID: "fast", Run: func() Check { return Check{Status: StatusOK, Message: "fine"} }},
+		{ID: "slow", Run: func() Check { <-block; return Check{Status: StatusOK} }},
+		{ID: "panics", Run: func() Check { panic("boom") }},
+	}
+
+	start := time.Now()
+	r := Run(probes, 50*time.Millisecond)
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Run should be bounded by the per-check timeout, took %v", elapsed)
+	}
+
+	if len(r.Checks) != 3 {
+		t.Fatalf("Expected 3 checks, got %d", len(r.Checks))
+	}
+	if r.Checks[0].ID != "fast" || r.Checks[0].Status != StatusOK {
+		t.Errorf("Unexpected first check: %+v", r.Checks[0])
+	}
+	if r.Checks[1].ID != "slow" || r.Checks[1].Status != StatusWarn || !strings.Contains(r.Checks[1].Message, "timed out") {
+		t.Errorf("Expected timed-out warning, got %+v", r.Checks[1])
+	}
+	if r.Checks[2].ID != "panics" || r.Checks[2].Status != StatusFail {
+		t.Errorf("Expected panic to be reported as failure, got %+v", r.Checks[2])
+	}
+}
+
+func TestReportText(t *testing.T) {
+	r := Run([]Probe{
+		{ID: "a", Run: func() Check { return Check{Status: StatusOK, Message: "ok msg", Details: []string{"detail"}} }},
+		{ID: "b", Run: func() Check { return Check{Status: StatusFail, Message: "bad"} }},
+	}, time.Second)
+
+	for _, want := range []string{"serial-mate diagnostics", "[OK] a: ok msg", "    detail", "[FAIL] b: bad"} {
+		if !strings.Contains(r.Text, want) {
+			t.Errorf("Report text missing %q:\n%s", want, r.Text)
+		}
+	}
+}
+
+func TestConfigDirCheck(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "serial-mate")
+	if c := ConfigDirCheck(dir); c.Status != StatusOK {
+		t.Errorf("Expected writable temp dir, got %+v", c)
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 0 {
+		t.Errorf("Probe file should be removed, found %d entries", len(entries))
+	}
+
+	if c := ConfigDirCheck(""); c.Status != StatusFail {
+		t.Errorf("Expected failure for empty dir, got %+v", c)
+	}
+
+	if runtime.GOOS != "windows" && os.Getuid() != 0 {
+		ro := t.TempDir()
+		os.Chmod(ro, 0555)
+		defer os.Chmod(ro, 0755)
+		if c := ConfigDirCheck(ro); c.Status != StatusFail {
+			t.Errorf("Expected failure for read-only dir, got %+v", c)
+		}
+	}
+}
+
+func TestDiskSpaceAndNetworkChecks(t *testing.T) {
+	if c := DiskSpaceCheck(t.TempDir()); c.Status == StatusFail {
+		t.Errorf("Disk space check failed: %+v", c)
+	}
+	if c := NetworkCheck(); c.Status != StatusOK {
+		t.Logf("Network check not ok in this environment: %+v", c)
+	}
+	if c := SystemCheck("v0.0.0"); !strings.Contains(c.Message, runtime.GOOS) {
+		t.Errorf("System check should mention OS, got %q", c.Message)
+	}
+}
@@ -0,0 +1,14 @@
+//go:build !windows
+
+package diag
+
+import "golang.org/x/sys/unix"
+
+// freeDiskBytes 返回 dir 所在文件系统对当前用户可用的字节数
+func freeDiskBytes(dir string) (uint64, error) {
+	var st unix.Statfs_t
+	if err := unix.Statfs(dir, &st); err != nil {
+		return 0, err
+	}
+	return uint64(st.Bavail) * uint64(st.Bsize), nil
+}
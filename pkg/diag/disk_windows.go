@@ -0,0 +1,18 @@
+//go:build windows
+
+package diag
+
+import "golang.org/x/sys/windows"
+
+// freeDiskBytes 返回 dir 所在磁盘对当前用户可用的字节数
+func freeDiskBytes(dir string) (uint64, error) {
+	path, err := windows.UTF16PtrFromString(dir)
+	if err != nil {
+		return 0, err
+	}
+	var free, total, totalFree uint64
+	if err := windows.GetDiskFreeSpaceEx(path, &free, &total, &totalFree); err != nil {
+		return 0, err
+	}
+	return free, nil
+}
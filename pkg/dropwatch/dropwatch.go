@@ -0,0 +1,325 @@
+// Package dropwatch 监视目录，把新出现或被修改的文件交给处理函数 (用于自动发送构建系统生成的指令文件)
+//
+// 通过 fsnotify 接收目录的变化通知：文件的大小与修改时间在最后一次变化后 Settle 时间内保持不变，
+// 才视为写入完成，避免读到写了一半的文件。同一时刻就绪的多个文件按名称顺序逐个处理，处理期间暂停接收通知
+// (通知在 fsnotify 中排队)，因此处理函数不会并发执行。
+package dropwatch
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// 默认参数
+const (
+	DefaultSettle = time.Second
+	// SentDir MoveSent 开启时已处理的文件移动到的子目录
+	SentDir = "sent"
+)
+
+// Config 监视参数
+type Config struct {
+	Dir      string
+	Pattern  string        // 按 filepath.Match 匹配文件名，为空时匹配全部文件
+	Settle   time.Duration // 文件保持不变多久后视为写入完成，<= 0 时使用 DefaultSettle
+	MoveSent bool          // 处理成功后移动到 Dir/sent
+}
+
+// Handler 处理一个文件，stop 在监视停止时关闭，长时间的处理应据此提前返回
+type Handler func(path string, data []byte, stop <-chan struct{}) error
+
+// Result 一个文件的处理结果
+type Result struct {
+	Path    string `json:"path"`
+	Bytes   int    `json:"bytes"`
+	Error   string `json:"error,omitempty"`
+	MovedTo string `json:"movedTo,omitempty"`
+}
+
+// signature 文件内容是否变化的依据
+type signature struct {
+	size int64
+	mod  time.Time
+}
+
+type fileState struct {
+	sig       signature
+	since     time.Time // sig 最近一次变化 (或收到通知) 的时间
+	processed signature // 最近一次处理时的 sig
+	done      bool
+}
+
+// Watcher 运行中的目录监视
+type Watcher struct {
+	cfg     Config
+	handle  Handler
+	report  func(Result)
+	files   map[string]*fileState
+	now     func() time.Time
+	watcher *fsnotify.Watcher
+
+	stop     chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// Start 开始监视 cfg.Dir。启动时已存在的文件视为已处理，之后被修改时才会处理
+// report 在每个文件处理后调用，可为 nil
+func Start(cfg Config, handle Handler, report func(Result)) (*Watcher, error) {
+	if cfg.Pattern != "" {
+		if _, err := filepath.Match(cfg.Pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", cfg.Pattern, err)
+		}
+	}
+	info, err := os.Stat(cfg.Dir)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", cfg.Dir)
+	}
+	if cfg.Settle <= 0 {
+		cfg.Settle = DefaultSettle
+	}
+
+	fw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create watcher: %w", err)
+	}
+	if err := fw.Add(cfg.Dir); err != nil {
+		fw.Close()
+		return nil, fmt.Errorf("failed to watch %s: %w", cfg.Dir, err)
+	}
+
+	w := &Watcher{
+		cfg:     cfg,
+		handle:  handle,
+		report:  report,
+		files:   make(map[string]*fileState),
+		now:     time.Now,
+		watcher: fw,
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	// 在开始监视之后列出已有文件，期间写入的文件同时会收到通知，不会遗漏
+	for name, sig := range w.scan() {
+		w.files[name] = &fileState{sig: sig, processed: sig, done: true}
+	}
+	go w.run()
+	return w, nil
+}
+
+// Stop 停止监视并等待正在处理的文件结束
+func (w *Watcher) Stop() {
+	w.stopOnce.Do(func() { close(w.stop) })
+	<-w.done
+}
+
+// Config 返回监视参数 (已填入默认值)
+func (w *Watcher) Config() Config {
+	return w.cfg
+}
+
+func (w *Watcher) run() {
+	defer close(w.done)
+	defer w.watcher.Close()
+
+	// settle 在最早的待处理文件写入完成时触发，没有待处理文件时停止
+	settle := time.NewTimer(time.Hour)
+	settle.Stop()
+	defer settle.Stop()
+	for {
+		select {
+		case <-w.stop:
+			return
+		case ev, ok := <-w.watcher.Events:
+			if !ok {
+				return
+			}
+			w.changed(filepath.Base(ev.Name))
+		case _, ok := <-w.watcher.Errors:
+			// 通知队列溢出等错误：重新扫描整个目录，补上可能丢失的变化
+			if !ok {
+				return
+			}
+			for name := range w.scan() {
+				w.changed(name)
+			}
+		case <-settle.C:
+			for _, name := range w.ready() {
+				select {
+				case <-w.stop:
+					return
+				default:
+				}
+				w.process(name)
+			}
+		}
+		settle.Stop()
+		if next, ok := w.nextSettle(); ok {
+			settle.Reset(next)
+		}
+	}
+}
+
+// changed 收到 name 的变化通知：更新文件状态，删除或不再匹配的文件不再跟踪
+func (w *Watcher) changed(name string) {
+	if !w.matches(name) {
+		return
+	}
+	info, err := os.Lstat(filepath.Join(w.cfg.Dir, name))
+	if err != nil || !info.Mode().IsRegular() {
+		delete(w.files, name)
+		return
+	}
+	sig := signature{size: info.Size(), mod: info.ModTime()}
+	now := w.now()
+	st, ok := w.files[name]
+	if !ok {
+		w.files[name] = &fileState{sig: sig, since: now}
+		return
+	}
+	// 大小与修改时间相同的写入 (修改时间精度较低时) 同样推迟就绪时间
+	st.sig = sig
+	st.since = now
+	if !st.done || sig != st.processed {
+		st.done = false
+	}
+}
+
+// nextSettle 返回距最早的待处理文件写入完成的时间
+func (w *Watcher) nextSettle() (time.Duration, bool) {
+	var first time.Time
+	for _, st := range w.files {
+		if st.done {
+			continue
+		}
+		if first.IsZero() || st.since.Before(first) {
+			first = st.since
+		}
+	}
+	if first.IsZero() {
+		return 0, false
+	}
+	d := first.Add(w.cfg.Settle).Sub(w.now())
+	if d < 0 {
+		d = 0
+	}
+	return d, true
+}
+
+// matches 文件名是否匹配 Pattern
+func (w *Watcher) matches(name string) bool {
+	if w.cfg.Pattern == "" {
+		return true
+	}
+	ok, _ := filepath.Match(w.cfg.Pattern, name)
+	return ok
+}
+
+// scan 列出匹配的普通文件
+func (w *Watcher) scan() map[string]signature {
+	entries, err := os.ReadDir(w.cfg.Dir)
+	if err != nil {
+		return nil
+	}
+	out := make(map[string]signature, len(entries))
+	for _, e := range entries {
+		if !e.Type().IsRegular() || !w.matches(e.Name()) {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		out[e.Name()] = signature{size: info.Size(), mod: info.ModTime()}
+	}
+	return out
+}
+
+// ready 重新检查待处理的文件，返回已就绪 (写入完成且内容与上次处理时不同) 的文件名
+// 通知之后文件仍在变化 (大小或修改时间与记录不同) 时推迟就绪时间
+func (w *Watcher) ready() []string {
+	now := w.now()
+	var out []string
+	for name, st := range w.files {
+		if st.done {
+			continue
+		}
+		info, err := os.Lstat(filepath.Join(w.cfg.Dir, name))
+		if err != nil || !info.Mode().IsRegular() {
+			delete(w.files, name)
+			continue
+		}
+		if sig := (signature{size: info.Size(), mod: info.ModTime()}); sig != st.sig {
+			st.sig = sig
+			st.since = now
+			continue
+		}
+		if st.sig == st.processed {
+			st.done = true
+			continue
+		}
+		if now.Sub(st.since) >= w.cfg.Settle {
+			out = append(out, name)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+func (w *Watcher) process(name string) {
+	st := w.files[name]
+	path := filepath.Join(w.cfg.Dir, name)
+	res := Result{Path: path}
+
+	data, err := os.ReadFile(path)
+	if err == nil {
+		res.Bytes = len(data)
+		err = w.handle(path, data, w.stop)
+	}
+	st.processed = st.sig
+	st.done = true
+
+	if err == nil && w.cfg.MoveSent {
+		var moved string
+		if moved, err = moveSent(w.cfg.Dir, name); err == nil {
+			res.MovedTo = moved
+			delete(w.files, name)
+		}
+	}
+	if err != nil {
+		res.Error = err.Error()
+	}
+	if w.report != nil {
+		w.report(res)
+	}
+}
+
+// moveSent 把文件移动到 dir/sent，已有同名文件时在名称后追加序号
+func moveSent(dir, name string) (string, error) {
+	sent := filepath.Join(dir, SentDir)
+	if err := os.MkdirAll(sent, 0755); err != nil {
+		return "", err
+	}
+	ext := filepath.Ext(name)
+	base := name[:len(name)-len(ext)]
+	target := filepath.Join(sent, name)
+	for n := 1; ; n++ {
+		if _, err := os.Lstat(target); os.IsNotExist(err) {
+			break
+		}
+		target = filepath.Join(sent, base+"_"+strconv.Itoa(n)+ext)
+	}
+	if err := os.Rename(filepath.Join(dir, name), target); err != nil {
+		return "", fmt.Errorf("failed to move to %s: %w", SentDir, err)
+	}
+	return target, nil
+}
@@ -0,0 +1,192 @@
+package dropwatch
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type recorder struct {
+	mu      sync.Mutex
+	files   map[string]string
+	active  atomic.Int32
+	overlap atomic.Int32
+	results chan Result
+}
+
+func newRecorder() *recorder {
+	return &recorder{files: map[string]string{}, results: make(chan Result, 16)}
+}
+
+func (r *recorder) handle(path string, data []byte, _ <-chan struct{}) error {
+	if r.active.Add(1) > 1 {
+		r.overlap.Add(1)
+	}
+	defer r.active.Add(-1)
+	time.Sleep(5 * time.Millisecond)
+	r.mu.Lock()
+	r.files[filepath.Base(path)] = string(data)
+	r.mu.Unlock()
+	return nil
+}
+
+func (r *recorder) next(t *testing.T) Result {
+	t.Helper()
+	select {
+	case res := <-r.results:
+		return res
+	case <-time.After(3 * time.Second):
+		t.Fatal("timed out waiting for a file to be processed")
+		return Result{}
+	}
+}
+
+func start(t *testing.T, cfg Config, r *recorder) *Watcher {
+	t.Helper()
+	if cfg.Settle == 0 {
+		cfg.Settle = 30 * time.Millisecond
+	}
+	w, err := Start(cfg, r.handle, func(res Result) { r.results <- res })
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(w.Stop)
+	return w
+}
+
+func TestWaitsForPartialWrites(t *testing.T) {
+	dir := t.TempDir()
+	r := newRecorder()
+	start(t, Config{Dir: dir, Pattern: "*.txt", Settle: 80 * time.Millisecond}, r)
+
+	f, _ := os.Create(filepath.Join(dir, "commands.txt"))
+	for _, part := range []string{"AT\n", "AT+GMR\n", "AT+RST\n"} {
+		f.WriteString(part)
+		f.Sync()
+		time.Sleep(30 * time.Millisecond)
+	}
+	f.Close()
+	os.WriteFile(filepath.Join(dir, "ignored.bin"), []byte("x"), 0644)
+
+	res := r.next(t)
+	if res.Error != "" || res.Bytes != 17 {
+		t.Fatalf("result = %+v", res)
+	}
+	if got := r.files["commands.txt"]; got != "AT\nAT+GMR\nAT+RST\n" {
+		t.Errorf("processed %q", got)
+	}
+	select {
+	case res := <-r.results:
+		t.Errorf("unexpected second result %+v", res)
+	case <-time.After(150 * time.Millisecond):
+	}
+}
+
+func TestQueuesConcurrentArrivalsAndReprocessesChanges(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "old.txt"), []byte("before start"), 0644)
+	r := newRecorder()
+	start(t, Config{Dir: dir}, r)
+
+	for _, name := range []string{"c.txt", "a.txt", "b.txt"} {
+		os.WriteFile(filepath.Join(dir, name), []byte(name), 0644)
+	}
+	var order []string
+	for i := 0; i < 3; i++ {
+		order = append(order, filepath.Base(r.next(t).Path))
+	}
+	if order[0] != "a.txt" || order[1] != "b.txt" || order[2] != "c.txt" {
+		t.Errorf("order = %v", order)
+	}
+	if r.overlap.Load() != 0 {
+		t.Error("handler ran concurrently")
+	}
+	if _, ok := r.files["old.txt"]; ok {
+		t.Error("file present at start was processed")
+	}
+
+	os.WriteFile(filepath.Join(dir, "b.txt"), []byte("changed"), 0644)
+	if res := r.next(t); filepath.Base(res.Path) != "b.txt" || r.files["b.txt"] != "changed" {
+		t.Errorf("change not reprocessed: %+v", res)
+	}
+}
+
+func TestMoveSent(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, SentDir), 0755)
+	os.WriteFile(filepath.Join(dir, SentDir, "job.txt"), []byte("previous"), 0644)
+	r := newRecorder()
+	start(t, Config{Dir: dir, MoveSent: true}, r)
+
+	os.WriteFile(filepath.Join(dir, "job.txt"), []byte("new"), 0644)
+	res := r.next(t)
+	if want := filepath.Join(dir, SentDir, "job_1.txt"); res.MovedTo != want {
+		t.Fatalf("moved to %q, want %q", res.MovedTo, want)
+	}
+	if data, _ := os.ReadFile(res.MovedTo); string(data) != "new" {
+		t.Errorf("moved file = %q", data)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "job.txt")); !os.IsNotExist(err) {
+		t.Error("file still in the watched directory")
+	}
+}
+
+func TestHandlerErrorIsReported(t *testing.T) {
+	dir := t.TempDir()
+	results := make(chan Result, 1)
+	w, err := Start(Config{Dir: dir, Settle: 10 * time.Millisecond, MoveSent: true},
+		func(string, []byte, <-chan struct{}) error { return os.ErrDeadlineExceeded },
+		func(res Result) { results <- res })
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Stop()
+	os.WriteFile(filepath.Join(dir, "x.txt"), []byte("x"), 0644)
+	select {
+	case res := <-results:
+		if res.Error == "" || res.MovedTo != "" {
+			t.Errorf("result = %+v", res)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("no result")
+	}
+}
+
+func TestStopCancelsHandler(t *testing.T) {
+	dir := t.TempDir()
+	entered := make(chan struct{})
+	w, err := Start(Config{Dir: dir, Settle: 10 * time.Millisecond},
+		func(_ string, _ []byte, stop <-chan struct{}) error {
+			close(entered)
+			<-stop
+			return nil
+		}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(filepath.Join(dir, "x.txt"), []byte("x"), 0644)
+	<-entered
+	stopped := make(chan struct{})
+	go func() {
+		w.Stop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("Stop did not return")
+	}
+	w.Stop()
+}
+
+func TestStartErrors(t *testing.T) {
+	if _, err := Start(Config{Dir: filepath.Join(t.TempDir(), "missing")}, nil, nil); err == nil {
+		t.Error("expected an error for a missing directory")
+	}
+	if _, err := Start(Config{Dir: t.TempDir(), Pattern: "["}, nil, nil); err == nil {
+		t.Error("expected an error for an invalid pattern")
+	}
+}
@@ -0,0 +1,154 @@
+package events
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// 默认限流参数
+const (
+	DefaultDedupWindow = time.Second
+	DefaultMaxPerSec   = 10
+)
+
+// Limiter 对消息类事件 (sys-msg、serial-error) 去重并限流，防止事件风暴
+//
+//   - 同一事件名下相同的消息在 Window 内只发送一次，窗口结束时再发送一条
+//     "<消息> (repeated N times)" 汇总重复次数
+//   - 每个事件名每秒最多发送 MaxPerSec 条，超出的丢弃，该秒结束后发送
+//     "N messages suppressed" 通知
+//
+// 待发送的汇总由 Flush 输出；Limiter 会在有待处理项时通过 afterFunc 自动安排 Flush
+type Limiter struct {
+	Window    time.Duration
+	MaxPerSec int
+
+	out       func(name, msg string)
+	now       func() time.Time
+	afterFunc func(d time.Duration, f func())
+
+	mu        sync.Mutex
+	repeats   map[repeatKey]*repeat
+	rates     map[string]*rate
+	scheduled bool
+}
+
+type repeatKey struct {
+	name, msg string
+}
+
+type repeat struct {
+	first time.Time
+	count int // 首条之后被合并的次数
+}
+
+type rate struct {
+	start      time.Time
+	sent       int
+	suppressed int
+}
+
+// NewLimiter 创建限流器，out 为实际发送消息的函数
+func NewLimiter(out func(name, msg string)) *Limiter {
+	return &Limiter{
+		Window:    DefaultDedupWindow,
+		MaxPerSec: DefaultMaxPerSec,
+		out:       out,
+		now:       time.Now,
+		afterFunc: func(d time.Duration, f func()) { time.AfterFunc(d, f) },
+		repeats:   make(map[repeatKey]*repeat),
+		rates:     make(map[string]*rate),
+	}
+}
+
+// Emit 发送一条消息，可能被合并或丢弃
+func (l *Limiter) Emit(name, msg string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.flushLocked(now)
+
+	key := repeatKey{name, msg}
+	if r, ok := l.repeats[key]; ok {
+		r.count++
+		l.scheduleLocked()
+		return
+	}
+	if l.deliverLocked(now, name, msg) {
+		l.repeats[key] = &repeat{first: now}
+		l.scheduleLocked()
+	}
+}
+
+// Flush 输出已到期的重复汇总与丢弃通知
+func (l *Limiter) Flush() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.scheduled = false
+	l.flushLocked(l.now())
+	l.scheduleLocked()
+}
+
+// deliverLocked 在速率上限内发送消息，返回是否已发送
+func (l *Limiter) deliverLocked(now time.Time, name, msg string) bool {
+	r := l.rates[name]
+	if r == nil {
+		r = &rate{start: now}
+		l.rates[name] = r
+	}
+	if now.Sub(r.start) >= time.Second {
+		l.noticeLocked(name, r)
+		r.start, r.sent = now, 0
+	}
+	if l.MaxPerSec > 0 && r.sent >= l.MaxPerSec {
+		r.suppressed++
+		return false
+	}
+	r.sent++
+	l.out(name, msg)
+	return true
+}
+
+// noticeLocked 发送丢弃通知 (不受速率限制)
+func (l *Limiter) noticeLocked(name string, r *rate) {
+	if r.suppressed > 0 {
+		l.out(name, fmt.Sprintf("%d messages suppressed", r.suppressed))
+		r.suppressed = 0
+	}
+}
+
+func (l *Limiter) flushLocked(now time.Time) {
+	for key, r := range l.repeats {
+		if now.Sub(r.first) < l.Window {
+			continue
+		}
+		delete(l.repeats, key)
+		if r.count > 0 {
+			l.deliverLocked(now, key.name, fmt.Sprintf("%s (repeated %d times)", key.msg, r.count))
+		}
+	}
+	for name, r := range l.rates {
+		if now.Sub(r.start) < time.Second {
+			continue
+		}
+		l.noticeLocked(name, r)
+		delete(l.rates, name)
+	}
+}
+
+// scheduleLocked 有待处理项时安排一次 Flush
+func (l *Limiter) scheduleLocked() {
+	if l.scheduled || (len(l.repeats) == 0 && len(l.rates) == 0) {
+		return
+	}
+	l.scheduled = true
+
+	d := l.Window
+	if d <= 0 || d > time.Second {
+		d = time.Second
+	}
+	l.afterFunc(d, l.Flush)
+}
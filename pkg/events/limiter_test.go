@@ -0,0 +1,104 @@
+package events
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// newTestLimiter 使用可控时钟的限流器，Flush 由测试手动触发
+func newTestLimiter() (*Limiter, *time.Time, *[]string) {
+	var out []string
+	clock := time.Unix(1700000000, 0)
+	l := NewLimiter(func(name, msg string) { out = append(out, name+"|"+msg) })
+	l.now = func() time.Time { return clock }
+	l.afterFunc = func(time.Duration, func()) {}
+	return l, &clock, &out
+}
+
+func TestLimiterCollapsesDuplicates(t *testing.T) {
+	l, clock, out := newTestLimiter()
+
+	for i := 0; i < 5; i++ {
+		l.Emit("serial-error", "connection refused")
+		*clock = clock.Add(100 * time.Millisecond)
+	}
+	l.Emit("serial-error", "timeout")
+	l.Emit("sys-msg", "connection refused")
+
+	expected := []string{"serial-error|connection refused", "serial-error|timeout", "sys-msg|connection refused"}
+	if !reflect.DeepEqual(*out, expected) {
+		t.Fatalf("Before window end: got %q, expected %q", *out, expected)
+	}
+
+	*clock = clock.Add(DefaultDedupWindow)
+	l.Flush()
+	expected = append(expected, "serial-error|connection refused (repeated 4 times)")
+	if !reflect.DeepEqual(*out, expected) {
+		t.Fatalf("After window end: got %q, expected %q", *out, expected)
+	}
+
+	// 窗口结束后相同消息重新发送
+	l.Emit("serial-error", "connection refused")
+	if last := (*out)[len(*out)-1]; last != "serial-error|connection refused" {
+		t.Errorf("Expected message to be re-sent after window, got %q", last)
+	}
+}
+
+func TestLimiterCapsRate(t *testing.T) {
+	l, clock, out := newTestLimiter()
+
+	for i := 0; i < 25; i++ {
+		l.Emit("sys-msg", fmt.Sprintf("message %d", i))
+	}
+	if len(*out) != DefaultMaxPerSec {
+		t.Fatalf("Expected %d messages within the first second, got %d", DefaultMaxPerSec, len(*out))
+	}
+
+	// 其他事件名不受影响
+	l.Emit("serial-error", "boom")
+	if len(*out) != DefaultMaxPerSec+1 {
+		t.Errorf("Expected serial-error to have its own budget")
+	}
+
+	*clock = clock.Add(time.Second)
+	l.Flush()
+	if last := (*out)[len(*out)-1]; last != "sys-msg|15 messages suppressed" {
+		t.Errorf("Expected suppression notice, got %q", last)
+	}
+
+	// 新的一秒重新计数
+	before := len(*out)
+	l.Emit("sys-msg", "fresh")
+	if len(*out) != before+1 {
+		t.Error("Expected message to pass in the next second")
+	}
+}
+
+func TestLimiterNoticeOnNextEmit(t *testing.T) {
+	l, clock, out := newTestLimiter()
+	l.MaxPerSec = 1
+
+	l.Emit("sys-msg", "a")
+	l.Emit("sys-msg", "b")
+	*clock = clock.Add(1500 * time.Millisecond)
+	l.Emit("sys-msg", "c")
+
+	expected := []string{"sys-msg|a", "sys-msg|1 messages suppressed", "sys-msg|c"}
+	if !reflect.DeepEqual(*out, expected) {
+		t.Errorf("Got %q, expected %q", *out, expected)
+	}
+}
+
+func TestLimiterSchedulesFlush(t *testing.T) {
+	l, _, _ := newTestLimiter()
+	scheduled := 0
+	l.afterFunc = func(d time.Duration, f func()) { scheduled++ }
+
+	l.Emit("sys-msg", "x")
+	l.Emit("sys-msg", "x")
+	if scheduled != 1 {
+		t.Errorf("Expected a single pending flush, got %d", scheduled)
+	}
+}
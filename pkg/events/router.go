@@ -0,0 +1,199 @@
+// Package events 为连接相关的事件提供按通道路由
+//
+// 每个连接分配一个通道 ID，事件以 "<事件名>:<通道 ID>" 的名称发送，
+// 只有被至少一个窗口订阅的通道才会发送。为了兼容旧版前端，
+// 当恰好存在一个连接时，同时以原始事件名 (例如 "serial-data") 发送。
+//
+// 连接设置了标签或颜色 (SetIdentity) 后，该连接的每个事件在原有参数之后追加一个 Identity 参数。
+package events
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// EmitFunc 实际发送事件的函数，通常包装 runtime.EventsEmit
+type EmitFunc func(name string, data ...interface{})
+
+// ChannelEvent 返回通道事件名，例如 ChannelEvent("serial-data", "conn-1") = "serial-data:conn-1"
+func ChannelEvent(name, channel string) string {
+	return name + ":" + channel
+}
+
+// Identity 连接的显示标识，由前端设置，后端在事件与导出文件中携带
+type Identity struct {
+	Channel string `json:"channel"`
+	Label   string `json:"label,omitempty"`
+	Color   string `json:"color,omitempty"` // "#RGB" 或 "#RRGGBB"
+}
+
+// MaxLabelLen 标签的最大字符数
+const MaxLabelLen = 64
+
+var colorPattern = regexp.MustCompile(`^#(?:[0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+
+// Router 按订阅关系分发连接事件，线程安全
+type Router struct {
+	emit EmitFunc
+
+	mu          sync.Mutex
+	nextConn    int
+	nextWindow  int
+	connections map[string]struct{}
+	identities  map[string]Identity
+	windows     map[string]map[string]struct{} // 窗口 ID -> 订阅的通道
+}
+
+// NewRouter 创建路由器
+func NewRouter(emit EmitFunc) *Router {
+	return &Router{
+		emit:        emit,
+		connections: make(map[string]struct{}),
+		identities:  make(map[string]Identity),
+		windows:     make(map[string]map[string]struct{}),
+	}
+}
+
+// NormalizeIdentity 校验并规范化标签与颜色：标签去掉首尾空白且不含控制字符，颜色为空或 "#RGB"/"#RRGGBB"
+func NormalizeIdentity(label, color string) (string, string, error) {
+	label = strings.TrimSpace(label)
+	if n := len([]rune(label)); n > MaxLabelLen {
+		return "", "", fmt.Errorf("label is %d characters long, maximum is %d", n, MaxLabelLen)
+	}
+	if strings.ContainsFunc(label, func(r rune) bool { return r < 0x20 || r == 0x7F }) {
+		return "", "", fmt.Errorf("label must not contain control characters")
+	}
+	if color != "" && !colorPattern.MatchString(color) {
+		return "", "", fmt.Errorf("invalid color %q (expected #RGB or #RRGGBB)", color)
+	}
+	return label, strings.ToLower(color), nil
+}
+
+// SetIdentity 设置连接的标签与颜色，均为空时清除；参数需先经过 NormalizeIdentity
+func (r *Router) SetIdentity(channel, label, color string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.connections[channel]; !ok {
+		return fmt.Errorf("unknown connection %q", channel)
+	}
+	if label == "" && color == "" {
+		delete(r.identities, channel)
+		return nil
+	}
+	r.identities[channel] = Identity{Channel: channel, Label: label, Color: color}
+	return nil
+}
+
+// Identity 返回连接的标识，未设置时只有 Channel
+func (r *Router) Identity(channel string) Identity {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if id, ok := r.identities[channel]; ok {
+		return id
+	}
+	return Identity{Channel: channel}
+}
+
+// AddConnection 为新连接分配通道 ID
+func (r *Router) AddConnection() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.nextConn++
+	id := fmt.Sprintf("conn-%d", r.nextConn)
+	r.connections[id] = struct{}{}
+	return id
+}
+
+// Has 判断通道是否属于一个存在的连接
+func (r *Router) Has(channel string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	_, ok := r.connections[channel]
+	return ok
+}
+
+// RemoveConnection 移除连接，之后该通道的事件不再发送
+func (r *Router) RemoveConnection(channel string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.connections, channel)
+	delete(r.identities, channel)
+}
+
+// RegisterWindow 注册一个窗口 (或前端实例)，返回窗口 ID
+func (r *Router) RegisterWindow() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.nextWindow++
+	id := fmt.Sprintf("win-%d", r.nextWindow)
+	r.windows[id] = make(map[string]struct{})
+	return id
+}
+
+// UnregisterWindow 注销窗口并取消其所有订阅
+func (r *Router) UnregisterWindow(window string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.windows, window)
+}
+
+// Subscribe 窗口订阅通道
+func (r *Router) Subscribe(window, channel string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	subs, ok := r.windows[window]
+	if !ok {
+		return fmt.Errorf("unknown window %q", window)
+	}
+	subs[channel] = struct{}{}
+	return nil
+}
+
+// Unsubscribe 窗口取消订阅通道
+func (r *Router) Unsubscribe(window, channel string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if subs, ok := r.windows[window]; ok {
+		delete(subs, channel)
+	}
+}
+
+// Emit 发送 channel 上的事件
+func (r *Router) Emit(channel, name string, data ...interface{}) {
+	r.mu.Lock()
+	_, live := r.connections[channel]
+	subscribed := live && r.subscribedLocked(channel)
+	legacy := live && len(r.connections) == 1
+	id, labeled := r.identities[channel]
+	r.mu.Unlock()
+
+	if labeled && (subscribed || legacy) {
+		data = append(data[:len(data):len(data)], id)
+	}
+	if subscribed {
+		r.emit(ChannelEvent(name, channel), data...)
+	}
+	if legacy {
+		r.emit(name, data...)
+	}
+}
+
+func (r *Router) subscribedLocked(channel string) bool {
+	for _, subs := range r.windows {
+		if _, ok := subs[channel]; ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,169 @@
+package events
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+)
+
+type fakeEmitter struct {
+	mu    sync.Mutex
+	names []string
+}
+
+func (f *fakeEmitter) emit(name string, data ...interface{}) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.names = append(f.names, name)
+}
+
+func (f *fakeEmitter) take() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	names := f.names
+	f.names = nil
+	return names
+}
+
+func TestLegacyEventsWithSingleConnection(t *testing.T) {
+	f := &fakeEmitter{}
+	r := NewRouter(f.emit)
+
+	conn := r.AddConnection()
+	r.Emit(conn, "serial-data", []byte("hi"))
+	if got := f.take(); !reflect.DeepEqual(got, []string{"serial-data"}) {
+		t.Errorf("Expected legacy event only, got %v", got)
+	}
+
+	win := r.RegisterWindow()
+	if err := r.Subscribe(win, conn); err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	r.Emit(conn, "serial-data", []byte("hi"))
+	expected := []string{"serial-data:" + conn, "serial-data"}
+	if got := f.take(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+}
+
+func TestRoutingWithMultipleConnections(t *testing.T) {
+	f := &fakeEmitter{}
+	r := NewRouter(f.emit)
+
+	c1 := r.AddConnection()
+	c2 := r.AddConnection()
+	w1 := r.RegisterWindow()
+	w2 := r.RegisterWindow()
+	r.Subscribe(w1, c1)
+	r.Subscribe(w2, c2)
+
+	r.Emit(c1, "serial-data")
+	r.Emit(c2, "serial-error")
+	expected := []string{"serial-data:" + c1, "serial-error:" + c2}
+	if got := f.take(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+
+	// 取消订阅后不再发送
+	r.Unsubscribe(w2, c2)
+	r.Emit(c2, "serial-data")
+	if got := f.take(); len(got) != 0 {
+		t.Errorf("Expected no events after unsubscribe, got %v", got)
+	}
+
+	// 注销窗口会移除其全部订阅
+	r.UnregisterWindow(w1)
+	r.Emit(c1, "serial-data")
+	if got := f.take(); len(got) != 0 {
+		t.Errorf("Expected no events after window unregistered, got %v", got)
+	}
+
+	// 回到单连接时恢复旧版事件
+	r.RemoveConnection(c2)
+	r.Emit(c1, "serial-data")
+	if got := f.take(); !reflect.DeepEqual(got, []string{"serial-data"}) {
+		t.Errorf("Expected legacy event after returning to one connection, got %v", got)
+	}
+}
+
+func TestEmitOnRemovedConnection(t *testing.T) {
+	f := &fakeEmitter{}
+	r := NewRouter(f.emit)
+
+	c := r.AddConnection()
+	w := r.RegisterWindow()
+	r.Subscribe(w, c)
+	if !r.Has(c) {
+		t.Fatalf("Has(%q) = false for a live connection", c)
+	}
+	r.RemoveConnection(c)
+	if r.Has(c) {
+		t.Errorf("Has(%q) = true after RemoveConnection", c)
+	}
+
+	r.Emit(c, "serial-data")
+	if got := f.take(); len(got) != 0 {
+		t.Errorf("Expected no events for removed connection, got %v", got)
+	}
+}
+
+func TestSubscribeUnknownWindow(t *testing.T) {
+	r := NewRouter(func(string, ...interface{}) {})
+	if err := r.Subscribe("win-99", "conn-1"); err == nil {
+		t.Error("Expected error for unknown window")
+	}
+}
+
+func TestIdentityAppendedToEvents(t *testing.T) {
+	var got [][]interface{}
+	r := NewRouter(func(name string, data ...interface{}) {
+		got = append(got, data)
+	})
+	c := r.AddConnection()
+
+	r.Emit(c, "serial-data", []byte("a"))
+	if len(got) != 1 || len(got[0]) != 1 {
+		t.Fatalf("Expected unlabeled event to keep its arguments, got %v", got)
+	}
+
+	label, color, err := NormalizeIdentity("  Board A ", "#FFAA00")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.SetIdentity(c, label, color); err != nil {
+		t.Fatal(err)
+	}
+	r.Emit(c, "serial-data", []byte("b"))
+	want := Identity{Channel: c, Label: "Board A", Color: "#ffaa00"}
+	if len(got) != 2 || len(got[1]) != 2 || got[1][1] != want {
+		t.Errorf("Expected identity appended, got %v", got[1:])
+	}
+	if r.Identity(c) != want {
+		t.Errorf("Unexpected identity %+v", r.Identity(c))
+	}
+
+	r.SetIdentity(c, "", "")
+	if r.Identity(c) != (Identity{Channel: c}) {
+		t.Error("Expected identity to be cleared")
+	}
+	if err := r.SetIdentity("conn-99", "x", ""); err == nil {
+		t.Error("Expected error for unknown connection")
+	}
+}
+
+func TestNormalizeIdentity(t *testing.T) {
+	invalid := []struct{ label, color string }{
+		{"bad\nlabel", ""},
+		{string(make([]rune, MaxLabelLen+1)), ""},
+		{"ok", "red"},
+		{"ok", "#12345"},
+	}
+	for _, tt := range invalid {
+		if _, _, err := NormalizeIdentity(tt.label, tt.color); err == nil {
+			t.Errorf("NormalizeIdentity(%q, %q): expected error", tt.label, tt.color)
+		}
+	}
+	if _, color, err := NormalizeIdentity("", "#abc"); err != nil || color != "#abc" {
+		t.Errorf("Unexpected result %q, %v", color, err)
+	}
+}
@@ -0,0 +1,108 @@
+package format
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DumpOptions 十六进制转储 (hexdump -C 风格) 选项
+type DumpOptions struct {
+	BytesPerRow int  `json:"bytesPerRow"` // 每行字节数，8 或 16
+	ASCII       bool `json:"ascii"`       // 行尾附加 |ASCII| 栏
+	Uppercase   bool `json:"uppercase"`
+	Continuous  bool `json:"continuous"` // 偏移在整个连接期间连续累加，否则每个数据块从 0 开始
+}
+
+// Validate 检查选项是否有效
+func (o DumpOptions) Validate() error {
+	if o.BytesPerRow != 8 && o.BytesPerRow != 16 {
+		return fmt.Errorf("bytes per row must be 8 or 16, got %d", o.BytesPerRow)
+	}
+	return nil
+}
+
+// Dumper 按 DumpOptions 生成多行十六进制转储，连续偏移模式下记录已转储的字节数
+// 非线程安全，由调用方加锁
+type Dumper struct {
+	opts   DumpOptions
+	offset uint64
+}
+
+// NewDumper 创建转储器，opts 需先通过 Validate
+func NewDumper(opts DumpOptions) *Dumper {
+	return &Dumper{opts: opts}
+}
+
+// Options 返回当前选项
+func (d *Dumper) Options() DumpOptions {
+	return d.opts
+}
+
+// Reset 将连续偏移归零 (新连接)
+func (d *Dumper) Reset() {
+	d.offset = 0
+}
+
+// Dump 转储 data，每个数据块从新的一行开始，行首为该行第一个字节的偏移
+// 结果超过 MaxEventPayload 时截断，truncated 为 true；连续偏移始终按完整长度累加
+func (d *Dumper) Dump(data []byte) (s string, truncated bool) {
+	base := uint64(0)
+	if d.opts.Continuous {
+		base = d.offset
+		d.offset += uint64(len(data))
+	}
+
+	digits := hexLower
+	offsetFmt := "%08x  "
+	if d.opts.Uppercase {
+		digits = hexUpper
+		offsetFmt = "%08X  "
+	}
+	perRow := d.opts.BytesPerRow
+
+	var b strings.Builder
+	for row := 0; row < len(data); row += perRow {
+		if b.Len() >= MaxEventPayload {
+			truncated = true
+			break
+		}
+		end := row + perRow
+		if end > len(data) {
+			end = len(data)
+		}
+
+		fmt.Fprintf(&b, offsetFmt, base+uint64(row))
+		for i := 0; i < perRow; i++ {
+			if i == 8 && (row+i < end || d.opts.ASCII) {
+				b.WriteByte(' ')
+			}
+			if row+i < end {
+				c := data[row+i]
+				b.WriteByte(digits[c>>4])
+				b.WriteByte(digits[c&0x0F])
+				b.WriteByte(' ')
+			} else if d.opts.ASCII {
+				// 补齐短行使 ASCII 栏对齐
+				b.WriteString("   ")
+			}
+		}
+
+		if d.opts.ASCII {
+			b.WriteString(" |")
+			for _, c := range data[row:end] {
+				if c < 0x20 || c > 0x7E {
+					c = '.'
+				}
+				b.WriteByte(c)
+			}
+			b.WriteByte('|')
+		}
+		b.WriteByte('\n')
+	}
+
+	s = b.String()
+	if len(s) > MaxEventPayload {
+		s, truncated = s[:MaxEventPayload], true
+	}
+	return s, truncated
+}
@@ -0,0 +1,86 @@
+package format
+
+import "testing"
+
+// TestDumpSnapshots 锁定各选项组合下的输出格式
+func TestDumpSnapshots(t *testing.T) {
+	data := []byte("Hello, serial!\r\n\x00\x7f\xff\xab")
+
+	tests := []struct {
+		name     string
+		opts     DumpOptions
+		expected string
+	}{
+		{
+			"16 per row with ASCII",
+			DumpOptions{BytesPerRow: 16, ASCII: true, Uppercase: true},
+			"00000000  48 65 6C 6C 6F 2C 20 73  65 72 69 61 6C 21 0D 0A  |Hello, serial!..|\n" +
+				"00000010  00 7F FF AB                                       |....|\n",
+		},
+		{
+			"16 per row lowercase without ASCII",
+			DumpOptions{BytesPerRow: 16},
+			"00000000  48 65 6c 6c 6f 2c 20 73  65 72 69 61 6c 21 0d 0a \n" +
+				"00000010  00 7f ff ab \n",
+		},
+		{
+			"8 per row with ASCII",
+			DumpOptions{BytesPerRow: 8, ASCII: true, Uppercase: true},
+			"00000000  48 65 6C 6C 6F 2C 20 73  |Hello, s|\n" +
+				"00000008  65 72 69 61 6C 21 0D 0A  |erial!..|\n" +
+				"00000010  00 7F FF AB              |....|\n",
+		},
+		{
+			"8 per row lowercase",
+			DumpOptions{BytesPerRow: 8},
+			"00000000  48 65 6c 6c 6f 2c 20 73 \n" +
+				"00000008  65 72 69 61 6c 21 0d 0a \n" +
+				"00000010  00 7f ff ab \n",
+		},
+	}
+	for _, tt := range tests {
+		got, truncated := NewDumper(tt.opts).Dump(data)
+		if got != tt.expected || truncated {
+			t.Errorf("%s:\ngot\n%q\nexpected\n%q", tt.name, got, tt.expected)
+		}
+	}
+}
+
+func TestDumpContinuousOffsets(t *testing.T) {
+	d := NewDumper(DumpOptions{BytesPerRow: 8, Uppercase: true, Continuous: true})
+
+	first, _ := d.Dump([]byte("0123456789"))
+	second, _ := d.Dump([]byte("abc"))
+	if first != "00000000  30 31 32 33 34 35 36 37 \n00000008  38 39 \n" {
+		t.Errorf("Unexpected first chunk %q", first)
+	}
+	if second != "0000000A  61 62 63 \n" {
+		t.Errorf("Expected second chunk to continue at 0x0A, got %q", second)
+	}
+
+	d.Reset()
+	if got, _ := d.Dump([]byte("x")); got != "00000000  78 \n" {
+		t.Errorf("Expected offset reset, got %q", got)
+	}
+
+	// 非连续模式每个数据块从 0 开始
+	d = NewDumper(DumpOptions{BytesPerRow: 8})
+	d.Dump([]byte("0123456789"))
+	if got, _ := d.Dump([]byte("a")); got != "00000000  61 \n" {
+		t.Errorf("Expected per-chunk offsets, got %q", got)
+	}
+}
+
+func TestDumpValidateAndTruncate(t *testing.T) {
+	if err := (DumpOptions{BytesPerRow: 12}).Validate(); err == nil {
+		t.Error("Expected error for 12 bytes per row")
+	}
+	if err := (DumpOptions{BytesPerRow: 8}).Validate(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	got, truncated := NewDumper(DumpOptions{BytesPerRow: 16, ASCII: true}).Dump(make([]byte, MaxEventPayload))
+	if !truncated || len(got) > MaxEventPayload {
+		t.Errorf("Expected truncation, got %d bytes (truncated=%v)", len(got), truncated)
+	}
+}
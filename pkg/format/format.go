@@ -0,0 +1,101 @@
+// Package format 将接收到的字节预先格式化为十六进制与可打印文本，
+// 避免前端在大量数据到达时在 UI 线程上做转换。该代码在每个数据块上运行，需保持高效
+package format
+
+import (
+	"strings"
+	"time"
+)
+
+// MaxEventPayload 单个事件中格式化字符串的最大长度（字符数），超出部分被截断
+const MaxEventPayload = 256 * 1024
+
+// Options 十六进制格式选项
+type Options struct {
+	GroupSize int  `json:"groupSize"` // 每组字节数，组之间以空格分隔；<= 0 表示不分组
+	Uppercase bool `json:"uppercase"`
+}
+
+// DefaultOptions 默认格式：每字节一组，大写（例如 "0A FF 10"）
+var DefaultOptions = Options{GroupSize: 1, Uppercase: true}
+
+const (
+	hexUpper = "0123456789ABCDEF"
+	hexLower = "0123456789abcdef"
+)
+
+// Hex 将 data 格式化为十六进制字符串
+// 结果超过 MaxEventPayload 时截断，truncated 为 true
+func Hex(data []byte, opts Options) (s string, truncated bool) {
+	if len(data) == 0 {
+		return "", false
+	}
+
+	digits := hexLower
+	if opts.Uppercase {
+		digits = hexUpper
+	}
+	group := opts.GroupSize
+
+	size := len(data) * 2
+	if group > 0 {
+		size += (len(data) - 1) / group
+	}
+	if size > MaxEventPayload {
+		size = MaxEventPayload
+		truncated = true
+	}
+
+	buf := make([]byte, 0, size)
+	for i, b := range data {
+		if group > 0 && i > 0 && i%group == 0 {
+			if len(buf)+3 > size {
+				break
+			}
+			buf = append(buf, ' ')
+		}
+		if len(buf)+2 > size {
+			break
+		}
+		buf = append(buf, digits[b>>4], digits[b&0x0F])
+	}
+	return string(buf), truncated
+}
+
+// Printable 将 data 转换为可打印的 ASCII 文本，不可打印字符替换为 '.'
+// 结果超过 MaxEventPayload 时截断，truncated 为 true
+func Printable(data []byte) (s string, truncated bool) {
+	n := len(data)
+	if n > MaxEventPayload {
+		n = MaxEventPayload
+		truncated = true
+	}
+
+	buf := make([]byte, n)
+	for i := 0; i < n; i++ {
+		b := data[i]
+		if b < 0x20 || b > 0x7E {
+			b = '.'
+		}
+		buf[i] = b
+	}
+	return string(buf), truncated
+}
+
+// AnnotationLine 标注在文本日志中的表示：以 "#" 开头的一行，包含时间戳
+// 多行标注的每一行都加上 "#" 前缀，保证不会与接收数据混淆
+func AnnotationLine(t time.Time, text string) string {
+	prefix := "# [" + t.Format("2006-01-02 15:04:05.000") + "] "
+	lines := strings.Split(strings.TrimRight(text, "\r\n"), "\n")
+	var b strings.Builder
+	for i, line := range lines {
+		if i == 0 {
+			b.WriteString(prefix)
+		} else {
+			b.WriteString("# ")
+		}
+		b.WriteString(strings.TrimRight(line, "\r"))
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
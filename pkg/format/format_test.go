@@ -0,0 +1,106 @@
+package format
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHex(t *testing.T) {
+	data := []byte{0x0A, 0xFF, 0x10, 0xab, 0x00}
+	tests := []struct {
+		opts     Options
+		expected string
+	}{
+		{Options{GroupSize: 1, Uppercase: true}, "0A FF 10 AB 00"},
+		{Options{GroupSize: 1, Uppercase: false}, "0a ff 10 ab 00"},
+		{Options{GroupSize: 2, Uppercase: true}, "0AFF 10AB 00"},
+		{Options{GroupSize: 0, Uppercase: true}, "0AFF10AB00"},
+		{Options{GroupSize: 8, Uppercase: true}, "0AFF10AB00"},
+	}
+	for _, tt := range tests {
+		got, truncated := Hex(data, tt.opts)
+		if got != tt.expected || truncated {
+			t.Errorf("Hex(%+v) = %q (truncated=%v), expected %q", tt.opts, got, truncated, tt.expected)
+		}
+	}
+
+	if got, _ := Hex(nil, DefaultOptions); got != "" {
+		t.Errorf("Hex(nil) = %q, expected empty", got)
+	}
+}
+
+func TestHexTruncation(t *testing.T) {
+	data := make([]byte, MaxEventPayload)
+	got, truncated := Hex(data, DefaultOptions)
+	if !truncated {
+		t.Error("Expected truncation for oversized input")
+	}
+	if len(got) > MaxEventPayload {
+		t.Errorf("Formatted length %d exceeds limit %d", len(got), MaxEventPayload)
+	}
+	if strings.HasSuffix(got, " ") || len(got)%3 != 2 {
+		t.Errorf("Truncated output should end on a complete byte, length %d", len(got))
+	}
+}
+
+func TestPrintable(t *testing.T) {
+	got, truncated := Printable([]byte("Hi\r\n\x00\x7f\xffok~"))
+	if got != "Hi.....ok~" || truncated {
+		t.Errorf("Printable() = %q (truncated=%v)", got, truncated)
+	}
+
+	_, truncated = Printable(make([]byte, MaxEventPayload+1))
+	if !truncated {
+		t.Error("Expected truncation for oversized input")
+	}
+}
+
+func benchmarkChunk() []byte {
+	chunk := make([]byte, 4096)
+	for i := range chunk {
+		chunk[i] = byte(i)
+	}
+	return chunk
+}
+
+func BenchmarkHex4K(b *testing.B) {
+	chunk := benchmarkChunk()
+	b.SetBytes(int64(len(chunk)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		Hex(chunk, DefaultOptions)
+	}
+}
+
+func BenchmarkHexUngrouped4K(b *testing.B) {
+	chunk := benchmarkChunk()
+	b.SetBytes(int64(len(chunk)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		Hex(chunk, Options{Uppercase: true})
+	}
+}
+
+func BenchmarkPrintable4K(b *testing.B) {
+	chunk := benchmarkChunk()
+	b.SetBytes(int64(len(chunk)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		Printable(chunk)
+	}
+}
+
+func TestAnnotationLine(t *testing.T) {
+	ts := time.Date(2024, 5, 6, 7, 8, 9, 123e6, time.Local)
+
+	got := AnnotationLine(ts, "=== power cycled DUT ===")
+	if got != "# [2024-05-06 07:08:09.123] === power cycled DUT ===\n" {
+		t.Errorf("Unexpected annotation line: %q", got)
+	}
+
+	got = AnnotationLine(ts, "first\r\nsecond\n")
+	if got != "# [2024-05-06 07:08:09.123] first\n# second\n" {
+		t.Errorf("Multi-line annotation should prefix every line, got %q", got)
+	}
+}
@@ -0,0 +1,205 @@
+// Package frame 实现长度前缀帧的编码与解码
+//
+// 帧格式：[同步头 (可选)] [长度 (1 或 2 字节)] [负载] [校验值 (可选)]
+// 长度字段只计负载字节数；校验值覆盖负载，算法见 checksum 包
+package frame
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+
+	"serial-assistant/pkg/checksum"
+)
+
+// MaxSyncLen 同步头的最大长度
+const MaxSyncLen = 8
+
+// Config 帧格式
+type Config struct {
+	Sync       []byte             `json:"sync,omitempty"` // 帧起始标记，为空时不做同步
+	LenBytes   int                `json:"lenBytes"`       // 长度字段字节数：1 或 2
+	BigEndian  bool               `json:"bigEndian"`      // 2 字节长度字段的字节序
+	MaxPayload int                `json:"maxPayload"`     // 超过该长度视为失步，0 表示长度字段的最大值
+	Checksum   checksum.Algorithm `json:"checksum"`       // 为空时不校验
+}
+
+// Validate 检查配置
+func (c Config) Validate() error {
+	if c.LenBytes != 1 && c.LenBytes != 2 {
+		return fmt.Errorf("length field must be 1 or 2 bytes, got %d", c.LenBytes)
+	}
+	if len(c.Sync) > MaxSyncLen {
+		return fmt.Errorf("sync marker must not exceed %d bytes", MaxSyncLen)
+	}
+	if c.MaxPayload < 0 || c.MaxPayload > c.lenLimit() {
+		return fmt.Errorf("max payload must be between 0 and %d, got %d", c.lenLimit(), c.MaxPayload)
+	}
+	if _, err := checksum.Parse(string(c.Checksum)); err != nil {
+		return err
+	}
+	return nil
+}
+
+// lenLimit 长度字段能表示的最大值
+func (c Config) lenLimit() int {
+	if c.LenBytes == 1 {
+		return 0xFF
+	}
+	return 0xFFFF
+}
+
+// PayloadLimit 返回负载的最大长度：MaxPayload，为 0 时为长度字段能表示的最大值
+func (c Config) PayloadLimit() int {
+	return c.maxPayload()
+}
+
+func (c Config) maxPayload() int {
+	if c.MaxPayload > 0 {
+		return c.MaxPayload
+	}
+	return c.lenLimit()
+}
+
+// Encode 按帧格式封装 payload
+func (c Config) Encode(payload []byte) ([]byte, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	if len(payload) > c.maxPayload() {
+		return nil, fmt.Errorf("payload of %d bytes exceeds the %d byte limit", len(payload), c.maxPayload())
+	}
+	out := append([]byte(nil), c.Sync...)
+	switch {
+	case c.LenBytes == 1:
+		out = append(out, byte(len(payload)))
+	case c.BigEndian:
+		out = binary.BigEndian.AppendUint16(out, uint16(len(payload)))
+	default:
+		out = binary.LittleEndian.AppendUint16(out, uint16(len(payload)))
+	}
+	out = append(out, payload...)
+	return append(out, c.Checksum.Sum(payload)...), nil
+}
+
+// Frame 解码出的一帧
+type Frame struct {
+	Payload     []byte `json:"payload"`
+	BadChecksum bool   `json:"badChecksum,omitempty"`
+	Checksum    []byte `json:"checksum,omitempty"` // 帧中携带的校验值
+	Expected    []byte `json:"expected,omitempty"` // 按负载计算的校验值，仅校验失败时填写
+}
+
+// Stats 解码统计
+type Stats struct {
+	Frames      int64 `json:"frames"`      // 校验通过的帧
+	BadChecksum int64 `json:"badChecksum"` // 校验失败的帧
+	Resyncs     int64 `json:"resyncs"`     // 因长度超限或同步头不匹配而丢弃的字节数
+}
+
+// Decoder 长度前缀帧解码器，帧可以跨越多次 Feed，非线程安全
+type Decoder struct {
+	cfg   Config
+	buf   []byte
+	stats Stats
+}
+
+// NewDecoder 创建解码器
+func NewDecoder(cfg Config) (*Decoder, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return &Decoder{cfg: cfg}, nil
+}
+
+// Feed 输入一段数据，返回其中完整的帧 (包括校验失败的帧，由调用方决定是否丢弃)
+func (d *Decoder) Feed(data []byte) []Frame {
+	d.buf = append(d.buf, data...)
+	var frames []Frame
+	for {
+		f, ok := d.next()
+		if !ok {
+			break
+		}
+		frames = append(frames, f)
+	}
+	if len(d.buf) == 0 {
+		d.buf = nil
+	}
+	return frames
+}
+
+// next 从缓冲区取出一帧，数据不足时返回 false
+func (d *Decoder) next() (Frame, bool) {
+	sync := d.cfg.Sync
+	for {
+		if len(sync) > 0 {
+			i := bytes.Index(d.buf, sync)
+			if i < 0 {
+				// 保留可能是同步头前缀的末尾字节
+				keep := len(sync) - 1
+				if len(d.buf) > keep {
+					d.stats.Resyncs += int64(len(d.buf) - keep)
+					d.buf = d.buf[len(d.buf)-keep:]
+				}
+				return Frame{}, false
+			}
+			d.stats.Resyncs += int64(i)
+			d.buf = d.buf[i:]
+		}
+
+		head := len(sync) + d.cfg.LenBytes
+		if len(d.buf) < head {
+			return Frame{}, false
+		}
+		n := d.length(d.buf[len(sync):head])
+		if n > d.cfg.maxPayload() {
+			// 长度不可信：丢弃一个字节后重新寻找帧头
+			d.stats.Resyncs++
+			d.buf = d.buf[1:]
+			continue
+		}
+		size := d.cfg.Checksum.Size()
+		if len(d.buf) < head+n+size {
+			return Frame{}, false
+		}
+
+		payload := append([]byte(nil), d.buf[head:head+n]...)
+		f := Frame{Payload: payload}
+		if size > 0 {
+			f.Checksum = append([]byte(nil), d.buf[head+n:head+n+size]...)
+			if !d.cfg.Checksum.Verify(payload, f.Checksum) {
+				f.BadChecksum = true
+				f.Expected = d.cfg.Checksum.Sum(payload)
+			}
+		}
+		if f.BadChecksum {
+			d.stats.BadChecksum++
+		} else {
+			d.stats.Frames++
+		}
+		d.buf = d.buf[head+n+size:]
+		return f, true
+	}
+}
+
+func (d *Decoder) length(b []byte) int {
+	switch {
+	case len(b) == 1:
+		return int(b[0])
+	case d.cfg.BigEndian:
+		return int(binary.BigEndian.Uint16(b))
+	default:
+		return int(binary.LittleEndian.Uint16(b))
+	}
+}
+
+// Stats 返回解码统计
+func (d *Decoder) Stats() Stats {
+	return d.stats
+}
+
+// Pending 返回缓存中尚未组成完整帧的字节数
+func (d *Decoder) Pending() int {
+	return len(d.buf)
+}
@@ -0,0 +1,111 @@
+package frame
+
+import (
+	"bytes"
+	"testing"
+
+	"serial-assistant/pkg/checksum"
+)
+
+func TestEncodeDecodeRoundTrip(t *testing.T) {
+	configs := []Config{
+		{LenBytes: 1, Checksum: checksum.CRC8Maxim},
+		{LenBytes: 2, BigEndian: true, Checksum: checksum.Fletcher16},
+		{LenBytes: 2, Sync: []byte{0xAA, 0x55}, Checksum: checksum.CRC8SMBus},
+		{LenBytes: 1, Sync: []byte{0x7E}},
+	}
+	payloads := [][]byte{{0x01}, []byte("temperature=21.5"), {}, {0xAA, 0x55, 0x00}}
+	for _, cfg := range configs {
+		var stream []byte
+		for _, p := range payloads {
+			framed, err := cfg.Encode(p)
+			if err != nil {
+				t.Fatalf("%+v: Encode failed: %v", cfg, err)
+			}
+			stream = append(stream, framed...)
+		}
+		// 逐字节输入，验证跨 Feed 的缓存
+		d, err := NewDecoder(cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []Frame
+		for i := range stream {
+			got = append(got, d.Feed(stream[i:i+1])...)
+		}
+		if len(got) != len(payloads) {
+			t.Fatalf("%+v: decoded %d frames, want %d", cfg, len(got), len(payloads))
+		}
+		for i, f := range got {
+			if !bytes.Equal(f.Payload, payloads[i]) || f.BadChecksum {
+				t.Errorf("%+v: frame %d = %+v", cfg, i, f)
+			}
+		}
+		if d.Pending() != 0 || d.Stats().Frames != int64(len(payloads)) {
+			t.Errorf("%+v: pending %d, stats %+v", cfg, d.Pending(), d.Stats())
+		}
+	}
+}
+
+// DS18B20 风格的传感器帧：1 字节长度 + 负载 + CRC-8/MAXIM
+func TestDecodeBadChecksum(t *testing.T) {
+	d, _ := NewDecoder(Config{LenBytes: 1, Checksum: checksum.CRC8Maxim})
+	good := []byte{0x08, 0x50, 0x05, 0x4B, 0x46, 0x7F, 0xFF, 0x0C, 0x10, 0x1C}
+	bad := append([]byte(nil), good...)
+	bad[9] = 0x1D
+
+	frames := d.Feed(append(append([]byte(nil), bad...), good...))
+	if len(frames) != 2 {
+		t.Fatalf("decoded %d frames", len(frames))
+	}
+	if !frames[0].BadChecksum || !bytes.Equal(frames[0].Checksum, []byte{0x1D}) || !bytes.Equal(frames[0].Expected, []byte{0x1C}) {
+		t.Errorf("bad frame = %+v", frames[0])
+	}
+	if frames[1].BadChecksum {
+		t.Errorf("good frame flagged: %+v", frames[1])
+	}
+	if s := d.Stats(); s.Frames != 1 || s.BadChecksum != 1 {
+		t.Errorf("Stats = %+v", s)
+	}
+}
+
+func TestDecodeResync(t *testing.T) {
+	cfg := Config{LenBytes: 1, Sync: []byte{0xAA, 0x55}, Checksum: checksum.XOR8}
+	framed, _ := cfg.Encode([]byte("ok"))
+	d, _ := NewDecoder(cfg)
+
+	// 噪声与一个不完整的同步头之后才是真正的帧
+	frames := d.Feed(append([]byte{0x01, 0x02, 0xAA}, framed...))
+	if len(frames) != 1 || string(frames[0].Payload) != "ok" {
+		t.Fatalf("frames = %+v", frames)
+	}
+	if d.Stats().Resyncs != 3 {
+		t.Errorf("Resyncs = %d, want 3", d.Stats().Resyncs)
+	}
+}
+
+func TestDecodeLengthLimit(t *testing.T) {
+	d, _ := NewDecoder(Config{LenBytes: 1, MaxPayload: 4})
+	// 0x40 超出上限，被当作噪声丢弃
+	frames := d.Feed([]byte{0x40, 0x02, 'h', 'i'})
+	if len(frames) != 1 || string(frames[0].Payload) != "hi" {
+		t.Fatalf("frames = %+v", frames)
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	bad := []Config{
+		{LenBytes: 3},
+		{LenBytes: 1, MaxPayload: 300},
+		{LenBytes: 1, Checksum: "md5"},
+		{LenBytes: 1, Sync: make([]byte, MaxSyncLen+1)},
+	}
+	for _, cfg := range bad {
+		if cfg.Validate() == nil {
+			t.Errorf("%+v should be invalid", cfg)
+		}
+	}
+	if _, err := (Config{LenBytes: 1, MaxPayload: 2}).Encode([]byte("abc")); err == nil {
+		t.Error("Encode should reject payloads over MaxPayload")
+	}
+}
@@ -0,0 +1,229 @@
+package frame
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// MaxFields 字段表中的最多字段数
+const MaxFields = 256
+
+// FieldType 字段的编码类型，整数与浮点类型的后缀 le/be 为字节序
+type FieldType string
+
+const (
+	U8      FieldType = "u8"
+	I8      FieldType = "i8"
+	U16LE   FieldType = "u16le"
+	U16BE   FieldType = "u16be"
+	I16LE   FieldType = "i16le"
+	I16BE   FieldType = "i16be"
+	U32LE   FieldType = "u32le"
+	U32BE   FieldType = "u32be"
+	I32LE   FieldType = "i32le"
+	I32BE   FieldType = "i32be"
+	F32LE   FieldType = "f32le"
+	F32BE   FieldType = "f32be"
+	F64LE   FieldType = "f64le"
+	F64BE   FieldType = "f64be"
+	CString FieldType = "cstring" // 最多 Length 字节的文本，遇到 0 字节结束
+	Bytes   FieldType = "bytes"   // Length 字节，以十六进制字符串表示
+)
+
+// size 返回定长类型的字节数，变长类型 (cstring、bytes) 返回 0
+func (t FieldType) size() (int, bool) {
+	switch t {
+	case U8, I8:
+		return 1, true
+	case U16LE, U16BE, I16LE, I16BE:
+		return 2, true
+	case U32LE, U32BE, I32LE, I32BE, F32LE, F32BE:
+		return 4, true
+	case F64LE, F64BE:
+		return 8, true
+	case CString, Bytes:
+		return 0, true
+	}
+	return 0, false
+}
+
+// Field 负载中的一个命名字段
+type Field struct {
+	Name   string    `json:"name"`
+	Offset int       `json:"offset"`           // 在负载中的起始字节
+	Length int       `json:"length,omitempty"` // 字节数，定长类型可省略，cstring/bytes 必填
+	Type   FieldType `json:"type"`
+	Scale  float64   `json:"scale,omitempty"` // 数值乘以该系数，0 表示 1
+	Unit   string    `json:"unit,omitempty"`
+	Plot   bool      `json:"plot,omitempty"` // 数值作为绘图通道 (按字段表中的顺序)
+}
+
+// Schema 帧负载的字段表
+type Schema struct {
+	Fields []Field `json:"fields"`
+}
+
+// FieldValue 解码出的字段，Value 为 float64 (数值类型，已乘以 Scale) 或 string (cstring/bytes)；
+// 浮点字段为 NaN 或 ±Inf 时 Value 为 nil (JSON 不能表示这些值)
+type FieldValue struct {
+	Name  string      `json:"name"`
+	Value interface{} `json:"value"`
+	Unit  string      `json:"unit,omitempty"`
+}
+
+// Validate 检查字段表：名称唯一、类型有效、字段互不重叠且不超出 maxPayload (<= 0 时不检查)；
+// 错误信息指出第一个有问题的字段
+func (s Schema) Validate(maxPayload int) error {
+	if len(s.Fields) > MaxFields {
+		return fmt.Errorf("too many fields (%d, max %d)", len(s.Fields), MaxFields)
+	}
+	names := make(map[string]bool, len(s.Fields))
+	for i, f := range s.Fields {
+		if strings.TrimSpace(f.Name) == "" {
+			return fmt.Errorf("field %d: name is required", i+1)
+		}
+		if names[f.Name] {
+			return fmt.Errorf("field %q: duplicate name", f.Name)
+		}
+		names[f.Name] = true
+
+		size, ok := f.Type.size()
+		switch {
+		case !ok:
+			return fmt.Errorf("field %q: unknown type %q", f.Name, f.Type)
+		case f.Offset < 0:
+			return fmt.Errorf("field %q: offset must not be negative", f.Name)
+		case size == 0 && f.Length <= 0:
+			return fmt.Errorf("field %q: length is required for %s", f.Name, f.Type)
+		case size > 0 && f.Length != 0 && f.Length != size:
+			return fmt.Errorf("field %q: %s is %d bytes, got length %d", f.Name, f.Type, size, f.Length)
+		case math.IsNaN(f.Scale) || math.IsInf(f.Scale, 0):
+			return fmt.Errorf("field %q: invalid scale", f.Name)
+		case f.Plot && size == 0:
+			return fmt.Errorf("field %q: only numeric fields can be plotted", f.Name)
+		}
+		if end := f.Offset + f.size(); maxPayload > 0 && end > maxPayload {
+			return fmt.Errorf("field %q: bytes %d-%d exceed the %d byte frame payload", f.Name, f.Offset, end-1, maxPayload)
+		}
+	}
+
+	// 按起始位置排序后比较相邻字段
+	sorted := append([]Field(nil), s.Fields...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Offset < sorted[j].Offset })
+	for i := 1; i < len(sorted); i++ {
+		prev, cur := sorted[i-1], sorted[i]
+		if cur.Offset < prev.Offset+prev.size() {
+			return fmt.Errorf("field %q (bytes %d-%d) overlaps field %q (bytes %d-%d)",
+				cur.Name, cur.Offset, cur.Offset+cur.size()-1, prev.Name, prev.Offset, prev.Offset+prev.size()-1)
+		}
+	}
+	return nil
+}
+
+// size 返回字段占用的字节数
+func (f Field) size() int {
+	if n, _ := f.Type.size(); n > 0 {
+		return n
+	}
+	return f.Length
+}
+
+// Decode 按字段表解码负载，超出负载长度的字段不出现在结果中
+func (s Schema) Decode(payload []byte) []FieldValue {
+	out := make([]FieldValue, 0, len(s.Fields))
+	for _, f := range s.Fields {
+		v, ok := f.decode(payload)
+		if !ok {
+			continue
+		}
+		if n, isNum := v.(float64); isNum && (math.IsNaN(n) || math.IsInf(n, 0)) {
+			v = nil
+		}
+		out = append(out, FieldValue{Name: f.Name, Value: v, Unit: f.Unit})
+	}
+	return out
+}
+
+// PlotFields 返回 Plot 为 true 的字段名，即绘图通道的顺序
+func (s Schema) PlotFields() []string {
+	var names []string
+	for _, f := range s.Fields {
+		if f.Plot {
+			names = append(names, f.Name)
+		}
+	}
+	return names
+}
+
+// PlotValues 按 PlotFields 的顺序取出数值，超出负载长度的字段为 NaN；没有绘图字段时 ok 为 false
+func (s Schema) PlotValues(payload []byte) (values []float64, ok bool) {
+	for _, f := range s.Fields {
+		if !f.Plot {
+			continue
+		}
+		v, found := f.decode(payload)
+		if n, isNum := v.(float64); found && isNum {
+			values = append(values, n)
+			ok = true
+		} else {
+			values = append(values, math.NaN())
+		}
+	}
+	return values, ok
+}
+
+func (f Field) decode(payload []byte) (interface{}, bool) {
+	end := f.Offset + f.size()
+	if end > len(payload) {
+		return nil, false
+	}
+	b := payload[f.Offset:end]
+
+	var v float64
+	switch f.Type {
+	case CString:
+		if i := bytes.IndexByte(b, 0); i >= 0 {
+			b = b[:i]
+		}
+		return strings.ToValidUTF8(string(b), "\uFFFD"), true
+	case Bytes:
+		return hex.EncodeToString(b), true
+	case U8:
+		v = float64(b[0])
+	case I8:
+		v = float64(int8(b[0]))
+	case U16LE:
+		v = float64(binary.LittleEndian.Uint16(b))
+	case U16BE:
+		v = float64(binary.BigEndian.Uint16(b))
+	case I16LE:
+		v = float64(int16(binary.LittleEndian.Uint16(b)))
+	case I16BE:
+		v = float64(int16(binary.BigEndian.Uint16(b)))
+	case U32LE:
+		v = float64(binary.LittleEndian.Uint32(b))
+	case U32BE:
+		v = float64(binary.BigEndian.Uint32(b))
+	case I32LE:
+		v = float64(int32(binary.LittleEndian.Uint32(b)))
+	case I32BE:
+		v = float64(int32(binary.BigEndian.Uint32(b)))
+	case F32LE:
+		v = float64(math.Float32frombits(binary.LittleEndian.Uint32(b)))
+	case F32BE:
+		v = float64(math.Float32frombits(binary.BigEndian.Uint32(b)))
+	case F64LE:
+		v = math.Float64frombits(binary.LittleEndian.Uint64(b))
+	case F64BE:
+		v = math.Float64frombits(binary.BigEndian.Uint64(b))
+	}
+	if f.Scale != 0 {
+		v *= f.Scale
+	}
+	return v, true
+}
@@ -0,0 +1,85 @@
+package frame
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestSchemaDecode(t *testing.T) {
+	s := Schema{Fields: []Field{
+		{Name: "id", Offset: 0, Type: U8},
+		{Name: "temp", Offset: 1, Type: I16LE, Scale: 0.1, Unit: "°C", Plot: true},
+		{Name: "counter", Offset: 3, Type: U32BE},
+		{Name: "volts", Offset: 7, Type: F32LE, Unit: "V", Plot: true},
+		{Name: "tag", Offset: 11, Type: CString, Length: 6},
+	}}
+	if err := s.Validate(64); err != nil {
+		t.Fatal(err)
+	}
+	payload := []byte{0x07, 0x13, 0xFF, 0x00, 0x01, 0x02, 0x03, 0x00, 0x00, 0x40, 0x40, 'n', 'o', 'd', 'e', 0, 'x'}
+
+	got := s.Decode(payload)
+	if len(got) != 5 {
+		t.Fatalf("%d fields", len(got))
+	}
+	want := []interface{}{7.0, -23.7, float64(0x00010203), 3.0, "node"}
+	for i, w := range want {
+		if n, ok := w.(float64); ok {
+			if v, _ := got[i].Value.(float64); math.Abs(v-n) > 1e-9 {
+				t.Errorf("%s = %v, want %v", got[i].Name, got[i].Value, w)
+			}
+		} else if got[i].Value != w {
+			t.Errorf("%s = %v, want %v", got[i].Name, got[i].Value, w)
+		}
+	}
+	if got[1].Unit != "°C" {
+		t.Errorf("unit = %q", got[1].Unit)
+	}
+
+	// 较短的帧只包含完整的字段，绘图通道缺失的值为 NaN
+	short := s.Decode(payload[:5])
+	if len(short) != 2 || short[1].Name != "temp" {
+		t.Errorf("short frame: %+v", short)
+	}
+	values, ok := s.PlotValues(payload[:5])
+	if !ok || len(values) != 2 || !math.IsNaN(values[1]) {
+		t.Errorf("PlotValues = %v, %v", values, ok)
+	}
+	if names := strings.Join(s.PlotFields(), ","); names != "temp,volts" {
+		t.Errorf("PlotFields = %s", names)
+	}
+}
+
+func TestSchemaNaNValue(t *testing.T) {
+	s := Schema{Fields: []Field{{Name: "v", Type: F32BE}}}
+	if got := s.Decode([]byte{0x7F, 0xC0, 0x00, 0x00}); len(got) != 1 || got[0].Value != nil {
+		t.Errorf("NaN decoded as %+v", got)
+	}
+}
+
+func TestSchemaValidate(t *testing.T) {
+	tests := []struct {
+		fields []Field
+		want   string
+	}{
+		{[]Field{{Name: "a", Type: U16LE}, {Name: "b", Offset: 1, Type: U8}}, `field "b" (bytes 1-1) overlaps field "a" (bytes 0-1)`},
+		{[]Field{{Name: "a", Offset: 30, Type: U32LE}}, "exceed the 32 byte frame payload"},
+		{[]Field{{Name: "s", Type: CString}}, "length is required"},
+		{[]Field{{Name: "a", Type: U16LE, Length: 4}}, "u16le is 2 bytes"},
+		{[]Field{{Name: "a", Type: "u24"}}, "unknown type"},
+		{[]Field{{Name: "a", Type: U8}, {Name: "a", Offset: 1, Type: U8}}, "duplicate name"},
+		{[]Field{{Name: "s", Type: Bytes, Length: 2, Plot: true}}, "only numeric fields"},
+		{[]Field{{Type: U8}}, "name is required"},
+	}
+	for _, tt := range tests {
+		err := Schema{Fields: tt.fields}.Validate(32)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%+v: got %v, want %q", tt.fields, err, tt.want)
+		}
+	}
+	// maxPayload <= 0 时不检查长度
+	if err := (Schema{Fields: []Field{{Name: "a", Offset: 1000, Type: U8}}}).Validate(0); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
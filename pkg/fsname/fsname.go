@@ -0,0 +1,48 @@
+// Package fsname 生成可安全用作文件名的字符串
+package fsname
+
+import (
+	"strings"
+	"unicode"
+)
+
+// MaxLen Sanitize 结果的最大字符数 (按 rune 计)
+const MaxLen = 64
+
+// windowsReserved Windows 上不能作为文件名 (不论扩展名) 的设备名
+var windowsReserved = map[string]bool{
+	"CON": true, "PRN": true, "AUX": true, "NUL": true,
+	"COM1": true, "COM2": true, "COM3": true, "COM4": true, "COM5": true, "COM6": true, "COM7": true, "COM8": true, "COM9": true,
+	"LPT1": true, "LPT2": true, "LPT3": true, "LPT4": true, "LPT5": true, "LPT6": true, "LPT7": true, "LPT8": true, "LPT9": true,
+}
+
+// Sanitize 将任意文本 (连接标签、端口名等) 转换为各平台通用的文件名片段：
+// 只保留字母、数字、'-'、'_' 与 '.'，其他字符 (包括路径分隔符与空白) 替换为 '_' 并合并，
+// 去掉首尾的 '.' 与 '_'，截断到 MaxLen 个字符；结果为空时返回空字符串，Windows 保留名前加 '_'
+func Sanitize(name string) string {
+	var sb strings.Builder
+	lastUnderscore := false
+	for _, r := range name {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '.':
+			sb.WriteRune(r)
+			lastUnderscore = false
+		default:
+			if !lastUnderscore {
+				sb.WriteByte('_')
+				lastUnderscore = true
+			}
+		}
+	}
+
+	out := strings.Trim(sb.String(), "._")
+	if runes := []rune(out); len(runes) > MaxLen {
+		out = strings.TrimRight(string(runes[:MaxLen]), "._")
+	}
+
+	base, _, _ := strings.Cut(out, ".")
+	if windowsReserved[strings.ToUpper(base)] {
+		out = "_" + out
+	}
+	return out
+}
@@ -0,0 +1,33 @@
+package fsname
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSanitize(t *testing.T) {
+	tests := []struct {
+		in, expected string
+	}{
+		{"Board A", "Board_A"},
+		{"/dev/ttyUSB0", "dev_ttyUSB0"},
+		{`COM3`, "_COM3"},
+		{"nul.txt", "_nul.txt"},
+		{"../../etc/passwd", "etc_passwd"},
+		{"a::b??c", "a_b_c"},
+		{"温度传感器#1", "温度传感器_1"},
+		{"  ...  ", ""},
+		{"v1.2-rc_3", "v1.2-rc_3"},
+		{"tab\there\nnewline", "tab_here_newline"},
+	}
+	for _, tt := range tests {
+		if got := Sanitize(tt.in); got != tt.expected {
+			t.Errorf("Sanitize(%q) = %q, expected %q", tt.in, got, tt.expected)
+		}
+	}
+
+	long := Sanitize(strings.Repeat("长", 100))
+	if n := len([]rune(long)); n != MaxLen {
+		t.Errorf("Expected %d runes, got %d", MaxLen, n)
+	}
+}
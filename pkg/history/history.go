@@ -0,0 +1,237 @@
+package history
+
+import (
+	"sync"
+	"time"
+)
+
+// DefaultLimit 历史缓冲区默认字节上限
+const DefaultLimit = 8 * 1024 * 1024 // 8MB
+
+// Entry 历史缓冲区中的一条记录，对应一次数据事件或一条用户标注
+type Entry struct {
+	Seq  uint64    `json:"seq"`
+	Time time.Time `json:"time"`
+	Data []byte    `json:"data"`
+
+	// Source 数据来源 (TCP 服务端的客户端地址或 UDP 数据报的来源地址)，其他连接为空
+	Source string `json:"source,omitempty"`
+
+	// Annotation 非空时该记录是用户标注 (见 AppendAnnotation)，Data 为空
+	Annotation string `json:"annotation,omitempty"`
+}
+
+// size 记录占用的字节数
+func (e Entry) size() int {
+	return len(e.Data) + len(e.Annotation)
+}
+
+// Range 描述缓冲区当前保留的序号范围及淘汰统计
+// 当缓冲区为空时 FirstSeq 为 NextSeq，LastSeq 为 NextSeq-1
+type Range struct {
+	FirstSeq uint64 `json:"firstSeq"`
+	LastSeq  uint64 `json:"lastSeq"`
+	NextSeq  uint64 `json:"nextSeq"`
+	Entries  int    `json:"entries"`
+	Bytes    int    `json:"bytes"`
+	Limit    int    `json:"limit"`
+
+	// 淘汰统计（自上次 Reset 起累计）
+	EvictedBytes   uint64    `json:"evictedBytes"`
+	EvictedEntries uint64    `json:"evictedEntries"`
+	OldestTime     time.Time `json:"oldestTime"` // 最早保留记录的时间，缓冲区为空时为零值
+}
+
+// Buffer 按序号索引的有界历史缓冲区，线程安全
+// 序号从 1 开始单调递增，超出字节上限时淘汰最旧的记录
+type Buffer struct {
+	mu      sync.Mutex
+	entries []Entry
+	bytes   int
+	limit   int
+	nextSeq uint64
+
+	evictedBytes   uint64
+	evictedEntries uint64
+}
+
+// New 创建历史缓冲区，limit <= 0 时使用 DefaultLimit
+func New(limit int) *Buffer {
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+	return &Buffer{limit: limit, nextSeq: 1}
+}
+
+// SetLimit 修改字节上限并立即淘汰超出部分，limit <= 0 时使用 DefaultLimit
+func (b *Buffer) SetLimit(limit int) {
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.limit = limit
+	b.evictLocked()
+	if cap(b.entries) > 2*len(b.entries)+64 {
+		// 大幅缩小后释放底层数组中多余的空间
+		b.entries = append([]Entry(nil), b.entries...)
+	}
+}
+
+// Append 记录一段数据并返回分配的序号
+// data 会被直接保存，调用方不得再修改它
+func (b *Buffer) Append(t time.Time, data []byte) uint64 {
+	return b.AppendFrom(t, data, "")
+}
+
+// AppendFrom 同 Append，并记录数据来源
+func (b *Buffer) AppendFrom(t time.Time, data []byte, source string) uint64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.appendLocked(Entry{Time: t, Data: data, Source: source})
+}
+
+// AppendAnnotation 记录一条带时间戳的标注，与数据共用序号
+func (b *Buffer) AppendAnnotation(t time.Time, text string) uint64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.appendLocked(Entry{Time: t, Annotation: text})
+}
+
+func (b *Buffer) appendLocked(e Entry) uint64 {
+	e.Seq = b.nextSeq
+	b.nextSeq++
+	b.entries = append(b.entries, e)
+	b.bytes += e.size()
+	b.evictLocked()
+	return e.Seq
+}
+
+// evictLocked 淘汰最旧的记录直到满足字节上限
+// 至少保留最新的一条记录，即使它本身超过上限
+func (b *Buffer) evictLocked() {
+	n := 0
+	for b.bytes > b.limit && len(b.entries)-n > 1 {
+		size := b.entries[n].size()
+		b.bytes -= size
+		b.evictedBytes += uint64(size)
+		b.evictedEntries++
+		b.entries[n] = Entry{} // 释放引用，便于 GC
+		n++
+	}
+	if n > 0 {
+		b.entries = b.entries[n:]
+	}
+}
+
+// Trim 在字节上限之外额外淘汰最旧的记录，直到至少释放 n 字节，返回实际释放的字节数
+// 与 evictLocked 一样至少保留最新的一条记录；用于全局内存预算不足时收缩历史
+func (b *Buffer) Trim(n int) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	freed, i := 0, 0
+	for freed < n && len(b.entries)-i > 1 {
+		size := b.entries[i].size()
+		freed += size
+		b.evictedBytes += uint64(size)
+		b.evictedEntries++
+		b.entries[i] = Entry{}
+		i++
+	}
+	b.entries = b.entries[i:]
+	b.bytes -= freed
+	return freed
+}
+
+// Bytes 返回当前保留的字节数
+func (b *Buffer) Bytes() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.bytes
+}
+
+// From 返回序号不小于 seq 的所有记录
+// 如果 seq 对应的数据已被淘汰，则从最早可用的记录开始返回，并将 truncated 置为 true
+func (b *Buffer) From(seq uint64) (entries []Entry, truncated bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if len(b.entries) == 0 {
+		// 缓冲区为空：请求的序号如果早于下一个序号，说明数据已不可用
+		return nil, seq < b.nextSeq
+	}
+
+	first := b.entries[0].Seq
+	start := 0
+	if seq < first {
+		truncated = true
+	} else {
+		// 序号连续，可以直接计算下标
+		start = int(seq - first)
+		if start >= len(b.entries) {
+			return nil, false
+		}
+	}
+
+	entries = make([]Entry, len(b.entries)-start)
+	copy(entries, b.entries[start:])
+	return entries, truncated
+}
+
+// Tail 返回最近的若干条完整记录，总字节数不超过 maxBytes
+// 不会截断单条记录 (开启分帧时每条记录是一个完整的帧)，放不下的较早记录被丢弃并将 truncated 置为 true
+// 只在锁内复制记录头，Data 与缓冲区共享，调用方不得修改
+func (b *Buffer) Tail(maxBytes int) (entries []Entry, truncated bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	total := 0
+	start := len(b.entries)
+	for start > 0 && total+b.entries[start-1].size() <= maxBytes {
+		start--
+		total += b.entries[start].size()
+	}
+	entries = make([]Entry, len(b.entries)-start)
+	copy(entries, b.entries[start:])
+	return entries, start > 0 || b.evictedEntries > 0
+}
+
+// Range 返回当前保留的序号范围
+func (b *Buffer) Range() Range {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	r := Range{
+		FirstSeq: b.nextSeq,
+		LastSeq:  b.nextSeq - 1,
+		NextSeq:  b.nextSeq,
+		Entries:  len(b.entries),
+		Bytes:    b.bytes,
+		Limit:    b.limit,
+
+		EvictedBytes:   b.evictedBytes,
+		EvictedEntries: b.evictedEntries,
+	}
+	if len(b.entries) > 0 {
+		r.FirstSeq = b.entries[0].Seq
+		r.OldestTime = b.entries[0].Time
+	}
+	return r
+}
+
+// Reset 清空缓冲区并将序号重置为 1（用于新连接）
+func (b *Buffer) Reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.entries = nil
+	b.bytes = 0
+	b.nextSeq = 1
+	b.evictedBytes = 0
+	b.evictedEntries = 0
+}
@@ -0,0 +1,253 @@
+package history
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAppendAssignsSequentialSeq(t *testing.T) {
+	b := New(1024)
+	now := time.Now()
+
+	for i := 1; i <= 5; i++ {
+		seq := b.Append(now, []byte("x"))
+		if seq != uint64(i) {
+			t.Errorf("Append #%d returned seq %d, expected %d", i, seq, i)
+		}
+	}
+
+	r := b.Range()
+	if r.FirstSeq != 1 || r.LastSeq != 5 || r.NextSeq != 6 {
+		t.Errorf("Unexpected range: %+v", r)
+	}
+	if r.Entries != 5 || r.Bytes != 5 {
+		t.Errorf("Expected 5 entries / 5 bytes, got %d / %d", r.Entries, r.Bytes)
+	}
+}
+
+func TestAnnotationsShareSequence(t *testing.T) {
+	b := New(1024)
+	now := time.Now()
+
+	b.Append(now, []byte("abc"))
+	seq := b.AppendAnnotation(now, "power cycled")
+	b.Append(now, []byte("d"))
+	if seq != 2 {
+		t.Errorf("Expected annotation seq 2, got %d", seq)
+	}
+
+	entries, _ := b.From(1)
+	if len(entries) != 3 || entries[1].Annotation != "power cycled" || entries[1].Data != nil {
+		t.Errorf("Unexpected entries: %+v", entries)
+	}
+	if r := b.Range(); r.Bytes != 3+len("power cycled")+1 {
+		t.Errorf("Annotation text should count towards the byte limit, got %d bytes", r.Bytes)
+	}
+}
+
+func TestAppendFromKeepsSource(t *testing.T) {
+	b := New(1024)
+	now := time.Now()
+
+	b.AppendFrom(now, []byte("a"), "10.0.0.2:5000")
+	b.AppendFrom(now, []byte("b"), "10.0.0.3:5001")
+	b.Append(now, []byte("c"))
+
+	entries, _ := b.From(1)
+	if len(entries) != 3 || entries[0].Source != "10.0.0.2:5000" || entries[1].Source != "10.0.0.3:5001" || entries[2].Source != "" {
+		t.Errorf("Unexpected entries: %+v", entries)
+	}
+}
+
+func TestFromReturnsRequestedTail(t *testing.T) {
+	b := New(1024)
+	now := time.Now()
+	for i := 0; i < 10; i++ {
+		b.Append(now, []byte{byte(i)})
+	}
+
+	entries, truncated := b.From(7)
+	if truncated {
+		t.Error("Expected truncated=false for retained sequence")
+	}
+	if len(entries) != 4 {
+		t.Fatalf("Expected 4 entries from seq 7, got %d", len(entries))
+	}
+	for i, e := range entries {
+		if e.Seq != uint64(7+i) {
+			t.Errorf("entries[%d].Seq = %d, expected %d", i, e.Seq, 7+i)
+		}
+		if e.Data[0] != byte(6+i) {
+			t.Errorf("entries[%d].Data = %v, expected %d", i, e.Data, 6+i)
+		}
+	}
+
+	// 请求尚未产生的序号时返回空
+	entries, truncated = b.From(11)
+	if len(entries) != 0 || truncated {
+		t.Errorf("Expected no entries for future seq, got %d (truncated=%v)", len(entries), truncated)
+	}
+}
+
+// TestFromEvictedSequence 验证请求已淘汰的序号时，返回最早可用数据并标记截断
+func TestFromEvictedSequence(t *testing.T) {
+	b := New(4) // 仅保留 4 字节
+	now := time.Now()
+	for i := 0; i < 10; i++ {
+		b.Append(now, []byte{byte(i)})
+	}
+
+	r := b.Range()
+	if r.FirstSeq != 7 || r.LastSeq != 10 {
+		t.Fatalf("Expected retained range 7-10, got %d-%d", r.FirstSeq, r.LastSeq)
+	}
+
+	entries, truncated := b.From(2)
+	if !truncated {
+		t.Error("Expected truncated=true when requested seq was evicted")
+	}
+	if len(entries) != 4 || entries[0].Seq != 7 {
+		t.Errorf("Expected replay to start at earliest seq 7 with 4 entries, got %d entries", len(entries))
+	}
+}
+
+func TestFromOnEmptyBuffer(t *testing.T) {
+	b := New(4)
+	entries, truncated := b.From(1)
+	if len(entries) != 0 || truncated {
+		t.Errorf("Empty buffer: expected no entries and no truncation, got %d (truncated=%v)", len(entries), truncated)
+	}
+}
+
+func TestOversizedEntryIsKept(t *testing.T) {
+	b := New(4)
+	b.Append(time.Now(), []byte("0123456789"))
+
+	r := b.Range()
+	if r.Entries != 1 || r.Bytes != 10 {
+		t.Errorf("Expected single oversized entry to be retained, got %+v", r)
+	}
+}
+
+func TestTailKeepsWholeEntries(t *testing.T) {
+	b := New(1024)
+	now := time.Now()
+	b.Append(now, []byte("frame-1\n"))
+	b.AppendAnnotation(now, "note")
+	b.Append(now, []byte("frame-2\n"))
+	b.Append(now, []byte("frame-3\n"))
+
+	// 12 字节只放得下最后一帧，不会返回半帧
+	entries, truncated := b.Tail(12)
+	if len(entries) != 1 || string(entries[0].Data) != "frame-3\n" || !truncated {
+		t.Errorf("Expected only the last frame, got %+v (truncated=%v)", entries, truncated)
+	}
+
+	entries, truncated = b.Tail(1024)
+	if len(entries) != 4 || truncated {
+		t.Errorf("Expected all entries without truncation, got %d (truncated=%v)", len(entries), truncated)
+	}
+	if entries[0].Seq != 1 || entries[1].Annotation != "note" {
+		t.Errorf("Unexpected entries: %+v", entries)
+	}
+
+	if entries, truncated = b.Tail(4); len(entries) != 0 || !truncated {
+		t.Errorf("Expected nothing when the newest entry does not fit, got %d", len(entries))
+	}
+}
+
+func TestTailReportsEvicted(t *testing.T) {
+	b := New(8)
+	b.Append(time.Now(), []byte("01234567"))
+	b.Append(time.Now(), []byte("89"))
+
+	entries, truncated := b.Tail(1024)
+	if len(entries) != 1 || !truncated {
+		t.Errorf("Expected evicted data to be reported as truncated, got %d (truncated=%v)", len(entries), truncated)
+	}
+}
+
+func TestReset(t *testing.T) {
+	b := New(1024)
+	b.Append(time.Now(), []byte("abc"))
+	b.Reset()
+
+	r := b.Range()
+	if r.Entries != 0 || r.Bytes != 0 || r.NextSeq != 1 {
+		t.Errorf("Expected empty buffer after Reset, got %+v", r)
+	}
+	if seq := b.Append(time.Now(), []byte("x")); seq != 1 {
+		t.Errorf("Expected seq to restart at 1, got %d", seq)
+	}
+}
+
+func TestSetLimitShrinksImmediately(t *testing.T) {
+	b := New(1024)
+	now := time.Now()
+	for i := 0; i < 100; i++ {
+		b.Append(now.Add(time.Duration(i)*time.Second), make([]byte, 10))
+	}
+
+	b.SetLimit(100)
+	r := b.Range()
+	if r.Bytes > 100 {
+		t.Errorf("Expected at most 100 bytes after SetLimit, got %d", r.Bytes)
+	}
+	if r.Limit != 100 {
+		t.Errorf("Expected limit 100, got %d", r.Limit)
+	}
+	if r.EvictedEntries != 90 || r.EvictedBytes != 900 {
+		t.Errorf("Expected 90 entries / 900 bytes evicted, got %d / %d", r.EvictedEntries, r.EvictedBytes)
+	}
+	if !r.OldestTime.Equal(now.Add(90 * time.Second)) {
+		t.Errorf("Expected oldest retained time to be entry #90, got %v", r.OldestTime)
+	}
+}
+
+func TestTrimReleasesOldest(t *testing.T) {
+	b := New(1024)
+	now := time.Now()
+	for i := 0; i < 10; i++ {
+		b.Append(now.Add(time.Duration(i)*time.Second), make([]byte, 10))
+	}
+
+	if freed := b.Trim(25); freed != 30 {
+		t.Errorf("Trim(25) freed %d bytes, want 30 (whole entries)", freed)
+	}
+	r := b.Range()
+	if b.Bytes() != 70 || r.FirstSeq != 4 || r.EvictedEntries != 3 || r.Limit != 1024 {
+		t.Errorf("after Trim: bytes %d, range %+v", b.Bytes(), r)
+	}
+
+	// 至少保留最新的一条记录
+	if freed := b.Trim(1000); freed != 60 || b.Bytes() != 10 || b.Range().FirstSeq != 10 {
+		t.Errorf("Trim(1000) freed %d, %d bytes left", freed, b.Bytes())
+	}
+}
+
+// TestSoakStaysBounded 写入 10 倍预算的数据，验证缓冲区大小始终不超过上限
+func TestSoakStaysBounded(t *testing.T) {
+	const limit = 64 * 1024
+	b := New(limit)
+	now := time.Now()
+
+	chunk := make([]byte, 1500)
+	written := 0
+	for written < 10*limit {
+		b.Append(now, chunk)
+		written += len(chunk)
+
+		if r := b.Range(); r.Bytes > limit {
+			t.Fatalf("Buffer exceeded limit: %d > %d after %d bytes written", r.Bytes, limit, written)
+		}
+	}
+
+	r := b.Range()
+	if r.EvictedBytes+uint64(r.Bytes) != uint64(written) {
+		t.Errorf("Eviction accounting mismatch: evicted %d + retained %d != written %d", r.EvictedBytes, r.Bytes, written)
+	}
+	// 底层数组不应无限增长
+	if cap(b.entries) > 4*(limit/len(chunk)+1) {
+		t.Errorf("Entry slice capacity grew unbounded: %d", cap(b.entries))
+	}
+}
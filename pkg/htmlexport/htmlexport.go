@@ -0,0 +1,156 @@
+// Package htmlexport 将历史记录渲染为单个自包含的 HTML 文件 (样式内嵌)，便于作为报告或邮件附件分享
+//
+// Writer 逐条写入记录，不在内存中构建整个文档；文本按行匹配分类规则并以 CSS 类高亮，
+// 二进制数据与开启 Hex 视图时的数据以十六进制/ASCII 转储块显示。
+package htmlexport
+
+import (
+	"bufio"
+	"bytes"
+	_ "embed"
+	"fmt"
+	"html"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"serial-assistant/pkg/classify"
+	"serial-assistant/pkg/format"
+	"serial-assistant/pkg/history"
+)
+
+//go:embed style.css
+var styleCSS string
+
+// dumpBlock 转储时每次送入的字节数，保持在 format.MaxEventPayload 以内
+const dumpBlock = 4096
+
+// Options 导出选项
+type Options struct {
+	Title      string
+	From, To   time.Time            // 导出的时间范围，显示在页首
+	Hex        bool                 // 所有数据以十六进制/ASCII 转储显示
+	Dump       format.DumpOptions   // 转储格式，需已通过 Validate
+	Classifier *classify.Classifier // 行分类规则，匹配的行以 "cls cls-<类别>" 高亮，可为 nil
+}
+
+// Writer 将记录写为 HTML，非线程安全
+type Writer struct {
+	w       *bufio.Writer
+	opts    Options
+	tracker classify.Tracker
+	entries int
+	bytes   int
+	notes   int
+}
+
+// NewWriter 写入文档头部 (包括内嵌样式)
+func NewWriter(w io.Writer, opts Options) (*Writer, error) {
+	if opts.Title == "" {
+		opts.Title = "serial-mate session"
+	}
+	hw := &Writer{w: bufio.NewWriterSize(w, 64*1024), opts: opts}
+	title := html.EscapeString(opts.Title)
+	fmt.Fprintf(hw.w, "<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n<title>%s</title>\n<style>\n%s</style>\n</head>\n<body>\n", title, styleCSS)
+	fmt.Fprintf(hw.w, "<header>\n<h1>%s</h1>\n<p>%s &ndash; %s</p>\n</header>\n<main>\n",
+		title, formatTime(opts.From, "2006-01-02 15:04:05"), formatTime(opts.To, "2006-01-02 15:04:05"))
+	return hw, hw.err()
+}
+
+// Write 写入一条记录：标注显示为 note 行，数据显示为 rx 行
+func (w *Writer) Write(e history.Entry) error {
+	w.entries++
+	if e.Annotation != "" {
+		w.notes++
+		w.row("note", e, "<pre class=\"data\">"+html.EscapeString(e.Annotation)+"</pre>")
+		return w.err()
+	}
+
+	w.bytes += len(e.Data)
+	if w.opts.Hex || isBinary(e.Data) {
+		w.tracker.Reset()
+		w.row("rx", e, "<pre class=\"data hex\">"+html.EscapeString(w.dump(e.Data))+"</pre>")
+		return w.err()
+	}
+	w.row("rx", e, "<pre class=\"data\">"+w.text(e.Data)+"</pre>")
+	return w.err()
+}
+
+// Close 写入页尾并刷新缓冲，不关闭底层的 io.Writer
+func (w *Writer) Close() error {
+	fmt.Fprintf(w.w, "</main>\n<footer>%d entries, %d bytes received, %d annotations</footer>\n</body>\n</html>\n",
+		w.entries, w.bytes, w.notes)
+	return w.w.Flush()
+}
+
+// row 写入一行记录，body 为已转义的内容
+func (w *Writer) row(class string, e history.Entry, body string) {
+	fmt.Fprintf(w.w, "<div class=\"entry %s\"><span class=\"ts\">%s</span>", class, formatTime(e.Time, "15:04:05.000"))
+	if e.Source != "" {
+		fmt.Fprintf(w.w, "<span class=\"src\">%s</span>", html.EscapeString(e.Source))
+	}
+	w.w.WriteString(body)
+	w.w.WriteString("</div>\n")
+}
+
+// err 返回缓冲写入遇到的错误 (bufio.Writer 出错后保留该错误)
+func (w *Writer) err() error {
+	_, err := w.w.Write(nil)
+	return err
+}
+
+// text 转义文本，结束于本块的行匹配到分类时以 span 包围
+func (w *Writer) text(data []byte) string {
+	var b strings.Builder
+	start := 0
+	for _, lc := range w.tracker.Feed(w.opts.Classifier, data) {
+		// 跨块的行只高亮本块中的部分
+		lineStart := bytes.LastIndexByte(data[:lc.End-1], '\n') + 1
+		b.WriteString(escape(data[start:lineStart]))
+		fmt.Fprintf(&b, "<span class=\"cls cls-%s\">%s</span>", lc.Class, escape(data[lineStart:lc.End]))
+		start = lc.End
+	}
+	b.WriteString(escape(data[start:]))
+	return b.String()
+}
+
+// dump 以转储格式显示 data，偏移从 0 开始
+func (w *Writer) dump(data []byte) string {
+	opts := w.opts.Dump
+	opts.Continuous = true
+	d := format.NewDumper(opts)
+	var b strings.Builder
+	for len(data) > 0 {
+		n := min(dumpBlock, len(data))
+		s, _ := d.Dump(data[:n])
+		b.WriteString(s)
+		data = data[n:]
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// escape 转义文本，无效的 UTF-8 以替换字符显示
+func escape(data []byte) string {
+	return html.EscapeString(strings.ToValidUTF8(string(data), "\uFFFD"))
+}
+
+// isBinary 判断数据是否应显示为转储：无效的 UTF-8 或含有 \t \r \n 以外的控制字符
+func isBinary(data []byte) bool {
+	if !utf8.Valid(data) {
+		return true
+	}
+	for _, c := range data {
+		if (c < 0x20 && c != '\t' && c != '\r' && c != '\n') || c == 0x7F {
+			return true
+		}
+	}
+	return false
+}
+
+func formatTime(t time.Time, layout string) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.Format(layout)
+}
@@ -0,0 +1,84 @@
+package htmlexport
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"serial-assistant/pkg/classify"
+	"serial-assistant/pkg/format"
+	"serial-assistant/pkg/history"
+)
+
+func render(t *testing.T, opts Options, entries ...history.Entry) string {
+	t.Helper()
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range entries {
+		if err := w.Write(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+func TestRenderEntries(t *testing.T) {
+	c, err := classify.Compile([]classify.Rule{{Pattern: "ERR", Class: "error"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t0 := time.Date(2026, 3, 1, 12, 0, 0, 0, time.Local)
+	out := render(t, Options{Title: "boot <log>", From: t0, To: t0.Add(time.Minute), Dump: format.DumpOptions{BytesPerRow: 16, ASCII: true}, Classifier: c},
+		history.Entry{Time: t0, Data: []byte("ok\r\nERR: <bad>\n")},
+		history.Entry{Time: t0.Add(time.Second), Annotation: "reset board"},
+		history.Entry{Time: t0.Add(2 * time.Second), Data: []byte{0x01, 0x02, 0xFF}, Source: "10.0.0.2:5000"},
+	)
+
+	for _, want := range []string{
+		"<title>boot &lt;log&gt;</title>",
+		"2026-03-01 12:00:00 &ndash; 2026-03-01 12:01:00",
+		".cls-error",
+		`<span class="ts">12:00:00.000</span><pre class="data">ok` + "\r\n" + `<span class="cls cls-error">ERR: &lt;bad&gt;` + "\n</span></pre>",
+		`<div class="entry note"><span class="ts">12:00:01.000</span><pre class="data">reset board</pre>`,
+		`<span class="src">10.0.0.2:5000</span><pre class="data hex">00000000  01 02 ff`,
+		"3 entries, 18 bytes received, 1 annotations",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Output does not contain %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "<link") || strings.Contains(out, "<script") {
+		t.Error("Output references external resources")
+	}
+}
+
+func TestHexView(t *testing.T) {
+	out := render(t, Options{Hex: true, Dump: format.DumpOptions{BytesPerRow: 8, Uppercase: true}},
+		history.Entry{Data: []byte("AB")})
+	if !strings.Contains(out, `<pre class="data hex">00000000  41 42 </pre>`) {
+		t.Errorf("Text was not dumped as hex:\n%s", out)
+	}
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) { return 0, errors.New("disk full") }
+
+func TestWriteErrorReported(t *testing.T) {
+	w, err := NewWriter(failingWriter{}, Options{Dump: format.DumpOptions{BytesPerRow: 16}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	big := history.Entry{Data: bytes.Repeat([]byte("x"), 128*1024)}
+	if err := w.Write(big); err == nil {
+		t.Error("Expected the write error once the buffer is flushed")
+	}
+}
@@ -0,0 +1,47 @@
+// Package httpclient 提供应用所有对外 HTTPS 请求共用的 http.Transport：
+// 可额外信任用户指定的 CA 证书 (企业网络的 TLS 拦截)，并使用系统代理设置
+package httpclient
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+)
+
+// Config 共享 Transport 的配置
+type Config struct {
+	CAFile string // 额外信任的 CA 证书文件 (PEM，可包含多个证书)，为空表示只信任系统证书
+}
+
+// NewTransport 按配置创建 Transport
+// CA 文件在这里读取并校验，无法读取或不包含证书时返回错误，而不是推迟到第一次请求失败
+func NewTransport(cfg Config) (*http.Transport, error) {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.Proxy = ProxyFunc()
+	if cfg.CAFile != "" {
+		pool, err := LoadCAFile(cfg.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		t.TLSClientConfig = &tls.Config{RootCAs: pool}
+	}
+	return t, nil
+}
+
+// LoadCAFile 返回系统证书加上 path 中的 PEM 证书
+func LoadCAFile(path string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA file: %w", err)
+	}
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("no PEM certificates found in %s", path)
+	}
+	return pool, nil
+}
@@ -0,0 +1,87 @@
+package httpclient
+
+import (
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCustomCATrust(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	// 未信任测试服务器的自签名证书时应失败
+	plain, err := NewTransport(Config{})
+	if err != nil {
+		t.Fatalf("NewTransport failed: %v", err)
+	}
+	if _, err := (&http.Client{Transport: plain}).Get(srv.URL); err == nil || !strings.Contains(err.Error(), "certificate") {
+		t.Fatalf("Expected certificate error without custom CA, got %v", err)
+	}
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	block := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+	if err := os.WriteFile(caFile, block, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	trusted, err := NewTransport(Config{CAFile: caFile})
+	if err != nil {
+		t.Fatalf("NewTransport with CA failed: %v", err)
+	}
+	resp, err := (&http.Client{Transport: trusted}).Get(srv.URL)
+	if err != nil {
+		t.Fatalf("Request with custom CA failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Unexpected status %d", resp.StatusCode)
+	}
+}
+
+func TestCAFileErrors(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := NewTransport(Config{CAFile: filepath.Join(dir, "missing.pem")}); err == nil {
+		t.Error("Expected error for missing CA file")
+	}
+	bad := filepath.Join(dir, "bad.pem")
+	os.WriteFile(bad, []byte("not a certificate"), 0o644)
+	if _, err := NewTransport(Config{CAFile: bad}); err == nil || !strings.Contains(err.Error(), "no PEM certificates") {
+		t.Errorf("Expected error for file without certificates, got %v", err)
+	}
+}
+
+func TestSelectProxy(t *testing.T) {
+	tests := []struct {
+		proxy, bypass, target, expected string
+	}{
+		{"proxy.corp:8080", "", "https://api.github.com/x", "http://proxy.corp:8080"},
+		{"http=web:80;https=secure:443", "", "https://api.github.com", "http://secure:443"},
+		{"http=web:80", "", "https://api.github.com", ""},
+		{"proxy:8080", "<local>;*.corp.example", "https://intranet/x", ""},
+		{"proxy:8080", "<local>;*.corp.example", "https://git.CORP.example/x", ""},
+		{"proxy:8080", "<local>", "https://api.github.com", "http://proxy:8080"},
+		{"socks=s:1080;https://tls-proxy:3128", "", "https://api.github.com", "https://tls-proxy:3128"},
+	}
+	for _, tt := range tests {
+		u, _ := url.Parse(tt.target)
+		got, err := selectProxy(tt.proxy, tt.bypass, u)
+		if err != nil {
+			t.Errorf("selectProxy(%q, %q, %s): %v", tt.proxy, tt.bypass, tt.target, err)
+			continue
+		}
+		gotStr := ""
+		if got != nil {
+			gotStr = got.String()
+		}
+		if gotStr != tt.expected {
+			t.Errorf("selectProxy(%q, %q, %s) = %q, expected %q", tt.proxy, tt.bypass, tt.target, gotStr, tt.expected)
+		}
+	}
+}
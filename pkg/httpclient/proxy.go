@@ -0,0 +1,73 @@
+package httpclient
+
+import (
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+)
+
+// proxyEnvVars 设置了任一变量时只使用环境变量中的代理配置
+var proxyEnvVars = []string{"HTTPS_PROXY", "https_proxy", "HTTP_PROXY", "http_proxy"}
+
+// ProxyFunc 返回 Transport 的代理选择函数：设置了 HTTPS_PROXY 等环境变量时按环境变量选择
+// (包括 NO_PROXY)，否则使用系统代理设置 (Windows 的 Internet 选项，即 WinHTTP 读取的当前用户配置)
+// 不支持代理自动配置脚本 (PAC)
+func ProxyFunc() func(*http.Request) (*url.URL, error) {
+	return func(req *http.Request) (*url.URL, error) {
+		for _, name := range proxyEnvVars {
+			if os.Getenv(name) != "" {
+				return http.ProxyFromEnvironment(req)
+			}
+		}
+		proxy, bypass, ok := systemProxy()
+		if !ok {
+			return nil, nil
+		}
+		return selectProxy(proxy, bypass, req.URL)
+	}
+}
+
+// selectProxy 按 Windows 代理设置的格式选择代理
+// proxy 为 "host:port" 或按协议区分的 "http=host:port;https=host:port"；
+// bypass 为以分号或空白分隔的主机模式，支持 * 通配符，"<local>" 表示不含点的主机名
+func selectProxy(proxy, bypass string, u *url.URL) (*url.URL, error) {
+	host := strings.ToLower(u.Hostname())
+	for _, pattern := range strings.FieldsFunc(bypass, isListSep) {
+		pattern = strings.ToLower(pattern)
+		if pattern == "<local>" {
+			if !strings.Contains(host, ".") {
+				return nil, nil
+			}
+			continue
+		}
+		if ok, _ := path.Match(pattern, host); ok {
+			return nil, nil
+		}
+	}
+
+	var chosen string
+	for _, entry := range strings.FieldsFunc(proxy, isListSep) {
+		scheme, addr, found := strings.Cut(entry, "=")
+		switch {
+		case !found:
+			if chosen == "" {
+				chosen = entry
+			}
+		case strings.EqualFold(scheme, u.Scheme):
+			chosen = addr
+		}
+	}
+	if chosen == "" {
+		return nil, nil
+	}
+	if !strings.Contains(chosen, "://") {
+		chosen = "http://" + chosen
+	}
+	return url.Parse(chosen)
+}
+
+func isListSep(r rune) bool {
+	return r == ';' || r == ' ' || r == '\t' || r == '\r' || r == '\n'
+}
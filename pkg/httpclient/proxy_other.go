@@ -0,0 +1,8 @@
+//go:build !windows
+
+package httpclient
+
+// systemProxy 其他平台的系统代理通过环境变量配置，由 ProxyFunc 处理
+func systemProxy() (proxy, bypass string, ok bool) {
+	return "", "", false
+}
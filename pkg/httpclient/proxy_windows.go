@@ -0,0 +1,41 @@
+//go:build windows
+
+package httpclient
+
+import (
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+var (
+	procGetIEProxyConfig = windows.NewLazySystemDLL("winhttp.dll").NewProc("WinHttpGetIEProxyConfigForCurrentUser")
+	procGlobalFree       = windows.NewLazySystemDLL("kernel32.dll").NewProc("GlobalFree")
+)
+
+// ieProxyConfig WINHTTP_CURRENT_USER_IE_PROXY_CONFIG
+type ieProxyConfig struct {
+	autoDetect    int32
+	autoConfigURL *uint16
+	proxy         *uint16
+	proxyBypass   *uint16
+}
+
+// systemProxy 读取当前用户的 Internet 选项代理设置 (手动配置的代理服务器与例外列表)
+func systemProxy() (proxy, bypass string, ok bool) {
+	if procGetIEProxyConfig.Find() != nil {
+		return "", "", false
+	}
+	var cfg ieProxyConfig
+	if r, _, _ := procGetIEProxyConfig.Call(uintptr(unsafe.Pointer(&cfg))); r == 0 {
+		return "", "", false
+	}
+	for _, p := range []*uint16{cfg.autoConfigURL, cfg.proxy, cfg.proxyBypass} {
+		if p != nil {
+			defer procGlobalFree.Call(uintptr(unsafe.Pointer(p)))
+		}
+	}
+	proxy = windows.UTF16PtrToString(cfg.proxy)
+	bypass = windows.UTF16PtrToString(cfg.proxyBypass)
+	return proxy, bypass, proxy != ""
+}
@@ -0,0 +1,89 @@
+// Package initpayload 连接建立后自动发送的初始化数据 (例如调制解调器的 "ATE0\r\n")
+//
+// 设置按连接类型生效 (默认只用于串口)，避免同一段数据被发送到 UDP、J-Link RTT 等无关的目标；
+// 发送在延迟之后进行，连接在此之前关闭时取消。
+package initpayload
+
+import (
+	"fmt"
+	"time"
+
+	"serial-assistant/pkg/connspec"
+	"serial-assistant/pkg/input"
+	"serial-assistant/pkg/settings"
+)
+
+// MaxDelay 连接建立后等待的上限
+const MaxDelay = time.Minute
+
+// kinds 可以发送初始化数据的连接类型；SLCAN 的串口由 CAN 协议占用，不在其中
+var kinds = []connspec.Kind{connspec.Serial, connspec.JLink, connspec.TcpClient,
+	connspec.TcpServer, connspec.Udp, connspec.Virtual}
+
+// ParseKinds 解析连接类型列表，去除重复项
+func ParseKinds(names []string) ([]connspec.Kind, error) {
+	var out []connspec.Kind
+	seen := make(map[connspec.Kind]bool)
+	for _, name := range names {
+		k := connspec.Kind(name)
+		if !known(k) {
+			return nil, fmt.Errorf("unknown connection type %q", name)
+		}
+		if !seen[k] {
+			seen[k] = true
+			out = append(out, k)
+		}
+	}
+	return out, nil
+}
+
+func known(k connspec.Kind) bool {
+	for _, v := range kinds {
+		if v == k {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate 检查设置：延迟范围、连接类型与数据格式
+func Validate(cfg settings.InitPayload) error {
+	if cfg.DelayMs < 0 || time.Duration(cfg.DelayMs)*time.Millisecond > MaxDelay {
+		return fmt.Errorf("init delay must be between 0 and %d ms, got %d", MaxDelay.Milliseconds(), cfg.DelayMs)
+	}
+	for _, k := range cfg.Kinds {
+		if !known(k) {
+			return fmt.Errorf("unknown connection type %q", k)
+		}
+	}
+	if _, err := input.Decode(cfg.Data, cfg.Hex); err != nil {
+		return fmt.Errorf("invalid hex payload: %v", err)
+	}
+	return nil
+}
+
+// Plan 返回 kind 类型的连接建立后要发送的数据；cfg 为 nil、不用于该类型或数据为空时返回 nil
+func Plan(cfg *settings.InitPayload, kind connspec.Kind) ([]byte, error) {
+	if cfg == nil || !cfg.AppliesTo(kind) {
+		return nil, nil
+	}
+	payload, err := input.Decode(cfg.Data, cfg.Hex)
+	if err != nil || len(payload) == 0 {
+		return nil, err
+	}
+	return payload, nil
+}
+
+// Schedule 在新的 goroutine 中等待 delay 后调用 send；stop 先关闭时不调用
+func Schedule(stop <-chan struct{}, delay time.Duration, send func()) {
+	go func() {
+		t := time.NewTimer(delay)
+		defer t.Stop()
+		select {
+		case <-stop:
+			return
+		case <-t.C:
+		}
+		send()
+	}()
+}
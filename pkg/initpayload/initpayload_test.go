@@ -0,0 +1,94 @@
+package initpayload
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"serial-assistant/pkg/connspec"
+	"serial-assistant/pkg/settings"
+)
+
+func TestPlanKinds(t *testing.T) {
+	cfg := &settings.InitPayload{Data: "ATE0\r\n"}
+	// 未指定类型时只用于串口
+	if p, err := Plan(cfg, connspec.Serial); err != nil || string(p) != "ATE0\r\n" {
+		t.Errorf("Serial: %q, %v", p, err)
+	}
+	for _, k := range []connspec.Kind{connspec.Udp, connspec.JLink, connspec.Virtual, connspec.TcpServer} {
+		if p, _ := Plan(cfg, k); p != nil {
+			t.Errorf("Default payload sent to %s", k)
+		}
+	}
+
+	cfg = &settings.InitPayload{Data: "01 02", Hex: true, Kinds: []connspec.Kind{connspec.TcpServer}}
+	if p, err := Plan(cfg, connspec.TcpServer); err != nil || !bytes.Equal(p, []byte{1, 2}) {
+		t.Errorf("TCP server: %x, %v", p, err)
+	}
+	if p, _ := Plan(cfg, connspec.Serial); p != nil {
+		t.Error("Payload sent to a type that was not selected")
+	}
+
+	if p, err := Plan(nil, connspec.Serial); p != nil || err != nil {
+		t.Errorf("nil config: %q, %v", p, err)
+	}
+	if p, err := Plan(&settings.InitPayload{Data: "zz", Hex: true}, connspec.Serial); p != nil || err == nil {
+		t.Errorf("Bad hex: %q, %v", p, err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	if err := Validate(settings.InitPayload{Data: "AT\r", DelayMs: 200, Kinds: []connspec.Kind{connspec.Serial}}); err != nil {
+		t.Error(err)
+	}
+	bad := []settings.InitPayload{
+		{Data: "AT", DelayMs: -1},
+		{Data: "AT", DelayMs: int(MaxDelay.Milliseconds()) + 1},
+		{Data: "0", Hex: true},
+		{Data: "AT", Kinds: []connspec.Kind{"modem"}},
+	}
+	for i, c := range bad {
+		if err := Validate(c); err == nil {
+			t.Errorf("case %d: expected an error", i)
+		}
+	}
+}
+
+func TestParseKinds(t *testing.T) {
+	kinds, err := ParseKinds([]string{"serial", "tcp-server", "serial"})
+	if err != nil || len(kinds) != 2 || kinds[0] != connspec.Serial || kinds[1] != connspec.TcpServer {
+		t.Errorf("ParseKinds = %v, %v", kinds, err)
+	}
+	for _, name := range []string{"SERIAL", "slcan"} {
+		if _, err := ParseKinds([]string{name}); err == nil {
+			t.Errorf("ParseKinds(%q): expected an error", name)
+		}
+	}
+}
+
+func TestScheduleDelay(t *testing.T) {
+	stop := make(chan struct{})
+	sent := make(chan time.Time, 1)
+	start := time.Now()
+	Schedule(stop, 30*time.Millisecond, func() { sent <- time.Now() })
+	select {
+	case at := <-sent:
+		if at.Sub(start) < 30*time.Millisecond {
+			t.Errorf("Sent after %v, before the delay", at.Sub(start))
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Payload not sent")
+	}
+}
+
+func TestScheduleCanceled(t *testing.T) {
+	stop := make(chan struct{})
+	sent := make(chan struct{}, 1)
+	Schedule(stop, 30*time.Millisecond, func() { sent <- struct{}{} })
+	close(stop)
+	select {
+	case <-sent:
+		t.Error("Payload sent after the connection closed")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
@@ -0,0 +1,304 @@
+package input
+
+import (
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"serial-assistant/pkg/apperr"
+)
+
+// HexDumpFormat ParseHexDump 识别出的文本格式
+type HexDumpFormat string
+
+const (
+	// FormatOffset 每行以偏移量开头：hexdump -C、xxd、Wireshark 的 "Copy as Hex + ASCII Dump"；
+	// ASCII 栏被忽略，hexdump -C 的 "*" 行 (重复上一行) 按下一行的偏移量展开
+	FormatOffset HexDumpFormat = "offset"
+	// FormatStream 连续的十六进制字符串，例如 Wireshark 的 "Copy as Hex Stream"
+	FormatStream HexDumpFormat = "stream"
+	// FormatPairs 以空白、冒号、短横线或逗号分隔的两位十六进制数
+	FormatPairs HexDumpFormat = "pairs"
+	// FormatCArray C 数组语法，例如 uint8_t buf[] = {0x01, 0x02};
+	FormatCArray HexDumpFormat = "c-array"
+)
+
+// HexDump ParseHexDump 的结果
+type HexDump struct {
+	Data   []byte        `json:"data"`
+	Count  int           `json:"count"`
+	Format HexDumpFormat `json:"format"`
+}
+
+// HexDumpError 无法解析的行，Line 从 1 开始
+type HexDumpError struct {
+	Line    int
+	Content string
+	Reason  string
+}
+
+func (e *HexDumpError) Error() string {
+	return fmt.Sprintf("line %d: %s: %q", e.Line, e.Reason, e.Content)
+}
+
+var (
+	offsetToken = regexp.MustCompile(`^[0-9A-Fa-f]{4,8}:?$`)
+	hexRun      = regexp.MustCompile(`^(?:[0-9A-Fa-f]{2})+$`)
+	cComment    = regexp.MustCompile(`//.*$`)
+	cByte       = regexp.MustCompile(`\b0[xX][0-9A-Fa-f]{1,2}\b`)
+)
+
+// dumpLine 一行非空文本及其行号
+type dumpLine struct {
+	no   int
+	text string
+}
+
+// ParseHexDump 将粘贴的十六进制转储文本还原为字节，支持的格式见 HexDumpFormat
+// 格式由整段文本决定，不同格式的行混在一起时返回 *HexDumpError，指出第一个不符合的行
+func ParseHexDump(text string) (HexDump, error) {
+	var lines []dumpLine
+	for i, l := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n") {
+		if l = strings.TrimRight(l, " \t\r"); strings.TrimSpace(l) != "" {
+			lines = append(lines, dumpLine{no: i + 1, text: l})
+		}
+	}
+	if len(lines) == 0 {
+		return HexDump{}, ErrEmpty
+	}
+
+	var (
+		data   []byte
+		format HexDumpFormat
+		err    error
+	)
+	switch {
+	case isOffsetLine(lines[0].text):
+		format = FormatOffset
+		data, err = parseOffsetDump(lines)
+	case cByte.MatchString(text):
+		format = FormatCArray
+		data, err = parseCArray(lines)
+	case len(strings.Fields(lines[0].text)) == 1 && len(strings.TrimSpace(lines[0].text)) > 2 && !strings.ContainsAny(lines[0].text, ":-,"):
+		format = FormatStream
+		data, err = parseStream(lines)
+	default:
+		format = FormatPairs
+		data, err = parsePairs(lines)
+	}
+	if err != nil {
+		return HexDump{}, err
+	}
+	if len(data) == 0 {
+		return HexDump{}, ErrEmpty
+	}
+	return HexDump{Data: data, Count: len(data), Format: format}, nil
+}
+
+// isOffsetLine 行首为 4-8 位十六进制偏移量且后面还有内容
+func isOffsetLine(line string) bool {
+	fields := strings.Fields(line)
+	return len(fields) >= 2 && offsetToken.MatchString(fields[0]) && (len(fields[0]) > 4 || fields[0][0] == '0' || strings.HasSuffix(fields[0], ":"))
+}
+
+// parseCArray 解析 {0x01, 0x02} 形式，允许声明、注释与跨行；大括号外的内容被忽略
+func parseCArray(lines []dumpLine) ([]byte, error) {
+	var data []byte
+	inside := !strings.Contains(joinLines(lines), "{")
+	for _, l := range lines {
+		body := cComment.ReplaceAllString(l.text, "")
+		if strings.TrimSpace(body) == "" {
+			continue
+		}
+		if !inside {
+			i := strings.Index(body, "{")
+			if i < 0 {
+				continue
+			}
+			inside = true
+			body = body[i+1:]
+		}
+		closed := false
+		if i := strings.Index(body, "}"); i >= 0 {
+			body, closed = body[:i], true
+		}
+		for _, tok := range strings.FieldsFunc(body, func(r rune) bool { return r == ',' || r == ' ' || r == '\t' }) {
+			digits, ok := cutHexPrefix(tok)
+			if !ok || len(digits) == 0 || len(digits) > 2 {
+				return nil, &HexDumpError{Line: l.no, Content: l.text, Reason: fmt.Sprintf("expected a C byte literal like 0x1f, got %q", tok)}
+			}
+			v, err := strconv.ParseUint(digits, 16, 8)
+			if err != nil {
+				return nil, &HexDumpError{Line: l.no, Content: l.text, Reason: fmt.Sprintf("invalid byte literal %q", tok)}
+			}
+			data = append(data, byte(v))
+		}
+		if closed {
+			break
+		}
+	}
+	return data, nil
+}
+
+func cutHexPrefix(tok string) (string, bool) {
+	if len(tok) > 2 && tok[0] == '0' && (tok[1] == 'x' || tok[1] == 'X') {
+		return tok[2:], true
+	}
+	return "", false
+}
+
+func joinLines(lines []dumpLine) string {
+	parts := make([]string, len(lines))
+	for i, l := range lines {
+		parts[i] = l.text
+	}
+	return strings.Join(parts, "\n")
+}
+
+// offsetRow 偏移格式的一行：偏移量与其后的十六进制字段 (含所在列，用于区分 ASCII 栏)
+type offsetRow struct {
+	line    dumpLine
+	offset  int
+	repeat  bool // hexdump -C 的 "*" 行
+	tokens  []string
+	columns []int
+}
+
+// parseOffsetDump 解析带偏移量的转储
+// 每行的字节数由相邻两行的偏移量差决定，最后一行只取位于整行 ASCII 栏左侧的字段
+func parseOffsetDump(lines []dumpLine) ([]byte, error) {
+	rows := make([]offsetRow, 0, len(lines))
+	for _, l := range lines {
+		if strings.TrimSpace(l.text) == "*" {
+			rows = append(rows, offsetRow{line: l, repeat: true})
+			continue
+		}
+		fields := strings.Fields(l.text)
+		if !offsetToken.MatchString(fields[0]) {
+			return nil, &HexDumpError{Line: l.no, Content: l.text, Reason: "expected a line starting with an offset (mixed formats?)"}
+		}
+		off, _ := strconv.ParseUint(strings.TrimSuffix(fields[0], ":"), 16, 32)
+		row := offsetRow{line: l, offset: int(off)}
+		col := strings.Index(l.text, fields[0]) + len(fields[0])
+		for _, f := range fields[1:] {
+			col = strings.Index(l.text[col:], f) + col
+			if strings.HasPrefix(f, "|") || !hexRun.MatchString(f) || len(f) > 8 {
+				break
+			}
+			row.tokens = append(row.tokens, f)
+			row.columns = append(row.columns, col)
+			col += len(f)
+		}
+		rows = append(rows, row)
+	}
+
+	// ASCII 栏左边界：完整行中最后一个十六进制字段之后的列
+	gutter := -1
+	for i := 0; i+1 < len(rows); i++ {
+		if rows[i].repeat || rows[i+1].repeat || len(rows[i].tokens) == 0 {
+			continue
+		}
+		need := rows[i+1].offset - rows[i].offset
+		n, last := 0, -1
+		for j, tok := range rows[i].tokens {
+			if n >= need {
+				break
+			}
+			n += len(tok) / 2
+			last = rows[i].columns[j] + len(tok)
+		}
+		if last > gutter {
+			gutter = last
+		}
+	}
+
+	var data []byte
+	var prev []byte
+	for i, row := range rows {
+		if row.repeat {
+			if i == 0 || i+1 >= len(rows) || rows[i+1].repeat || len(prev) == 0 {
+				return nil, &HexDumpError{Line: row.line.no, Content: row.line.text, Reason: "'*' must sit between two offset lines"}
+			}
+			for len(data) < rows[i+1].offset {
+				data = append(data, prev...)
+			}
+			continue
+		}
+		if row.offset != len(data) {
+			return nil, &HexDumpError{Line: row.line.no, Content: row.line.text,
+				Reason: fmt.Sprintf("offset 0x%x does not follow the %d bytes before it", row.offset, len(data))}
+		}
+		need := -1 // 最后一行：按 ASCII 栏边界截取
+		if i+1 < len(rows) && !rows[i+1].repeat {
+			need = rows[i+1].offset - row.offset
+		}
+		var bytes []byte
+		for j, tok := range row.tokens {
+			if need < 0 && gutter >= 0 && row.columns[j] >= gutter {
+				break
+			}
+			b, _ := hex.DecodeString(tok)
+			bytes = append(bytes, b...)
+		}
+		if need >= 0 {
+			if len(bytes) < need {
+				return nil, &HexDumpError{Line: row.line.no, Content: row.line.text,
+					Reason: fmt.Sprintf("found %d bytes but the next offset expects %d", len(bytes), need)}
+			}
+			bytes = bytes[:need]
+		}
+		data = append(data, bytes...)
+		if len(bytes) > 0 {
+			prev = bytes
+		}
+	}
+	return data, nil
+}
+
+// parseStream 解析连续十六进制字符串，允许折成多行
+func parseStream(lines []dumpLine) ([]byte, error) {
+	var data []byte
+	for _, l := range lines {
+		s := strings.TrimSpace(l.text)
+		if strings.ContainsAny(s, " \t") || !hexRun.MatchString(s) {
+			return nil, &HexDumpError{Line: l.no, Content: l.text, Reason: "expected a continuous hex stream with an even number of digits"}
+		}
+		b, _ := hex.DecodeString(s)
+		data = append(data, b...)
+	}
+	return data, nil
+}
+
+// parsePairs 解析以空白、冒号、短横线或逗号分隔的两位十六进制数
+func parsePairs(lines []dumpLine) ([]byte, error) {
+	var data []byte
+	for _, l := range lines {
+		for _, tok := range strings.FieldsFunc(l.text, func(r rune) bool {
+			return r == ' ' || r == '\t' || r == ':' || r == '-' || r == ','
+		}) {
+			if len(tok) != 2 || !hexRun.MatchString(tok) {
+				return nil, &HexDumpError{Line: l.no, Content: l.text, Reason: fmt.Sprintf("expected two hex digits, got %q", tok)}
+			}
+			b, _ := hex.DecodeString(tok)
+			data = append(data, b...)
+		}
+	}
+	return data, nil
+}
+
+// HexDump 解析十六进制转储文本 (见 ParseHexDump) 并检查大小
+func (l Limits) HexDump(s string) (HexDump, error) {
+	dump, err := ParseHexDump(s)
+	if err == ErrEmpty {
+		return HexDump{}, err
+	}
+	if err != nil {
+		return HexDump{}, apperr.Wrap(apperr.InvalidPayload, err, "invalid hex dump")
+	}
+	if err := l.Check(dump.Data); err != nil {
+		return HexDump{}, err
+	}
+	return dump, nil
+}
@@ -0,0 +1,203 @@
+package input
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// 以下样本取自各工具的实际输出，粘贴时常见的缩进、CRLF 与尾随空白均保留
+func TestParseHexDump(t *testing.T) {
+	hello := []byte("Hello, serial-mate!\n")
+	tests := []struct {
+		name   string
+		text   string
+		want   []byte
+		format HexDumpFormat
+	}{
+		{
+			name: "hexdump -C",
+			text: "00000000  48 65 6c 6c 6f 2c 20 73  65 72 69 61 6c 2d 6d 61  |Hello, serial-ma|\n" +
+				"00000010  74 65 21 0a                                       |te!.|\n" +
+				"00000014\n",
+			want:   hello,
+			format: FormatOffset,
+		},
+		{
+			name: "hexdump -C with repeated lines",
+			text: "00000000  00 00 00 00 00 00 00 00  00 00 00 00 00 00 00 00  |................|\n" +
+				"*\n" +
+				"00000030  ff 01                                             |..|\n" +
+				"00000032\n",
+			want:   append(make([]byte, 48), 0xFF, 0x01),
+			format: FormatOffset,
+		},
+		{
+			name: "hexdump -C ASCII gutter that looks like hex",
+			text: "00000000  61 62 63 64 65 66 20 31  32 33 34 35 36 37 38 39  |abcdef 123456789|\n" +
+				"00000010  61 62                                             |ab|\n",
+			want:   []byte("abcdef 123456789ab"),
+			format: FormatOffset,
+		},
+		{
+			name: "xxd",
+			text: "00000000: 4865 6c6c 6f2c 2073 6572 6961 6c2d 6d61  Hello, serial-ma\n" +
+				"00000010: 7465 210a                                te!.\n",
+			want:   hello,
+			format: FormatOffset,
+		},
+		{
+			name: "wireshark hex + ASCII dump",
+			text: "0000   45 00 00 1c 1c 46 40 00 40 11 b1 e6 c0 a8 01 0a   E....F@.@.......\r\n" +
+				"0010   c0 a8 01 01 ca fe                                 ......\r\n",
+			want: []byte{0x45, 0x00, 0x00, 0x1c, 0x1c, 0x46, 0x40, 0x00, 0x40, 0x11, 0xb1, 0xe6, 0xc0, 0xa8, 0x01, 0x0a,
+				0xc0, 0xa8, 0x01, 0x01, 0xca, 0xfe},
+			format: FormatOffset,
+		},
+		{
+			name: "wireshark legacy hex dump, last line ASCII is hex-like",
+			text: "0000  de ad be ef 00 01 02 03  04 05 06 07 08 09 0a 0b   ........ ........\n" +
+				"0010  ca fe                                              ..\n",
+			want:   []byte{0xde, 0xad, 0xbe, 0xef, 0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 0xca, 0xfe},
+			format: FormatOffset,
+		},
+		{
+			name:   "wireshark hex stream",
+			text:   "48656c6c6f2c2073657269616c2d6d617465210a",
+			want:   hello,
+			format: FormatStream,
+		},
+		{
+			name:   "hex stream wrapped by a mail client",
+			text:   "48656c6c6f2c2073657269616c2d\n6d617465210a\n",
+			want:   hello,
+			format: FormatStream,
+		},
+		{name: "space separated", text: "  01 02 0A ff  ", want: []byte{1, 2, 0x0A, 0xFF}, format: FormatPairs},
+		{name: "colon separated", text: "de:ad:be:ef", want: []byte{0xde, 0xad, 0xbe, 0xef}, format: FormatPairs},
+		{name: "MAC style dashes", text: "00-1A-2B-3C-4D-5E", want: []byte{0x00, 0x1a, 0x2b, 0x3c, 0x4d, 0x5e}, format: FormatPairs},
+		{name: "multi-line pairs", text: "01 02\r\n03 04\r\n", want: []byte{1, 2, 3, 4}, format: FormatPairs},
+		{name: "single byte", text: "7e", want: []byte{0x7e}, format: FormatPairs},
+		{
+			name:   "C array literal",
+			text:   "{0x01, 0x02, 0xA0, 0xff}",
+			want:   []byte{1, 2, 0xA0, 0xFF},
+			format: FormatCArray,
+		},
+		{
+			name: "C declaration with comments",
+			text: "static const uint8_t frame[6] = {\n" +
+				"    0xAA, 0x55, // header\n" +
+				"    0x02, 0x01, 0x2,\n" +
+				"    0x0F,\n" +
+				"};\n",
+			want:   []byte{0xAA, 0x55, 0x02, 0x01, 0x02, 0x0F},
+			format: FormatCArray,
+		},
+		{name: "bare 0x list", text: "0x10 0x20 0x30", want: []byte{0x10, 0x20, 0x30}, format: FormatCArray},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseHexDump(tt.text)
+			if err != nil {
+				t.Fatalf("ParseHexDump failed: %v", err)
+			}
+			if !bytes.Equal(got.Data, tt.want) {
+				t.Errorf("Data = % x\nwant   % x", got.Data, tt.want)
+			}
+			if got.Count != len(tt.want) || got.Format != tt.format {
+				t.Errorf("Count = %d, Format = %s; want %d, %s", got.Count, got.Format, len(tt.want), tt.format)
+			}
+		})
+	}
+}
+
+func TestParseHexDumpErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		line int
+		msg  string
+	}{
+		{
+			name: "pairs followed by an offset line",
+			text: "01 02 03\n00000010  04 05\n",
+			line: 2, msg: "00000010",
+		},
+		{
+			name: "offset dump followed by plain pairs",
+			text: "00000000  01 02 03 04 05 06 07 08  09 0a 0b 0c 0d 0e 0f 10  |................|\n\n01 02 03\n",
+			line: 3, msg: "offset",
+		},
+		{
+			name: "offset jumps past the bytes on the line",
+			text: "0000  01 02 03 04\n0004  05 06\n0010  07\n",
+			line: 2, msg: "expects 12",
+		},
+		{
+			name: "offset does not follow the data",
+			text: "0000  01 02 03 04\n0008  05 06\n",
+			line: 1, msg: "expects 8",
+		},
+		{
+			name: "offset moves backwards",
+			text: "0010  01 02\n0000  05 06\n",
+			line: 1, msg: "0x10",
+		},
+		{
+			name: "short line",
+			text: "0000  01 02\n0004  05 06\n",
+			line: 1, msg: "expects 4",
+		},
+		{
+			name: "C array mixed with bare hex",
+			text: "{0x01, 0x02,\n 03, 0x04}",
+			line: 2, msg: `"03"`,
+		},
+		{
+			name: "odd hex stream",
+			text: "48656c6c6f\n48656c6c6\n",
+			line: 2, msg: "even number",
+		},
+		{
+			name: "stream then pairs",
+			text: "48656c6c6f\n20 21\n",
+			line: 2, msg: "continuous",
+		},
+		{
+			name: "three-digit pair",
+			text: "01 02 003",
+			line: 1, msg: `"003"`,
+		},
+		{
+			name: "dangling repeat marker",
+			text: "00000000  01 02 03 04  |....|\n*\n",
+			line: 2, msg: "'*'",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseHexDump(tt.text)
+			var de *HexDumpError
+			if !errors.As(err, &de) {
+				t.Fatalf("err = %v, want *HexDumpError", err)
+			}
+			if de.Line != tt.line || !strings.Contains(err.Error(), tt.msg) {
+				t.Errorf("err = %v, want line %d mentioning %s", err, tt.line, tt.msg)
+			}
+		})
+	}
+	if _, err := ParseHexDump(" \n\t\n"); err != ErrEmpty {
+		t.Errorf("blank input: err = %v, want ErrEmpty", err)
+	}
+}
+
+func TestLimitsHexDump(t *testing.T) {
+	if _, err := (Limits{MaxBytes: 2}).HexDump("01 02 03"); err == nil {
+		t.Error("HexDump should enforce the size limit")
+	}
+	if dump, err := (Limits{}).HexDump("01 02 03"); err != nil || dump.Count != 3 {
+		t.Errorf("HexDump = %+v, %v", dump, err)
+	}
+}
@@ -0,0 +1,119 @@
+// Package input 校验从前端传入的发送数据：大小上限、空数据、文本模式下的 UTF-8 合法性以及十六进制解析
+package input
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"serial-assistant/pkg/apperr"
+)
+
+// DefaultMaxBytes 默认的单次发送大小上限
+const DefaultMaxBytes = 4 << 20
+
+// UTF8Policy 文本模式下遇到不合法 UTF-8 时的处理方式
+type UTF8Policy string
+
+const (
+	// UTF8Reject 拒绝发送 (默认)
+	UTF8Reject UTF8Policy = "reject"
+	// UTF8Replace 将不合法的字节序列替换为 U+FFFD 后发送
+	UTF8Replace UTF8Policy = "replace"
+)
+
+// ParseUTF8Policy 解析 UTF-8 处理方式，空字符串视为 UTF8Reject
+func ParseUTF8Policy(name string) (UTF8Policy, error) {
+	switch UTF8Policy(name) {
+	case "", UTF8Reject:
+		return UTF8Reject, nil
+	case UTF8Replace:
+		return UTF8Replace, nil
+	default:
+		return "", fmt.Errorf("unknown UTF-8 policy %q (expected reject or replace)", name)
+	}
+}
+
+// ErrEmpty 发送数据为空
+var ErrEmpty = apperr.New(apperr.EmptyPayload, "nothing to send")
+
+// Limits 发送数据的校验规则，零值表示使用 DefaultMaxBytes 与 UTF8Reject
+type Limits struct {
+	MaxBytes int        `json:"maxBytes"`
+	UTF8     UTF8Policy `json:"utf8"`
+}
+
+func (l Limits) maxBytes() int {
+	if l.MaxBytes <= 0 {
+		return DefaultMaxBytes
+	}
+	return l.MaxBytes
+}
+
+// Check 检查字节数据是否为空或超过大小上限
+func (l Limits) Check(data []byte) error {
+	return l.checkLen(len(data))
+}
+
+func (l Limits) checkLen(n int) error {
+	if n == 0 {
+		return ErrEmpty
+	}
+	if max := l.maxBytes(); n > max {
+		return apperr.New(apperr.PayloadTooLarge, "payload is %d bytes, limit is %d", n, max)
+	}
+	return nil
+}
+
+// Text 校验文本模式的发送数据并转换为字节
+// 前端字符串中孤立的 UTF-16 代理项在跨越绑定边界时已被替换为 U+FFFD，
+// 因此 UTF8Reject 同时拒绝 U+FFFD，UTF8Replace 则保留它
+func (l Limits) Text(s string) ([]byte, error) {
+	// 先检查长度，避免为超大字符串做转换
+	if err := l.checkLen(len(s)); err != nil {
+		return nil, err
+	}
+	if l.UTF8 == UTF8Replace {
+		data := []byte(strings.ToValidUTF8(s, string(utf8.RuneError)))
+		if err := l.Check(data); err != nil {
+			return nil, err
+		}
+		return data, nil
+	}
+	for i, r := range s {
+		if r == utf8.RuneError {
+			return nil, apperr.New(apperr.InvalidPayload, "invalid UTF-8 or unpaired surrogate at byte %d", i)
+		}
+	}
+	return []byte(s), nil
+}
+
+// Hex 解析十六进制发送数据 (见 ParseHex) 并检查大小
+func (l Limits) Hex(s string) ([]byte, error) {
+	// 每个字节至少占两个字符，超长输入在解码前即可拒绝
+	if max := l.maxBytes(); len(s) > 2*max && len(strings.Join(strings.Fields(s), "")) > 2*max {
+		return nil, apperr.New(apperr.PayloadTooLarge, "hex payload exceeds %d bytes", max)
+	}
+	data, err := ParseHex(s)
+	if err != nil {
+		return nil, apperr.Wrap(apperr.InvalidPayload, err, "invalid hex payload")
+	}
+	if err := l.Check(data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// ParseHex 解码十六进制字符串，字节之间允许任意空白分隔
+func ParseHex(s string) ([]byte, error) {
+	return hex.DecodeString(strings.Join(strings.Fields(s), ""))
+}
+
+// Decode 将用户输入的数据转换为字节：isHex 时按 ParseHex 解码，否则原样使用文本
+func Decode(data string, isHex bool) ([]byte, error) {
+	if !isHex {
+		return []byte(data), nil
+	}
+	return ParseHex(data)
+}
@@ -0,0 +1,111 @@
+package input
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"serial-assistant/pkg/apperr"
+)
+
+func TestText(t *testing.T) {
+	var l Limits
+	if data, err := l.Text("héllo\r\n"); err != nil || string(data) != "héllo\r\n" {
+		t.Errorf("Unexpected result %q, %v", data, err)
+	}
+	if _, err := l.Text(""); err != ErrEmpty {
+		t.Errorf("Expected ErrEmpty, got %v", err)
+	}
+
+	for _, s := range []string{"bad \xff byte", "lone surrogate �"} {
+		if _, err := l.Text(s); apperr.CodeOf(err) != apperr.InvalidPayload {
+			t.Errorf("Text(%q): expected INVALID_PAYLOAD, got %v", s, err)
+		}
+	}
+
+	l.UTF8 = UTF8Replace
+	if data, err := l.Text("a\xffb"); err != nil || string(data) != "a�b" {
+		t.Errorf("Expected replacement, got %q, %v", data, err)
+	}
+}
+
+func TestSizeLimit(t *testing.T) {
+	l := Limits{MaxBytes: 4}
+	if _, err := l.Text("12345"); apperr.CodeOf(err) != apperr.PayloadTooLarge {
+		t.Errorf("Expected PAYLOAD_TOO_LARGE, got %v", err)
+	}
+	if _, err := l.Hex("01 02 03 04 05"); apperr.CodeOf(err) != apperr.PayloadTooLarge {
+		t.Errorf("Expected PAYLOAD_TOO_LARGE for hex, got %v", err)
+	}
+	if data, err := l.Hex(" 01 02\t03\n04 "); err != nil || !bytes.Equal(data, []byte{1, 2, 3, 4}) {
+		t.Errorf("Unexpected hex result % X, %v", data, err)
+	}
+	if err := (Limits{}).Check(make([]byte, DefaultMaxBytes+1)); apperr.CodeOf(err) != apperr.PayloadTooLarge {
+		t.Errorf("Expected default limit to apply, got %v", err)
+	}
+}
+
+func TestHexErrors(t *testing.T) {
+	var l Limits
+	for _, s := range []string{"0", "zz", "0x12", "  "} {
+		if _, err := l.Hex(s); err == nil {
+			t.Errorf("Hex(%q): expected error", s)
+		}
+	}
+	if _, err := ParseUTF8Policy("drop"); err == nil {
+		t.Error("Expected error for unknown policy")
+	}
+}
+
+func FuzzParseHex(f *testing.F) {
+	for _, s := range []string{"", "00", "aa 55 01", "0x12", "\xff\xfe", "1 2 3"} {
+		f.Add(s)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		data, err := ParseHex(s)
+		if err != nil {
+			return
+		}
+		if got := len(strings.Join(strings.Fields(s), "")); got != 2*len(data) {
+			t.Errorf("ParseHex(%q) decoded %d bytes from %d hex digits", s, len(data), got)
+		}
+	})
+}
+
+func FuzzText(f *testing.F) {
+	for _, s := range []string{"", "AT\r\n", "\xed\xa0\x80", "�", "é"} {
+		f.Add(s, false)
+	}
+	f.Fuzz(func(t *testing.T, s string, replace bool) {
+		l := Limits{MaxBytes: 64}
+		if replace {
+			l.UTF8 = UTF8Replace
+		}
+		data, err := l.Text(s)
+		if err != nil {
+			return
+		}
+		if !utf8.Valid(data) || len(data) == 0 || len(data) > l.MaxBytes {
+			t.Errorf("Text(%q) accepted %q", s, data)
+		}
+	})
+}
+
+func TestDecode(t *testing.T) {
+	if data, err := Decode("ATE0\r\n", false); err != nil || string(data) != "ATE0\r\n" {
+		t.Errorf("Text: %q, %v", data, err)
+	}
+	if data, err := Decode("01 02\tff\n10", true); err != nil || !bytes.Equal(data, []byte{0x01, 0x02, 0xFF, 0x10}) {
+		t.Errorf("Hex: %x, %v", data, err)
+	}
+	// 文本模式不解释十六进制
+	if data, _ := Decode("0a", false); string(data) != "0a" {
+		t.Errorf("Text mode decoded hex: %q", data)
+	}
+	for _, s := range []string{"0", "zz", "0 1 2"} {
+		if _, err := Decode(s, true); err == nil {
+			t.Errorf("Decode(%q, true): expected an error", s)
+		}
+	}
+}
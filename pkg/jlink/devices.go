@@ -0,0 +1,284 @@
+package jlink
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unsafe"
+)
+
+// DeviceInfo J-Link 设备数据库中的一个芯片
+type DeviceInfo struct {
+	Name      string `json:"name"`
+	Vendor    string `json:"vendor"`
+	Core      string `json:"core"`
+	FlashSize uint32 `json:"flashSize"` // 字节，未知时为 0
+}
+
+// deviceInfoC 对应 DLL 中的 JLINKARM_DEVICE_INFO 结构体（仅使用前面的字段）
+// 字段按 C 的自然对齐排列，SizeOfStruct 告诉 DLL 我们能接收的大小
+type deviceInfoC struct {
+	SizeOfStruct uint32
+	sName        *byte
+	CoreId       uint32
+	FlashAddr    uint32
+	RAMAddr      uint32
+	EndianMode   byte
+	FlashSize    uint32
+	RAMSize      uint32
+	sManu        *byte
+}
+
+// coreNames 常见内核 ID 与名称的对应关系
+var coreNames = map[uint32]string{
+	0x010000FF: "Cortex-M1",
+	0x030000FF: "Cortex-M3",
+	0x060000FF: "Cortex-M0",
+	0x0E0000FF: "Cortex-M4",
+	0x0E0100FF: "Cortex-M7",
+}
+
+// deviceCatalog 缓存已解析的设备列表，成功加载一次后重复查询无需再次访问 DLL
+var deviceCatalog struct {
+	sync.Mutex
+	devices []DeviceInfo
+}
+
+// Devices 返回 DLL 内置设备列表与 JLinkDevices.xml 合并后的结果（按名称排序）
+// 结果会被缓存；只有成功得到非空列表时才缓存，失败时下次调用会重试
+func (jl *JLinkWrapper) Devices() []DeviceInfo {
+	deviceCatalog.Lock()
+	defer deviceCatalog.Unlock()
+
+	if deviceCatalog.devices != nil {
+		return deviceCatalog.devices
+	}
+
+	devices := jl.dllDevices()
+	if libPath, err := getLibraryPath(); err == nil {
+		if extra, err := LoadDevicesXML(filepath.Join(filepath.Dir(libPath), "JLinkDevices.xml")); err == nil {
+			devices = mergeDevices(devices, extra)
+		}
+	}
+	sort.Slice(devices, func(i, j int) bool { return devices[i].Name < devices[j].Name })
+
+	if len(devices) > 0 {
+		deviceCatalog.devices = devices
+	}
+	return devices
+}
+
+// dllDevices 通过 JLINKARM_DEVICE_GetInfo 枚举 DLL 内置的设备
+func (jl *JLinkWrapper) dllDevices() []DeviceInfo {
+	if jl.apiDeviceGetInfo == nil {
+		return nil
+	}
+
+	if jl.gate.acquire(false) != nil {
+		return nil
+	}
+	defer jl.gate.release()
+
+	// index = -1 时返回设备总数
+	count := jl.apiDeviceGetInfo(-1, 0)
+	if count <= 0 {
+		return nil
+	}
+
+	devices := make([]DeviceInfo, 0, count)
+	for i := 0; i < count; i++ {
+		info := deviceInfoC{SizeOfStruct: uint32(unsafe.Sizeof(deviceInfoC{}))}
+		if jl.apiDeviceGetInfo(i, uintptr(unsafe.Pointer(&info))) < 0 {
+			continue
+		}
+		name := goString(info.sName)
+		if name == "" {
+			continue
+		}
+		core, ok := coreNames[info.CoreId]
+		if !ok {
+			core = fmt.Sprintf("0x%08X", info.CoreId)
+		}
+		devices = append(devices, DeviceInfo{
+			Name:      name,
+			Vendor:    goString(info.sManu),
+			Core:      core,
+			FlashSize: info.FlashSize,
+		})
+	}
+	return devices
+}
+
+// goString 读取 DLL 返回的以 NUL 结尾的 C 字符串
+func goString(p *byte) string {
+	if p == nil {
+		return ""
+	}
+	var sb strings.Builder
+	for i := 0; i < 256; i++ {
+		b := *(*byte)(unsafe.Add(unsafe.Pointer(p), i))
+		if b == 0 {
+			break
+		}
+		sb.WriteByte(b)
+	}
+	return sb.String()
+}
+
+// devicesXML JLinkDevices.xml 的结构（仅解析需要的字段）
+type devicesXML struct {
+	Devices []struct {
+		ChipInfo struct {
+			Vendor string `xml:"Vendor,attr"`
+			Name   string `xml:"Name,attr"`
+			Core   string `xml:"Core,attr"`
+		} `xml:"ChipInfo"`
+		FlashBanks []struct {
+			MaxSize string `xml:"MaxSize,attr"`
+		} `xml:"FlashBankInfo"`
+	} `xml:"Device"`
+}
+
+// LoadDevicesXML 解析 J-Link 安装目录下的 JLinkDevices.xml
+func LoadDevicesXML(path string) ([]DeviceInfo, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return parseDevicesXML(raw)
+}
+
+func parseDevicesXML(raw []byte) ([]DeviceInfo, error) {
+	var db devicesXML
+	if err := xml.Unmarshal(raw, &db); err != nil {
+		return nil, fmt.Errorf("failed to parse JLinkDevices.xml: %w", err)
+	}
+
+	devices := make([]DeviceInfo, 0, len(db.Devices))
+	for _, d := range db.Devices {
+		if d.ChipInfo.Name == "" {
+			continue
+		}
+		var flash uint32
+		for _, bank := range d.FlashBanks {
+			if size, err := strconv.ParseUint(bank.MaxSize, 0, 32); err == nil {
+				flash += uint32(size)
+			}
+		}
+		devices = append(devices, DeviceInfo{
+			Name:      d.ChipInfo.Name,
+			Vendor:    d.ChipInfo.Vendor,
+			Core:      strings.TrimPrefix(d.ChipInfo.Core, "JLINK_CORE_"),
+			FlashSize: flash,
+		})
+	}
+	return devices, nil
+}
+
+// mergeDevices 合并两个设备列表，同名设备以 extra 中的为准
+func mergeDevices(base, extra []DeviceInfo) []DeviceInfo {
+	index := make(map[string]int, len(base))
+	for i, d := range base {
+		index[strings.ToUpper(d.Name)] = i
+	}
+	for _, d := range extra {
+		if i, ok := index[strings.ToUpper(d.Name)]; ok {
+			base[i] = d
+			continue
+		}
+		index[strings.ToUpper(d.Name)] = len(base)
+		base = append(base, d)
+	}
+	return base
+}
+
+// FilterDevices 按名称过滤设备（不区分大小写），前缀匹配排在子串匹配之前
+// limit <= 0 表示不限制数量
+func FilterDevices(devices []DeviceInfo, filter string, limit int) []DeviceInfo {
+	filter = strings.ToUpper(strings.TrimSpace(filter))
+
+	var prefix, contains []DeviceInfo
+	for _, d := range devices {
+		name := strings.ToUpper(d.Name)
+		switch {
+		case strings.HasPrefix(name, filter):
+			prefix = append(prefix, d)
+		case strings.Contains(name, filter):
+			contains = append(contains, d)
+		}
+	}
+
+	result := append(prefix, contains...)
+	if limit > 0 && len(result) > limit {
+		result = result[:limit]
+	}
+	return result
+}
+
+// FindDevice 按名称精确查找设备（不区分大小写）
+func FindDevice(devices []DeviceInfo, name string) (DeviceInfo, bool) {
+	for _, d := range devices {
+		if strings.EqualFold(d.Name, name) {
+			return d, true
+		}
+	}
+	return DeviceInfo{}, false
+}
+
+// SuggestDevices 返回与 name 最接近的若干设备名，用于 "您是否想要…" 提示
+func SuggestDevices(devices []DeviceInfo, name string, limit int) []string {
+	target := strings.ToUpper(name)
+
+	type candidate struct {
+		name string
+		dist int
+	}
+	var candidates []candidate
+	for _, d := range devices {
+		upper := strings.ToUpper(d.Name)
+		dist := editDistance(target, upper)
+		// 仅考虑差异不超过名称长度三分之一的候选
+		if dist <= len(target)/3+1 || strings.HasPrefix(upper, target) {
+			candidates = append(candidates, candidate{d.Name, dist})
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].dist != candidates[j].dist {
+			return candidates[i].dist < candidates[j].dist
+		}
+		return candidates[i].name < candidates[j].name
+	})
+
+	var names []string
+	for i := 0; i < len(candidates) && i < limit; i++ {
+		names = append(names, candidates[i].name)
+	}
+	return names
+}
+
+// editDistance 计算两个字符串的 Levenshtein 编辑距离
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
@@ -0,0 +1,117 @@
+package jlink
+
+import (
+	"reflect"
+	"runtime"
+	"testing"
+	"unsafe"
+)
+
+const sampleDevicesXML = `<DataBase>
+  <Device>
+    <ChipInfo Vendor="ST" Name="STM32F407VG" Core="JLINK_CORE_CORTEX_M4" WorkRAMAddr="0x20000000" />
+    <FlashBankInfo Name="Internal Flash" BaseAddr="0x08000000" MaxSize="0x100000" AlwaysPresent="1" />
+  </Device>
+  <Device>
+    <ChipInfo Vendor="ST" Name="STM32F103C8" Core="JLINK_CORE_CORTEX_M3" />
+    <FlashBankInfo Name="Internal Flash" BaseAddr="0x08000000" MaxSize="0x10000" />
+  </Device>
+  <Device>
+    <ChipInfo Vendor="Nordic" Name="nRF52840_xxAA" Core="JLINK_CORE_CORTEX_M4" />
+  </Device>
+</DataBase>`
+
+func TestParseDevicesXML(t *testing.T) {
+	devices, err := parseDevicesXML([]byte(sampleDevicesXML))
+	if err != nil {
+		t.Fatalf("parseDevicesXML failed: %v", err)
+	}
+	expected := []DeviceInfo{
+		{Name: "STM32F407VG", Vendor: "ST", Core: "CORTEX_M4", FlashSize: 0x100000},
+		{Name: "STM32F103C8", Vendor: "ST", Core: "CORTEX_M3", FlashSize: 0x10000},
+		{Name: "nRF52840_xxAA", Vendor: "Nordic", Core: "CORTEX_M4"},
+	}
+	if !reflect.DeepEqual(devices, expected) {
+		t.Errorf("parseDevicesXML = %+v, expected %+v", devices, expected)
+	}
+
+	if _, err := parseDevicesXML([]byte("<DataBase><Device>")); err == nil {
+		t.Error("Expected error for malformed XML")
+	}
+}
+
+func TestFilterDevices(t *testing.T) {
+	devices, _ := parseDevicesXML([]byte(sampleDevicesXML))
+
+	got := FilterDevices(devices, "stm32f", 0)
+	if len(got) != 2 {
+		t.Fatalf("Expected 2 STM32F matches, got %d", len(got))
+	}
+
+	// 前缀匹配优先于子串匹配
+	devices = append(devices, DeviceInfo{Name: "F103_EVAL"})
+	got = FilterDevices(devices, "f103", 0)
+	if len(got) != 2 || got[0].Name != "F103_EVAL" || got[1].Name != "STM32F103C8" {
+		t.Fatalf("Expected prefix match before substring match, got %+v", got)
+	}
+	got = FilterDevices(devices, "nrf", 1)
+	if len(got) != 1 || got[0].Name != "nRF52840_xxAA" {
+		t.Errorf("Expected limited nRF match, got %+v", got)
+	}
+}
+
+func TestSuggestDevices(t *testing.T) {
+	devices, _ := parseDevicesXML([]byte(sampleDevicesXML))
+
+	if _, ok := FindDevice(devices, "stm32f407vg"); !ok {
+		t.Error("FindDevice should be case-insensitive")
+	}
+
+	suggestions := SuggestDevices(devices, "STM32F470VG", 3)
+	if len(suggestions) == 0 || suggestions[0] != "STM32F407VG" {
+		t.Errorf("Expected STM32F407VG as first suggestion, got %q", suggestions)
+	}
+
+	if s := SuggestDevices(devices, "ATSAMD21", 3); len(s) != 0 {
+		t.Errorf("Expected no suggestions for unrelated name, got %q", s)
+	}
+}
+
+func TestMergeDevices(t *testing.T) {
+	base := []DeviceInfo{{Name: "A", Core: "old"}, {Name: "B"}}
+	extra := []DeviceInfo{{Name: "a", Core: "new"}, {Name: "C"}}
+	merged := mergeDevices(base, extra)
+	if len(merged) != 3 || merged[0].Core != "new" || merged[2].Name != "C" {
+		t.Errorf("Unexpected merge result: %+v", merged)
+	}
+}
+
+// TestDllDevices verifies enumeration through a fake JLINKARM_DEVICE_GetInfo
+func TestDllDevices(t *testing.T) {
+	names := [][]byte{[]byte("STM32F407VG\x00"), []byte("nRF52832_xxAA\x00")}
+	manu := []byte("ST\x00")
+
+	jl := &JLinkWrapper{}
+	jl.apiDeviceGetInfo = func(index int, p uintptr) int {
+		if index < 0 {
+			return len(names)
+		}
+		info := (*deviceInfoC)(unsafe.Pointer(p))
+		info.sName = &names[index][0]
+		info.sManu = &manu[0]
+		info.CoreId = 0x0E0000FF
+		info.FlashSize = 1024
+		return 0
+	}
+
+	devices := jl.dllDevices()
+	runtime.KeepAlive(names)
+	runtime.KeepAlive(manu)
+
+	if len(devices) != 2 {
+		t.Fatalf("Expected 2 devices, got %d", len(devices))
+	}
+	if devices[0].Name != "STM32F407VG" || devices[0].Vendor != "ST" || devices[0].Core != "Cortex-M4" || devices[0].FlashSize != 1024 {
+		t.Errorf("Unexpected device: %+v", devices[0])
+	}
+}
@@ -0,0 +1,208 @@
+package jlink
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ebitengine/purego"
+)
+
+// LogLevel J-Link DLL 日志的转发级别
+type LogLevel string
+
+const (
+	// LogOff 不注册任何日志回调（默认）
+	LogOff LogLevel = "off"
+	// LogErrors 只转发 DLL 报告的错误
+	LogErrors LogLevel = "errors"
+	// LogInfo 转发错误和警告
+	LogInfo LogLevel = "info"
+	// LogDebug 额外转发 DLL 的完整调试日志 (JLINKARM_EnableLog)，数据量很大
+	LogDebug LogLevel = "debug"
+)
+
+// logLevelRank 级别由低到高的顺序
+var logLevelRank = map[LogLevel]int{LogOff: 0, LogErrors: 1, LogInfo: 2, LogDebug: 3}
+
+// ParseLogLevel 解析日志级别名称，空字符串视为 LogOff
+func ParseLogLevel(name string) (LogLevel, error) {
+	level := LogLevel(strings.ToLower(strings.TrimSpace(name)))
+	if level == "" {
+		return LogOff, nil
+	}
+	if _, ok := logLevelRank[level]; !ok {
+		return "", fmt.Errorf("未知的 J-Link 日志级别 %q (可选: off, errors, info, debug)", name)
+	}
+	return level, nil
+}
+
+// DLLLogMaxPerSec 每秒最多转发到 logCallback 的 DLL 日志条数，超出部分只写入日志文件
+const DLLLogMaxPerSec = 20
+
+// dllLog 接收 DLL 的日志回调：按级别过滤后完整写入可选的日志文件，
+// 并限流转发到 logCallback，避免输出频繁的 DLL 淹没接收区
+type dllLog struct {
+	mu    sync.Mutex
+	level LogLevel
+	file  io.Writer
+	out   LogCallback
+	now   func() time.Time
+
+	windowStart time.Time
+	count       int // 当前一秒窗口内已转发的条数
+	suppressed  int // 当前窗口内被限流丢弃的条数
+}
+
+func newDLLLog(out LogCallback) *dllLog {
+	return &dllLog{level: LogOff, out: out, now: time.Now}
+}
+
+// configure 更新级别与日志文件，file 为 nil 表示不写文件
+func (d *dllLog) configure(level LogLevel, file io.Writer) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.level = level
+	d.file = file
+}
+
+// handle 处理一条来自 kind 类回调的日志
+func (d *dllLog) handle(kind LogLevel, msg string) {
+	msg = strings.TrimRight(msg, "\r\n")
+	if msg == "" {
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if logLevelRank[d.level] < logLevelRank[kind] {
+		return
+	}
+
+	now := d.now()
+	if d.file != nil {
+		fmt.Fprintf(d.file, "%s [%s] %s\n", now.Format("2006-01-02 15:04:05.000"), kind, msg)
+	}
+	if d.out == nil {
+		return
+	}
+
+	if now.Sub(d.windowStart) >= time.Second {
+		if d.suppressed > 0 {
+			d.out(fmt.Sprintf("[J-Link] 已省略 %d 条 DLL 日志", d.suppressed))
+		}
+		d.windowStart = now
+		d.count = 0
+		d.suppressed = 0
+	}
+	if d.count >= DLLLogMaxPerSec {
+		d.suppressed++
+		return
+	}
+	d.count++
+	d.out("[J-Link] " + msg)
+}
+
+// DLL 日志回调。purego 创建的回调无法释放且数量有限，因此全进程只创建一次，
+// 通过 active 分发给当前设置了日志级别的 JLinkWrapper
+var dllLogCallbacks struct {
+	once sync.Once
+	err  error
+
+	errorOut uintptr
+	warnOut  uintptr
+	log      uintptr
+
+	mu     sync.Mutex
+	active *dllLog
+}
+
+// dispatchDLLLog 将回调收到的 C 字符串交给当前活动的 dllLog
+func dispatchDLLLog(kind LogLevel, p *byte) {
+	dllLogCallbacks.mu.Lock()
+	d := dllLogCallbacks.active
+	dllLogCallbacks.mu.Unlock()
+	if d != nil && p != nil {
+		d.handle(kind, goString(p))
+	}
+}
+
+// initDLLLogCallbacks 创建日志回调，平台不支持时 (例如 Linux 386) 返回错误而不是崩溃
+func initDLLLogCallbacks() error {
+	dllLogCallbacks.once.Do(func() {
+		defer func() {
+			if r := recover(); r != nil {
+				dllLogCallbacks.err = fmt.Errorf("当前平台不支持 DLL 日志回调: %v", r)
+			}
+		}()
+		newCallback := func(kind LogLevel) uintptr {
+			return purego.NewCallback(func(p *byte) uintptr {
+				dispatchDLLLog(kind, p)
+				return 0
+			})
+		}
+		dllLogCallbacks.errorOut = newCallback(LogErrors)
+		dllLogCallbacks.warnOut = newCallback(LogInfo)
+		dllLogCallbacks.log = newCallback(LogDebug)
+	})
+	return dllLogCallbacks.err
+}
+
+// SetLogLevel 设置 DLL 日志的转发级别，file 非 nil 时同时把日志完整写入 file
+// 旧版 DLL 缺少的日志函数会被跳过并记录一条提示，不视为错误；
+// 只有在平台无法创建回调时才返回错误，此时连接本身不受影响
+func (jl *JLinkWrapper) SetLogLevel(level LogLevel, file io.Writer) error {
+	if _, ok := logLevelRank[level]; !ok {
+		return fmt.Errorf("未知的 J-Link 日志级别 %q", level)
+	}
+	if level != LogOff {
+		if err := initDLLLogCallbacks(); err != nil {
+			return err
+		}
+	}
+
+	if err := jl.gate.acquire(false); err != nil {
+		return err
+	}
+	defer jl.gate.release()
+	jl.applyLogLevel(level, file)
+	return nil
+}
+
+// applyLogLevel 配置日志转发并向 DLL 安装或注销回调，调用方需持有驱动访问权
+func (jl *JLinkWrapper) applyLogLevel(level LogLevel, file io.Writer) {
+	if jl.dllLog == nil {
+		jl.dllLog = newDLLLog(jl.logCallback)
+	}
+	jl.dllLog.configure(level, file)
+
+	dllLogCallbacks.mu.Lock()
+	if level != LogOff {
+		dllLogCallbacks.active = jl.dllLog
+	} else if dllLogCallbacks.active == jl.dllLog {
+		dllLogCallbacks.active = nil
+	}
+	dllLogCallbacks.mu.Unlock()
+
+	var missing []string
+	install := func(api func(uintptr), name string, kind LogLevel, cb uintptr) {
+		if api == nil {
+			if logLevelRank[level] >= logLevelRank[kind] {
+				missing = append(missing, name)
+			}
+			return
+		}
+		if logLevelRank[level] < logLevelRank[kind] {
+			cb = 0 // 传入空指针注销回调
+		}
+		api(cb)
+	}
+	install(jl.apiSetErrorOutHandler, "JLINKARM_SetErrorOutHandler", LogErrors, dllLogCallbacks.errorOut)
+	install(jl.apiSetWarnOutHandler, "JLINKARM_SetWarnOutHandler", LogInfo, dllLogCallbacks.warnOut)
+	install(jl.apiEnableLog, "JLINKARM_EnableLog", LogDebug, dllLogCallbacks.log)
+	if len(missing) > 0 {
+		jl.log(fmt.Sprintf("[RTT] 当前 J-Link 库不支持 %s，已跳过对应的日志输出", strings.Join(missing, ", ")))
+	}
+}
@@ -0,0 +1,114 @@
+package jlink
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseLogLevel(t *testing.T) {
+	tests := map[string]LogLevel{
+		"":       LogOff,
+		"off":    LogOff,
+		"errors": LogErrors,
+		" Info ": LogInfo,
+		"DEBUG":  LogDebug,
+	}
+	for input, expected := range tests {
+		got, err := ParseLogLevel(input)
+		if err != nil || got != expected {
+			t.Errorf("ParseLogLevel(%q) = %q, %v; expected %q", input, got, err, expected)
+		}
+	}
+	if _, err := ParseLogLevel("verbose"); err == nil {
+		t.Error("Expected error for unknown level")
+	}
+}
+
+// TestDLLLogLevelFilter verifies messages above the configured level are dropped
+func TestDLLLogLevelFilter(t *testing.T) {
+	var out []string
+	d := newDLLLog(func(msg string) { out = append(out, msg) })
+	var file bytes.Buffer
+	d.configure(LogInfo, &file)
+
+	d.handle(LogErrors, "Could not connect to target.\n")
+	d.handle(LogInfo, "Target voltage low")
+	d.handle(LogDebug, "JLINK_ReadMem(0x20000000, 0x100)")
+
+	expected := []string{"[J-Link] Could not connect to target.", "[J-Link] Target voltage low"}
+	if strings.Join(out, "|") != strings.Join(expected, "|") {
+		t.Errorf("Expected %q, got %q", expected, out)
+	}
+	if lines := strings.Count(file.String(), "\n"); lines != 2 {
+		t.Errorf("Expected 2 lines in log file, got %d: %q", lines, file.String())
+	}
+	if !strings.Contains(file.String(), "[errors] Could not connect to target.") {
+		t.Errorf("Log file missing error entry: %q", file.String())
+	}
+}
+
+// TestDLLLogRateLimit verifies UI forwarding is capped per second while the file receives everything
+func TestDLLLogRateLimit(t *testing.T) {
+	var out []string
+	d := newDLLLog(func(msg string) { out = append(out, msg) })
+	now := time.Unix(1000, 0)
+	d.now = func() time.Time { return now }
+	var file bytes.Buffer
+	d.configure(LogDebug, &file)
+
+	for i := 0; i < DLLLogMaxPerSec+5; i++ {
+		d.handle(LogDebug, "tick")
+	}
+	if len(out) != DLLLogMaxPerSec {
+		t.Fatalf("Expected %d forwarded messages, got %d", DLLLogMaxPerSec, len(out))
+	}
+	if lines := strings.Count(file.String(), "\n"); lines != DLLLogMaxPerSec+5 {
+		t.Errorf("Expected all %d messages in file, got %d", DLLLogMaxPerSec+5, lines)
+	}
+
+	now = now.Add(time.Second)
+	d.handle(LogDebug, "next")
+	tail := out[DLLLogMaxPerSec:]
+	if len(tail) != 2 || tail[0] != "[J-Link] 已省略 5 条 DLL 日志" || tail[1] != "[J-Link] next" {
+		t.Errorf("Unexpected messages after window: %q", tail)
+	}
+}
+
+// TestSetLogLevelMissingFunctions verifies older DLLs without log functions are handled gracefully
+func TestSetLogLevelMissingFunctions(t *testing.T) {
+	var logs []string
+	jl := &JLinkWrapper{logCallback: func(msg string) { logs = append(logs, msg) }}
+	var errorHandler uintptr = 1
+	jl.apiSetErrorOutHandler = func(cb uintptr) { errorHandler = cb }
+
+	// 关闭时只注销已有的回调，不提示缺少的函数
+	if err := jl.SetLogLevel(LogOff, nil); err != nil {
+		t.Fatalf("SetLogLevel(off) failed: %v", err)
+	}
+	if errorHandler != 0 {
+		t.Error("Expected error handler to be cleared")
+	}
+	if len(logs) != 0 {
+		t.Errorf("Expected no messages for off level, got %q", logs)
+	}
+
+	if err := jl.SetLogLevel(LogInfo, nil); err != nil {
+		t.Skipf("DLL log callbacks unavailable on this platform: %v", err)
+	}
+	defer jl.SetLogLevel(LogOff, nil)
+	if errorHandler == 0 {
+		t.Error("Expected error handler to be installed")
+	}
+	if len(logs) != 1 || !strings.Contains(logs[0], "JLINKARM_SetWarnOutHandler") || strings.Contains(logs[0], "JLINKARM_EnableLog") {
+		t.Errorf("Expected a single note about the missing warn handler, got %q", logs)
+	}
+
+	// 回调收到的 C 字符串分发给当前 wrapper
+	msg := []byte("Failed to measure target voltage\x00")
+	dispatchDLLLog(LogErrors, &msg[0])
+	if last := logs[len(logs)-1]; last != "[J-Link] Failed to measure target voltage" {
+		t.Errorf("Expected dispatched DLL message, got %q", last)
+	}
+}
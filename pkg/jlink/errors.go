@@ -0,0 +1,37 @@
+package jlink
+
+import (
+	"errors"
+
+	"serial-assistant/pkg/apperr"
+)
+
+var (
+	// ErrOffsetOutOfBounds RTT 控制块中的读写偏移量超出缓冲区，通常是目标复位后控制块被重新初始化
+	ErrOffsetOutOfBounds = errors.New("RTT offset out of bounds")
+	// ErrMemoryAccess 通过探针读取目标内存失败
+	ErrMemoryAccess = errors.New("target memory access failed")
+)
+
+// TranslateError 将 J-Link 读写错误映射为带错误码与建议操作的事件
+func TranslateError(err error) apperr.Event {
+	detail := ""
+	if err != nil {
+		detail = err.Error()
+	}
+	var coded *apperr.Error
+	switch {
+	case err == nil:
+		return apperr.NewEvent(apperr.IOError, detail)
+	case errors.As(err, &coded):
+		return apperr.NewEvent(coded.Code, detail)
+	case errors.Is(err, ErrOffsetOutOfBounds):
+		return apperr.NewEvent(apperr.RTTOffsetCorrupt, detail)
+	case errors.Is(err, ErrMemoryAccess):
+		return apperr.NewEvent(apperr.ProbeLost, detail)
+	case errors.Is(err, ErrSWOReadOnly):
+		return apperr.NewEvent(apperr.ReadOnly, detail)
+	default:
+		return apperr.NewEvent(apperr.IOError, detail)
+	}
+}
@@ -0,0 +1,55 @@
+package jlink
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"unsafe"
+
+	"serial-assistant/pkg/apperr"
+)
+
+func TestTranslateError(t *testing.T) {
+	tests := []struct {
+		err  error
+		code apperr.Code
+	}{
+		{ErrSWOReadOnly, apperr.ReadOnly},
+		{errors.New("unexpected"), apperr.IOError},
+	}
+	for _, tt := range tests {
+		if ev := TranslateError(tt.err); ev.Code != tt.code {
+			t.Errorf("TranslateError(%v) = %s, want %s", tt.err, ev.Code, tt.code)
+		}
+	}
+}
+
+// 软件 RTT 读取返回的错误能被正确归类
+func TestTranslateSoftRTTErrors(t *testing.T) {
+	jl := &JLinkWrapper{
+		useSoftRTT:    true,
+		rttControlBlk: 0x20000000,
+		rttUpBuffer:   RTTBufferDesc{BufferPtr: 0x20001000, Size: 64},
+	}
+
+	// 探针无法访问目标
+	jl.apiReadMem = func(addr, size uint32, buf uintptr) int { return -1 }
+	_, err := jl.ReadRTT()
+	if ev := TranslateError(err); ev.Code != apperr.ProbeLost {
+		t.Errorf("memory read failure mapped to %s (%v)", ev.Code, err)
+	}
+
+	// 目标复位后偏移量无效
+	jl.apiReadMem = func(addr, size uint32, buf uintptr) int {
+		*(*uint32)(unsafe.Pointer(buf)) = 0xFFFF
+		return 0
+	}
+	_, err = jl.ReadRTT()
+	if ev := TranslateError(err); ev.Code != apperr.RTTOffsetCorrupt {
+		t.Errorf("bad offset mapped to %s (%v)", ev.Code, err)
+	}
+	// 读取循环仍按文本识别复位
+	if !strings.Contains(err.Error(), "offset out of bounds") {
+		t.Errorf("error text changed: %v", err)
+	}
+}
@@ -0,0 +1,94 @@
+package jlink
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrClosed 连接已关闭，排队中或之后发起的驱动调用都会返回该错误
+var ErrClosed = errors.New("J-Link connection closed")
+
+// MaxPollDelay RTT 轮询让出驱动的最长时间
+//
+// SEGGER DLL 不保证线程安全，所有 api* 调用都经过 dllGate 串行执行。交互命令
+// (内存读取、监视、复位、写入) 优先于 RTT 轮询：只要有交互命令在排队，轮询就让出，
+// 因此交互命令最多等待一次正在进行的驱动调用 (通常是一次不超过 64KB 的 RTT 读取，
+// 约数百微秒到数毫秒)。轮询的等待有上限：排队超过 MaxPollDelay 后，它会在当前调用
+// 结束后优先执行一次，所以密集的内存监视最多让 RTT 数据晚 MaxPollDelay 加一次交互
+// 命令的时间才被读出，目标端缓冲区需能容纳这段时间内产生的数据
+const MaxPollDelay = 20 * time.Millisecond
+
+// dllGate 对驱动调用进行串行化的优先级锁，零值可用
+type dllGate struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	busy    bool
+	waiting int  // 排队中的交互命令数
+	urgent  bool // 轮询已等待超过 MaxPollDelay，下一次由轮询执行
+	closed  bool
+}
+
+func (g *dllGate) init() {
+	if g.cond == nil {
+		g.cond = sync.NewCond(&g.mu)
+	}
+}
+
+// acquire 获取驱动的独占访问权，poll 表示调用来自 RTT 轮询
+// 连接关闭后立即返回 ErrClosed，包括已在排队的调用
+func (g *dllGate) acquire(poll bool) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.init()
+
+	if poll {
+		deadline := time.Now().Add(MaxPollDelay)
+		for !g.closed && (g.busy || (g.waiting > 0 && !g.urgent)) {
+			if !g.urgent && g.waiting > 0 && !time.Now().Before(deadline) {
+				g.urgent = true
+				continue
+			}
+			g.cond.Wait()
+		}
+		g.urgent = false
+	} else {
+		g.waiting++
+		for !g.closed && (g.busy || g.urgent) {
+			g.cond.Wait()
+		}
+		g.waiting--
+	}
+	if g.closed {
+		return ErrClosed
+	}
+	g.busy = true
+	return nil
+}
+
+// release 释放独占访问权并唤醒排队的调用
+func (g *dllGate) release() {
+	g.mu.Lock()
+	g.busy = false
+	g.mu.Unlock()
+	g.cond.Broadcast()
+}
+
+// shutdown 拒绝之后的全部调用并唤醒排队者，等待正在执行的调用结束后由调用方独占驱动
+// 已关闭时返回 false
+func (g *dllGate) shutdown() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.init()
+
+	if g.closed {
+		return false
+	}
+	g.closed = true
+	g.cond.Broadcast()
+	for g.busy {
+		g.cond.Wait()
+	}
+	g.busy = true
+	return true
+}
@@ -0,0 +1,210 @@
+package jlink
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeDLL 记录同时进入驱动调用的数量，用于检测并发访问
+type fakeDLL struct {
+	active  atomic.Int32
+	overlap atomic.Int32
+	calls   atomic.Int64
+}
+
+func (f *fakeDLL) enter(d time.Duration) {
+	if f.active.Add(1) > 1 {
+		f.overlap.Add(1)
+	}
+	f.calls.Add(1)
+	if d > 0 {
+		time.Sleep(d)
+	}
+	f.active.Add(-1)
+}
+
+func newFakeWrapper(f *fakeDLL, callTime time.Duration) *JLinkWrapper {
+	jl := &JLinkWrapper{readBuffer: make([]byte, 64)}
+	jl.apiRTTRead = func(_ uint32, _ uintptr, size uint32) int {
+		f.enter(callTime)
+		return 4
+	}
+	jl.apiReadMem = func(_ uint32, _ uint32, _ uintptr) int {
+		f.enter(callTime)
+		return 0
+	}
+	jl.apiReset = func() int {
+		f.enter(callTime)
+		return 0
+	}
+	jl.apiGo = func() { f.enter(0) }
+	jl.apiClose = func() { f.enter(0) }
+	return jl
+}
+
+func TestConcurrentDLLCallsAreSerialized(t *testing.T) {
+	f := &fakeDLL{}
+	jl := newFakeWrapper(f, 50*time.Microsecond)
+
+	var wg sync.WaitGroup
+	run := func(n int, call func() error) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < n; i++ {
+				if err := call(); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+	for i := 0; i < 2; i++ {
+		run(200, func() error { _, err := jl.ReadRTT(); return err })
+		run(100, func() error { _, err := jl.ReadMemory(0x20000000, 4); return err })
+		run(20, jl.Reset)
+		run(50, func() error { jl.Stats(); return nil })
+	}
+	if err := jl.AddWatch(Watch{ID: "w", Addr: 0x20000000, Size: 4, Interval: MinWatchInterval}, nil); err != nil {
+		t.Fatal(err)
+	}
+	wg.Wait()
+	jl.Close()
+
+	if n := f.overlap.Load(); n != 0 {
+		t.Errorf("%d DLL calls overlapped", n)
+	}
+	if f.calls.Load() < 2*(200+100+20*2) {
+		t.Errorf("only %d DLL calls made", f.calls.Load())
+	}
+}
+
+func TestInteractiveCommandsPreemptPolling(t *testing.T) {
+	f := &fakeDLL{}
+	jl := newFakeWrapper(f, time.Millisecond)
+	stop := make(chan struct{})
+	var polls sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		polls.Add(1)
+		go func() {
+			defer polls.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				jl.ReadRTT()
+			}
+		}()
+	}
+	defer func() {
+		close(stop)
+		polls.Wait()
+	}()
+
+	for i := 0; i < 20; i++ {
+		start := time.Now()
+		if _, err := jl.ReadMemory(0x20000000, 4); err != nil {
+			t.Fatal(err)
+		}
+		// 最多等待一次正在进行的 RTT 读取
+		if d := time.Since(start); d > 15*time.Millisecond {
+			t.Fatalf("ReadMemory waited %v behind RTT polling", d)
+		}
+	}
+}
+
+func TestPollingWaitIsBounded(t *testing.T) {
+	f := &fakeDLL{}
+	jl := newFakeWrapper(f, time.Millisecond)
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				jl.ReadMemory(0x20000000, 4)
+			}
+		}()
+	}
+	defer func() {
+		close(stop)
+		wg.Wait()
+	}()
+
+	time.Sleep(5 * time.Millisecond)
+	for i := 0; i < 5; i++ {
+		start := time.Now()
+		if _, err := jl.ReadRTT(); err != nil {
+			t.Fatal(err)
+		}
+		if d := time.Since(start); d > MaxPollDelay+50*time.Millisecond {
+			t.Fatalf("ReadRTT starved for %v", d)
+		}
+	}
+}
+
+func TestCloseCancelsQueuedCalls(t *testing.T) {
+	f := &fakeDLL{}
+	jl := newFakeWrapper(f, 0)
+	entered := make(chan struct{})
+	unblock := make(chan struct{})
+	jl.apiReset = func() int {
+		close(entered)
+		<-unblock
+		return 0
+	}
+
+	go jl.Reset()
+	<-entered
+
+	errs := make(chan error, 3)
+	go func() { _, err := jl.ReadRTT(); errs <- err }()
+	go func() { _, err := jl.ReadMemory(0, 4); errs <- err }()
+	go func() { errs <- jl.Reset() }()
+	time.Sleep(10 * time.Millisecond)
+
+	closed := make(chan struct{})
+	go func() {
+		jl.Close()
+		close(closed)
+	}()
+	for i := 0; i < 3; i++ {
+		select {
+		case err := <-errs:
+			if !errors.Is(err, ErrClosed) {
+				t.Errorf("queued call returned %v, want ErrClosed", err)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("queued calls were not cancelled by Close")
+		}
+	}
+
+	// Close 等待正在执行的调用结束后再卸载驱动
+	select {
+	case <-closed:
+		t.Fatal("Close returned while a DLL call was still running")
+	case <-time.After(10 * time.Millisecond):
+	}
+	close(unblock)
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		t.Fatal("Close did not return")
+	}
+
+	if _, err := jl.ReadMemory(0, 4); !errors.Is(err, ErrClosed) {
+		t.Errorf("ReadMemory after Close = %v", err)
+	}
+	jl.Close() // 重复关闭无副作用
+}
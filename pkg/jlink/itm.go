@@ -0,0 +1,100 @@
+package jlink
+
+// ITM (Instrumentation Trace Macrocell) 数据包解码
+// SWO 输出的是 ITM 数据包流，固件通过 ITM_SendChar 等写入刺激端口 (stimulus port)，
+// 这里只提取指定端口的软件源数据包负载，时间戳、扩展包和硬件源 (DWT) 数据包被跳过
+
+// ITM 包头
+const (
+	itmOverflow  = 0x70 // 溢出包：ITM FIFO 已满，至少一个数据包被丢弃
+	itmSyncEnd   = 0x80 // 同步包以至少 47 个 0 位后跟一个 1 位结束
+	itmSyncZeros = 5    // 同步包结束字节之前至少需要的 0x00 字节数
+)
+
+// itmState 解码器状态
+type itmState int
+
+const (
+	itmIdle         itmState = iota // 等待包头
+	itmPayload                      // 读取源数据包负载
+	itmContinuation                 // 跳过时间戳/扩展包的后续字节 (bit7 为 1 表示还有后续)
+)
+
+// ITMDecoder 从 SWO 字节流中提取单个刺激端口的数据
+// 数据包可能跨越多次读取，解码器在调用之间保留状态
+type ITMDecoder struct {
+	port uint8 // 提取的刺激端口
+
+	state     itmState
+	zeros     int   // 空闲状态下连续的 0x00 字节数，用于识别同步包
+	keep      bool  // 当前数据包的负载是否输出
+	remaining int   // 当前数据包剩余的负载字节数
+	synced    bool  // 是否已见过同步包
+	overflows int64 // 溢出包计数
+}
+
+// NewITMDecoder 创建提取指定刺激端口 (0-31) 的解码器
+func NewITMDecoder(port uint8) *ITMDecoder {
+	return &ITMDecoder{port: port & 0x1F}
+}
+
+// Decode 解码一段 SWO 数据，返回目标端口的负载字节
+func (d *ITMDecoder) Decode(data []byte) []byte {
+	var out []byte
+	for _, b := range data {
+		switch d.state {
+		case itmPayload:
+			if d.keep {
+				out = append(out, b)
+			}
+			d.remaining--
+			if d.remaining == 0 {
+				d.state = itmIdle
+			}
+		case itmContinuation:
+			if b&0x80 == 0 {
+				d.state = itmIdle
+			}
+		default:
+			out = d.header(b, out)
+		}
+	}
+	return out
+}
+
+// header 处理空闲状态下的一个字节
+func (d *ITMDecoder) header(b byte, out []byte) []byte {
+	if b == 0x00 {
+		d.zeros++
+		return out
+	}
+	zeros := d.zeros
+	d.zeros = 0
+
+	switch {
+	case b == itmSyncEnd && zeros >= itmSyncZeros:
+		d.synced = true
+	case b == itmOverflow:
+		d.overflows++
+	case b&0x03 != 0:
+		// 源数据包：bit1:0 为负载长度 (1/2/4 字节)，bit2 为 1 表示硬件源，bit7:3 为端口号
+		d.remaining = [4]int{0, 1, 2, 4}[b&0x03]
+		d.keep = b&0x04 == 0 && b>>3 == d.port
+		d.state = itmPayload
+	case b&0x80 != 0:
+		// 时间戳、扩展或全局时间戳包，后续字节同样以 bit7 表示是否继续
+		d.state = itmContinuation
+	}
+	// 其余为单字节的时间戳/扩展包，直接跳过
+	return out
+}
+
+// Overflows 返回已解码的溢出包数量，每个溢出包表示目标端至少丢失了一个数据包
+func (d *ITMDecoder) Overflows() int64 {
+	return d.overflows
+}
+
+// Synced 是否已在数据流中见过同步包
+func (d *ITMDecoder) Synced() bool {
+	return d.synced
+}
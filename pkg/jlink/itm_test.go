@@ -0,0 +1,141 @@
+package jlink
+
+import (
+	"bytes"
+	"testing"
+	"unsafe"
+)
+
+// 以下字节序列取自 STM32F4 上 ITM_SendChar 输出的 SWO 抓包
+var (
+	// 同步包 + 端口 0 的 "Hi\n"
+	itmCaptureHello = []byte{0x00, 0x00, 0x00, 0x00, 0x00, 0x80, 0x01, 'H', 0x01, 'i', 0x01, '\n'}
+	// 端口 0 的 32 位写入 ("ABCD")，之后是一个单字节本地时间戳和端口 1 的数据
+	itmCaptureWord = []byte{0x03, 'A', 'B', 'C', 'D', 0x10, 0x09, 'x', 0x01, '!'}
+)
+
+func TestITMDecodeStimulusPort0(t *testing.T) {
+	d := NewITMDecoder(0)
+	if got := d.Decode(itmCaptureHello); string(got) != "Hi\n" {
+		t.Fatalf("Decode = %q, want %q", got, "Hi\n")
+	}
+	if !d.Synced() {
+		t.Error("sync packet not recognized")
+	}
+	if got := d.Decode(itmCaptureWord); string(got) != "ABCD!" {
+		t.Fatalf("Decode = %q, want %q", got, "ABCD!")
+	}
+}
+
+func TestITMDecodeSplitAcrossReads(t *testing.T) {
+	stream := append(append([]byte{}, itmCaptureHello...), itmCaptureWord...)
+	for split := 0; split <= len(stream); split++ {
+		d := NewITMDecoder(0)
+		got := append(d.Decode(stream[:split]), d.Decode(stream[split:])...)
+		if string(got) != "Hi\nABCD!" {
+			t.Fatalf("split at %d: got %q", split, got)
+		}
+	}
+}
+
+func TestITMDecodeOverflow(t *testing.T) {
+	d := NewITMDecoder(0)
+	got := d.Decode([]byte{0x01, 'a', itmOverflow, 0x01, 'b', itmOverflow, itmOverflow})
+	if string(got) != "ab" {
+		t.Fatalf("Decode = %q, want %q", got, "ab")
+	}
+	if d.Overflows() != 3 {
+		t.Errorf("Overflows = %d, want 3", d.Overflows())
+	}
+}
+
+func TestITMDecodeSkipsNonPayloadPackets(t *testing.T) {
+	d := NewITMDecoder(0)
+	stream := []byte{
+		0xC0, 0x85, 0x02, // 多字节本地时间戳
+		0x94, 0x81, 0x80, 0x00, // 全局时间戳 1
+		0x08,       // 扩展包
+		0x05, 0x20, // DWT 硬件源包 (端口 0，bit2 置位)
+		0x0A, 0x11, 0x22, // 端口 1 的 16 位写入
+		0x02, 0x00, 'z', // 端口 0 的 16 位写入，负载中的 0x00 不是同步
+		0x01, 0x70, // 负载中的 0x70 不是溢出包
+	}
+	got := d.Decode(stream)
+	if !bytes.Equal(got, []byte{0x00, 'z', 0x70}) {
+		t.Fatalf("Decode = %x, want 007a70", got)
+	}
+	if d.Overflows() != 0 {
+		t.Errorf("Overflows = %d, want 0", d.Overflows())
+	}
+}
+
+func TestITMDecodeOtherPort(t *testing.T) {
+	d := NewITMDecoder(1)
+	if got := d.Decode(itmCaptureWord); string(got) != "x" {
+		t.Fatalf("Decode = %q, want %q", got, "x")
+	}
+}
+
+func TestParseMode(t *testing.T) {
+	for name, want := range map[string]Mode{"": ModeRTT, "rtt": ModeRTT, "swo": ModeSWO} {
+		if got, err := ParseMode(name); err != nil || got != want {
+			t.Errorf("ParseMode(%q) = %q, %v", name, got, err)
+		}
+	}
+	if _, err := ParseMode("jtag"); err == nil {
+		t.Error("ParseMode(jtag) should fail")
+	}
+}
+
+func TestStartSWOMissingSymbols(t *testing.T) {
+	jl := &JLinkWrapper{readBuffer: make([]byte, 64)}
+	if err := jl.startSWO(72000000, 0); err == nil {
+		t.Fatal("startSWO should fail without SWO entry points")
+	}
+	if jl.useSWO {
+		t.Error("wrapper switched to SWO despite the error")
+	}
+}
+
+func TestReadSWO(t *testing.T) {
+	jl := &JLinkWrapper{readBuffer: make([]byte, 64)}
+	var enabled [4]uint32
+	var flushed uint32
+	jl.apiSWOEnableTarget = func(cpu, swo uint32, mode int, mask uint32) int {
+		enabled = [4]uint32{cpu, swo, uint32(mode), mask}
+		return 0
+	}
+	pending := append([]byte{}, itmCaptureHello...)
+	pending = append(pending, itmOverflow)
+	jl.apiSWORead = func(buf uintptr, offset uint32, pn uintptr) {
+		n := (*uint32)(unsafe.Pointer(pn))
+		dst := unsafe.Slice((*byte)(unsafe.Pointer(buf)), *n)
+		*n = uint32(copy(dst, pending))
+	}
+	jl.apiSWOControl = func(cmd uint32, p uintptr) int {
+		if cmd == swoCmdFlush {
+			flushed += *(*uint32)(unsafe.Pointer(p))
+		}
+		return 0
+	}
+
+	if err := jl.startSWO(72000000, 0); err != nil {
+		t.Fatalf("startSWO failed: %v", err)
+	}
+	if enabled != [4]uint32{72000000, DefaultSWOFreqHz, swoIfUART, 1} {
+		t.Errorf("SWO_EnableTarget args = %v", enabled)
+	}
+	data, err := jl.ReadRTT()
+	if err != nil || string(data) != "Hi\n" {
+		t.Fatalf("ReadRTT = %q, %v", data, err)
+	}
+	if flushed != uint32(len(pending)) {
+		t.Errorf("flushed %d bytes, want %d", flushed, len(pending))
+	}
+	if stats := jl.Stats(); stats.Mode != "swo" || stats.Drops != 1 {
+		t.Errorf("Stats = %+v", stats)
+	}
+	if _, err := jl.WriteRTT([]byte("x")); err != ErrSWOReadOnly {
+		t.Errorf("WriteRTT err = %v, want ErrSWOReadOnly", err)
+	}
+}
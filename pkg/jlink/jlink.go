@@ -2,12 +2,18 @@ package jlink
 
 import (
 	"bytes"
+	"context"
 	"encoding/binary"
 	"fmt"
 	"os"
 	"runtime"
+	"strings"
+	"sync"
 	"time"
 	"unsafe"
+
+	"serial-assistant/pkg/openguard"
+	"serial-assistant/pkg/transport"
 )
 
 // LogCallback 日志回调函数类型
@@ -17,6 +23,9 @@ type LogCallback func(message string)
 type JLinkWrapper struct {
 	libHandle uintptr
 
+	// gate 串行化对驱动的全部调用，RTT 轮询让出给交互命令，见 MaxPollDelay
+	gate dllGate
+
 	// 基础 API
 	apiOpen        func() int
 	apiClose       func()
@@ -26,22 +35,92 @@ type JLinkWrapper struct {
 	apiIsConnected func() bool
 	apiReadMem     func(uint32, uint32, uintptr) int
 	apiWriteMem    func(uint32, uint32, uintptr) int
+	apiReset       func() int
+	apiGo          func()
+
+	// 设备数据库 API
+	apiDeviceGetInfo func(int, uintptr) int
 
 	// RTT API
 	apiRTTStart func() int
 	apiRTTRead  func(uint32, uintptr, uint32) int
 	apiRTTWrite func(uint32, uintptr, uint32) int
 
+	// SWO API（部分 DLL 可能缺少，仅 SWO 模式需要）
+	apiSWOEnableTarget  func(uint32, uint32, int, uint32) int
+	apiSWODisableTarget func(uint32) int
+	apiSWORead          func(uintptr, uint32, uintptr)
+	apiSWOControl       func(uint32, uintptr) int
+
+	// 日志 API（旧版 DLL 可能缺少，均为可选）
+	apiEnableLog          func(uintptr)
+	apiSetWarnOutHandler  func(uintptr)
+	apiSetErrorOutHandler func(uintptr)
+
+	// SWO 状态：useSWO 为 true 时 ReadRTT 从 SWO 读取并解码 ITM 数据包
+	useSWO bool
+	itm    *ITMDecoder
+
 	// 软 RTT 状态
 	useSoftRTT    bool
 	rttControlBlk uint32
 	rttUpBuffer   RTTBufferDesc
 
+	// 软 RTT 控制块搜索范围
+	rttSearchStart uint32
+	rttSearchSize  uint32
+
+	// 加载时库中缺少的函数，见 MissingSymbols
+	missing []string
+
 	// 日志回调
 	logCallback LogCallback
 
+	// DLL 日志转发，由 SetLogLevel 按需创建
+	dllLog *dllLog
+
 	// 读取缓冲区重用（避免频繁分配）
 	readBuffer []byte
+
+	// 软 RTT 缓冲区占用率统计
+	occupancy          float64 // 最近一次读取前的占用率 (0-1)
+	highOccupancyPolls int     // 占用率连续超过阈值的轮询次数
+
+	// 内存监视
+	watchMu sync.Mutex
+	watches map[string]*watchState
+}
+
+// RTTStats RTT 通道统计信息
+type RTTStats struct {
+	Mode         string  `json:"mode"`         // "native" 或 "soft"
+	BufferSize   uint32  `json:"bufferSize"`   // 上行缓冲区大小，原生 RTT 下为 0（由 DLL 管理）
+	OverflowMode string  `json:"overflowMode"` // 目标端缓冲区满时的行为，原生 RTT 下为 "unknown"
+	Occupancy    float64 `json:"occupancy"`    // 最近一次读取前的缓冲区占用率 (0-1)，未知时为 -1
+	Saturated    bool    `json:"saturated"`    // 占用率已连续多次超过阈值，目标端可能正在丢数据
+	Drops        int64   `json:"drops"`        // 已知的丢包次数，SWO 模式下为 ITM 溢出包数量
+}
+
+// 缓冲区占用率告警参数
+const (
+	// OccupancyWarnThreshold 占用率超过该值视为接近溢出
+	OccupancyWarnThreshold = 0.9
+	// OccupancyWarnPolls 连续多少次轮询超过阈值后判定为饱和
+	OccupancyWarnPolls = 5
+)
+
+// overflowModeName 将 RTT 缓冲区 Flags 的低两位转换为可读名称
+func overflowModeName(flags uint32) string {
+	switch flags & 0x3 {
+	case 0:
+		return "skip" // SEGGER_RTT_MODE_NO_BLOCK_SKIP：缓冲区满时丢弃整条数据
+	case 1:
+		return "trim" // SEGGER_RTT_MODE_NO_BLOCK_TRIM：缓冲区满时截断
+	case 2:
+		return "block" // SEGGER_RTT_MODE_BLOCK_IF_FIFO_FULL：目标端阻塞等待
+	default:
+		return "unknown"
+	}
 }
 
 // RTTBufferDesc RTT 缓冲区描述符
@@ -96,25 +175,22 @@ func NewJLinkWrapper(logCallback LogCallback) (*JLinkWrapper, error) {
 	}
 
 	// 注册函数 - registerLibFunc 是跨平台的，可以在这里安全使用
-	register := func(dest interface{}, name string) {
-		defer func() { recover() }()
+	// 符号不存在时 purego 会 panic，这里捕获并记为缺少
+	missing, err := jl.bindSymbols(func(dest interface{}, name string) (ok bool) {
+		defer func() {
+			if recover() != nil {
+				ok = false
+			}
+		}()
 		registerLibFunc(dest, lib, name)
+		return true
+	})
+	if err != nil {
+		closeLibrary(lib)
+		return nil, err
 	}
-
-	register(&jl.apiOpen, "JLINK_Open")
-	register(&jl.apiClose, "JLINK_Close")
-	register(&jl.apiConnect, "JLINK_Connect")
-	register(&jl.apiTIFSelect, "JLINK_TIF_Select")
-	register(&jl.apiExecCommand, "JLINK_ExecCommand")
-	register(&jl.apiIsConnected, "JLINK_IsConnected")
-	register(&jl.apiReadMem, "JLINK_ReadMem")
-	register(&jl.apiWriteMem, "JLINK_WriteMem")
-	register(&jl.apiRTTStart, "JLINK_RTT_Start")
-	register(&jl.apiRTTRead, "JLINK_RTT_Read")
-	register(&jl.apiRTTWrite, "JLINK_RTT_Write")
-
-	if jl.apiOpen == nil || jl.apiReadMem == nil {
-		return nil, fmt.Errorf("RTT 库已加载但缺少核心函数")
+	if len(missing) > 0 {
+		jl.log(fmt.Sprintf("[RTT] 库已加载但缺少: %s (相关功能将不可用)", strings.Join(missing, ", ")))
 	}
 
 	return jl, nil
@@ -127,14 +203,83 @@ func (jl *JLinkWrapper) log(message string) {
 	}
 }
 
+// ResetStrategy 连接时的复位策略
+type ResetStrategy string
+
+const (
+	// ResetAttach 直接附加到正在运行的目标，不做复位（默认行为）
+	ResetAttach ResetStrategy = "attach-no-reset"
+	// ResetNormal 连接后执行一次普通复位（SYSRESETREQ）并继续运行
+	ResetNormal ResetStrategy = "normal"
+	// ResetUnderReset 连接期间保持 RESET 引脚有效，适用于低功耗休眠或内核锁死的目标
+	ResetUnderReset ResetStrategy = "under-reset"
+)
+
+// ParseResetStrategy 解析复位策略名称，空字符串视为 ResetAttach
+func ParseResetStrategy(name string) (ResetStrategy, error) {
+	switch ResetStrategy(name) {
+	case "", ResetAttach:
+		return ResetAttach, nil
+	case ResetNormal, ResetUnderReset:
+		return ResetStrategy(name), nil
+	default:
+		return "", fmt.Errorf("未知的复位策略 %q (可选: normal, under-reset, attach-no-reset)", name)
+	}
+}
+
+// ConnectOptions 连接选项
+type ConnectOptions struct {
+	ResetStrategy ResetStrategy
+	// RTTSearchStart/RTTSearchSize 软件 RTT 控制块搜索范围，为 0 时使用默认值
+	RTTSearchStart uint32
+	RTTSearchSize  uint32
+	// Mode 数据通道，ModeRTT (默认) 或 ModeSWO
+	Mode Mode
+	// CPUFreqHz/SWOFreqHz SWO 模式下的目标内核时钟与 SWO 波特率，SWOFreqHz 为 0 时使用 DefaultSWOFreqHz
+	CPUFreqHz uint32
+	SWOFreqHz uint32
+}
+
 // Connect 连接芯片
-func (jl *JLinkWrapper) Connect(chipName string, speed int, iface string) error {
+func (jl *JLinkWrapper) Connect(chipName string, speed int, iface string, opts ConnectOptions) error {
+	return jl.ConnectContext(context.Background(), chipName, speed, iface, opts)
+}
+
+// ConnectContext 同 Connect，ctx 结束时在下一步驱动调用之前返回 ctx.Err()，
+// 包括等待芯片稳定与软件 RTT 重试的间隔；正在进行的驱动调用无法中断
+func (jl *JLinkWrapper) ConnectContext(ctx context.Context, chipName string, speed int, iface string, opts ConnectOptions) error {
 	if jl.apiOpen == nil {
 		return fmt.Errorf("RTT API 未初始化")
 	}
+	speed, err := NormalizeSpeed(speed)
+	if err != nil {
+		return err
+	}
+	iface, err = ParseInterface(iface)
+	if err != nil {
+		return err
+	}
+	if err := jl.gate.acquire(false); err != nil {
+		return err
+	}
+	defer jl.gate.release()
+
+	jl.rttSearchStart = opts.RTTSearchStart
+	if jl.rttSearchStart == 0 {
+		jl.rttSearchStart = DefaultRTTSearchStart
+	}
+	jl.rttSearchSize = opts.RTTSearchSize
+	if jl.rttSearchSize == 0 {
+		jl.rttSearchSize = DefaultRTTSearchSize
+	}
 	jl.apiOpen()
 
-	if iface == "JTAG" {
+	strategy := opts.ResetStrategy
+	if strategy == "" {
+		strategy = ResetAttach
+	}
+
+	if iface == InterfaceJTAG {
 		if jl.apiTIFSelect != nil {
 			jl.apiTIFSelect(0)
 		}
@@ -145,42 +290,98 @@ func (jl *JLinkWrapper) Connect(chipName string, speed int, iface string) error
 	}
 
 	if jl.apiExecCommand != nil {
-		jl.apiExecCommand(fmt.Sprintf("Speed = %d", speed), 0, 0)
+		jl.apiExecCommand("Speed = "+SpeedString(speed), 0, 0)
 		jl.apiExecCommand(fmt.Sprintf("Device = %s", chipName), 0, 0)
+		switch strategy {
+		case ResetNormal:
+			jl.apiExecCommand("SetResetType = 0", 0, 0) // 内核及外设复位
+		case ResetUnderReset:
+			jl.apiExecCommand("SetResetType = 2", 0, 0) // 通过 RESET 引脚复位
+		}
+	}
+
+	if strategy == ResetUnderReset {
+		if jl.apiReset == nil {
+			return fmt.Errorf("当前 J-Link 库不支持复位操作，无法使用 under-reset 策略")
+		}
+		// 在 RESET 引脚有效期间建立连接
+		jl.apiReset()
 	}
 
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 	if jl.apiConnect != nil {
 		if ret := jl.apiConnect(); ret < 0 {
+			if strategy != ResetUnderReset {
+				return fmt.Errorf("RTT 连接失败 (返回值: %d)，如果目标处于低功耗或内核锁死状态，可尝试 under-reset 复位策略", ret)
+			}
 			return fmt.Errorf("RTT 连接失败 (返回值: %d)", ret)
 		}
 	}
 
-	jl.log("[RTT] 已连接，等待芯片稳定...")
-	time.Sleep(500 * time.Millisecond)
+	switch strategy {
+	case ResetNormal:
+		if jl.apiReset != nil {
+			jl.apiReset()
+		}
+		fallthrough
+	case ResetUnderReset:
+		// 复位后内核处于暂停状态，需要恢复运行，RTT 控制块才会被固件初始化
+		if jl.apiGo != nil {
+			jl.apiGo()
+		}
+	}
+
+	jl.log(fmt.Sprintf("[RTT] 已连接 %s (接口 %s, 速度 %s kHz, RTT 搜索范围 0x%08X+0x%X)，等待芯片稳定...",
+		chipName, iface, SpeedString(speed), jl.rttSearchStart, jl.rttSearchSize))
+	if err := openguard.Sleep(ctx, 500*time.Millisecond); err != nil {
+		return err
+	}
+
+	if opts.Mode == ModeSWO {
+		return jl.startSWO(opts.CPUFreqHz, opts.SWOFreqHz)
+	}
 
-	if jl.apiRTTStart != nil && jl.apiRTTRead != nil {
+	if jl.nativeRTTAvailable() {
 		jl.log("[RTT] 尝试启动原生 RTT...")
 		if ret := jl.apiRTTStart(); ret >= 0 {
 			jl.log("[RTT] 原生 RTT 已启动")
 			jl.useSoftRTT = false
+			if jl.apiRTTWrite == nil {
+				jl.log("[RTT] 当前库缺少 JLINK_RTT_Write，发送功能不可用")
+			}
 			return nil
 		}
 	}
 
-	jl.log("[RTT] 原生 RTT 不可用，切换到软件 RTT")
-	var err error
+	if !jl.softRTTAvailable() {
+		return fmt.Errorf("原生 RTT 启动失败，且当前库缺少软件 RTT 所需的 JLINK_WriteMem，无法读取 RTT 数据")
+	}
+	jl.log("[RTT] 原生 RTT 不可用，切换到软件 RTT (只读)")
 	for i := 0; i < 3; i++ {
 		if err = jl.initSoftRTT(); err == nil {
 			jl.useSoftRTT = true
 			return nil
 		}
-		time.Sleep(500 * time.Millisecond)
+		if err := openguard.Sleep(ctx, 500*time.Millisecond); err != nil {
+			return err
+		}
 	}
 
 	return fmt.Errorf("软件 RTT 初始化失败: %v", err)
 }
 
+// ReadRTT 读取一次 RTT (或 SWO) 数据，以轮询优先级访问驱动
 func (jl *JLinkWrapper) ReadRTT() ([]byte, error) {
+	if err := jl.gate.acquire(true); err != nil {
+		return nil, err
+	}
+	defer jl.gate.release()
+
+	if jl.useSWO {
+		return jl.readSWO()
+	}
 	if !jl.useSoftRTT {
 		if jl.apiRTTRead == nil {
 			return nil, nil
@@ -202,35 +403,101 @@ func (jl *JLinkWrapper) WriteRTT(data []byte) (int, error) {
 	if len(data) == 0 {
 		return 0, nil
 	}
-	if !jl.useSoftRTT {
-		if jl.apiRTTWrite == nil {
-			return 0, nil
+	if err := jl.gate.acquire(false); err != nil {
+		return 0, err
+	}
+	defer jl.gate.release()
+
+	if jl.useSWO {
+		return 0, ErrSWOReadOnly
+	}
+	if !jl.writeAvailable() {
+		// 软件 RTT 尚未实现下行写入
+		return 0, ErrWriteUnavailable
+	}
+	n := jl.apiRTTWrite(0, uintptr(unsafe.Pointer(&data[0])), uint32(len(data)))
+	return int(n), nil
+}
+
+// WriteRTTTimeout 循环写入直到全部数据被目标端缓冲区接受，或超过 timeout
+// 目标端下行缓冲区满时 RTT_Write 只会接受部分数据，这里会短暂等待后重试剩余部分
+func (jl *JLinkWrapper) WriteRTTTimeout(data []byte, timeout time.Duration) (int, error) {
+	deadline := time.Now().Add(timeout)
+	written := 0
+	for written < len(data) {
+		n, err := jl.WriteRTT(data[written:])
+		if err != nil {
+			return written, err
+		}
+		written += n
+		if written >= len(data) {
+			break
 		}
-		n := jl.apiRTTWrite(0, uintptr(unsafe.Pointer(&data[0])), uint32(len(data)))
-		return int(n), nil
+		if timeout > 0 && time.Now().After(deadline) {
+			return written, transport.ErrTimeout
+		}
+		time.Sleep(time.Millisecond)
+	}
+	return written, nil
+}
+
+// Reset 复位目标并恢复运行；软件 RTT 的控制块会在下一次读取检测到偏移量异常后重新定位
+func (jl *JLinkWrapper) Reset() error {
+	if jl.apiReset == nil {
+		return fmt.Errorf("当前 J-Link 库不支持复位操作")
+	}
+	if err := jl.gate.acquire(false); err != nil {
+		return err
+	}
+	defer jl.gate.release()
+
+	if ret := jl.apiReset(); ret < 0 {
+		return fmt.Errorf("复位失败 (返回值: %d)", ret)
+	}
+	if jl.apiGo != nil {
+		jl.apiGo()
 	}
-	// Soft RTT Write not implemented yet
-	return 0, nil
+	return nil
 }
 
+// Close 断开连接并卸载驱动库
+// 排队中的驱动调用立即返回 ErrClosed，Close 只需等待正在执行的那一次调用结束
 func (jl *JLinkWrapper) Close() {
+	if !jl.gate.shutdown() {
+		return
+	}
+	jl.stopWatches()
+	// 卸载库之前注销日志回调，避免 DLL 在关闭过程中回调已失效的接收方
+	jl.applyLogLevel(LogOff, nil)
+
+	if jl.useSWO && jl.apiSWODisableTarget != nil {
+		jl.apiSWODisableTarget(1 << swoStimulusPort)
+	}
 	if jl.apiClose != nil {
 		jl.apiClose()
 	}
 	// 使用我们定义的 closeLibrary
-	closeLibrary(jl.libHandle)
+	if jl.libHandle != 0 {
+		closeLibrary(jl.libHandle)
+	}
 }
 
 // --- Soft RTT Logic ---
 
 func (jl *JLinkWrapper) initSoftRTT() error {
-	searchStart := uint32(0x20000000)
-	searchSize := uint32(0x10000)
+	searchStart := jl.rttSearchStart
+	if searchStart == 0 {
+		searchStart = DefaultRTTSearchStart
+	}
+	searchSize := jl.rttSearchSize
+	if searchSize == 0 {
+		searchSize = DefaultRTTSearchSize
+	}
 	chunkSize := uint32(0x800)
 	memBuf := make([]byte, chunkSize)
 	signature := []byte("SEGGER RTT")
 
-	jl.log("[RTT] 搜索 RTT 控制块...")
+	jl.log(fmt.Sprintf("[RTT] 搜索 RTT 控制块 (0x%08X+0x%X)...", searchStart, searchSize))
 	for offset := uint32(0); offset < searchSize; offset += chunkSize {
 		addr := searchStart + offset
 		if jl.apiReadMem(addr, chunkSize, uintptr(unsafe.Pointer(&memBuf[0]))) < 0 {
@@ -260,12 +527,12 @@ func (jl *JLinkWrapper) readSoftRTT() ([]byte, error) {
 	wrOffAddr := jl.rttControlBlk + 24 + 12
 	var wrOff uint32
 	if jl.apiReadMem(wrOffAddr, 4, uintptr(unsafe.Pointer(&wrOff))) < 0 {
-		return nil, fmt.Errorf("failed to read write offset")
+		return nil, fmt.Errorf("failed to read write offset: %w", ErrMemoryAccess)
 	}
 	rdOffAddr := jl.rttControlBlk + 24 + 16
 	var rdOff uint32
 	if jl.apiReadMem(rdOffAddr, 4, uintptr(unsafe.Pointer(&rdOff))) < 0 {
-		return nil, fmt.Errorf("failed to read read offset")
+		return nil, fmt.Errorf("failed to read read offset: %w", ErrMemoryAccess)
 	}
 
 	bufBase := jl.rttUpBuffer.BufferPtr
@@ -275,9 +542,11 @@ func (jl *JLinkWrapper) readSoftRTT() ([]byte, error) {
 	// 如果连接中断或状态损坏，偏移量可能变得异常大
 	if wrOff >= bufSize || rdOff >= bufSize {
 		jl.log(fmt.Sprintf("[RTT] 错误：偏移量超出范围 (wrOff=%d, rdOff=%d, bufSize=%d)", wrOff, rdOff, bufSize))
-		return nil, fmt.Errorf("RTT offset out of bounds: wrOff=%d, rdOff=%d, bufSize=%d", wrOff, rdOff, bufSize)
+		return nil, fmt.Errorf("%w: wrOff=%d, rdOff=%d, bufSize=%d", ErrOffsetOutOfBounds, wrOff, rdOff, bufSize)
 	}
 
+	jl.updateOccupancy(wrOff, rdOff, bufSize)
+
 	if wrOff == rdOff {
 		return nil, nil
 	}
@@ -293,7 +562,7 @@ func (jl *JLinkWrapper) readSoftRTT() ([]byte, error) {
 		}
 		chunk := make([]byte, readLen)
 		if jl.apiReadMem(bufBase+rdOff, readLen, uintptr(unsafe.Pointer(&chunk[0]))) < 0 {
-			return nil, fmt.Errorf("failed to read RTT data")
+			return nil, fmt.Errorf("failed to read RTT data: %w", ErrMemoryAccess)
 		}
 		data = chunk
 		rdOff += readLen
@@ -318,14 +587,14 @@ func (jl *JLinkWrapper) readSoftRTT() ([]byte, error) {
 		if len1 > 0 {
 			chunk1 := make([]byte, len1)
 			if jl.apiReadMem(bufBase+rdOff, len1, uintptr(unsafe.Pointer(&chunk1[0]))) < 0 {
-				return nil, fmt.Errorf("failed to read RTT data (segment 1)")
+				return nil, fmt.Errorf("failed to read RTT data (segment 1): %w", ErrMemoryAccess)
 			}
 			data = append(data, chunk1...)
 		}
 		if len2 > 0 {
 			chunk2 := make([]byte, len2)
 			if jl.apiReadMem(bufBase, len2, uintptr(unsafe.Pointer(&chunk2[0]))) < 0 {
-				return nil, fmt.Errorf("failed to read RTT data (segment 2)")
+				return nil, fmt.Errorf("failed to read RTT data (segment 2): %w", ErrMemoryAccess)
 			}
 			data = append(data, chunk2...)
 		}
@@ -335,12 +604,52 @@ func (jl *JLinkWrapper) readSoftRTT() ([]byte, error) {
 	}
 
 	// 写回更新的读偏移量
+	if jl.apiWriteMem == nil {
+		return nil, fmt.Errorf("当前库缺少 JLINK_WriteMem，无法更新 RTT 读偏移量")
+	}
 	if jl.apiWriteMem(rdOffAddr, 4, uintptr(unsafe.Pointer(&rdOff))) < 0 {
 		jl.log("[RTT] 警告：无法更新读偏移量")
 	}
 	return data, nil
 }
 
+// updateOccupancy 根据读写偏移量估算上行缓冲区的占用率
+// 环形缓冲区始终保留一个空位，因此可用容量为 bufSize-1
+func (jl *JLinkWrapper) updateOccupancy(wrOff, rdOff, bufSize uint32) {
+	if bufSize < 2 {
+		return
+	}
+	used := (wrOff + bufSize - rdOff) % bufSize
+	jl.occupancy = float64(used) / float64(bufSize-1)
+	if jl.occupancy >= OccupancyWarnThreshold {
+		jl.highOccupancyPolls++
+	} else {
+		jl.highOccupancyPolls = 0
+	}
+}
+
+// Stats 返回 RTT 通道统计信息
+func (jl *JLinkWrapper) Stats() RTTStats {
+	if err := jl.gate.acquire(false); err != nil {
+		return RTTStats{Mode: "closed", OverflowMode: "unknown", Occupancy: -1}
+	}
+	defer jl.gate.release()
+
+	if jl.useSWO {
+		return RTTStats{Mode: "swo", OverflowMode: "unknown", Occupancy: -1, Drops: jl.itm.Overflows()}
+	}
+	if !jl.useSoftRTT {
+		return RTTStats{Mode: "native", OverflowMode: "unknown", Occupancy: -1}
+	}
+	return RTTStats{
+		Mode:         "soft",
+		BufferSize:   jl.rttUpBuffer.Size,
+		OverflowMode: overflowModeName(jl.rttUpBuffer.Flags),
+		Occupancy:    jl.occupancy,
+		Saturated:    jl.highOccupancyPolls >= OccupancyWarnPolls,
+	}
+}
+
 func parseBufferDesc(data []byte) RTTBufferDesc {
 	return RTTBufferDesc{
 		NamePtr:   binary.LittleEndian.Uint32(data[0:4]),
@@ -358,9 +667,60 @@ func (jl *JLinkWrapper) ReinitSoftRTT() error {
 		return fmt.Errorf("not using soft RTT")
 	}
 	jl.log("[RTT] 检测到偏移量异常，尝试重新初始化 RTT...")
+	if err := jl.gate.acquire(true); err != nil {
+		return err
+	}
+	defer jl.gate.release()
 	return jl.initSoftRTT()
 }
 
+// LibraryProbe 驱动库探测结果，用于环境诊断
+type LibraryProbe struct {
+	Path    string `json:"path"`
+	Loaded  bool   `json:"loaded"`
+	Version string `json:"version"` // 例如 "7.82"，未知时为空
+	Error   string `json:"error,omitempty"`
+}
+
+// ProbeLibrary 尝试加载驱动库并读取版本号，不会打开任何探针连接
+func ProbeLibrary() LibraryProbe {
+	path, err := getLibraryPath()
+	if err != nil {
+		return LibraryProbe{Error: err.Error()}
+	}
+	probe := LibraryProbe{Path: path}
+
+	lib, err := openLibrary(path)
+	if err != nil {
+		probe.Error = err.Error()
+		return probe
+	}
+	defer closeLibrary(lib)
+	probe.Loaded = true
+
+	var getVersion func() uint32
+	func() {
+		defer func() { recover() }()
+		registerLibFunc(&getVersion, lib, "JLINKARM_GetDLLVersion")
+	}()
+	if getVersion != nil {
+		probe.Version = formatDLLVersion(getVersion())
+	}
+	return probe
+}
+
+// formatDLLVersion 将 DLL 返回的版本号 (例如 78200) 转换为 "7.82"
+// 末尾两位为修订字母 (01 = a)
+func formatDLLVersion(v uint32) string {
+	major := v / 10000
+	minor := (v / 100) % 100
+	rev := v % 100
+	if rev > 0 && rev <= 26 {
+		return fmt.Sprintf("%d.%02d%c", major, minor, 'a'+rune(rev-1))
+	}
+	return fmt.Sprintf("%d.%02d", major, minor)
+}
+
 // getLibraryPath 跨平台路径选择
 func getLibraryPath() (string, error) {
 	switch runtime.GOOS {
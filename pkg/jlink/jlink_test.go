@@ -3,8 +3,12 @@ package jlink
 import (
 	"os"
 	"runtime"
+	"strings"
 	"testing"
+	"time"
 	"unsafe"
+
+	"serial-assistant/pkg/transport"
 )
 
 // TestGetLibraryPath verifies that the library path detection works for all platforms
@@ -235,3 +239,172 @@ func TestBufferReuse(t *testing.T) {
 		t.Errorf("readBuffer capacity should remain 4096, got %d", cap(jl.readBuffer))
 	}
 }
+
+// TestOccupancyTracking verifies buffer occupancy estimation and saturation detection in soft RTT
+func TestOccupancyTracking(t *testing.T) {
+	jl := &JLinkWrapper{
+		useSoftRTT:    true,
+		rttControlBlk: 0x20000000,
+		rttUpBuffer: RTTBufferDesc{
+			BufferPtr: 0x20001000,
+			Size:      101, // 可用容量 100 字节
+			Flags:     2,
+		},
+	}
+
+	var wrOff, rdOff uint32
+	jl.apiReadMem = func(addr uint32, size uint32, buf uintptr) int {
+		switch addr {
+		case jl.rttControlBlk + 24 + 12:
+			*(*uint32)(unsafe.Pointer(buf)) = wrOff
+		case jl.rttControlBlk + 24 + 16:
+			*(*uint32)(unsafe.Pointer(buf)) = rdOff
+		}
+		return 0
+	}
+	jl.apiWriteMem = func(addr uint32, size uint32, buf uintptr) int { return 0 }
+
+	// 95% 占用，连续 OccupancyWarnPolls 次后判定为饱和
+	for i := 0; i < OccupancyWarnPolls; i++ {
+		wrOff, rdOff = 95, 0
+		if _, err := jl.readSoftRTT(); err != nil {
+			t.Fatalf("readSoftRTT failed: %v", err)
+		}
+		stats := jl.Stats()
+		if stats.Occupancy < 0.94 || stats.Occupancy > 0.96 {
+			t.Errorf("Expected occupancy ~0.95, got %f", stats.Occupancy)
+		}
+		if expected := i == OccupancyWarnPolls-1; stats.Saturated != expected {
+			t.Errorf("Poll %d: expected Saturated=%v, got %v", i, expected, stats.Saturated)
+		}
+	}
+
+	if mode := jl.Stats().OverflowMode; mode != "block" {
+		t.Errorf("Expected overflow mode 'block' for flags=2, got %q", mode)
+	}
+
+	// 回绕情况下的占用率，低于阈值后饱和状态解除
+	wrOff, rdOff = 10, 90
+	if _, err := jl.readSoftRTT(); err != nil {
+		t.Fatalf("readSoftRTT failed: %v", err)
+	}
+	stats := jl.Stats()
+	if stats.Occupancy < 0.20 || stats.Occupancy > 0.22 {
+		t.Errorf("Expected wrapped occupancy ~0.21, got %f", stats.Occupancy)
+	}
+	if stats.Saturated {
+		t.Error("Expected saturation to clear when occupancy drops")
+	}
+}
+
+func TestNativeRTTStats(t *testing.T) {
+	jl := &JLinkWrapper{useSoftRTT: false}
+	stats := jl.Stats()
+	if stats.Mode != "native" || stats.Occupancy != -1 {
+		t.Errorf("Unexpected native stats: %+v", stats)
+	}
+}
+
+func TestParseResetStrategy(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected ResetStrategy
+	}{
+		{"", ResetAttach},
+		{"attach-no-reset", ResetAttach},
+		{"normal", ResetNormal},
+		{"under-reset", ResetUnderReset},
+	}
+	for _, tt := range tests {
+		got, err := ParseResetStrategy(tt.input)
+		if err != nil || got != tt.expected {
+			t.Errorf("ParseResetStrategy(%q) = %q, %v; expected %q", tt.input, got, err, tt.expected)
+		}
+	}
+	if _, err := ParseResetStrategy("hard"); err == nil {
+		t.Error("Expected error for unknown strategy")
+	}
+}
+
+// TestConnectUnderResetCommandOrder verifies the DLL call sequence for the under-reset strategy
+func TestConnectUnderResetCommandOrder(t *testing.T) {
+	var calls []string
+	jl := &JLinkWrapper{}
+	jl.apiOpen = func() int { calls = append(calls, "open"); return 0 }
+	jl.apiExecCommand = func(cmd string, _ int, _ int) int { calls = append(calls, cmd); return 0 }
+	jl.apiReset = func() int { calls = append(calls, "reset"); return 0 }
+	jl.apiConnect = func() int { calls = append(calls, "connect"); return -1 }
+
+	err := jl.Connect("STM32F103C8", 4000, "SWD", ConnectOptions{ResetStrategy: ResetUnderReset})
+	if err == nil {
+		t.Fatal("Expected connect failure")
+	}
+
+	expected := []string{"open", "Speed = 4000", "Device = STM32F103C8", "SetResetType = 2", "reset", "connect"}
+	if len(calls) != len(expected) {
+		t.Fatalf("Expected calls %q, got %q", expected, calls)
+	}
+	for i := range expected {
+		if calls[i] != expected[i] {
+			t.Errorf("Call %d: expected %q, got %q", i, expected[i], calls[i])
+		}
+	}
+}
+
+// TestConnectFailureSuggestsUnderReset verifies the error hint for the default strategy
+func TestConnectFailureSuggestsUnderReset(t *testing.T) {
+	jl := &JLinkWrapper{}
+	jl.apiOpen = func() int { return 0 }
+	jl.apiConnect = func() int { return -1 }
+
+	err := jl.Connect("STM32F103C8", 4000, "SWD", ConnectOptions{})
+	if err == nil || !strings.Contains(err.Error(), "under-reset") {
+		t.Errorf("Expected error suggesting under-reset, got %v", err)
+	}
+}
+
+// TestWriteRTTTimeout verifies partial RTT writes are retried and bounded by the timeout
+func TestWriteRTTTimeout(t *testing.T) {
+	jl := &JLinkWrapper{}
+	accepted := 0
+	jl.apiRTTWrite = func(channel uint32, buf uintptr, size uint32) int {
+		// 每次只接受 2 字节
+		n := 2
+		if int(size) < n {
+			n = int(size)
+		}
+		accepted += n
+		return n
+	}
+	n, err := jl.WriteRTTTimeout([]byte("abcdef"), time.Second)
+	if err != nil || n != 6 || accepted != 6 {
+		t.Errorf("Expected all 6 bytes written, got n=%d err=%v accepted=%d", n, err, accepted)
+	}
+
+	// 目标端缓冲区一直满
+	jl.apiRTTWrite = func(channel uint32, buf uintptr, size uint32) int { return 0 }
+	start := time.Now()
+	n, err = jl.WriteRTTTimeout([]byte("abc"), 20*time.Millisecond)
+	if err != transport.ErrTimeout || n != 0 {
+		t.Errorf("Expected timeout with 0 bytes written, got n=%d err=%v", n, err)
+	}
+	if time.Since(start) > time.Second {
+		t.Error("WriteRTTTimeout exceeded its bound")
+	}
+}
+
+func TestFormatDLLVersion(t *testing.T) {
+	tests := []struct {
+		input    uint32
+		expected string
+	}{
+		{78200, "7.82"},
+		{79402, "7.94b"},
+		{69801, "6.98a"},
+	}
+	for _, tt := range tests {
+		if got := formatDLLVersion(tt.input); got != tt.expected {
+			t.Errorf("formatDLLVersion(%d) = %q, expected %q", tt.input, got, tt.expected)
+		}
+	}
+}
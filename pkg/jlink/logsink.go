@@ -0,0 +1,64 @@
+package jlink
+
+import (
+	"sync"
+	"time"
+)
+
+// LogEntry jlink-log 事件的数据：一条驱动日志 (本包的 "[RTT] ..." 状态信息或 DLL 转发的 "[J-Link] ..." 日志)
+type LogEntry struct {
+	Time    time.Time `json:"time"`
+	Message string    `json:"message"`
+}
+
+// LogSink 将驱动日志与目标端 RTT 数据分开：日志只交给 emitLog，
+// 数据流 (emitData) 中只有从目标读取的字节，因此原始捕获、历史与录制都不会混入日志文本
+//
+// 开启合并模式 (SetMerge) 后日志会额外以 "<消息>\n" 的形式交给 emitText，
+// 兼容习惯在接收区查看驱动日志的用户；emitText 不应写入任何捕获
+type LogSink struct {
+	mu       sync.Mutex
+	merge    bool
+	emitLog  func(LogEntry)
+	emitText func([]byte)
+	now      func() time.Time
+}
+
+// NewLogSink 创建日志分发器，emitText 可以为 nil (此时合并模式无效)
+func NewLogSink(emitLog func(LogEntry), emitText func([]byte)) *LogSink {
+	return &LogSink{emitLog: emitLog, emitText: emitText, now: time.Now}
+}
+
+// SetMerge 设置是否把日志同时并入接收区显示
+func (s *LogSink) SetMerge(merge bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.merge = merge
+}
+
+// Merge 返回当前是否处于合并模式
+func (s *LogSink) Merge() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.merge
+}
+
+// Log 分发一条日志
+func (s *LogSink) Log(message string) {
+	s.mu.Lock()
+	merge := s.merge
+	now := s.now()
+	s.mu.Unlock()
+
+	if s.emitLog != nil {
+		s.emitLog(LogEntry{Time: now, Message: message})
+	}
+	if merge && s.emitText != nil {
+		s.emitText([]byte(message + "\n"))
+	}
+}
+
+// Callback 返回可传给 NewJLinkWrapper 的日志回调
+func (s *LogSink) Callback() LogCallback {
+	return s.Log
+}
@@ -0,0 +1,97 @@
+package jlink
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"unsafe"
+)
+
+func TestLogSinkMerge(t *testing.T) {
+	var entries []LogEntry
+	var text bytes.Buffer
+	sink := NewLogSink(func(e LogEntry) { entries = append(entries, e) }, func(b []byte) { text.Write(b) })
+
+	sink.Log("[RTT] 正在加载库")
+	if len(entries) != 1 || entries[0].Message != "[RTT] 正在加载库" || entries[0].Time.IsZero() {
+		t.Errorf("Unexpected log entries: %+v", entries)
+	}
+	if text.Len() != 0 {
+		t.Errorf("Expected no merged text by default, got %q", text.String())
+	}
+
+	sink.SetMerge(true)
+	sink.Log("[RTT] 原生 RTT 已启动")
+	if len(entries) != 2 {
+		t.Errorf("Expected log event in merge mode too, got %d entries", len(entries))
+	}
+	if text.String() != "[RTT] 原生 RTT 已启动\n" {
+		t.Errorf("Unexpected merged text %q", text.String())
+	}
+}
+
+// TestLogSinkKeepsRTTDataClean verifies that driver logs produced while polling never appear in the
+// bytes returned by ReadRTT, which is what gets recorded into history and raw captures
+func TestLogSinkKeepsRTTDataClean(t *testing.T) {
+	var logs []string
+	var merged bytes.Buffer
+	sink := NewLogSink(func(e LogEntry) { logs = append(logs, e.Message) }, func(b []byte) { merged.Write(b) })
+
+	jl := &JLinkWrapper{
+		logCallback:   sink.Callback(),
+		useSoftRTT:    true,
+		rttControlBlk: 0x20000000,
+		rttUpBuffer:   RTTBufferDesc{BufferPtr: 0x20001000, Size: 64},
+	}
+	target := []byte("\x00\xffTARGET\r\n")
+	var wrOff, rdOff uint32
+	jl.apiReadMem = func(addr uint32, size uint32, buf uintptr) int {
+		switch {
+		case addr == jl.rttControlBlk+24+12:
+			*(*uint32)(unsafe.Pointer(buf)) = wrOff
+		case addr == jl.rttControlBlk+24+16:
+			*(*uint32)(unsafe.Pointer(buf)) = rdOff
+		case addr >= jl.rttUpBuffer.BufferPtr:
+			copy(unsafe.Slice((*byte)(unsafe.Pointer(buf)), size), target[addr-jl.rttUpBuffer.BufferPtr:])
+		}
+		return 0
+	}
+	// 写回读偏移量失败会产生一条警告日志，而这次读取同时返回了数据
+	jl.apiWriteMem = func(addr uint32, size uint32, buf uintptr) int { return -1 }
+
+	var capture []byte
+	for _, merge := range []bool{false, true} {
+		sink.SetMerge(merge)
+
+		wrOff, rdOff = 1000, 0 // 偏移量损坏，只产生日志
+		data, _ := jl.ReadRTT()
+		capture = append(capture, data...)
+
+		wrOff, rdOff = uint32(len(target)), 0
+		data, err := jl.ReadRTT()
+		if err != nil {
+			t.Fatalf("ReadRTT failed: %v", err)
+		}
+		capture = append(capture, data...)
+
+		// 模拟 DLL 转发的日志
+		jl.dllLog = newDLLLog(jl.logCallback)
+		jl.dllLog.configure(LogDebug, nil)
+		jl.dllLog.handle(LogDebug, "JLINK_ReadMem(0x20001000, 0x0A)")
+	}
+
+	if want := append(append([]byte{}, target...), target...); !bytes.Equal(capture, want) {
+		t.Errorf("Capture contains non-target bytes: %q", capture)
+	}
+	if len(logs) != 6 {
+		t.Errorf("Expected 6 log events, got %d: %q", len(logs), logs)
+	}
+	for _, marker := range []string{"[RTT]", "[J-Link]"} {
+		if bytes.Contains(capture, []byte(marker)) {
+			t.Errorf("Capture contains log marker %q", marker)
+		}
+		if !strings.Contains(merged.String(), marker) {
+			t.Errorf("Merged text missing %q: %q", marker, merged.String())
+		}
+	}
+}
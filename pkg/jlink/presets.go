@@ -0,0 +1,133 @@
+package jlink
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	// MinSpeedKHz J-Link 支持的最低接口速度 (kHz)
+	MinSpeedKHz = 5
+	// MaxSpeedKHz J-Link 支持的最高接口速度 (kHz)
+	MaxSpeedKHz = 50000
+	// SpeedAuto 速度为 0 时使用 J-Link 自动速度
+	SpeedAuto = 0
+
+	// DefaultRTTSearchStart 软件 RTT 默认搜索起始地址（Cortex-M SRAM）
+	DefaultRTTSearchStart = 0x20000000
+	// DefaultRTTSearchSize 软件 RTT 默认搜索范围
+	DefaultRTTSearchSize = 0x10000
+)
+
+// 目标接口
+const (
+	InterfaceSWD  = "SWD"
+	InterfaceJTAG = "JTAG"
+)
+
+// NormalizeSpeed 校验接口速度：0 表示自动，其余值限制在 MinSpeedKHz~MaxSpeedKHz 之间
+func NormalizeSpeed(speed int) (int, error) {
+	switch {
+	case speed < 0:
+		return 0, fmt.Errorf("无效的接口速度 %d kHz", speed)
+	case speed == SpeedAuto:
+		return SpeedAuto, nil
+	case speed < MinSpeedKHz:
+		return MinSpeedKHz, nil
+	case speed > MaxSpeedKHz:
+		return MaxSpeedKHz, nil
+	default:
+		return speed, nil
+	}
+}
+
+// SpeedString 返回速度的显示/命令形式，0 显示为 auto
+func SpeedString(speed int) string {
+	if speed == SpeedAuto {
+		return "auto"
+	}
+	return fmt.Sprintf("%d", speed)
+}
+
+// ParseInterface 严格校验目标接口，仅接受 SWD 与 JTAG（不区分大小写）
+func ParseInterface(iface string) (string, error) {
+	switch strings.ToUpper(strings.TrimSpace(iface)) {
+	case InterfaceSWD:
+		return InterfaceSWD, nil
+	case InterfaceJTAG:
+		return InterfaceJTAG, nil
+	default:
+		return "", fmt.Errorf("不支持的接口 %q (可选: SWD, JTAG)", iface)
+	}
+}
+
+// Preset 芯片连接预设
+type Preset struct {
+	Speed          int    `json:"speed"`
+	Interface      string `json:"interface"`
+	RTTSearchStart uint32 `json:"rttSearchStart"`
+	RTTSearchSize  uint32 `json:"rttSearchSize"`
+}
+
+// builtinPresets 内置芯片预设，键为芯片名前缀（大写）
+var builtinPresets = map[string]Preset{
+	"STM32F0":    {Speed: 4000, Interface: InterfaceSWD, RTTSearchStart: 0x20000000, RTTSearchSize: 0x2000},
+	"STM32F1":    {Speed: 4000, Interface: InterfaceSWD, RTTSearchStart: 0x20000000, RTTSearchSize: 0x5000},
+	"STM32F4":    {Speed: 4000, Interface: InterfaceSWD, RTTSearchStart: 0x20000000, RTTSearchSize: 0x30000},
+	"STM32G0":    {Speed: 4000, Interface: InterfaceSWD, RTTSearchStart: 0x20000000, RTTSearchSize: 0x9000},
+	"STM32H7":    {Speed: 12000, Interface: InterfaceSWD, RTTSearchStart: 0x24000000, RTTSearchSize: 0x80000},
+	"STM32L4":    {Speed: 4000, Interface: InterfaceSWD, RTTSearchStart: 0x20000000, RTTSearchSize: 0x28000},
+	"NRF52":      {Speed: 4000, Interface: InterfaceSWD, RTTSearchStart: 0x20000000, RTTSearchSize: 0x40000},
+	"GD32F3":     {Speed: 4000, Interface: InterfaceSWD, RTTSearchStart: 0x20000000, RTTSearchSize: 0x18000},
+	"RP2040":     {Speed: 4000, Interface: InterfaceSWD, RTTSearchStart: 0x20000000, RTTSearchSize: 0x42000},
+	"MIMXRT106":  {Speed: 12000, Interface: InterfaceSWD, RTTSearchStart: 0x20000000, RTTSearchSize: 0x80000},
+	"ATSAMD21":   {Speed: 4000, Interface: InterfaceSWD, RTTSearchStart: 0x20000000, RTTSearchSize: 0x8000},
+	"ATSAME70":   {Speed: 4000, Interface: InterfaceSWD, RTTSearchStart: 0x20400000, RTTSearchSize: 0x60000},
+	"CORTEX-M4":  {Speed: 4000, Interface: InterfaceSWD, RTTSearchStart: 0x20000000, RTTSearchSize: 0x10000},
+	"CORTEX-M33": {Speed: 4000, Interface: InterfaceSWD, RTTSearchStart: 0x20000000, RTTSearchSize: 0x10000},
+}
+
+// LookupPreset 查找芯片预设：先按最长前缀匹配内置表，再叠加用户覆盖项
+// （按芯片名精确匹配，不区分大小写；覆盖项中的零值字段沿用内置值）
+func LookupPreset(chip string, overrides map[string]Preset) (Preset, bool) {
+	name := strings.ToUpper(strings.TrimSpace(chip))
+	if name == "" {
+		return Preset{}, false
+	}
+
+	var preset Preset
+	found := false
+	prefixes := make([]string, 0, len(builtinPresets))
+	for prefix := range builtinPresets {
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Slice(prefixes, func(i, j int) bool { return len(prefixes[i]) > len(prefixes[j]) })
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(name, prefix) {
+			preset, found = builtinPresets[prefix], true
+			break
+		}
+	}
+
+	for key, o := range overrides {
+		if strings.ToUpper(key) != name {
+			continue
+		}
+		if o.Speed != 0 {
+			preset.Speed = o.Speed
+		}
+		if o.Interface != "" {
+			preset.Interface = o.Interface
+		}
+		if o.RTTSearchStart != 0 {
+			preset.RTTSearchStart = o.RTTSearchStart
+		}
+		if o.RTTSearchSize != 0 {
+			preset.RTTSearchSize = o.RTTSearchSize
+		}
+		found = true
+		break
+	}
+	return preset, found
+}
@@ -0,0 +1,97 @@
+package jlink
+
+import "testing"
+
+func TestNormalizeSpeed(t *testing.T) {
+	tests := []struct {
+		input    int
+		expected int
+	}{
+		{0, SpeedAuto},
+		{1, MinSpeedKHz},
+		{4000, 4000},
+		{50000, 50000},
+		{100000, MaxSpeedKHz},
+	}
+	for _, tt := range tests {
+		got, err := NormalizeSpeed(tt.input)
+		if err != nil || got != tt.expected {
+			t.Errorf("NormalizeSpeed(%d) = %d, %v; expected %d", tt.input, got, err, tt.expected)
+		}
+	}
+	if _, err := NormalizeSpeed(-1); err == nil {
+		t.Error("Expected error for negative speed")
+	}
+	if SpeedString(0) != "auto" || SpeedString(4000) != "4000" {
+		t.Errorf("Unexpected SpeedString output: %q, %q", SpeedString(0), SpeedString(4000))
+	}
+}
+
+func TestParseInterface(t *testing.T) {
+	for input, expected := range map[string]string{"SWD": "SWD", "swd": "SWD", " jtag ": "JTAG"} {
+		got, err := ParseInterface(input)
+		if err != nil || got != expected {
+			t.Errorf("ParseInterface(%q) = %q, %v; expected %q", input, got, err, expected)
+		}
+	}
+	for _, input := range []string{"", "cJTAG", "FINE", "SPI"} {
+		if _, err := ParseInterface(input); err == nil {
+			t.Errorf("Expected error for interface %q", input)
+		}
+	}
+}
+
+func TestLookupPreset(t *testing.T) {
+	p, ok := LookupPreset("stm32h743zi", nil)
+	if !ok || p.RTTSearchStart != 0x24000000 || p.Speed != 12000 {
+		t.Errorf("Unexpected STM32H7 preset: %+v, %v", p, ok)
+	}
+
+	// 最长前缀优先
+	p, ok = LookupPreset("Cortex-M33", nil)
+	if !ok || p.RTTSearchSize != 0x10000 {
+		t.Errorf("Unexpected Cortex-M33 preset: %+v, %v", p, ok)
+	}
+
+	if _, ok := LookupPreset("UNKNOWN-CHIP", nil); ok {
+		t.Error("Expected no preset for unknown chip")
+	}
+
+	// 用户覆盖只替换非零字段
+	overrides := map[string]Preset{"STM32F103C8": {Speed: 1000}}
+	p, ok = LookupPreset("stm32f103c8", overrides)
+	if !ok || p.Speed != 1000 || p.RTTSearchSize != 0x5000 || p.Interface != InterfaceSWD {
+		t.Errorf("Unexpected overridden preset: %+v, %v", p, ok)
+	}
+
+	// 覆盖项也可以为内置表之外的芯片提供预设
+	overrides = map[string]Preset{"MyChip": {Interface: InterfaceJTAG, RTTSearchStart: 0x10000000}}
+	p, ok = LookupPreset("MYCHIP", overrides)
+	if !ok || p.Interface != InterfaceJTAG || p.RTTSearchStart != 0x10000000 {
+		t.Errorf("Unexpected custom preset: %+v, %v", p, ok)
+	}
+}
+
+// TestConnectAutoSpeedAndInvalidInterface verifies speed 0 maps to "Speed = auto" and bad interfaces are rejected
+func TestConnectAutoSpeedAndInvalidInterface(t *testing.T) {
+	var calls []string
+	jl := &JLinkWrapper{}
+	jl.apiOpen = func() int { calls = append(calls, "open"); return 0 }
+	jl.apiExecCommand = func(cmd string, _ int, _ int) int { calls = append(calls, cmd); return 0 }
+	jl.apiConnect = func() int { return -1 }
+
+	if err := jl.Connect("STM32F103C8", 0, "SWD", ConnectOptions{}); err == nil {
+		t.Fatal("Expected connect failure")
+	}
+	if len(calls) < 2 || calls[1] != "Speed = auto" {
+		t.Errorf("Expected \"Speed = auto\", got %q", calls)
+	}
+
+	calls = nil
+	if err := jl.Connect("STM32F103C8", 4000, "UART", ConnectOptions{}); err == nil {
+		t.Error("Expected error for invalid interface")
+	}
+	if len(calls) != 0 {
+		t.Errorf("Expected no DLL calls for invalid interface, got %q", calls)
+	}
+}
@@ -0,0 +1,81 @@
+package jlink
+
+import (
+	"errors"
+	"fmt"
+	"unsafe"
+)
+
+// Mode J-Link 数据通道
+type Mode string
+
+const (
+	// ModeRTT 通过 RTT 读写目标内存中的环形缓冲区 (默认)
+	ModeRTT Mode = "rtt"
+	// ModeSWO 通过 SWO 引脚接收 ITM 刺激端口 0 的输出，只读
+	ModeSWO Mode = "swo"
+)
+
+// ParseMode 解析通道名称，空字符串视为 ModeRTT
+func ParseMode(name string) (Mode, error) {
+	switch Mode(name) {
+	case "", ModeRTT:
+		return ModeRTT, nil
+	case ModeSWO:
+		return ModeSWO, nil
+	default:
+		return "", fmt.Errorf("未知的 J-Link 模式 %q (可选: rtt, swo)", name)
+	}
+}
+
+// DefaultSWOFreqHz 未指定 SWO 频率时使用的波特率
+const DefaultSWOFreqHz = 2000000
+
+// ErrSWOReadOnly SWO 为单向通道，不支持向目标发送数据
+var ErrSWOReadOnly = errors.New("SWO mode is read-only")
+
+const (
+	swoStimulusPort = 0 // 读取的 ITM 刺激端口
+	swoIfUART       = 0 // JLINKARM_SWO_IF_UART：NRZ (UART) 编码
+	swoCmdFlush     = 2 // JLINKARM_SWO_CMD_FLUSH：丢弃主机端缓冲区中已读取的字节
+)
+
+// startSWO 在目标上启用 SWO 输出，调用方需保证驱动已连接
+func (jl *JLinkWrapper) startSWO(cpuFreqHz, swoFreqHz uint32) error {
+	if jl.apiSWOEnableTarget == nil || jl.apiSWORead == nil || jl.apiSWOControl == nil {
+		return fmt.Errorf("当前 J-Link 库缺少 SWO 函数 (JLINKARM_SWO_EnableTarget/JLINKARM_SWO_Read/JLINKARM_SWO_Control)，请升级 J-Link 软件或改用 RTT 模式")
+	}
+	if cpuFreqHz == 0 {
+		return fmt.Errorf("SWO 模式需要指定目标内核时钟频率 (cpuFreqHz)")
+	}
+	if swoFreqHz == 0 {
+		swoFreqHz = DefaultSWOFreqHz
+	}
+	if swoFreqHz > cpuFreqHz {
+		return fmt.Errorf("SWO 频率 %d Hz 不能高于内核时钟 %d Hz", swoFreqHz, cpuFreqHz)
+	}
+	if ret := jl.apiSWOEnableTarget(cpuFreqHz, swoFreqHz, swoIfUART, 1<<swoStimulusPort); ret < 0 {
+		return fmt.Errorf("SWO 启用失败 (返回值: %d)，请确认内核时钟频率正确且目标支持 SWO", ret)
+	}
+	jl.log(fmt.Sprintf("[RTT] SWO 已启用 (内核时钟 %d Hz, SWO %d Hz, 刺激端口 %d)", cpuFreqHz, swoFreqHz, swoStimulusPort))
+	jl.itm = NewITMDecoder(swoStimulusPort)
+	jl.useSWO = true
+	jl.useSoftRTT = false
+	return nil
+}
+
+// readSWO 读取主机端已缓冲的 SWO 数据并解码，调用方需持有驱动访问权
+func (jl *JLinkWrapper) readSWO() ([]byte, error) {
+	n := uint32(len(jl.readBuffer))
+	jl.apiSWORead(uintptr(unsafe.Pointer(&jl.readBuffer[0])), 0, uintptr(unsafe.Pointer(&n)))
+	if n == 0 {
+		return nil, nil
+	}
+	if n > uint32(len(jl.readBuffer)) {
+		return nil, fmt.Errorf("SWO 读取返回了无效的长度 %d", n)
+	}
+	// SWO_Read 不会移除数据，需要显式丢弃已读取的部分
+	consumed := n
+	jl.apiSWOControl(swoCmdFlush, uintptr(unsafe.Pointer(&consumed)))
+	return jl.itm.Decode(jl.readBuffer[:n]), nil
+}
@@ -0,0 +1,83 @@
+package jlink
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// apiSymbol DLL 导出函数与 JLinkWrapper 字段的对应关系
+type apiSymbol struct {
+	dest     interface{}
+	name     string
+	required bool // 缺少时无法使用该库
+}
+
+// symbols 返回需要注册的全部函数，顺序即注册顺序
+func (jl *JLinkWrapper) symbols() []apiSymbol {
+	return []apiSymbol{
+		{&jl.apiOpen, "JLINK_Open", true},
+		{&jl.apiClose, "JLINK_Close", false},
+		{&jl.apiConnect, "JLINK_Connect", false},
+		{&jl.apiTIFSelect, "JLINK_TIF_Select", false},
+		{&jl.apiExecCommand, "JLINK_ExecCommand", false},
+		{&jl.apiIsConnected, "JLINK_IsConnected", false},
+		{&jl.apiReadMem, "JLINK_ReadMem", true},
+		{&jl.apiWriteMem, "JLINK_WriteMem", false},
+		{&jl.apiReset, "JLINK_Reset", false},
+		{&jl.apiGo, "JLINK_Go", false},
+		{&jl.apiDeviceGetInfo, "JLINKARM_DEVICE_GetInfo", false},
+		{&jl.apiRTTStart, "JLINK_RTT_Start", false},
+		{&jl.apiRTTRead, "JLINK_RTT_Read", false},
+		{&jl.apiRTTWrite, "JLINK_RTT_Write", false},
+		{&jl.apiSWOEnableTarget, "JLINKARM_SWO_EnableTarget", false},
+		{&jl.apiSWODisableTarget, "JLINKARM_SWO_DisableTarget", false},
+		{&jl.apiSWORead, "JLINKARM_SWO_Read", false},
+		{&jl.apiSWOControl, "JLINKARM_SWO_Control", false},
+		{&jl.apiEnableLog, "JLINKARM_EnableLog", false},
+		{&jl.apiSetWarnOutHandler, "JLINKARM_SetWarnOutHandler", false},
+		{&jl.apiSetErrorOutHandler, "JLINKARM_SetErrorOutHandler", false},
+	}
+}
+
+// bindSymbols 通过 resolve 注册全部函数，resolve 返回 false 表示库中没有该符号
+// 返回缺少的可选函数；缺少必需函数时返回错误，错误信息列出所有缺少的函数
+func (jl *JLinkWrapper) bindSymbols(resolve func(dest interface{}, name string) bool) ([]string, error) {
+	var missing []string
+	requiredMissing := false
+	for _, sym := range jl.symbols() {
+		if resolve(sym.dest, sym.name) {
+			continue
+		}
+		missing = append(missing, sym.name)
+		requiredMissing = requiredMissing || sym.required
+	}
+	jl.missing = missing
+	if requiredMissing {
+		return missing, fmt.Errorf("RTT 库已加载但缺少核心函数: %s", strings.Join(missing, ", "))
+	}
+	return missing, nil
+}
+
+// MissingSymbols 返回加载时库中缺少的函数名
+func (jl *JLinkWrapper) MissingSymbols() []string {
+	return append([]string(nil), jl.missing...)
+}
+
+// ErrWriteUnavailable 当前 RTT 模式或库版本不支持向目标写入
+var ErrWriteUnavailable = errors.New("RTT write is not supported by this J-Link library or RTT mode")
+
+// nativeRTTAvailable 库是否提供原生 RTT 读取所需的函数
+func (jl *JLinkWrapper) nativeRTTAvailable() bool {
+	return jl.apiRTTStart != nil && jl.apiRTTRead != nil
+}
+
+// softRTTAvailable 软件 RTT 需要读内存，并写回读偏移量
+func (jl *JLinkWrapper) softRTTAvailable() bool {
+	return jl.apiReadMem != nil && jl.apiWriteMem != nil
+}
+
+// writeAvailable 当前模式下能否向目标写入，软件 RTT 暂不支持下行
+func (jl *JLinkWrapper) writeAvailable() bool {
+	return !jl.useSWO && !jl.useSoftRTT && jl.apiRTTWrite != nil
+}
@@ -0,0 +1,139 @@
+package jlink
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"unsafe"
+)
+
+// fakeResolver 模拟只导出部分函数的 DLL：absent 中的符号注册失败，
+// 其余符号注册为返回零值的桩函数
+func fakeResolver(absent ...string) func(dest interface{}, name string) bool {
+	skip := make(map[string]bool, len(absent))
+	for _, name := range absent {
+		skip[name] = true
+	}
+	return func(dest interface{}, name string) bool {
+		if skip[name] {
+			return false
+		}
+		fn := reflect.ValueOf(dest).Elem()
+		fn.Set(reflect.MakeFunc(fn.Type(), func([]reflect.Value) []reflect.Value {
+			out := make([]reflect.Value, fn.Type().NumOut())
+			for i := range out {
+				out[i] = reflect.Zero(fn.Type().Out(i))
+			}
+			return out
+		}))
+		return true
+	}
+}
+
+func TestBindSymbolsMissingRequired(t *testing.T) {
+	jl := &JLinkWrapper{}
+	missing, err := jl.bindSymbols(fakeResolver("JLINK_ReadMem", "JLINK_RTT_Start"))
+	if err == nil {
+		t.Fatal("expected error when JLINK_ReadMem is missing")
+	}
+	for _, name := range []string{"JLINK_ReadMem", "JLINK_RTT_Start"} {
+		if !strings.Contains(err.Error(), name) {
+			t.Errorf("error %q does not list %s", err, name)
+		}
+	}
+	if len(missing) != 2 {
+		t.Errorf("missing = %v", missing)
+	}
+}
+
+func TestBindSymbolsOptional(t *testing.T) {
+	jl := &JLinkWrapper{}
+	missing, err := jl.bindSymbols(fakeResolver("JLINK_RTT_Write", "JLINKARM_SWO_Read"))
+	if err != nil {
+		t.Fatalf("bindSymbols failed: %v", err)
+	}
+	if !reflect.DeepEqual(missing, []string{"JLINK_RTT_Write", "JLINKARM_SWO_Read"}) {
+		t.Errorf("missing = %v", missing)
+	}
+	if !reflect.DeepEqual(jl.MissingSymbols(), missing) {
+		t.Errorf("MissingSymbols = %v", jl.MissingSymbols())
+	}
+	if jl.apiRTTWrite != nil || jl.apiSWORead != nil || jl.apiOpen == nil {
+		t.Error("function fields do not match the resolved symbols")
+	}
+}
+
+func TestFeatureFlags(t *testing.T) {
+	tests := []struct {
+		absent          []string
+		native, soft    bool
+		writeWhenNative bool
+	}{
+		{nil, true, true, true},
+		{[]string{"JLINK_RTT_Start"}, false, true, true},
+		{[]string{"JLINK_RTT_Read", "JLINK_WriteMem"}, false, false, true},
+		{[]string{"JLINK_RTT_Write"}, true, true, false},
+	}
+	for _, tt := range tests {
+		jl := &JLinkWrapper{}
+		if _, err := jl.bindSymbols(fakeResolver(tt.absent...)); err != nil {
+			t.Fatalf("%v: bindSymbols failed: %v", tt.absent, err)
+		}
+		if got := jl.nativeRTTAvailable(); got != tt.native {
+			t.Errorf("%v: nativeRTTAvailable = %v", tt.absent, got)
+		}
+		if got := jl.softRTTAvailable(); got != tt.soft {
+			t.Errorf("%v: softRTTAvailable = %v", tt.absent, got)
+		}
+		if got := jl.writeAvailable(); got != tt.writeWhenNative {
+			t.Errorf("%v: writeAvailable = %v", tt.absent, got)
+		}
+	}
+}
+
+// 原生 RTT 与 JLINK_WriteMem 均缺少时，Connect 应返回错误而不是进入软件 RTT
+func TestConnectWithoutAnyRTT(t *testing.T) {
+	jl := &JLinkWrapper{}
+	if _, err := jl.bindSymbols(fakeResolver("JLINK_RTT_Start", "JLINK_WriteMem")); err != nil {
+		t.Fatalf("bindSymbols failed: %v", err)
+	}
+	err := jl.Connect("STM32F103C8", 4000, "SWD", ConnectOptions{})
+	if err == nil || !strings.Contains(err.Error(), "JLINK_WriteMem") {
+		t.Fatalf("Connect error = %v", err)
+	}
+}
+
+// 写入在缺少 JLINK_RTT_Write 或处于软件 RTT 时返回错误，而不是静默丢弃
+func TestWriteRTTUnavailable(t *testing.T) {
+	jl := &JLinkWrapper{}
+	if _, err := jl.bindSymbols(fakeResolver("JLINK_RTT_Write")); err != nil {
+		t.Fatalf("bindSymbols failed: %v", err)
+	}
+	if _, err := jl.WriteRTT([]byte("x")); err != ErrWriteUnavailable {
+		t.Errorf("native without RTT_Write: err = %v", err)
+	}
+
+	jl = &JLinkWrapper{useSoftRTT: true}
+	jl.apiRTTWrite = func(uint32, uintptr, uint32) int { return 1 }
+	if _, err := jl.WriteRTTTimeout([]byte("x"), 0); err != ErrWriteUnavailable {
+		t.Errorf("soft RTT: err = %v", err)
+	}
+}
+
+// 软件 RTT 读取在缺少 JLINK_WriteMem 时返回错误而不是空指针崩溃
+func TestReadSoftRTTWithoutWriteMem(t *testing.T) {
+	jl := &JLinkWrapper{
+		useSoftRTT:    true,
+		rttControlBlk: 0x20000000,
+		rttUpBuffer:   RTTBufferDesc{BufferPtr: 0x20001000, Size: 64},
+	}
+	jl.apiReadMem = func(addr, size uint32, buf uintptr) int {
+		if addr == 0x20000000+24+12 && size == 4 {
+			*(*uint32)(unsafe.Pointer(buf)) = 8 // wrOff
+		}
+		return 0
+	}
+	if _, err := jl.ReadRTT(); err == nil || !strings.Contains(err.Error(), "JLINK_WriteMem") {
+		t.Fatalf("ReadRTT error = %v", err)
+	}
+}
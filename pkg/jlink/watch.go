@@ -0,0 +1,240 @@
+package jlink
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unsafe"
+)
+
+// MinWatchInterval 内存监视的最小轮询间隔
+const MinWatchInterval = 10 * time.Millisecond
+
+// WatchFormat 内存监视值的显示格式
+type WatchFormat string
+
+const (
+	WatchU8    WatchFormat = "u8"
+	WatchU16   WatchFormat = "u16"
+	WatchU32   WatchFormat = "u32"
+	WatchI32   WatchFormat = "i32"
+	WatchFloat WatchFormat = "float"
+	WatchHex   WatchFormat = "hex"
+)
+
+// ParseWatchFormat 解析显示格式并校验读取长度是否足够，空字符串视为 hex
+func ParseWatchFormat(name string, size int) (WatchFormat, error) {
+	f := WatchFormat(strings.ToLower(name))
+	need := 1
+	switch f {
+	case "":
+		f = WatchHex
+	case WatchU8, WatchHex:
+	case WatchU16:
+		need = 2
+	case WatchU32, WatchI32, WatchFloat:
+		need = 4
+	default:
+		return "", fmt.Errorf("未知的显示格式 %q (可选: u8, u16, u32, i32, float, hex)", name)
+	}
+	if size < need {
+		return "", fmt.Errorf("格式 %s 至少需要 %d 字节，当前为 %d", f, need, size)
+	}
+	return f, nil
+}
+
+// FormatWatchValue 按格式将小端内存数据格式化为字符串
+func FormatWatchValue(data []byte, f WatchFormat) string {
+	switch f {
+	case WatchU8:
+		return strconv.FormatUint(uint64(data[0]), 10)
+	case WatchU16:
+		return strconv.FormatUint(uint64(binary.LittleEndian.Uint16(data)), 10)
+	case WatchU32:
+		return strconv.FormatUint(uint64(binary.LittleEndian.Uint32(data)), 10)
+	case WatchI32:
+		return strconv.FormatInt(int64(int32(binary.LittleEndian.Uint32(data))), 10)
+	case WatchFloat:
+		return strconv.FormatFloat(float64(math.Float32frombits(binary.LittleEndian.Uint32(data))), 'g', -1, 32)
+	default:
+		return strings.ToUpper(hex.EncodeToString(data))
+	}
+}
+
+// ParseAddress 解析目标地址，支持 0x 前缀的十六进制和十进制
+func ParseAddress(s string) (uint32, error) {
+	v, err := strconv.ParseUint(strings.TrimSpace(s), 0, 32)
+	if err != nil {
+		return 0, fmt.Errorf("无效的地址 %q", s)
+	}
+	return uint32(v), nil
+}
+
+// Watch 内存监视项
+type Watch struct {
+	ID       string
+	Addr     uint32
+	Size     int
+	Interval time.Duration
+	Format   WatchFormat
+}
+
+// WatchEvent 监视值变化或读取失败时的通知
+type WatchEvent struct {
+	ID    string `json:"id"`
+	Addr  string `json:"addr"`
+	Value string `json:"value,omitempty"`
+	Raw   string `json:"raw,omitempty"`   // 原始字节 (十六进制)
+	Error string `json:"error,omitempty"` // 读取失败原因，非空时 Value 无效
+}
+
+// watchState 运行中的监视项
+type watchState struct {
+	Watch
+	stop chan struct{}
+	done chan struct{}
+}
+
+// ReadMemory 读取目标内存，size 不超过 64KB，与 RTT 轮询串行访问驱动
+func (jl *JLinkWrapper) ReadMemory(addr uint32, size int) ([]byte, error) {
+	if size <= 0 || size > maxRTTReadSize {
+		return nil, fmt.Errorf("读取长度 %d 超出范围 (1-%d)", size, maxRTTReadSize)
+	}
+	if jl.apiReadMem == nil {
+		return nil, fmt.Errorf("RTT API 未初始化")
+	}
+	buf := make([]byte, size)
+
+	if err := jl.gate.acquire(false); err != nil {
+		return nil, err
+	}
+	defer jl.gate.release()
+	if ret := jl.apiReadMem(addr, uint32(size), uintptr(unsafe.Pointer(&buf[0]))); ret < 0 {
+		return nil, fmt.Errorf("读取 0x%08X 失败 (返回值: %d)", addr, ret)
+	}
+	return buf, nil
+}
+
+// AddWatch 注册内存监视，由独立的 goroutine 按 Interval 轮询
+// 值发生变化时调用 notify；读取失败也会通知 (相同错误只通知一次)，不影响 RTT 会话
+// 已存在相同 ID 的监视项会被替换
+func (jl *JLinkWrapper) AddWatch(w Watch, notify func(WatchEvent)) error {
+	if w.ID == "" {
+		return fmt.Errorf("监视 ID 不能为空")
+	}
+	if w.Size <= 0 || w.Size > maxRTTReadSize {
+		return fmt.Errorf("读取长度 %d 超出范围 (1-%d)", w.Size, maxRTTReadSize)
+	}
+	f, err := ParseWatchFormat(string(w.Format), w.Size)
+	if err != nil {
+		return err
+	}
+	w.Format = f
+	if w.Interval < MinWatchInterval {
+		w.Interval = MinWatchInterval
+	}
+
+	jl.RemoveWatch(w.ID)
+
+	s := &watchState{Watch: w, stop: make(chan struct{}), done: make(chan struct{})}
+	jl.watchMu.Lock()
+	if jl.watches == nil {
+		jl.watches = make(map[string]*watchState)
+	}
+	jl.watches[w.ID] = s
+	jl.watchMu.Unlock()
+
+	go jl.pollWatch(s, notify)
+	return nil
+}
+
+// RemoveWatch 停止并移除监视项，返回是否存在
+func (jl *JLinkWrapper) RemoveWatch(id string) bool {
+	jl.watchMu.Lock()
+	s, ok := jl.watches[id]
+	delete(jl.watches, id)
+	jl.watchMu.Unlock()
+
+	if ok {
+		close(s.stop)
+		<-s.done
+	}
+	return ok
+}
+
+// Watches 返回当前的监视项，按 ID 排序
+func (jl *JLinkWrapper) Watches() []Watch {
+	jl.watchMu.Lock()
+	defer jl.watchMu.Unlock()
+
+	list := make([]Watch, 0, len(jl.watches))
+	for _, s := range jl.watches {
+		list = append(list, s.Watch)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	return list
+}
+
+// stopWatches 停止全部监视项并等待轮询 goroutine 退出
+func (jl *JLinkWrapper) stopWatches() {
+	jl.watchMu.Lock()
+	watches := jl.watches
+	jl.watches = nil
+	jl.watchMu.Unlock()
+
+	for _, s := range watches {
+		close(s.stop)
+	}
+	for _, s := range watches {
+		<-s.done
+	}
+}
+
+func (jl *JLinkWrapper) pollWatch(s *watchState, notify func(WatchEvent)) {
+	defer close(s.done)
+
+	ticker := time.NewTicker(s.Interval)
+	defer ticker.Stop()
+
+	addr := fmt.Sprintf("0x%08X", s.Addr)
+	var last []byte
+	lastErr := ""
+	for {
+		data, err := jl.ReadMemory(s.Addr, s.Size)
+		if errors.Is(err, ErrClosed) {
+			return
+		}
+		if err != nil {
+			if err.Error() != lastErr && notify != nil {
+				notify(WatchEvent{ID: s.ID, Addr: addr, Error: err.Error()})
+			}
+			lastErr = err.Error()
+			last = nil // 恢复后重新上报当前值
+		} else {
+			lastErr = ""
+			if last == nil || string(data) != string(last) {
+				last = data
+				if notify != nil {
+					notify(WatchEvent{
+						ID:    s.ID,
+						Addr:  addr,
+						Value: FormatWatchValue(data, s.Format),
+						Raw:   strings.ToUpper(hex.EncodeToString(data)),
+					})
+				}
+			}
+		}
+
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
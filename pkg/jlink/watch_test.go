@@ -0,0 +1,163 @@
+package jlink
+
+import (
+	"encoding/binary"
+	"math"
+	"sync"
+	"testing"
+	"time"
+	"unsafe"
+)
+
+func TestParseWatchFormat(t *testing.T) {
+	if f, err := ParseWatchFormat("", 3); err != nil || f != WatchHex {
+		t.Errorf("Expected hex default, got %q, %v", f, err)
+	}
+	if f, err := ParseWatchFormat("U32", 4); err != nil || f != WatchU32 {
+		t.Errorf("Expected u32, got %q, %v", f, err)
+	}
+	if _, err := ParseWatchFormat("float", 2); err == nil {
+		t.Error("Expected error for float with 2 bytes")
+	}
+	if _, err := ParseWatchFormat("u64", 8); err == nil {
+		t.Error("Expected error for unknown format")
+	}
+}
+
+func TestFormatWatchValue(t *testing.T) {
+	f := make([]byte, 4)
+	binary.LittleEndian.PutUint32(f, math.Float32bits(1.5))
+
+	tests := []struct {
+		data     []byte
+		format   WatchFormat
+		expected string
+	}{
+		{[]byte{0xFF, 0x01}, WatchU8, "255"},
+		{[]byte{0x34, 0x12}, WatchU16, "4660"},
+		{[]byte{0xFF, 0xFF, 0xFF, 0xFF}, WatchU32, "4294967295"},
+		{[]byte{0xFF, 0xFF, 0xFF, 0xFF}, WatchI32, "-1"},
+		{f, WatchFloat, "1.5"},
+		{[]byte{0xDE, 0xAD, 0xbe}, WatchHex, "DEADBE"},
+	}
+	for _, tt := range tests {
+		if got := FormatWatchValue(tt.data, tt.format); got != tt.expected {
+			t.Errorf("FormatWatchValue(% X, %s) = %q; expected %q", tt.data, tt.format, got, tt.expected)
+		}
+	}
+}
+
+func TestParseAddress(t *testing.T) {
+	if v, err := ParseAddress("0x20000010"); err != nil || v != 0x20000010 {
+		t.Errorf("ParseAddress hex = %X, %v", v, err)
+	}
+	if v, err := ParseAddress("1024"); err != nil || v != 1024 {
+		t.Errorf("ParseAddress decimal = %d, %v", v, err)
+	}
+	if _, err := ParseAddress("0x1FFFFFFFF"); err == nil {
+		t.Error("Expected error for address beyond 32 bits")
+	}
+}
+
+func TestReadMemorySizeLimit(t *testing.T) {
+	jl := &JLinkWrapper{}
+	jl.apiReadMem = func(uint32, uint32, uintptr) int { return 0 }
+	if _, err := jl.ReadMemory(0x20000000, maxRTTReadSize+1); err == nil {
+		t.Error("Expected error for read beyond 64KB")
+	}
+	if _, err := jl.ReadMemory(0x20000000, 0); err == nil {
+		t.Error("Expected error for zero-length read")
+	}
+}
+
+// TestWatchReportsChangesAndErrors verifies only changes are reported and read failures don't stop the watch
+func TestWatchReportsChangesAndErrors(t *testing.T) {
+	var mu sync.Mutex
+	value := byte(1)
+	failing := false
+
+	jl := &JLinkWrapper{}
+	jl.apiReadMem = func(addr uint32, size uint32, buf uintptr) int {
+		mu.Lock()
+		defer mu.Unlock()
+		if failing {
+			return -1
+		}
+		*(*byte)(unsafe.Pointer(buf)) = value
+		return 0
+	}
+
+	events := make(chan WatchEvent, 16)
+	if err := jl.AddWatch(Watch{ID: "state", Addr: 0x20000100, Size: 1, Format: WatchU8, Interval: MinWatchInterval}, func(e WatchEvent) {
+		events <- e
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	next := func() WatchEvent {
+		select {
+		case e := <-events:
+			return e
+		case <-time.After(time.Second):
+			t.Fatal("Timed out waiting for watch event")
+			return WatchEvent{}
+		}
+	}
+
+	if e := next(); e.Value != "1" || e.Addr != "0x20000100" {
+		t.Errorf("Unexpected initial event: %+v", e)
+	}
+
+	// 值不变时不应上报
+	select {
+	case e := <-events:
+		t.Errorf("Unexpected event without change: %+v", e)
+	case <-time.After(5 * MinWatchInterval):
+	}
+
+	mu.Lock()
+	value = 2
+	mu.Unlock()
+	if e := next(); e.Value != "2" {
+		t.Errorf("Expected value 2, got %+v", e)
+	}
+
+	mu.Lock()
+	failing = true
+	mu.Unlock()
+	if e := next(); e.Error == "" {
+		t.Errorf("Expected read failure event, got %+v", e)
+	}
+
+	mu.Lock()
+	failing = false
+	mu.Unlock()
+	if e := next(); e.Value != "2" {
+		t.Errorf("Expected value after recovery, got %+v", e)
+	}
+
+	if list := jl.Watches(); len(list) != 1 || list[0].ID != "state" {
+		t.Errorf("Unexpected watch list: %+v", list)
+	}
+	if !jl.RemoveWatch("state") {
+		t.Error("Expected RemoveWatch to find the watch")
+	}
+	if len(jl.Watches()) != 0 {
+		t.Error("Expected no watches after removal")
+	}
+}
+
+// TestStopWatches verifies Close's watch shutdown stops every polling goroutine
+func TestStopWatches(t *testing.T) {
+	jl := &JLinkWrapper{}
+	jl.apiReadMem = func(uint32, uint32, uintptr) int { return 0 }
+	for _, id := range []string{"a", "b"} {
+		if err := jl.AddWatch(Watch{ID: id, Addr: 0x20000000, Size: 4, Format: WatchHex}, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	jl.stopWatches()
+	if len(jl.Watches()) != 0 {
+		t.Error("Expected all watches to be stopped")
+	}
+}
@@ -0,0 +1,294 @@
+// Package jsonl 逐行解析接收数据中的 JSON 对象 (JSON Lines)，记录出现过的键及其类型
+//
+// 每行一个 JSON 对象；行可以跨越多个数据块，"\r\n" 与 "\n" 均视为行结束。不以 '{' 开头的行
+// (固件的普通日志) 计入 Skipped，以 '{' 开头但解析失败的行计入 Failures，两者都不影响之后的行。
+// 数字保留原有的类别：没有小数点与指数的整数解析为 int64，其余为 float64。
+// 键集合只增不减，固件增加或删除字段时无需重新配置 (模式漂移)。
+package jsonl
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// MaxLine 单行的最大字节数，超过后该行按解析失败丢弃
+const MaxLine = 64 << 10
+
+// MaxKeys 模式中记录的最多键数，防止以数据为键的对象使模式无限增长
+const MaxKeys = 1024
+
+// Options 解析选项
+type Options struct {
+	// Flatten 把嵌套对象展开为以 '.' 连接的键 (例如 {"imu":{"x":1}} 展开为 "imu.x")，数组保持原样
+	Flatten bool `json:"flatten"`
+}
+
+// Record 解析出的一行
+type Record struct {
+	Fields map[string]interface{} `json:"fields"`
+	Raw    string                 `json:"raw"`
+}
+
+// Key 模式中的一个键
+type Key struct {
+	Name  string   `json:"name"`
+	Types []string `json:"types"` // 出现过的类型：int、float、string、bool、null、object、array
+	Count int      `json:"count"` // 含该键的记录数
+}
+
+// Schema 到目前为止的键集合与统计
+type Schema struct {
+	Keys      []Key  `json:"keys"` // 按首次出现的顺序，同一记录中的新键按字母顺序
+	Records   int    `json:"records"`
+	Failures  int    `json:"failures"`
+	Skipped   int    `json:"skipped"`
+	Truncated bool   `json:"truncated,omitempty"` // 键数达到 MaxKeys，之后的新键未记录
+	LastError string `json:"lastError,omitempty"`
+}
+
+type keyState struct {
+	types map[string]bool
+	count int
+}
+
+// Decoder 流式解析器，非线程安全
+type Decoder struct {
+	opts      Options
+	line      []byte
+	overflow  bool // 当前行已超过 MaxLine，丢弃到行尾
+	keys      map[string]*keyState
+	order     []string
+	records   int
+	failures  int
+	skipped   int
+	truncated bool
+	lastErr   string
+}
+
+// New 创建解析器
+func New(opts Options) *Decoder {
+	return &Decoder{opts: opts, keys: make(map[string]*keyState)}
+}
+
+// Options 返回解析选项
+func (d *Decoder) Options() Options {
+	return d.opts
+}
+
+// Feed 输入一段数据，返回其中完整且解析成功的记录
+func (d *Decoder) Feed(data []byte) []Record {
+	var out []Record
+	for len(data) > 0 {
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			d.appendLine(data)
+			break
+		}
+		d.appendLine(data[:i])
+		data = data[i+1:]
+		if rec, ok := d.finishLine(); ok {
+			out = append(out, rec)
+		}
+	}
+	return out
+}
+
+func (d *Decoder) appendLine(b []byte) {
+	if d.overflow {
+		return
+	}
+	if len(d.line)+len(b) > MaxLine {
+		d.overflow = true
+		d.line = d.line[:0]
+		return
+	}
+	d.line = append(d.line, b...)
+}
+
+func (d *Decoder) finishLine() (Record, bool) {
+	line := bytes.TrimSpace(d.line)
+	overflow := d.overflow
+	d.overflow = false
+	defer func() { d.line = d.line[:0] }()
+
+	switch {
+	case overflow:
+		d.fail(fmt.Sprintf("line longer than %d bytes", MaxLine))
+		return Record{}, false
+	case len(line) == 0:
+		return Record{}, false
+	case line[0] != '{':
+		d.skipped++
+		return Record{}, false
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(line))
+	dec.UseNumber()
+	var obj map[string]interface{}
+	if err := dec.Decode(&obj); err != nil {
+		d.fail(err.Error())
+		return Record{}, false
+	}
+	if dec.More() {
+		d.fail("unexpected data after JSON object")
+		return Record{}, false
+	}
+
+	fields := make(map[string]interface{}, len(obj))
+	for _, k := range sortedKeys(obj) {
+		d.put(fields, k, convert(obj[k]))
+	}
+	d.records++
+	return Record{Fields: fields, Raw: string(line)}, true
+}
+
+func (d *Decoder) fail(msg string) {
+	d.failures++
+	d.lastErr = msg
+}
+
+// put 写入字段 (需要时展开嵌套对象) 并更新模式
+func (d *Decoder) put(fields map[string]interface{}, key string, v interface{}) {
+	if obj, ok := v.(map[string]interface{}); ok && d.opts.Flatten && len(obj) > 0 {
+		for _, k := range sortedKeys(obj) {
+			d.put(fields, key+"."+k, obj[k])
+		}
+		return
+	}
+	fields[key] = v
+	st, ok := d.keys[key]
+	if !ok {
+		if len(d.order) >= MaxKeys {
+			d.truncated = true
+			return
+		}
+		st = &keyState{types: make(map[string]bool)}
+		d.keys[key] = st
+		d.order = append(d.order, key)
+	}
+	st.types[typeName(v)] = true
+	st.count++
+}
+
+// sortedKeys 返回按字母排序的键，使同一记录中新出现的键在模式中的顺序固定
+func sortedKeys(obj map[string]interface{}) []string {
+	keys := make([]string, 0, len(obj))
+	for k := range obj {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// convert 把 json.Number 转换为 int64 或 float64
+func convert(v interface{}) interface{} {
+	switch x := v.(type) {
+	case json.Number:
+		s := string(x)
+		if !strings.ContainsAny(s, ".eE") {
+			if n, err := x.Int64(); err == nil {
+				return n
+			}
+		}
+		f, _ := x.Float64()
+		return f
+	case map[string]interface{}:
+		for k, child := range x {
+			x[k] = convert(child)
+		}
+		return x
+	case []interface{}:
+		for i, child := range x {
+			x[i] = convert(child)
+		}
+		return x
+	}
+	return v
+}
+
+func typeName(v interface{}) string {
+	switch v.(type) {
+	case int64:
+		return "int"
+	case float64:
+		return "float"
+	case string:
+		return "string"
+	case bool:
+		return "bool"
+	case nil:
+		return "null"
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	}
+	return "unknown"
+}
+
+// Schema 返回键集合与统计
+func (d *Decoder) Schema() Schema {
+	s := Schema{
+		Keys:      make([]Key, 0, len(d.order)),
+		Records:   d.records,
+		Failures:  d.failures,
+		Skipped:   d.skipped,
+		Truncated: d.truncated,
+		LastError: d.lastErr,
+	}
+	for _, name := range d.order {
+		st := d.keys[name]
+		types := make([]string, 0, len(st.types))
+		for t := range st.types {
+			types = append(types, t)
+		}
+		sort.Strings(types)
+		s.Keys = append(s.Keys, Key{Name: name, Types: types, Count: st.count})
+	}
+	return s
+}
+
+// Values 按 keys 的顺序取出记录中的数值 (int、float 或 bool)，缺失或非数值的键为 NaN；
+// 所有键都缺失时 ok 为 false。不展开时可以用 "a.b" 访问嵌套对象
+func Values(fields map[string]interface{}, keys []string) (values []float64, ok bool) {
+	values = make([]float64, len(keys))
+	for i, key := range keys {
+		v, found := lookup(fields, key)
+		values[i] = math.NaN()
+		if !found {
+			continue
+		}
+		switch x := v.(type) {
+		case int64:
+			values[i], ok = float64(x), true
+		case float64:
+			values[i], ok = x, true
+		case bool:
+			values[i], ok = 0, true
+			if x {
+				values[i] = 1
+			}
+		}
+	}
+	return values, ok
+}
+
+// lookup 先按完整键查找，再按 '.' 逐级查找嵌套对象
+func lookup(fields map[string]interface{}, key string) (interface{}, bool) {
+	if v, ok := fields[key]; ok {
+		return v, true
+	}
+	head, rest, found := strings.Cut(key, ".")
+	if !found {
+		return nil, false
+	}
+	child, ok := fields[head].(map[string]interface{})
+	if !ok {
+		return nil, false
+	}
+	return lookup(child, rest)
+}
@@ -0,0 +1,120 @@
+package jsonl
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestFeedAcrossChunks(t *testing.T) {
+	d := New(Options{})
+	input := "boot ok\r\n{\"t\":1,\"temp\":21.5,\"ok\":true}\r\n{\"t\":2,\"te" + "mp\":22}\n\n"
+	var recs []Record
+	for i := 0; i < len(input); i += 7 {
+		recs = append(recs, d.Feed([]byte(input[i:min(i+7, len(input))]))...)
+	}
+	if len(recs) != 2 {
+		t.Fatalf("%d records", len(recs))
+	}
+	if v, ok := recs[0].Fields["t"].(int64); !ok || v != 1 {
+		t.Errorf("t = %#v, want int64", recs[0].Fields["t"])
+	}
+	if v, ok := recs[0].Fields["temp"].(float64); !ok || v != 21.5 {
+		t.Errorf("temp = %#v, want float64", recs[0].Fields["temp"])
+	}
+	if recs[1].Raw != `{"t":2,"temp":22}` {
+		t.Errorf("raw = %q", recs[1].Raw)
+	}
+
+	s := d.Schema()
+	if s.Records != 2 || s.Skipped != 1 || s.Failures != 0 {
+		t.Errorf("schema = %+v", s)
+	}
+	if len(s.Keys) != 3 || s.Keys[2].Name != "temp" || strings.Join(s.Keys[2].Types, ",") != "float,int" || s.Keys[2].Count != 2 {
+		t.Errorf("keys = %+v", s.Keys)
+	}
+}
+
+func TestFailuresDoNotInterruptStream(t *testing.T) {
+	d := New(Options{})
+	recs := d.Feed([]byte("{\"a\":1\n{\"a\":2} trailing\n{\"a\":3}\n"))
+	if len(recs) != 1 || recs[0].Fields["a"] != int64(3) {
+		t.Fatalf("records = %+v", recs)
+	}
+	if s := d.Schema(); s.Failures != 2 || s.LastError == "" {
+		t.Errorf("schema = %+v", s)
+	}
+
+	// 超长的行整行丢弃，之后的行正常解析
+	long := "{\"x\":\"" + strings.Repeat("y", MaxLine) + "\"}\n"
+	recs = d.Feed([]byte(long + "{\"a\":4}\n"))
+	if len(recs) != 1 || recs[0].Fields["a"] != int64(4) || d.Schema().Failures != 3 {
+		t.Errorf("after long line: %+v, %+v", recs, d.Schema())
+	}
+}
+
+func TestFlattenAndSchemaDrift(t *testing.T) {
+	d := New(Options{Flatten: true})
+	recs := d.Feed([]byte(`{"imu":{"x":1,"y":-2.5},"tags":[1,2]}` + "\n" + `{"imu":{"x":3,"z":9007199254740993}}` + "\n"))
+	if len(recs) != 2 {
+		t.Fatalf("%d records", len(recs))
+	}
+	if recs[0].Fields["imu.y"] != -2.5 || recs[1].Fields["imu.z"] != int64(9007199254740993) {
+		t.Errorf("fields = %+v / %+v", recs[0].Fields, recs[1].Fields)
+	}
+	names := []string{}
+	for _, k := range d.Schema().Keys {
+		names = append(names, k.Name)
+	}
+	// 新出现的键追加在后，缺失的键保留
+	if strings.Join(names, ",") != "imu.x,imu.y,tags,imu.z" {
+		t.Errorf("keys = %v", names)
+	}
+
+	nested := New(Options{})
+	rec := nested.Feed([]byte(`{"imu":{"x":1}}` + "\n"))[0]
+	if _, ok := rec.Fields["imu"].(map[string]interface{}); !ok {
+		t.Errorf("nested object was flattened: %+v", rec.Fields)
+	}
+	if k := nested.Schema().Keys; len(k) != 1 || k[0].Types[0] != "object" {
+		t.Errorf("keys = %+v", k)
+	}
+}
+
+func TestValues(t *testing.T) {
+	d := New(Options{})
+	rec := d.Feed([]byte(`{"t":5,"v":1.5,"on":true,"name":"x","imu":{"x":-1}}` + "\n"))[0]
+	got, ok := Values(rec.Fields, []string{"v", "t", "on", "name", "missing", "imu.x"})
+	if !ok {
+		t.Fatal("ok = false")
+	}
+	want := []float64{1.5, 5, 1, math.NaN(), math.NaN(), -1}
+	for i := range want {
+		if got[i] != want[i] && !(math.IsNaN(got[i]) && math.IsNaN(want[i])) {
+			t.Errorf("values[%d] = %v, want %v", i, got[i], want[i])
+		}
+	}
+	if _, ok := Values(rec.Fields, []string{"missing"}); ok {
+		t.Error("ok = true with every key missing")
+	}
+}
+
+func TestMaxKeys(t *testing.T) {
+	d := New(Options{})
+	var sb strings.Builder
+	sb.WriteString("{")
+	for i := 0; i < MaxKeys+10; i++ {
+		if i > 0 {
+			sb.WriteString(",")
+		}
+		sb.WriteString(`"k` + strconv.Itoa(i) + `":1`)
+	}
+	sb.WriteString("}\n")
+	if recs := d.Feed([]byte(sb.String())); len(recs) != 1 || len(recs[0].Fields) != MaxKeys+10 {
+		t.Fatalf("record not decoded in full")
+	}
+	if s := d.Schema(); len(s.Keys) != MaxKeys || !s.Truncated {
+		t.Errorf("%d keys, truncated %v", len(s.Keys), s.Truncated)
+	}
+}
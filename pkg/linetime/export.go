@@ -0,0 +1,117 @@
+package linetime
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"serial-assistant/pkg/format"
+)
+
+// Format 导出格式
+type Format string
+
+const (
+	CSV  Format = "csv"
+	Text Format = "text"
+)
+
+// ParseFormat 解析导出格式，空字符串为 csv
+func ParseFormat(name string) (Format, error) {
+	switch Format(strings.ToLower(strings.TrimSpace(name))) {
+	case "", CSV:
+		return CSV, nil
+	case Text, "txt":
+		return Text, nil
+	}
+	return "", fmt.Errorf("unknown export format %q (expected csv or text)", name)
+}
+
+// Writer 将行及其耗时写成 CSV 或文本
+//
+// CSV 的列为 timestamp、delta_us、since_mark_us、mark、line、annotation，标注单独占一行；
+// 文本每行为 "[时间] +距上一行 T+距标记 行内容"，计时标记所在的行以 "*" 代替 "T"，
+// 标注与文件头写成 "#" 开头的行 (见 format.AnnotationLine)
+type Writer struct {
+	buf    *bufio.Writer
+	csv    *csv.Writer
+	format Format
+	rows   int
+}
+
+// NewWriter 创建写入器，comments 的每一行写成 "# " 开头的注释 (例如文件头说明)，CSV 还写入表头
+func NewWriter(w io.Writer, f Format, comments []string) (*Writer, error) {
+	buf := bufio.NewWriter(w)
+	lw := &Writer{buf: buf, format: f}
+	for _, line := range comments {
+		if _, err := buf.WriteString("# " + strings.TrimRight(line, "\r\n") + "\n"); err != nil {
+			return nil, err
+		}
+	}
+	if f == CSV {
+		lw.csv = csv.NewWriter(buf)
+		if err := lw.csv.Write([]string{"timestamp", "delta_us", "since_mark_us", "mark", "line", "annotation"}); err != nil {
+			return nil, err
+		}
+	}
+	return lw, nil
+}
+
+// WriteLine 写入一行，at 为该行的接收时间
+func (w *Writer) WriteLine(at time.Time, line []byte, l Line) error {
+	w.rows++
+	if w.csv != nil {
+		mark := ""
+		if l.Mark {
+			mark = "1"
+		}
+		return w.csv.Write([]string{at.Format(time.RFC3339Nano), strconv.FormatInt(l.DeltaUs, 10),
+			strconv.FormatInt(l.SinceMarkUs, 10), mark, string(line), ""})
+	}
+	sign := "T"
+	if l.Mark {
+		sign = "*"
+	}
+	_, err := fmt.Fprintf(w.buf, "[%s] +%s %s+%s %s\n", at.Format("2006-01-02 15:04:05.000000"),
+		seconds(l.DeltaUs), sign, seconds(l.SinceMarkUs), line)
+	return err
+}
+
+// WriteAnnotation 写入一条标注
+func (w *Writer) WriteAnnotation(at time.Time, text string) error {
+	w.rows++
+	if w.csv != nil {
+		return w.csv.Write([]string{at.Format(time.RFC3339Nano), "", "", "", "", text})
+	}
+	_, err := w.buf.WriteString(format.AnnotationLine(at, text))
+	return err
+}
+
+// Rows 返回已写入的行数 (包括标注)
+func (w *Writer) Rows() int {
+	return w.rows
+}
+
+// Close 写入缓冲的数据，不关闭底层 Writer
+func (w *Writer) Close() error {
+	if w.csv != nil {
+		w.csv.Flush()
+		if err := w.csv.Error(); err != nil {
+			return err
+		}
+	}
+	return w.buf.Flush()
+}
+
+// seconds 将微秒格式化为带 6 位小数的秒数
+func seconds(us int64) string {
+	sign := ""
+	if us < 0 {
+		sign, us = "-", -us
+	}
+	return fmt.Sprintf("%s%d.%06ds", sign, us/1e6, us%1e6)
+}
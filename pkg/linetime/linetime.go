@@ -0,0 +1,162 @@
+// Package linetime 计算接收行之间的耗时 (微秒精度)：距上一行与距计时标记，
+// 用于测量固件启动各阶段的时间而无需手动相减时间戳
+//
+// 时间总是调用方传入的接收时间戳 (读取数据的时刻，与历史记录一致)，而不是事件发送或显示的时间，
+// 因此实时计算与按历史记录重新计算 (导出) 的结果相同。计时标记可以手动设置 (Mark)，
+// 也可以由触发规则在匹配的行上自动设置 (例如 "^BOOT")，该行的距标记耗时为 0。
+package linetime
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"time"
+)
+
+const (
+	// MaxLineLen 跟踪未结束行时保留的最大字节数，超出部分不参与匹配
+	MaxLineLen = 4096
+	// MaxTriggers 触发规则的最大数量
+	MaxTriggers = 16
+)
+
+// Line 数据块中结束的一行的耗时
+type Line struct {
+	End         int   `json:"end"`         // 该行在当前数据块中的结束位置 (不含，包括 '\n')，同 classify.LineClass
+	DeltaUs     int64 `json:"deltaUs"`     // 距上一行结束的微秒数，第一行距计时开始
+	SinceMarkUs int64 `json:"sinceMarkUs"` // 距计时标记的微秒数
+	Mark        bool  `json:"mark,omitempty"`
+}
+
+// CompileTriggers 校验并编译触发规则 (正则表达式，匹配不含行尾的一行)
+func CompileTriggers(patterns []string) ([]*regexp.Regexp, error) {
+	if len(patterns) > MaxTriggers {
+		return nil, fmt.Errorf("too many mark triggers (%d, max %d)", len(patterns), MaxTriggers)
+	}
+	out := make([]*regexp.Regexp, 0, len(patterns))
+	for i, p := range patterns {
+		if p == "" {
+			return nil, fmt.Errorf("mark trigger %d: pattern is empty", i+1)
+		}
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("mark trigger %d: %v", i+1, err)
+		}
+		out = append(out, re)
+	}
+	return out, nil
+}
+
+// Timer 跟踪跨数据块的行并计算耗时，每条连接一个，不是线程安全的
+// 未结束的行按来源 (TCP Server 的客户端、UDP 数据报的来源) 分别保存，不同来源的数据不会拼成一行；
+// 耗时与标记在所有来源间共用
+type Timer struct {
+	triggers []*regexp.Regexp
+	prev     time.Time // 上一行结束的时间
+	mark     time.Time
+	partial  map[string][]byte
+}
+
+// New 创建计时器，patterns 为触发规则 (见 CompileTriggers)
+func New(patterns []string) (*Timer, error) {
+	triggers, err := CompileTriggers(patterns)
+	if err != nil {
+		return nil, err
+	}
+	return &Timer{triggers: triggers, partial: make(map[string][]byte)}, nil
+}
+
+// Reset 丢弃未结束的行，并以 at 作为计时开始 (新连接)
+func (t *Timer) Reset(at time.Time) {
+	t.partial = make(map[string][]byte)
+	t.prev = time.Time{}
+	t.Mark(at)
+}
+
+// Forget 丢弃 source 未结束的行 (客户端断开时)
+func (t *Timer) Forget(source string) {
+	delete(t.partial, source)
+}
+
+// Mark 设置计时标记；还没有收到行时 at 同时作为第一行耗时的起点
+func (t *Timer) Mark(at time.Time) {
+	t.mark = at
+	if t.prev.IsZero() {
+		t.prev = at
+	}
+}
+
+// Feed 计算 chunk 中结束的每一行的耗时，at 为 chunk 的接收时间
+func (t *Timer) Feed(chunk []byte, at time.Time) []Line {
+	return t.FeedFrom("", chunk, at)
+}
+
+// FeedFrom 同 Feed，chunk 来自 source，与该来源之前未结束的行拼接
+func (t *Timer) FeedFrom(source string, chunk []byte, at time.Time) []Line {
+	var out []Line
+	t.FeedFuncFrom(source, chunk, at, func(_ []byte, l Line) {
+		out = append(out, l)
+	})
+	return out
+}
+
+// FeedFunc 同 Feed，对每一行调用 fn；line 为完整的行 (不含行尾，跨块的行超过 MaxLineLen 的部分被截去)，
+// 只在回调期间有效
+func (t *Timer) FeedFunc(chunk []byte, at time.Time, fn func(line []byte, l Line)) {
+	t.FeedFuncFrom("", chunk, at, fn)
+}
+
+// FeedFuncFrom 同 FeedFunc，chunk 来自 source
+func (t *Timer) FeedFuncFrom(source string, chunk []byte, at time.Time, fn func(line []byte, l Line)) {
+	if t.partial == nil {
+		t.partial = make(map[string][]byte)
+	}
+	partial := t.partial[source]
+	start := 0
+	for {
+		i := bytes.IndexByte(chunk[start:], '\n')
+		if i < 0 {
+			break
+		}
+		end := start + i + 1
+		line := chunk[start : end-1]
+		if len(partial) > 0 {
+			line = append(partial, line...)
+			partial = line[:0]
+		}
+		fn(bytes.TrimSuffix(line, []byte{'\r'}), t.next(line, at, end))
+		start = end
+	}
+
+	if rest := chunk[start:]; len(rest) > 0 {
+		if room := MaxLineLen - len(partial); room > 0 {
+			if len(rest) > room {
+				rest = rest[:room]
+			}
+			partial = append(partial, rest...)
+		}
+	}
+	if len(partial) > 0 {
+		t.partial[source] = partial
+	} else {
+		delete(t.partial, source)
+	}
+}
+
+func (t *Timer) next(line []byte, at time.Time, end int) Line {
+	if t.prev.IsZero() {
+		t.Mark(at)
+	}
+	l := Line{End: end, DeltaUs: at.Sub(t.prev).Microseconds()}
+	t.prev = at
+	line = bytes.TrimSuffix(line, []byte{'\r'})
+	for _, re := range t.triggers {
+		if re.Match(line) {
+			t.mark = at
+			l.Mark = true
+			break
+		}
+	}
+	l.SinceMarkUs = at.Sub(t.mark).Microseconds()
+	return l
+}
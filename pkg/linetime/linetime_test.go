@@ -0,0 +1,180 @@
+package linetime
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeClock 手动推进的时钟，模拟数据块的接收时间
+type fakeClock struct{ t time.Time }
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{t: time.Date(2026, 10, 15, 8, 0, 0, 0, time.UTC)}
+}
+
+func (c *fakeClock) Now() time.Time { return c.t }
+
+func (c *fakeClock) Advance(d time.Duration) time.Time {
+	c.t = c.t.Add(d)
+	return c.t
+}
+
+func TestDeltas(t *testing.T) {
+	clock := newFakeClock()
+	timer, err := New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	timer.Reset(clock.Now())
+
+	lines := timer.Feed([]byte("stage1 done\r\n"), clock.Advance(1500*time.Microsecond))
+	if len(lines) != 1 || lines[0].DeltaUs != 1500 || lines[0].SinceMarkUs != 1500 || lines[0].End != 13 {
+		t.Fatalf("first line = %+v", lines)
+	}
+
+	// 跨块的行按结束时的数据块计时
+	if lines := timer.Feed([]byte("stage2 "), clock.Advance(10*time.Millisecond)); len(lines) != 0 {
+		t.Fatalf("unterminated line reported: %+v", lines)
+	}
+	lines = timer.Feed([]byte("done\nstage3 done\n"), clock.Advance(2345*time.Microsecond))
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 lines, got %+v", lines)
+	}
+	if lines[0].DeltaUs != 12345 || lines[0].SinceMarkUs != 13845 || lines[0].End != 5 {
+		t.Errorf("stage2 = %+v", lines[0])
+	}
+	// 同一数据块中的行接收时间相同
+	if lines[1].DeltaUs != 0 || lines[1].SinceMarkUs != 13845 {
+		t.Errorf("stage3 = %+v", lines[1])
+	}
+
+	// 手动标记
+	timer.Mark(clock.Advance(time.Second))
+	lines = timer.Feed([]byte("after\n"), clock.Advance(250*time.Microsecond))
+	if lines[0].DeltaUs != 1000250 || lines[0].SinceMarkUs != 250 || lines[0].Mark {
+		t.Errorf("after mark = %+v", lines[0])
+	}
+}
+
+func TestTriggerMarks(t *testing.T) {
+	clock := newFakeClock()
+	timer, err := New([]string{`^BOOT`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	timer.Reset(clock.Now())
+
+	timer.Feed([]byte("noise\n"), clock.Advance(time.Second))
+	lines := timer.Feed([]byte("BOOT v1.2\r\n"), clock.Advance(3*time.Second))
+	if !lines[0].Mark || lines[0].SinceMarkUs != 0 || lines[0].DeltaUs != 3000000 {
+		t.Errorf("trigger line = %+v", lines[0])
+	}
+	lines = timer.Feed([]byte("clock ok\nBOOTLOADER skipped\n"), clock.Advance(42*time.Millisecond))
+	if lines[0].SinceMarkUs != 42000 || lines[0].Mark {
+		t.Errorf("line after trigger = %+v", lines[0])
+	}
+	if !lines[1].Mark || lines[1].SinceMarkUs != 0 {
+		t.Errorf("second trigger = %+v", lines[1])
+	}
+}
+
+func TestFirstLineWithoutReset(t *testing.T) {
+	clock := newFakeClock()
+	timer, _ := New(nil)
+	lines := timer.Feed([]byte("a\n"), clock.Advance(time.Second))
+	if lines[0].DeltaUs != 0 || lines[0].SinceMarkUs != 0 {
+		t.Errorf("first line = %+v", lines[0])
+	}
+}
+
+func TestCompileTriggers(t *testing.T) {
+	for _, patterns := range [][]string{{""}, {"("}, make([]string, MaxTriggers+1)} {
+		if _, err := New(patterns); err == nil {
+			t.Errorf("New(%q): expected an error", patterns)
+		}
+	}
+}
+
+func TestWriter(t *testing.T) {
+	clock := newFakeClock()
+	timer, _ := New([]string{`^BOOT`})
+	timer.Reset(clock.Now())
+
+	for _, f := range []Format{CSV, Text} {
+		var buf bytes.Buffer
+		w, err := NewWriter(&buf, f, []string{"header"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		at := clock.Advance(1234567 * time.Microsecond)
+		timer.FeedFunc([]byte("BOOT\nstage1, \"ok\"\n"), at, func(line []byte, l Line) {
+			if err := w.WriteLine(at, line, l); err != nil {
+				t.Fatal(err)
+			}
+		})
+		w.WriteAnnotation(at, "note")
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+		got := buf.String()
+		var want []string
+		if f == CSV {
+			want = []string{
+				"# header\ntimestamp,delta_us,since_mark_us,mark,line,annotation\n",
+				",1234567,0,1,BOOT,\n",
+				`,0,0,,"stage1, ""ok""",` + "\n",
+				",,,,,note\n",
+			}
+		} else {
+			want = []string{"# header\n", "] +1.234567s *+0.000000s BOOT\n", "] +0.000000s T+0.000000s stage1, \"ok\"\n", "] note\n"}
+		}
+		for _, s := range want {
+			if !strings.Contains(got, s) {
+				t.Errorf("%s output missing %q:\n%s", f, s, got)
+			}
+		}
+		if w.Rows() != 3 {
+			t.Errorf("Rows = %d", w.Rows())
+		}
+	}
+	if s := seconds(1234567); s != "1.234567s" {
+		t.Errorf("seconds = %s", s)
+	}
+	if f, err := ParseFormat("TXT"); err != nil || f != Text {
+		t.Errorf("ParseFormat = %v, %v", f, err)
+	}
+}
+
+func TestSourcesKeepSeparateLines(t *testing.T) {
+	clock := newFakeClock()
+	timer, _ := New(nil)
+	timer.Reset(clock.Now())
+
+	if lines := timer.FeedFrom("10.0.0.2:5000", []byte("temp="), clock.Advance(time.Millisecond)); len(lines) != 0 {
+		t.Fatalf("unterminated line reported: %+v", lines)
+	}
+	// 另一个来源的行不与之前的部分拼接
+	var got []string
+	timer.FeedFuncFrom("10.0.0.3:5001", []byte("ok\n"), clock.Advance(time.Millisecond), func(line []byte, l Line) {
+		got = append(got, string(line))
+	})
+	timer.FeedFuncFrom("10.0.0.2:5000", []byte("21.5\n"), clock.Advance(time.Millisecond), func(line []byte, l Line) {
+		got = append(got, string(line))
+		if l.DeltaUs != 1000 {
+			t.Errorf("delta = %d, expected the shared delta", l.DeltaUs)
+		}
+	})
+	if len(got) != 2 || got[0] != "ok" || got[1] != "temp=21.5" {
+		t.Errorf("lines = %q", got)
+	}
+
+	timer.FeedFrom("10.0.0.2:5000", []byte("partial"), clock.Advance(time.Millisecond))
+	timer.Forget("10.0.0.2:5000")
+	timer.FeedFuncFrom("10.0.0.2:5000", []byte("new\n"), clock.Advance(time.Millisecond), func(line []byte, _ Line) {
+		if string(line) != "new" {
+			t.Errorf("line after Forget = %q", line)
+		}
+	})
+}
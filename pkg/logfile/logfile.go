@@ -0,0 +1,94 @@
+// Package logfile 创建导出与抓包文件，可选 gzip 压缩
+package logfile
+
+import (
+	"compress/gzip"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FlushInterval 压缩流的最小刷新间隔：进程崩溃时最多丢失最后一个间隔内的数据
+const FlushInterval = time.Second
+
+// File 导出文件，压缩时 Write 经过 gzip 写入磁盘，线程安全
+type File struct {
+	path string
+
+	mu        sync.Mutex
+	f         *os.File
+	gz        *gzip.Writer
+	lastFlush time.Time
+	now       func() time.Time
+}
+
+// Create 创建文件 (已存在时覆盖)；compress 时文件名不以 ".gz" 结尾则自动追加
+func Create(path string, compress bool) (*File, error) {
+	if compress && !strings.HasSuffix(strings.ToLower(path), ".gz") {
+		path += ".gz"
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+
+	lf := &File{path: path, f: f, now: time.Now}
+	if compress {
+		lf.gz = gzip.NewWriter(f)
+		lf.lastFlush = lf.now()
+	}
+	return lf, nil
+}
+
+// Path 返回实际的文件路径 (压缩时带 ".gz")
+func (lf *File) Path() string {
+	return lf.path
+}
+
+// Compressed 返回文件是否为 gzip 压缩
+func (lf *File) Compressed() bool {
+	return lf.gz != nil
+}
+
+func (lf *File) Write(p []byte) (int, error) {
+	lf.mu.Lock()
+	defer lf.mu.Unlock()
+
+	if lf.gz != nil {
+		return lf.gz.Write(p)
+	}
+	return lf.f.Write(p)
+}
+
+// Flush 将压缩流刷新到可解压的边界，距上次刷新不足 FlushInterval 时跳过
+// (调用方可以在每次写入后调用而不影响压缩率)；未压缩的文件无需刷新
+func (lf *File) Flush() error {
+	lf.mu.Lock()
+	defer lf.mu.Unlock()
+
+	if lf.gz == nil {
+		return nil
+	}
+	now := lf.now()
+	if now.Sub(lf.lastFlush) < FlushInterval {
+		return nil
+	}
+	lf.lastFlush = now
+	return lf.gz.Flush()
+}
+
+// Close 结束压缩流 (写入 gzip 尾部) 并关闭文件
+func (lf *File) Close() error {
+	lf.mu.Lock()
+	defer lf.mu.Unlock()
+
+	var err error
+	if lf.gz != nil {
+		err = lf.gz.Close()
+	}
+	if closeErr := lf.f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
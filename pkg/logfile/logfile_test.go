@@ -0,0 +1,111 @@
+package logfile
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestUncompressed(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "capture.log")
+	lf, err := Create(path, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lf.Path() != path || lf.Compressed() {
+		t.Errorf("Unexpected path %q", lf.Path())
+	}
+	lf.Write([]byte("hello"))
+	if err := lf.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "hello" {
+		t.Errorf("Unexpected content %q", data)
+	}
+}
+
+func TestCompressedRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	lf, err := Create(filepath.Join(dir, "capture.log"), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lf.Path() != filepath.Join(dir, "capture.log.gz") {
+		t.Errorf("Expected .gz suffix, got %q", lf.Path())
+	}
+	again, _ := Create(filepath.Join(dir, "x.GZ"), true)
+	defer again.Close()
+	if again.Path() != filepath.Join(dir, "x.GZ") {
+		t.Errorf("Expected existing .gz suffix to be kept, got %q", again.Path())
+	}
+
+	payload := bytes.Repeat([]byte("temperature=23.5 humidity=41\n"), 1000)
+	lf.Write(payload)
+	if err := lf.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	got := readGzip(t, lf.Path())
+	if !bytes.Equal(got, payload) {
+		t.Errorf("Round trip mismatch: %d bytes, expected %d", len(got), len(payload))
+	}
+	if info, _ := os.Stat(lf.Path()); info.Size() > int64(len(payload)/10) {
+		t.Errorf("Expected repetitive text to compress well, got %d bytes", info.Size())
+	}
+}
+
+// TestUnclosedFileReadableToLastFlush 模拟崩溃：未调用 Close 时，最后一次刷新之前的数据仍可解压
+func TestUnclosedFileReadableToLastFlush(t *testing.T) {
+	lf, err := Create(filepath.Join(t.TempDir(), "crash.log"), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	lf.now = func() time.Time { return now }
+
+	lf.Write([]byte("line 1\n"))
+	lf.Flush() // 距创建不足 FlushInterval，跳过
+	now = now.Add(FlushInterval)
+	lf.Write([]byte("line 2\n"))
+	if err := lf.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	lf.Write([]byte("line 3 (lost)\n"))
+	defer lf.f.Close()
+
+	raw, _ := os.ReadFile(lf.Path())
+	zr, err := gzip.NewReader(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatalf("gzip header unreadable: %v", err)
+	}
+	got, err := io.ReadAll(zr)
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("Expected unexpected EOF for an unfinished stream, got %v", err)
+	}
+	if string(got) != "line 1\nline 2\n" {
+		t.Errorf("Expected data up to the last flush, got %q", got)
+	}
+}
+
+func readGzip(t *testing.T, path string) []byte {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
@@ -0,0 +1,66 @@
+// Package loghdr 生成导出文件与连接开始时的说明头，使日后找到的日志文件可以自我说明
+//
+// 头部由模板展开得到，模板语法与发送模板相同 (见 tmpl 包)，额外支持以下占位符：
+//
+//	{{app}}         应用名称
+//	{{version}}     应用版本
+//	{{connection}}  连接字符串 (见 connspec 包)，未连接时为 "not connected"
+//	{{pipeline}}    收发处理设置摘要
+//
+// 头部只写入文件与接收区的标注，不会发送到连接。
+package loghdr
+
+import (
+	"strings"
+	"time"
+
+	"serial-assistant/pkg/tmpl"
+)
+
+// DefaultTemplate 未自定义时使用的模板
+const DefaultTemplate = "{{app}} {{version}}\n" +
+	"Started: {{ts_iso}}\n" +
+	"Connection: {{connection}}\n" +
+	"Pipeline: {{pipeline}}"
+
+// MaxTemplateLen 自定义模板的最大长度
+const MaxTemplateLen = 4096
+
+// Info 展开头部所需的上下文
+type Info struct {
+	App        string
+	Version    string
+	Time       time.Time
+	Connection string // 为空表示未连接
+	Pipeline   string
+}
+
+// Render 展开模板，template 为空时使用 DefaultTemplate；返回的文本不含结尾换行
+func Render(template string, info Info) (string, error) {
+	if template == "" {
+		template = DefaultTemplate
+	}
+	conn := info.Connection
+	if conn == "" {
+		conn = "not connected"
+	}
+	text, err := tmpl.ExpandText(template, info.Time, map[string]string{
+		"app":        info.App,
+		"version":    info.Version,
+		"connection": conn,
+		"pipeline":   info.Pipeline,
+	})
+	if err != nil {
+		return "", err
+	}
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	return strings.TrimRight(text, "\n"), nil
+}
+
+// Lines 将头部拆分为行，空头部返回 nil
+func Lines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(text, "\n")
+}
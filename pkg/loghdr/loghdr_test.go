@@ -0,0 +1,61 @@
+package loghdr
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testInfo = Info{
+	App:        "Serial Mate",
+	Version:    "v1.2.3",
+	Time:       time.Date(2024, 3, 4, 5, 6, 7, 0, time.UTC),
+	Connection: "serial:COM7@115200,8N1",
+	Pipeline:   "hex=group1, echo-suppression",
+}
+
+func TestRenderDefault(t *testing.T) {
+	got, err := Render("", testInfo)
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	want := []string{
+		"Serial Mate v1.2.3",
+		"Started: 2024-03-04T05:06:07.000Z",
+		"Connection: serial:COM7@115200,8N1",
+		"Pipeline: hex=group1, echo-suppression",
+	}
+	if !reflect.DeepEqual(Lines(got), want) {
+		t.Errorf("Render = %q", got)
+	}
+}
+
+func TestRenderCustom(t *testing.T) {
+	got, err := Render("bench {{connection}} @ {{ts_ms}}\r\n\n", Info{Time: testInfo.Time})
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	if want := "bench not connected @ 1709528767000"; got != want {
+		t.Errorf("Render = %q, want %q", got, want)
+	}
+}
+
+func TestRenderErrors(t *testing.T) {
+	for _, template := range []string{"{{seq}}", "{{operator}}", "{{app"} {
+		if _, err := Render(template, testInfo); err == nil {
+			t.Errorf("Render(%q) should fail", template)
+		} else if !strings.Contains(err.Error(), "placeholder") && !strings.Contains(err.Error(), "seq") {
+			t.Errorf("Render(%q) error = %v", template, err)
+		}
+	}
+}
+
+func TestLines(t *testing.T) {
+	if Lines("") != nil {
+		t.Error("empty header should have no lines")
+	}
+	if got := Lines("a\nb"); !reflect.DeepEqual(got, []string{"a", "b"}) {
+		t.Errorf("Lines = %q", got)
+	}
+}
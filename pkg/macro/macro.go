@@ -0,0 +1,317 @@
+// Package macro 按顺序执行宏的各个步骤：展开发送模板并发送，等待接收数据匹配正则表达式，
+// 并把匹配的分组保存到会话环境中，供之后的步骤以 {{env.NAME}} 引用
+//
+// 每一步的结果区分模板错误 (包括引用了未定义的变量)、发送失败、期望未满足 (超时未匹配) 与
+// 捕获失败 (已匹配但要保存的分组为空)；遇到第一个失败的步骤时停止，其余步骤记为 skipped。
+package macro
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sync"
+	"time"
+
+	"serial-assistant/pkg/stream"
+	"serial-assistant/pkg/tmpl"
+)
+
+const (
+	// MaxSteps 一个宏最多的步骤数
+	MaxSteps = 256
+	// DefaultTimeout TimeoutMs 为 0 时等待匹配的时间
+	DefaultTimeout = time.Second
+	// MaxTimeout 等待匹配的最长时间
+	MaxTimeout = time.Minute
+)
+
+// Step 宏的一个步骤；Send 与 Expect 至少有一个
+type Step struct {
+	Send      string `json:"send,omitempty"`      // 发送模板，见 tmpl 包
+	Hex       bool   `json:"hex,omitempty"`       // Send 展开后按十六进制解码
+	Expect    string `json:"expect,omitempty"`    // 在之后收到的数据中查找的正则表达式
+	TimeoutMs int    `json:"timeoutMs,omitempty"` // 等待 Expect 的时间，0 表示 DefaultTimeout
+	// CaptureAs 匹配后保存到会话环境的变量名：Expect 中有同名的命名分组时取该分组，
+	// 否则取第一个分组，没有分组时取整个匹配
+	CaptureAs string `json:"captureAs,omitempty"`
+	DelayMs   int    `json:"delayMs,omitempty"` // 步骤完成后的等待时间
+}
+
+// Validate 检查步骤的参数
+func (s Step) Validate() error {
+	switch {
+	case s.Send == "" && s.Expect == "":
+		return errors.New("step needs send or expect")
+	case s.CaptureAs != "" && s.Expect == "":
+		return errors.New("captureAs requires expect")
+	case s.TimeoutMs < 0 || time.Duration(s.TimeoutMs)*time.Millisecond > MaxTimeout:
+		return fmt.Errorf("timeout must be between 0 and %d ms", MaxTimeout.Milliseconds())
+	case s.DelayMs < 0 || time.Duration(s.DelayMs)*time.Millisecond > MaxTimeout:
+		return fmt.Errorf("delay must be between 0 and %d ms", MaxTimeout.Milliseconds())
+	}
+	if s.Expect != "" {
+		if _, err := regexp.Compile(s.Expect); err != nil {
+			return fmt.Errorf("invalid expect pattern: %w", err)
+		}
+	}
+	if s.CaptureAs != "" {
+		if err := tmpl.ValidEnvKey(s.CaptureAs); err != nil {
+			return fmt.Errorf("captureAs: %w", err)
+		}
+	}
+	return nil
+}
+
+// Validate 检查全部步骤，错误信息包含步骤的序号
+func Validate(steps []Step) error {
+	if len(steps) == 0 {
+		return errors.New("macro has no steps")
+	}
+	if len(steps) > MaxSteps {
+		return fmt.Errorf("too many steps (%d, max %d)", len(steps), MaxSteps)
+	}
+	for i, s := range steps {
+		if err := s.Validate(); err != nil {
+			return fmt.Errorf("step %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+// Status 步骤的结果
+type Status string
+
+const (
+	StatusOK            Status = "ok"
+	StatusTemplateError Status = "template-error" // 发送模板无效或引用了未定义的变量
+	StatusSendError     Status = "send-error"
+	StatusExpectFailed  Status = "expect-failed"  // 超时前没有匹配 Expect
+	StatusCaptureFailed Status = "capture-failed" // 已匹配，但要保存的分组没有参与匹配或为空
+	StatusCancelled     Status = "cancelled"
+	StatusSkipped       Status = "skipped" // 之前的步骤失败
+)
+
+// StepResult 一个步骤的结果
+type StepResult struct {
+	Index    int    `json:"index"` // 从 1 开始
+	Status   Status `json:"status"`
+	Match    string `json:"match,omitempty"`    // Expect 匹配的文本
+	Captured string `json:"captured,omitempty"` // 保存到 CaptureAs 的值
+	Error    string `json:"error,omitempty"`
+}
+
+// Result 宏的执行结果
+type Result struct {
+	OK     bool         `json:"ok"`
+	Status Status       `json:"status"` // 第一个失败步骤的状态，全部成功时为 ok
+	Failed int          `json:"failed"` // 第一个失败步骤的序号，全部成功时为 0
+	Steps  []StepResult `json:"steps"`
+}
+
+// Session 宏运行所在的连接
+type Session interface {
+	// Expand 按会话环境展开发送模板
+	Expand(template string, hex bool) ([]byte, error)
+	Send(payload []byte) error
+	// Subscribe 订阅之后收到的数据
+	Subscribe(fn func([]byte)) (unsubscribe func())
+	SetEnv(key, value string) error
+}
+
+// Run 依次执行 steps (应已通过 Validate)；report 在每个步骤结束时调用，可为 nil
+func Run(ctx context.Context, sess Session, steps []Step, report func(StepResult)) Result {
+	res := Result{OK: true, Status: StatusOK}
+	for i, step := range steps {
+		r := StepResult{Index: i + 1}
+		if res.OK {
+			r = runStep(ctx, sess, step, i+1)
+			if r.Status != StatusOK {
+				res.OK, res.Status, res.Failed = false, r.Status, r.Index
+			}
+		} else {
+			r.Status = StatusSkipped
+		}
+		res.Steps = append(res.Steps, r)
+		if report != nil {
+			report(r)
+		}
+		if res.OK && step.DelayMs > 0 && !sleep(ctx, time.Duration(step.DelayMs)*time.Millisecond) {
+			res.OK, res.Status, res.Failed = false, StatusCancelled, r.Index
+		}
+	}
+	return res
+}
+
+func runStep(ctx context.Context, sess Session, step Step, index int) StepResult {
+	r := StepResult{Index: index}
+	fail := func(status Status, err error) StepResult {
+		r.Status, r.Error = status, err.Error()
+		return r
+	}
+
+	var m *matcher
+	if step.Expect != "" {
+		re, err := regexp.Compile(step.Expect)
+		if err != nil {
+			return fail(StatusExpectFailed, fmt.Errorf("invalid expect pattern: %w", err))
+		}
+		// 先订阅再发送，避免丢失快速到达的回复
+		m = newMatcher(re)
+		unsubscribe := sess.Subscribe(m.Write)
+		defer unsubscribe()
+	}
+
+	if step.Send != "" {
+		payload, err := sess.Expand(step.Send, step.Hex)
+		if err != nil {
+			return fail(StatusTemplateError, err)
+		}
+		if err := sess.Send(payload); err != nil {
+			return fail(StatusSendError, err)
+		}
+	}
+	if m == nil {
+		r.Status = StatusOK
+		return r
+	}
+
+	timeout := time.Duration(step.TimeoutMs) * time.Millisecond
+	if timeout == 0 {
+		timeout = DefaultTimeout
+	}
+	groups, received, err := m.Wait(ctx, timeout)
+	if err != nil {
+		return fail(StatusCancelled, err)
+	}
+	if groups == nil {
+		return fail(StatusExpectFailed, fmt.Errorf("no match for %q within %d ms (%d bytes received)", step.Expect, timeout.Milliseconds(), received))
+	}
+	r.Match = string(groups[0])
+
+	if step.CaptureAs == "" {
+		r.Status = StatusOK
+		return r
+	}
+	value := groups[captureGroup(m.re, step.CaptureAs)]
+	if len(value) == 0 {
+		return fail(StatusCaptureFailed, fmt.Errorf("pattern matched %q but captured nothing for %s", r.Match, step.CaptureAs))
+	}
+	if err := sess.SetEnv(step.CaptureAs, string(value)); err != nil {
+		return fail(StatusCaptureFailed, err)
+	}
+	r.Status, r.Captured = StatusOK, string(value)
+	return r
+}
+
+// captureGroup 返回 CaptureAs 对应的分组序号，见 Step.CaptureAs
+func captureGroup(re *regexp.Regexp, name string) int {
+	if i := re.SubexpIndex(name); i > 0 {
+		return i
+	}
+	if re.NumSubexp() > 0 {
+		return 1
+	}
+	return 0
+}
+
+// settle 匹配延伸到已收到数据的末尾时，再等待该时间没有新数据后才接受 (之后的数据可能使匹配更长，
+// 例如 \d+ 在数据块边界处被截断)
+const settle = 50 * time.Millisecond
+
+// matcher 在收到的数据中查找正则表达式，线程安全
+type matcher struct {
+	re *regexp.Regexp
+
+	mu      sync.Mutex
+	buf     []byte
+	groups  [][]byte // 已确定的匹配
+	pending [][]byte // 延伸到数据末尾的匹配，等待 settle
+	changed chan struct{}
+	done    chan struct{}
+}
+
+func newMatcher(re *regexp.Regexp) *matcher {
+	return &matcher{re: re, changed: make(chan struct{}, 1), done: make(chan struct{})}
+}
+
+// Write 追加收到的数据，超过 stream.MaxCollect 的部分被丢弃
+func (m *matcher) Write(chunk []byte) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.groups != nil || len(m.buf) >= stream.MaxCollect {
+		return
+	}
+	if room := stream.MaxCollect - len(m.buf); len(chunk) > room {
+		chunk = chunk[:room]
+	}
+	m.buf = append(m.buf, chunk...)
+	loc := m.re.FindSubmatchIndex(m.buf)
+	if loc == nil {
+		return
+	}
+	groups := submatches(m.buf, loc)
+	if loc[1] < len(m.buf) || len(m.buf) >= stream.MaxCollect {
+		m.groups = groups
+		close(m.done)
+		return
+	}
+	m.pending = groups
+	select {
+	case m.changed <- struct{}{}:
+	default:
+	}
+}
+
+// Wait 等待匹配，超时返回 nil 分组与已收到的字节数；ctx 取消时返回 ctx.Err()
+func (m *matcher) Wait(ctx context.Context, timeout time.Duration) (groups [][]byte, received int, err error) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	quiet := time.NewTimer(settle)
+	quiet.Stop()
+	defer quiet.Stop()
+
+	for {
+		select {
+		case <-m.done:
+			m.mu.Lock()
+			defer m.mu.Unlock()
+			return m.groups, len(m.buf), nil
+		case <-m.changed:
+			quiet.Reset(settle)
+			continue
+		case <-quiet.C:
+		case <-timer.C:
+		case <-ctx.Done():
+			return nil, 0, ctx.Err()
+		}
+		// 没有新数据或已超时：接受延伸到末尾的匹配
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		return m.pending, len(m.buf), nil
+	}
+}
+
+// submatches 按 FindSubmatchIndex 的结果复制各分组，没有参与匹配的分组为 nil
+func submatches(b []byte, loc []int) [][]byte {
+	groups := make([][]byte, len(loc)/2)
+	for i := range groups {
+		if loc[2*i] >= 0 {
+			groups[i] = append([]byte(nil), b[loc[2*i]:loc[2*i+1]]...)
+		}
+	}
+	return groups
+}
+
+// sleep 等待 d 或 ctx 取消，取消时返回 false
+func sleep(ctx context.Context, d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,210 @@
+package macro
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"serial-assistant/pkg/stream"
+	"serial-assistant/pkg/tmpl"
+)
+
+// fakeDevice 模拟设备：按收到的命令回复，会话环境使用真实的 tmpl.Expander
+type fakeDevice struct {
+	*tmpl.Expander
+	hub     *stream.Hub
+	replies map[string]string
+
+	mu   sync.Mutex
+	sent []string
+}
+
+func newFakeDevice(replies map[string]string) *fakeDevice {
+	return &fakeDevice{Expander: tmpl.NewExpander(), hub: stream.NewHub(), replies: replies}
+}
+
+func (d *fakeDevice) Send(payload []byte) error {
+	d.mu.Lock()
+	d.sent = append(d.sent, string(payload))
+	d.mu.Unlock()
+	if payload := string(payload); payload == "FAIL" {
+		return errors.New("write failed")
+	}
+	if reply, ok := d.replies[string(payload)]; ok {
+		// 分两段到达，匹配可能被数据块边界截断
+		half := len(reply) / 2
+		d.hub.Publish([]byte(reply[:half]))
+		d.hub.Publish([]byte(reply[half:]))
+	}
+	return nil
+}
+
+func (d *fakeDevice) Subscribe(fn func([]byte)) func() {
+	return d.hub.Subscribe(fn)
+}
+
+func TestCaptureAndReuse(t *testing.T) {
+	d := newFakeDevice(map[string]string{
+		"ID?\r\n":              "ID: SN-00421\r\nOK\r\n",
+		"REV?\r\n":             "hw rev=B\r\n",
+		"KEY SN-00421 B\r\n":   "KEY OK\r\n",
+		"WRITE SN-00421 4\r\n": "DONE\r\n",
+	})
+	steps := []Step{
+		{Send: "ID?\r\n", Expect: `ID: (SN-\d+)`, CaptureAs: "serial"},
+		{Send: "REV?\r\n", Expect: `(\w+) rev=(?P<rev>\w)`, CaptureAs: "rev", TimeoutMs: 200},
+		{Send: "KEY {{env.serial}} {{env.rev}}\r\n", Expect: "KEY OK"},
+		{Send: "WRITE {{env.serial}} {{seq}}\r\n", Expect: `DONE`},
+	}
+	if err := Validate(steps); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+	var reported []StepResult
+	res := Run(context.Background(), d, steps, func(r StepResult) { reported = append(reported, r) })
+	if !res.OK || res.Status != StatusOK || res.Failed != 0 {
+		t.Fatalf("Run = %+v", res)
+	}
+	if len(reported) != len(steps) {
+		t.Errorf("Expected %d reports, got %d", len(steps), len(reported))
+	}
+	if res.Steps[0].Captured != "SN-00421" || res.Steps[1].Captured != "B" {
+		t.Errorf("Captured %q and %q", res.Steps[0].Captured, res.Steps[1].Captured)
+	}
+	if env := d.Env(); env["serial"] != "SN-00421" || env["rev"] != "B" {
+		t.Errorf("Env = %v", env)
+	}
+	if d.sent[3] != "WRITE SN-00421 4\r\n" {
+		t.Errorf("Expected the captured value in later sends, got %q", d.sent)
+	}
+}
+
+func TestCaptureWholeMatch(t *testing.T) {
+	d := newFakeDevice(map[string]string{"V?": "version 2.7.1\n"})
+	res := Run(context.Background(), d, []Step{{Send: "V?", Expect: `\d+\.\d+\.\d+`, CaptureAs: "fw"}}, nil)
+	if !res.OK || d.Env()["fw"] != "2.7.1" {
+		t.Errorf("Run = %+v, env %v", res, d.Env())
+	}
+}
+
+func TestFailureKinds(t *testing.T) {
+	tests := []struct {
+		name    string
+		steps   []Step
+		status  Status
+		failed  int
+		message string
+	}{
+		{
+			name:    "missing variable",
+			steps:   []Step{{Send: "SET {{env.serial}}"}, {Send: "NEXT"}},
+			status:  StatusTemplateError,
+			failed:  1,
+			message: "env.serial",
+		},
+		{
+			name:   "send error",
+			steps:  []Step{{Send: "FAIL"}},
+			status: StatusSendError,
+			failed: 1,
+		},
+		{
+			name:    "expectation not met",
+			steps:   []Step{{Send: "ID?", Expect: "OK"}, {Send: "ID?", Expect: "NEVER", TimeoutMs: 50}, {Send: "NEXT"}},
+			status:  StatusExpectFailed,
+			failed:  2,
+			message: "bytes received",
+		},
+		{
+			name:    "capture group did not participate",
+			steps:   []Step{{Send: "ID?", Expect: `OK|ID=(\d+)`, CaptureAs: "id"}},
+			status:  StatusCaptureFailed,
+			failed:  1,
+			message: "captured nothing",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := newFakeDevice(map[string]string{"ID?": "OK\n"})
+			res := Run(context.Background(), d, tt.steps, nil)
+			if res.OK || res.Status != tt.status || res.Failed != tt.failed {
+				t.Fatalf("Run = %+v, expected %s at step %d", res, tt.status, tt.failed)
+			}
+			failed := res.Steps[tt.failed-1]
+			if !strings.Contains(failed.Error, tt.message) {
+				t.Errorf("Error %q should contain %q", failed.Error, tt.message)
+			}
+			for _, r := range res.Steps[tt.failed:] {
+				if r.Status != StatusSkipped {
+					t.Errorf("Step %d after the failure: %s", r.Index, r.Status)
+				}
+			}
+			if _, ok := d.Env()["id"]; ok {
+				t.Error("Failed capture must not set the variable")
+			}
+		})
+	}
+}
+
+func TestTemplateErrorDoesNotSend(t *testing.T) {
+	d := newFakeDevice(nil)
+	Run(context.Background(), d, []Step{{Send: "A {{env.x}}"}}, nil)
+	if len(d.sent) != 0 {
+		t.Errorf("Nothing must be sent when the template fails, sent %q", d.sent)
+	}
+}
+
+func TestCancel(t *testing.T) {
+	d := newFakeDevice(nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+	res := Run(ctx, d, []Step{{Expect: "x", TimeoutMs: 5000}, {Send: "y"}}, nil)
+	if res.Status != StatusCancelled || res.Steps[1].Status != StatusSkipped {
+		t.Errorf("Run = %+v", res)
+	}
+}
+
+func TestMatcherSettle(t *testing.T) {
+	m := newMatcher(regexp.MustCompile(`V(\d+)`))
+	go func() {
+		m.Write([]byte("V1"))
+		time.Sleep(settle / 5)
+		m.Write([]byte("23"))
+	}()
+	start := time.Now()
+	groups, _, err := m.Wait(context.Background(), 5*time.Second)
+	if err != nil || groups == nil || string(groups[1]) != "123" {
+		t.Fatalf("Wait = %q, %v", groups, err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("A match at the end of the data must be accepted after the quiet period, took %v", elapsed)
+	}
+
+	// 匹配之后还有数据时立即接受
+	m = newMatcher(regexp.MustCompile(`OK`))
+	m.Write([]byte("OK\r\n"))
+	if groups, _, _ := m.Wait(context.Background(), time.Millisecond); groups == nil {
+		t.Error("Expected an immediate match")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	bad := [][]Step{
+		nil,
+		{{}},
+		{{Send: "x", CaptureAs: "v"}},
+		{{Expect: "(", CaptureAs: "v"}},
+		{{Expect: "x", CaptureAs: "bad name"}},
+		{{Expect: "x", TimeoutMs: -1}},
+		{{Send: "x", DelayMs: int(MaxTimeout.Milliseconds()) + 1}},
+		make([]Step, MaxSteps+1),
+	}
+	for i, steps := range bad {
+		if err := Validate(steps); err == nil {
+			t.Errorf("case %d: expected an error", i)
+		}
+	}
+}
@@ -0,0 +1,247 @@
+// Package membudget 为各缓冲功能 (接收历史、导出缓冲、接收队列等) 设置共享的全局内存预算
+//
+// 每个功能以 Consumer 登记自己的用量查询函数，Accountant 定期 (或按需调用 Check) 汇总用量；
+// 总用量超过预算时，按 Priority 从小到大依次调用可释放的消费者的 Release，直到回到预算以内。
+// 没有 Release 的消费者 (例如尚未处理的接收数据、日志写入器中待写入的数据块) 只计入用量，从不被释放。
+package membudget
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// 预算的默认值与取值范围
+const (
+	DefaultBudget = 64 << 20
+	MinBudget     = 4 << 20
+	MaxBudget     = 4 << 30
+)
+
+// 建议的释放优先级：数值小的先释放
+const (
+	PriorityHistory   = 0  // 接收历史：淘汰最旧的记录
+	PriorityRecording = 10 // 导出与录制的缓冲：刷新到磁盘
+)
+
+// Consumer 一个使用内存的功能
+type Consumer struct {
+	Name     string
+	Priority int
+	// Usage 返回当前用量 (字节)，可能在任意 goroutine 中调用，必须线程安全
+	Usage func() int64
+	// Release 尽量释放至少 excess 字节，实际释放量由前后两次 Usage 之差计算；nil 表示不可释放
+	// 调用期间持有 Accountant 的锁，不能回调 Accountant 的方法
+	Release func(excess int64)
+}
+
+// ConsumerUsage 一个消费者的用量
+type ConsumerUsage struct {
+	Name       string `json:"name"`
+	Priority   int    `json:"priority"`
+	Bytes      int64  `json:"bytes"`
+	Releasable bool   `json:"releasable"`
+	Released   int64  `json:"released"` // 因超出预算累计释放的字节数
+	Releases   int64  `json:"releases"` // 被要求释放的次数
+}
+
+// Usage 全部消费者的用量，按释放顺序排列
+type Usage struct {
+	Budget    int64           `json:"budget"`
+	Total     int64           `json:"total"`
+	Consumers []ConsumerUsage `json:"consumers"`
+	Pressure  int64           `json:"pressure"` // 超出预算的次数
+}
+
+// Released 一次释放的结果
+type Released struct {
+	Name  string `json:"name"`
+	Bytes int64  `json:"bytes"`
+}
+
+// Pressure 一次超出预算及其处理结果
+type Pressure struct {
+	Budget   int64      `json:"budget"`
+	Before   int64      `json:"before"`   // 释放前的总用量
+	After    int64      `json:"after"`    // 释放后的总用量
+	Released []Released `json:"released"` // 按调用顺序
+	Over     bool       `json:"over"`     // 释放全部可释放的消费者后仍超出预算
+}
+
+// String 返回用于提示的单行说明
+func (p Pressure) String() string {
+	s := fmt.Sprintf("memory use %d KB exceeded the %d KB budget", p.Before>>10, p.Budget>>10)
+	for _, r := range p.Released {
+		s += fmt.Sprintf("; released %d KB from %s", r.Bytes>>10, r.Name)
+	}
+	if p.Over {
+		s += fmt.Sprintf("; still %d KB over", (p.After-p.Budget)>>10)
+	}
+	return s
+}
+
+type consumer struct {
+	Consumer
+	seq      int // 登记顺序，优先级相同时按登记顺序释放
+	released int64
+	releases int64
+}
+
+// Accountant 全局内存预算，线程安全
+type Accountant struct {
+	mu         sync.Mutex
+	budget     int64
+	consumers  []*consumer
+	nextSeq    int
+	pressure   int64
+	onPressure func(Pressure)
+}
+
+// New 创建预算为 budget 字节的 Accountant (<= 0 时使用 DefaultBudget)
+// onPressure 在每次超出预算并完成释放后调用 (不持有锁)，可为 nil
+func New(budget int64, onPressure func(Pressure)) *Accountant {
+	if budget <= 0 {
+		budget = DefaultBudget
+	}
+	return &Accountant{budget: budget, onPressure: onPressure}
+}
+
+// Register 登记消费者，返回注销函数；同名消费者会被替换
+func (a *Accountant) Register(c Consumer) (unregister func()) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.removeLocked(c.Name)
+	entry := &consumer{Consumer: c, seq: a.nextSeq}
+	a.nextSeq++
+	a.consumers = append(a.consumers, entry)
+	sort.SliceStable(a.consumers, func(i, j int) bool {
+		if a.consumers[i].Priority != a.consumers[j].Priority {
+			return a.consumers[i].Priority < a.consumers[j].Priority
+		}
+		return a.consumers[i].seq < a.consumers[j].seq
+	})
+	return func() {
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		for i, e := range a.consumers {
+			if e == entry {
+				a.consumers = append(a.consumers[:i], a.consumers[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+func (a *Accountant) removeLocked(name string) {
+	for i, e := range a.consumers {
+		if e.Name == name {
+			a.consumers = append(a.consumers[:i], a.consumers[i+1:]...)
+			return
+		}
+	}
+}
+
+// SetBudget 修改预算 (<= 0 时使用 DefaultBudget)，下一次 Check 时生效
+func (a *Accountant) SetBudget(budget int64) {
+	if budget <= 0 {
+		budget = DefaultBudget
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.budget = budget
+}
+
+// Budget 返回当前预算
+func (a *Accountant) Budget() int64 {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.budget
+}
+
+// Check 汇总用量，超出预算时按优先级释放，返回本次的处理结果；未超出时 ok 为 false
+func (a *Accountant) Check() (p Pressure, ok bool) {
+	a.mu.Lock()
+	usage := make([]int64, len(a.consumers))
+	var total int64
+	for i, c := range a.consumers {
+		usage[i] = c.Usage()
+		total += usage[i]
+	}
+	if total <= a.budget {
+		a.mu.Unlock()
+		return Pressure{}, false
+	}
+
+	p = Pressure{Budget: a.budget, Before: total}
+	for i, c := range a.consumers {
+		if total <= a.budget {
+			break
+		}
+		if c.Release == nil || usage[i] == 0 {
+			continue
+		}
+		c.Release(total - a.budget)
+		after := c.Usage()
+		freed := usage[i] - after
+		if freed < 0 {
+			// 释放期间又有新数据写入
+			freed = 0
+		}
+		total += after - usage[i]
+		c.releases++
+		c.released += freed
+		p.Released = append(p.Released, Released{Name: c.Name, Bytes: freed})
+	}
+	p.After = total
+	p.Over = total > a.budget
+	a.pressure++
+	onPressure := a.onPressure
+	a.mu.Unlock()
+
+	if onPressure != nil {
+		onPressure(p)
+	}
+	return p, true
+}
+
+// Usage 返回各消费者的当前用量
+func (a *Accountant) Usage() Usage {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	u := Usage{Budget: a.budget, Pressure: a.pressure, Consumers: make([]ConsumerUsage, len(a.consumers))}
+	for i, c := range a.consumers {
+		bytes := c.Usage()
+		u.Total += bytes
+		u.Consumers[i] = ConsumerUsage{
+			Name:       c.Name,
+			Priority:   c.Priority,
+			Bytes:      bytes,
+			Releasable: c.Release != nil,
+			Released:   c.released,
+			Releases:   c.releases,
+		}
+	}
+	return u
+}
+
+// Start 每隔 interval 调用一次 Check，返回停止函数
+func (a *Accountant) Start(interval time.Duration) (stop func()) {
+	done := make(chan struct{})
+	var once sync.Once
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				a.Check()
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() { once.Do(func() { close(done) }) }
+}
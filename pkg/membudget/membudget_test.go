@@ -0,0 +1,183 @@
+package membudget
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// fake 模拟一个消费者，release 时释放请求量与自身用量中较小者
+type fake struct {
+	mu    sync.Mutex
+	bytes int64
+	calls *[]string
+	name  string
+}
+
+func (f *fake) usage() int64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.bytes
+}
+
+func (f *fake) release(excess int64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	*f.calls = append(*f.calls, f.name)
+	if excess > f.bytes {
+		excess = f.bytes
+	}
+	f.bytes -= excess
+}
+
+func (f *fake) consumer(priority int, releasable bool) Consumer {
+	c := Consumer{Name: f.name, Priority: priority, Usage: f.usage}
+	if releasable {
+		c.Release = f.release
+	}
+	return c
+}
+
+func TestCheckReleasesInPriorityOrder(t *testing.T) {
+	var calls []string
+	history := &fake{name: "history", bytes: 600, calls: &calls}
+	recording := &fake{name: "recording", bytes: 300, calls: &calls}
+	logw := &fake{name: "log", bytes: 200, calls: &calls}
+	queue := &fake{name: "rx-queue", bytes: 100, calls: &calls}
+
+	var events []Pressure
+	a := New(500, func(p Pressure) { events = append(events, p) })
+	// 登记顺序与释放顺序无关
+	a.Register(logw.consumer(-1, false))
+	a.Register(recording.consumer(PriorityRecording, true))
+	a.Register(queue.consumer(PriorityHistory, false))
+	a.Register(history.consumer(PriorityHistory, true))
+
+	// 总量 1200，超出 700：历史全部释放 (600) 仍超出 100，再释放录制缓冲 100
+	p, ok := a.Check()
+	if !ok {
+		t.Fatal("Check did not report pressure")
+	}
+	if len(calls) != 2 || calls[0] != "history" || calls[1] != "recording" {
+		t.Fatalf("release order = %v", calls)
+	}
+	if p.Before != 1200 || p.After != 500 || p.Over {
+		t.Errorf("pressure = %+v", p)
+	}
+	if len(p.Released) != 2 || p.Released[0] != (Released{"history", 600}) || p.Released[1] != (Released{"recording", 100}) {
+		t.Errorf("released = %+v", p.Released)
+	}
+	if logw.bytes != 200 || queue.bytes != 100 {
+		t.Error("consumers without Release must not be touched")
+	}
+	if len(events) != 1 {
+		t.Errorf("onPressure called %d times", len(events))
+	}
+
+	u := a.Usage()
+	if u.Total != 500 || u.Budget != 500 || u.Pressure != 1 {
+		t.Errorf("usage = %+v", u)
+	}
+	want := []string{"log", "rx-queue", "history", "recording"}
+	for i, c := range u.Consumers {
+		if c.Name != want[i] {
+			t.Errorf("consumer %d = %s, want %s", i, c.Name, want[i])
+		}
+	}
+	if h := u.Consumers[2]; h.Released != 600 || h.Releases != 1 || !h.Releasable {
+		t.Errorf("history usage = %+v", h)
+	}
+}
+
+func TestCheckStopsOnceWithinBudget(t *testing.T) {
+	var calls []string
+	history := &fake{name: "history", bytes: 800, calls: &calls}
+	recording := &fake{name: "recording", bytes: 100, calls: &calls}
+	a := New(600, nil)
+	a.Register(history.consumer(PriorityHistory, true))
+	a.Register(recording.consumer(PriorityRecording, true))
+
+	if _, ok := a.Check(); !ok {
+		t.Fatal("expected pressure")
+	}
+	if len(calls) != 1 || history.bytes != 500 || recording.bytes != 100 {
+		t.Errorf("calls = %v, history = %d, recording = %d", calls, history.bytes, recording.bytes)
+	}
+	if _, ok := a.Check(); ok || len(calls) != 1 {
+		t.Error("second Check should be within budget")
+	}
+}
+
+func TestCheckOverWhenNothingReleasable(t *testing.T) {
+	var calls []string
+	logw := &fake{name: "log", bytes: 900, calls: &calls}
+	history := &fake{name: "history", bytes: 0, calls: &calls}
+	a := New(500, nil)
+	a.Register(logw.consumer(0, false))
+	a.Register(history.consumer(PriorityHistory, true))
+
+	p, ok := a.Check()
+	if !ok || !p.Over || p.After != 900 || len(p.Released) != 0 {
+		t.Errorf("pressure = %+v", p)
+	}
+	if len(calls) != 0 {
+		t.Errorf("empty consumers should not be asked to release: %v", calls)
+	}
+}
+
+func TestRegisterReplaceAndUnregister(t *testing.T) {
+	var calls []string
+	a := New(100, nil)
+	first := &fake{name: "history", bytes: 50, calls: &calls}
+	a.Register(first.consumer(0, true))
+	second := &fake{name: "history", bytes: 70, calls: &calls}
+	unregister := a.Register(second.consumer(0, true))
+	if u := a.Usage(); len(u.Consumers) != 1 || u.Total != 70 {
+		t.Errorf("usage after replace = %+v", u)
+	}
+	unregister()
+	if u := a.Usage(); len(u.Consumers) != 0 || u.Total != 0 {
+		t.Errorf("usage after unregister = %+v", u)
+	}
+}
+
+func TestSetBudget(t *testing.T) {
+	a := New(0, nil)
+	if a.Budget() != DefaultBudget {
+		t.Errorf("default budget = %d", a.Budget())
+	}
+	var calls []string
+	h := &fake{name: "history", bytes: 1000, calls: &calls}
+	a.Register(h.consumer(0, true))
+	if _, ok := a.Check(); ok {
+		t.Fatal("within the default budget")
+	}
+	a.SetBudget(400)
+	if p, ok := a.Check(); !ok || p.After != 400 {
+		t.Errorf("after lowering the budget: %+v", p)
+	}
+}
+
+func TestStart(t *testing.T) {
+	var calls []string
+	h := &fake{name: "history", bytes: 1000, calls: &calls}
+	got := make(chan Pressure, 1)
+	a := New(100, func(p Pressure) {
+		select {
+		case got <- p:
+		default:
+		}
+	})
+	a.Register(h.consumer(0, true))
+	stop := a.Start(time.Millisecond)
+	defer stop()
+	select {
+	case p := <-got:
+		if p.After != 100 {
+			t.Errorf("pressure = %+v", p)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("periodic check did not run")
+	}
+	stop()
+}
@@ -0,0 +1,205 @@
+// Package modbus 实现 Modbus RTU 主站的读请求与应答解析，以及按寄存器表循环读取多个从站的 Poller
+//
+// 只支持读功能 (线圈、离散输入、保持寄存器、输入寄存器)；请求与应答均为带 CRC-16/MODBUS 的 RTU 帧
+package modbus
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"serial-assistant/pkg/checksum"
+)
+
+// Function 读功能
+type Function string
+
+const (
+	Coils            Function = "coils"    // 功能码 01，读线圈
+	DiscreteInputs   Function = "discrete" // 功能码 02，读离散输入
+	HoldingRegisters Function = "holding"  // 功能码 03，读保持寄存器
+	InputRegisters   Function = "input"    // 功能码 04，读输入寄存器
+)
+
+// ParseFunction 解析读功能，空字符串等同于 "holding"
+func ParseFunction(name string) (Function, error) {
+	switch f := Function(name); f {
+	case "":
+		return HoldingRegisters, nil
+	case Coils, DiscreteInputs, HoldingRegisters, InputRegisters:
+		return f, nil
+	default:
+		return "", fmt.Errorf("unknown function %q (expected coils, discrete, holding or input)", name)
+	}
+}
+
+// Code 返回功能码
+func (f Function) Code() byte {
+	switch f {
+	case Coils:
+		return 0x01
+	case DiscreteInputs:
+		return 0x02
+	case InputRegisters:
+		return 0x04
+	}
+	return 0x03
+}
+
+// bitwise 按位读取 (线圈与离散输入)
+func (f Function) bitwise() bool {
+	return f == Coils || f == DiscreteInputs
+}
+
+// MaxCount 单次请求可读取的最大数量
+func (f Function) MaxCount() int {
+	if f.bitwise() {
+		return 2000
+	}
+	return 125
+}
+
+// exceptionLen 异常应答的长度：从站地址、功能码 | 0x80、异常码与 CRC
+const exceptionLen = 5
+
+// ReadRequest 构造读请求帧
+func ReadRequest(slave byte, fn Function, start, count uint16) []byte {
+	req := []byte{slave, fn.Code(), 0, 0, 0, 0}
+	binary.BigEndian.PutUint16(req[2:], start)
+	binary.BigEndian.PutUint16(req[4:], count)
+	return checksum.CRC16Modbus.Append(req)
+}
+
+// ResponseLen 返回正常应答帧的长度
+func ResponseLen(fn Function, count int) int {
+	return 3 + byteCount(fn, count) + 2
+}
+
+func byteCount(fn Function, count int) int {
+	if fn.bitwise() {
+		return (count + 7) / 8
+	}
+	return count * 2
+}
+
+// ExceptionError 从站返回的异常应答
+type ExceptionError struct {
+	Code byte
+}
+
+func (e *ExceptionError) Error() string {
+	switch e.Code {
+	case 1:
+		return "slave exception 1 (illegal function)"
+	case 2:
+		return "slave exception 2 (illegal data address)"
+	case 3:
+		return "slave exception 3 (illegal data value)"
+	case 4:
+		return "slave exception 4 (slave device failure)"
+	}
+	return fmt.Sprintf("slave exception %d", e.Code)
+}
+
+// ErrNoResponse 超时前没有收到完整的应答
+var ErrNoResponse = errors.New("no response")
+
+// ParseResponse 校验应答帧并返回读取的数值：寄存器为 16 位无符号数，线圈与离散输入为 0 或 1
+// 从站返回异常应答时错误为 *ExceptionError
+func ParseResponse(slave byte, fn Function, count int, frame []byte) ([]uint16, error) {
+	if len(frame) < exceptionLen {
+		return nil, fmt.Errorf("response too short (%d bytes)", len(frame))
+	}
+	body, sum := frame[:len(frame)-2], frame[len(frame)-2:]
+	if !checksum.CRC16Modbus.Verify(body, sum) {
+		return nil, fmt.Errorf("CRC mismatch")
+	}
+	if frame[0] != slave {
+		return nil, fmt.Errorf("response from slave %d, expected %d", frame[0], slave)
+	}
+	switch frame[1] {
+	case fn.Code():
+	case fn.Code() | 0x80:
+		return nil, &ExceptionError{Code: frame[2]}
+	default:
+		return nil, fmt.Errorf("unexpected function code 0x%02X", frame[1])
+	}
+	n := byteCount(fn, count)
+	if int(frame[2]) != n || len(body) != 3+n {
+		return nil, fmt.Errorf("byte count %d, expected %d", frame[2], n)
+	}
+
+	data := body[3:]
+	values := make([]uint16, count)
+	for i := range values {
+		if fn.bitwise() {
+			values[i] = uint16(data[i/8]>>(i%8)) & 1
+		} else {
+			values[i] = binary.BigEndian.Uint16(data[i*2:])
+		}
+	}
+	return values, nil
+}
+
+// FrameGap 返回帧间最小静默时间：3.5 个字符时间 (每字符按 11 位计)，
+// 波特率高于 19200 时按规范固定为 1.75 ms；baud <= 0 (非串口) 时为 0
+func FrameGap(baud int) time.Duration {
+	switch {
+	case baud <= 0:
+		return 0
+	case baud > 19200:
+		return 1750 * time.Microsecond
+	}
+	return time.Duration(float64(time.Second) * 3.5 * 11 / float64(baud))
+}
+
+// Collector 收集一次请求的应答：收到 want 字节或完整的异常应答时结束；线程安全
+type Collector struct {
+	mu   sync.Mutex
+	fn   Function
+	want int
+	buf  []byte
+	done chan struct{}
+}
+
+// NewCollector 创建应答收集器，want 为正常应答的长度 (见 ResponseLen)
+func NewCollector(fn Function, want int) *Collector {
+	return &Collector{fn: fn, want: want, done: make(chan struct{})}
+}
+
+// Write 追加收到的数据，可直接用作 stream.Hub 的订阅回调
+func (c *Collector) Write(chunk []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	select {
+	case <-c.done:
+		return
+	default:
+	}
+	c.buf = append(c.buf, chunk...)
+	exception := len(c.buf) >= exceptionLen && c.buf[1] == c.fn.Code()|0x80
+	if len(c.buf) >= c.want || exception {
+		close(c.done)
+	}
+}
+
+// Wait 等待应答完成，超时返回 ErrNoResponse，ctx 取消时返回 ctx.Err()
+func (c *Collector) Wait(ctx context.Context, timeout time.Duration) ([]byte, error) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case <-c.done:
+	case <-timer.C:
+		return nil, ErrNoResponse
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]byte(nil), c.buf...), nil
+}
@@ -0,0 +1,85 @@
+package modbus
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"serial-assistant/pkg/checksum"
+)
+
+func TestReadRequest(t *testing.T) {
+	// 规范中的示例：从站 1，读保持寄存器 0 开始的 10 个
+	want := []byte{0x01, 0x03, 0x00, 0x00, 0x00, 0x0A, 0xC5, 0xCD}
+	if got := ReadRequest(1, HoldingRegisters, 0, 10); !bytes.Equal(got, want) {
+		t.Errorf("ReadRequest() = % X, want % X", got, want)
+	}
+}
+
+func TestParseResponse(t *testing.T) {
+	frame := checksum.CRC16Modbus.Append([]byte{0x11, 0x04, 0x04, 0x00, 0xFA, 0xFF, 0x38})
+	values, err := ParseResponse(0x11, InputRegisters, 2, frame)
+	if err != nil || len(values) != 2 || values[0] != 250 || values[1] != 0xFF38 {
+		t.Fatalf("ParseResponse() = %v, %v", values, err)
+	}
+	if len(frame) != ResponseLen(InputRegisters, 2) {
+		t.Errorf("ResponseLen() = %d, frame is %d bytes", ResponseLen(InputRegisters, 2), len(frame))
+	}
+
+	// 线圈按 LSB 优先排列
+	coils := checksum.CRC16Modbus.Append([]byte{0x02, 0x01, 0x02, 0b00000101, 0b1})
+	values, err = ParseResponse(0x02, Coils, 9, coils)
+	if err != nil || values[0] != 1 || values[1] != 0 || values[2] != 1 || values[8] != 1 {
+		t.Errorf("coils: %v, %v", values, err)
+	}
+
+	exc := checksum.CRC16Modbus.Append([]byte{0x11, 0x84, 0x02})
+	var ee *ExceptionError
+	if _, err := ParseResponse(0x11, InputRegisters, 2, exc); !errors.As(err, &ee) || ee.Code != 2 {
+		t.Errorf("exception: %v", err)
+	}
+
+	bad := append([]byte(nil), frame...)
+	bad[3] ^= 0xFF
+	if _, err := ParseResponse(0x11, InputRegisters, 2, bad); err == nil {
+		t.Error("Expected CRC error")
+	}
+	if _, err := ParseResponse(0x12, InputRegisters, 2, frame); err == nil {
+		t.Error("Expected error for wrong slave")
+	}
+}
+
+func TestCollector(t *testing.T) {
+	c := NewCollector(HoldingRegisters, 7)
+	c.Write([]byte{0x01, 0x03, 0x02})
+	c.Write([]byte{0x00, 0x2A, 0x38, 0x5B, 0xFF}) // 多余的字节一并返回
+	if got, err := c.Wait(context.Background(), time.Second); err != nil || len(got) != 8 {
+		t.Errorf("Wait() = % X, %v", got, err)
+	}
+
+	// 异常应答比正常应答短
+	c = NewCollector(HoldingRegisters, 7)
+	c.Write([]byte{0x01, 0x83, 0x02, 0xC0, 0xF1})
+	if got, err := c.Wait(context.Background(), time.Second); err != nil || len(got) != 5 {
+		t.Errorf("exception Wait() = % X, %v", got, err)
+	}
+
+	c = NewCollector(HoldingRegisters, 7)
+	if _, err := c.Wait(context.Background(), 10*time.Millisecond); !errors.Is(err, ErrNoResponse) {
+		t.Errorf("Wait() error = %v, want ErrNoResponse", err)
+	}
+}
+
+func TestFrameGap(t *testing.T) {
+	if g := FrameGap(9600); g < 4*time.Millisecond || g > 4100*time.Microsecond {
+		t.Errorf("FrameGap(9600) = %v", g)
+	}
+	if g := FrameGap(115200); g != 1750*time.Microsecond {
+		t.Errorf("FrameGap(115200) = %v", g)
+	}
+	if g := FrameGap(0); g != 0 {
+		t.Errorf("FrameGap(0) = %v", g)
+	}
+}
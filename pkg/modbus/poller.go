@@ -0,0 +1,337 @@
+package modbus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Register 寄存器表中的一项，对应请求中的一个寄存器 (或一个线圈位)
+type Register struct {
+	Name   string  `json:"name"`
+	Scale  float64 `json:"scale,omitempty"`  // 数值乘以该系数，0 表示 1
+	Signed bool    `json:"signed,omitempty"` // 按 16 位有符号数解释 (仅寄存器)
+	Unit   string  `json:"unit,omitempty"`
+}
+
+// SlaveConfig 一个从站的轮询设置
+type SlaveConfig struct {
+	Name      string     `json:"name"`
+	Slave     int        `json:"slave"` // 从站地址 1-247
+	Function  Function   `json:"function"`
+	Start     int        `json:"start"` // 起始地址
+	Count     int        `json:"count"`
+	Registers []Register `json:"registers"` // 每个读取的数量一项，按地址顺序
+}
+
+// Validate 检查设置，错误信息以从站名称开头
+func (s SlaveConfig) Validate() error {
+	fn, err := ParseFunction(string(s.Function))
+	if err != nil {
+		return fmt.Errorf("slave %q: %w", s.Name, err)
+	}
+	switch {
+	case strings.TrimSpace(s.Name) == "":
+		return fmt.Errorf("slave %d: name is required", s.Slave)
+	case s.Slave < 1 || s.Slave > 247:
+		return fmt.Errorf("slave %q: address must be between 1 and 247, got %d", s.Name, s.Slave)
+	case s.Count < 1 || s.Count > fn.MaxCount():
+		return fmt.Errorf("slave %q: count must be between 1 and %d, got %d", s.Name, fn.MaxCount(), s.Count)
+	case s.Start < 0 || s.Start+s.Count > 65536:
+		return fmt.Errorf("slave %q: addresses %d-%d are out of range", s.Name, s.Start, s.Start+s.Count-1)
+	case len(s.Registers) != s.Count:
+		return fmt.Errorf("slave %q: %d register names for %d registers", s.Name, len(s.Registers), s.Count)
+	}
+	for _, r := range s.Registers {
+		if strings.TrimSpace(r.Name) == "" {
+			return fmt.Errorf("slave %q: register name is required", s.Name)
+		}
+		if math.IsNaN(r.Scale) || math.IsInf(r.Scale, 0) {
+			return fmt.Errorf("slave %q: register %q has an invalid scale", s.Name, r.Name)
+		}
+	}
+	return nil
+}
+
+const (
+	// DefaultTimeout Timeout 为 0 时等待应答的时间
+	DefaultTimeout = time.Second
+	// DefaultFailThreshold FailThreshold 为 0 时开始退避的连续失败次数
+	DefaultFailThreshold = 3
+	// DefaultMaxBackoff MaxBackoff 为 0 时退避时间的上限
+	DefaultMaxBackoff = time.Minute
+)
+
+// Config 轮询设置
+type Config struct {
+	Slaves        []SlaveConfig
+	Interval      time.Duration // 相邻两轮的起始间隔，一轮耗时超过间隔时立即开始下一轮
+	Timeout       time.Duration // 等待单个应答的时间
+	Gap           time.Duration // 相邻两次请求之间的最小静默时间，见 FrameGap
+	FailThreshold int           // 连续失败达到该次数后开始退避
+	MaxBackoff    time.Duration // 退避时间从 Interval 开始逐次加倍，不超过该值
+}
+
+// Validate 检查设置
+func (c Config) Validate() error {
+	if len(c.Slaves) == 0 {
+		return errors.New("no slaves to poll")
+	}
+	if c.Interval <= 0 {
+		return errors.New("poll interval must be positive")
+	}
+	if c.Timeout < 0 || c.Gap < 0 || c.FailThreshold < 0 || c.MaxBackoff < 0 {
+		return errors.New("poller limits must not be negative")
+	}
+	names := make(map[string]bool, len(c.Slaves))
+	for _, s := range c.Slaves {
+		if err := s.Validate(); err != nil {
+			return err
+		}
+		if names[s.Name] {
+			return fmt.Errorf("slave %q: duplicate name", s.Name)
+		}
+		names[s.Name] = true
+	}
+	return nil
+}
+
+// Value 一个寄存器的读数
+type Value struct {
+	Name  string  `json:"name"`
+	Value float64 `json:"value"` // 已乘以 Scale
+	Raw   int     `json:"raw"`   // 原始值 (Signed 时已按有符号数解释)
+	Unit  string  `json:"unit,omitempty"`
+}
+
+// Reading 一次成功读取的结果
+type Reading struct {
+	Time   int64   `json:"time"` // Unix 毫秒
+	Name   string  `json:"name"`
+	Slave  int     `json:"slave"`
+	Values []Value `json:"values"`
+}
+
+// SlaveStats 从站的累计统计
+type SlaveStats struct {
+	Name        string `json:"name"`
+	Slave       int    `json:"slave"`
+	Polls       int    `json:"polls"`       // 发出的请求数
+	Errors      int    `json:"errors"`      // 失败数 (包括超时)
+	Timeouts    int    `json:"timeouts"`    // 其中没有应答的次数
+	Consecutive int    `json:"consecutive"` // 当前连续失败次数
+	LastError   string `json:"lastError,omitempty"`
+	// 退避结束的时间 (Unix 毫秒)，0 表示未在退避；退避期间跳过该从站
+	BackoffUntil int64 `json:"backoffUntil,omitempty"`
+}
+
+// Exchange 发送请求并在 timeout 内等待应答帧，want 为正常应答的长度 (可配合 Collector 使用)；
+// 没有完整应答时返回 ErrNoResponse，ctx 取消时应尽快返回
+type Exchange func(ctx context.Context, request []byte, fn Function, want int, timeout time.Duration) ([]byte, error)
+
+// Hooks 轮询结果的回调，均在轮询 goroutine 中调用，可为 nil
+type Hooks struct {
+	Data  func(Reading)
+	Error func(SlaveStats, error)
+	// Cycle 在每轮结束时调用，values 按配置顺序包含所有从站的全部寄存器，本轮未读到的为 NaN
+	Cycle func(t time.Time, values []float64)
+}
+
+// Poller 按顺序循环读取多个从站
+type Poller struct {
+	cfg      Config
+	exchange Exchange
+	hooks    Hooks
+
+	mu     sync.Mutex
+	stats  []SlaveStats
+	until  []time.Time // 各从站的退避结束时间
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// New 创建轮询器，cfg 应已通过 Validate
+func New(cfg Config, exchange Exchange, hooks Hooks) *Poller {
+	if cfg.Timeout == 0 {
+		cfg.Timeout = DefaultTimeout
+	}
+	if cfg.FailThreshold == 0 {
+		cfg.FailThreshold = DefaultFailThreshold
+	}
+	if cfg.MaxBackoff == 0 {
+		cfg.MaxBackoff = DefaultMaxBackoff
+	}
+	cfg.Slaves = append([]SlaveConfig(nil), cfg.Slaves...)
+	for i := range cfg.Slaves {
+		cfg.Slaves[i].Function, _ = ParseFunction(string(cfg.Slaves[i].Function))
+	}
+	p := &Poller{cfg: cfg, exchange: exchange, hooks: hooks, until: make([]time.Time, len(cfg.Slaves))}
+	for _, s := range cfg.Slaves {
+		p.stats = append(p.stats, SlaveStats{Name: s.Name, Slave: s.Slave})
+	}
+	return p
+}
+
+// Start 在新的 goroutine 中开始轮询
+func (p *Poller) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	p.cancel = cancel
+	p.done = make(chan struct{})
+	go p.run(ctx)
+}
+
+// Stop 结束轮询并等待 goroutine 退出；正在等待的应答被放弃，不再调用任何回调。可重复调用
+func (p *Poller) Stop() {
+	if p.cancel == nil {
+		return
+	}
+	p.cancel()
+	<-p.done
+}
+
+// Stats 返回各从站的统计，按配置顺序
+func (p *Poller) Stats() []SlaveStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]SlaveStats(nil), p.stats...)
+}
+
+func (p *Poller) run(ctx context.Context) {
+	defer close(p.done)
+
+	total := 0
+	for _, s := range p.cfg.Slaves {
+		total += s.Count
+	}
+	var lastReq time.Time
+	for {
+		start := time.Now()
+		values := make([]float64, 0, total)
+		for i, s := range p.cfg.Slaves {
+			got, ok := p.pollSlave(ctx, i, s, &lastReq)
+			if ctx.Err() != nil {
+				return
+			}
+			for j := range s.Registers {
+				if ok {
+					values = append(values, got[j].Value)
+				} else {
+					values = append(values, math.NaN())
+				}
+			}
+		}
+		if p.hooks.Cycle != nil {
+			p.hooks.Cycle(time.Now(), values)
+		}
+		if !sleep(ctx, time.Until(start.Add(p.cfg.Interval))) {
+			return
+		}
+	}
+}
+
+// pollSlave 读取一个从站并更新统计；处于退避中或读取失败时 ok 为 false
+func (p *Poller) pollSlave(ctx context.Context, i int, s SlaveConfig, lastReq *time.Time) (values []Value, ok bool) {
+	p.mu.Lock()
+	backoff := time.Now().Before(p.until[i])
+	p.mu.Unlock()
+	if backoff {
+		return nil, false
+	}
+
+	// 与上一次请求 (包括其应答) 之间保持帧间静默
+	if !sleep(ctx, time.Until(lastReq.Add(p.cfg.Gap))) {
+		return nil, false
+	}
+	req := ReadRequest(byte(s.Slave), s.Function, uint16(s.Start), uint16(s.Count))
+	resp, err := p.exchange(ctx, req, s.Function, ResponseLen(s.Function, s.Count), p.cfg.Timeout)
+	*lastReq = time.Now()
+	if ctx.Err() != nil {
+		return nil, false
+	}
+	var raw []uint16
+	if err == nil {
+		raw, err = ParseResponse(byte(s.Slave), s.Function, s.Count, resp)
+	}
+
+	p.mu.Lock()
+	st := &p.stats[i]
+	st.Polls++
+	if err != nil {
+		st.Errors++
+		if errors.Is(err, ErrNoResponse) {
+			st.Timeouts++
+		}
+		st.Consecutive++
+		st.LastError = err.Error()
+		if n := st.Consecutive - p.cfg.FailThreshold; n >= 0 {
+			p.until[i] = time.Now().Add(p.backoff(n))
+			st.BackoffUntil = p.until[i].UnixMilli()
+		}
+	} else {
+		st.Consecutive = 0
+		st.BackoffUntil = 0
+	}
+	snapshot := *st
+	p.mu.Unlock()
+
+	if err != nil {
+		if p.hooks.Error != nil {
+			p.hooks.Error(snapshot, err)
+		}
+		return nil, false
+	}
+	values = scale(s, raw)
+	if p.hooks.Data != nil {
+		p.hooks.Data(Reading{Time: lastReq.UnixMilli(), Name: s.Name, Slave: s.Slave, Values: values})
+	}
+	return values, true
+}
+
+// backoff 返回第 n 次 (从 0 开始) 退避的时间
+func (p *Poller) backoff(n int) time.Duration {
+	d := p.cfg.Interval
+	for ; n > 0 && d < p.cfg.MaxBackoff; n-- {
+		d *= 2
+	}
+	if d > p.cfg.MaxBackoff {
+		d = p.cfg.MaxBackoff
+	}
+	return d
+}
+
+// scale 按寄存器表换算原始值
+func scale(s SlaveConfig, raw []uint16) []Value {
+	values := make([]Value, len(raw))
+	for i, r := range raw {
+		reg := s.Registers[i]
+		v := int(r)
+		if reg.Signed && !s.Function.bitwise() {
+			v = int(int16(r))
+		}
+		f := float64(v)
+		if reg.Scale != 0 {
+			f *= reg.Scale
+		}
+		values[i] = Value{Name: reg.Name, Value: f, Raw: v, Unit: reg.Unit}
+	}
+	return values
+}
+
+// sleep 等待 d 或 ctx 取消，取消时返回 false
+func sleep(ctx context.Context, d time.Duration) bool {
+	if d <= 0 {
+		return ctx.Err() == nil
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
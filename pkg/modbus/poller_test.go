@@ -0,0 +1,150 @@
+package modbus
+
+import (
+	"context"
+	"encoding/binary"
+	"math"
+	"sync"
+	"testing"
+	"time"
+
+	"serial-assistant/pkg/checksum"
+)
+
+// fakeBus 模拟总线上的从站：registers 中的从站按请求返回寄存器值，其他地址没有应答
+type fakeBus struct {
+	mu        sync.Mutex
+	registers map[byte][]uint16
+	requests  map[byte]int
+}
+
+func (b *fakeBus) exchange(ctx context.Context, req []byte, fn Function, want int, timeout time.Duration) ([]byte, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.requests[req[0]]++
+	regs, ok := b.registers[req[0]]
+	if !ok {
+		return nil, ErrNoResponse
+	}
+	start, count := binary.BigEndian.Uint16(req[2:]), binary.BigEndian.Uint16(req[4:])
+	resp := []byte{req[0], req[1], byte(count * 2)}
+	for _, r := range regs[start : start+count] {
+		resp = binary.BigEndian.AppendUint16(resp, r)
+	}
+	return checksum.CRC16Modbus.Append(resp), nil
+}
+
+func TestPollerCycle(t *testing.T) {
+	bus := &fakeBus{
+		registers: map[byte][]uint16{1: {0, 215, 0xFFF6}, 2: {1234}},
+		requests:  make(map[byte]int),
+	}
+	cfg := Config{
+		Slaves: []SlaveConfig{
+			{Name: "temp", Slave: 1, Start: 1, Count: 2, Registers: []Register{
+				{Name: "t1", Scale: 0.1, Unit: "C"}, {Name: "t2", Scale: 0.1, Signed: true, Unit: "C"},
+			}},
+			{Name: "dead", Slave: 9, Count: 1, Registers: []Register{{Name: "x"}}},
+			{Name: "flow", Slave: 2, Function: InputRegisters, Count: 1, Registers: []Register{{Name: "f"}}},
+		},
+		Interval:      5 * time.Millisecond,
+		FailThreshold: 2,
+		MaxBackoff:    time.Hour,
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	var mu sync.Mutex
+	var readings []Reading
+	var cycles [][]float64
+	p := New(cfg, bus.exchange, Hooks{
+		Data: func(r Reading) { mu.Lock(); readings = append(readings, r); mu.Unlock() },
+		Cycle: func(_ time.Time, v []float64) {
+			mu.Lock()
+			cycles = append(cycles, v)
+			mu.Unlock()
+		},
+	})
+	p.Start()
+	time.Sleep(50 * time.Millisecond)
+	p.Stop()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(cycles) < 3 {
+		t.Fatalf("only %d cycles", len(cycles))
+	}
+	c := cycles[0]
+	if len(c) != 4 || math.Abs(c[0]-21.5) > 1e-9 || math.Abs(c[1]+1) > 1e-9 || !math.IsNaN(c[2]) || c[3] != 1234 {
+		t.Errorf("first cycle = %v", c)
+	}
+	if readings[0].Name != "temp" || readings[0].Values[1].Raw != -10 || readings[0].Values[1].Unit != "C" {
+		t.Errorf("first reading = %+v", readings[0])
+	}
+
+	// 连续失败两次后进入退避，退避时间逐次加倍，期间跳过该从站
+	stats := p.Stats()
+	if dead := stats[1]; dead.Polls < 2 || dead.Polls >= len(cycles) || dead.Timeouts != dead.Polls || dead.BackoffUntil == 0 {
+		t.Errorf("dead slave stats = %+v", dead)
+	}
+	if stats[0].Errors != 0 || stats[0].Polls != len(cycles) {
+		t.Errorf("temp stats = %+v (%d cycles)", stats[0], len(cycles))
+	}
+}
+
+func TestPollerStopMidTransaction(t *testing.T) {
+	started := make(chan struct{})
+	calls := 0
+	exchange := func(ctx context.Context, req []byte, fn Function, want int, timeout time.Duration) ([]byte, error) {
+		calls++
+		close(started)
+		<-ctx.Done() // 应答一直没有到达
+		return nil, ctx.Err()
+	}
+	cfg := Config{
+		Slaves:   []SlaveConfig{{Name: "s", Slave: 1, Count: 1, Registers: []Register{{Name: "r"}}}},
+		Interval: time.Second,
+		Timeout:  time.Hour,
+	}
+	hooked := false
+	p := New(cfg, exchange, Hooks{
+		Error: func(SlaveStats, error) { hooked = true },
+		Cycle: func(time.Time, []float64) { hooked = true },
+	})
+	p.Start()
+	<-started
+
+	stopped := make(chan struct{})
+	go func() { p.Stop(); close(stopped) }()
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("Stop() did not return")
+	}
+	if hooked || calls != 1 || p.Stats()[0].Polls != 0 {
+		t.Errorf("hooks called after stop: %v, calls %d, stats %+v", hooked, calls, p.Stats())
+	}
+	p.Stop()
+}
+
+func TestValidateSlave(t *testing.T) {
+	ok := SlaveConfig{Name: "s", Slave: 1, Count: 1, Registers: []Register{{Name: "r"}}}
+	if err := ok.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	bad := []SlaveConfig{
+		{Name: "s", Slave: 0, Count: 1, Registers: ok.Registers},
+		{Name: "s", Slave: 1, Count: 2, Registers: ok.Registers},
+		{Name: "s", Slave: 1, Count: 1, Function: "write", Registers: ok.Registers},
+		{Name: "s", Slave: 1, Start: 65535, Count: 2, Registers: []Register{{Name: "a"}, {Name: "b"}}},
+	}
+	for i, s := range bad {
+		if err := s.Validate(); err == nil {
+			t.Errorf("case %d: expected error", i)
+		}
+	}
+	if err := (Config{Slaves: []SlaveConfig{ok, ok}, Interval: time.Second}).Validate(); err == nil {
+		t.Error("Expected error for duplicate names")
+	}
+}
@@ -0,0 +1,161 @@
+// Package nametmpl 展开导出、抓包文件名中的占位符
+//
+// 支持的占位符：
+//
+//	{{port}}   当前连接的端点 (端口名、芯片或 主机_端口)，经 fsname.Sanitize 处理，未连接时为 "none"
+//	{{date}}   日期，格式见 Vars.DateLayout
+//	{{time}}   时间，格式见 Vars.TimeLayout
+//	{{n}}      从 1 开始的序号，取第一个不与已有文件冲突的值
+//
+// 例如 "captures/capture_{{port}}_{{date}}_{{n}}.log"。模板中没有 {{n}} 时按普通路径处理，
+// 已存在的同名文件会被覆盖 (与直接给出路径的行为一致)；出现未知占位符或未闭合的 "{{" 时返回错误。
+package nametmpl
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"serial-assistant/pkg/fsname"
+)
+
+// 默认的日期与时间格式 (Go 时间格式)
+const (
+	DefaultDateLayout = "2006-01-02"
+	DefaultTimeLayout = "150405"
+)
+
+// MaxN {{n}} 的上限，超过后返回错误
+const MaxN = 9999
+
+// Vars 占位符的取值
+type Vars struct {
+	Port       string // 连接端点，展开前经 fsname.Sanitize 处理
+	Time       time.Time
+	DateLayout string // 为空时使用 DefaultDateLayout
+	TimeLayout string // 为空时使用 DefaultTimeLayout
+}
+
+// segment 模板的一段：字面文本或占位符
+type segment struct {
+	text string
+	name string // 非空时为占位符
+}
+
+var known = map[string]bool{"port": true, "date": true, "time": true, "n": true}
+
+func parse(tmpl string) ([]segment, error) {
+	if strings.TrimSpace(tmpl) == "" {
+		return nil, fmt.Errorf("file name template is empty")
+	}
+	var segs []segment
+	rest := tmpl
+	for {
+		i := strings.Index(rest, "{{")
+		if i < 0 {
+			segs = append(segs, segment{text: rest})
+			return segs, nil
+		}
+		j := strings.Index(rest[i+2:], "}}")
+		if j < 0 {
+			return nil, fmt.Errorf("unterminated placeholder in file name template %q", tmpl)
+		}
+		name := strings.TrimSpace(rest[i+2 : i+2+j])
+		if !known[name] {
+			return nil, fmt.Errorf("unknown placeholder {{%s}} in file name template (available: port, date, time, n)", name)
+		}
+		segs = append(segs, segment{text: rest[:i]}, segment{name: name})
+		rest = rest[i+2+j+2:]
+	}
+}
+
+// Validate 检查模板的语法
+func Validate(tmpl string) error {
+	_, err := parse(tmpl)
+	return err
+}
+
+// layoutPart 按格式输出时间，并把格式中可能出现的路径分隔符与冒号替换为 '-'
+func layoutPart(t time.Time, layout, def string) string {
+	if layout == "" {
+		layout = def
+	}
+	return strings.NewReplacer("/", "-", `\`, "-", ":", "-").Replace(t.Format(layout))
+}
+
+func expand(segs []segment, v Vars, n int) string {
+	var sb strings.Builder
+	for _, s := range segs {
+		switch s.name {
+		case "":
+			sb.WriteString(s.text)
+		case "port":
+			port := fsname.Sanitize(v.Port)
+			if port == "" {
+				port = "none"
+			}
+			sb.WriteString(port)
+		case "date":
+			sb.WriteString(layoutPart(v.Time, v.DateLayout, DefaultDateLayout))
+		case "time":
+			sb.WriteString(layoutPart(v.Time, v.TimeLayout, DefaultTimeLayout))
+		case "n":
+			sb.WriteString(strconv.Itoa(n))
+		}
+	}
+	return sb.String()
+}
+
+// exists 文件或其压缩版本 (导出开启压缩时追加 ".gz") 是否已存在
+func exists(path string) bool {
+	for _, p := range []string{path, path + ".gz"} {
+		if _, err := os.Lstat(p); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// Resolve 展开模板并创建所在目录，返回绝对路径
+// 含 {{n}} 时从 1 开始递增，直到文件及其 ".gz" 版本都不存在；调用方应随即创建文件
+func Resolve(tmpl string, v Vars) (string, error) {
+	segs, err := parse(tmpl)
+	if err != nil {
+		return "", err
+	}
+	if v.Time.IsZero() {
+		v.Time = time.Now()
+	}
+	counted := false
+	for _, s := range segs {
+		if s.name == "n" {
+			counted = true
+		}
+	}
+
+	path := expand(segs, v, 0)
+	if counted {
+		path = ""
+		for n := 1; n <= MaxN; n++ {
+			if p := expand(segs, v, n); !exists(p) {
+				path = p
+				break
+			}
+		}
+		if path == "" {
+			return "", fmt.Errorf("no free file name for template %q after %d attempts", tmpl, MaxN)
+		}
+	}
+
+	path, err = filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", fmt.Errorf("failed to create directory: %w", err)
+	}
+	return path, nil
+}
@@ -0,0 +1,100 @@
+package nametmpl
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+var at = time.Date(2024, 3, 4, 5, 6, 7, 0, time.UTC)
+
+func TestResolvePlaceholders(t *testing.T) {
+	dir := t.TempDir()
+	got, err := Resolve(filepath.Join(dir, "capture_{{port}}_{{date}}_{{time}}.log"), Vars{Port: "/dev/ttyUSB0", Time: at})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(dir, "capture_dev_ttyUSB0_2024-03-04_050607.log"); got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+
+	got, err = Resolve(filepath.Join(dir, "{{date}}.csv"), Vars{Time: at, DateLayout: "2006/01/02 15:04"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filepath.Base(got) != "2024-03-04 05-06.csv" {
+		t.Errorf("layout separators not replaced: %s", got)
+	}
+
+	got, _ = Resolve(filepath.Join(dir, "{{port}}.log"), Vars{Time: at})
+	if filepath.Base(got) != "none.log" {
+		t.Errorf("empty port = %s", got)
+	}
+}
+
+func TestResolveIncrementsN(t *testing.T) {
+	dir := t.TempDir()
+	tmpl := filepath.Join(dir, "cap_{{n}}.log")
+	for i, want := range []string{"cap_1.log", "cap_2.log", "cap_4.log"} {
+		got, err := Resolve(tmpl, Vars{Time: at})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if filepath.Base(got) != want {
+			t.Fatalf("resolve %d = %s, want %s", i, got, want)
+		}
+		os.WriteFile(got, nil, 0644)
+		if i == 1 {
+			// 压缩导出的文件同样视为冲突
+			os.WriteFile(filepath.Join(dir, "cap_3.log.gz"), nil, 0644)
+		}
+	}
+}
+
+func TestResolveWithoutNOverwrites(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "fixed.log")
+	os.WriteFile(path, []byte("old"), 0644)
+	got, err := Resolve(path, Vars{})
+	if err != nil || got != path {
+		t.Errorf("got %s, %v", got, err)
+	}
+}
+
+func TestResolveCreatesDirectories(t *testing.T) {
+	dir := t.TempDir()
+	got, err := Resolve(filepath.Join(dir, "{{date}}", "sub", "x_{{n}}.pcapng"), Vars{Time: at})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info, err := os.Stat(filepath.Dir(got)); err != nil || !info.IsDir() {
+		t.Errorf("directory not created: %v", err)
+	}
+
+	// 目录位置被普通文件占用
+	blocker := filepath.Join(dir, "file")
+	os.WriteFile(blocker, nil, 0644)
+	if _, err := Resolve(filepath.Join(blocker, "x.log"), Vars{}); err == nil {
+		t.Error("expected an error when the directory cannot be created")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	for _, ok := range []string{"plain.log", "{{port}}_{{ n }}.log", "a{b}c.log"} {
+		if err := Validate(ok); err != nil {
+			t.Errorf("Validate(%q) = %v", ok, err)
+		}
+	}
+	tests := []struct{ tmpl, msg string }{
+		{"", "empty"},
+		{"cap_{{seq}}.log", "unknown placeholder {{seq}}"},
+		{"cap_{{port.log", "unterminated"},
+	}
+	for _, tt := range tests {
+		if err := Validate(tt.tmpl); err == nil || !strings.Contains(err.Error(), tt.msg) {
+			t.Errorf("Validate(%q) = %v, want %q", tt.tmpl, err, tt.msg)
+		}
+	}
+}
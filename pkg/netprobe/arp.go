@@ -0,0 +1,28 @@
+package netprobe
+
+import (
+	"net"
+	"strconv"
+	"strings"
+)
+
+// atfComplete ARP 条目标志位：地址已解析
+const atfComplete = 0x2
+
+// parseARPTable 解析 /proc/net/arp 格式的表，判断 ip 是否有已解析的条目
+//
+//	IP address       HW type     Flags       HW address            Mask     Device
+//	192.168.1.1      0x1         0x2         00:11:22:33:44:55     *        eth0
+func parseARPTable(table string, ip net.IP) bool {
+	for _, line := range strings.Split(table, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 4 || !ip.Equal(net.ParseIP(fields[0])) {
+			continue
+		}
+		flags, err := strconv.ParseUint(strings.TrimPrefix(fields[2], "0x"), 16, 32)
+		if err == nil && flags&atfComplete != 0 && fields[3] != "00:00:00:00:00:00" {
+			return true
+		}
+	}
+	return false
+}
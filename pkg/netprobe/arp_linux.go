@@ -0,0 +1,17 @@
+//go:build linux
+
+package netprobe
+
+import (
+	"net"
+	"os"
+)
+
+// arpPresent 在 /proc/net/arp 中查找 ip 的完整条目
+func arpPresent(ip net.IP) (bool, error) {
+	data, err := os.ReadFile("/proc/net/arp")
+	if err != nil {
+		return false, err
+	}
+	return parseARPTable(string(data), ip), nil
+}
@@ -0,0 +1,13 @@
+//go:build !linux
+
+package netprobe
+
+import (
+	"errors"
+	"net"
+)
+
+// arpPresent 其他平台没有无需特权即可读取的 ARP 表，结果视为 Unknown
+func arpPresent(ip net.IP) (bool, error) {
+	return false, errors.New("arp table not available on this platform")
+}
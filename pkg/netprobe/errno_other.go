@@ -0,0 +1,12 @@
+//go:build !windows
+
+package netprobe
+
+import "syscall"
+
+// 连接错误对应的系统错误码
+var (
+	errConnRefused = syscall.ECONNREFUSED
+	errNetUnreach  = syscall.ENETUNREACH
+	errHostUnreach = syscall.EHOSTUNREACH
+)
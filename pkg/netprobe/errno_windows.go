@@ -0,0 +1,12 @@
+//go:build windows
+
+package netprobe
+
+import "syscall"
+
+// 连接错误对应的 Winsock 错误码 (WSAECONNREFUSED、WSAENETUNREACH、WSAEHOSTUNREACH)
+var (
+	errConnRefused = syscall.Errno(10061)
+	errNetUnreach  = syscall.Errno(10051)
+	errHostUnreach = syscall.Errno(10065)
+)
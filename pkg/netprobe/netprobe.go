@@ -0,0 +1,212 @@
+// Package netprobe 在 TCP 连接失败后做快速的后续探测 (ping、备用端口、ARP)，
+// 把 "i/o timeout" 之类的底层错误转换为可操作的诊断结论
+package netprobe
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Timeout 全部后续探测的总耗时上限
+const Timeout = 2 * time.Second
+
+// Result 单项探测结果
+type Result int
+
+const (
+	// Unknown 未执行或无法执行 (例如没有原始套接字权限)
+	Unknown Result = iota
+	// Reachable 探测成功
+	Reachable
+	// Unreachable 探测已执行但没有响应
+	Unreachable
+)
+
+func (r Result) String() string {
+	switch r {
+	case Reachable:
+		return "reachable"
+	case Unreachable:
+		return "unreachable"
+	default:
+		return "unknown"
+	}
+}
+
+// Findings 一次连接失败的探测结果
+type Findings struct {
+	Host string
+	Port string
+
+	DialErr error // 原始的连接错误
+
+	Ping        Result
+	AltPort     int    // 备用端口 (例如设备的 Web 界面)，0 表示未提供
+	AltPortDial Result // 连接备用端口的结果
+	LocalSubnet bool   // 目标地址位于本机某个网卡的子网内
+	ARP         Result // 本地子网内 ARP 表中是否有该地址的完整条目
+}
+
+// Prober 执行各项探测，字段可替换以便测试；为 nil 的探测视为 Unknown
+type Prober struct {
+	Resolve     func(ctx context.Context, host string) (net.IP, error)
+	Ping        func(ctx context.Context, ip net.IP) (bool, error)
+	Dial        func(ctx context.Context, addr string) error
+	ARP         func(ip net.IP) (bool, error)
+	LocalSubnet func(ip net.IP) bool
+}
+
+// DefaultProber 使用真实网络的探测器：ICMP 需要原始套接字权限，没有权限时 Ping 结果为 Unknown；
+// ARP 只在 Linux 上可用
+func DefaultProber() Prober {
+	return Prober{
+		Resolve: resolve,
+		Ping:    ping,
+		Dial: func(ctx context.Context, addr string) error {
+			var d net.Dialer
+			conn, err := d.DialContext(ctx, "tcp", addr)
+			if err == nil {
+				conn.Close()
+			}
+			return err
+		},
+		ARP:         arpPresent,
+		LocalSubnet: localSubnet,
+	}
+}
+
+// Run 在 Timeout 内并发执行后续探测；连接被拒绝或域名无法解析时错误本身已足够明确，不再探测
+func (p Prober) Run(ctx context.Context, host, port string, altPort int, dialErr error) Findings {
+	f := Findings{Host: host, Port: port, DialErr: dialErr, AltPort: altPort}
+	if errors.Is(dialErr, errConnRefused) || isDNSError(dialErr) {
+		return f
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, Timeout)
+	defer cancel()
+
+	if p.Resolve == nil {
+		return f
+	}
+	ip, err := p.Resolve(ctx, host)
+	if err != nil {
+		return f
+	}
+	if p.LocalSubnet != nil {
+		f.LocalSubnet = p.LocalSubnet(ip)
+	}
+
+	var wg sync.WaitGroup
+	if p.Ping != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ok, err := p.Ping(ctx, ip)
+			f.Ping = toResult(ok, err)
+		}()
+	}
+	if p.Dial != nil && altPort > 0 && strconv.Itoa(altPort) != port {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := p.Dial(ctx, net.JoinHostPort(ip.String(), strconv.Itoa(altPort)))
+			f.AltPortDial = toResult(err == nil, nil)
+		}()
+	}
+	wg.Wait()
+
+	// 前面的连接尝试已经触发了 ARP 解析，此时查表即可
+	if p.ARP != nil && f.LocalSubnet {
+		ok, err := p.ARP(ip)
+		f.ARP = toResult(ok, err)
+	}
+	return f
+}
+
+func toResult(ok bool, err error) Result {
+	switch {
+	case err != nil:
+		return Unknown
+	case ok:
+		return Reachable
+	default:
+		return Unreachable
+	}
+}
+
+func isDNSError(err error) bool {
+	var dnsErr *net.DNSError
+	return errors.As(err, &dnsErr)
+}
+
+// Diagnose 根据探测结果给出诊断结论，无法得出结论时返回空字符串
+func Diagnose(f Findings) string {
+	switch {
+	case f.DialErr == nil:
+		return ""
+	case errors.Is(f.DialErr, errConnRefused):
+		return fmt.Sprintf("host %s is up but port %s is closed (connection refused)", f.Host, f.Port)
+	case isDNSError(f.DialErr):
+		return fmt.Sprintf("cannot resolve host name %q", f.Host)
+	case errors.Is(f.DialErr, errNetUnreach):
+		return fmt.Sprintf("network unreachable: no route to the subnet of %s, check this computer's IP address and gateway", f.Host)
+	}
+
+	hostUnreach := errors.Is(f.DialErr, errHostUnreach)
+	switch {
+	case f.Ping == Reachable && f.AltPortDial == Reachable:
+		return fmt.Sprintf("host responds to ping and port %d but port %s does not answer (closed or filtered)", f.AltPort, f.Port)
+	case f.Ping == Reachable:
+		return fmt.Sprintf("host responds to ping but port %s does not answer (closed or filtered)", f.Port)
+	case f.AltPortDial == Reachable:
+		return fmt.Sprintf("host is up (port %d answers) but port %s does not answer (closed or filtered)", f.AltPort, f.Port)
+	case f.LocalSubnet && f.ARP == Reachable:
+		return fmt.Sprintf("host is present on the local network but does not answer on port %s, a firewall may be blocking it", f.Port)
+	case f.LocalSubnet && f.ARP == Unreachable:
+		return fmt.Sprintf("no device answers at %s on the local subnet, check power, cabling and the IP address", f.Host)
+	case f.Ping == Unreachable && !f.LocalSubnet:
+		return fmt.Sprintf("host does not respond to ping and port %s times out, it may be down or a router/firewall is dropping traffic", f.Port)
+	case hostUnreach:
+		return fmt.Sprintf("host %s is unreachable, check that it is powered and on the same network", f.Host)
+	}
+	return ""
+}
+
+// resolve 解析主机名，优先使用 IPv4 地址
+func resolve(ctx context.Context, host string) (net.IP, error) {
+	if ip := net.ParseIP(host); ip != nil {
+		return ip, nil
+	}
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	for _, a := range addrs {
+		if a.IP.To4() != nil {
+			return a.IP, nil
+		}
+	}
+	if len(addrs) == 0 {
+		return nil, fmt.Errorf("no address for %s", host)
+	}
+	return addrs[0].IP, nil
+}
+
+// localSubnet 判断 ip 是否位于本机某个网卡的子网内
+func localSubnet(ip net.IP) bool {
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return false
+	}
+	for _, a := range addrs {
+		if ipNet, ok := a.(*net.IPNet); ok && !ipNet.IP.IsLoopback() && ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
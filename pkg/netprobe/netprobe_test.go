@@ -0,0 +1,158 @@
+package netprobe
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func dialError(err error) error {
+	return &net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", err)}
+}
+
+var errTimeout = &net.OpError{Op: "dial", Net: "tcp", Err: os.ErrDeadlineExceeded}
+
+func TestDiagnose(t *testing.T) {
+	tests := []struct {
+		name     string
+		findings Findings
+		contains string
+	}{
+		{"refused", Findings{DialErr: dialError(errConnRefused)}, "port 4001 is closed"},
+		{"dns", Findings{DialErr: &net.DNSError{Err: "no such host", Name: "plc.local"}}, "cannot resolve"},
+		{"net unreachable", Findings{DialErr: dialError(errNetUnreach)}, "network unreachable"},
+		{"ping ok", Findings{DialErr: errTimeout, Ping: Reachable}, "responds to ping but port 4001"},
+		{"ping and web ui ok", Findings{DialErr: errTimeout, Ping: Reachable, AltPort: 80, AltPortDial: Reachable}, "ping and port 80"},
+		{"web ui only", Findings{DialErr: errTimeout, Ping: Unknown, AltPort: 80, AltPortDial: Reachable}, "port 80 answers"},
+		{"arp present", Findings{DialErr: errTimeout, Ping: Unreachable, LocalSubnet: true, ARP: Reachable}, "firewall"},
+		{"arp absent", Findings{DialErr: errTimeout, Ping: Unknown, LocalSubnet: true, ARP: Unreachable}, "no device answers"},
+		{"remote silent", Findings{DialErr: errTimeout, Ping: Unreachable}, "does not respond to ping"},
+		{"host unreachable", Findings{DialErr: dialError(errHostUnreach)}, "is unreachable"},
+		{"nothing known", Findings{DialErr: errTimeout}, ""},
+		{"no error", Findings{Ping: Reachable}, ""},
+	}
+	for _, tt := range tests {
+		tt.findings.Host, tt.findings.Port = "192.168.1.50", "4001"
+		got := Diagnose(tt.findings)
+		if tt.contains == "" {
+			if got != "" {
+				t.Errorf("%s: expected no diagnosis, got %q", tt.name, got)
+			}
+			continue
+		}
+		if !strings.Contains(got, tt.contains) {
+			t.Errorf("%s: expected diagnosis containing %q, got %q", tt.name, tt.contains, got)
+		}
+	}
+}
+
+func fakeProber(ping Result, alt Result, subnet bool, arp Result) (Prober, *[]string) {
+	var calls []string
+	result := func(r Result) (bool, error) {
+		if r == Unknown {
+			return false, errors.New("operation not permitted")
+		}
+		return r == Reachable, nil
+	}
+	return Prober{
+		Resolve: func(ctx context.Context, host string) (net.IP, error) {
+			calls = append(calls, "resolve")
+			return net.ParseIP(host), nil
+		},
+		Ping: func(ctx context.Context, ip net.IP) (bool, error) { return result(ping) },
+		Dial: func(ctx context.Context, addr string) error {
+			if ok, _ := result(alt); !ok {
+				return errors.New("timeout")
+			}
+			return nil
+		},
+		ARP: func(ip net.IP) (bool, error) {
+			calls = append(calls, "arp")
+			return result(arp)
+		},
+		LocalSubnet: func(ip net.IP) bool { return subnet },
+	}, &calls
+}
+
+func TestRun(t *testing.T) {
+	p, calls := fakeProber(Reachable, Reachable, false, Reachable)
+	f := p.Run(context.Background(), "10.0.0.5", "4001", 80, errTimeout)
+	if f.Ping != Reachable || f.AltPortDial != Reachable || f.LocalSubnet || f.ARP != Unknown {
+		t.Errorf("Unexpected findings: %+v", f)
+	}
+	for _, c := range *calls {
+		if c == "arp" {
+			t.Error("ARP should only be checked for local subnet targets")
+		}
+	}
+
+	// 没有原始套接字权限时 ping 结果为 Unknown；未提供备用端口时不探测
+	p, _ = fakeProber(Unknown, Reachable, true, Unreachable)
+	f = p.Run(context.Background(), "192.168.1.50", "4001", 0, errTimeout)
+	if f.Ping != Unknown || f.AltPortDial != Unknown || f.ARP != Unreachable {
+		t.Errorf("Unexpected findings: %+v", f)
+	}
+	if d := Diagnose(f); !strings.Contains(d, "no device answers") {
+		t.Errorf("Unexpected diagnosis %q", d)
+	}
+
+	// 连接被拒绝时不做后续探测
+	p, calls = fakeProber(Reachable, Reachable, true, Reachable)
+	f = p.Run(context.Background(), "192.168.1.50", "4001", 80, dialError(errConnRefused))
+	if len(*calls) != 0 || f.Ping != Unknown {
+		t.Errorf("Expected no probes for refused connection, got calls %q", *calls)
+	}
+}
+
+// TestRunBounded verifies a hanging probe cannot delay the result past the context deadline
+func TestRunBounded(t *testing.T) {
+	p := Prober{
+		Resolve: func(ctx context.Context, host string) (net.IP, error) { return net.ParseIP(host), nil },
+		Ping: func(ctx context.Context, ip net.IP) (bool, error) {
+			<-ctx.Done()
+			return false, nil
+		},
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	f := p.Run(ctx, "10.0.0.5", "4001", 0, errTimeout)
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Run took %v", elapsed)
+	}
+	if f.Ping != Unreachable {
+		t.Errorf("Expected ping timeout to be reported as unreachable, got %v", f.Ping)
+	}
+}
+
+func TestParseARPTable(t *testing.T) {
+	table := `IP address       HW type     Flags       HW address            Mask     Device
+192.168.1.1      0x1         0x2         00:11:22:33:44:55     *        eth0
+192.168.1.50     0x1         0x0         00:00:00:00:00:00     *        eth0
+`
+	if !parseARPTable(table, net.ParseIP("192.168.1.1")) {
+		t.Error("Expected complete entry to be present")
+	}
+	if parseARPTable(table, net.ParseIP("192.168.1.50")) {
+		t.Error("Expected incomplete entry to be absent")
+	}
+	if parseARPTable(table, net.ParseIP("192.168.1.99")) {
+		t.Error("Expected missing entry to be absent")
+	}
+}
+
+func TestEchoRequestChecksum(t *testing.T) {
+	msg := echoRequest(icmpv4EchoRequest, 0x1234, 1, true)
+	if msg[0] != icmpv4EchoRequest || msg[4] != 0x12 || msg[5] != 0x34 {
+		t.Errorf("Unexpected header % X", msg[:8])
+	}
+	// 含校验和的报文再次计算校验和应为 0
+	if sum := icmpChecksum(msg); sum != 0 {
+		t.Errorf("Expected checksum to verify, got 0x%04X", sum)
+	}
+}
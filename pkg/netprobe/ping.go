@@ -0,0 +1,83 @@
+package netprobe
+
+import (
+	"context"
+	"encoding/binary"
+	"net"
+	"os"
+	"time"
+)
+
+// ICMP 报文类型
+const (
+	icmpv4EchoRequest = 8
+	icmpv4EchoReply   = 0
+	icmpv6EchoRequest = 128
+	icmpv6EchoReply   = 129
+)
+
+// ping 发送一个 ICMP Echo 请求并等待应答，直到 ctx 超时
+// 需要原始套接字权限，没有权限时返回错误 (调用方将其视为 Unknown)
+func ping(ctx context.Context, ip net.IP) (bool, error) {
+	network, request, reply := "ip4:icmp", byte(icmpv4EchoRequest), byte(icmpv4EchoReply)
+	if ip.To4() == nil {
+		network, request, reply = "ip6:ipv6-icmp", icmpv6EchoRequest, icmpv6EchoReply
+	}
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, network, ip.String())
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	id := uint16(os.Getpid())
+	if _, err := conn.Write(echoRequest(request, id, 1, ip.To4() != nil)); err != nil {
+		return false, err
+	}
+
+	buf := make([]byte, 1500)
+	for {
+		n, err := conn.Read(buf)
+		if err != nil {
+			if ne, ok := err.(net.Error); ok && ne.Timeout() {
+				return false, nil
+			}
+			return false, err
+		}
+		if n >= 8 && buf[0] == reply && binary.BigEndian.Uint16(buf[4:6]) == id {
+			return true, nil
+		}
+	}
+}
+
+// echoRequest 构造 ICMP Echo 请求；IPv6 的校验和由内核计算
+func echoRequest(typ byte, id, seq uint16, checksum bool) []byte {
+	msg := make([]byte, 8, 8+16)
+	msg[0] = typ
+	binary.BigEndian.PutUint16(msg[4:6], id)
+	binary.BigEndian.PutUint16(msg[6:8], seq)
+	msg = append(msg, []byte(time.Now().Format("150405.000000"))...)
+	if checksum {
+		binary.BigEndian.PutUint16(msg[2:4], icmpChecksum(msg))
+	}
+	return msg
+}
+
+// icmpChecksum RFC 1071 互联网校验和
+func icmpChecksum(b []byte) uint16 {
+	var sum uint32
+	for i := 0; i+1 < len(b); i += 2 {
+		sum += uint32(b[i])<<8 | uint32(b[i+1])
+	}
+	if len(b)%2 == 1 {
+		sum += uint32(b[len(b)-1]) << 8
+	}
+	for sum>>16 != 0 {
+		sum = sum&0xffff + sum>>16
+	}
+	return ^uint16(sum)
+}
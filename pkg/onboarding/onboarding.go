@@ -0,0 +1,109 @@
+// Package onboarding 提供首次启动时安装的示例内容：几条示例连接配置，以及一段演示会话
+// (载入历史缓冲区后可通过 RequestReplay 回放)
+//
+// 示例内容以 go:embed 打包在程序中，修改 assets 目录下的文件后需递增 Version
+package onboarding
+
+import (
+	"bufio"
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"serial-assistant/pkg/input"
+	"serial-assistant/pkg/settings"
+)
+
+// Version 示例内容的版本；设置中记录的版本较旧时会补装新增的示例
+const Version = 1
+
+//go:embed assets/profiles.json
+var profilesJSON []byte
+
+//go:embed assets/demo.jsonl
+var demoJSONL []byte
+
+// Profiles 返回内置的示例连接配置
+func Profiles() ([]settings.Profile, error) {
+	var list []settings.Profile
+	if err := json.Unmarshal(profilesJSON, &list); err != nil {
+		return nil, fmt.Errorf("sample profiles: %w", err)
+	}
+	for i := range list {
+		list[i].Sample = true
+	}
+	return list, nil
+}
+
+// Install 将示例配置追加到 existing，返回新的列表与实际添加的名称
+// 已存在同名配置 (无论是否被用户修改过) 时跳过该示例，因此重复调用不会产生重复或覆盖用户的配置
+func Install(existing []settings.Profile) (out []settings.Profile, added []string, err error) {
+	samples, err := Profiles()
+	if err != nil {
+		return existing, nil, err
+	}
+	names := make(map[string]bool, len(existing))
+	for _, p := range existing {
+		names[p.Name] = true
+	}
+	out = append([]settings.Profile(nil), existing...)
+	for _, p := range samples {
+		if names[p.Name] {
+			continue
+		}
+		out = append(out, p)
+		added = append(added, p.Name)
+	}
+	return out, added, nil
+}
+
+// demoRecord 演示会话文件 (JSON Lines) 中的一行，Text、Hex 与 Annotation 三选一
+type demoRecord struct {
+	OffsetMs   int64  `json:"offsetMs"`
+	Text       string `json:"text,omitempty"`
+	Hex        string `json:"hex,omitempty"`
+	Annotation string `json:"annotation,omitempty"`
+}
+
+// DemoChunk 演示会话中的一次接收或一条标注
+type DemoChunk struct {
+	Time       time.Time
+	Data       []byte
+	Annotation string
+}
+
+// Demo 返回演示会话，时间从 start 开始按录制时的间隔排列
+func Demo(start time.Time) ([]DemoChunk, error) {
+	var out []DemoChunk
+	sc := bufio.NewScanner(bytes.NewReader(demoJSONL))
+	line := 0
+	for sc.Scan() {
+		line++
+		raw := bytes.TrimSpace(sc.Bytes())
+		if len(raw) == 0 {
+			continue
+		}
+		var rec demoRecord
+		if err := json.Unmarshal(raw, &rec); err != nil {
+			return nil, fmt.Errorf("demo session line %d: %w", line, err)
+		}
+		c := DemoChunk{Time: start.Add(time.Duration(rec.OffsetMs) * time.Millisecond), Annotation: rec.Annotation}
+		switch {
+		case rec.Annotation != "":
+		case rec.Hex != "":
+			data, err := input.ParseHex(rec.Hex)
+			if err != nil {
+				return nil, fmt.Errorf("demo session line %d: %w", line, err)
+			}
+			c.Data = data
+		case rec.Text != "":
+			c.Data = []byte(rec.Text)
+		default:
+			return nil, fmt.Errorf("demo session line %d: empty record", line)
+		}
+		out = append(out, c)
+	}
+	return out, sc.Err()
+}
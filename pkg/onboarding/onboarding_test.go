@@ -0,0 +1,94 @@
+package onboarding
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"serial-assistant/pkg/connspec"
+	"serial-assistant/pkg/settings"
+)
+
+func TestProfilesAreValid(t *testing.T) {
+	list, err := Profiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) == 0 {
+		t.Fatal("no sample profiles")
+	}
+	kinds := map[connspec.Kind]bool{}
+	for _, p := range list {
+		spec, err := connspec.Parse(p.Spec)
+		if err != nil {
+			t.Errorf("%s: %v", p.Name, err)
+			continue
+		}
+		kinds[spec.Kind] = true
+		if !p.Sample || p.Description == "" {
+			t.Errorf("%s: %+v", p.Name, p)
+		}
+	}
+	for _, k := range []connspec.Kind{connspec.Virtual, connspec.TcpClient, connspec.JLink} {
+		if !kinds[k] {
+			t.Errorf("no sample profile for %s", k)
+		}
+	}
+}
+
+func TestInstallIsIdempotent(t *testing.T) {
+	samples, _ := Profiles()
+	mine := settings.Profile{Name: samples[0].Name, Spec: "serial:COM9@9600,8N1", Description: "edited by the user"}
+	existing := []settings.Profile{{Name: "bench PSU", Spec: "serial:COM3@9600,8N1"}, mine}
+
+	out, added, err := Install(existing)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(added) != len(samples)-1 || len(out) != len(existing)+len(added) {
+		t.Fatalf("added %v, %d profiles", added, len(out))
+	}
+	if !reflect.DeepEqual(out[1], mine) {
+		t.Errorf("user profile with a sample's name was overwritten: %+v", out[1])
+	}
+	if existing[0].Name != "bench PSU" || len(existing) != 2 {
+		t.Error("Install modified its input")
+	}
+
+	again, added, err := Install(out)
+	if err != nil || len(added) != 0 || len(again) != len(out) {
+		t.Errorf("second Install added %v (%d profiles), err %v", added, len(again), err)
+	}
+}
+
+func TestDemo(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+	chunks, err := Demo(start)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(chunks) < 5 {
+		t.Fatalf("demo has only %d records", len(chunks))
+	}
+	if chunks[0].Annotation == "" || !chunks[0].Time.Equal(start) {
+		t.Errorf("first record = %+v", chunks[0])
+	}
+	var data, hex int
+	for i, c := range chunks {
+		if i > 0 && c.Time.Before(chunks[i-1].Time) {
+			t.Errorf("record %d goes back in time", i)
+		}
+		if c.Annotation == "" {
+			data++
+			if len(c.Data) == 0 {
+				t.Errorf("record %d has no data", i)
+			}
+			if c.Data[0] == 0xAA {
+				hex++
+			}
+		}
+	}
+	if data == 0 || hex != 1 {
+		t.Errorf("%d data records, %d binary", data, hex)
+	}
+}
@@ -0,0 +1,131 @@
+// Package openguard 为可能长时间阻塞的打开操作 (串口驱动、DNS 解析、调试探针连接) 提供超时与取消
+//
+// 打开操作在单独的 goroutine 中执行，超时或取消时立即返回 OPEN_TIMEOUT / OPEN_CANCELED 错误；
+// 之后才完成的打开结果交给 discard 释放，不会泄漏句柄。
+package openguard
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"serial-assistant/pkg/apperr"
+)
+
+// DefaultTimeout 打开操作的默认超时
+const DefaultTimeout = 5 * time.Second
+
+// errCanceled Cancel 使用的取消原因
+var errCanceled = errors.New("canceled by user")
+
+// Guard 记录正在进行的打开操作以便取消，线程安全；零值可用
+type Guard struct {
+	mu     sync.Mutex
+	cancel context.CancelCauseFunc
+	seq    uint64 // 每次 Begin 递增，过期操作的 end 不清除之后的操作
+}
+
+// Begin 开始一次打开操作，返回的 ctx 在 Cancel 时结束；操作结束后必须调用 end
+// 同一时间只跟踪一个操作：之后的 Begin 取代之前的操作 (例如释放连接锁后仍在进行的诊断探测)，
+// 之前操作的 end 不影响之后的操作
+func (g *Guard) Begin() (ctx context.Context, end func()) {
+	ctx, cancel := context.WithCancelCause(context.Background())
+	g.mu.Lock()
+	g.seq++
+	seq := g.seq
+	g.cancel = cancel
+	g.mu.Unlock()
+	return ctx, func() {
+		g.mu.Lock()
+		if g.seq == seq {
+			g.cancel = nil
+		}
+		g.mu.Unlock()
+		cancel(nil)
+	}
+}
+
+// Cancel 取消正在进行的打开操作，没有时返回 false
+func (g *Guard) Cancel() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.cancel == nil {
+		return false
+	}
+	g.cancel(errCanceled)
+	g.cancel = nil
+	return true
+}
+
+// Pending 是否有正在进行的打开操作
+func (g *Guard) Pending() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.cancel != nil
+}
+
+// Run 在 goroutine 中执行 open 并等待其结果、超时 (timeout <= 0 时不限) 或 ctx 结束。
+// open 收到的 ctx 在超时或取消时结束，可用于中断其中的等待；提前返回时错误为
+// OPEN_TIMEOUT 或 OPEN_CANCELED (见 Aborted)，open 之后成功返回的结果交给 discard 释放
+func Run[T any](ctx context.Context, timeout time.Duration, what string, open func(ctx context.Context) (T, error), discard func(T)) (T, error) {
+	var cancel context.CancelFunc
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+	} else {
+		ctx, cancel = context.WithCancel(ctx)
+	}
+	defer cancel()
+
+	type result struct {
+		v   T
+		err error
+	}
+	done := make(chan result, 1)
+	go func() {
+		v, err := open(ctx)
+		done <- result{v, err}
+	}()
+
+	select {
+	case r := <-done:
+		return r.v, r.err
+	case <-ctx.Done():
+	}
+	// 结果与超时同时就绪时以结果为准
+	select {
+	case r := <-done:
+		return r.v, r.err
+	default:
+	}
+	go func() {
+		if r := <-done; r.err == nil && discard != nil {
+			discard(r.v)
+		}
+	}()
+
+	var zero T
+	if errors.Is(context.Cause(ctx), context.DeadlineExceeded) {
+		return zero, apperr.New(apperr.OpenTimeout, "%s did not complete within %s", what, timeout)
+	}
+	return zero, apperr.New(apperr.OpenCanceled, "%s was canceled", what)
+}
+
+// Aborted 判断错误是否表示打开操作超时或被取消
+func Aborted(err error) bool {
+	var coded *apperr.Error
+	return errors.As(err, &coded) && (coded.Code == apperr.OpenTimeout || coded.Code == apperr.OpenCanceled)
+}
+
+// Sleep 等待 d 或 ctx 结束，ctx 先结束时返回其错误
+func Sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
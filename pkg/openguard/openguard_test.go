@@ -0,0 +1,100 @@
+package openguard
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"serial-assistant/pkg/apperr"
+)
+
+// handle 模拟打开得到的句柄
+type handle struct{ closed chan struct{} }
+
+func code(err error) apperr.Code {
+	var coded *apperr.Error
+	if errors.As(err, &coded) {
+		return coded.Code
+	}
+	return ""
+}
+
+func TestRunTimeoutClosesStaleHandle(t *testing.T) {
+	release := make(chan struct{})
+	h := &handle{closed: make(chan struct{})}
+	start := time.Now()
+	_, err := Run(context.Background(), 20*time.Millisecond, "open COM9", func(context.Context) (*handle, error) {
+		<-release // 驱动迟迟不返回
+		return h, nil
+	}, func(h *handle) { close(h.closed) })
+
+	if code(err) != apperr.OpenTimeout || !Aborted(err) || !strings.Contains(err.Error(), "open COM9") {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if time.Since(start) > time.Second {
+		t.Error("Run did not return at the timeout")
+	}
+
+	// 打开最终完成后句柄被关闭
+	close(release)
+	select {
+	case <-h.closed:
+	case <-time.After(time.Second):
+		t.Fatal("Stale handle was not closed")
+	}
+}
+
+func TestCancel(t *testing.T) {
+	var g Guard
+	if g.Cancel() {
+		t.Error("Cancel() = true with nothing pending")
+	}
+	ctx, end := g.Begin()
+	defer end()
+	if !g.Pending() {
+		t.Fatal("Pending() = false after Begin")
+	}
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		g.Cancel()
+	}()
+	_, err := Run(ctx, time.Minute, "connect to STM32F103C8", func(ctx context.Context) (int, error) {
+		// 可取消的等待随 ctx 一起结束
+		return 0, Sleep(ctx, time.Minute)
+	}, nil)
+	if code(err) != apperr.OpenCanceled {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if g.Pending() {
+		t.Error("Pending() = true after Cancel")
+	}
+}
+
+func TestStaleEndKeepsNewerOperation(t *testing.T) {
+	var g Guard
+	_, endOld := g.Begin()
+	ctx, endNew := g.Begin()
+	defer endNew()
+
+	endOld()
+	if !g.Pending() {
+		t.Fatal("Pending() = false after the older operation ended")
+	}
+	if !g.Cancel() || ctx.Err() == nil {
+		t.Error("Cancel() did not cancel the newer operation")
+	}
+}
+
+func TestRunResult(t *testing.T) {
+	v, err := Run(context.Background(), 0, "open", func(context.Context) (int, error) { return 42, nil }, nil)
+	if v != 42 || err != nil {
+		t.Errorf("Run() = %d, %v", v, err)
+	}
+	boom := errors.New("access denied")
+	if _, err := Run(context.Background(), time.Second, "open", func(context.Context) (int, error) { return 0, boom }, nil); err != boom || Aborted(err) {
+		t.Errorf("Run() error = %v, want the open error", err)
+	}
+}
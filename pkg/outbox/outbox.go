@@ -0,0 +1,152 @@
+// Package outbox 在自动重连期间暂存待发送的数据，连接恢复后按顺序发出 (store-and-forward)
+//
+// 队列按条数与字节数限定容量；已满时按 Overflow 丢弃最旧的数据或新到的数据，并累计丢弃数。
+// Queue 非线程安全，由调用方加锁。
+package outbox
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Overflow 队列已满时的处理方式
+type Overflow string
+
+const (
+	// DropOldest 丢弃最早入队的数据，为新数据腾出空间 (默认)
+	DropOldest Overflow = "drop-oldest"
+	// DropNewest 丢弃新到的数据，保留已入队的数据
+	DropNewest Overflow = "drop-newest"
+)
+
+// ParseOverflow 解析溢出策略，空字符串视为 DropOldest
+func ParseOverflow(name string) (Overflow, error) {
+	switch Overflow(name) {
+	case "", DropOldest:
+		return DropOldest, nil
+	case DropNewest:
+		return DropNewest, nil
+	default:
+		return "", fmt.Errorf("unknown overflow policy %q (expected drop-oldest or drop-newest)", name)
+	}
+}
+
+const (
+	// DefaultMaxCount MaxCount 为 0 时的条数上限
+	DefaultMaxCount = 100
+	// DefaultMaxBytes MaxBytes 为 0 时的字节数上限
+	DefaultMaxBytes = 64 << 10
+)
+
+// Config 队列设置
+type Config struct {
+	MaxCount int      `json:"maxCount"` // 条数上限，0 表示 DefaultMaxCount
+	MaxBytes int      `json:"maxBytes"` // 字节数上限，0 表示 DefaultMaxBytes
+	Overflow Overflow `json:"overflow"`
+}
+
+// Validate 检查设置
+func (c Config) Validate() error {
+	if c.MaxCount < 0 || c.MaxBytes < 0 {
+		return errors.New("queue limits must not be negative")
+	}
+	_, err := ParseOverflow(string(c.Overflow))
+	return err
+}
+
+// Stats 队列状态
+type Stats struct {
+	Queued  int `json:"queued"`  // 当前排队的条数
+	Bytes   int `json:"bytes"`   // 当前排队的字节数
+	Dropped int `json:"dropped"` // 因队列已满累计丢弃的条数
+}
+
+// ErrTooLarge 单条数据超过队列的字节数上限，无论策略如何都无法入队
+var ErrTooLarge = errors.New("payload is larger than the send queue")
+
+// ErrFull 队列已满且策略为 DropNewest，新数据被丢弃
+var ErrFull = errors.New("send queue is full, payload dropped")
+
+// Queue 待发送数据的队列
+type Queue struct {
+	cfg     Config
+	items   [][]byte
+	bytes   int
+	dropped int
+}
+
+// New 创建队列，cfg 应已通过 Validate
+func New(cfg Config) *Queue {
+	if cfg.MaxCount == 0 {
+		cfg.MaxCount = DefaultMaxCount
+	}
+	if cfg.MaxBytes == 0 {
+		cfg.MaxBytes = DefaultMaxBytes
+	}
+	if cfg.Overflow == "" {
+		cfg.Overflow = DropOldest
+	}
+	return &Queue{cfg: cfg}
+}
+
+// Config 返回生效的设置 (已填入默认值)
+func (q *Queue) Config() Config {
+	return q.cfg
+}
+
+// Push 复制 p 并入队。队列已满时按策略丢弃：DropOldest 移除最早的数据直到放得下，
+// DropNewest 丢弃 p 并返回 ErrFull；evicted 为因此被移除的已入队条数
+func (q *Queue) Push(p []byte) (evicted int, err error) {
+	if len(p) > q.cfg.MaxBytes {
+		q.dropped++
+		return 0, fmt.Errorf("%w (%d > %d bytes)", ErrTooLarge, len(p), q.cfg.MaxBytes)
+	}
+	for len(q.items) >= q.cfg.MaxCount || q.bytes+len(p) > q.cfg.MaxBytes {
+		if q.cfg.Overflow == DropNewest {
+			q.dropped++
+			return evicted, ErrFull
+		}
+		q.bytes -= len(q.items[0])
+		q.items[0] = nil
+		q.items = q.items[1:]
+		q.dropped++
+		evicted++
+	}
+	q.items = append(q.items, append([]byte(nil), p...))
+	q.bytes += len(p)
+	return evicted, nil
+}
+
+// Flush 按入队顺序调用 send 发出数据，send 返回错误时停止，未发出的数据 (包括失败的一条) 留在队首
+// 返回成功发出的条数
+func (q *Queue) Flush(send func([]byte) error) (sent int, err error) {
+	for len(q.items) > 0 {
+		if err := send(q.items[0]); err != nil {
+			return sent, err
+		}
+		q.bytes -= len(q.items[0])
+		q.items[0] = nil
+		q.items = q.items[1:]
+		sent++
+	}
+	q.items = nil
+	return sent, nil
+}
+
+// Discard 清空队列，返回清空前的状态
+func (q *Queue) Discard() Stats {
+	s := q.Stats()
+	q.items = nil
+	q.bytes = 0
+	return s
+}
+
+// Len 返回排队的条数
+func (q *Queue) Len() int {
+	return len(q.items)
+}
+
+// Stats 返回队列状态
+func (q *Queue) Stats() Stats {
+	return Stats{Queued: len(q.items), Bytes: q.bytes, Dropped: q.dropped}
+}
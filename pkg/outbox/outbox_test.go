@@ -0,0 +1,139 @@
+package outbox
+
+import (
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"testing"
+)
+
+// flappingLink 以 net.Pipe 模拟反复断开重连的链路：每次 up 建立新的管道，
+// 对端读取到的数据汇总到 received；limit 条写入后链路断开
+type flappingLink struct {
+	conn     net.Conn
+	done     chan struct{}
+	received []string
+}
+
+func (l *flappingLink) up(t *testing.T, limit int) {
+	t.Helper()
+	local, peer := net.Pipe()
+	l.conn = local
+	l.done = make(chan struct{})
+	go func() {
+		defer close(l.done)
+		defer peer.Close()
+		buf := make([]byte, 64)
+		for i := 0; i < limit; i++ {
+			n, err := peer.Read(buf)
+			if err != nil {
+				return
+			}
+			l.received = append(l.received, string(buf[:n]))
+		}
+	}()
+}
+
+func (l *flappingLink) send(p []byte) error {
+	_, err := l.conn.Write(p)
+	return err
+}
+
+func (l *flappingLink) down() {
+	<-l.done
+	l.conn.Close()
+}
+
+func TestFlushAcrossFlappingLink(t *testing.T) {
+	q := New(Config{})
+	link := &flappingLink{}
+	for _, s := range []string{"a", "b", "c", "d", "e"} {
+		if _, err := q.Push([]byte(s)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 链路恢复后只接收两条就再次断开：失败的一条及之后的数据留在队首
+	link.up(t, 2)
+	writes := 0
+	sent, err := q.Flush(func(p []byte) error {
+		if writes == 2 {
+			link.down()
+		}
+		writes++
+		return link.send(p)
+	})
+	if sent != 2 || !errors.Is(err, io.ErrClosedPipe) {
+		t.Fatalf("Flush() = %d, %v", sent, err)
+	}
+	if s := q.Stats(); s.Queued != 3 || s.Bytes != 3 {
+		t.Errorf("after partial flush: %+v", s)
+	}
+
+	// 断开期间继续入队，再次恢复后全部按顺序发出
+	q.Push([]byte("f"))
+	link.up(t, 4)
+	if sent, err := q.Flush(link.send); sent != 4 || err != nil {
+		t.Fatalf("Flush() = %d, %v", sent, err)
+	}
+	link.down()
+	if got := strings.Join(link.received, ""); got != "abcdef" {
+		t.Errorf("received %q, want abcdef", got)
+	}
+	if q.Len() != 0 || q.Stats().Bytes != 0 {
+		t.Errorf("queue not empty: %+v", q.Stats())
+	}
+}
+
+func TestOverflowDropOldest(t *testing.T) {
+	q := New(Config{MaxCount: 3, MaxBytes: 7})
+	for _, s := range []string{"11", "22", "33"} {
+		q.Push([]byte(s))
+	}
+	// 条数已满：移除最旧的一条
+	if evicted, err := q.Push([]byte("44")); evicted != 1 || err != nil {
+		t.Fatalf("Push() = %d, %v", evicted, err)
+	}
+	// 字节数超限：移除最旧的数据直到放得下
+	if evicted, err := q.Push([]byte("5555")); evicted != 2 || err != nil {
+		t.Fatalf("Push() = %d, %v", evicted, err)
+	}
+	var got []string
+	q.Flush(func(p []byte) error { got = append(got, string(p)); return nil })
+	if strings.Join(got, ",") != "44,5555" || q.Stats().Dropped != 3 {
+		t.Errorf("got %v, stats %+v", got, q.Stats())
+	}
+}
+
+func TestOverflowDropNewest(t *testing.T) {
+	q := New(Config{MaxCount: 2, Overflow: DropNewest})
+	q.Push([]byte("first"))
+	q.Push([]byte("second"))
+	if _, err := q.Push([]byte("third")); !errors.Is(err, ErrFull) {
+		t.Fatalf("Push() error = %v, want ErrFull", err)
+	}
+	if _, err := New(Config{MaxBytes: 4}).Push([]byte("too long")); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("oversized Push() error = %v", err)
+	}
+
+	s := q.Discard()
+	if s.Queued != 2 || s.Bytes != 11 || s.Dropped != 1 {
+		t.Errorf("Discard() = %+v", s)
+	}
+	if q.Len() != 0 || q.Stats().Dropped != 1 {
+		t.Errorf("after Discard: %+v", q.Stats())
+	}
+}
+
+func TestParseOverflow(t *testing.T) {
+	if p, err := ParseOverflow(""); p != DropOldest || err != nil {
+		t.Errorf("ParseOverflow(\"\") = %q, %v", p, err)
+	}
+	if err := (Config{Overflow: "drop-random"}).Validate(); err == nil {
+		t.Error("Expected error for unknown policy")
+	}
+	if err := (Config{MaxCount: -1}).Validate(); err == nil {
+		t.Error("Expected error for negative limit")
+	}
+}
@@ -0,0 +1,357 @@
+// Package pcap 将 TCP/UDP 连接的收发数据写成 pcapng 文件，供 Wireshark 分析
+//
+// 数据来自应用层的 Read/Write，而不是真实的网络包，因此以太网/IP/TCP/UDP 头部
+// 都是根据已知的两端地址合成的：每次 Read 或 Write 对应一条记录 (TCP 不保留真实的
+// 报文边界)，但每个方向的 TCP 序号是连续的，Wireshark 可以正常重组数据流。
+package pcap
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+// pcapng 块类型
+const (
+	blockSHB = 0x0A0D0D0A
+	blockIDB = 0x00000001
+	blockEPB = 0x00000006
+
+	linkTypeEthernet = 1
+	byteOrderMagic   = 0x1A2B3C4D
+
+	optEndOfOpt = 0
+	optComment  = 1 // opt_comment
+	optIfName   = 2 // if_name
+	optTsResol  = 9 // if_tsresol
+	optEPBFlags = 2 // epb_flags
+)
+
+// 合成头部的长度
+const (
+	ethHeaderLen  = 14
+	ipv4HeaderLen = 20
+	ipv6HeaderLen = 40
+	tcpHeaderLen  = 20
+	udpHeaderLen  = 8
+)
+
+// maxSegment 单条记录的最大负载，超过时拆分为多条记录 (保证 IP 总长度不溢出)
+const maxSegment = 65000
+
+// 合成的 MAC 地址：本地与远端各一个
+var (
+	localMAC  = []byte{0x02, 0x00, 0x00, 0x00, 0x00, 0x01}
+	remoteMAC = []byte{0x02, 0x00, 0x00, 0x00, 0x00, 0x02}
+)
+
+// Writer pcapng 写入器，线程安全
+type Writer struct {
+	mu  sync.Mutex
+	w   *bufio.Writer
+	seq map[string]uint32 // 每个方向 ("src>dst") 的下一个 TCP 序号
+}
+
+// NewWriter 写入 Section Header 与 Interface Description 块
+func NewWriter(w io.Writer) (*Writer, error) {
+	return NewNamedWriter(w, "")
+}
+
+// NewNamedWriter 同 NewWriter，ifName 非空时写入接口名 (Wireshark 的接口列表中显示，例如连接标签)
+func NewNamedWriter(w io.Writer, ifName string) (*Writer, error) {
+	return NewCommentedWriter(w, ifName, "")
+}
+
+// NewCommentedWriter 同 NewNamedWriter，comment 非空时作为文件注释写入 Section Header
+// (Wireshark 的 "捕获文件属性" 中显示)
+func NewCommentedWriter(w io.Writer, ifName string, comment string) (*Writer, error) {
+	pw := &Writer{w: bufio.NewWriter(w), seq: make(map[string]uint32)}
+
+	shb := make([]byte, 16, 16+len(comment)+12)
+	binary.LittleEndian.PutUint32(shb[0:], byteOrderMagic)
+	binary.LittleEndian.PutUint16(shb[4:], 1) // major
+	binary.LittleEndian.PutUint16(shb[6:], 0) // minor
+	binary.LittleEndian.PutUint64(shb[8:], 0xFFFFFFFFFFFFFFFF)
+	if comment != "" {
+		shb = appendOption(shb, optComment, []byte(comment))
+		shb = appendOption(shb, optEndOfOpt, nil)
+	}
+	if err := pw.writeBlock(blockSHB, shb); err != nil {
+		return nil, err
+	}
+
+	// 时间戳精度为纳秒 (10^-9)
+	idb := make([]byte, 8, 20+len(ifName)+4)
+	binary.LittleEndian.PutUint16(idb[0:], linkTypeEthernet)
+	if ifName != "" {
+		idb = appendOption(idb, optIfName, []byte(ifName))
+	}
+	idb = appendOption(idb, optTsResol, []byte{9})
+	idb = appendOption(idb, optEndOfOpt, nil)
+	if err := pw.writeBlock(blockIDB, idb); err != nil {
+		return nil, err
+	}
+	return pw, pw.w.Flush()
+}
+
+// WritePacket 写入一次 Read (outbound=false) 或 Write (outbound=true) 的数据
+// local/remote 必须同为 *net.TCPAddr 或 *net.UDPAddr
+func (pw *Writer) WritePacket(t time.Time, local, remote net.Addr, outbound bool, payload []byte) error {
+	pw.mu.Lock()
+	defer pw.mu.Unlock()
+
+	for len(payload) > 0 {
+		n := len(payload)
+		if n > maxSegment {
+			n = maxSegment
+		}
+		frame, err := pw.buildFrame(local, remote, outbound, payload[:n])
+		if err != nil {
+			return err
+		}
+		if err := pw.writeEPB(t, frame, outbound); err != nil {
+			return err
+		}
+		payload = payload[n:]
+	}
+	return nil
+}
+
+// Flush 将缓冲数据写入底层 Writer
+func (pw *Writer) Flush() error {
+	pw.mu.Lock()
+	defer pw.mu.Unlock()
+
+	return pw.w.Flush()
+}
+
+func (pw *Writer) buildFrame(local, remote net.Addr, outbound bool, payload []byte) ([]byte, error) {
+	var (
+		proto             byte
+		localIP, remoteIP net.IP
+		localPt, remotePt int
+	)
+	switch l := local.(type) {
+	case *net.TCPAddr:
+		r, ok := remote.(*net.TCPAddr)
+		if !ok {
+			return nil, fmt.Errorf("mismatched address types %T and %T", local, remote)
+		}
+		proto, localIP, localPt, remoteIP, remotePt = 6, l.IP, l.Port, r.IP, r.Port
+	case *net.UDPAddr:
+		r, ok := remote.(*net.UDPAddr)
+		if !ok {
+			return nil, fmt.Errorf("mismatched address types %T and %T", local, remote)
+		}
+		proto, localIP, localPt, remoteIP, remotePt = 17, l.IP, l.Port, r.IP, r.Port
+	default:
+		return nil, fmt.Errorf("unsupported address type %T", local)
+	}
+
+	srcIP, dstIP, srcPort, dstPort := remoteIP, localIP, remotePt, localPt
+	srcMAC, dstMAC := remoteMAC, localMAC
+	if outbound {
+		srcIP, dstIP, srcPort, dstPort = localIP, remoteIP, localPt, remotePt
+		srcMAC, dstMAC = localMAC, remoteMAC
+	}
+	srcIP, dstIP, v4 := normalizeIPs(srcIP, dstIP)
+
+	var l4 []byte
+	if proto == 6 {
+		fwd := fmt.Sprintf("%s:%d>%s:%d", srcIP, srcPort, dstIP, dstPort)
+		rev := fmt.Sprintf("%s:%d>%s:%d", dstIP, dstPort, srcIP, srcPort)
+		seq := pw.initSeq(fwd)
+		ack := pw.initSeq(rev)
+		pw.seq[fwd] = seq + uint32(len(payload))
+		l4 = tcpSegment(srcPort, dstPort, seq, ack, payload)
+	} else {
+		l4 = udpDatagram(srcPort, dstPort, payload)
+	}
+
+	var ip []byte
+	etherType := uint16(0x0800)
+	if v4 {
+		ip = ipv4Header(srcIP, dstIP, proto, len(l4))
+	} else {
+		ip = ipv6Header(srcIP, dstIP, proto, len(l4))
+		etherType = 0x86DD
+	}
+	setL4Checksum(l4, proto, srcIP, dstIP)
+
+	frame := make([]byte, 0, ethHeaderLen+len(ip)+len(l4))
+	frame = append(frame, dstMAC...)
+	frame = append(frame, srcMAC...)
+	frame = binary.BigEndian.AppendUint16(frame, etherType)
+	frame = append(frame, ip...)
+	frame = append(frame, l4...)
+	return frame, nil
+}
+
+// initSeq 返回某个方向的当前序号，首次出现时使用固定的初始序号
+func (pw *Writer) initSeq(flow string) uint32 {
+	seq, ok := pw.seq[flow]
+	if !ok {
+		seq = 1
+		pw.seq[flow] = seq
+	}
+	return seq
+}
+
+// normalizeIPs 统一两端的地址族；未指定的地址 (例如监听在 0.0.0.0) 跟随另一端
+func normalizeIPs(src, dst net.IP) (net.IP, net.IP, bool) {
+	if src == nil || src.IsUnspecified() {
+		src = unspecifiedLike(dst)
+	}
+	if dst == nil || dst.IsUnspecified() {
+		dst = unspecifiedLike(src)
+	}
+	if s4, d4 := src.To4(), dst.To4(); s4 != nil && d4 != nil {
+		return s4, d4, true
+	}
+	return src.To16(), dst.To16(), false
+}
+
+func unspecifiedLike(ip net.IP) net.IP {
+	if ip != nil && ip.To4() == nil {
+		return net.IPv6unspecified
+	}
+	return net.IPv4zero.To4()
+}
+
+func ipv4Header(src, dst net.IP, proto byte, payloadLen int) []byte {
+	h := make([]byte, ipv4HeaderLen)
+	h[0] = 0x45 // version 4, IHL 5
+	binary.BigEndian.PutUint16(h[2:], uint16(ipv4HeaderLen+payloadLen))
+	h[6] = 0x40 // don't fragment
+	h[8] = 64   // TTL
+	h[9] = proto
+	copy(h[12:], src)
+	copy(h[16:], dst)
+	binary.BigEndian.PutUint16(h[10:], checksum(h, 0))
+	return h
+}
+
+func ipv6Header(src, dst net.IP, proto byte, payloadLen int) []byte {
+	h := make([]byte, ipv6HeaderLen)
+	h[0] = 0x60
+	binary.BigEndian.PutUint16(h[4:], uint16(payloadLen))
+	h[6] = proto
+	h[7] = 64 // hop limit
+	copy(h[8:], src)
+	copy(h[24:], dst)
+	return h
+}
+
+func tcpSegment(srcPort, dstPort int, seq, ack uint32, payload []byte) []byte {
+	h := make([]byte, tcpHeaderLen, tcpHeaderLen+len(payload))
+	binary.BigEndian.PutUint16(h[0:], uint16(srcPort))
+	binary.BigEndian.PutUint16(h[2:], uint16(dstPort))
+	binary.BigEndian.PutUint32(h[4:], seq)
+	binary.BigEndian.PutUint32(h[8:], ack)
+	h[12] = 5 << 4 // data offset
+	h[13] = 0x18   // PSH, ACK
+	binary.BigEndian.PutUint16(h[14:], 0xFFFF)
+	return append(h, payload...)
+}
+
+func udpDatagram(srcPort, dstPort int, payload []byte) []byte {
+	h := make([]byte, udpHeaderLen, udpHeaderLen+len(payload))
+	binary.BigEndian.PutUint16(h[0:], uint16(srcPort))
+	binary.BigEndian.PutUint16(h[2:], uint16(dstPort))
+	binary.BigEndian.PutUint16(h[4:], uint16(udpHeaderLen+len(payload)))
+	return append(h, payload...)
+}
+
+// setL4Checksum 计算 TCP/UDP 校验和 (包含伪首部)
+func setL4Checksum(l4 []byte, proto byte, src, dst net.IP) {
+	var pseudo []byte
+	pseudo = append(pseudo, src...)
+	pseudo = append(pseudo, dst...)
+	if len(src) == net.IPv4len {
+		pseudo = append(pseudo, 0, proto)
+		pseudo = binary.BigEndian.AppendUint16(pseudo, uint16(len(l4)))
+	} else {
+		pseudo = binary.BigEndian.AppendUint32(pseudo, uint32(len(l4)))
+		pseudo = append(pseudo, 0, 0, 0, proto)
+	}
+
+	offset := 16 // TCP
+	if proto == 17 {
+		offset = 6
+	}
+	sum := checksum(l4, partialSum(pseudo))
+	if proto == 17 && sum == 0 {
+		sum = 0xFFFF
+	}
+	binary.BigEndian.PutUint16(l4[offset:], sum)
+}
+
+func partialSum(b []byte) uint32 {
+	var sum uint32
+	for i := 0; i+1 < len(b); i += 2 {
+		sum += uint32(b[i])<<8 | uint32(b[i+1])
+	}
+	if len(b)%2 == 1 {
+		sum += uint32(b[len(b)-1]) << 8
+	}
+	return sum
+}
+
+// checksum 互联网校验和 (RFC 1071)
+func checksum(b []byte, initial uint32) uint16 {
+	sum := initial + partialSum(b)
+	for sum>>16 != 0 {
+		sum = sum&0xFFFF + sum>>16
+	}
+	return ^uint16(sum)
+}
+
+func (pw *Writer) writeEPB(t time.Time, frame []byte, outbound bool) error {
+	ts := uint64(t.UnixNano())
+	body := make([]byte, 20, 20+len(frame)+16)
+	binary.LittleEndian.PutUint32(body[0:], 0) // interface id
+	binary.LittleEndian.PutUint32(body[4:], uint32(ts>>32))
+	binary.LittleEndian.PutUint32(body[8:], uint32(ts))
+	binary.LittleEndian.PutUint32(body[12:], uint32(len(frame)))
+	binary.LittleEndian.PutUint32(body[16:], uint32(len(frame)))
+	body = append(body, frame...)
+	body = append(body, make([]byte, pad4(len(frame)))...)
+
+	// epb_flags: 方向位 1 = 入站, 2 = 出站
+	flags := make([]byte, 4)
+	if outbound {
+		binary.LittleEndian.PutUint32(flags, 2)
+	} else {
+		binary.LittleEndian.PutUint32(flags, 1)
+	}
+	body = appendOption(body, optEPBFlags, flags)
+	body = appendOption(body, optEndOfOpt, nil)
+	return pw.writeBlock(blockEPB, body)
+}
+
+func appendOption(b []byte, code uint16, value []byte) []byte {
+	b = binary.LittleEndian.AppendUint16(b, code)
+	b = binary.LittleEndian.AppendUint16(b, uint16(len(value)))
+	b = append(b, value...)
+	return append(b, make([]byte, pad4(len(value)))...)
+}
+
+func pad4(n int) int {
+	return (4 - n%4) % 4
+}
+
+// writeBlock 写入一个完整的块：类型、总长度、内容、总长度
+func (pw *Writer) writeBlock(blockType uint32, body []byte) error {
+	total := uint32(12 + len(body))
+	buf := make([]byte, 0, total)
+	buf = binary.LittleEndian.AppendUint32(buf, blockType)
+	buf = binary.LittleEndian.AppendUint32(buf, total)
+	buf = append(buf, body...)
+	buf = binary.LittleEndian.AppendUint32(buf, total)
+	_, err := pw.w.Write(buf)
+	return err
+}
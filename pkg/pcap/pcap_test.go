@@ -0,0 +1,227 @@
+package pcap
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"testing"
+	"time"
+)
+
+type block struct {
+	typ  uint32
+	body []byte
+}
+
+func readBlocks(t *testing.T, data []byte) []block {
+	t.Helper()
+	var blocks []block
+	for len(data) > 0 {
+		if len(data) < 12 {
+			t.Fatalf("Truncated block header")
+		}
+		typ := binary.LittleEndian.Uint32(data[0:])
+		total := binary.LittleEndian.Uint32(data[4:])
+		if total%4 != 0 || int(total) > len(data) {
+			t.Fatalf("Invalid block length %d", total)
+		}
+		if trailer := binary.LittleEndian.Uint32(data[total-4:]); trailer != total {
+			t.Fatalf("Block length trailer %d != %d", trailer, total)
+		}
+		blocks = append(blocks, block{typ, data[8 : total-4]})
+		data = data[total:]
+	}
+	return blocks
+}
+
+// packetOf 从 EPB 中取出以太网帧与方向标志
+func packetOf(t *testing.T, b block) ([]byte, uint32) {
+	t.Helper()
+	if b.typ != blockEPB {
+		t.Fatalf("Expected EPB, got block type %#x", b.typ)
+	}
+	capLen := binary.LittleEndian.Uint32(b.body[12:])
+	frame := b.body[20 : 20+capLen]
+	opts := b.body[20+int(capLen)+pad4(int(capLen)):]
+	if binary.LittleEndian.Uint16(opts[0:]) != optEPBFlags {
+		t.Fatalf("Expected epb_flags option")
+	}
+	return frame, binary.LittleEndian.Uint32(opts[4:])
+}
+
+func TestTCPCapture(t *testing.T) {
+	var out bytes.Buffer
+	pw, err := NewWriter(&out)
+	if err != nil {
+		t.Fatalf("NewWriter failed: %v", err)
+	}
+
+	local := &net.TCPAddr{IP: net.ParseIP("192.168.1.10"), Port: 50000}
+	remote := &net.TCPAddr{IP: net.ParseIP("192.168.1.20"), Port: 502}
+	now := time.Now()
+
+	pw.WritePacket(now, local, remote, true, []byte("hello"))
+	pw.WritePacket(now, local, remote, false, []byte("world!"))
+	pw.WritePacket(now, local, remote, true, []byte("again"))
+	if err := pw.Flush(); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+
+	blocks := readBlocks(t, out.Bytes())
+	if len(blocks) != 5 {
+		t.Fatalf("Expected SHB + IDB + 3 EPB, got %d blocks", len(blocks))
+	}
+	if blocks[0].typ != blockSHB || binary.LittleEndian.Uint32(blocks[0].body) != byteOrderMagic {
+		t.Fatalf("Invalid section header block")
+	}
+	if blocks[1].typ != blockIDB || binary.LittleEndian.Uint16(blocks[1].body) != linkTypeEthernet {
+		t.Fatalf("Invalid interface description block")
+	}
+
+	type seg struct {
+		seq, ack uint32
+		srcPort  uint16
+		flags    uint32
+	}
+	var segs []seg
+	for _, b := range blocks[2:] {
+		frame, flags := packetOf(t, b)
+		if binary.BigEndian.Uint16(frame[12:]) != 0x0800 {
+			t.Fatalf("Expected IPv4 ethertype")
+		}
+		ip := frame[ethHeaderLen : ethHeaderLen+ipv4HeaderLen]
+		if checksum(ip, 0) != 0 {
+			t.Errorf("Invalid IPv4 header checksum")
+		}
+		tcp := frame[ethHeaderLen+ipv4HeaderLen:]
+		pseudo := append(append([]byte{}, ip[12:20]...), 0, 6, 0, 0)
+		binary.BigEndian.PutUint16(pseudo[10:], uint16(len(tcp)))
+		if checksum(tcp, partialSum(pseudo)) != 0 {
+			t.Errorf("Invalid TCP checksum")
+		}
+		segs = append(segs, seg{
+			seq:     binary.BigEndian.Uint32(tcp[4:]),
+			ack:     binary.BigEndian.Uint32(tcp[8:]),
+			srcPort: binary.BigEndian.Uint16(tcp[0:]),
+			flags:   flags,
+		})
+	}
+
+	expected := []seg{
+		{seq: 1, ack: 1, srcPort: 50000, flags: 2},
+		{seq: 1, ack: 6, srcPort: 502, flags: 1},
+		{seq: 6, ack: 7, srcPort: 50000, flags: 2},
+	}
+	for i := range expected {
+		if segs[i] != expected[i] {
+			t.Errorf("Segment %d = %+v, expected %+v", i, segs[i], expected[i])
+		}
+	}
+}
+
+func TestUDPCaptureWithUnspecifiedLocal(t *testing.T) {
+	var out bytes.Buffer
+	pw, _ := NewWriter(&out)
+
+	local := &net.UDPAddr{IP: net.IPv6unspecified, Port: 8080}
+	remote := &net.UDPAddr{IP: net.ParseIP("10.0.0.5"), Port: 9000}
+	if err := pw.WritePacket(time.Now(), local, remote, false, []byte{1, 2, 3}); err != nil {
+		t.Fatalf("WritePacket failed: %v", err)
+	}
+	pw.Flush()
+
+	blocks := readBlocks(t, out.Bytes())
+	frame, flags := packetOf(t, blocks[2])
+	if flags != 1 {
+		t.Errorf("Expected inbound flag, got %d", flags)
+	}
+	if binary.BigEndian.Uint16(frame[12:]) != 0x0800 {
+		t.Errorf("Unspecified local address should follow the IPv4 remote")
+	}
+	udp := frame[ethHeaderLen+ipv4HeaderLen:]
+	if binary.BigEndian.Uint16(udp[0:]) != 9000 || binary.BigEndian.Uint16(udp[2:]) != 8080 {
+		t.Errorf("Unexpected UDP ports in %x", udp[:4])
+	}
+	if !bytes.Equal(udp[udpHeaderLen:], []byte{1, 2, 3}) {
+		t.Errorf("Unexpected UDP payload %x", udp[udpHeaderLen:])
+	}
+}
+
+func TestLargeWriteIsSplit(t *testing.T) {
+	var out bytes.Buffer
+	pw, _ := NewWriter(&out)
+
+	local := &net.TCPAddr{IP: net.ParseIP("::1"), Port: 1}
+	remote := &net.TCPAddr{IP: net.ParseIP("::1"), Port: 2}
+	pw.WritePacket(time.Now(), local, remote, true, make([]byte, maxSegment+10))
+	pw.Flush()
+
+	blocks := readBlocks(t, out.Bytes())
+	if len(blocks) != 4 {
+		t.Fatalf("Expected payload split into 2 records, got %d blocks", len(blocks))
+	}
+	frame, _ := packetOf(t, blocks[3])
+	if binary.BigEndian.Uint16(frame[12:]) != 0x86DD {
+		t.Errorf("Expected IPv6 ethertype")
+	}
+	tcp := frame[ethHeaderLen+ipv6HeaderLen:]
+	if seq := binary.BigEndian.Uint32(tcp[4:]); seq != 1+maxSegment {
+		t.Errorf("Second segment seq = %d, expected %d", seq, 1+maxSegment)
+	}
+}
+
+func TestMismatchedAddresses(t *testing.T) {
+	pw, _ := NewWriter(&bytes.Buffer{})
+	err := pw.WritePacket(time.Now(), &net.TCPAddr{}, &net.UDPAddr{}, true, []byte{1})
+	if err == nil {
+		t.Error("Expected error for mismatched address types")
+	}
+}
+
+func TestNamedWriterInterfaceName(t *testing.T) {
+	var out bytes.Buffer
+	if _, err := NewNamedWriter(&out, "Board A"); err != nil {
+		t.Fatalf("NewNamedWriter failed: %v", err)
+	}
+
+	blocks := readBlocks(t, out.Bytes())
+	if len(blocks) != 2 || blocks[1].typ != blockIDB {
+		t.Fatalf("Expected SHB and IDB, got %d blocks", len(blocks))
+	}
+	opts := blocks[1].body[8:]
+	if code := binary.LittleEndian.Uint16(opts[0:]); code != optIfName {
+		t.Fatalf("Expected if_name option first, got code %d", code)
+	}
+	n := binary.LittleEndian.Uint16(opts[2:])
+	if name := string(opts[4 : 4+n]); name != "Board A" {
+		t.Errorf("Unexpected interface name %q", name)
+	}
+}
+
+func TestCommentedWriterSectionComment(t *testing.T) {
+	var out bytes.Buffer
+	comment := "Serial Mate v1.2.3\nConnection: tcp://10.0.0.2:4001"
+	if _, err := NewCommentedWriter(&out, "", comment); err != nil {
+		t.Fatalf("NewCommentedWriter failed: %v", err)
+	}
+
+	blocks := readBlocks(t, out.Bytes())
+	if len(blocks) != 2 || blocks[0].typ != blockSHB {
+		t.Fatalf("Expected SHB and IDB, got %d blocks", len(blocks))
+	}
+	opts := blocks[0].body[16:]
+	if code := binary.LittleEndian.Uint16(opts[0:]); code != optComment {
+		t.Fatalf("Expected opt_comment in SHB, got code %d", code)
+	}
+	n := binary.LittleEndian.Uint16(opts[2:])
+	if got := string(opts[4 : 4+n]); got != comment {
+		t.Errorf("Unexpected comment %q", got)
+	}
+
+	// 无注释时 SHB 不带选项
+	out.Reset()
+	NewNamedWriter(&out, "")
+	if blocks := readBlocks(t, out.Bytes()); len(blocks[0].body) != 16 {
+		t.Errorf("Expected SHB without options, got %d bytes", len(blocks[0].body))
+	}
+}
@@ -0,0 +1,207 @@
+// Package pipecfg 定义收发处理设置的整体快照 (格式化、编码、分类、绘图、看门狗、发送节奏与限制等)，
+// 用于一次性读取与应用；应用前集中校验，任何字段无效时整体不生效
+package pipecfg
+
+import (
+	"fmt"
+	"strings"
+
+	"serial-assistant/pkg/classify"
+	"serial-assistant/pkg/format"
+	"serial-assistant/pkg/input"
+	"serial-assistant/pkg/plot"
+	"serial-assistant/pkg/serialport"
+	"serial-assistant/pkg/textenc"
+	"serial-assistant/pkg/transport"
+)
+
+// 取值范围
+const (
+	MaxWatchdogSec   = 3600
+	MaxPasteDelayMs  = 60000
+	MaxPromptTimeout = 60000
+)
+
+// Config 收发处理设置的快照
+type Config struct {
+	// 接收处理
+	Formatting      bool               `json:"formatting"`
+	Format          format.Options     `json:"format"`
+	HexDump         format.DumpOptions `json:"hexDump"` // BytesPerRow 为 0 表示单行分组格式
+	RxEncoding      textenc.Mode       `json:"rxEncoding"`
+	ClassifierRules []classify.Rule    `json:"classifierRules"`
+	PlotParser      string             `json:"plotParser"` // 空字符串表示关闭
+	EchoSuppression bool               `json:"echoSuppression"`
+	Zmodem          Zmodem             `json:"zmodem"`
+	Watchdog        Watchdog           `json:"watchdog"`
+
+	// 发送节奏与限制
+	TxRate             int                      `json:"txRate"` // 字节/秒，0 表示不限速
+	WriteTimeoutMs     int                      `json:"writeTimeoutMs"`
+	UDPLimit           int                      `json:"udpLimit"`
+	UDPPolicy          transport.DatagramPolicy `json:"udpPolicy"`
+	LargeSendThreshold int                      `json:"largeSendThreshold"`
+	Payload            input.Limits             `json:"payload"`
+	Paste              Paste                    `json:"paste"`
+	LinkCheck          serialport.LinkCheck     `json:"linkCheck"`
+}
+
+// Zmodem ZMODEM 自动接收设置
+type Zmodem struct {
+	Auto bool   `json:"auto"`
+	Dir  string `json:"dir"`
+}
+
+// Watchdog 接收静默看门狗设置
+type Watchdog struct {
+	Enabled    bool   `json:"enabled"`
+	TimeoutSec int    `json:"timeoutSec"`
+	Action     string `json:"action"` // "event"、"send" 或 "reconnect"，未开启时可为空
+	Probe      []byte `json:"probe,omitempty"`
+}
+
+// Paste 粘贴模式设置
+type Paste struct {
+	Enabled         bool   `json:"enabled"`
+	Threshold       int    `json:"threshold"`
+	ChunkSize       int    `json:"chunkSize"`
+	DelayMs         int    `json:"delayMs"`
+	Prompt          string `json:"prompt,omitempty"`
+	PromptTimeoutMs int    `json:"promptTimeoutMs"`
+}
+
+// FieldError 一个无效字段
+type FieldError struct {
+	Field string `json:"field"`
+	Error string `json:"error"`
+}
+
+// Errors 校验失败的全部字段
+type Errors []FieldError
+
+func (e Errors) Error() string {
+	parts := make([]string, len(e))
+	for i, fe := range e {
+		parts[i] = fe.Field + ": " + fe.Error
+	}
+	return "invalid pipeline config: " + strings.Join(parts, "; ")
+}
+
+// Validate 校验所有字段，返回全部无效字段 (类型为 Errors)，全部有效时返回 nil
+func (c Config) Validate() error {
+	var errs Errors
+	add := func(field string, err error) {
+		if err != nil {
+			errs = append(errs, FieldError{Field: field, Error: err.Error()})
+		}
+	}
+	nonNegative := func(field string, v int) {
+		if v < 0 {
+			add(field, fmt.Errorf("must not be negative, got %d", v))
+		}
+	}
+
+	if c.HexDump.BytesPerRow != 0 {
+		add("hexDump", c.HexDump.Validate())
+	}
+	_, err := textenc.ParseMode(string(c.RxEncoding))
+	add("rxEncoding", err)
+	_, err = classify.Compile(c.ClassifierRules)
+	add("classifierRules", err)
+	if c.PlotParser != "" {
+		_, err = plot.ParseProtocol(c.PlotParser)
+		add("plotParser", err)
+	}
+
+	switch c.Watchdog.Action {
+	case "event", "send", "reconnect":
+	case "":
+		if c.Watchdog.Enabled {
+			add("watchdog.action", fmt.Errorf("required when the watchdog is enabled"))
+		}
+	default:
+		add("watchdog.action", fmt.Errorf("unknown action %q (expected event, send or reconnect)", c.Watchdog.Action))
+	}
+	if c.Watchdog.Enabled {
+		if c.Watchdog.TimeoutSec < 1 || c.Watchdog.TimeoutSec > MaxWatchdogSec {
+			add("watchdog.timeoutSec", fmt.Errorf("must be between 1 and %d, got %d", MaxWatchdogSec, c.Watchdog.TimeoutSec))
+		}
+		if c.Watchdog.Action == "send" && len(c.Watchdog.Probe) == 0 {
+			add("watchdog.probe", fmt.Errorf("required for the send action"))
+		}
+	}
+
+	nonNegative("txRate", c.TxRate)
+	nonNegative("writeTimeoutMs", c.WriteTimeoutMs)
+	if c.UDPLimit < 0 || c.UDPLimit > transport.MaxUDPPayload {
+		add("udpLimit", fmt.Errorf("must be between 0 and %d, got %d", transport.MaxUDPPayload, c.UDPLimit))
+	}
+	_, err = transport.ParseDatagramPolicy(string(c.UDPPolicy))
+	add("udpPolicy", err)
+	nonNegative("largeSendThreshold", c.LargeSendThreshold)
+	nonNegative("payload.maxBytes", c.Payload.MaxBytes)
+	_, err = input.ParseUTF8Policy(string(c.Payload.UTF8))
+	add("payload.utf8", err)
+
+	nonNegative("paste.threshold", c.Paste.Threshold)
+	nonNegative("paste.chunkSize", c.Paste.ChunkSize)
+	if c.Paste.DelayMs < 0 || c.Paste.DelayMs > MaxPasteDelayMs {
+		add("paste.delayMs", fmt.Errorf("must be between 0 and %d, got %d", MaxPasteDelayMs, c.Paste.DelayMs))
+	}
+	if c.Paste.PromptTimeoutMs < 0 || c.Paste.PromptTimeoutMs > MaxPromptTimeout {
+		add("paste.promptTimeoutMs", fmt.Errorf("must be between 0 and %d, got %d", MaxPromptTimeout, c.Paste.PromptTimeoutMs))
+	}
+	_, err = serialport.ParseLinkCheck(string(c.LinkCheck))
+	add("linkCheck", err)
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// Summary 返回单行摘要，列出影响接收内容与发送节奏的主要设置，用于日志文件头
+// 未开启的功能不列出；分类规则只给出数量
+func (c Config) Summary() string {
+	var parts []string
+	if c.Formatting {
+		parts = append(parts, fmt.Sprintf("hex=group%d", c.Format.GroupSize))
+	}
+	if c.HexDump.BytesPerRow > 0 {
+		parts = append(parts, fmt.Sprintf("hexdump=%d", c.HexDump.BytesPerRow))
+	}
+	if c.RxEncoding != "" && c.RxEncoding != textenc.Raw {
+		parts = append(parts, "encoding="+string(c.RxEncoding))
+	}
+	if len(c.ClassifierRules) > 0 {
+		parts = append(parts, fmt.Sprintf("classifier=%d rules", len(c.ClassifierRules)))
+	}
+	if c.PlotParser != "" {
+		parts = append(parts, "plot="+c.PlotParser)
+	}
+	if c.EchoSuppression {
+		parts = append(parts, "echo-suppression")
+	}
+	if c.Zmodem.Auto {
+		parts = append(parts, "zmodem-auto")
+	}
+	if c.Watchdog.Enabled {
+		parts = append(parts, fmt.Sprintf("watchdog=%ds/%s", c.Watchdog.TimeoutSec, c.Watchdog.Action))
+	}
+	if c.TxRate > 0 {
+		parts = append(parts, fmt.Sprintf("tx-rate=%dB/s", c.TxRate))
+	}
+	if c.WriteTimeoutMs > 0 {
+		parts = append(parts, fmt.Sprintf("write-timeout=%dms", c.WriteTimeoutMs))
+	}
+	if c.Paste.Enabled {
+		parts = append(parts, fmt.Sprintf("paste=%dB/%dms", c.Paste.ChunkSize, c.Paste.DelayMs))
+	}
+	if c.LinkCheck != "" && c.LinkCheck != serialport.LinkCheckOff {
+		parts = append(parts, "link-check="+string(c.LinkCheck))
+	}
+	if len(parts) == 0 {
+		return "defaults"
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,123 @@
+package pipecfg
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	"serial-assistant/pkg/classify"
+	"serial-assistant/pkg/format"
+	"serial-assistant/pkg/input"
+	"serial-assistant/pkg/serialport"
+	"serial-assistant/pkg/textenc"
+	"serial-assistant/pkg/transport"
+)
+
+// fullConfig 每个字段都设置为非零值
+func fullConfig() Config {
+	return Config{
+		Formatting:      true,
+		Format:          format.Options{GroupSize: 2, Uppercase: true},
+		HexDump:         format.DumpOptions{BytesPerRow: 16, ASCII: true, Continuous: true},
+		RxEncoding:      textenc.Auto,
+		ClassifierRules: []classify.Rule{{Pattern: "ERR", Class: "error", Priority: 2}},
+		PlotParser:      "string",
+		EchoSuppression: true,
+		Zmodem:          Zmodem{Auto: true, Dir: "/tmp/zmodem"},
+		Watchdog:        Watchdog{Enabled: true, TimeoutSec: 30, Action: "send", Probe: []byte("AT\r")},
+
+		TxRate:             9600,
+		WriteTimeoutMs:     2000,
+		UDPLimit:           1472,
+		UDPPolicy:          transport.DatagramSplit,
+		LargeSendThreshold: 4096,
+		Payload:            input.Limits{MaxBytes: 1 << 20, UTF8: input.UTF8Replace},
+		Paste:              Paste{Enabled: true, Threshold: 512, ChunkSize: 64, DelayMs: 20, Prompt: "> ", PromptTimeoutMs: 3000},
+		LinkCheck:          serialport.LinkCheckDSR,
+	}
+}
+
+func TestValidConfigRoundTrip(t *testing.T) {
+	cfg := fullConfig()
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate failed: %v", err)
+	}
+
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded Config
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decoded, cfg) {
+		t.Errorf("JSON round trip mismatch:\n got %+v\nwant %+v", decoded, cfg)
+	}
+
+	// 每个字段都要出现在 JSON 中，新增字段时防止遗漏标签
+	var fields map[string]json.RawMessage
+	json.Unmarshal(data, &fields)
+	if n := reflect.TypeOf(cfg).NumField(); len(fields) != n {
+		t.Errorf("Expected %d JSON fields, got %d", n, len(fields))
+	}
+}
+
+func TestZeroConfigIsValid(t *testing.T) {
+	if err := (Config{}).Validate(); err != nil {
+		t.Errorf("Zero config should be valid (all features off), got %v", err)
+	}
+}
+
+func TestValidateReportsEveryField(t *testing.T) {
+	cfg := fullConfig()
+	cfg.HexDump.BytesPerRow = 7
+	cfg.RxEncoding = "utf-32"
+	cfg.ClassifierRules = []classify.Rule{{Pattern: "(", Regex: true, Class: "error"}}
+	cfg.PlotParser = "csv"
+	cfg.Watchdog = Watchdog{Enabled: true, TimeoutSec: 0, Action: "send"}
+	cfg.TxRate = -1
+	cfg.UDPLimit = 70000
+	cfg.UDPPolicy = "drop"
+	cfg.Payload.UTF8 = "ignore"
+	cfg.Paste.DelayMs = 60001
+	cfg.LinkCheck = "cts"
+
+	err := cfg.Validate()
+	var errs Errors
+	if !errors.As(err, &errs) {
+		t.Fatalf("Expected Errors, got %v", err)
+	}
+	want := []string{
+		"hexDump", "rxEncoding", "classifierRules", "plotParser",
+		"watchdog.timeoutSec", "watchdog.probe", "txRate", "udpLimit", "udpPolicy",
+		"payload.utf8", "paste.delayMs", "linkCheck",
+	}
+	var got []string
+	for _, fe := range errs {
+		got = append(got, fe.Field)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Unexpected invalid fields:\n got %v\nwant %v", got, want)
+	}
+}
+
+func TestWatchdogActionRequiredWhenEnabled(t *testing.T) {
+	cfg := Config{Watchdog: Watchdog{Enabled: true, TimeoutSec: 5}}
+	var errs Errors
+	if err := cfg.Validate(); !errors.As(err, &errs) || errs[0].Field != "watchdog.action" {
+		t.Errorf("Expected watchdog.action error, got %v", err)
+	}
+}
+
+func TestSummary(t *testing.T) {
+	if got := (Config{}).Summary(); got != "defaults" {
+		t.Errorf("zero config summary = %q", got)
+	}
+	want := "hex=group2, hexdump=16, encoding=auto, classifier=1 rules, plot=string, echo-suppression, zmodem-auto, " +
+		"watchdog=30s/send, tx-rate=9600B/s, write-timeout=2000ms, paste=64B/20ms, link-check=dsr"
+	if got := fullConfig().Summary(); got != want {
+		t.Errorf("Summary = %q\nwant      %q", got, want)
+	}
+}
@@ -0,0 +1,113 @@
+package plot
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CSVFlushInterval CSV 导出的刷新间隔
+const CSVFlushInterval = time.Second
+
+// CSVWriter 将采样点写成 CSV：第一列为时间戳，其后每列对应一个通道，最后一列为标注
+//
+// 列集合在创建时固定：第 i 列对应采样点的第 i 个通道，缺失的值 (包括 NaN) 留空，多余的通道被忽略。
+// 需要不同的列时必须先结束当前导出，再以新的列创建新文件。
+// 标注 (见 WriteAnnotation) 单独占一行，通道列留空，文本写在 annotation 列。
+type CSVWriter struct {
+	buf       *bufio.Writer
+	csv       *csv.Writer
+	columns   []string
+	lastFlush time.Time
+	rows      int
+}
+
+// NewCSVWriter 创建 CSV 写入器并写入表头
+func NewCSVWriter(w io.Writer, columns []string) (*CSVWriter, error) {
+	return NewCommentedCSVWriter(w, columns, nil)
+}
+
+// NewCommentedCSVWriter 同 NewCSVWriter，在表头之前为 comments 的每一行写入一行 "# " 开头的注释
+// (例如文件头说明)，读取时可用 pandas.read_csv(comment='#') 等方式跳过
+func NewCommentedCSVWriter(w io.Writer, columns []string, comments []string) (*CSVWriter, error) {
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("at least one column is required")
+	}
+	buf := bufio.NewWriter(w)
+	cw := &CSVWriter{buf: buf, csv: csv.NewWriter(buf), columns: columns, lastFlush: time.Now()}
+
+	for _, line := range comments {
+		if _, err := buf.WriteString("# " + strings.TrimRight(line, "\r\n") + "\n"); err != nil {
+			return nil, err
+		}
+	}
+
+	header := append([]string{"timestamp"}, columns...)
+	header = append(header, "annotation")
+	if err := cw.csv.Write(header); err != nil {
+		return nil, err
+	}
+	return cw, cw.Flush()
+}
+
+// WriteSample 写入一行，距上次刷新超过 CSVFlushInterval 时自动刷新
+func (cw *CSVWriter) WriteSample(s Sample) error {
+	record := cw.newRecord(s.Time)
+	for i := range cw.columns {
+		if i < len(s.Values) && !math.IsNaN(s.Values[i]) {
+			record[i+1] = strconv.FormatFloat(s.Values[i], 'g', -1, 64)
+		}
+	}
+	return cw.writeRecord(record)
+}
+
+// WriteAnnotation 写入一行标注
+func (cw *CSVWriter) WriteAnnotation(t time.Time, text string) error {
+	record := cw.newRecord(t)
+	record[len(record)-1] = text
+	return cw.writeRecord(record)
+}
+
+func (cw *CSVWriter) newRecord(t time.Time) []string {
+	record := make([]string, len(cw.columns)+2)
+	record[0] = t.Format(time.RFC3339Nano)
+	return record
+}
+
+func (cw *CSVWriter) writeRecord(record []string) error {
+	if err := cw.csv.Write(record); err != nil {
+		return err
+	}
+	cw.rows++
+
+	if time.Since(cw.lastFlush) >= CSVFlushInterval {
+		return cw.Flush()
+	}
+	return nil
+}
+
+// Flush 将缓冲的数据写入底层 Writer
+func (cw *CSVWriter) Flush() error {
+	cw.lastFlush = time.Now()
+	cw.csv.Flush()
+	if err := cw.csv.Error(); err != nil {
+		return err
+	}
+	return cw.buf.Flush()
+}
+
+// Buffered 返回尚未刷新到文件的字节数
+func (cw *CSVWriter) Buffered() int {
+	// csv.Writer 复用了 cw.buf (bufio.NewWriter 对足够大的 *bufio.Writer 直接返回原对象)，没有单独的缓冲
+	return cw.buf.Buffered()
+}
+
+// Rows 返回已写入的数据行数（不含表头，包含标注行）
+func (cw *CSVWriter) Rows() int {
+	return cw.rows
+}
@@ -0,0 +1,172 @@
+// Package plot 解析实时绘图协议，与前端的绘图解析保持一致：
+//
+//   - 字符串协议: "&DRAW,1.5,2.3,-0.8#"，逗号分隔的浮点数
+//   - 十六进制协议: 若干 32 位小端浮点数，以 00 00 80 7F 结尾
+package plot
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Protocol 绘图协议
+type Protocol string
+
+const (
+	ProtocolString Protocol = "string"
+	ProtocolHex    Protocol = "hex"
+	ProtocolBoth   Protocol = "both" // 同时解析两种协议（与前端行为一致）
+)
+
+// ParseProtocol 解析协议名称
+func ParseProtocol(name string) (Protocol, error) {
+	switch Protocol(name) {
+	case ProtocolString, ProtocolHex, ProtocolBoth:
+		return Protocol(name), nil
+	default:
+		return "", fmt.Errorf("unknown plot protocol %q (expected string, hex or both)", name)
+	}
+}
+
+// Sample 一个绘图采样点，Values[i] 为第 i 个通道的值
+// 缺失的值为 NaN，编码为 JSON 时 NaN 与 ±Inf 写为 null (JSON 不能表示这些值)
+type Sample struct {
+	Time   time.Time `json:"time"`
+	Values []float64 `json:"values"`
+}
+
+// MarshalJSON 实现 json.Marshaler
+func (s Sample) MarshalJSON() ([]byte, error) {
+	values := make([]*float64, len(s.Values))
+	for i := range s.Values {
+		if !math.IsNaN(s.Values[i]) && !math.IsInf(s.Values[i], 0) {
+			values[i] = &s.Values[i]
+		}
+	}
+	return json.Marshal(struct {
+		Time   time.Time  `json:"time"`
+		Values []*float64 `json:"values"`
+	}{s.Time, values})
+}
+
+var (
+	drawPrefix = []byte("&DRAW,")
+	hexSuffix  = []byte{0x00, 0x00, 0x80, 0x7F}
+)
+
+// 缓冲区上限，防止没有结束符的数据无限累积
+const (
+	maxStringBuffer = 4096
+	maxHexBuffer    = 4096
+)
+
+// Parser 流式绘图解析器，能处理跨数据块拆分的数据包；非线程安全
+type Parser struct {
+	protocol Protocol
+	strBuf   []byte
+	hexBuf   []byte
+}
+
+// NewParser 创建解析器
+func NewParser(protocol Protocol) *Parser {
+	return &Parser{protocol: protocol}
+}
+
+// Protocol 返回解析器的协议
+func (p *Parser) Protocol() Protocol {
+	return p.protocol
+}
+
+// Feed 输入一段接收数据，返回其中完整的采样点
+func (p *Parser) Feed(t time.Time, data []byte) []Sample {
+	var samples []Sample
+	if p.protocol == ProtocolString || p.protocol == ProtocolBoth {
+		samples = append(samples, p.feedString(t, data)...)
+	}
+	if p.protocol == ProtocolHex || p.protocol == ProtocolBoth {
+		samples = append(samples, p.feedHex(t, data)...)
+	}
+	return samples
+}
+
+func (p *Parser) feedString(t time.Time, data []byte) []Sample {
+	p.strBuf = append(p.strBuf, data...)
+
+	var samples []Sample
+	for {
+		start := bytes.Index(p.strBuf, drawPrefix)
+		if start < 0 {
+			// 保留可能是前缀开头的尾部字节
+			keep := len(drawPrefix) - 1
+			if len(p.strBuf) > keep {
+				p.strBuf = append(p.strBuf[:0], p.strBuf[len(p.strBuf)-keep:]...)
+			}
+			break
+		}
+		end := bytes.IndexByte(p.strBuf[start:], '#')
+		if end < 0 {
+			p.strBuf = append(p.strBuf[:0], p.strBuf[start:]...)
+			if len(p.strBuf) > maxStringBuffer {
+				p.strBuf = p.strBuf[:0]
+			}
+			break
+		}
+
+		body := string(p.strBuf[start+len(drawPrefix) : start+end])
+		p.strBuf = p.strBuf[start+end+1:]
+		if values, ok := parseValues(body); ok {
+			samples = append(samples, Sample{Time: t, Values: values})
+		}
+	}
+	return samples
+}
+
+// parseValues 解析逗号分隔的浮点数，任意一项非法则整包丢弃
+func parseValues(body string) ([]float64, bool) {
+	parts := strings.Split(body, ",")
+	values := make([]float64, 0, len(parts))
+	for _, part := range parts {
+		v, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			return nil, false
+		}
+		values = append(values, v)
+	}
+	return values, len(values) > 0
+}
+
+func (p *Parser) feedHex(t time.Time, data []byte) []Sample {
+	p.hexBuf = append(p.hexBuf, data...)
+
+	var samples []Sample
+	for {
+		end := bytes.Index(p.hexBuf, hexSuffix)
+		if end < 0 {
+			if len(p.hexBuf) > maxHexBuffer {
+				p.hexBuf = append(p.hexBuf[:0], p.hexBuf[len(p.hexBuf)-len(hexSuffix)+1:]...)
+			}
+			break
+		}
+
+		// 与前端一致：结束符之前的字节按 4 字节对齐解析为浮点数，开头不足 4 字节的部分丢弃
+		payload := p.hexBuf[:end]
+		start := len(payload) % 4
+		count := len(payload) / 4
+		if count > 0 {
+			values := make([]float64, count)
+			for i := 0; i < count; i++ {
+				bits := binary.LittleEndian.Uint32(payload[start+i*4:])
+				values[i] = float64(math.Float32frombits(bits))
+			}
+			samples = append(samples, Sample{Time: t, Values: values})
+		}
+		p.hexBuf = p.hexBuf[end+len(hexSuffix):]
+	}
+	return samples
+}
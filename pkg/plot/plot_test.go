@@ -0,0 +1,164 @@
+package plot
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func hexPacket(values ...float32) []byte {
+	var buf []byte
+	for _, v := range values {
+		buf = binary.LittleEndian.AppendUint32(buf, math.Float32bits(v))
+	}
+	return append(buf, 0x00, 0x00, 0x80, 0x7F)
+}
+
+func TestStringProtocol(t *testing.T) {
+	p := NewParser(ProtocolString)
+	now := time.Now()
+
+	samples := p.Feed(now, []byte("noise&DRAW,1.5,2.5,-3#\n&DRAW,4#"))
+	if len(samples) != 2 {
+		t.Fatalf("Expected 2 samples, got %d", len(samples))
+	}
+	if !reflect.DeepEqual(samples[0].Values, []float64{1.5, 2.5, -3}) {
+		t.Errorf("Unexpected values: %v", samples[0].Values)
+	}
+
+	// 跨数据块拆分
+	if s := p.Feed(now, []byte("&DR")); len(s) != 0 {
+		t.Errorf("Partial prefix should not produce samples, got %v", s)
+	}
+	if s := p.Feed(now, []byte("AW,7,8")); len(s) != 0 {
+		t.Errorf("Incomplete packet should not produce samples, got %v", s)
+	}
+	s := p.Feed(now, []byte(".5#\n"))
+	if len(s) != 1 || !reflect.DeepEqual(s[0].Values, []float64{7, 8.5}) {
+		t.Errorf("Expected split packet to decode as [7 8.5], got %v", s)
+	}
+
+	// 非法数值整包丢弃
+	if s := p.Feed(now, []byte("&DRAW,1,abc#")); len(s) != 0 {
+		t.Errorf("Malformed packet should be dropped, got %v", s)
+	}
+}
+
+func TestHexProtocol(t *testing.T) {
+	p := NewParser(ProtocolHex)
+	now := time.Now()
+
+	packet := hexPacket(1.1, -2)
+	s := p.Feed(now, packet[:5])
+	if len(s) != 0 {
+		t.Fatalf("Partial packet should not produce samples")
+	}
+	s = p.Feed(now, packet[5:])
+	if len(s) != 1 || len(s[0].Values) != 2 {
+		t.Fatalf("Expected one sample with 2 values, got %v", s)
+	}
+	if math.Abs(s[0].Values[0]-1.1) > 1e-6 || s[0].Values[1] != -2 {
+		t.Errorf("Unexpected values: %v", s[0].Values)
+	}
+
+	// 开头未对齐的字节被丢弃
+	s = p.Feed(now, append([]byte{0xAA}, hexPacket(3)...))
+	if len(s) != 1 || s[0].Values[0] != 3 {
+		t.Errorf("Expected misaligned prefix to be skipped, got %v", s)
+	}
+}
+
+func TestSampleJSONWithoutNumber(t *testing.T) {
+	ts := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	raw, err := json.Marshal(Sample{Time: ts, Values: []float64{1.5, math.NaN(), math.Inf(1)}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"time":"2024-01-02T03:04:05Z","values":[1.5,null,null]}`; string(raw) != want {
+		t.Errorf("got %s, want %s", raw, want)
+	}
+}
+
+func TestParseProtocol(t *testing.T) {
+	for _, name := range []string{"string", "hex", "both"} {
+		if _, err := ParseProtocol(name); err != nil {
+			t.Errorf("ParseProtocol(%q) failed: %v", name, err)
+		}
+	}
+	if _, err := ParseProtocol("json"); err == nil {
+		t.Error("Expected error for unknown protocol")
+	}
+}
+
+func TestCSVWriter(t *testing.T) {
+	var out bytes.Buffer
+	cw, err := NewCSVWriter(&out, []string{"temp", "humidity", "pressure"})
+	if err != nil {
+		t.Fatalf("NewCSVWriter failed: %v", err)
+	}
+
+	ts := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	cw.WriteSample(Sample{Time: ts, Values: []float64{21.5, 40}})
+	cw.WriteSample(Sample{Time: ts, Values: []float64{1, 2, 3, 4}})
+	cw.WriteSample(Sample{Time: ts, Values: []float64{math.NaN(), 7, math.NaN()}})
+	cw.WriteAnnotation(ts, "power cycled, DUT")
+	if err := cw.Flush(); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+
+	expected := "timestamp,temp,humidity,pressure,annotation\n" +
+		"2024-01-02T03:04:05Z,21.5,40,,\n" +
+		"2024-01-02T03:04:05Z,1,2,3,\n" +
+		"2024-01-02T03:04:05Z,,7,,\n" +
+		"2024-01-02T03:04:05Z,,,,\"power cycled, DUT\"\n"
+	if out.String() != expected {
+		t.Errorf("CSV output mismatch:\n%s\nexpected:\n%s", out.String(), expected)
+	}
+	if cw.Rows() != 4 {
+		t.Errorf("Expected 4 rows, got %d", cw.Rows())
+	}
+
+	if _, err := NewCSVWriter(&out, nil); err == nil {
+		t.Error("Expected error for empty column set")
+	}
+}
+
+func TestCommentedCSVWriter(t *testing.T) {
+	var out bytes.Buffer
+	cw, err := NewCommentedCSVWriter(&out, []string{"temp"}, []string{"Serial Mate v1.2.3", "Connection: serial:COM7@115200,8N1"})
+	if err != nil {
+		t.Fatalf("NewCommentedCSVWriter failed: %v", err)
+	}
+	cw.WriteSample(Sample{Time: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), Values: []float64{21.5}})
+	if err := cw.Flush(); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+
+	expected := "# Serial Mate v1.2.3\n" +
+		"# Connection: serial:COM7@115200,8N1\n" +
+		"timestamp,temp,annotation\n" +
+		"2024-01-02T03:04:05Z,21.5,\n"
+	if out.String() != expected {
+		t.Errorf("CSV output mismatch:\n%s\nexpected:\n%s", out.String(), expected)
+	}
+	if cw.Rows() != 1 {
+		t.Errorf("Expected 1 row, got %d", cw.Rows())
+	}
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) { return 0, errors.New("disk full") }
+
+func TestCSVWriterReportsWriteErrors(t *testing.T) {
+	_, err := NewCSVWriter(failingWriter{}, []string{"a"})
+	if err == nil || !strings.Contains(err.Error(), "disk full") {
+		t.Errorf("Expected write error to surface, got %v", err)
+	}
+}
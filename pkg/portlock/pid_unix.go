@@ -0,0 +1,19 @@
+//go:build !windows
+
+package portlock
+
+import (
+	"errors"
+
+	"golang.org/x/sys/unix"
+)
+
+// pidAlive 判断进程是否存活：信号 0 不会真正发送，只做存在性与权限检查
+// EPERM 表示进程存在但属于其他用户
+func pidAlive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	err := unix.Kill(pid, 0)
+	return err == nil || errors.Is(err, unix.EPERM)
+}
@@ -0,0 +1,49 @@
+//go:build windows
+
+package portlock
+
+import (
+	"strconv"
+
+	"golang.org/x/sys/windows"
+)
+
+// stillActive GetExitCodeProcess 对仍在运行的进程返回的退出码 (STILL_ACTIVE)
+const stillActive = 259
+
+// pidAlive 判断进程是否存活：能打开进程句柄且尚未退出
+func pidAlive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	h, err := windows.OpenProcess(windows.PROCESS_QUERY_LIMITED_INFORMATION, false, uint32(pid))
+	if err != nil {
+		// 拒绝访问说明进程存在 (属于更高权限的用户)
+		return err == windows.ERROR_ACCESS_DENIED
+	}
+	defer windows.CloseHandle(h)
+
+	var code uint32
+	if err := windows.GetExitCodeProcess(h, &code); err != nil {
+		return false
+	}
+	return code == stillActive
+}
+
+// pidStart 返回进程创建时间 (FILETIME 的 100 纳秒计数) 作为启动时间的标识，无法获取时返回空
+func pidStart(pid int) string {
+	if pid <= 0 {
+		return ""
+	}
+	h, err := windows.OpenProcess(windows.PROCESS_QUERY_LIMITED_INFORMATION, false, uint32(pid))
+	if err != nil {
+		return ""
+	}
+	defer windows.CloseHandle(h)
+
+	var created, exited, kernel, user windows.Filetime
+	if err := windows.GetProcessTimes(h, &created, &exited, &kernel, &user); err != nil {
+		return ""
+	}
+	return strconv.FormatInt(created.Nanoseconds()/100, 10)
+}
@@ -0,0 +1,227 @@
+// Package portlock 通过锁文件防止多个 serial-mate 实例同时打开同一个串口
+//
+// 每个端口在锁目录下对应一个锁文件，内容为持有者的 PID、进程启动时间与加锁时间。打开端口前检查锁文件：
+// 持有者进程仍然存活时拒绝打开；进程已退出 (例如崩溃) 的锁会被自动清理。锁文件在重启后仍然存在，
+// PID 可能已被无关的进程重用，因此还要比较进程启动时间。
+//
+// 锁文件先完整写入临时文件，再以硬链接放到最终路径，其他实例不会读到写了一半的锁文件。
+// 清理过期锁时先把锁文件改名移开，确认内容仍是判断为过期的那个锁后才删除，
+// 不会误删另一个实例刚刚创建的新锁。
+package portlock
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Info 锁文件内容
+type Info struct {
+	PID  int       `json:"pid"`
+	Port string    `json:"port"`
+	Time time.Time `json:"time"`
+	// Start 持有者进程启动时间的标识 (平台相关，只用于比较)，无法获取时为空
+	Start string `json:"start,omitempty"`
+}
+
+// ClaimedError 端口已被另一个存活的实例占用
+type ClaimedError struct {
+	Info
+}
+
+func (e *ClaimedError) Error() string {
+	return fmt.Sprintf("port %s is in use by another serial-mate instance (PID %d, since %s)",
+		e.Port, e.PID, e.Time.Format("2006-01-02 15:04:05"))
+}
+
+// Lock 当前进程持有的端口锁
+type Lock struct {
+	path string
+	pid  int
+}
+
+// 便于测试替换
+var (
+	processAlive = pidAlive
+	processStart = pidStart
+	currentPID   = os.Getpid
+)
+
+// Path 返回端口对应的锁文件路径
+func Path(dir, port string) string {
+	return filepath.Join(dir, sanitize(port)+".lock")
+}
+
+// sanitize 将端口名 (例如 /dev/ttyUSB0、COM3) 转换为安全的文件名
+func sanitize(port string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '-':
+			return r
+		default:
+			return '_'
+		}
+	}, port)
+}
+
+// Acquire 获取端口锁
+// 锁被存活的其他进程持有时返回 *ClaimedError；属于已退出进程的锁会被清理后重新获取
+func Acquire(dir, port string) (*Lock, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create lock dir: %w", err)
+	}
+
+	path := Path(dir, port)
+	pid := currentPID()
+	info := Info{PID: pid, Port: port, Time: time.Now(), Start: processStart(pid)}
+	raw, err := json.Marshal(info)
+	if err != nil {
+		return nil, err
+	}
+
+	// 第二次尝试用于清理过期锁之后重新创建
+	for attempt := 0; attempt < 2; attempt++ {
+		err := create(path, raw)
+		if err == nil {
+			return &Lock{path: path, pid: pid}, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, err
+		}
+
+		seen, err := os.ReadFile(path)
+		if errors.Is(err, os.ErrNotExist) {
+			// 持有者刚刚释放
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read lock file: %w", err)
+		}
+		holder, err := parse(seen)
+		if err == nil && holder.PID != pid && holderAlive(holder) {
+			return nil, &ClaimedError{Info: holder}
+		}
+		// 持有者已退出、锁文件损坏或属于本进程：视为过期锁
+		if err := removeStale(path, seen); err != nil {
+			return nil, fmt.Errorf("failed to remove stale lock: %w", err)
+		}
+	}
+	return nil, fmt.Errorf("failed to acquire lock for %s", port)
+}
+
+// create 原子地创建内容为 raw 的锁文件，path 已存在时返回 os.ErrExist
+// 内容先写入同目录的临时文件，再硬链接到 path：链接要么失败，要么得到完整的文件
+func create(path string, raw []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".portlock-*")
+	if err != nil {
+		return fmt.Errorf("failed to create lock file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(raw)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write lock file: %w", err)
+	}
+	if err := os.Link(tmp.Name(), path); err != nil {
+		if errors.Is(err, os.ErrExist) {
+			return os.ErrExist
+		}
+		return fmt.Errorf("failed to create lock file: %w", err)
+	}
+	return nil
+}
+
+// removeStale 删除内容仍为 seen 的过期锁文件
+// 两个实例可能同时读到同一个过期锁：先到的实例删除后创建了自己的锁，后到的实例直接删除 path
+// 就会删掉这个新锁。因此先把 path 改名为唯一的临时文件，再比较内容，不是 seen 时放回原处
+func removeStale(path string, seen []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".portlock-stale-*")
+	if err != nil {
+		return err
+	}
+	name := tmp.Name()
+	tmp.Close()
+	defer os.Remove(name)
+
+	if err := os.Rename(path, name); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	if raw, err := os.ReadFile(name); err != nil || bytes.Equal(raw, seen) {
+		return nil
+	}
+	// 移开的是其他实例的新锁：放回原处，path 已被再次创建时以那个锁为准
+	if err := os.Link(name, path); err != nil && !errors.Is(err, os.ErrExist) {
+		return err
+	}
+	return nil
+}
+
+// holderAlive 判断锁的持有者是否仍在运行：PID 存活且 (两者都已知时) 进程启动时间一致，
+// 启动时间不同说明 PID 已被其他进程重用 (例如重启之后)
+func holderAlive(holder Info) bool {
+	if !processAlive(holder.PID) {
+		return false
+	}
+	if holder.Start == "" {
+		return true
+	}
+	start := processStart(holder.PID)
+	return start == "" || start == holder.Start
+}
+
+// Read 读取锁文件内容
+func Read(path string) (Info, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return Info{}, err
+	}
+	return parse(raw)
+}
+
+// parse 解析锁文件内容
+func parse(raw []byte) (Info, error) {
+	var info Info
+	if err := json.Unmarshal(raw, &info); err != nil {
+		return info, fmt.Errorf("invalid lock file: %w", err)
+	}
+	return info, nil
+}
+
+// Release 释放锁；锁文件已被其他进程接管 (例如被强制覆盖) 时不做任何操作
+// nil 锁上调用是安全的
+func (l *Lock) Release() error {
+	if l == nil {
+		return nil
+	}
+	info, err := Read(l.path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	if info.PID != l.pid {
+		return nil
+	}
+	return os.Remove(l.path)
+}
+
+// ForceRelease 无条件删除端口的锁文件，用于用户确认要强制接管端口
+func ForceRelease(dir, port string) error {
+	err := os.Remove(Path(dir, port))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
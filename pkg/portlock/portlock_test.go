@@ -0,0 +1,226 @@
+package portlock
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func writeLock(t *testing.T, dir, port string, pid int) {
+	t.Helper()
+	os.MkdirAll(dir, 0755)
+	raw := []byte(`{"pid":` + strconv.Itoa(pid) + `,"port":"` + port + `","time":"2024-01-01T00:00:00Z"}`)
+	if err := os.WriteFile(Path(dir, port), raw, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// deadPID 启动并等待一个子进程退出，返回其 (已失效的) PID
+func deadPID(t *testing.T) int {
+	t.Helper()
+	cmd := exec.Command(os.Args[0], "-test.run=^$")
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("Failed to run helper process: %v", err)
+	}
+	return cmd.Process.Pid
+}
+
+func TestAcquireAndRelease(t *testing.T) {
+	dir := t.TempDir()
+
+	lock, err := Acquire(dir, "/dev/ttyUSB0")
+	if err != nil {
+		t.Fatalf("Acquire failed: %v", err)
+	}
+	if filepath.Base(Path(dir, "/dev/ttyUSB0")) != "_dev_ttyUSB0.lock" {
+		t.Errorf("Unexpected lock file name %s", Path(dir, "/dev/ttyUSB0"))
+	}
+	info, err := Read(Path(dir, "/dev/ttyUSB0"))
+	if err != nil || info.PID != os.Getpid() {
+		t.Errorf("Lock file should record our PID, got %+v, %v", info, err)
+	}
+
+	// 本进程再次获取 (例如上次没有正常释放) 不应失败
+	if _, err := Acquire(dir, "/dev/ttyUSB0"); err != nil {
+		t.Errorf("Re-acquire by the same process failed: %v", err)
+	}
+
+	if err := lock.Release(); err != nil {
+		t.Fatalf("Release failed: %v", err)
+	}
+	if _, err := os.Stat(Path(dir, "/dev/ttyUSB0")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Lock file should be removed after release")
+	}
+}
+
+func TestClaimedByLiveProcess(t *testing.T) {
+	dir := t.TempDir()
+	processAlive = func(int) bool { return true }
+	defer func() { processAlive = pidAlive }()
+
+	writeLock(t, dir, "COM3", 424242)
+	_, err := Acquire(dir, "COM3")
+
+	var claimed *ClaimedError
+	if !errors.As(err, &claimed) {
+		t.Fatalf("Expected ClaimedError, got %v", err)
+	}
+	if claimed.PID != 424242 {
+		t.Errorf("Expected holder PID 424242, got %d", claimed.PID)
+	}
+}
+
+func TestStaleLockFromDeadProcessIsCleaned(t *testing.T) {
+	dir := t.TempDir()
+	pid := deadPID(t)
+	if pidAlive(pid) {
+		t.Skipf("PID %d was reused before the test could check it", pid)
+	}
+
+	writeLock(t, dir, "COM4", pid)
+	lock, err := Acquire(dir, "COM4")
+	if err != nil {
+		t.Fatalf("Stale lock should be cleaned, got %v", err)
+	}
+	defer lock.Release()
+
+	info, _ := Read(Path(dir, "COM4"))
+	if info.PID != os.Getpid() {
+		t.Errorf("Expected lock to be taken over, holder is %d", info.PID)
+	}
+	if time.Since(info.Time) > time.Minute {
+		t.Errorf("Lock timestamp should be refreshed, got %v", info.Time)
+	}
+}
+
+func TestCorruptLockIsCleaned(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(Path(dir, "COM5"), []byte("garbage"), 0644)
+
+	lock, err := Acquire(dir, "COM5")
+	if err != nil {
+		t.Fatalf("Corrupt lock should be treated as stale, got %v", err)
+	}
+	lock.Release()
+}
+
+func TestPidAlive(t *testing.T) {
+	if !pidAlive(os.Getpid()) {
+		t.Error("Current process should be alive")
+	}
+	if pidAlive(0) || pidAlive(-1) {
+		t.Error("Invalid PIDs should not be alive")
+	}
+}
+
+func TestReleaseAfterForceOverride(t *testing.T) {
+	dir := t.TempDir()
+	lock, _ := Acquire(dir, "COM6")
+
+	// 另一个实例强制接管
+	ForceRelease(dir, "COM6")
+	writeLock(t, dir, "COM6", 999999)
+
+	if err := lock.Release(); err != nil {
+		t.Fatalf("Release failed: %v", err)
+	}
+	if info, err := Read(Path(dir, "COM6")); err != nil || info.PID != 999999 {
+		t.Errorf("Release must not remove a lock owned by another process")
+	}
+}
+
+func TestReusedPIDIsStale(t *testing.T) {
+	dir := t.TempDir()
+	processAlive = func(int) bool { return true }
+	processStart = func(int) string { return "boot-2:777" }
+	defer func() { processAlive, processStart = pidAlive, pidStart }()
+
+	// 锁文件来自重启之前：PID 存在但属于另一个进程
+	raw := []byte(`{"pid":424242,"port":"COM7","time":"2024-01-01T00:00:00Z","start":"boot-1:123"}`)
+	os.WriteFile(Path(dir, "COM7"), raw, 0644)
+	lock, err := Acquire(dir, "COM7")
+	if err != nil {
+		t.Fatalf("Lock of a reused PID should be stale, got %v", err)
+	}
+	lock.Release()
+
+	// 启动时间一致时仍由原进程持有
+	raw = []byte(`{"pid":424242,"port":"COM7","time":"2024-01-01T00:00:00Z","start":"boot-2:777"}`)
+	os.WriteFile(Path(dir, "COM7"), raw, 0644)
+	var claimed *ClaimedError
+	if _, err := Acquire(dir, "COM7"); !errors.As(err, &claimed) {
+		t.Errorf("Expected ClaimedError, got %v", err)
+	}
+}
+
+func TestPidStart(t *testing.T) {
+	if runtime.GOOS != "linux" && runtime.GOOS != "windows" {
+		t.Skip("process start time is not read on this platform")
+	}
+	start := pidStart(os.Getpid())
+	if start == "" || start != pidStart(os.Getpid()) {
+		t.Errorf("Expected a stable start time, got %q", start)
+	}
+	if pidStart(deadPID(t)) != "" {
+		t.Error("Exited process should have no start time")
+	}
+}
+
+func TestAcquireWritesCompleteFile(t *testing.T) {
+	dir := t.TempDir()
+	lock, err := Acquire(dir, "COM8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lock.Release()
+
+	info, err := Read(Path(dir, "COM8"))
+	if err != nil || info.PID != os.Getpid() {
+		t.Errorf("Lock file = %+v, %v", info, err)
+	}
+	// 临时文件不会留在锁目录中
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Errorf("Expected only the lock file, got %d entries", len(entries))
+	}
+}
+
+func TestRemoveStaleKeepsNewLock(t *testing.T) {
+	dir := t.TempDir()
+	path := Path(dir, "COM7")
+	writeLock(t, dir, "COM7", 424242)
+	seen, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 另一个实例已清理同一个过期锁并创建了自己的锁
+	fresh := []byte(`{"pid":1,"port":"COM7","time":"2024-01-02T00:00:00Z"}`)
+	os.Remove(path)
+	if err := create(path, fresh); err != nil {
+		t.Fatal(err)
+	}
+	if err := removeStale(path, seen); err != nil {
+		t.Fatalf("removeStale failed: %v", err)
+	}
+	if raw, err := os.ReadFile(path); err != nil || string(raw) != string(fresh) {
+		t.Errorf("New lock was removed: %q, %v", raw, err)
+	}
+
+	// 内容未变时删除
+	if err := removeStale(path, fresh); err != nil {
+		t.Fatalf("removeStale failed: %v", err)
+	}
+	if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+		t.Error("Stale lock should be removed")
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 0 {
+		t.Errorf("Temporary files left behind: %v", entries)
+	}
+}
@@ -0,0 +1,31 @@
+package portlock
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// pidStart 返回进程启动时间的标识：本次开机的 boot_id 与 /proc/<pid>/stat 的 starttime (开机后的时钟节拍数)
+// 进程不存在或无法读取时返回空
+func pidStart(pid int) string {
+	if pid <= 0 {
+		return ""
+	}
+	stat, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return ""
+	}
+	// 进程名可能包含空格与括号，从最后一个 ')' 之后开始按字段分割；starttime 为第 22 个字段
+	i := bytes.LastIndexByte(stat, ')')
+	if i < 0 {
+		return ""
+	}
+	fields := strings.Fields(string(stat[i+1:]))
+	if len(fields) < 20 {
+		return ""
+	}
+	boot, _ := os.ReadFile("/proc/sys/kernel/random/boot_id")
+	return strings.TrimSpace(string(boot)) + ":" + fields[19]
+}
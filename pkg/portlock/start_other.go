@@ -0,0 +1,8 @@
+//go:build !linux && !windows
+
+package portlock
+
+// pidStart 该平台不读取进程启动时间，只按 PID 判断持有者
+func pidStart(pid int) string {
+	return ""
+}
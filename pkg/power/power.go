@@ -0,0 +1,85 @@
+// Package power 监听系统睡眠与唤醒
+//
+// Windows 的电源广播由 Wails (options.Windows 的 OnSuspend/OnResume) 转发，不经过本包；
+// Linux 通过 logind 的 PrepareForSleep 信号同时获得睡眠与唤醒通知；
+// 没有系统通知时 (macOS、没有 logind 的 Linux) 依靠时钟检测：单调时钟在睡眠期间停止，
+// 墙上时钟继续走，两者的差值突然增大说明刚刚从睡眠中唤醒 (此时只有唤醒通知)
+package power
+
+import (
+	"sync"
+	"time"
+)
+
+// Handler 睡眠/唤醒回调，在本包的 goroutine 中调用
+type Handler struct {
+	Suspend func()
+	Resume  func()
+}
+
+// 时钟检测参数
+const (
+	// PollInterval 时钟检测的采样间隔
+	PollInterval = 5 * time.Second
+	// SleepThreshold 墙上时钟比单调时钟多走超过该值时视为发生过睡眠
+	SleepThreshold = 10 * time.Second
+)
+
+// Watch 开始监听，返回停止函数
+func Watch(h Handler) (stop func()) {
+	done := make(chan struct{})
+	var once sync.Once
+	stopPlatform, notified := watchPlatform(h, done)
+	if notified {
+		// 系统会发送唤醒通知，不再需要时钟检测 (避免同一次唤醒报告两次)
+		return func() {
+			once.Do(func() {
+				close(done)
+				stopPlatform()
+			})
+		}
+	}
+
+	go func() {
+		start := time.Now()
+		d := sleepDetector{threshold: SleepThreshold, lastWall: start}
+		ticker := time.NewTicker(PollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case now := <-ticker.C:
+				if d.observe(now, now.Sub(start)) > 0 && h.Resume != nil {
+					h.Resume()
+				}
+			}
+		}
+	}()
+
+	return func() {
+		once.Do(func() {
+			close(done)
+			stopPlatform()
+		})
+	}
+}
+
+// sleepDetector 比较相邻两次采样的墙上时间与单调时间
+type sleepDetector struct {
+	threshold time.Duration
+	lastWall  time.Time
+	lastMono  time.Duration
+}
+
+// observe 记录一次采样，返回自上次采样以来的睡眠时长 (低于阈值时为 0)
+// mono 为单调时钟读数，wall 的单调部分会被忽略
+func (d *sleepDetector) observe(wall time.Time, mono time.Duration) time.Duration {
+	wall = wall.Round(0)
+	slept := wall.Sub(d.lastWall) - (mono - d.lastMono)
+	d.lastWall, d.lastMono = wall, mono
+	if slept < d.threshold {
+		return 0
+	}
+	return slept
+}
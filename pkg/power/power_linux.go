@@ -0,0 +1,48 @@
+//go:build linux
+
+package power
+
+import (
+	"github.com/godbus/dbus/v5"
+)
+
+// watchPlatform 订阅 logind 的 PrepareForSleep 信号 (参数为 true 表示即将睡眠，false 表示已唤醒)
+// 系统总线不可用时返回 notified 为 false，由时钟检测代替
+func watchPlatform(h Handler, done <-chan struct{}) (stop func(), notified bool) {
+	conn, err := dbus.ConnectSystemBus()
+	if err != nil {
+		return func() {}, false
+	}
+	if err := conn.AddMatchSignal(
+		dbus.WithMatchInterface("org.freedesktop.login1.Manager"),
+		dbus.WithMatchMember("PrepareForSleep"),
+	); err != nil {
+		conn.Close()
+		return func() {}, false
+	}
+
+	signals := make(chan *dbus.Signal, 4)
+	conn.Signal(signals)
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case sig, ok := <-signals:
+				if !ok {
+					return
+				}
+				if len(sig.Body) == 0 {
+					continue
+				}
+				start, _ := sig.Body[0].(bool)
+				if start && h.Suspend != nil {
+					h.Suspend()
+				} else if !start && h.Resume != nil {
+					h.Resume()
+				}
+			}
+		}
+	}()
+	return func() { conn.Close() }, true
+}
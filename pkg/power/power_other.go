@@ -0,0 +1,8 @@
+//go:build !linux && !windows
+
+package power
+
+// watchPlatform 其他平台 (macOS) 没有可用的通知来源，依靠时钟检测
+func watchPlatform(h Handler, done <-chan struct{}) (stop func(), notified bool) {
+	return func() {}, false
+}
@@ -0,0 +1,27 @@
+package power
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSleepDetector(t *testing.T) {
+	start := time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC)
+	d := sleepDetector{threshold: SleepThreshold, lastWall: start}
+
+	// 正常运行：墙上时钟与单调时钟同步
+	if slept := d.observe(start.Add(5*time.Second), 5*time.Second); slept != 0 {
+		t.Errorf("Expected no sleep, got %v", slept)
+	}
+	// 小幅的时钟校准不算睡眠
+	if slept := d.observe(start.Add(12*time.Second), 10*time.Second); slept != 0 {
+		t.Errorf("Expected small clock step to be ignored, got %v", slept)
+	}
+	// 睡眠 10 分钟：单调时钟只走了一个采样间隔
+	if slept := d.observe(start.Add(12*time.Second+10*time.Minute+5*time.Second), 15*time.Second); slept != 10*time.Minute {
+		t.Errorf("Expected 10m sleep, got %v", slept)
+	}
+	if slept := d.observe(start.Add(12*time.Second+10*time.Minute+10*time.Second), 20*time.Second); slept != 0 {
+		t.Errorf("Expected detector to re-arm, got %v", slept)
+	}
+}
@@ -0,0 +1,8 @@
+//go:build windows
+
+package power
+
+// watchPlatform Windows 的电源广播由 Wails 转发给应用 (options.Windows 的 OnSuspend/OnResume)
+func watchPlatform(h Handler, done <-chan struct{}) (stop func(), notified bool) {
+	return func() {}, true
+}
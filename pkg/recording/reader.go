@@ -0,0 +1,153 @@
+package recording
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"os"
+	"time"
+)
+
+// ErrNotRecording 文件不是录制文件 (文件头不匹配)
+var ErrNotRecording = errors.New("not a recording file")
+
+// Integrity 读取录制文件的完整性结果
+type Integrity struct {
+	Records  int  `json:"records"`  // 恢复的数据记录数
+	Expected int  `json:"expected"` // 最后一条有效索引给出的数据记录数，没有索引时为 0
+	Lost     int  `json:"lost"`     // 最后一条有效索引之前缺失的数据记录数
+	Complete bool `json:"complete"` // 找到最终索引，录制正常结束
+	// Corrupt 文件中间跳过的损坏记录段数 (CRC 错误或长度无效，之后仍找到了有效记录)
+	Corrupt      int  `json:"corrupt"`
+	SkippedBytes int  `json:"skippedBytes"` // 因损坏或不完整而跳过的字节数
+	Truncated    bool `json:"truncated"`    // 末尾有不完整或损坏的记录 (通常是写入时崩溃)
+	OK           bool `json:"ok"`           // 录制正常结束且没有任何损坏
+}
+
+// Summary 返回一行说明
+func (i Integrity) Summary() string {
+	if i.OK {
+		return fmt.Sprintf("recording intact: %d records", i.Records)
+	}
+	s := fmt.Sprintf("recovered %d records", i.Records)
+	if i.Expected > 0 {
+		s += fmt.Sprintf(" (index expects at least %d, %d lost)", i.Expected, i.Lost)
+	}
+	if i.Corrupt > 0 {
+		s += fmt.Sprintf(", skipped %d corrupt sections", i.Corrupt)
+	}
+	if i.Truncated {
+		s += ", trailing record incomplete"
+	}
+	if !i.Complete {
+		s += ", recording was not closed cleanly"
+	}
+	return s
+}
+
+// Read 解析录制文件的内容，按顺序对每条数据记录调用 fn (可为 nil)；fn 返回错误时停止并返回该错误
+// 损坏的记录被跳过：从下一个同步标记开始重新查找有效记录，末尾不完整的记录记为 Truncated
+func Read(data []byte, fn func(Record) error) (Integrity, error) {
+	var in Integrity
+	if !bytes.HasPrefix(data, []byte(Magic)) {
+		return in, ErrNotRecording
+	}
+	atIndex := 0 // 最后一条有效索引时已恢复的数据记录数
+	pos := len(Magic)
+	for pos < len(data) {
+		rec, size, ok := decodeAt(data[pos:])
+		if !ok {
+			next := resync(data, pos+1)
+			if next < 0 {
+				in.Truncated = true
+				in.SkippedBytes += len(data) - pos
+				break
+			}
+			in.Corrupt++
+			in.SkippedBytes += next - pos
+			pos = next
+			continue
+		}
+		pos += size
+
+		if rec.Kind == KindIndex {
+			if len(rec.Data) != indexLen {
+				continue
+			}
+			in.Expected = int(binary.LittleEndian.Uint64(rec.Data))
+			atIndex = in.Records
+			if rec.Data[8]&flagFinal != 0 {
+				in.Complete = true
+			}
+			continue
+		}
+		in.Records++
+		if fn != nil {
+			if err := fn(rec); err != nil {
+				return in, err
+			}
+		}
+	}
+	if in.Expected > atIndex {
+		in.Lost = in.Expected - atIndex
+	}
+	in.OK = in.Complete && in.Corrupt == 0 && !in.Truncated && in.Lost == 0
+	return in, nil
+}
+
+// ReadFile 读取录制文件，见 Read
+func ReadFile(path string, fn func(Record) error) (Integrity, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Integrity{}, err
+	}
+	return Read(data, fn)
+}
+
+// Verify 校验录制文件的完整性
+func Verify(path string) (Integrity, error) {
+	return ReadFile(path, nil)
+}
+
+// decodeAt 解析 b 开头的一条记录，ok 为 false 表示不完整或已损坏
+func decodeAt(b []byte) (rec Record, size int, ok bool) {
+	if len(b) < syncLen+lenLen || b[0] != sync0 || b[1] != sync1 {
+		return rec, 0, false
+	}
+	n := int(binary.LittleEndian.Uint32(b[syncLen:]))
+	if n < bodyHead || n > bodyHead+MaxPayload {
+		return rec, 0, false
+	}
+	size = syncLen + lenLen + n + crcLen
+	if len(b) < size {
+		return rec, 0, false
+	}
+	body := b[syncLen+lenLen : syncLen+lenLen+n]
+	if crc32.ChecksumIEEE(b[syncLen:size-crcLen]) != binary.LittleEndian.Uint32(b[size-crcLen:]) {
+		return rec, 0, false
+	}
+	rec = Record{
+		Kind: Kind(body[0]),
+		Time: time.Unix(0, int64(binary.LittleEndian.Uint64(body[1:]))),
+		Data: body[bodyHead:],
+	}
+	return rec, size, true
+}
+
+// resync 从 from 开始查找下一条有效记录的位置，没有时返回 -1
+func resync(data []byte, from int) int {
+	marker := []byte{sync0, sync1}
+	for from < len(data) {
+		i := bytes.Index(data[from:], marker)
+		if i < 0 {
+			return -1
+		}
+		if _, _, ok := decodeAt(data[from+i:]); ok {
+			return from + i
+		}
+		from += i + 1
+	}
+	return -1
+}
@@ -0,0 +1,253 @@
+// Package recording 定义会话录制文件的格式：崩溃后仍可读取的预写式日志
+//
+// 文件以 8 字节的 Magic 开头，之后是一串记录：
+//
+//	偏移  长度  内容
+//	0     2     同步标记 0xA5 0x5A
+//	2     4     正文长度 N (小端)
+//	6     N     正文：类型 (1 字节)、Unix 纳秒时间戳 (8 字节，小端)、负载
+//	6+N   4     CRC-32 (IEEE，小端)，覆盖长度与正文
+//
+// 每条记录以一次 Write 写入文件，程序崩溃时至多丢失最后一条不完整的记录；
+// 操作系统崩溃或断电时丢失上一次 fsync 之后的数据 (见 Options.SyncInterval)。
+// 写入器每 IndexEvery 条记录写一条索引记录 (KindIndex)，负载为此前的数据记录数，
+// 关闭时写入带结束标志的最终索引；读取时据此判断丢失了多少记录以及录制是否正常结束。
+package recording
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"os"
+	"sync"
+	"time"
+)
+
+// Magic 文件头
+const Magic = "SMREC1\r\n"
+
+// MaxPayload 单条记录负载的上限，更长的数据由 Write 拆分为多条记录；读取时超过该值的长度视为损坏
+const MaxPayload = 1 << 20
+
+// 记录的固定部分
+const (
+	syncLen   = 2
+	lenLen    = 4
+	bodyHead  = 1 + 8 // 类型与时间戳
+	crcLen    = 4
+	overhead  = syncLen + lenLen + bodyHead + crcLen
+	sync0     = 0xA5
+	sync1     = 0x5A
+	indexLen  = 8 + 1 // 记录数与标志
+	flagFinal = 1
+)
+
+// Kind 记录类型
+type Kind byte
+
+const (
+	KindRx    Kind = 1 // 接收的数据
+	KindTx    Kind = 2 // 发送的数据
+	KindNote  Kind = 3 // 标注，负载为 UTF-8 文本
+	KindIndex Kind = 4 // 索引 (写入器内部使用)
+)
+
+// String 返回类型名称，与历史记录的 "rx"/"note" 一致
+func (k Kind) String() string {
+	switch k {
+	case KindRx:
+		return "rx"
+	case KindTx:
+		return "tx"
+	case KindNote:
+		return "note"
+	case KindIndex:
+		return "index"
+	}
+	return fmt.Sprintf("kind-%d", k)
+}
+
+// Record 一条数据记录
+type Record struct {
+	Kind Kind
+	Time time.Time
+	Data []byte
+}
+
+const (
+	// DefaultSyncInterval SyncInterval 为 0 时的 fsync 间隔
+	DefaultSyncInterval = time.Second
+	// DefaultIndexEvery IndexEvery 为 0 时索引记录的间隔 (数据记录数)
+	DefaultIndexEvery = 256
+)
+
+// Options 写入设置
+type Options struct {
+	// SyncInterval 两次 fsync 之间的最长时间，在写入记录时检查；小于 0 表示每条记录都 fsync
+	SyncInterval time.Duration
+	IndexEvery   int
+}
+
+// File 写入器使用的文件，*os.File 满足该接口
+type File interface {
+	Write(p []byte) (int, error)
+	Sync() error
+	Close() error
+}
+
+// Writer 录制文件的写入器，线程安全
+type Writer struct {
+	mu         sync.Mutex
+	f          File
+	opts       Options
+	count      uint64 // 已写入的数据记录数
+	sinceIndex int
+	lastSync   time.Time
+	err        error // 第一次写入错误，之后的写入直接返回该错误
+}
+
+// Create 创建录制文件并写入文件头
+func Create(path string, opts Options) (*Writer, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	w, err := NewWriter(f, opts)
+	if err != nil {
+		f.Close()
+		os.Remove(path)
+		return nil, err
+	}
+	return w, nil
+}
+
+// NewWriter 在 f 上写入文件头并返回写入器
+func NewWriter(f File, opts Options) (*Writer, error) {
+	if opts.SyncInterval == 0 {
+		opts.SyncInterval = DefaultSyncInterval
+	}
+	if opts.IndexEvery <= 0 {
+		opts.IndexEvery = DefaultIndexEvery
+	}
+	if _, err := f.Write([]byte(Magic)); err != nil {
+		return nil, err
+	}
+	if err := f.Sync(); err != nil {
+		return nil, err
+	}
+	return &Writer{f: f, opts: opts, lastSync: time.Now()}, nil
+}
+
+// Write 追加一条数据记录 (超过 MaxPayload 时拆分为多条)，到达 IndexEvery 时追加索引记录，
+// 到达 SyncInterval 时 fsync
+func (w *Writer) Write(kind Kind, t time.Time, data []byte) error {
+	if kind == KindIndex {
+		return errors.New("index records are written automatically")
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for first := true; first || len(data) > 0; first = false {
+		part := data
+		if len(part) > MaxPayload {
+			part = part[:MaxPayload]
+		}
+		data = data[len(part):]
+		if err := w.appendLocked(kind, t, part); err != nil {
+			return err
+		}
+		w.count++
+		w.sinceIndex++
+		if w.sinceIndex >= w.opts.IndexEvery {
+			if err := w.indexLocked(t, 0); err != nil {
+				return err
+			}
+		}
+	}
+	if time.Since(w.lastSync) >= w.opts.SyncInterval {
+		return w.syncLocked()
+	}
+	return nil
+}
+
+// Count 返回已写入的数据记录数
+func (w *Writer) Count() uint64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.count
+}
+
+// Sync 立即 fsync
+func (w *Writer) Sync() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.syncLocked()
+}
+
+// Close 写入最终索引、fsync 并关闭文件
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	err := w.err
+	if err == nil {
+		err = w.indexLocked(time.Now(), flagFinal)
+	}
+	if err == nil {
+		err = w.syncLocked()
+	}
+	if closeErr := w.f.Close(); err == nil {
+		err = closeErr
+	}
+	if w.err == nil {
+		w.err = errors.New("recording is closed")
+	}
+	return err
+}
+
+func (w *Writer) indexLocked(t time.Time, flags byte) error {
+	payload := binary.LittleEndian.AppendUint64(make([]byte, 0, indexLen), w.count)
+	if err := w.appendLocked(KindIndex, t, append(payload, flags)); err != nil {
+		return err
+	}
+	w.sinceIndex = 0
+	return nil
+}
+
+// appendLocked 以一次 Write 写入完整的记录
+func (w *Writer) appendLocked(kind Kind, t time.Time, data []byte) error {
+	if w.err != nil {
+		return w.err
+	}
+	if _, err := w.f.Write(encode(kind, t, data)); err != nil {
+		w.err = err
+		return err
+	}
+	return nil
+}
+
+func (w *Writer) syncLocked() error {
+	if w.err != nil {
+		return w.err
+	}
+	if err := w.f.Sync(); err != nil {
+		w.err = err
+		return err
+	}
+	w.lastSync = time.Now()
+	return nil
+}
+
+// encode 编码一条记录
+func encode(kind Kind, t time.Time, data []byte) []byte {
+	n := bodyHead + len(data)
+	rec := make([]byte, 0, overhead+len(data))
+	rec = append(rec, sync0, sync1)
+	rec = binary.LittleEndian.AppendUint32(rec, uint32(n))
+	rec = append(rec, byte(kind))
+	rec = binary.LittleEndian.AppendUint64(rec, uint64(t.UnixNano()))
+	rec = append(rec, data...)
+	return binary.LittleEndian.AppendUint32(rec, crc32.ChecksumIEEE(rec[syncLen:]))
+}
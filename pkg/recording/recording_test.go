@@ -0,0 +1,178 @@
+package recording
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// record 写入 n 条数据记录，close 为 false 时模拟写入过程中崩溃 (不写最终索引)
+func record(t *testing.T, n int, close bool) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "session.smrec")
+	w, err := Create(path, Options{IndexEvery: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Unix(1700000000, 0)
+	for i := 0; i < n; i++ {
+		if err := w.Write(KindRx, start.Add(time.Duration(i)*time.Millisecond), []byte(fmt.Sprintf("line %03d\r\n", i))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if close {
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+	} else {
+		w.f.Close()
+	}
+	return path
+}
+
+func readAll(t *testing.T, data []byte) ([]Record, Integrity) {
+	t.Helper()
+	var recs []Record
+	in, err := Read(data, func(r Record) error {
+		recs = append(recs, r)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return recs, in
+}
+
+func TestRoundTrip(t *testing.T) {
+	path := record(t, 35, true)
+	data, _ := os.ReadFile(path)
+	recs, in := readAll(t, data)
+	if !in.OK || in.Records != 35 || in.Expected != 35 || len(recs) != 35 {
+		t.Fatalf("Read() = %+v, %d records", in, len(recs))
+	}
+	if r := recs[7]; r.Kind != KindRx || string(r.Data) != "line 007\r\n" || r.Time.UnixMilli() != 1700000000007 {
+		t.Errorf("record 7 = %+v", r)
+	}
+	if in.Summary() != "recording intact: 35 records" {
+		t.Errorf("Summary() = %q", in.Summary())
+	}
+}
+
+func TestTruncatedTail(t *testing.T) {
+	// 崩溃时最后一条记录只写了一半
+	data, _ := os.ReadFile(record(t, 35, false))
+	data = data[:len(data)-5]
+	recs, in := readAll(t, data)
+	if in.OK || in.Complete || !in.Truncated || in.Records != 34 || len(recs) != 34 {
+		t.Fatalf("Read() = %+v", in)
+	}
+	// 周期索引在第 30 条之后，之后的记录仍然恢复，没有丢失
+	if in.Expected != 30 || in.Lost != 0 || in.Corrupt != 0 {
+		t.Errorf("Read() = %+v", in)
+	}
+}
+
+func TestBitFlipMidFile(t *testing.T) {
+	data, _ := os.ReadFile(record(t, 35, true))
+	// 翻转第 5 条记录负载中的一位
+	i := bytes.Index(data, []byte("line 004"))
+	data[i+6] ^= 0x01
+	recs, in := readAll(t, data)
+	if in.OK || in.Corrupt != 1 || in.Truncated || !in.Complete {
+		t.Fatalf("Read() = %+v", in)
+	}
+	if in.Records != 34 || in.Expected != 35 || in.Lost != 1 {
+		t.Errorf("Read() = %+v", in)
+	}
+	if string(recs[4].Data) != "line 005\r\n" {
+		t.Errorf("record after the corrupt one = %q", recs[4].Data)
+	}
+}
+
+func TestCorruptLength(t *testing.T) {
+	data, _ := os.ReadFile(record(t, 20, true))
+	// 长度字段损坏 (指向文件末尾之后)：跳过该记录并重新同步到下一条
+	i := bytes.Index(data, []byte("line 010")) - bodyHead - lenLen
+	data[i+3] = 0x7F
+	recs, in := readAll(t, data)
+	if in.Corrupt != 1 || in.Records != 19 || in.Lost != 1 || string(recs[10].Data) != "line 011\r\n" {
+		t.Fatalf("Read() = %+v", in)
+	}
+}
+
+func TestTruncateEverywhere(t *testing.T) {
+	// 在任意位置截断都不会出错，恢复的记录数单调不减
+	data, _ := os.ReadFile(record(t, 12, true))
+	prev := 0
+	for n := len(Magic); n <= len(data); n++ {
+		in, err := Read(data[:n], nil)
+		if err != nil {
+			t.Fatalf("Read(%d bytes): %v", n, err)
+		}
+		if in.Records < prev || in.Corrupt != 0 {
+			t.Fatalf("Read(%d bytes) = %+v after %d records", n, in, prev)
+		}
+		prev = in.Records
+	}
+	if prev != 12 {
+		t.Errorf("recovered %d records from the full file", prev)
+	}
+}
+
+func TestNotRecording(t *testing.T) {
+	if _, err := Read([]byte("hello world"), nil); !errors.Is(err, ErrNotRecording) {
+		t.Errorf("Read() error = %v", err)
+	}
+}
+
+type syncCounter struct {
+	bytes.Buffer
+	syncs int
+}
+
+func (s *syncCounter) Sync() error  { s.syncs++; return nil }
+func (s *syncCounter) Close() error { return nil }
+
+func TestSyncCadence(t *testing.T) {
+	f := &syncCounter{}
+	w, err := NewWriter(f, Options{SyncInterval: -1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		w.Write(KindTx, time.Now(), []byte("x"))
+	}
+	if f.syncs != 4 { // 文件头一次，每条记录一次
+		t.Errorf("%d syncs, want 4", f.syncs)
+	}
+
+	f = &syncCounter{}
+	w, _ = NewWriter(f, Options{SyncInterval: time.Hour})
+	for i := 0; i < 3; i++ {
+		w.Write(KindRx, time.Now(), []byte("x"))
+	}
+	w.Close()
+	if f.syncs != 2 { // 文件头与关闭
+		t.Errorf("%d syncs, want 2", f.syncs)
+	}
+	if err := w.Write(KindRx, time.Now(), nil); err == nil {
+		t.Error("Expected error writing after Close")
+	}
+}
+
+func TestSplitLargeRecord(t *testing.T) {
+	f := &syncCounter{}
+	w, _ := NewWriter(f, Options{})
+	if err := w.Write(KindTx, time.Now(), make([]byte, MaxPayload*2+1)); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+	recs, in := readAll(t, f.Bytes())
+	if !in.OK || len(recs) != 3 || len(recs[2].Data) != 1 {
+		t.Errorf("Read() = %+v, %d records", in, len(recs))
+	}
+}
@@ -0,0 +1,175 @@
+// Package rxcheck 分析一段接收数据的样本，推测显示乱码的可能原因 (波特率不匹配、数据位错误、
+// UTF-16 文本或二进制协议)
+//
+// 只做启发式判断：结果用于给出排查建议，不保证正确
+package rxcheck
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"serial-assistant/pkg/textenc"
+)
+
+// MaxSample Analyze 只检查样本开头的字节数
+const MaxSample = 64 << 10
+
+// Cause 推测的原因
+type Cause string
+
+const (
+	CauseNoData   Cause = "no-data"       // 采样期间没有收到数据
+	CauseText     Cause = "text"          // 数据看起来是正常的文本
+	CauseBaud     Cause = "baud-mismatch" // 波特率不匹配
+	CauseDataBits Cause = "data-bits"     // 7 位数据 (带校验位) 按 8 位接收
+	CauseUTF16    Cause = "utf16"         // UTF-16 文本
+	CauseBinary   Cause = "binary"        // 二进制协议
+)
+
+// 判定阈值
+const (
+	textRatio    = 0.9  // 可打印比例达到该值视为文本
+	binaryRatio  = 0.7  // 可打印比例低于该值视为非文本
+	framingRatio = 0.3  // 帧错误形态的字节达到该比例视为波特率不匹配
+	noiseHighBit = 0.35 // 非文本数据中最高位置位的比例达到该值且几乎没有 NUL 时视为波特率不匹配
+	noiseNul     = 0.05
+)
+
+// Report 分析结果，比例均为 0-1
+type Report struct {
+	Bytes     int     `json:"bytes"`     // 分析的字节数
+	Printable float64 `json:"printable"` // 可打印 ASCII、常见空白与有效 UTF-8 多字节字符所占的比例
+	HighBit   float64 `json:"highBit"`   // 最高位置位的字节比例
+	Nul       float64 `json:"nul"`       // 0x00 字节的比例
+	// Framing 形如 0x00、0xFF、0xF0、0x80、0x0F 的字节 (连续的 1 与连续的 0 各占一端) 所占比例，
+	// 接收端波特率高于发送端时，每个发送位被拆成多个接收位，帧错误产生的字节多为这种形态
+	Framing        float64  `json:"framing"`
+	FramingPattern bool     `json:"framingPattern"`        // Framing 达到判定阈值
+	Terminators    []string `json:"terminators,omitempty"` // 出现的行结束符 ("CRLF"、"LF"、"CR")，按出现次数从多到少
+	Cause          Cause    `json:"cause"`
+	Suggestion     string   `json:"suggestion"`
+}
+
+// Analyze 分析接收数据的样本
+func Analyze(sample []byte) Report {
+	if len(sample) > MaxSample {
+		sample = sample[:MaxSample]
+	}
+	r := Report{Bytes: len(sample)}
+	if len(sample) == 0 {
+		r.Cause = CauseNoData
+		r.Suggestion = "no data was received while sampling — check the wiring, the port and that the device is sending"
+		return r
+	}
+
+	n := float64(len(sample))
+	var highBit, nul, framing, masked int
+	for _, b := range sample {
+		if b&0x80 != 0 {
+			highBit++
+		}
+		if b == 0 {
+			nul++
+		}
+		if framingShape(b) {
+			framing++
+		}
+		if printableASCII(b & 0x7F) {
+			masked++
+		}
+	}
+	r.Printable = float64(printableCount(sample)) / n
+	r.HighBit = float64(highBit) / n
+	r.Nul = float64(nul) / n
+	r.Framing = float64(framing) / n
+	r.FramingPattern = r.Framing >= framingRatio && r.Printable < binaryRatio
+	r.Terminators = terminators(sample)
+
+	switch mode, _ := textenc.Detect(sample); {
+	case mode != textenc.Raw:
+		r.Cause = CauseUTF16
+		r.Suggestion = "looks like UTF-16 text — set the RX encoding to auto or " + string(mode)
+	case r.Printable >= textRatio:
+		r.Cause = CauseText
+		r.Suggestion = "data looks like valid text"
+		if len(r.Terminators) == 0 {
+			r.Suggestion += " without line terminators — lines may run together"
+		}
+	case highBit > 0 && float64(masked)/n >= textRatio:
+		// 去掉最高位后是文本：最高位是校验位
+		r.Cause = CauseDataBits
+		r.Suggestion = "looks like 7-bit data with parity read as 8 bits — set data bits to 7 (e.g. 7E1) or enable 7-bit masking"
+	case r.FramingPattern, r.Printable < binaryRatio && r.HighBit >= noiseHighBit && r.Nul < noiseNul:
+		r.Cause = CauseBaud
+		r.Suggestion = "looks like a baud rate mismatch — check the device's baud rate and try a standard rate such as 115200"
+	default:
+		r.Cause = CauseBinary
+		r.Suggestion = "looks like a binary protocol — switch to hex view"
+	}
+	return r
+}
+
+// printableASCII 可打印 ASCII 或制表符、回车、换行
+func printableASCII(b byte) bool {
+	return b >= 0x20 && b < 0x7F || b == '\t' || b == '\r' || b == '\n'
+}
+
+// printableCount 统计可打印的字节数，有效 UTF-8 多字节字符的所有字节都计入
+func printableCount(data []byte) int {
+	count := 0
+	for len(data) > 0 {
+		if data[0] < utf8.RuneSelf {
+			if printableASCII(data[0]) {
+				count++
+			}
+			data = data[1:]
+			continue
+		}
+		r, size := utf8.DecodeRune(data)
+		if r != utf8.RuneError && size > 1 {
+			count += size
+		}
+		data = data[size:]
+	}
+	return count
+}
+
+// framingShape 字节的各位为连续的 1 接连续的 0 (如 0xF0、0x80) 或相反 (如 0x0F、0x01)，包括 0x00 与 0xFF
+func framingShape(b byte) bool {
+	return highRun(b) || highRun(^b)
+}
+
+// highRun b 为 1..10..0 形态：取反后为 0..01..1，加 1 后为 2 的幂 (或溢出为 0)
+func highRun(b byte) bool {
+	inv := ^b
+	return inv&(inv+1) == 0
+}
+
+// terminators 统计行结束符，按出现次数从多到少返回
+func terminators(data []byte) []string {
+	s := string(data)
+	crlf := strings.Count(s, "\r\n")
+	counts := []struct {
+		name string
+		n    int
+	}{
+		{"CRLF", crlf},
+		{"LF", strings.Count(s, "\n") - crlf},
+		{"CR", strings.Count(s, "\r") - crlf},
+	}
+	var out []string
+	for len(counts) > 0 {
+		best := 0
+		for i, c := range counts {
+			if c.n > counts[best].n {
+				best = i
+			}
+		}
+		if counts[best].n == 0 {
+			break
+		}
+		out = append(out, counts[best].name)
+		counts = append(counts[:best], counts[best+1:]...)
+	}
+	return out
+}
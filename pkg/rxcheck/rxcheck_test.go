@@ -0,0 +1,141 @@
+package rxcheck
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math/bits"
+	"strings"
+	"testing"
+	"unicode/utf16"
+)
+
+// capture 模拟波特率不匹配：按 txBaud 以 8N1 发送 data，接收端按 rxBaud 在起始位下降沿后
+// 于每位中点采样，得到的字节即接收端实际收到的数据 (帧错误的字节照常交给上层)
+func capture(data []byte, txBaud, rxBaud int) []byte {
+	line := []bool{true, true}
+	for _, b := range data {
+		line = append(line, false)
+		for i := 0; i < 8; i++ {
+			line = append(line, b>>i&1 == 1)
+		}
+		line = append(line, true)
+	}
+	level := func(t float64) bool {
+		i := int(t * float64(txBaud))
+		return i >= len(line) || line[i]
+	}
+
+	end := float64(len(line)) / float64(txBaud)
+	bit := 1 / float64(rxBaud)
+	var out []byte
+	for t := 0.0; t < end; {
+		if level(t) {
+			t += bit / 16
+			continue
+		}
+		var b byte
+		for i := 0; i < 8; i++ {
+			if level(t + (1.5+float64(i))*bit) {
+				b |= 1 << i
+			}
+		}
+		out = append(out, b)
+		t += 9.5 * bit
+	}
+	return out
+}
+
+const sampleLog = "[00:01:23.456] INFO sensor: temperature=23.5C humidity=41% battery=3.71V\r\n" +
+	"[00:01:24.456] WARN link: retrying connection to gateway (attempt 2/5)\r\n" +
+	"[00:01:25.456] INFO sensor: temperature=23.6C humidity=41% battery=3.71V\r\n"
+
+// sevenE1 以 7E1 发送的文本按 8N1 接收：校验位落在最高位
+func sevenE1(s string) []byte {
+	out := []byte(s)
+	for i, b := range out {
+		if bits.OnesCount8(b)%2 == 1 {
+			out[i] = b | 0x80
+		}
+	}
+	return out
+}
+
+func utf16le(s string) []byte {
+	var out []byte
+	for _, u := range utf16.Encode([]rune(s)) {
+		out = binary.LittleEndian.AppendUint16(out, u)
+	}
+	return out
+}
+
+// telemetry 二进制遥测帧：帧头、序号、若干小整数与校验
+func telemetry() []byte {
+	var out []byte
+	for i := 0; i < 40; i++ {
+		out = append(out, 0xAA, 0x55, byte(i), 0x08)
+		out = binary.LittleEndian.AppendUint16(out, uint16(2350+i))
+		out = binary.LittleEndian.AppendUint16(out, 410)
+		out = binary.LittleEndian.AppendUint16(out, 3710)
+		out = append(out, 0x00, 0x01, byte(i*7))
+	}
+	return out
+}
+
+func TestAnalyze(t *testing.T) {
+	text := []byte(strings.Repeat(sampleLog, 4))
+	tests := []struct {
+		name   string
+		sample []byte
+		want   Cause
+	}{
+		{"empty", nil, CauseNoData},
+		{"matching baud", capture(text, 115200, 115200), CauseText},
+		{"utf-8 text", []byte(strings.Repeat("温度 23.5°C 湿度 41%\n", 20)), CauseText},
+		{"9600 read at 115200", capture(text, 9600, 115200), CauseBaud},
+		{"9600 read at 57600", capture(text, 9600, 57600), CauseBaud},
+		{"115200 read at 9600", capture(text, 115200, 9600), CauseBaud},
+		{"115200 read at 38400", capture(text, 115200, 38400), CauseBaud},
+		{"57600 read at 115200", capture(text, 57600, 115200), CauseBaud},
+		{"7E1 read as 8N1", sevenE1(string(text)), CauseDataBits},
+		{"utf-16le", utf16le(string(text)), CauseUTF16},
+		{"binary telemetry", telemetry(), CauseBinary},
+	}
+	for _, tt := range tests {
+		r := Analyze(tt.sample)
+		if r.Cause != tt.want {
+			t.Errorf("%s: cause %q, want %q (%+v)", tt.name, r.Cause, tt.want, r)
+		}
+		if r.Suggestion == "" {
+			t.Errorf("%s: no suggestion", tt.name)
+		}
+	}
+}
+
+func TestCaptureAtMatchingBaud(t *testing.T) {
+	if got := capture([]byte(sampleLog), 9600, 9600); !bytes.Equal(got, []byte(sampleLog)) {
+		t.Fatalf("capture() at matching baud = %q", got)
+	}
+}
+
+func TestTerminators(t *testing.T) {
+	r := Analyze([]byte("a\r\nb\r\nc\nd\r\n"))
+	if strings.Join(r.Terminators, ",") != "CRLF,LF" {
+		t.Errorf("Terminators = %v", r.Terminators)
+	}
+	if r := Analyze([]byte(strings.Repeat("no newline here ", 10))); len(r.Terminators) != 0 || !strings.Contains(r.Suggestion, "without line terminators") {
+		t.Errorf("Analyze() = %+v", r)
+	}
+}
+
+func TestFramingShape(t *testing.T) {
+	for _, b := range []byte{0x00, 0xFF, 0x80, 0xF0, 0xFE, 0x01, 0x0F, 0x7F} {
+		if !framingShape(b) {
+			t.Errorf("framingShape(0x%02X) = false", b)
+		}
+	}
+	for _, b := range []byte{'A', 0x55, 0xAA, 0x81, 0x18} {
+		if framingShape(b) {
+			t.Errorf("framingShape(0x%02X) = true", b)
+		}
+	}
+}
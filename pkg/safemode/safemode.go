@@ -0,0 +1,210 @@
+// Package safemode 检测接收处理阶段 (分类规则、帧解码、JSON 解析等用户配置的阶段) 的崩溃，
+// 使反复崩溃的配置在下次启动时不再自动生效
+//
+// 状态文件记录 "处理链正常" 标志：应用处理阶段时清除，之后 HealthyAfter 内没有崩溃则重新设置。
+// 只有 Run 的 panic 恢复处理会写入崩溃记录 (阶段名、panic 信息与输入样本)；启动时标志已清除且有崩溃记录
+// 才进入安全模式。正常的强制退出只留下清除的标志而没有崩溃记录，不会误判。
+package safemode
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+const (
+	// HealthyAfter 应用处理阶段后没有崩溃多久视为正常
+	HealthyAfter = 10 * time.Second
+	// MaxSample 崩溃记录中保留的输入字节数
+	MaxSample = 256
+	// maxStack 崩溃记录中保留的调用栈长度
+	maxStack = 8192
+)
+
+// Crash 一次处理阶段崩溃的记录
+type Crash struct {
+	Stage  string    `json:"stage"`  // 崩溃的阶段，例如 "classifier-rules"
+	Panic  string    `json:"panic"`  // panic 的值
+	Sample string    `json:"sample"` // 触发崩溃的输入 (十六进制，最多 MaxSample 字节)
+	Size   int       `json:"size"`   // 输入的实际字节数
+	Stack  string    `json:"stack,omitempty"`
+	Time   time.Time `json:"time"`
+	// Stages 崩溃时生效的全部用户配置阶段
+	Stages []string `json:"stages,omitempty"`
+}
+
+func (c Crash) String() string {
+	return fmt.Sprintf("stage %s panicked: %s (input %d bytes: %s)", c.Stage, c.Panic, c.Size, c.Sample)
+}
+
+// state 状态文件的内容
+type state struct {
+	Healthy bool     `json:"healthy"`
+	Stages  []string `json:"stages,omitempty"` // 最近一次应用的阶段
+	Crash   *Crash   `json:"crash,omitempty"`
+}
+
+// Monitor 处理链的健康状态，线程安全
+type Monitor struct {
+	path  string // 为空时只在内存中记录
+	after time.Duration
+
+	mu      sync.Mutex
+	st      state
+	gen     uint64 // 每次 Applied 递增，过期的计时器不再设置标志
+	timer   *time.Timer
+	onCrash func(Crash, bool)
+	fired   bool // 本次应用后已通知过崩溃
+}
+
+// Open 读取状态文件，返回上一次运行中崩溃且之后没有恢复正常时的崩溃记录 (应进入安全模式)，否则为 nil；
+// after 为 0 时使用 HealthyAfter。文件不存在或无法解析时视为正常
+func Open(path string, after time.Duration) (*Monitor, *Crash) {
+	if after <= 0 {
+		after = HealthyAfter
+	}
+	m := &Monitor{path: path, after: after, st: state{Healthy: true}}
+	if path == "" {
+		return m, nil
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return m, nil
+	}
+	var st state
+	if json.Unmarshal(raw, &st) != nil {
+		return m, nil
+	}
+	m.st = st
+	if st.Healthy || st.Crash == nil {
+		return m, nil
+	}
+	c := *st.Crash
+	return m, &c
+}
+
+// OnCrash 设置 Run 恢复 panic 后的通知函数，每次崩溃都在新的 goroutine 中调用 (调用方可能持有锁)；
+// first 表示这是本次 Applied 之后的第一次崩溃，调用方据此停用处理阶段，之后的崩溃只需记录
+func (m *Monitor) OnCrash(fn func(c Crash, first bool)) {
+	m.mu.Lock()
+	m.onCrash = fn
+	m.mu.Unlock()
+}
+
+// Applied 记录应用了 stages (用户配置的阶段名)：清除正常标志与之前的崩溃记录，HealthyAfter 后没有崩溃则重新设置
+// stages 为空时不修改状态
+func (m *Monitor) Applied(stages []string) {
+	if len(stages) == 0 {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.gen++
+	gen := m.gen
+	m.fired = false
+	m.st = state{Stages: append([]string(nil), stages...)}
+	m.saveLocked()
+	if m.timer != nil {
+		m.timer.Stop()
+	}
+	m.timer = time.AfterFunc(m.after, func() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		if m.gen != gen || m.st.Crash != nil {
+			return
+		}
+		m.st.Healthy = true
+		m.saveLocked()
+	})
+}
+
+// Healthy 返回正常标志
+func (m *Monitor) Healthy() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.st.Healthy
+}
+
+// LastCrash 返回最近一次崩溃记录，之后已重新应用阶段时为 nil
+func (m *Monitor) LastCrash() *Crash {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.st.Crash == nil {
+		return nil
+	}
+	c := *m.st.Crash
+	return &c
+}
+
+// Run 执行阶段 stage，恢复其中的 panic：写入崩溃记录 (清除正常标志) 并通知 OnCrash，返回 false。
+// input 为该阶段的输入，作为样本记录
+func (m *Monitor) Run(stage string, input []byte, fn func()) (ok bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			m.crashed(stage, input, r, debug.Stack())
+			ok = false
+		}
+	}()
+	fn()
+	return true
+}
+
+func (m *Monitor) crashed(stage string, input []byte, r interface{}, stack []byte) {
+	sample := input
+	if len(sample) > MaxSample {
+		sample = sample[:MaxSample]
+	}
+	if len(stack) > maxStack {
+		stack = stack[:maxStack]
+	}
+	c := Crash{
+		Stage:  stage,
+		Panic:  fmt.Sprint(r),
+		Sample: hex.EncodeToString(sample),
+		Size:   len(input),
+		Stack:  string(stack),
+		Time:   time.Now(),
+	}
+
+	m.mu.Lock()
+	c.Stages = append([]string(nil), m.st.Stages...)
+	m.gen++ // 正在计时的正常标志作废
+	m.st.Healthy = false
+	m.st.Crash = &c
+	m.saveLocked()
+	fn := m.onCrash
+	first := !m.fired
+	m.fired = true
+	m.mu.Unlock()
+
+	if fn != nil {
+		go fn(c, first)
+	}
+}
+
+// saveLocked 原子写入状态文件，失败时只在内存中记录
+func (m *Monitor) saveLocked() {
+	if m.path == "" {
+		return
+	}
+	raw, err := json.MarshalIndent(m.st, "", "  ")
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(m.path), 0755); err != nil {
+		return
+	}
+	tmp := m.path + ".tmp"
+	if err := os.WriteFile(tmp, raw, 0644); err != nil {
+		return
+	}
+	if err := os.Rename(tmp, m.path); err != nil {
+		os.Remove(tmp)
+	}
+}
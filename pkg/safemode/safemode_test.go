@@ -0,0 +1,115 @@
+package safemode
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCrashEntersSafeModeOnNextStart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pipeline-health.json")
+	m, crash := Open(path, time.Hour)
+	if crash != nil || !m.Healthy() {
+		t.Fatalf("Fresh state: crash %v, healthy %v", crash, m.Healthy())
+	}
+
+	type notice struct {
+		c     Crash
+		first bool
+	}
+	notified := make(chan notice, 1)
+	m.OnCrash(func(c Crash, first bool) { notified <- notice{c, first} })
+	m.Applied([]string{"classifier-rules", "frame-decoder"})
+	ok := m.Run("frame-decoder", []byte{0xAA, 0x55, 0xFF}, func() {
+		var table []int
+		_ = table[3]
+	})
+	if ok {
+		t.Fatal("Run should report the panic")
+	}
+	select {
+	case n := <-notified:
+		c := n.c
+		if !n.first || c.Stage != "frame-decoder" || c.Sample != "aa55ff" || c.Size != 3 || !strings.Contains(c.Panic, "index out of range") {
+			t.Errorf("Crash = %+v (first %v)", c, n.first)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("OnCrash not called")
+	}
+
+	// 同一次应用后的崩溃照常通知，但不再是第一次
+	m.Run("frame-decoder", nil, func() { panic("again") })
+	select {
+	case n := <-notified:
+		if n.first || n.c.Panic != "again" {
+			t.Errorf("Second crash = %+v (first %v)", n.c, n.first)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("OnCrash not called for the second crash")
+	}
+
+	_, crash = Open(path, time.Hour)
+	if crash == nil || crash.Stage != "frame-decoder" || len(crash.Stages) != 2 || crash.Stack == "" {
+		t.Fatalf("Expected the crash to be reported at the next start, got %+v", crash)
+	}
+}
+
+func TestForceQuitIsNotACrash(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pipeline-health.json")
+	m, _ := Open(path, time.Hour)
+	m.Applied([]string{"classifier-rules"})
+	// 进程在 HealthyAfter 之前被强制结束：标志已清除但没有崩溃记录
+	m2, crash := Open(path, time.Hour)
+	if crash != nil {
+		t.Errorf("Force quit reported as crash: %+v", crash)
+	}
+	if m2.Healthy() {
+		t.Error("Flag should still be cleared")
+	}
+}
+
+func TestHealthyAfterQuietPeriod(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pipeline-health.json")
+	m, _ := Open(path, 20*time.Millisecond)
+	m.Run("plot-parser", []byte("x"), func() { panic("boom") })
+	if m.LastCrash() == nil {
+		t.Fatal("Expected a crash record")
+	}
+
+	// 重新应用后正常运行一段时间，崩溃记录与标志都恢复
+	m.Applied([]string{"plot-parser"})
+	if m.Healthy() || m.LastCrash() != nil {
+		t.Error("Applied should clear the flag and the old crash")
+	}
+	deadline := time.Now().Add(time.Second)
+	for !m.Healthy() && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if !m.Healthy() {
+		t.Fatal("Flag not set after the quiet period")
+	}
+	if _, crash := Open(path, 0); crash != nil {
+		t.Errorf("Healthy state reported crash %+v", crash)
+	}
+
+	// 计时期间崩溃，不会被设置为正常
+	m.Applied([]string{"plot-parser"})
+	m.Run("plot-parser", nil, func() { panic("boom") })
+	time.Sleep(50 * time.Millisecond)
+	if m.Healthy() {
+		t.Error("A crash during the quiet period must keep the flag cleared")
+	}
+}
+
+func TestSampleTruncated(t *testing.T) {
+	m, _ := Open("", 0)
+	m.Run("json-lines", make([]byte, MaxSample*2), func() { panic("x") })
+	c := m.LastCrash()
+	if c == nil || len(c.Sample) != MaxSample*2 || c.Size != MaxSample*2 {
+		t.Errorf("Crash = %+v", c)
+	}
+	if m.Run("json-lines", nil, func() {}) != true {
+		t.Error("Run without panic should succeed")
+	}
+}
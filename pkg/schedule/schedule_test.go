@@ -0,0 +1,181 @@
+package schedule
+
+import (
+	"sync"
+	"testing"
+	"time"
+	_ "time/tzdata"
+)
+
+func TestParse(t *testing.T) {
+	valid := []struct {
+		expr     string
+		expected string
+	}{
+		{"02:00", "02:00:00"},
+		{"04:00, 02:00,02:00", "02:00:00,04:00:00"},
+		{"23:59:30", "23:59:30"},
+		{"@every 30m", "@every 30m0s"},
+		{"@every  1s", "@every 1s"},
+	}
+	for _, tt := range valid {
+		spec, err := Parse(tt.expr)
+		if err != nil {
+			t.Errorf("Parse(%q): unexpected error: %v", tt.expr, err)
+			continue
+		}
+		if spec.String() != tt.expected {
+			t.Errorf("Parse(%q) = %q, expected %q", tt.expr, spec.String(), tt.expected)
+		}
+	}
+
+	invalid := []string{"", "24:00", "12:60", "12", "1:2:3:4", "ab:cd", "12:00,", "@every", "@every 500ms", "@every soon", "0 2 * * *"}
+	for _, expr := range invalid {
+		if _, err := Parse(expr); err == nil {
+			t.Errorf("Parse(%q): expected error", expr)
+		}
+	}
+}
+
+func TestNextDaily(t *testing.T) {
+	spec, _ := Parse("02:00,04:00")
+	loc := time.UTC
+
+	tests := []struct {
+		after, expected time.Time
+	}{
+		{time.Date(2024, 5, 1, 0, 0, 0, 0, loc), time.Date(2024, 5, 1, 2, 0, 0, 0, loc)},
+		{time.Date(2024, 5, 1, 2, 0, 0, 0, loc), time.Date(2024, 5, 1, 4, 0, 0, 0, loc)},
+		{time.Date(2024, 5, 1, 4, 0, 0, 0, loc), time.Date(2024, 5, 2, 2, 0, 0, 0, loc)},
+		{time.Date(2024, 12, 31, 23, 0, 0, 0, loc), time.Date(2025, 1, 1, 2, 0, 0, 0, loc)},
+	}
+	for _, tt := range tests {
+		if got := spec.Next(tt.after); !got.Equal(tt.expected) {
+			t.Errorf("Next(%v) = %v, expected %v", tt.after, got, tt.expected)
+		}
+	}
+}
+
+func TestNextInterval(t *testing.T) {
+	spec, _ := Parse("@every 90s")
+	after := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	if got := spec.Next(after); !got.Equal(after.Add(90 * time.Second)) {
+		t.Errorf("Unexpected next fire %v", got)
+	}
+}
+
+// TestNextDST 夏令时切换当天：跳过的时刻顺延，重复的时刻只触发一次，且始终向前推进
+func TestNextDST(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatalf("LoadLocation: %v", err)
+	}
+	spec, _ := Parse("01:30,02:30")
+
+	// 2024-03-10 02:00 EST 跳到 03:00 EDT，02:30 不存在
+	next := spec.Next(time.Date(2024, 3, 10, 1, 45, 0, 0, loc))
+	if next.Hour() != 3 || next.Minute() != 30 || next.Day() != 10 {
+		t.Errorf("Expected skipped 02:30 to fire at 03:30, got %v", next)
+	}
+
+	// 2024-11-03 02:00 EDT 回到 01:00 EST，01:30 出现两次
+	after := time.Date(2024, 11, 3, 0, 0, 0, 0, loc)
+	var fires []time.Time
+	for i := 0; i < 4; i++ {
+		next := spec.Next(after)
+		if !next.After(after) {
+			t.Fatalf("Next(%v) = %v did not advance", after, next)
+		}
+		fires = append(fires, next)
+		after = next
+	}
+	if fires[0].Hour() != 1 || fires[1].Hour() != 2 || fires[2].Day() != 4 {
+		t.Errorf("Unexpected fall-back firings: %v", fires)
+	}
+
+	// 逐小时遍历整年，确保不会停滞或返回零值
+	spec, _ = Parse("00:00,01:59:59,02:00,02:30,03:00,23:59:59")
+	for tm := time.Date(2024, 1, 1, 0, 0, 0, 0, loc); tm.Year() == 2024; tm = tm.Add(time.Hour) {
+		next := spec.Next(tm)
+		if !next.After(tm) || next.Sub(tm) > 25*time.Hour {
+			t.Fatalf("Next(%v) = %v", tm, next)
+		}
+	}
+}
+
+func TestSchedulerTakeDue(t *testing.T) {
+	now := time.Date(2024, 5, 1, 1, 59, 0, 0, time.UTC)
+	s := New(func(Job, time.Time) {})
+	s.now = func() time.Time { return now }
+
+	if err := s.Add(Job{ID: "b", Expr: "02:00"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Add(Job{ID: "a", Expr: "02:00"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Add(Job{ID: "", Expr: "02:00"}); err == nil {
+		t.Error("Expected error for empty id")
+	}
+	if err := s.Add(Job{ID: "bad", Expr: "25:00"}); err == nil {
+		t.Error("Expected error for invalid expression")
+	}
+
+	due, wait := s.takeDue()
+	if len(due) != 0 || wait != time.Minute {
+		t.Errorf("Expected nothing due and 1m wait, got %d due, %v", len(due), wait)
+	}
+
+	// 系统休眠跨过多个触发点，只补发一次
+	now = now.Add(72 * time.Hour)
+	due, _ = s.takeDue()
+	if len(due) != 2 || due[0].ID != "a" || due[1].ID != "b" {
+		t.Fatalf("Expected a and b due in ID order, got %+v", due)
+	}
+	if due, _ = s.takeDue(); len(due) != 0 {
+		t.Errorf("Expected no repeated firing, got %+v", due)
+	}
+
+	list := s.List()
+	if len(list) != 2 || !list[0].Next.Equal(time.Date(2024, 5, 4, 2, 0, 0, 0, time.UTC)) {
+		t.Errorf("Unexpected list %+v", list)
+	}
+	if !s.Remove("a") || s.Remove("a") {
+		t.Error("Expected Remove to report existence")
+	}
+	if len(s.List()) != 1 {
+		t.Error("Expected one job after removal")
+	}
+}
+
+func TestSchedulerRun(t *testing.T) {
+	var mu sync.Mutex
+	var fired []string
+	done := make(chan struct{}, 1)
+
+	s := New(func(j Job, _ time.Time) {
+		mu.Lock()
+		fired = append(fired, j.ID)
+		mu.Unlock()
+		done <- struct{}{}
+	})
+	s.Start()
+	defer s.Stop()
+
+	// 启动后添加的任务需要唤醒后台 goroutine
+	if err := s.Add(Job{ID: "tick", Expr: "@every 1s", Data: "PING"}); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-done:
+	case <-time.After(3 * time.Second):
+		t.Fatal("Job did not fire")
+	}
+	s.Stop()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(fired) != 1 || fired[0] != "tick" {
+		t.Errorf("Unexpected firings %v", fired)
+	}
+}
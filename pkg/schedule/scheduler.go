@@ -0,0 +1,180 @@
+package schedule
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Job 一个定时任务，Data 由调用方解释 (例如待发送的数据)
+type Job struct {
+	ID   string
+	Expr string
+	Data interface{}
+}
+
+// Entry Job 及其下一次触发时间
+type Entry struct {
+	Job
+	Next time.Time
+}
+
+// Scheduler 在单个后台 goroutine 中按时间顺序触发任务，线程安全
+// fire 在 goroutine 中同步调用 (锁外)，同一时刻到期的任务按 ID 顺序触发
+type Scheduler struct {
+	fire func(job Job, scheduled time.Time)
+	now  func() time.Time
+
+	mu   sync.Mutex
+	jobs map[string]*job
+	wake chan struct{}
+	stop chan struct{}
+}
+
+type job struct {
+	Job
+	spec Spec
+	next time.Time
+}
+
+// New 创建调度器，需调用 Start 开始运行
+func New(fire func(job Job, scheduled time.Time)) *Scheduler {
+	return &Scheduler{
+		fire: fire,
+		now:  time.Now,
+		jobs: make(map[string]*job),
+		wake: make(chan struct{}, 1),
+	}
+}
+
+// Add 添加任务，相同 ID 的任务被替换
+func (s *Scheduler) Add(j Job) error {
+	if j.ID == "" {
+		return fmt.Errorf("schedule id must not be empty")
+	}
+	spec, err := Parse(j.Expr)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.jobs[j.ID] = &job{Job: j, spec: spec, next: spec.Next(s.now())}
+	s.mu.Unlock()
+	s.notify()
+	return nil
+}
+
+// Remove 删除任务，返回任务是否存在
+func (s *Scheduler) Remove(id string) bool {
+	s.mu.Lock()
+	_, ok := s.jobs[id]
+	delete(s.jobs, id)
+	s.mu.Unlock()
+
+	if ok {
+		s.notify()
+	}
+	return ok
+}
+
+// List 返回所有任务，按下一次触发时间排序
+func (s *Scheduler) List() []Entry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	out := make([]Entry, 0, len(s.jobs))
+	for _, j := range s.jobs {
+		out = append(out, Entry{Job: j.Job, Next: j.next})
+	}
+	sort.Slice(out, func(i, k int) bool {
+		if !out[i].Next.Equal(out[k].Next) {
+			return out[i].Next.Before(out[k].Next)
+		}
+		return out[i].ID < out[k].ID
+	})
+	return out
+}
+
+// Start 启动后台 goroutine，可重复调用
+func (s *Scheduler) Start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.stop != nil {
+		return
+	}
+	s.stop = make(chan struct{})
+	go s.run(s.stop)
+}
+
+// Stop 停止后台 goroutine，可重复调用；正在执行的 fire 会执行完毕
+func (s *Scheduler) Stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.stop != nil {
+		close(s.stop)
+		s.stop = nil
+	}
+}
+
+func (s *Scheduler) notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (s *Scheduler) run(stop chan struct{}) {
+	for {
+		due, wait := s.takeDue()
+		for _, e := range due {
+			s.fire(e.Job, e.Next)
+		}
+
+		// 没有任务时只等待唤醒；定时器最长一分钟，以便系统时间跳变后重新计算
+		if wait < 0 || wait > time.Minute {
+			wait = time.Minute
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-stop:
+			timer.Stop()
+			return
+		case <-s.wake:
+			timer.Stop()
+		case <-timer.C:
+		}
+	}
+}
+
+// takeDue 取出已到期的任务并计算各自的下一次触发时间
+// 返回距最早的未到期任务的等待时间，没有任务时返回 -1
+// 错过的多次触发 (例如系统休眠) 只补发一次
+func (s *Scheduler) takeDue() ([]Entry, time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	var due []Entry
+	var earliest time.Time
+	for _, j := range s.jobs {
+		if j.next.IsZero() {
+			continue
+		}
+		if !j.next.After(now) {
+			due = append(due, Entry{Job: j.Job, Next: j.next})
+			j.next = j.spec.Next(now)
+		}
+		if earliest.IsZero() || j.next.Before(earliest) {
+			earliest = j.next
+		}
+	}
+	sort.Slice(due, func(i, k int) bool { return due[i].ID < due[k].ID })
+
+	if earliest.IsZero() {
+		return due, -1
+	}
+	return due, earliest.Sub(now)
+}
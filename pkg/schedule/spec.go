@@ -0,0 +1,131 @@
+// Package schedule 实现按绝对时间或固定间隔触发的定时任务
+//
+// 支持的表达式 (cron 的最小子集)：
+//
+//	HH:MM[:SS][,HH:MM[:SS]...]  每天在本地时间的这些时刻触发，例如 "02:00,04:00"
+//	@every DURATION             固定间隔触发，例如 "@every 30m"，间隔不小于 MinInterval
+package schedule
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MinInterval 间隔任务的最小间隔
+const MinInterval = time.Second
+
+// TimeOfDay 一天中的时刻 (本地时间)
+type TimeOfDay struct {
+	Hour, Minute, Second int
+}
+
+func (t TimeOfDay) String() string {
+	return fmt.Sprintf("%02d:%02d:%02d", t.Hour, t.Minute, t.Second)
+}
+
+// Spec 解析后的触发规则，Every 与 Times 二者之一有效
+type Spec struct {
+	Every time.Duration
+	Times []TimeOfDay // 已排序、去重
+}
+
+// Parse 解析触发表达式
+func Parse(expr string) (Spec, error) {
+	expr = strings.TrimSpace(expr)
+	if expr == "" {
+		return Spec{}, fmt.Errorf("empty schedule")
+	}
+
+	if rest, ok := strings.CutPrefix(expr, "@every"); ok {
+		d, err := time.ParseDuration(strings.TrimSpace(rest))
+		if err != nil {
+			return Spec{}, fmt.Errorf("invalid interval %q: %w", strings.TrimSpace(rest), err)
+		}
+		if d < MinInterval {
+			return Spec{}, fmt.Errorf("interval %v is shorter than %v", d, MinInterval)
+		}
+		return Spec{Every: d}, nil
+	}
+
+	var spec Spec
+	seen := make(map[TimeOfDay]bool)
+	for _, part := range strings.Split(expr, ",") {
+		t, err := parseTimeOfDay(strings.TrimSpace(part))
+		if err != nil {
+			return Spec{}, err
+		}
+		if !seen[t] {
+			seen[t] = true
+			spec.Times = append(spec.Times, t)
+		}
+	}
+	sort.Slice(spec.Times, func(i, j int) bool {
+		a, b := spec.Times[i], spec.Times[j]
+		return a.Hour*3600+a.Minute*60+a.Second < b.Hour*3600+b.Minute*60+b.Second
+	})
+	return spec, nil
+}
+
+func parseTimeOfDay(s string) (TimeOfDay, error) {
+	fields := strings.Split(s, ":")
+	if len(fields) != 2 && len(fields) != 3 {
+		return TimeOfDay{}, fmt.Errorf("invalid time of day %q (expected HH:MM or HH:MM:SS)", s)
+	}
+
+	limits := []int{23, 59, 59}
+	var v [3]int
+	for i, f := range fields {
+		n, err := strconv.Atoi(f)
+		if err != nil || len(f) > 2 || n < 0 || n > limits[i] {
+			return TimeOfDay{}, fmt.Errorf("invalid time of day %q", s)
+		}
+		v[i] = n
+	}
+	return TimeOfDay{v[0], v[1], v[2]}, nil
+}
+
+// Next 返回 after 之后 (不含) 的下一次触发时间
+// 每日时刻按 after 所在时区计算：夏令时跳过的时刻顺延 (例如 02:30 变为 03:30)，
+// 重复的时刻只触发一次
+func (s Spec) Next(after time.Time) time.Time {
+	if s.Every > 0 {
+		return after.Add(s.Every)
+	}
+
+	loc := after.Location()
+	y, m, d := after.Date()
+	// 相邻两天的最早时刻至少相隔约 23 小时，检查三天足以覆盖夏令时切换
+	for day := 0; day < 3; day++ {
+		for _, t := range s.Times {
+			next := time.Date(y, m, d+day, t.Hour, t.Minute, t.Second, 0, loc)
+			if h, mi, sec := next.Clock(); h != t.Hour || mi != t.Minute || sec != t.Second {
+				// 时刻落在夏令时跳过的区间内，time.Date 可能将其归到切换之前，按差值顺延
+				want := time.Duration(t.Hour*3600+t.Minute*60+t.Second) * time.Second
+				got := time.Duration(h*3600+mi*60+sec) * time.Second
+				if diff := want - got; diff > 0 {
+					next = next.Add(diff)
+				}
+			}
+			if next.After(after) {
+				return next
+			}
+		}
+	}
+	// Times 为空的 Spec 不会触发
+	return time.Time{}
+}
+
+// String 返回规范化的表达式
+func (s Spec) String() string {
+	if s.Every > 0 {
+		return "@every " + s.Every.String()
+	}
+	parts := make([]string, len(s.Times))
+	for i, t := range s.Times {
+		parts[i] = t.String()
+	}
+	return strings.Join(parts, ",")
+}
@@ -0,0 +1,189 @@
+package serialport
+
+import (
+	"fmt"
+	"time"
+
+	"go.bug.st/serial"
+)
+
+// ParseMode 由界面参数构造串口参数：stopBits 为 1、15 (1.5) 或 2，parity 为 "None"、"Odd"、"Even"、"Mark" 或 "Space"
+// 无法识别的停止位与校验按 1 位、无校验处理 (与旧版行为一致)
+func ParseMode(baudRate, dataBits, stopBits int, parity string) *serial.Mode {
+	mode := &serial.Mode{BaudRate: baudRate, DataBits: dataBits}
+	switch parity {
+	case "Odd":
+		mode.Parity = serial.OddParity
+	case "Even":
+		mode.Parity = serial.EvenParity
+	case "Mark":
+		mode.Parity = serial.MarkParity
+	case "Space":
+		mode.Parity = serial.SpaceParity
+	default:
+		mode.Parity = serial.NoParity
+	}
+	switch stopBits {
+	case 15:
+		mode.StopBits = serial.OnePointFiveStopBits
+	case 2:
+		mode.StopBits = serial.TwoStopBits
+	default:
+		mode.StopBits = serial.OneStopBit
+	}
+	return mode
+}
+
+// FrameBits 返回传输一个字节占用的位数：起始位 + 数据位 + 校验位 + 停止位
+// DataBits 为 0 时按 8 位计算
+func FrameBits(mode serial.Mode) float64 {
+	bits := float64(mode.DataBits)
+	if bits == 0 {
+		bits = 8
+	}
+	bits++ // 起始位
+	if mode.Parity != serial.NoParity {
+		bits++
+	}
+	switch mode.StopBits {
+	case serial.OnePointFiveStopBits:
+		bits += 1.5
+	case serial.TwoStopBits:
+		bits += 2
+	default:
+		bits++
+	}
+	return bits
+}
+
+// Capacity 返回线路的理论最大吞吐量 (字节/秒)，帧之间没有空闲位
+func Capacity(mode serial.Mode) float64 {
+	if mode.BaudRate <= 0 {
+		return 0
+	}
+	return float64(mode.BaudRate) / FrameBits(mode)
+}
+
+// 发送需求的统计参数
+const (
+	// OvercommitRatio 持续需求超过线路容量的该比例时视为超额
+	OvercommitRatio = 0.9
+	// demandWindow 计算需求速率的滑动窗口
+	demandWindow = 2 * time.Second
+	// minSustained 窗口内的发送至少跨越该时长才算持续需求，单次大块发送不会触发
+	minSustained = time.Second
+	// reportInterval 超额后 TxReport 的最小间隔
+	reportInterval = time.Second
+)
+
+// TxReport 发送需求与线路容量的对比
+type TxReport struct {
+	CapacityBps float64 `json:"capacityBps"` // 线路容量 (字节/秒)
+	DemandBps   float64 `json:"demandBps"`   // 最近窗口内写入的速率 (字节/秒)
+	Utilization float64 `json:"utilization"` // DemandBps / CapacityBps
+	BacklogB    int     `json:"backlogB"`    // 估计尚未发出的字节数 (驱动与设备缓冲中排队)
+	BacklogMs   int64   `json:"backlogMs"`   // 按线路容量发完积压需要的时间
+}
+
+// String 返回用于提示的单行说明
+func (r TxReport) String() string {
+	return fmt.Sprintf("send rate %.0f B/s is %.0f%% of the %.0f B/s the line can carry; about %d bytes (%d ms) are queued",
+		r.DemandBps, r.Utilization*100, r.CapacityBps, r.BacklogB, r.BacklogMs)
+}
+
+type txSample struct {
+	at time.Time
+	n  int
+}
+
+// TxMonitor 按串口参数估算发送积压，检测持续超出线路容量的发送，非线程安全
+type TxMonitor struct {
+	capacity float64
+	samples  []txSample
+	backlog  float64
+	last     time.Time // 上一次更新积压的时间
+
+	warned     bool
+	lastReport time.Time
+}
+
+// NewTxMonitor 创建按 mode 计算容量的监视器
+func NewTxMonitor(mode serial.Mode) *TxMonitor {
+	return &TxMonitor{capacity: Capacity(mode)}
+}
+
+// SetMode 串口参数变化 (例如修改波特率) 后更新容量，已有的积压按新容量继续消耗
+func (m *TxMonitor) SetMode(mode serial.Mode) {
+	m.capacity = Capacity(mode)
+}
+
+// drain 按线路容量消耗积压
+func (m *TxMonitor) drain(now time.Time) {
+	if !m.last.IsZero() {
+		m.backlog -= m.capacity * now.Sub(m.last).Seconds()
+		if m.backlog < 0 {
+			m.backlog = 0
+		}
+	}
+	m.last = now
+}
+
+// Add 记录一次写入 n 字节
+// 持续需求首次超过容量的 OvercommitRatio 时 first 为 true；超额状态下每秒最多返回一次 report (ok 为 true)，
+// 需求回落后停止报告，再次超额时不重复 first
+func (m *TxMonitor) Add(now time.Time, n int) (report TxReport, ok, first bool) {
+	if m.capacity <= 0 {
+		return TxReport{}, false, false
+	}
+	m.drain(now)
+	m.backlog += float64(n)
+
+	m.samples = append(m.samples, txSample{at: now, n: n})
+	cut := 0
+	for cut < len(m.samples) && now.Sub(m.samples[cut].at) > demandWindow {
+		cut++
+	}
+	m.samples = m.samples[cut:]
+
+	report = m.Report(now)
+	span := now.Sub(m.samples[0].at)
+	if span < minSustained || report.Utilization < OvercommitRatio {
+		return report, false, false
+	}
+	first = !m.warned
+	m.warned = true
+	if !first && now.Sub(m.lastReport) < reportInterval {
+		return report, false, false
+	}
+	m.lastReport = now
+	return report, true, first
+}
+
+// Report 返回当前的需求与积压估计
+func (m *TxMonitor) Report(now time.Time) TxReport {
+	r := TxReport{CapacityBps: m.capacity}
+	if len(m.samples) > 0 {
+		total := 0
+		for _, s := range m.samples {
+			total += s.n
+		}
+		// 窗口未满时按实际跨度计算，至少按 minSustained 计以避免单次写入得出极大的速率
+		span := now.Sub(m.samples[0].at)
+		if span < minSustained {
+			span = minSustained
+		}
+		if span > demandWindow {
+			span = demandWindow
+		}
+		r.DemandBps = float64(total) / span.Seconds()
+	}
+	if m.capacity > 0 {
+		r.Utilization = r.DemandBps / m.capacity
+		backlog := m.backlog - m.capacity*now.Sub(m.last).Seconds()
+		if backlog > 0 {
+			r.BacklogB = int(backlog)
+			r.BacklogMs = int64(backlog / m.capacity * 1000)
+		}
+	}
+	return r
+}
@@ -0,0 +1,117 @@
+package serialport
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"go.bug.st/serial"
+)
+
+func TestCapacity(t *testing.T) {
+	tests := []struct {
+		baud, dataBits, stopBits int
+		parity                   string
+		bits, bps                float64
+	}{
+		{9600, 8, 1, "None", 10, 960},
+		{115200, 8, 1, "None", 10, 11520},
+		{9600, 8, 1, "Even", 11, 872.73},
+		{9600, 7, 2, "Odd", 11, 872.73},
+		{19200, 8, 2, "None", 11, 1745.45},
+		{4800, 5, 15, "None", 7.5, 640},
+		{1000000, 8, 1, "Mark", 11, 90909.09},
+		{300, 0, 1, "None", 10, 30}, // 数据位未设置时按 8 位
+	}
+	for _, tt := range tests {
+		mode := *ParseMode(tt.baud, tt.dataBits, tt.stopBits, tt.parity)
+		if got := FrameBits(mode); got != tt.bits {
+			t.Errorf("%d %d%s%d: FrameBits = %v, want %v", tt.baud, tt.dataBits, tt.parity, tt.stopBits, got, tt.bits)
+		}
+		if got := Capacity(mode); math.Abs(got-tt.bps) > 0.01 {
+			t.Errorf("%d %d%s%d: Capacity = %.2f, want %.2f", tt.baud, tt.dataBits, tt.parity, tt.stopBits, got, tt.bps)
+		}
+	}
+	if Capacity(serial.Mode{}) != 0 {
+		t.Error("zero baud rate should have no capacity")
+	}
+}
+
+// 9600 8N1 下每 10ms 发送 2KB：约 1 秒后报告一次超额，之后每秒最多报告一次，积压持续增长
+func TestTxMonitorOvercommit(t *testing.T) {
+	m := NewTxMonitor(*ParseMode(9600, 8, 1, "None"))
+	start := time.Unix(1000, 0)
+	var firsts, reports int
+	var lastBacklog int
+	for i := 0; i <= 300; i++ {
+		now := start.Add(time.Duration(i) * 10 * time.Millisecond)
+		r, ok, first := m.Add(now, 2048)
+		if first {
+			firsts++
+			if now.Sub(start) < time.Second {
+				t.Errorf("warned after only %v", now.Sub(start))
+			}
+			if r.Utilization < 100 {
+				t.Errorf("first report utilization = %.1f", r.Utilization)
+			}
+		}
+		if ok {
+			reports++
+			if r.BacklogB <= lastBacklog {
+				t.Errorf("backlog did not grow: %d -> %d", lastBacklog, r.BacklogB)
+			}
+			lastBacklog = r.BacklogB
+		}
+	}
+	if firsts != 1 {
+		t.Errorf("first = true %d times, want 1", firsts)
+	}
+	if reports < 2 || reports > 3 {
+		t.Errorf("%d reports in 3 seconds, want 2-3", reports)
+	}
+}
+
+func TestTxMonitorWithinCapacity(t *testing.T) {
+	// 115200 8N1 容量 11520 B/s，每 10ms 发送 100 字节 (10000 B/s，约 87%)
+	m := NewTxMonitor(*ParseMode(115200, 8, 1, "None"))
+	start := time.Unix(1000, 0)
+	for i := 0; i <= 500; i++ {
+		now := start.Add(time.Duration(i) * 10 * time.Millisecond)
+		if _, ok, _ := m.Add(now, 100); ok {
+			t.Fatalf("reported overcommit at %v", now.Sub(start))
+		}
+	}
+	if r := m.Report(start.Add(5 * time.Second)); r.BacklogB > 100 {
+		t.Errorf("backlog = %d, expected the line to keep up", r.BacklogB)
+	}
+}
+
+func TestTxMonitorSingleBurst(t *testing.T) {
+	// 单次粘贴 10KB 超过 9600 波特一秒的容量，但不是持续需求
+	m := NewTxMonitor(*ParseMode(9600, 8, 1, "None"))
+	if _, ok, _ := m.Add(time.Unix(1000, 0), 10240); ok {
+		t.Error("a single burst should not be reported")
+	}
+}
+
+func TestTxMonitorSetMode(t *testing.T) {
+	m := NewTxMonitor(*ParseMode(9600, 8, 1, "None"))
+	start := time.Unix(1000, 0)
+	m.SetMode(*ParseMode(921600, 8, 1, "None"))
+	for i := 0; i <= 300; i++ {
+		// 每 10ms 200 字节：9600 波特下超额，921600 波特下远低于容量
+		if _, ok, _ := m.Add(start.Add(time.Duration(i)*10*time.Millisecond), 200); ok {
+			t.Fatal("reported overcommit after raising the baud rate")
+		}
+	}
+	m.SetMode(*ParseMode(9600, 8, 1, "None"))
+	var warned bool
+	for i := 301; i <= 500; i++ {
+		if _, _, first := m.Add(start.Add(time.Duration(i)*10*time.Millisecond), 200); first {
+			warned = true
+		}
+	}
+	if !warned {
+		t.Error("lowering the baud rate should trigger the warning")
+	}
+}
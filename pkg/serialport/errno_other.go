@@ -0,0 +1,8 @@
+//go:build !windows
+
+package serialport
+
+import "syscall"
+
+// deviceGoneErrnos 设备被拔出后读写返回的错误码
+var deviceGoneErrnos = []syscall.Errno{syscall.EIO, syscall.ENXIO, syscall.ENODEV}
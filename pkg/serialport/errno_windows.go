@@ -0,0 +1,15 @@
+package serialport
+
+import (
+	"syscall"
+
+	"golang.org/x/sys/windows"
+)
+
+// deviceGoneErrnos 设备被拔出后读写返回的错误码
+var deviceGoneErrnos = []syscall.Errno{
+	windows.ERROR_DEVICE_NOT_CONNECTED,
+	windows.ERROR_GEN_FAILURE,
+	windows.ERROR_BAD_COMMAND,
+	windows.ERROR_DEV_NOT_EXIST,
+}
@@ -0,0 +1,91 @@
+package serialport
+
+import (
+	"errors"
+	"io"
+	"os"
+	"syscall"
+
+	"go.bug.st/serial"
+
+	"serial-assistant/pkg/apperr"
+)
+
+// portError 串口库的 PortError (值或指针形式) 都实现该接口
+type portError interface {
+	error
+	Code() serial.PortErrorCode
+}
+
+// portErrorCodes 串口库错误码与应用错误码的对应关系，未列出的按普通读写错误处理
+var portErrorCodes = map[serial.PortErrorCode]apperr.Code{
+	serial.PortBusy:          apperr.PortBusy,
+	serial.PortNotFound:      apperr.PortNotFound,
+	serial.InvalidSerialPort: apperr.InvalidPort,
+	serial.PermissionDenied:  apperr.PermissionDenied,
+	serial.InvalidSpeed:      apperr.UnsupportedSettings,
+	serial.InvalidDataBits:   apperr.UnsupportedSettings,
+	serial.InvalidParity:     apperr.UnsupportedSettings,
+	serial.InvalidStopBits:   apperr.UnsupportedSettings,
+	serial.PortClosed:        apperr.PortClosed,
+}
+
+// portErrorCode 返回错误链中串口库 PortError 的错误码
+func portErrorCode(err error) (serial.PortErrorCode, bool) {
+	var pe portError
+	if errors.As(err, &pe) {
+		return pe.Code(), true
+	}
+	return 0, false
+}
+
+// IsClosed 判断错误是否表示端口已被关闭，通常是本程序的 Close 与阻塞中的 Read 竞争的结果
+func IsClosed(err error) bool {
+	code, ok := portErrorCode(err)
+	return ok && code == serial.PortClosed
+}
+
+// ReadError 分类读取循环中的错误；expected 为 true 时端口是被本程序关闭的，不应向用户提示错误
+func ReadError(err error) (ev apperr.Event, expected bool) {
+	if IsClosed(err) {
+		return apperr.Event{}, true
+	}
+	return TranslateError(err), false
+}
+
+// TranslateError 将串口读写错误映射为带错误码与建议操作的事件
+// USB 转串口适配器被拔出时，Linux 报告 EIO/ENXIO/ENODEV，Windows 报告设备未连接等错误
+func TranslateError(err error) apperr.Event {
+	detail := ""
+	if err != nil {
+		detail = err.Error()
+	}
+	var coded *apperr.Error
+	var errno syscall.Errno
+	portCode, isPortErr := portErrorCode(err)
+	switch {
+	case err == nil:
+		return apperr.NewEvent(apperr.IOError, detail)
+	case errors.As(err, &coded):
+		return apperr.NewEvent(coded.Code, detail)
+	case isPortErr && portErrorCodes[portCode] != "":
+		return apperr.NewEvent(portErrorCodes[portCode], detail)
+	case errors.Is(err, os.ErrPermission):
+		return apperr.NewEvent(apperr.PermissionDenied, detail)
+	case errors.Is(err, io.EOF), errors.Is(err, os.ErrNotExist):
+		return apperr.NewEvent(apperr.DeviceRemoved, detail)
+	case errors.As(err, &errno) && isDeviceGone(errno):
+		return apperr.NewEvent(apperr.DeviceRemoved, detail)
+	default:
+		return apperr.NewEvent(apperr.IOError, detail)
+	}
+}
+
+func isDeviceGone(errno syscall.Errno) bool {
+	for _, e := range deviceGoneErrnos {
+		if e == errno {
+			return true
+		}
+	}
+	return false
+}
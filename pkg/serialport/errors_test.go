@@ -0,0 +1,78 @@
+package serialport
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"testing"
+
+	"go.bug.st/serial"
+
+	"serial-assistant/pkg/apperr"
+)
+
+func TestTranslateError(t *testing.T) {
+	tests := []struct {
+		err  error
+		code apperr.Code
+	}{
+		{os.NewSyscallError("read", deviceGoneErrnos[0]), apperr.DeviceRemoved},
+		{fmt.Errorf("read /dev/ttyUSB0: %w", deviceGoneErrnos[len(deviceGoneErrnos)-1]), apperr.DeviceRemoved},
+		{io.EOF, apperr.DeviceRemoved},
+		{&os.PathError{Op: "open", Path: "/dev/ttyUSB0", Err: os.ErrPermission}, apperr.PermissionDenied},
+		{apperr.New(apperr.WriteTimeout, "timeout"), apperr.WriteTimeout},
+		{errors.New("framing error"), apperr.IOError},
+	}
+	for _, tt := range tests {
+		ev := TranslateError(tt.err)
+		if ev.Code != tt.code {
+			t.Errorf("TranslateError(%v) = %s, want %s", tt.err, ev.Code, tt.code)
+		}
+		if ev.Detail != tt.err.Error() || len(ev.SuggestedActions) == 0 {
+			t.Errorf("TranslateError(%v) = %+v", tt.err, ev)
+		}
+	}
+}
+
+// fakePortError 串口库的 PortError 字段未导出，测试用相同的 Code 方法构造其他错误码
+type fakePortError struct{ code serial.PortErrorCode }
+
+func (e fakePortError) Error() string              { return fmt.Sprintf("port error %d", e.code) }
+func (e fakePortError) Code() serial.PortErrorCode { return e.code }
+
+func TestTranslatePortError(t *testing.T) {
+	tests := []struct {
+		err  error
+		code apperr.Code
+	}{
+		{&serial.PortError{}, apperr.PortBusy}, // 零值即 PortBusy
+		{serial.PortError{}, apperr.PortBusy},
+		{fmt.Errorf("open COM3: %w", &serial.PortError{}), apperr.PortBusy},
+		{fakePortError{serial.PortNotFound}, apperr.PortNotFound},
+		{fakePortError{serial.InvalidSerialPort}, apperr.InvalidPort},
+		{fakePortError{serial.PermissionDenied}, apperr.PermissionDenied},
+		{fakePortError{serial.InvalidSpeed}, apperr.UnsupportedSettings},
+		{fakePortError{serial.PortClosed}, apperr.PortClosed},
+		{fakePortError{serial.FunctionNotImplemented}, apperr.IOError},
+	}
+	for _, tt := range tests {
+		if ev := TranslateError(tt.err); ev.Code != tt.code {
+			t.Errorf("TranslateError(%v) = %s, want %s", tt.err, ev.Code, tt.code)
+		}
+	}
+}
+
+func TestReadErrorSuppressesPortClosed(t *testing.T) {
+	if ev, expected := ReadError(fmt.Errorf("read: %w", fakePortError{serial.PortClosed})); !expected || ev.Code != "" {
+		t.Errorf("PortClosed should be expected, got %+v", ev)
+	}
+	for _, err := range []error{&serial.PortError{}, io.EOF, os.NewSyscallError("read", deviceGoneErrnos[0])} {
+		if ev, expected := ReadError(err); expected || ev.Code == "" {
+			t.Errorf("ReadError(%v) = %+v, %v; want a reported error", err, ev, expected)
+		}
+	}
+	if IsClosed(errors.New("Port has been closed")) {
+		t.Error("IsClosed matched a plain error by its text")
+	}
+}
@@ -0,0 +1,59 @@
+package serialport
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Holder 打开了串口设备的一个进程
+type Holder struct {
+	PID     int    `json:"pid"`
+	Name    string `json:"name"`
+	Cmdline string `json:"cmdline,omitempty"`
+	Self    bool   `json:"self,omitempty"` // 本程序自身
+}
+
+// HolderReport WhoHasPort 的结果
+// Supported 为 false 时当前平台无法查询；Complete 为 false 时部分进程因权限不足无法检查，
+// 此时 Holders 为空不代表没有进程占用端口
+type HolderReport struct {
+	Port         string   `json:"port"`
+	Device       string   `json:"device,omitempty"` // 实际比较的设备路径 (Linux) 或内核设备名 (Windows)
+	Holders      []Holder `json:"holders"`
+	Supported    bool     `json:"supported"`
+	Complete     bool     `json:"complete"`
+	Inaccessible int      `json:"inaccessible,omitempty"` // 无法检查的进程数
+	Note         string   `json:"note,omitempty"`
+}
+
+// Summary 返回适合附加在错误信息后的简短描述
+func (r HolderReport) Summary() string {
+	if len(r.Holders) > 0 {
+		names := make([]string, len(r.Holders))
+		for i, h := range r.Holders {
+			names[i] = fmt.Sprintf("%s (pid %d)", h.Name, h.PID)
+			if h.Self {
+				names[i] += " [this program]"
+			}
+		}
+		s := "held by " + strings.Join(names, ", ")
+		if !r.Complete {
+			s += "; " + r.Note
+		}
+		return s
+	}
+	if r.Note != "" {
+		return "holder unknown: " + r.Note
+	}
+	return "no other process has the port open"
+}
+
+// WhoHasPort 查找打开了串口的进程：Linux 扫描 /proc/*/fd，Windows 枚举系统句柄表
+// 无法查询或查询不完整时在 Note 中说明原因，而不是只返回空列表
+func WhoHasPort(portName string) (HolderReport, error) {
+	report, err := whoHasPort(portName)
+	if report.Holders == nil {
+		report.Holders = []Holder{}
+	}
+	return report, err
+}
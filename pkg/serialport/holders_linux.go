@@ -0,0 +1,100 @@
+//go:build linux
+
+package serialport
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// procRoot 与 readDir 便于测试替换
+var (
+	procRoot = "/proc"
+	readDir  = os.ReadDir
+)
+
+func whoHasPort(portName string) (HolderReport, error) {
+	report := HolderReport{Port: portName, Supported: true}
+	dev := portName
+	if !filepath.IsAbs(dev) {
+		dev = filepath.Join("/dev", dev)
+	}
+	// /dev/serial/by-id 等符号链接解析为实际的设备节点，与 fd 链接的目标一致
+	resolved, err := filepath.EvalSymlinks(dev)
+	if err != nil {
+		return report, fmt.Errorf("cannot resolve %s: %w", dev, err)
+	}
+	report.Device = resolved
+
+	report.Holders, report.Inaccessible, err = scanProc(procRoot, resolved, os.Getpid())
+	if err != nil {
+		report.Supported = false
+		report.Note = fmt.Sprintf("cannot read %s: %v", procRoot, err)
+		return report, nil
+	}
+	report.Complete = report.Inaccessible == 0
+	if !report.Complete {
+		report.Note = fmt.Sprintf("%d processes could not be inspected (owned by other users); run as root to see all holders", report.Inaccessible)
+	}
+	return report, nil
+}
+
+// scanProc 在 root (/proc 的布局) 中查找打开了 device 的进程，返回因权限不足无法检查的进程数
+func scanProc(root, device string, self int) (holders []Holder, inaccessible int, err error) {
+	entries, err := readDir(root)
+	if err != nil {
+		return nil, 0, err
+	}
+	for _, e := range entries {
+		pid, err := strconv.Atoi(e.Name())
+		if err != nil || !e.IsDir() {
+			continue
+		}
+		fdDir := filepath.Join(root, e.Name(), "fd")
+		fds, err := readDir(fdDir)
+		if err != nil {
+			// 进程可能已经退出，只有权限错误说明结果不完整
+			if os.IsPermission(err) {
+				inaccessible++
+			}
+			continue
+		}
+		for _, fd := range fds {
+			target, err := os.Readlink(filepath.Join(fdDir, fd.Name()))
+			if err != nil || target != device {
+				continue
+			}
+			holders = append(holders, Holder{
+				PID:     pid,
+				Name:    procName(root, e.Name()),
+				Cmdline: procCmdline(root, e.Name()),
+				Self:    pid == self,
+			})
+			break
+		}
+	}
+	sort.Slice(holders, func(i, j int) bool { return holders[i].PID < holders[j].PID })
+	return holders, inaccessible, nil
+}
+
+// procName 读取 /proc/<pid>/comm，失败时返回 "?"
+func procName(root, pid string) string {
+	raw, err := os.ReadFile(filepath.Join(root, pid, "comm"))
+	if name := strings.TrimSpace(string(raw)); err == nil && name != "" {
+		return name
+	}
+	return "?"
+}
+
+// procCmdline 读取 /proc/<pid>/cmdline，参数之间以 NUL 分隔
+func procCmdline(root, pid string) string {
+	raw, err := os.ReadFile(filepath.Join(root, pid, "cmdline"))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(strings.ReplaceAll(strings.TrimRight(string(raw), "\x00"), "\x00", " "))
+}
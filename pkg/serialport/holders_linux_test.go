@@ -0,0 +1,123 @@
+//go:build linux
+
+package serialport
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeProc 在临时目录中构造 /proc 的布局：每个进程的 fd 目录为指向 targets 的符号链接
+func fakeProc(t *testing.T, procs map[string][]string, comm map[string]string) string {
+	t.Helper()
+	root := t.TempDir()
+	for pid, targets := range procs {
+		fd := filepath.Join(root, pid, "fd")
+		if err := os.MkdirAll(fd, 0755); err != nil {
+			t.Fatal(err)
+		}
+		for i, target := range targets {
+			if err := os.Symlink(target, filepath.Join(fd, string(rune('3'+i)))); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if name, ok := comm[pid]; ok {
+			os.WriteFile(filepath.Join(root, pid, "comm"), []byte(name+"\n"), 0644)
+			os.WriteFile(filepath.Join(root, pid, "cmdline"), []byte(name+"\x00-b\x00115200\x00"), 0644)
+		}
+	}
+	// 非进程目录被忽略
+	os.MkdirAll(filepath.Join(root, "sys"), 0755)
+	os.WriteFile(filepath.Join(root, "uptime"), []byte("1.0"), 0644)
+	return root
+}
+
+func TestScanProcFindsHolders(t *testing.T) {
+	root := fakeProc(t, map[string][]string{
+		"812":  {"/dev/pts/1", "/dev/ttyUSB0"},
+		"77":   {"/dev/ttyUSB0", "socket:[1234]", "/dev/ttyUSB0"},
+		"1500": {"/dev/ttyUSB1"},
+		"20":   nil,
+	}, map[string]string{"812": "minicom", "77": "putty"})
+
+	holders, inaccessible, err := scanProc(root, "/dev/ttyUSB0", 812)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if inaccessible != 0 || len(holders) != 2 {
+		t.Fatalf("holders = %+v, inaccessible = %d", holders, inaccessible)
+	}
+	if h := holders[0]; h.PID != 77 || h.Name != "putty" || h.Cmdline != "putty -b 115200" || h.Self {
+		t.Errorf("holders[0] = %+v", h)
+	}
+	if h := holders[1]; h.PID != 812 || h.Name != "minicom" || !h.Self {
+		t.Errorf("holders[1] = %+v", h)
+	}
+}
+
+func TestScanProcReportsInaccessibleProcesses(t *testing.T) {
+	root := fakeProc(t, map[string][]string{"10": {"/dev/ttyS0"}, "11": nil, "12": nil}, nil)
+	defer func(orig func(string) ([]os.DirEntry, error)) { readDir = orig }(readDir)
+	readDir = func(dir string) ([]os.DirEntry, error) {
+		switch filepath.Base(filepath.Dir(dir)) {
+		case "11":
+			return nil, &fs.PathError{Op: "open", Path: dir, Err: fs.ErrPermission}
+		case "12":
+			// 进程在扫描期间退出，不算作无法检查
+			return nil, &fs.PathError{Op: "open", Path: dir, Err: fs.ErrNotExist}
+		}
+		return os.ReadDir(dir)
+	}
+
+	holders, inaccessible, err := scanProc(root, "/dev/ttyS0", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(holders) != 1 || holders[0].Name != "?" || inaccessible != 1 {
+		t.Errorf("holders = %+v, inaccessible = %d", holders, inaccessible)
+	}
+}
+
+func TestWhoHasPortReportsIncompleteScan(t *testing.T) {
+	dir := t.TempDir()
+	dev := filepath.Join(dir, "ttyFAKE0")
+	os.WriteFile(dev, nil, 0644)
+	link := filepath.Join(dir, "by-id")
+	os.Symlink(dev, link)
+
+	procRoot = fakeProc(t, map[string][]string{"42": {dev}, "43": nil}, map[string]string{"42": "screen"})
+	defer func(orig func(string) ([]os.DirEntry, error)) { readDir, procRoot = orig, "/proc" }(readDir)
+	readDir = func(d string) ([]os.DirEntry, error) {
+		if strings.Contains(d, "/43/") {
+			return nil, &fs.PathError{Op: "open", Path: d, Err: fs.ErrPermission}
+		}
+		return os.ReadDir(d)
+	}
+
+	report, err := WhoHasPort(link)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Device != dev || len(report.Holders) != 1 || report.Complete || report.Inaccessible != 1 {
+		t.Fatalf("report = %+v", report)
+	}
+	if s := report.Summary(); !strings.Contains(s, "screen (pid 42)") || !strings.Contains(s, "run as root") {
+		t.Errorf("summary = %q", s)
+	}
+
+	if _, err := WhoHasPort(filepath.Join(dir, "missing")); err == nil {
+		t.Error("expected an error for a missing device")
+	}
+}
+
+func TestHolderSummaryWithoutHolders(t *testing.T) {
+	if s := (HolderReport{Supported: true, Complete: true}).Summary(); s != "no other process has the port open" {
+		t.Errorf("summary = %q", s)
+	}
+	if s := (HolderReport{Note: "not supported"}).Summary(); s != "holder unknown: not supported" {
+		t.Errorf("summary = %q", s)
+	}
+}
@@ -0,0 +1,16 @@
+//go:build !linux && !windows
+
+package serialport
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// whoHasPort 其他平台没有不依赖外部工具的查询方式，明确报告不支持
+func whoHasPort(portName string) (HolderReport, error) {
+	return HolderReport{
+		Port: portName,
+		Note: fmt.Sprintf("finding the process that holds a port is not supported on %s; try `lsof %s`", runtime.GOOS, portName),
+	}, nil
+}
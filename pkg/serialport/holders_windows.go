@@ -0,0 +1,186 @@
+//go:build windows
+
+package serialport
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+var procNtQueryObject = windows.NewLazySystemDLL("ntdll.dll").NewProc("NtQueryObject")
+
+// objectNameInformation NtQueryObject 的 ObjectNameInformation 信息类
+const objectNameInformation = 1
+
+// handleEntry SYSTEM_HANDLE_TABLE_ENTRY_INFO_EX
+type handleEntry struct {
+	Object                uintptr
+	UniqueProcessID       uintptr
+	HandleValue           uintptr
+	GrantedAccess         uint32
+	CreatorBackTraceIndex uint16
+	ObjectTypeIndex       uint16
+	HandleAttributes      uint32
+	Reserved              uint32
+}
+
+// whoHasPort 枚举系统句柄表，找出打开了与 COMn 对应的内核设备 (例如 \Device\Serial0) 的进程
+// 只检查 File 类型且 GetFileType 为字符设备的句柄，避免对管道调用 NtQueryObject 时阻塞
+func whoHasPort(portName string) (HolderReport, error) {
+	report := HolderReport{Port: portName, Supported: true}
+	device, err := dosDevice(strings.TrimPrefix(portName, `\\.\`))
+	if err != nil {
+		return report, fmt.Errorf("cannot resolve %s: %w", portName, err)
+	}
+	report.Device = device
+
+	// 枚举期间保持打开一个已知的文件句柄，用于确定 File 对象的类型编号 (各 Windows 版本不同)
+	probe, err := os.Open(os.Args[0])
+	if err != nil {
+		return report, err
+	}
+	handles, err := systemHandles()
+	fileType, ok := fileTypeIndex(handles, probe.Fd())
+	probe.Close()
+	if err != nil {
+		report.Supported = false
+		report.Note = fmt.Sprintf("cannot enumerate system handles: %v", err)
+		return report, nil
+	}
+	if !ok {
+		report.Supported = false
+		report.Note = "cannot determine the file handle type"
+		return report, nil
+	}
+
+	self := windows.CurrentProcess()
+	selfPID := os.Getpid()
+	procs := make(map[uintptr]windows.Handle) // 0 表示无法打开
+	defer func() {
+		for _, h := range procs {
+			if h != 0 {
+				windows.CloseHandle(h)
+			}
+		}
+	}()
+	found := make(map[int]bool)
+	for _, e := range handles {
+		pid := int(e.UniqueProcessID)
+		if e.ObjectTypeIndex != fileType || found[pid] {
+			continue
+		}
+		proc, seen := procs[e.UniqueProcessID]
+		if !seen {
+			proc, err = windows.OpenProcess(windows.PROCESS_DUP_HANDLE|windows.PROCESS_QUERY_LIMITED_INFORMATION, false, uint32(pid))
+			if err != nil {
+				proc = 0
+				if err == windows.ERROR_ACCESS_DENIED {
+					report.Inaccessible++
+				}
+			}
+			procs[e.UniqueProcessID] = proc
+		}
+		if proc == 0 {
+			continue
+		}
+		var dup windows.Handle
+		if windows.DuplicateHandle(proc, windows.Handle(e.HandleValue), self, &dup, 0, false, windows.DUPLICATE_SAME_ACCESS) != nil {
+			continue
+		}
+		match := false
+		if t, err := windows.GetFileType(dup); err == nil && t == windows.FILE_TYPE_CHAR {
+			name, err := objectName(dup)
+			match = err == nil && strings.EqualFold(name, device)
+		}
+		windows.CloseHandle(dup)
+		if match {
+			found[pid] = true
+			report.Holders = append(report.Holders, Holder{PID: pid, Name: processName(proc), Self: pid == selfPID})
+		}
+	}
+	sort.Slice(report.Holders, func(i, j int) bool { return report.Holders[i].PID < report.Holders[j].PID })
+
+	report.Complete = report.Inaccessible == 0
+	if !report.Complete {
+		report.Note = fmt.Sprintf("%d processes could not be inspected (elevated or protected); run as administrator to see all holders", report.Inaccessible)
+	}
+	return report, nil
+}
+
+// dosDevice 返回 DOS 设备名 (COM7) 对应的内核设备名
+func dosDevice(name string) (string, error) {
+	p, err := windows.UTF16PtrFromString(name)
+	if err != nil {
+		return "", err
+	}
+	buf := make([]uint16, windows.MAX_PATH)
+	n, err := windows.QueryDosDevice(p, &buf[0], uint32(len(buf)))
+	if err != nil {
+		return "", err
+	}
+	// 结果为以两个 NUL 结尾的列表，取第一项
+	return windows.UTF16ToString(buf[:n]), nil
+}
+
+// systemHandles 返回系统中所有句柄
+func systemHandles() ([]handleEntry, error) {
+	size := uint32(1 << 20)
+	for {
+		buf := make([]byte, size)
+		var need uint32
+		err := windows.NtQuerySystemInformation(windows.SystemExtendedHandleInformation, unsafe.Pointer(&buf[0]), size, &need)
+		if err == windows.STATUS_INFO_LENGTH_MISMATCH {
+			if size >= 1<<30 {
+				return nil, err
+			}
+			size = max(size*2, need+1<<16)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		// SYSTEM_HANDLE_INFORMATION_EX: NumberOfHandles、Reserved，之后为句柄数组
+		count := *(*uintptr)(unsafe.Pointer(&buf[0]))
+		first := unsafe.Pointer(&buf[2*unsafe.Sizeof(uintptr(0))])
+		return append([]handleEntry(nil), unsafe.Slice((*handleEntry)(first), count)...), nil
+	}
+}
+
+// fileTypeIndex 返回本进程的文件句柄 fd 在句柄表中的类型编号
+func fileTypeIndex(handles []handleEntry, fd uintptr) (uint16, bool) {
+	pid := uintptr(os.Getpid())
+	for _, e := range handles {
+		if e.UniqueProcessID == pid && e.HandleValue == fd {
+			return e.ObjectTypeIndex, true
+		}
+	}
+	return 0, false
+}
+
+// objectName 返回句柄对应的内核对象名
+func objectName(h windows.Handle) (string, error) {
+	buf := make([]byte, 2048)
+	var need uint32
+	r, _, _ := procNtQueryObject.Call(uintptr(h), objectNameInformation, uintptr(unsafe.Pointer(&buf[0])), uintptr(len(buf)), uintptr(unsafe.Pointer(&need)))
+	if r != 0 {
+		return "", windows.NTStatus(r)
+	}
+	name := (*windows.NTUnicodeString)(unsafe.Pointer(&buf[0]))
+	return name.String(), nil
+}
+
+// processName 返回进程映像的文件名
+func processName(proc windows.Handle) string {
+	buf := make([]uint16, windows.MAX_PATH)
+	n := uint32(len(buf))
+	if err := windows.QueryFullProcessImageName(proc, 0, &buf[0], &n); err != nil {
+		return "?"
+	}
+	return filepath.Base(windows.UTF16ToString(buf[:n]))
+}
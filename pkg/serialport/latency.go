@@ -0,0 +1,17 @@
+package serialport
+
+// LatencyResult SetLowLatency 的结果，描述实际生效的调整
+type LatencyResult struct {
+	Supported bool     `json:"supported"`
+	Applied   []string `json:"applied,omitempty"` // 已生效的调整，例如 "latency_timer=1ms"
+	Message   string   `json:"message,omitempty"` // 不支持或部分生效时的说明
+}
+
+// FTDI 延迟定时器 (毫秒)：驱动默认 16ms，低延迟模式使用 1ms
+const (
+	defaultLatencyTimer = 16
+	lowLatencyTimer     = 1
+)
+
+// bufferSizeNote 串口库不支持设置系统接收缓冲区大小，作为说明附加到结果中
+const bufferSizeNote = "OS receive buffer size is not adjustable through the serial library"
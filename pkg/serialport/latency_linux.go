@@ -0,0 +1,53 @@
+//go:build linux
+
+package serialport
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// sysfsRoot 便于测试替换
+var sysfsRoot = "/sys"
+
+// SetLowLatency 通过 sysfs 调整 USB 串口适配器的延迟定时器 (FTDI 的 latency_timer)
+// enabled 为 false 时恢复驱动默认值
+func SetLowLatency(portName string, enabled bool) (LatencyResult, error) {
+	dev := portName
+	if resolved, err := filepath.EvalSymlinks(portName); err == nil {
+		dev = resolved // 例如 /dev/serial/by-id/... -> /dev/ttyUSB0
+	}
+	path := filepath.Join(sysfsRoot, "class", "tty", filepath.Base(dev), "device", "latency_timer")
+
+	if _, err := os.Stat(path); err != nil {
+		return LatencyResult{
+			Message: fmt.Sprintf("%s does not expose latency_timer (only FTDI-style adapters support it); %s", filepath.Base(dev), bufferSizeNote),
+		}, nil
+	}
+
+	value := defaultLatencyTimer
+	if enabled {
+		value = lowLatencyTimer
+	}
+	if err := os.WriteFile(path, []byte(strconv.Itoa(value)), 0644); err != nil {
+		if errors.Is(err, os.ErrPermission) {
+			return LatencyResult{}, fmt.Errorf("permission denied writing %s (a udev rule can grant access): %w", path, err)
+		}
+		return LatencyResult{}, fmt.Errorf("failed to write %s: %w", path, err)
+	}
+
+	// 读回确认实际生效的值
+	applied := strconv.Itoa(value)
+	if raw, err := os.ReadFile(path); err == nil {
+		applied = strings.TrimSpace(string(raw))
+	}
+	return LatencyResult{
+		Supported: true,
+		Applied:   []string{"latency_timer=" + applied + "ms"},
+		Message:   bufferSizeNote,
+	}, nil
+}
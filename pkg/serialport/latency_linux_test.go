@@ -0,0 +1,52 @@
+package serialport
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func fakeSysfs(t *testing.T, tty string, withTimer bool) string {
+	t.Helper()
+	root := t.TempDir()
+	dir := filepath.Join(root, "class", "tty", tty, "device")
+	os.MkdirAll(dir, 0755)
+	if withTimer {
+		os.WriteFile(filepath.Join(dir, "latency_timer"), []byte("16\n"), 0644)
+	}
+	sysfsRoot = root
+	t.Cleanup(func() { sysfsRoot = "/sys" })
+	return filepath.Join(dir, "latency_timer")
+}
+
+func TestSetLowLatencyFTDI(t *testing.T) {
+	path := fakeSysfs(t, "ttyUSB7", true)
+
+	res, err := SetLowLatency("/dev/ttyUSB7", true)
+	if err != nil {
+		t.Fatalf("SetLowLatency failed: %v", err)
+	}
+	if !res.Supported || len(res.Applied) != 1 || res.Applied[0] != "latency_timer=1ms" {
+		t.Errorf("Unexpected result %+v", res)
+	}
+	if raw, _ := os.ReadFile(path); string(raw) != "1" {
+		t.Errorf("Expected latency_timer to be 1, got %q", raw)
+	}
+
+	res, _ = SetLowLatency("/dev/ttyUSB7", false)
+	if res.Applied[0] != "latency_timer=16ms" {
+		t.Errorf("Disabling should restore the default, got %+v", res)
+	}
+}
+
+func TestSetLowLatencyUnsupportedDriver(t *testing.T) {
+	fakeSysfs(t, "ttyACM0", false)
+
+	res, err := SetLowLatency("/dev/ttyACM0", true)
+	if err != nil {
+		t.Fatalf("Unsupported driver should not be an error: %v", err)
+	}
+	if res.Supported || res.Message == "" {
+		t.Errorf("Expected unsupported result with a message, got %+v", res)
+	}
+}
@@ -0,0 +1,16 @@
+//go:build !linux
+
+package serialport
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// SetLowLatency 在非 Linux 平台上不做任何操作
+// Windows 的 FTDI 延迟定时器只能通过设备管理器 (驱动高级属性) 修改，串口库也不提供底层句柄
+func SetLowLatency(portName string, enabled bool) (LatencyResult, error) {
+	return LatencyResult{
+		Message: fmt.Sprintf("low latency tuning is not supported on %s; adjust the adapter's latency timer in the driver settings. %s", runtime.GOOS, bufferSizeNote),
+	}, nil
+}
@@ -0,0 +1,80 @@
+package serialport
+
+import (
+	"fmt"
+
+	"go.bug.st/serial"
+)
+
+// LinkCheck 发送前用于判断对端是否存在的调制解调器状态线
+// 许多三线制线缆从不驱动这些线，因此默认关闭
+type LinkCheck string
+
+const (
+	LinkCheckOff LinkCheck = "off"
+	LinkCheckDSR LinkCheck = "dsr" // DSR 有效视为设备存在
+	LinkCheckDCD LinkCheck = "dcd" // DCD 有效视为设备存在
+	LinkCheckAny LinkCheck = "any" // DSR 或 DCD 任一有效
+)
+
+// ParseLinkCheck 解析检查方式，空字符串等同于 "off"
+func ParseLinkCheck(name string) (LinkCheck, error) {
+	switch c := LinkCheck(name); c {
+	case "":
+		return LinkCheckOff, nil
+	case LinkCheckOff, LinkCheckDSR, LinkCheckDCD, LinkCheckAny:
+		return c, nil
+	default:
+		return "", fmt.Errorf("unknown link check %q (expected off, dsr, dcd or any)", name)
+	}
+}
+
+// ModemLines 调制解调器输入线的状态
+type ModemLines struct {
+	CTS bool `json:"cts"`
+	DSR bool `json:"dsr"`
+	RI  bool `json:"ri"`
+	DCD bool `json:"dcd"`
+}
+
+// StatusReader 可读取状态线的端口，serial.Port 满足该接口
+type StatusReader interface {
+	GetModemStatusBits() (*serial.ModemStatusBits, error)
+}
+
+// ReadLines 读取端口当前的状态线
+func ReadLines(port StatusReader) (ModemLines, error) {
+	bits, err := port.GetModemStatusBits()
+	if err != nil {
+		return ModemLines{}, fmt.Errorf("failed to read modem status: %w", err)
+	}
+	return ModemLines{CTS: bits.CTS, DSR: bits.DSR, RI: bits.RI, DCD: bits.DCD}, nil
+}
+
+// DevicePresent 按检查方式判断设备是否存在，LinkCheckOff 总是返回 true
+func (c LinkCheck) DevicePresent(l ModemLines) bool {
+	switch c {
+	case LinkCheckDSR:
+		return l.DSR
+	case LinkCheckDCD:
+		return l.DCD
+	case LinkCheckAny:
+		return l.DSR || l.DCD
+	default:
+		return true
+	}
+}
+
+// Lines 返回检查的状态线名称，用于提示信息
+func (c LinkCheck) Lines() string {
+	switch c {
+	case LinkCheckDSR:
+		return "DSR"
+	case LinkCheckDCD:
+		return "DCD"
+	case LinkCheckAny:
+		return "DSR/DCD"
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,143 @@
+package serialport
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"go.bug.st/serial"
+)
+
+// ModeStatus 读回比较的结论
+type ModeStatus string
+
+const (
+	ModeVerified   ModeStatus = "verified"   // 读回的参数与请求一致
+	ModeMismatch   ModeStatus = "mismatch"   // 驱动实际使用的参数与请求不同
+	ModeUnverified ModeStatus = "unverified" // 当前平台或驱动无法读回
+)
+
+// ModeInfo 串口参数，格式同 OpenSerial：StopBits 为 1、15 (1.5) 或 2，Parity 为 None/Odd/Even/Mark/Space
+type ModeInfo struct {
+	BaudRate int    `json:"baudRate"`
+	DataBits int    `json:"dataBits"`
+	StopBits int    `json:"stopBits"`
+	Parity   string `json:"parity"`
+}
+
+// String 返回 "115200 8N1" 形式的描述
+func (m ModeInfo) String() string {
+	stop := fmt.Sprint(m.StopBits)
+	if m.StopBits == 15 {
+		stop = "1.5"
+	}
+	return fmt.Sprintf("%d %d%c%s", m.BaudRate, m.DataBits, m.Parity[0], stop)
+}
+
+// ModeCheck 请求的串口参数与驱动实际生效的参数的比较结果
+type ModeCheck struct {
+	Status    ModeStatus `json:"status"`
+	Mismatch  bool       `json:"mismatch"`
+	Requested ModeInfo   `json:"requested"`
+	Effective *ModeInfo  `json:"effective,omitempty"` // 无法读回时为 nil
+	Fields    []string   `json:"fields,omitempty"`    // 不一致的字段 (baudRate、dataBits、stopBits、parity)
+	Note      string     `json:"note,omitempty"`      // 无法读回的原因
+}
+
+// Summary 返回适合附加在结果或提示中的简短描述，一致时为空字符串
+func (c ModeCheck) Summary() string {
+	switch c.Status {
+	case ModeMismatch:
+		return fmt.Sprintf("mode mismatch: requested %s, driver uses %s (%s)",
+			c.Requested, c.Effective, strings.Join(c.Fields, ", "))
+	case ModeUnverified:
+		return "mode unverified: " + c.Note
+	}
+	return ""
+}
+
+// ModeInfoOf 将 serial.Mode 转换为 OpenSerial 的参数格式，DataBits 为 0 时按驱动默认的 8 位
+func ModeInfoOf(mode serial.Mode) ModeInfo {
+	info := ModeInfo{BaudRate: mode.BaudRate, DataBits: mode.DataBits, StopBits: 1, Parity: "None"}
+	if info.DataBits == 0 {
+		info.DataBits = 8
+	}
+	switch mode.StopBits {
+	case serial.OnePointFiveStopBits:
+		info.StopBits = 15
+	case serial.TwoStopBits:
+		info.StopBits = 2
+	}
+	switch mode.Parity {
+	case serial.OddParity:
+		info.Parity = "Odd"
+	case serial.EvenParity:
+		info.Parity = "Even"
+	case serial.MarkParity:
+		info.Parity = "Mark"
+	case serial.SpaceParity:
+		info.Parity = "Space"
+	}
+	return info
+}
+
+// CompareMode 比较请求的参数与读回的参数；effective 为 nil 时结论为 unverified，note 说明原因
+func CompareMode(requested serial.Mode, effective *serial.Mode, note string) ModeCheck {
+	check := ModeCheck{Status: ModeUnverified, Requested: ModeInfoOf(requested), Note: note}
+	if effective == nil {
+		return check
+	}
+	eff := ModeInfoOf(*effective)
+	check.Effective = &eff
+	check.Note = ""
+	req := check.Requested
+	if req.BaudRate != eff.BaudRate {
+		check.Fields = append(check.Fields, "baudRate")
+	}
+	if req.DataBits != eff.DataBits {
+		check.Fields = append(check.Fields, "dataBits")
+	}
+	if req.StopBits != eff.StopBits {
+		check.Fields = append(check.Fields, "stopBits")
+	}
+	if req.Parity != eff.Parity {
+		check.Fields = append(check.Fields, "parity")
+	}
+	check.Status = ModeVerified
+	if len(check.Fields) > 0 {
+		check.Status, check.Mismatch = ModeMismatch, true
+	}
+	return check
+}
+
+// ErrReadbackUnsupported 当前平台或端口实现无法读回参数
+var ErrReadbackUnsupported = errors.New("reading back port settings is not supported on this platform")
+
+// CheckMode 读回端口实际生效的参数并与 requested 比较，无法读回时结论为 unverified
+func CheckMode(port serial.Port, requested serial.Mode) ModeCheck {
+	effective, err := readMode(port)
+	if err != nil {
+		return CompareMode(requested, nil, err.Error())
+	}
+	return CompareMode(requested, &effective, "")
+}
+
+// portHandle 取出 go.bug.st/serial 端口实现中未导出的 handle 字段 (Unix 为文件描述符，Windows 为句柄)
+// 该库没有提供读取当前参数的接口，只能借助系统调用读回
+func portHandle(port serial.Port) (uint64, bool) {
+	v := reflect.ValueOf(port)
+	if v.Kind() != reflect.Pointer || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return 0, false
+	}
+	f := v.Elem().FieldByName("handle")
+	switch {
+	case !f.IsValid():
+		return 0, false
+	case f.CanInt():
+		return uint64(f.Int()), true
+	case f.CanUint():
+		return f.Uint(), true
+	}
+	return 0, false
+}
@@ -0,0 +1,55 @@
+//go:build linux
+
+package serialport
+
+import (
+	"fmt"
+
+	"go.bug.st/serial"
+	"golang.org/x/sys/unix"
+)
+
+// readMode 通过 TCGETS2 读回终端设置，c_ospeed 为驱动实际使用的波特率 (包括非标准波特率)
+func readMode(port serial.Port) (serial.Mode, error) {
+	fd, ok := portHandle(port)
+	if !ok {
+		return serial.Mode{}, ErrReadbackUnsupported
+	}
+	t, err := unix.IoctlGetTermios(int(fd), unix.TCGETS2)
+	if err != nil {
+		return serial.Mode{}, fmt.Errorf("TCGETS2: %w", err)
+	}
+	return modeFromTermios(t), nil
+}
+
+// modeFromTermios 解析 termios2 中的波特率、数据位、停止位与校验
+func modeFromTermios(t *unix.Termios) serial.Mode {
+	mode := serial.Mode{BaudRate: int(t.Ospeed), StopBits: serial.OneStopBit, Parity: serial.NoParity}
+	switch t.Cflag & unix.CSIZE {
+	case unix.CS5:
+		mode.DataBits = 5
+	case unix.CS6:
+		mode.DataBits = 6
+	case unix.CS7:
+		mode.DataBits = 7
+	default:
+		mode.DataBits = 8
+	}
+	if t.Cflag&unix.CSTOPB != 0 {
+		mode.StopBits = serial.TwoStopBits
+	}
+	if t.Cflag&unix.PARENB != 0 {
+		odd := t.Cflag&unix.PARODD != 0
+		switch {
+		case t.Cflag&unix.CMSPAR != 0 && odd:
+			mode.Parity = serial.MarkParity
+		case t.Cflag&unix.CMSPAR != 0:
+			mode.Parity = serial.SpaceParity
+		case odd:
+			mode.Parity = serial.OddParity
+		default:
+			mode.Parity = serial.EvenParity
+		}
+	}
+	return mode
+}
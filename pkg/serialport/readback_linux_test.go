@@ -0,0 +1,81 @@
+package serialport
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+
+	"go.bug.st/serial"
+	"golang.org/x/sys/unix"
+)
+
+// ptyPort 模拟 go.bug.st/serial 的 unixPort，只提供 readMode 需要的 handle 字段
+type ptyPort struct {
+	serial.Port
+	handle int
+}
+
+// openPty 打开一对伪终端，返回从设备
+func openPty(t *testing.T) *os.File {
+	t.Helper()
+	master, err := os.OpenFile("/dev/ptmx", os.O_RDWR|unix.O_NOCTTY, 0)
+	if err != nil {
+		t.Skipf("no pseudo terminals: %v", err)
+	}
+	t.Cleanup(func() { master.Close() })
+	if err := unix.IoctlSetPointerInt(int(master.Fd()), unix.TIOCSPTLCK, 0); err != nil {
+		t.Skipf("unlockpt: %v", err)
+	}
+	n, err := unix.IoctlGetInt(int(master.Fd()), unix.TIOCGPTN)
+	if err != nil {
+		t.Skipf("ptsname: %v", err)
+	}
+	slave, err := os.OpenFile("/dev/pts/"+strconv.Itoa(n), os.O_RDWR|unix.O_NOCTTY, 0)
+	if err != nil {
+		t.Skipf("open slave: %v", err)
+	}
+	t.Cleanup(func() { slave.Close() })
+	return slave
+}
+
+// TestReadModeFromPty 伪终端驱动强制使用 8 位无校验，正好模拟驱动悄悄改写请求的参数
+func TestReadModeFromPty(t *testing.T) {
+	slave := openPty(t)
+	fd := int(slave.Fd())
+
+	tio, err := unix.IoctlGetTermios(fd, unix.TCGETS2)
+	if err != nil {
+		t.Skipf("TCGETS2: %v", err)
+	}
+	tio.Cflag &^= unix.CBAUD | unix.CSIZE | unix.PARODD | unix.CMSPAR
+	tio.Cflag |= unix.BOTHER | unix.CS7 | unix.CSTOPB | unix.PARENB
+	tio.Ispeed, tio.Ospeed = 250000, 250000
+	if err := unix.IoctlSetTermios(fd, unix.TCSETS2, tio); err != nil {
+		t.Skipf("TCSETS2: %v", err)
+	}
+
+	check := CheckMode(&ptyPort{handle: fd}, *ParseMode(250000, 7, 2, "Even"))
+	if check.Status != ModeMismatch || strings.Join(check.Fields, ",") != "dataBits,parity" {
+		t.Fatalf("Unexpected check: %+v", check)
+	}
+	if eff := check.Effective; eff.BaudRate != 250000 || eff.DataBits != 8 || eff.StopBits != 2 || eff.Parity != "None" {
+		t.Errorf("Unexpected effective mode: %+v", eff)
+	}
+}
+
+func TestModeFromTermiosParity(t *testing.T) {
+	for _, tc := range []struct {
+		cflag  uint32
+		parity serial.Parity
+	}{
+		{unix.CS8, serial.NoParity},
+		{unix.CS8 | unix.PARENB | unix.PARODD, serial.OddParity},
+		{unix.CS8 | unix.PARENB | unix.CMSPAR | unix.PARODD, serial.MarkParity},
+		{unix.CS8 | unix.PARENB | unix.CMSPAR, serial.SpaceParity},
+	} {
+		if mode := modeFromTermios(&unix.Termios{Cflag: tc.cflag, Ospeed: 9600}); mode.Parity != tc.parity || mode.DataBits != 8 {
+			t.Errorf("cflag %#x: %+v", tc.cflag, mode)
+		}
+	}
+}
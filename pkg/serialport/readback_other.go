@@ -0,0 +1,10 @@
+//go:build !linux && !windows
+
+package serialport
+
+import "go.bug.st/serial"
+
+// readMode 其他平台暂不读回，结论为 unverified
+func readMode(port serial.Port) (serial.Mode, error) {
+	return serial.Mode{}, ErrReadbackUnsupported
+}
@@ -0,0 +1,48 @@
+package serialport
+
+import (
+	"strings"
+	"testing"
+
+	"go.bug.st/serial"
+)
+
+func TestCompareModeVerified(t *testing.T) {
+	req := serial.Mode{BaudRate: 115200, StopBits: serial.OneStopBit}
+	eff := serial.Mode{BaudRate: 115200, DataBits: 8, StopBits: serial.OneStopBit, Parity: serial.NoParity}
+	check := CompareMode(req, &eff, "")
+	if check.Status != ModeVerified || check.Mismatch || len(check.Fields) != 0 || check.Summary() != "" {
+		t.Errorf("Unexpected check: %+v", check)
+	}
+	// DataBits 为 0 按 8 位比较
+	if check.Requested.DataBits != 8 || check.Requested.Parity != "None" {
+		t.Errorf("Requested not normalized: %+v", check.Requested)
+	}
+}
+
+func TestCompareModeMismatch(t *testing.T) {
+	req := *ParseMode(9600, 8, 15, "Even")
+	eff := serial.Mode{BaudRate: 9600, DataBits: 8, StopBits: serial.TwoStopBits, Parity: serial.NoParity}
+	check := CompareMode(req, &eff, "")
+	if check.Status != ModeMismatch || !check.Mismatch || strings.Join(check.Fields, ",") != "stopBits,parity" {
+		t.Fatalf("Unexpected check: %+v", check)
+	}
+	if want := "mode mismatch: requested 9600 8E1.5, driver uses 9600 8N2 (stopBits, parity)"; check.Summary() != want {
+		t.Errorf("Summary() = %q, want %q", check.Summary(), want)
+	}
+}
+
+func TestCompareModeUnverified(t *testing.T) {
+	check := CompareMode(*ParseMode(57600, 7, 1, "Odd"), nil, ErrReadbackUnsupported.Error())
+	if check.Status != ModeUnverified || check.Mismatch || check.Effective != nil || check.Requested.String() != "57600 7O1" {
+		t.Errorf("Unexpected check: %+v", check)
+	}
+	if !strings.HasPrefix(check.Summary(), "mode unverified: ") {
+		t.Errorf("Summary() = %q", check.Summary())
+	}
+
+	// 无法取得句柄的端口实现 (例如测试替身) 同样为 unverified
+	if check := CheckMode(nil, *ParseMode(9600, 8, 1, "None")); check.Status != ModeUnverified {
+		t.Errorf("CheckMode(nil) = %+v", check)
+	}
+}
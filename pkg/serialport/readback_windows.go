@@ -0,0 +1,47 @@
+//go:build windows
+
+package serialport
+
+import (
+	"fmt"
+	"unsafe"
+
+	"go.bug.st/serial"
+	"golang.org/x/sys/windows"
+)
+
+// readMode 通过 GetCommState 读回驱动保存的 DCB
+func readMode(port serial.Port) (serial.Mode, error) {
+	h, ok := portHandle(port)
+	if !ok {
+		return serial.Mode{}, ErrReadbackUnsupported
+	}
+	var dcb windows.DCB
+	dcb.DCBlength = uint32(unsafe.Sizeof(dcb))
+	if err := windows.GetCommState(windows.Handle(h), &dcb); err != nil {
+		return serial.Mode{}, fmt.Errorf("GetCommState: %w", err)
+	}
+	return modeFromDCB(&dcb), nil
+}
+
+// modeFromDCB 解析 DCB 中的波特率、数据位、停止位与校验
+func modeFromDCB(dcb *windows.DCB) serial.Mode {
+	mode := serial.Mode{BaudRate: int(dcb.BaudRate), DataBits: int(dcb.ByteSize), StopBits: serial.OneStopBit, Parity: serial.NoParity}
+	switch dcb.StopBits {
+	case windows.ONE5STOPBITS:
+		mode.StopBits = serial.OnePointFiveStopBits
+	case windows.TWOSTOPBITS:
+		mode.StopBits = serial.TwoStopBits
+	}
+	switch dcb.Parity {
+	case windows.ODDPARITY:
+		mode.Parity = serial.OddParity
+	case windows.EVENPARITY:
+		mode.Parity = serial.EvenParity
+	case windows.MARKPARITY:
+		mode.Parity = serial.MarkParity
+	case windows.SPACEPARITY:
+		mode.Parity = serial.SpaceParity
+	}
+	return mode
+}
@@ -0,0 +1,104 @@
+// Package serialport 封装串口打开时的辅助逻辑：DTR/RTS 初始状态与打开重试
+package serialport
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"go.bug.st/serial"
+)
+
+// LineState 控制线 (DTR/RTS) 在打开后的初始状态
+type LineState string
+
+const (
+	LineDefault LineState = ""     // 使用该端口保存的默认值，没有则为 high
+	LineHigh    LineState = "high" // 置为有效
+	LineLow     LineState = "low"  // 置为无效
+	LineKeep    LineState = "keep" // 不修改，保持驱动打开后的状态
+)
+
+// DefaultRetryDelay 打开重试的间隔
+const DefaultRetryDelay = 500 * time.Millisecond
+
+// MaxRetries 打开重试次数上限
+const MaxRetries = 20
+
+// ParseLineState 解析控制线状态名称
+func ParseLineState(name string) (LineState, error) {
+	switch s := LineState(name); s {
+	case LineDefault, LineHigh, LineLow, LineKeep:
+		return s, nil
+	default:
+		return "", fmt.Errorf("unknown line state %q (expected high, low or keep)", name)
+	}
+}
+
+// Resolve 将 LineDefault 替换为保存的默认值；没有保存值时退回 high (与旧版行为一致)
+func (s LineState) Resolve(saved LineState) LineState {
+	if s != LineDefault {
+		return s
+	}
+	if saved != LineDefault {
+		return saved
+	}
+	return LineHigh
+}
+
+// LineSetter 可设置 DTR/RTS 的端口，serial.Port 满足该接口
+type LineSetter interface {
+	SetDTR(dtr bool) error
+	SetRTS(rts bool) error
+}
+
+// ApplyLines 按给定状态设置 DTR 与 RTS，LineKeep 的线不做任何操作
+func ApplyLines(port LineSetter, dtr, rts LineState) error {
+	if err := applyLine(port.SetDTR, dtr); err != nil {
+		return fmt.Errorf("failed to set DTR: %w", err)
+	}
+	if err := applyLine(port.SetRTS, rts); err != nil {
+		return fmt.Errorf("failed to set RTS: %w", err)
+	}
+	return nil
+}
+
+func applyLine(set func(bool) error, state LineState) error {
+	switch state {
+	case LineHigh:
+		return set(true)
+	case LineLow:
+		return set(false)
+	default:
+		return nil
+	}
+}
+
+// IsNotFound 判断错误是否表示端口 (暂时) 不存在
+// 设备刚插入时驱动可能尚未创建端口，此类错误值得重试；
+// 权限不足、端口被占用等错误重试也不会改变，应立即返回
+func IsNotFound(err error) bool {
+	if code, ok := portErrorCode(err); ok {
+		return code == serial.PortNotFound
+	}
+	return errors.Is(err, os.ErrNotExist)
+}
+
+// 便于测试替换
+var sleep = time.Sleep
+
+// OpenWithRetry 调用 open 打开端口，端口不存在时最多重试 retries 次，每次间隔 delay
+// 其他错误立即返回
+func OpenWithRetry[P any](open func() (P, error), retries int, delay time.Duration) (P, error) {
+	if retries > MaxRetries {
+		retries = MaxRetries
+	}
+	for attempt := 0; ; attempt++ {
+		port, err := open()
+		if err == nil || attempt >= retries || !IsNotFound(err) {
+			return port, err
+		}
+		sleep(delay)
+	}
+}
@@ -0,0 +1,184 @@
+package serialport
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"go.bug.st/serial"
+)
+
+type fakeLines struct {
+	calls []string
+}
+
+func (f *fakeLines) SetDTR(v bool) error {
+	f.calls = append(f.calls, fmt.Sprintf("DTR=%v", v))
+	return nil
+}
+
+func (f *fakeLines) SetRTS(v bool) error {
+	f.calls = append(f.calls, fmt.Sprintf("RTS=%v", v))
+	return nil
+}
+
+func TestApplyLines(t *testing.T) {
+	tests := []struct {
+		dtr, rts LineState
+		expected string
+	}{
+		{LineHigh, LineHigh, "[DTR=true RTS=true]"},
+		{LineLow, LineKeep, "[DTR=false]"},
+		{LineKeep, LineLow, "[RTS=false]"},
+		{LineKeep, LineKeep, "[]"},
+	}
+	for _, tt := range tests {
+		f := &fakeLines{}
+		if err := ApplyLines(f, tt.dtr, tt.rts); err != nil {
+			t.Fatalf("ApplyLines failed: %v", err)
+		}
+		if got := fmt.Sprint(f.calls); got != tt.expected {
+			t.Errorf("ApplyLines(%q, %q) = %s, expected %s", tt.dtr, tt.rts, got, tt.expected)
+		}
+	}
+}
+
+func TestResolve(t *testing.T) {
+	if got := LineDefault.Resolve(LineDefault); got != LineHigh {
+		t.Errorf("Expected unsaved default to resolve to high, got %q", got)
+	}
+	if got := LineDefault.Resolve(LineLow); got != LineLow {
+		t.Errorf("Expected saved default low, got %q", got)
+	}
+	if got := LineKeep.Resolve(LineLow); got != LineKeep {
+		t.Errorf("Explicit state should win over saved default, got %q", got)
+	}
+	if _, err := ParseLineState("toggle"); err == nil {
+		t.Error("Expected error for unknown line state")
+	}
+}
+
+func TestIsNotFound(t *testing.T) {
+	tests := []struct {
+		err      error
+		expected bool
+	}{
+		{&serial.PortError{}, false}, // PortBusy
+		{fmt.Errorf("wrapped: %w", syscall.ENOENT), true},
+		{os.ErrNotExist, true},
+		{syscall.EACCES, false},
+		{errors.New("other"), false},
+	}
+	for _, tt := range tests {
+		if got := IsNotFound(tt.err); got != tt.expected {
+			t.Errorf("IsNotFound(%v) = %v, expected %v", tt.err, got, tt.expected)
+		}
+	}
+}
+
+func withFakeSleep(t *testing.T) *int {
+	slept := 0
+	sleep = func(time.Duration) { slept++ }
+	t.Cleanup(func() { sleep = time.Sleep })
+	return &slept
+}
+
+func TestOpenWithRetrySucceedsAfterEnumeration(t *testing.T) {
+	slept := withFakeSleep(t)
+	attempts := 0
+	port, err := OpenWithRetry(func() (string, error) {
+		attempts++
+		if attempts < 3 {
+			return "", syscall.ENOENT
+		}
+		return "COM3", nil
+	}, 5, time.Millisecond)
+
+	if err != nil || port != "COM3" {
+		t.Fatalf("Expected success, got %q, %v", port, err)
+	}
+	if attempts != 3 || *slept != 2 {
+		t.Errorf("Expected 3 attempts and 2 sleeps, got %d and %d", attempts, *slept)
+	}
+}
+
+func TestOpenWithRetryFailsFast(t *testing.T) {
+	withFakeSleep(t)
+	attempts := 0
+	_, err := OpenWithRetry(func() (string, error) {
+		attempts++
+		return "", syscall.EACCES
+	}, 5, time.Millisecond)
+
+	if !errors.Is(err, syscall.EACCES) {
+		t.Errorf("Expected access denied error, got %v", err)
+	}
+	if attempts != 1 {
+		t.Errorf("Access denied should not be retried, got %d attempts", attempts)
+	}
+}
+
+func TestOpenWithRetryGivesUp(t *testing.T) {
+	withFakeSleep(t)
+	attempts := 0
+	_, err := OpenWithRetry(func() (string, error) {
+		attempts++
+		return "", os.ErrNotExist
+	}, 2, time.Millisecond)
+
+	if err == nil || attempts != 3 {
+		t.Errorf("Expected failure after 3 attempts, got %d attempts, err=%v", attempts, err)
+	}
+}
+
+type fakeStatus struct {
+	bits serial.ModemStatusBits
+	err  error
+}
+
+func (f *fakeStatus) GetModemStatusBits() (*serial.ModemStatusBits, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	bits := f.bits
+	return &bits, nil
+}
+
+func TestLinkCheck(t *testing.T) {
+	if c, err := ParseLinkCheck(""); err != nil || c != LinkCheckOff {
+		t.Errorf("Expected empty name to mean off, got %q, %v", c, err)
+	}
+	if _, err := ParseLinkCheck("cts"); err == nil {
+		t.Error("Expected error for unsupported line")
+	}
+
+	tests := []struct {
+		check    LinkCheck
+		bits     serial.ModemStatusBits
+		expected bool
+	}{
+		{LinkCheckOff, serial.ModemStatusBits{}, true},
+		{LinkCheckDSR, serial.ModemStatusBits{DCD: true}, false},
+		{LinkCheckDSR, serial.ModemStatusBits{DSR: true}, true},
+		{LinkCheckDCD, serial.ModemStatusBits{DSR: true}, false},
+		{LinkCheckDCD, serial.ModemStatusBits{DCD: true}, true},
+		{LinkCheckAny, serial.ModemStatusBits{}, false},
+		{LinkCheckAny, serial.ModemStatusBits{DCD: true}, true},
+	}
+	for _, tt := range tests {
+		lines, err := ReadLines(&fakeStatus{bits: tt.bits})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := tt.check.DevicePresent(lines); got != tt.expected {
+			t.Errorf("%s with %+v: got %v, expected %v", tt.check, tt.bits, got, tt.expected)
+		}
+	}
+
+	if _, err := ReadLines(&fakeStatus{err: errors.New("ioctl failed")}); err == nil {
+		t.Error("Expected read error to be returned")
+	}
+}
@@ -0,0 +1,132 @@
+package serialport
+
+import (
+	"fmt"
+	"math/bits"
+	"sync"
+)
+
+// SevenBit 7 位数据模式下接收字节最高位的处理方式
+// 部分驱动或 USB 转换器在 7 位模式下仍把校验位或线路噪声放在最高位交给上层，
+// 不处理时文本中会出现乱码
+type SevenBit string
+
+const (
+	SevenBitAuto SevenBit = "auto" // 数据位为 7 时按 mask 处理，否则不处理 (默认)
+	SevenBitOff  SevenBit = "off"  // 原样保留
+	SevenBitMask SevenBit = "mask" // 清除最高位
+	SevenBitEven SevenBit = "even" // 最高位视为偶校验位：按 8 位校验后清除，失败时计入 ParityErrors
+	SevenBitOdd  SevenBit = "odd"  // 最高位视为奇校验位，同上
+)
+
+// ParseSevenBit 解析处理方式，空字符串等同于 "auto"
+func ParseSevenBit(name string) (SevenBit, error) {
+	switch m := SevenBit(name); m {
+	case "":
+		return SevenBitAuto, nil
+	case SevenBitAuto, SevenBitOff, SevenBitMask, SevenBitEven, SevenBitOdd:
+		return m, nil
+	default:
+		return "", fmt.Errorf("unknown 7-bit mode %q (expected auto, off, mask, even or odd)", name)
+	}
+}
+
+// Resolve 按数据位数确定实际生效的处理方式，结果不会是 SevenBitAuto
+func (m SevenBit) Resolve(dataBits int) SevenBit {
+	switch m {
+	case "", SevenBitAuto:
+		if dataBits == 7 {
+			return SevenBitMask
+		}
+		return SevenBitOff
+	}
+	return m
+}
+
+// Active 是否需要处理最高位
+func (m SevenBit) Active() bool {
+	return m != SevenBitOff && m != SevenBitAuto && m != ""
+}
+
+// SevenBitFilter 接收数据的最高位处理，位于接收处理链的最前端 (分帧与事件发送之前)；线程安全
+type SevenBitFilter struct {
+	mu           sync.Mutex
+	mode         SevenBit
+	parityErrors uint64
+}
+
+// NewSevenBitFilter 创建不做处理的过滤器
+func NewSevenBitFilter() *SevenBitFilter {
+	return &SevenBitFilter{mode: SevenBitOff}
+}
+
+// SetMode 设置生效的处理方式 (应已经过 Resolve)，不清零校验错误计数
+func (f *SevenBitFilter) SetMode(m SevenBit) {
+	f.mu.Lock()
+	f.mode = m
+	f.mu.Unlock()
+}
+
+// Mode 返回生效的处理方式
+func (f *SevenBitFilter) Mode() SevenBit {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.mode
+}
+
+// Reset 清零校验错误计数，在新连接建立时调用
+func (f *SevenBitFilter) Reset() {
+	f.mu.Lock()
+	f.parityErrors = 0
+	f.mu.Unlock()
+}
+
+// ParityErrors 返回 even/odd 模式下校验失败的字节数
+func (f *SevenBitFilter) ParityErrors() uint64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.parityErrors
+}
+
+// Process 按处理方式转换 data，可直接用作 stream.Map 的处理函数
+// 不处理时原样返回 data，否则返回新的切片；校验失败的字节同样清除最高位后保留
+func (f *SevenBitFilter) Process(data []byte) []byte {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !f.mode.Active() || len(data) == 0 {
+		return data
+	}
+	out := make([]byte, len(data))
+	for i, b := range data {
+		switch f.mode {
+		case SevenBitEven:
+			if bits.OnesCount8(b)%2 != 0 {
+				f.parityErrors++
+			}
+		case SevenBitOdd:
+			if bits.OnesCount8(b)%2 != 1 {
+				f.parityErrors++
+			}
+		}
+		out[i] = b & 0x7F
+	}
+	return out
+}
+
+// MaskTx 清除发送数据中的最高位，保证 7 位模式下只发送有效的 7 位
+// 没有字节需要修改时原样返回 p；masked 为被修改的字节数
+func MaskTx(p []byte) (out []byte, masked int) {
+	for _, b := range p {
+		if b&0x80 != 0 {
+			masked++
+		}
+	}
+	if masked == 0 {
+		return p, 0
+	}
+	out = make([]byte, len(p))
+	for i, b := range p {
+		out[i] = b & 0x7F
+	}
+	return out, masked
+}
@@ -0,0 +1,85 @@
+package serialport
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestSevenBitMask(t *testing.T) {
+	f := NewSevenBitFilter()
+	in := []byte{'H' | 0x80, 'i', '\r' | 0x80, '\n', 0xFF, 0x00}
+	if got := f.Process(in); !bytes.Equal(got, in) {
+		t.Fatalf("off: Process() = % X", got)
+	}
+
+	f.SetMode(SevenBitAuto.Resolve(7))
+	got := f.Process(in)
+	if want := []byte{'H', 'i', '\r', '\n', 0x7F, 0x00}; !bytes.Equal(got, want) {
+		t.Errorf("mask: Process() = % X, want % X", got, want)
+	}
+	if in[0] != 'H'|0x80 {
+		t.Error("Process() modified its input")
+	}
+	if f.ParityErrors() != 0 {
+		t.Errorf("mask counted %d parity errors", f.ParityErrors())
+	}
+}
+
+func TestSevenBitParity(t *testing.T) {
+	// 'A' = 0x41 有两个 1，偶校验位为 0；'C' = 0x43 有三个 1，偶校验位为 1
+	even := []byte{0x41, 0xC3, 0xC1, 0x43}
+	f := NewSevenBitFilter()
+	f.SetMode(SevenBitEven)
+	if got := f.Process(even); string(got) != "ACAC" {
+		t.Errorf("even: Process() = %q", got)
+	}
+	if n := f.ParityErrors(); n != 2 {
+		t.Errorf("even: ParityErrors() = %d, want 2", n)
+	}
+
+	// 同样的字节按奇校验，通过与失败的正好相反；计数跨 SetMode 累计，Reset 清零
+	f.SetMode(SevenBitOdd)
+	f.Process(even)
+	if n := f.ParityErrors(); n != 4 {
+		t.Errorf("odd: ParityErrors() = %d, want 4", n)
+	}
+	f.Reset()
+	if n := f.ParityErrors(); n != 0 {
+		t.Errorf("after Reset: %d", n)
+	}
+}
+
+func TestSevenBitResolve(t *testing.T) {
+	tests := []struct {
+		mode     SevenBit
+		dataBits int
+		want     SevenBit
+	}{
+		{SevenBitAuto, 7, SevenBitMask},
+		{SevenBitAuto, 8, SevenBitOff},
+		{SevenBitOff, 7, SevenBitOff},
+		{SevenBitEven, 8, SevenBitEven},
+	}
+	for _, tt := range tests {
+		if got := tt.mode.Resolve(tt.dataBits); got != tt.want {
+			t.Errorf("%q.Resolve(%d) = %q, want %q", tt.mode, tt.dataBits, got, tt.want)
+		}
+	}
+	if m, err := ParseSevenBit(""); m != SevenBitAuto || err != nil {
+		t.Errorf("ParseSevenBit(\"\") = %q, %v", m, err)
+	}
+	if _, err := ParseSevenBit("mark"); err == nil {
+		t.Error("Expected error for unknown mode")
+	}
+}
+
+func TestMaskTx(t *testing.T) {
+	p := []byte("plain")
+	if out, n := MaskTx(p); n != 0 || &out[0] != &p[0] {
+		t.Errorf("MaskTx(ASCII) = %q, %d", out, n)
+	}
+	out, n := MaskTx([]byte{'o', 0xEB, 0x80})
+	if n != 2 || !bytes.Equal(out, []byte{'o', 0x6B, 0x00}) {
+		t.Errorf("MaskTx() = % X, %d", out, n)
+	}
+}
@@ -0,0 +1,142 @@
+// Package session 统计一次连接 (会话) 的收发量、速率与错误次数，在断开时生成摘要
+package session
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Stats 一次连接的运行统计，线程安全
+type Stats struct {
+	mu    sync.Mutex
+	start time.Time
+
+	rx, tx direction
+
+	errors    uint64
+	triggers  uint64
+	lastError string
+}
+
+// direction 单个方向的累计量与峰值速率 (按一秒的固定窗口统计)
+type direction struct {
+	bytes       uint64
+	frames      uint64
+	windowStart time.Time
+	windowBytes uint64
+	peak        uint64 // 字节/秒
+}
+
+func (d *direction) add(now time.Time, n int) {
+	if now.Sub(d.windowStart) >= time.Second {
+		d.windowStart = now
+		d.windowBytes = 0
+	}
+	d.bytes += uint64(n)
+	d.frames++
+	d.windowBytes += uint64(n)
+	if d.windowBytes > d.peak {
+		d.peak = d.windowBytes
+	}
+}
+
+// New 创建从 start 开始的统计
+func New(start time.Time) *Stats {
+	return &Stats{start: start}
+}
+
+// AddRx 记录一个接收数据块
+func (s *Stats) AddRx(now time.Time, n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rx.add(now, n)
+}
+
+// AddTx 记录一次成功的发送
+func (s *Stats) AddTx(now time.Time, n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tx.add(now, n)
+}
+
+// AddError 记录一次连接错误
+func (s *Stats) AddError(msg string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.errors++
+	s.lastError = msg
+}
+
+// AddTrigger 记录一次触发 (例如接收静默看门狗)
+func (s *Stats) AddTrigger() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.triggers++
+}
+
+// Summary 会话摘要
+type Summary struct {
+	Type       string    `json:"type"`
+	Start      time.Time `json:"start"`
+	End        time.Time `json:"end"`
+	DurationMs int64     `json:"durationMs"`
+
+	RxBytes  uint64  `json:"rxBytes"`
+	TxBytes  uint64  `json:"txBytes"`
+	RxFrames uint64  `json:"rxFrames"` // 接收数据块数
+	TxFrames uint64  `json:"txFrames"` // 成功发送次数
+	RxAvg    float64 `json:"rxAvg"`    // 平均速率 (字节/秒)
+	TxAvg    float64 `json:"txAvg"`
+	RxPeak   uint64  `json:"rxPeak"` // 一秒窗口内的峰值 (字节/秒)
+	TxPeak   uint64  `json:"txPeak"`
+
+	Errors    uint64 `json:"errors"`
+	Triggers  uint64 `json:"triggers"`
+	Drops     uint64 `json:"drops"` // 由调用方填写，例如被历史缓冲区淘汰的字节数
+	LastError string `json:"lastError,omitempty"`
+}
+
+// Summary 生成截至 end 的摘要
+func (s *Stats) Summary(end time.Time) Summary {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sum := Summary{
+		Start:     s.start,
+		End:       end,
+		RxBytes:   s.rx.bytes,
+		TxBytes:   s.tx.bytes,
+		RxFrames:  s.rx.frames,
+		TxFrames:  s.tx.frames,
+		RxPeak:    s.rx.peak,
+		TxPeak:    s.tx.peak,
+		Errors:    s.errors,
+		Triggers:  s.triggers,
+		LastError: s.lastError,
+	}
+	d := end.Sub(s.start)
+	sum.DurationMs = d.Milliseconds()
+	if secs := d.Seconds(); secs > 0 {
+		sum.RxAvg = float64(s.rx.bytes) / secs
+		sum.TxAvg = float64(s.tx.bytes) / secs
+	}
+	return sum
+}
+
+// String 单行文本表示，用于标注与文件尾
+func (s Summary) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Session summary: %s", (time.Duration(s.DurationMs) * time.Millisecond).String())
+	if s.Type != "" {
+		fmt.Fprintf(&b, " %s", s.Type)
+	}
+	fmt.Fprintf(&b, ", RX %d B in %d frames (avg %.0f B/s, peak %d B/s)", s.RxBytes, s.RxFrames, s.RxAvg, s.RxPeak)
+	fmt.Fprintf(&b, ", TX %d B in %d frames (avg %.0f B/s, peak %d B/s)", s.TxBytes, s.TxFrames, s.TxAvg, s.TxPeak)
+	fmt.Fprintf(&b, ", %d errors, %d triggers, %d dropped", s.Errors, s.Triggers, s.Drops)
+	if s.LastError != "" {
+		fmt.Fprintf(&b, ", last error: %s", s.LastError)
+	}
+	return b.String()
+}
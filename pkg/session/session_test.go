@@ -0,0 +1,51 @@
+package session
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSummary(t *testing.T) {
+	start := time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC)
+	s := New(start)
+
+	s.AddRx(start, 100)
+	s.AddRx(start.Add(500*time.Millisecond), 300)
+	s.AddRx(start.Add(1500*time.Millisecond), 200)
+	s.AddTx(start.Add(2*time.Second), 50)
+	s.AddError("device removed")
+	s.AddTrigger()
+	s.AddTrigger()
+
+	sum := s.Summary(start.Add(4 * time.Second))
+	if sum.DurationMs != 4000 || sum.RxBytes != 600 || sum.RxFrames != 3 || sum.TxBytes != 50 || sum.TxFrames != 1 {
+		t.Errorf("Unexpected totals: %+v", sum)
+	}
+	if sum.RxAvg != 150 || sum.TxAvg != 12.5 {
+		t.Errorf("Unexpected averages: rx %v tx %v", sum.RxAvg, sum.TxAvg)
+	}
+	if sum.RxPeak != 400 || sum.TxPeak != 50 {
+		t.Errorf("Unexpected peaks: rx %d tx %d", sum.RxPeak, sum.TxPeak)
+	}
+	if sum.Errors != 1 || sum.Triggers != 2 {
+		t.Errorf("Unexpected counters: %+v", sum)
+	}
+
+	sum.Type = "SERIAL"
+	text := sum.String()
+	for _, want := range []string{"4s SERIAL", "RX 600 B in 3 frames", "peak 400 B/s", "1 errors", "last error: device removed"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("Expected %q in %q", want, text)
+		}
+	}
+}
+
+func TestSummaryZeroDuration(t *testing.T) {
+	start := time.Now()
+	s := New(start)
+	s.AddRx(start, 10)
+	if sum := s.Summary(start); sum.RxAvg != 0 || sum.RxBytes != 10 {
+		t.Errorf("Unexpected summary %+v", sum)
+	}
+}
@@ -0,0 +1,140 @@
+package settings
+
+import (
+	"fmt"
+	"time"
+
+	"serial-assistant/pkg/frame"
+	"serial-assistant/pkg/nametmpl"
+	"serial-assistant/pkg/textenc"
+)
+
+// 连接配置打开后自动执行的动作类型
+const (
+	ActionPcap         = "pcap"          // 开始抓包，Path 为文件名模板，为空时使用默认模板
+	ActionPlotCsv      = "plot-csv"      // 开始绘图 CSV 导出，Path 同上，Columns 为列名
+	ActionFrameDecoder = "frame-decoder" // 设置长度前缀帧解码，Frame 为帧格式，Schema 为可选的字段表
+	ActionRxEncoding   = "rx-encoding"   // 设置接收编码，Value 为编码名称
+	ActionCommand      = "command"       // 发送一次快捷指令，Name 为指令名称
+	ActionPeriodic     = "periodic"      // 每隔 IntervalMs 发送快捷指令 Name，直到连接关闭
+)
+
+// MaxActions 每个连接配置最多的动作数
+const MaxActions = 32
+
+// MinPeriodicInterval periodic 动作的最小间隔
+const MinPeriodicInterval = 10 * time.Millisecond
+
+// ProfileAction 连接配置打开后按顺序执行的一个动作，只使用与 Type 对应的字段
+type ProfileAction struct {
+	Type       string        `json:"type"`
+	Path       string        `json:"path,omitempty"`
+	Columns    []string      `json:"columns,omitempty"`
+	Frame      *frame.Config `json:"frame,omitempty"`
+	Schema     *frame.Schema `json:"schema,omitempty"`
+	Value      string        `json:"value,omitempty"`
+	Name       string        `json:"name,omitempty"`
+	IntervalMs int           `json:"intervalMs,omitempty"`
+}
+
+// Describe 返回动作的简短描述，用于事件与日志
+func (a ProfileAction) Describe() string {
+	switch a.Type {
+	case ActionPcap, ActionPlotCsv:
+		if a.Path == "" {
+			return a.Type + " (default file name)"
+		}
+		return a.Type + " " + a.Path
+	case ActionRxEncoding:
+		return a.Type + " " + a.Value
+	case ActionCommand:
+		return fmt.Sprintf("%s %q", a.Type, a.Name)
+	case ActionPeriodic:
+		return fmt.Sprintf("%s %q every %d ms", a.Type, a.Name, a.IntervalMs)
+	}
+	return a.Type
+}
+
+// Validate 检查动作的参数，并确认引用的快捷指令在 s 中存在
+func (a ProfileAction) Validate(s *Settings) error {
+	switch a.Type {
+	case ActionPcap, ActionPlotCsv:
+		if a.Path != "" {
+			if err := nametmpl.Validate(a.Path); err != nil {
+				return err
+			}
+		}
+	case ActionFrameDecoder:
+		if a.Frame == nil {
+			return fmt.Errorf("frame format is required")
+		}
+		if err := a.Frame.Validate(); err != nil {
+			return err
+		}
+		if a.Schema != nil {
+			if err := a.Schema.Validate(a.Frame.PayloadLimit()); err != nil {
+				return err
+			}
+		}
+	case ActionRxEncoding:
+		if _, err := textenc.ParseMode(a.Value); err != nil {
+			return err
+		}
+	case ActionCommand, ActionPeriodic:
+		if a.Type == ActionPeriodic && time.Duration(a.IntervalMs)*time.Millisecond < MinPeriodicInterval {
+			return fmt.Errorf("interval must be at least %d ms", MinPeriodicInterval.Milliseconds())
+		}
+		if !s.hasCommand(a.Name) {
+			return fmt.Errorf("command %q does not exist", a.Name)
+		}
+	default:
+		return fmt.Errorf("unknown action type %q", a.Type)
+	}
+	return nil
+}
+
+// ValidateActions 检查连接配置的全部动作，错误信息包含动作的序号
+func (p Profile) ValidateActions(s *Settings) error {
+	if len(p.OnConnect) > MaxActions {
+		return fmt.Errorf("too many onConnect actions (%d, max %d)", len(p.OnConnect), MaxActions)
+	}
+	for i, a := range p.OnConnect {
+		if err := a.Validate(s); err != nil {
+			return fmt.Errorf("onConnect action %d (%s): %w", i+1, a.Type, err)
+		}
+	}
+	return nil
+}
+
+func (d *Settings) hasCommand(name string) bool {
+	for _, c := range d.Commands {
+		if c.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// cloneActions 深拷贝动作列表
+func cloneActions(list []ProfileAction) []ProfileAction {
+	if list == nil {
+		return nil
+	}
+	out := make([]ProfileAction, len(list))
+	for i, a := range list {
+		if a.Columns != nil {
+			a.Columns = append([]string(nil), a.Columns...)
+		}
+		if a.Frame != nil {
+			f := *a.Frame
+			f.Sync = append([]byte(nil), f.Sync...)
+			a.Frame = &f
+		}
+		if a.Schema != nil {
+			s := frame.Schema{Fields: append([]frame.Field(nil), a.Schema.Fields...)}
+			a.Schema = &s
+		}
+		out[i] = a
+	}
+	return out
+}
@@ -0,0 +1,61 @@
+package settings
+
+import (
+	"strings"
+	"testing"
+
+	"serial-assistant/pkg/commands"
+	"serial-assistant/pkg/frame"
+)
+
+func TestValidateActions(t *testing.T) {
+	s := &Settings{Commands: []commands.Command{{Name: "start", Payload: "GO"}}}
+	ok := Profile{Name: "dut", OnConnect: []ProfileAction{
+		{Type: ActionPcap, Path: "captures/{{port}}_{{date}}_{{n}}.pcapng"},
+		{Type: ActionPlotCsv},
+		{Type: ActionFrameDecoder, Frame: &frame.Config{LenBytes: 1}},
+		{Type: ActionRxEncoding, Value: "utf-16le"},
+		{Type: ActionCommand, Name: "start"},
+		{Type: ActionPeriodic, Name: "start", IntervalMs: 1000},
+	}}
+	if err := ok.ValidateActions(s); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		action ProfileAction
+		msg    string
+	}{
+		{ProfileAction{Type: ActionCommand, Name: "missing"}, `command "missing" does not exist`},
+		{ProfileAction{Type: ActionPeriodic, Name: "start", IntervalMs: 1}, "interval"},
+		{ProfileAction{Type: ActionPcap, Path: "{{seq}}.pcapng"}, "unknown placeholder"},
+		{ProfileAction{Type: ActionFrameDecoder}, "frame format is required"},
+		{ProfileAction{Type: ActionFrameDecoder, Frame: &frame.Config{LenBytes: 3}}, "length field"},
+		{ProfileAction{Type: ActionFrameDecoder, Frame: &frame.Config{LenBytes: 1, MaxPayload: 4},
+			Schema: &frame.Schema{Fields: []frame.Field{{Name: "v", Offset: 2, Type: frame.U32LE}}}}, "exceed the 4 byte"},
+		{ProfileAction{Type: "reboot"}, "unknown action type"},
+	}
+	for _, tt := range tests {
+		p := Profile{OnConnect: []ProfileAction{{Type: ActionCommand, Name: "start"}, tt.action}}
+		err := p.ValidateActions(s)
+		if err == nil || !strings.Contains(err.Error(), tt.msg) || !strings.Contains(err.Error(), "action 2") {
+			t.Errorf("%+v: got %v, want %q", tt.action, err, tt.msg)
+		}
+	}
+}
+
+func TestProfileActionsAreCloned(t *testing.T) {
+	s := NewMemoryStore()
+	s.Update(func(d *Settings) {
+		d.Profiles = []Profile{{Name: "dut", OnConnect: []ProfileAction{
+			{Type: ActionFrameDecoder, Frame: &frame.Config{Sync: []byte{0xAA}, LenBytes: 1}},
+		}}}
+	})
+	got := s.Get()
+	got.Profiles[0].OnConnect[0].Frame.Sync[0] = 0
+	got.Profiles[0].OnConnect[0].Type = ActionCommand
+	again := s.Get().Profiles[0].OnConnect[0]
+	if again.Type != ActionFrameDecoder || again.Frame.Sync[0] != 0xAA {
+		t.Errorf("stored action was modified through a copy: %+v", again)
+	}
+}
@@ -0,0 +1,48 @@
+package settings
+
+import (
+	"serial-assistant/pkg/connspec"
+	"serial-assistant/pkg/devid"
+)
+
+// LinesFor 返回设备的 DTR/RTS 设置：优先按指纹查找，其次按端口名 (旧版本保存的或无法识别的设备)
+func (d *Settings) LinesFor(fingerprint, port string) (SerialLines, bool) {
+	if fingerprint != "" {
+		if l, ok := d.SerialLines[fingerprint]; ok {
+			return l, true
+		}
+	}
+	l, ok := d.SerialLines[port]
+	return l, ok
+}
+
+// AdoptDevice 把按端口名保存的设置迁移到当前插在该端口上的设备：DTR/RTS 设置改为按指纹保存，
+// 引用该端口且未绑定设备的串口/SLCAN 配置绑定到该指纹。返回修改的条目数
+// 只迁移带序列号的指纹；没有序列号的设备无法与同型号的其他设备区分，仍按端口名保存
+func (d *Settings) AdoptDevice(port, fingerprint string) int {
+	if port == "" || !devid.Fingerprint(fingerprint).Unique() {
+		return 0
+	}
+	changed := 0
+	if l, ok := d.SerialLines[port]; ok {
+		// 已有按指纹保存的设置时以其为准，端口名的条目已过时
+		if _, exists := d.SerialLines[fingerprint]; !exists {
+			d.SerialLines[fingerprint] = l
+		}
+		delete(d.SerialLines, port)
+		changed++
+	}
+	for i := range d.Profiles {
+		p := &d.Profiles[i]
+		if p.Device != "" {
+			continue
+		}
+		spec, err := connspec.Parse(p.Spec)
+		if err != nil || (spec.Kind != connspec.Serial && spec.Kind != connspec.Slcan) || spec.Port != port {
+			continue
+		}
+		p.Device = fingerprint
+		changed++
+	}
+	return changed
+}
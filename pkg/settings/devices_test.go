@@ -0,0 +1,44 @@
+package settings
+
+import "testing"
+
+func TestAdoptDevice(t *testing.T) {
+	const fp = "usb:0483:5740:3671359A3133"
+	d := &Settings{
+		SerialLines: map[string]SerialLines{"COM5": {DTR: "low"}, "COM6": {RTS: "low"}},
+		Profiles: []Profile{
+			{Name: "board", Spec: "serial:COM5@115200"},
+			{Name: "can", Spec: "slcan:COM5@500k"},
+			{Name: "other", Spec: "serial:COM6@9600"},
+			{Name: "bound", Spec: "serial:COM5@9600", Device: "usb:1a86:7523"},
+			{Name: "net", Spec: "tcp://COM5:23"},
+		},
+	}
+	if n := d.AdoptDevice("COM5", fp); n != 3 {
+		t.Errorf("AdoptDevice changed %d entries, expected 3", n)
+	}
+	if _, ok := d.SerialLines["COM5"]; ok || d.SerialLines[fp].DTR != "low" {
+		t.Errorf("SerialLines not migrated: %v", d.SerialLines)
+	}
+	want := map[string]string{"board": fp, "can": fp, "other": "", "bound": "usb:1a86:7523", "net": ""}
+	for _, p := range d.Profiles {
+		if p.Device != want[p.Name] {
+			t.Errorf("Profile %q bound to %q, expected %q", p.Name, p.Device, want[p.Name])
+		}
+	}
+	if l, ok := d.LinesFor(fp, "COM9"); !ok || l.DTR != "low" {
+		t.Errorf("LinesFor by fingerprint = %+v, %v", l, ok)
+	}
+	if l, ok := d.LinesFor("", "COM6"); !ok || l.RTS != "low" {
+		t.Errorf("LinesFor by port = %+v, %v", l, ok)
+	}
+
+	// 第二次迁移没有可改的条目
+	if n := d.AdoptDevice("COM5", fp); n != 0 {
+		t.Errorf("Second AdoptDevice changed %d entries", n)
+	}
+	// 没有序列号的设备不迁移
+	if n := d.AdoptDevice("COM6", "usb:1a86:7523"); n != 0 || d.SerialLines["COM6"].RTS != "low" {
+		t.Errorf("Devices without a serial number must keep port-name keys, changed %d", n)
+	}
+}
@@ -0,0 +1,66 @@
+package settings
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// SchemaVersion 当前设置文件的格式版本，保存时总是写入该版本
+const SchemaVersion = 1
+
+// migration 将版本 n 的文档就地转换为版本 n+1 的形状
+type migration func(doc map[string]json.RawMessage) error
+
+// migrations[n] 处理版本 n，新增格式变化时在末尾追加并递增 SchemaVersion
+var migrations = []migration{
+	// 0 -> 1：引入 schemaVersion 之前写入的文件已是版本 1 的形状，只需写入版本号
+	func(doc map[string]json.RawMessage) error { return nil },
+}
+
+// ErrReadOnly 设置文件由更新版本的程序写入，本次运行不保存任何修改，避免丢失不认识的字段
+var ErrReadOnly = errors.New("settings are read-only")
+
+// schemaVersionOf 返回文档的格式版本，没有 schemaVersion 字段时为 0
+func schemaVersionOf(doc map[string]json.RawMessage) (int, error) {
+	raw, ok := doc["schemaVersion"]
+	if !ok {
+		return 0, nil
+	}
+	var v int
+	if err := json.Unmarshal(raw, &v); err != nil || v < 0 {
+		return 0, fmt.Errorf("invalid schemaVersion %s", raw)
+	}
+	return v, nil
+}
+
+// migrate 依次执行 from 之后的迁移，返回当前版本形状的文档
+func migrate(doc map[string]json.RawMessage, from int) error {
+	for v := from; v < len(migrations); v++ {
+		if err := migrations[v](doc); err != nil {
+			return fmt.Errorf("migrating settings from version %d: %w", v, err)
+		}
+	}
+	raw, _ := json.Marshal(len(migrations))
+	doc["schemaVersion"] = raw
+	return nil
+}
+
+// BackupPath 迁移前原文件的备份路径，例如 settings.json.v0.bak
+func BackupPath(path string, version int) string {
+	return fmt.Sprintf("%s.v%d.bak", path, version)
+}
+
+// writeBackup 原子写入迁移前的原文件，已有的同版本备份被覆盖
+func writeBackup(path string, raw []byte) error {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, raw, 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
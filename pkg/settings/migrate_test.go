@@ -0,0 +1,148 @@
+package settings
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// copyFixture 将 testdata 中的设置文件复制到临时目录
+func copyFixture(t *testing.T, name string) (path string, orig []byte) {
+	t.Helper()
+	orig, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	path = filepath.Join(t.TempDir(), "settings.json")
+	if err := os.WriteFile(path, orig, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path, orig
+}
+
+func fileVersion(t *testing.T, path string) int {
+	t.Helper()
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		t.Fatal(err)
+	}
+	v, err := schemaVersionOf(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func TestEveryVersionHasMigration(t *testing.T) {
+	if len(migrations) != SchemaVersion {
+		t.Fatalf("%d migrations for schema version %d", len(migrations), SchemaVersion)
+	}
+}
+
+func TestMigrateV0(t *testing.T) {
+	path, orig := copyFixture(t, "settings-v0.json")
+	s, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open() failed: %v", err)
+	}
+	if s.FileVersion() != 0 || s.ReadOnly() || s.Backup() != BackupPath(path, 0) {
+		t.Fatalf("version %d, read-only %v, backup %q", s.FileVersion(), s.ReadOnly(), s.Backup())
+	}
+
+	got := s.Get()
+	if got.SchemaVersion != SchemaVersion || got.JLinkResetStrategies["STM32F407VG"] != "under-reset" ||
+		got.SerialLines["COM3"].DTR != "low" || got.InitPayload.Data != "ATE0\r\n" || got.InitPayload.DelayMs != 200 ||
+		len(got.Schedules) != 1 || got.Commands[0].Payload != "AT+GMR" || got.UpdatePolicy != "notify-only" ||
+		got.Profiles[0].Spec != "serial:COM3?baud=9600" || got.OnboardingVersion != 1 {
+		t.Errorf("Migrated settings lost data: %+v", got)
+	}
+
+	// 原文件保留在备份中，设置文件已按当前版本重写
+	if backup, _ := os.ReadFile(s.Backup()); !bytes.Equal(backup, orig) {
+		t.Error("Backup differs from the original file")
+	}
+	if v := fileVersion(t, path); v != SchemaVersion {
+		t.Errorf("Saved file has version %d", v)
+	}
+	reopened, err := Open(path)
+	if err != nil || reopened.Backup() != "" || reopened.FileVersion() != SchemaVersion {
+		t.Errorf("Reopen: %v, backup %q, version %d", err, reopened.Backup(), reopened.FileVersion())
+	}
+}
+
+func TestMigrationFailureLeavesFileUntouched(t *testing.T) {
+	saved := migrations
+	t.Cleanup(func() { migrations = saved })
+	migrations = []migration{func(map[string]json.RawMessage) error { return errors.New("boom") }}
+
+	path, orig := copyFixture(t, "settings-v0.json")
+	if _, err := Open(path); err == nil {
+		t.Fatal("Expected the migration error")
+	}
+	if raw, _ := os.ReadFile(path); !bytes.Equal(raw, orig) {
+		t.Error("Settings file was modified")
+	}
+	if _, err := os.Stat(BackupPath(path, 0)); !os.IsNotExist(err) {
+		t.Errorf("Backup written for a failed migration: %v", err)
+	}
+}
+
+func TestMigrationsRunInOrder(t *testing.T) {
+	saved := migrations
+	t.Cleanup(func() { migrations = saved })
+	var order []string
+	step := func(name string) migration {
+		return func(doc map[string]json.RawMessage) error {
+			order = append(order, name)
+			doc["caFile"] = json.RawMessage(`"` + name + `"`)
+			return nil
+		}
+	}
+	migrations = []migration{step("0->1"), step("1->2"), step("2->3")}
+
+	doc := map[string]json.RawMessage{}
+	if err := migrate(doc, 1); err != nil {
+		t.Fatal(err)
+	}
+	if len(order) != 2 || order[0] != "1->2" || string(doc["caFile"]) != `"2->3"` || string(doc["schemaVersion"]) != "3" {
+		t.Errorf("order %v, doc %s %s", order, doc["caFile"], doc["schemaVersion"])
+	}
+}
+
+func TestOpenNewerVersionReadOnly(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "settings.json")
+	orig := []byte(`{"schemaVersion": 99, "caFile": "/etc/ca.pem", "somethingNew": {"x": 1}}`)
+	os.WriteFile(path, orig, 0644)
+
+	s, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open() failed: %v", err)
+	}
+	if !s.ReadOnly() || s.FileVersion() != 99 || s.Get().CAFile != "/etc/ca.pem" {
+		t.Fatalf("read-only %v, version %d, settings %+v", s.ReadOnly(), s.FileVersion(), s.Get())
+	}
+	if err := s.Update(func(d *Settings) { d.CAFile = "" }); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("Update() = %v, want ErrReadOnly", err)
+	}
+	if raw, _ := os.ReadFile(path); !bytes.Equal(raw, orig) {
+		t.Error("Newer settings file was modified")
+	}
+	if s.Get().CAFile != "/etc/ca.pem" {
+		t.Error("Rejected update changed the settings in memory")
+	}
+}
+
+func TestOpenInvalidVersion(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "settings.json")
+	os.WriteFile(path, []byte(`{"schemaVersion": "two"}`), 0644)
+	if _, err := Open(path); err == nil {
+		t.Error("Expected error for invalid schemaVersion")
+	}
+}
@@ -0,0 +1,414 @@
+// Package settings 负责持久化用户设置 (JSON 文件，位于用户配置目录)
+package settings
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"serial-assistant/pkg/classify"
+	"serial-assistant/pkg/commands"
+	"serial-assistant/pkg/connspec"
+)
+
+// AppDirName 配置目录名称
+const AppDirName = "serial-mate"
+
+// Settings 持久化的用户设置
+type Settings struct {
+	// SchemaVersion 文件的格式版本 (见 SchemaVersion 常量)，保存时总是写入当前版本
+	SchemaVersion int `json:"schemaVersion"`
+
+	// JLinkResetStrategies 按芯片名记录用户选择的复位策略
+	JLinkResetStrategies map[string]string `json:"jlinkResetStrategies,omitempty"`
+
+	// JLinkPresets 按芯片名覆盖内置的 J-Link 连接预设
+	JLinkPresets map[string]JLinkPreset `json:"jlinkPresets,omitempty"`
+
+	// JLinkLogLevel J-Link DLL 日志的转发级别 (off/errors/info/debug)，空表示 off
+	JLinkLogLevel string `json:"jlinkLogLevel,omitempty"`
+	// JLinkLogToFile 同时把 DLL 日志写入配置目录下的 logs/jlink.log
+	JLinkLogToFile bool `json:"jlinkLogToFile,omitempty"`
+	// JLinkLogMerge 驱动日志同时显示在接收区 (旧版行为)
+	JLinkLogMerge bool `json:"jlinkLogMerge,omitempty"`
+
+	// SerialLines 记录 DTR/RTS 的初始状态，键为设备指纹 (见 devid 包) 或旧版本保存的端口名，
+	// 旧的端口名键在设备下次出现时迁移 (见 AdoptDevice)
+	SerialLines map[string]SerialLines `json:"serialLines,omitempty"`
+
+	// DeviceAliases 按设备指纹记录用户起的别名
+	DeviceAliases map[string]string `json:"deviceAliases,omitempty"`
+
+	// InitPayload 连接建立后自动发送的初始化数据，nil 表示不发送
+	InitPayload *InitPayload `json:"initPayload,omitempty"`
+
+	// Schedules 定时发送任务
+	Schedules []ScheduledSend `json:"schedules,omitempty"`
+
+	// Commands 快捷指令
+	Commands []commands.Command `json:"commands,omitempty"`
+
+	// ClassifierRules 接收行分类规则
+	ClassifierRules []classify.Rule `json:"classifierRules,omitempty"`
+
+	// LineTiming 接收行的耗时计算 (见 linetime 包)，nil 表示关闭
+	LineTiming *LineTiming `json:"lineTiming,omitempty"`
+
+	// IgnoreSleep 系统睡眠/唤醒时不关闭与重新打开连接
+	IgnoreSleep bool `json:"ignoreSleep,omitempty"`
+
+	// CAFile 对外 HTTPS 请求 (检查更新等) 额外信任的 CA 证书文件 (PEM)
+	CAFile string `json:"caFile,omitempty"`
+
+	// LogHeaderTemplate 日志文件头模板 (语法见 loghdr 包)，为空时使用默认模板
+	LogHeaderTemplate string `json:"logHeaderTemplate,omitempty"`
+
+	// UpdatePolicy 自动更新策略："full"、"notify-only" 或 "disabled"，为空时为 full；
+	// 系统策略文件与环境变量优先 (见 updater.ResolvePolicy)
+	UpdatePolicy string `json:"updatePolicy,omitempty"`
+
+	// RxQueue 读取循环与处理 goroutine 之间的接收队列，为空时使用默认值
+	RxQueue *RxQueue `json:"rxQueue,omitempty"`
+
+	// MemoryBudgetMB 历史、导出缓冲与接收队列共享的内存预算 (MB)，0 表示默认值
+	MemoryBudgetMB int `json:"memoryBudgetMB,omitempty"`
+
+	// FileNames 导出与抓包的默认文件名模板 (语法见 nametmpl 包)
+	FileNames *FileNames `json:"fileNames,omitempty"`
+
+	// Profiles 保存的连接配置
+	Profiles []Profile `json:"profiles,omitempty"`
+
+	// UserActions 用户定义的动作 (快捷键等)，见 UserAction
+	UserActions []UserAction `json:"userActions,omitempty"`
+
+	// OnboardingVersion 已完成的首次启动引导版本 (安装示例配置)，0 表示尚未运行
+	OnboardingVersion int `json:"onboardingVersion,omitempty"`
+}
+
+// Profile 保存的连接配置，Spec 为连接字符串 (语法见 connspec 包)
+type Profile struct {
+	Name        string `json:"name"`
+	Spec        string `json:"spec"`
+	Description string `json:"description,omitempty"`
+	Sample      bool   `json:"sample,omitempty"` // 由首次启动引导安装的示例，用户修改后清除
+	// Device 串口/SLCAN 配置绑定的设备指纹，打开时 Spec 中的端口名替换为设备当前的端口
+	Device string `json:"device,omitempty"`
+	// OnConnect 打开成功后按顺序执行的动作，引用的快捷指令在保存时检查
+	OnConnect []ProfileAction `json:"onConnect,omitempty"`
+}
+
+// FileNames 文件名模板设置
+type FileNames struct {
+	// Templates 按功能 ("plot-csv"、"pcap"、"commands"、"diagnostics"、"html"、"recording"、"lines"、"burst") 记录默认模板
+	Templates map[string]string `json:"templates,omitempty"`
+	// DateLayout/TimeLayout {{date}} 与 {{time}} 的格式 (Go 时间格式)，为空时使用默认值
+	DateLayout string `json:"dateLayout,omitempty"`
+	TimeLayout string `json:"timeLayout,omitempty"`
+}
+
+// LineTiming 接收行的耗时设置
+type LineTiming struct {
+	Enabled bool `json:"enabled"` // 数据事件附带每行的耗时
+	// MarkPatterns 匹配时自动设置计时标记的正则表达式 (例如 "^BOOT")，导出同样使用
+	MarkPatterns []string `json:"markPatterns,omitempty"`
+}
+
+// RxQueue 接收队列参数
+type RxQueue struct {
+	Depth        int    `json:"depth,omitempty"`        // 可容纳的数据块数，0 表示默认值
+	Policy       string `json:"policy,omitempty"`       // 队列满时的策略："block" (默认) 或 "drop"
+	DrainOnClose bool   `json:"drainOnClose,omitempty"` // 关闭连接时处理完已读取的数据，而不是丢弃
+}
+
+// ScheduledSend 定时发送任务，Expr 的格式见 schedule 包
+type ScheduledSend struct {
+	ID        string `json:"id"`
+	Expr      string `json:"expr"`
+	Data      string `json:"data"`
+	Hex       bool   `json:"hex,omitempty"`
+	Reconnect bool   `json:"reconnect,omitempty"` // 触发时未连接则先重新打开上一次的连接
+}
+
+// InitPayload 连接建立后自动发送的数据 (例如调制解调器的 "ATE0\r\n")
+type InitPayload struct {
+	Data    string `json:"data"`
+	Hex     bool   `json:"hex,omitempty"` // Data 为十六进制字符串 (允许空格分隔)
+	DelayMs int    `json:"delayMs,omitempty"`
+	// Kinds 发送初始化数据的连接类型，为空时只用于串口
+	Kinds []connspec.Kind `json:"kinds,omitempty"`
+}
+
+// AppliesTo 初始化数据是否用于 kind 类型的连接
+func (p InitPayload) AppliesTo(kind connspec.Kind) bool {
+	if len(p.Kinds) == 0 {
+		return kind == connspec.Serial
+	}
+	for _, k := range p.Kinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// JLinkPreset 芯片的 J-Link 连接预设，零值字段表示沿用内置预设
+type JLinkPreset struct {
+	Speed          int    `json:"speed,omitempty"`
+	Interface      string `json:"interface,omitempty"`
+	RTTSearchStart uint32 `json:"rttSearchStart,omitempty"`
+	RTTSearchSize  uint32 `json:"rttSearchSize,omitempty"`
+}
+
+// SerialLines 串口控制线的初始状态 ("high"/"low"/"keep")
+type SerialLines struct {
+	DTR string `json:"dtr,omitempty"`
+	RTS string `json:"rts,omitempty"`
+}
+
+// Store 线程安全的设置存储
+// path 为空时仅保存在内存中（例如无法确定配置目录时）
+type Store struct {
+	mu    sync.Mutex
+	path  string
+	data  Settings
+	fresh bool
+
+	// fileVersion 打开时文件的格式版本，backup 为迁移前的备份路径 (未迁移时为空)，
+	// readOnly 为 true 时文件版本比程序更新，Update 不保存
+	fileVersion int
+	backup      string
+	readOnly    bool
+}
+
+// ConfigDir 返回应用配置目录 (例如 ~/.config/serial-mate)
+func ConfigDir() (string, error) {
+	base, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate config dir: %w", err)
+	}
+	return filepath.Join(base, AppDirName), nil
+}
+
+// DefaultPath 返回默认的设置文件路径
+func DefaultPath() (string, error) {
+	dir, err := ConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "settings.json"), nil
+}
+
+// Open 从 path 加载设置，文件不存在时返回空设置
+// 旧版本的文件依次执行迁移后立即以当前版本保存，原文件先备份到 BackupPath；迁移失败时文件保持不变。
+// 比程序更新的版本照常加载 (不认识的字段被忽略)，但存储为只读 (见 ReadOnly)，以免覆盖新版本的设置
+func Open(path string) (*Store, error) {
+	s := &Store{path: path}
+	if path == "" {
+		return s, nil
+	}
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			s.fresh = true
+			return s, nil
+		}
+		return nil, fmt.Errorf("failed to read settings: %w", err)
+	}
+	var doc map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse settings: %w", err)
+	}
+	if s.fileVersion, err = schemaVersionOf(doc); err != nil {
+		return nil, fmt.Errorf("failed to parse settings: %w", err)
+	}
+
+	orig := raw
+	switch {
+	case s.fileVersion > SchemaVersion:
+		s.readOnly = true
+	case s.fileVersion < SchemaVersion:
+		if err := migrate(doc, s.fileVersion); err != nil {
+			return nil, err
+		}
+		if raw, err = json.Marshal(doc); err != nil {
+			return nil, fmt.Errorf("failed to encode migrated settings: %w", err)
+		}
+	}
+	if err := json.Unmarshal(raw, &s.data); err != nil {
+		return nil, fmt.Errorf("failed to parse settings: %w", err)
+	}
+	if s.fileVersion < SchemaVersion {
+		if err := s.saveMigrated(orig); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// saveMigrated 备份原文件内容 orig 并以当前版本保存迁移后的设置
+func (s *Store) saveMigrated(orig []byte) error {
+	backup := BackupPath(s.path, s.fileVersion)
+	if err := writeBackup(backup, orig); err != nil {
+		return fmt.Errorf("failed to back up settings before migration: %w", err)
+	}
+	if err := s.saveLocked(s.data); err != nil {
+		return err
+	}
+	s.backup = backup
+	return nil
+}
+
+// NewMemoryStore 创建不落盘的设置存储
+func NewMemoryStore() *Store {
+	return &Store{}
+}
+
+// Fresh 打开时设置文件尚不存在 (首次启动)；内存存储返回 false
+func (s *Store) Fresh() bool {
+	return s.fresh
+}
+
+// Path 返回设置文件路径，内存存储返回空字符串
+func (s *Store) Path() string {
+	return s.path
+}
+
+// FileVersion 返回打开时文件的格式版本，文件不存在或内存存储时为 0
+func (s *Store) FileVersion() int {
+	return s.fileVersion
+}
+
+// Backup 返回打开时迁移前的备份路径，未迁移时为空字符串
+func (s *Store) Backup() string {
+	return s.backup
+}
+
+// ReadOnly 文件由更新版本的程序写入，Update 返回 ErrReadOnly 且不保存
+func (s *Store) ReadOnly() bool {
+	return s.readOnly
+}
+
+// Get 返回当前设置的副本
+func (s *Store) Get() Settings {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.data.clone()
+}
+
+// Update 修改设置并立即保存
+// fn 收到的是副本，只有保存成功后才会替换内存中的设置
+func (s *Store) Update(fn func(*Settings)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.readOnly {
+		return fmt.Errorf("%w: the settings file is version %d, this build supports version %d; update serial-mate to change settings",
+			ErrReadOnly, s.fileVersion, SchemaVersion)
+	}
+	next := s.data.clone()
+	fn(&next)
+	if err := s.saveLocked(next); err != nil {
+		return err
+	}
+	s.data = next
+	return nil
+}
+
+// saveLocked 原子写入：先写临时文件，再重命名覆盖
+func (s *Store) saveLocked(data Settings) error {
+	if s.path == "" {
+		return nil
+	}
+
+	data.SchemaVersion = SchemaVersion
+	raw, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode settings: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return fmt.Errorf("failed to create config dir: %w", err)
+	}
+
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, raw, 0644); err != nil {
+		return fmt.Errorf("failed to write settings: %w", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to replace settings: %w", err)
+	}
+	return nil
+}
+
+// clone 深拷贝设置，避免调用方修改共享的 map
+func (d Settings) clone() Settings {
+	out := d
+	if d.JLinkResetStrategies != nil {
+		out.JLinkResetStrategies = make(map[string]string, len(d.JLinkResetStrategies))
+		for k, v := range d.JLinkResetStrategies {
+			out.JLinkResetStrategies[k] = v
+		}
+	}
+	if d.JLinkPresets != nil {
+		out.JLinkPresets = make(map[string]JLinkPreset, len(d.JLinkPresets))
+		for k, v := range d.JLinkPresets {
+			out.JLinkPresets[k] = v
+		}
+	}
+	if d.InitPayload != nil {
+		p := *d.InitPayload
+		p.Kinds = append([]connspec.Kind(nil), p.Kinds...)
+		out.InitPayload = &p
+	}
+	if d.SerialLines != nil {
+		out.SerialLines = make(map[string]SerialLines, len(d.SerialLines))
+		for k, v := range d.SerialLines {
+			out.SerialLines[k] = v
+		}
+	}
+	if d.DeviceAliases != nil {
+		out.DeviceAliases = make(map[string]string, len(d.DeviceAliases))
+		for k, v := range d.DeviceAliases {
+			out.DeviceAliases[k] = v
+		}
+	}
+	if d.Schedules != nil {
+		out.Schedules = append([]ScheduledSend(nil), d.Schedules...)
+	}
+	if d.Commands != nil {
+		out.Commands = append([]commands.Command(nil), d.Commands...)
+	}
+	if d.ClassifierRules != nil {
+		out.ClassifierRules = append([]classify.Rule(nil), d.ClassifierRules...)
+	}
+	if d.Profiles != nil {
+		out.Profiles = append([]Profile(nil), d.Profiles...)
+		for i := range out.Profiles {
+			out.Profiles[i].OnConnect = cloneActions(out.Profiles[i].OnConnect)
+		}
+	}
+	if d.UserActions != nil {
+		out.UserActions = append([]UserAction(nil), d.UserActions...)
+	}
+	if d.LineTiming != nil {
+		lt := *d.LineTiming
+		lt.MarkPatterns = append([]string(nil), lt.MarkPatterns...)
+		out.LineTiming = &lt
+	}
+	if d.FileNames != nil {
+		fn := *d.FileNames
+		if fn.Templates != nil {
+			fn.Templates = make(map[string]string, len(d.FileNames.Templates))
+			for k, v := range d.FileNames.Templates {
+				fn.Templates[k] = v
+			}
+		}
+		out.FileNames = &fn
+	}
+	return out
+}
@@ -0,0 +1,94 @@
+package settings
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"serial-assistant/pkg/commands"
+	"serial-assistant/pkg/connspec"
+)
+
+func TestOpenMissingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "missing", "settings.json")
+	s, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open() on missing file failed: %v", err)
+	}
+	if got := s.Get(); got.JLinkResetStrategies != nil {
+		t.Errorf("Expected empty settings, got %+v", got)
+	}
+	if !s.Fresh() {
+		t.Error("Expected a missing settings file to be reported as fresh")
+	}
+}
+
+func TestUpdatePersists(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "settings.json")
+	s, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open() failed: %v", err)
+	}
+
+	err = s.Update(func(d *Settings) {
+		d.JLinkResetStrategies = map[string]string{"STM32F407VG": "under-reset"}
+	})
+	if err != nil {
+		t.Fatalf("Update() failed: %v", err)
+	}
+
+	if _, err := os.Stat(path + ".tmp"); !os.IsNotExist(err) {
+		t.Error("Temporary file should not remain after save")
+	}
+
+	reloaded, err := Open(path)
+	if err != nil {
+		t.Fatalf("Reopen failed: %v", err)
+	}
+	if got := reloaded.Get().JLinkResetStrategies["STM32F407VG"]; got != "under-reset" {
+		t.Errorf("Expected persisted strategy 'under-reset', got %q", got)
+	}
+	if reloaded.Fresh() {
+		t.Error("Existing settings file should not be reported as fresh")
+	}
+	if reloaded.FileVersion() != SchemaVersion || reloaded.Backup() != "" {
+		t.Errorf("Saved file has version %d (backup %q), want %d", reloaded.FileVersion(), reloaded.Backup(), SchemaVersion)
+	}
+}
+
+func TestGetReturnsCopy(t *testing.T) {
+	s := NewMemoryStore()
+	s.Update(func(d *Settings) {
+		d.JLinkResetStrategies = map[string]string{"a": "normal"}
+		d.Schedules = []ScheduledSend{{ID: "reboot", Expr: "02:00", Data: "REBOOT"}}
+		d.Commands = []commands.Command{{Name: "version", Payload: "AT+GMR"}}
+		d.InitPayload = &InitPayload{Data: "ATE0\r\n", Kinds: []connspec.Kind{connspec.Serial}}
+	})
+
+	copy := s.Get()
+	copy.JLinkResetStrategies["a"] = "changed"
+	copy.Schedules[0].Data = "changed"
+	copy.Commands[0].Payload = "changed"
+	copy.InitPayload.Kinds[0] = connspec.Udp
+
+	if got := s.Get().JLinkResetStrategies["a"]; got != "normal" {
+		t.Errorf("Modifying Get() result changed the store: %q", got)
+	}
+	if got := s.Get().Schedules[0].Data; got != "REBOOT" {
+		t.Errorf("Modifying Get() schedules changed the store: %q", got)
+	}
+	if got := s.Get().Commands[0].Payload; got != "AT+GMR" {
+		t.Errorf("Modifying Get() commands changed the store: %q", got)
+	}
+	if got := s.Get().InitPayload.Kinds[0]; got != connspec.Serial {
+		t.Errorf("Modifying Get() init payload kinds changed the store: %q", got)
+	}
+}
+
+func TestOpenCorruptFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "settings.json")
+	os.WriteFile(path, []byte("{not json"), 0644)
+	if _, err := Open(path); err == nil {
+		t.Error("Expected error for corrupt settings file")
+	}
+}
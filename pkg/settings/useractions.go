@@ -0,0 +1,117 @@
+package settings
+
+import (
+	"fmt"
+	"strings"
+
+	"serial-assistant/pkg/input"
+	"serial-assistant/pkg/nametmpl"
+)
+
+// 用户动作类型，见 UserAction
+const (
+	UserSend           = "send"            // 发送 Payload，Hex 时为十六进制字符串
+	UserCommand        = "command"         // 发送快捷指令 Name
+	UserSetLine        = "set-line"        // 将串口控制线 Line ("dtr" 或 "rts") 设为 Value ("high"、"low" 或 "toggle")
+	UserStartRecording = "start-recording" // 开始会话录制，Path 为文件名模板，为空时使用默认模板
+	UserStopRecording  = "stop-recording"  // 结束会话录制
+	UserOpenProfile    = "open-profile"    // 打开连接配置 Name
+)
+
+// MaxUserActions 最多可注册的用户动作数
+const MaxUserActions = 64
+
+// MaxActionIDLen 动作 ID 的最大长度
+const MaxActionIDLen = 64
+
+// UserAction 用户定义的动作，前端的快捷键等只需按 ID 调用，只使用与 Type 对应的参数
+type UserAction struct {
+	ID     string       `json:"id"`
+	Type   string       `json:"type"`
+	Params ActionParams `json:"params"`
+}
+
+// ActionParams 用户动作的参数
+type ActionParams struct {
+	Payload string `json:"payload,omitempty"`
+	Hex     bool   `json:"hex,omitempty"`
+	Name    string `json:"name,omitempty"`
+	Line    string `json:"line,omitempty"`
+	Value   string `json:"value,omitempty"`
+	Path    string `json:"path,omitempty"`
+}
+
+// Describe 返回动作的简短描述，用于事件与日志
+func (a UserAction) Describe() string {
+	p := a.Params
+	switch a.Type {
+	case UserSend:
+		if p.Hex {
+			return fmt.Sprintf("%s hex %s", a.Type, p.Payload)
+		}
+		return fmt.Sprintf("%s %q", a.Type, p.Payload)
+	case UserCommand, UserOpenProfile:
+		return fmt.Sprintf("%s %q", a.Type, p.Name)
+	case UserSetLine:
+		return fmt.Sprintf("%s %s %s", a.Type, strings.ToUpper(p.Line), p.Value)
+	case UserStartRecording:
+		if p.Path == "" {
+			return a.Type + " (default file name)"
+		}
+		return a.Type + " " + p.Path
+	}
+	return a.Type
+}
+
+// Validate 检查 ID 与参数，并确认引用的快捷指令或连接配置在 s 中存在
+func (a UserAction) Validate(s *Settings) error {
+	if a.ID == "" || len(a.ID) > MaxActionIDLen || strings.TrimSpace(a.ID) != a.ID {
+		return fmt.Errorf("action id must be 1 to %d characters without leading or trailing spaces", MaxActionIDLen)
+	}
+	p := a.Params
+	switch a.Type {
+	case UserSend:
+		if p.Payload == "" {
+			return fmt.Errorf("payload is required")
+		}
+		if p.Hex {
+			if _, err := input.ParseHex(p.Payload); err != nil {
+				return fmt.Errorf("invalid hex payload: %w", err)
+			}
+		}
+	case UserCommand:
+		if !s.hasCommand(p.Name) {
+			return fmt.Errorf("command %q does not exist", p.Name)
+		}
+	case UserSetLine:
+		if p.Line != "dtr" && p.Line != "rts" {
+			return fmt.Errorf("unknown line %q (expected dtr or rts)", p.Line)
+		}
+		if p.Value != "high" && p.Value != "low" && p.Value != "toggle" {
+			return fmt.Errorf("unknown line value %q (expected high, low or toggle)", p.Value)
+		}
+	case UserStartRecording:
+		if p.Path != "" {
+			if err := nametmpl.Validate(p.Path); err != nil {
+				return err
+			}
+		}
+	case UserStopRecording:
+	case UserOpenProfile:
+		if !s.hasProfile(p.Name) {
+			return fmt.Errorf("profile %q does not exist", p.Name)
+		}
+	default:
+		return fmt.Errorf("unknown action type %q", a.Type)
+	}
+	return nil
+}
+
+func (d *Settings) hasProfile(name string) bool {
+	for _, p := range d.Profiles {
+		if p.Name == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,47 @@
+package settings
+
+import (
+	"strings"
+	"testing"
+
+	"serial-assistant/pkg/commands"
+)
+
+func TestValidateUserAction(t *testing.T) {
+	s := &Settings{
+		Commands: []commands.Command{{Name: "reset", Payload: "AT+RST"}},
+		Profiles: []Profile{{Name: "dut", Spec: "serial:COM3@115200"}},
+	}
+	for _, a := range []UserAction{
+		{ID: "f1", Type: UserSend, Params: ActionParams{Payload: "AA 55 01", Hex: true}},
+		{ID: "f2", Type: UserCommand, Params: ActionParams{Name: "reset"}},
+		{ID: "f3", Type: UserSetLine, Params: ActionParams{Line: "dtr", Value: "toggle"}},
+		{ID: "f4", Type: UserStartRecording, Params: ActionParams{Path: "rec/{{port}}_{{n}}.smrec"}},
+		{ID: "f5", Type: UserStopRecording},
+		{ID: "f6", Type: UserOpenProfile, Params: ActionParams{Name: "dut"}},
+	} {
+		if err := a.Validate(s); err != nil {
+			t.Errorf("%s: %v", a.Describe(), err)
+		}
+	}
+
+	tests := []struct {
+		action UserAction
+		msg    string
+	}{
+		{UserAction{ID: "", Type: UserStopRecording}, "action id"},
+		{UserAction{ID: " f1", Type: UserStopRecording}, "action id"},
+		{UserAction{ID: "f1", Type: UserSend}, "payload is required"},
+		{UserAction{ID: "f1", Type: UserSend, Params: ActionParams{Payload: "ZZ", Hex: true}}, "invalid hex"},
+		{UserAction{ID: "f1", Type: UserCommand, Params: ActionParams{Name: "missing"}}, `command "missing" does not exist`},
+		{UserAction{ID: "f1", Type: UserSetLine, Params: ActionParams{Line: "cts", Value: "high"}}, "unknown line"},
+		{UserAction{ID: "f1", Type: UserSetLine, Params: ActionParams{Line: "rts", Value: "on"}}, "unknown line value"},
+		{UserAction{ID: "f1", Type: UserOpenProfile, Params: ActionParams{Name: "lab"}}, `profile "lab" does not exist`},
+		{UserAction{ID: "f1", Type: "reboot"}, "unknown action type"},
+	}
+	for _, tt := range tests {
+		if err := tt.action.Validate(s); err == nil || !strings.Contains(err.Error(), tt.msg) {
+			t.Errorf("%+v: got %v, want %q", tt.action, err, tt.msg)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+// Package shutdown 按顺序执行退出前的清理步骤 (关闭连接、刷新文件等)，并限制总耗时
+package shutdown
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// DefaultTimeout 退出清理的默认总时限
+const DefaultTimeout = 5 * time.Second
+
+// Step 一个清理步骤
+type Step struct {
+	Name string
+	Run  func(ctx context.Context) error
+}
+
+// StepResult 单个步骤的执行结果
+type StepResult struct {
+	Name     string        `json:"name"`
+	Error    string        `json:"error,omitempty"`
+	Duration time.Duration `json:"duration"`
+}
+
+// Report 清理结果
+// TimedOut 为 true 时，Skipped 中的步骤没有执行 (或仍在后台运行)
+type Report struct {
+	Steps    []StepResult `json:"steps"`
+	TimedOut bool         `json:"timedOut"`
+	Skipped  []string     `json:"skipped,omitempty"`
+}
+
+// Orchestrator 按注册顺序执行清理步骤，只会执行一次
+type Orchestrator struct {
+	mu     sync.Mutex
+	steps  []Step
+	once   sync.Once
+	report Report
+}
+
+// Add 注册清理步骤，按注册顺序执行
+func (o *Orchestrator) Add(name string, fn func(ctx context.Context) error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.steps = append(o.steps, Step{Name: name, Run: fn})
+}
+
+// Run 依次执行所有步骤，总耗时超过 timeout 时放弃剩余步骤并返回
+// 重复调用 (例如窗口关闭与 QuitApp 同时触发) 直接返回第一次的结果
+func (o *Orchestrator) Run(timeout time.Duration) Report {
+	o.once.Do(func() {
+		o.mu.Lock()
+		steps := append([]Step(nil), o.steps...)
+		o.mu.Unlock()
+
+		o.report = run(steps, timeout)
+	})
+	return o.report
+}
+
+func run(steps []Step, timeout time.Duration) Report {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var report Report
+	for i, step := range steps {
+		start := time.Now()
+		done := make(chan error, 1)
+		go func(step Step) {
+			defer func() {
+				if r := recover(); r != nil {
+					done <- fmt.Errorf("panic: %v", r)
+				}
+			}()
+			done <- step.Run(ctx)
+		}(step)
+
+		select {
+		case err := <-done:
+			res := StepResult{Name: step.Name, Duration: time.Since(start)}
+			if err != nil {
+				res.Error = err.Error()
+			}
+			report.Steps = append(report.Steps, res)
+		case <-ctx.Done():
+			// 卡住的步骤留在后台，不再等待
+			report.TimedOut = true
+			for _, s := range steps[i:] {
+				report.Skipped = append(report.Skipped, s.Name)
+			}
+			return report
+		}
+	}
+	return report
+}
@@ -0,0 +1,94 @@
+package shutdown
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestStepsRunInOrder(t *testing.T) {
+	var o Orchestrator
+	var mu sync.Mutex
+	var order []string
+	step := func(name string, err error) func(context.Context) error {
+		return func(context.Context) error {
+			mu.Lock()
+			order = append(order, name)
+			mu.Unlock()
+			return err
+		}
+	}
+
+	o.Add("close connection", step("close connection", nil))
+	o.Add("flush csv", step("flush csv", errors.New("disk full")))
+	o.Add("save settings", step("save settings", nil))
+
+	report := o.Run(time.Second)
+	expected := []string{"close connection", "flush csv", "save settings"}
+	if !reflect.DeepEqual(order, expected) {
+		t.Errorf("Steps ran in order %v, expected %v", order, expected)
+	}
+	if report.TimedOut || len(report.Steps) != 3 {
+		t.Fatalf("Unexpected report %+v", report)
+	}
+	if report.Steps[1].Error != "disk full" {
+		t.Errorf("A failing step should be reported but not stop later steps, got %+v", report.Steps[1])
+	}
+}
+
+func TestTimeoutSkipsRemainingSteps(t *testing.T) {
+	var o Orchestrator
+	ran := false
+	release := make(chan struct{})
+	defer close(release)
+
+	o.Add("stuck connection", func(context.Context) error {
+		<-release // 模拟卡住的连接，不响应 ctx
+		return nil
+	})
+	o.Add("after", func(context.Context) error {
+		ran = true
+		return nil
+	})
+
+	start := time.Now()
+	report := o.Run(50 * time.Millisecond)
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Run should give up after the timeout, took %v", elapsed)
+	}
+	if !report.TimedOut || !reflect.DeepEqual(report.Skipped, []string{"stuck connection", "after"}) {
+		t.Errorf("Unexpected report %+v", report)
+	}
+	if ran {
+		t.Error("Steps after the timeout must not run")
+	}
+}
+
+func TestRunOnlyOnce(t *testing.T) {
+	var o Orchestrator
+	calls := 0
+	o.Add("close", func(context.Context) error {
+		calls++
+		return nil
+	})
+
+	o.Run(time.Second)
+	o.Run(time.Second)
+	if calls != 1 {
+		t.Errorf("Expected cleanup to run once, ran %d times", calls)
+	}
+}
+
+func TestPanicIsReported(t *testing.T) {
+	var o Orchestrator
+	o.Add("boom", func(context.Context) error { panic("bad") })
+	o.Add("next", func(context.Context) error { return nil })
+
+	report := o.Run(time.Second)
+	if len(report.Steps) != 2 || report.Steps[0].Error != "panic: bad" {
+		t.Errorf("Unexpected report %+v", report)
+	}
+}
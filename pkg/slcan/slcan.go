@@ -0,0 +1,205 @@
+// Package slcan 实现 Lawicel SLCAN ASCII 协议的编码与解析
+// 用于 CANable 等通过虚拟串口通信的 USB-CAN 适配器
+package slcan
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// 协议常量
+const (
+	MaxStandardID = 0x7FF
+	MaxExtendedID = 0x1FFFFFFF
+	MaxDataLen    = 8
+
+	// BEL 适配器在命令出错时返回的字节
+	BEL = '\a'
+)
+
+// Bitrates 位速率代码 S0-S8 对应的波特率
+var Bitrates = []int{10000, 20000, 50000, 100000, 125000, 250000, 500000, 800000, 1000000}
+
+// ErrNotFrame 表示该行不是 CAN 帧（例如发送应答 "z"/"Z" 或其他命令回复）
+var ErrNotFrame = errors.New("not a CAN frame")
+
+// Frame 解析后的 CAN 帧
+type Frame struct {
+	ID           uint32 `json:"id"`
+	Extended     bool   `json:"extended"`
+	RTR          bool   `json:"rtr"`
+	DLC          int    `json:"dlc"`
+	Data         []byte `json:"data"`
+	Timestamp    int    `json:"timestamp"`    // 适配器时间戳 (0-59999 ms)，仅当 HasTimestamp 为 true 时有效
+	HasTimestamp bool   `json:"hasTimestamp"` // 行尾是否带有可选的时间戳后缀
+}
+
+// SetupCommands 返回配置并打开通道的命令序列
+// 先发送 C 关闭可能残留打开的通道，再设置速率并打开
+func SetupCommands(bitrateCode int) ([]string, error) {
+	if bitrateCode < 0 || bitrateCode >= len(Bitrates) {
+		return nil, fmt.Errorf("invalid bitrate code %d (expected 0-%d)", bitrateCode, len(Bitrates)-1)
+	}
+	return []string{"C\r", fmt.Sprintf("S%d\r", bitrateCode), "O\r"}, nil
+}
+
+// ListenOnlyCommands 同 SetupCommands，但以只听模式 (L) 打开通道：适配器不发送任何帧，也不应答 ACK
+func ListenOnlyCommands(bitrateCode int) ([]string, error) {
+	cmds, err := SetupCommands(bitrateCode)
+	if err != nil {
+		return nil, err
+	}
+	cmds[len(cmds)-1] = "L\r"
+	return cmds, nil
+}
+
+// CloseCommand 关闭 CAN 通道的命令
+const CloseCommand = "C\r"
+
+// Encode 将 CAN 帧编码为 SLCAN 发送命令 (包含结尾的 \r)
+func Encode(id uint32, data []byte, extended bool, rtr bool) (string, error) {
+	if len(data) > MaxDataLen {
+		return "", fmt.Errorf("data length %d exceeds %d bytes", len(data), MaxDataLen)
+	}
+
+	var sb strings.Builder
+	if extended {
+		if id > MaxExtendedID {
+			return "", fmt.Errorf("extended id 0x%X exceeds 0x%X", id, MaxExtendedID)
+		}
+		if rtr {
+			sb.WriteByte('R')
+		} else {
+			sb.WriteByte('T')
+		}
+		fmt.Fprintf(&sb, "%08X", id)
+	} else {
+		if id > MaxStandardID {
+			return "", fmt.Errorf("standard id 0x%X exceeds 0x%X", id, MaxStandardID)
+		}
+		if rtr {
+			sb.WriteByte('r')
+		} else {
+			sb.WriteByte('t')
+		}
+		fmt.Fprintf(&sb, "%03X", id)
+	}
+
+	fmt.Fprintf(&sb, "%d", len(data))
+	if !rtr {
+		sb.WriteString(strings.ToUpper(hex.EncodeToString(data)))
+	}
+	sb.WriteByte('\r')
+	return sb.String(), nil
+}
+
+// Parse 解析一行 SLCAN 数据（不含结尾的 \r）
+// 非帧的行返回 ErrNotFrame，格式错误的帧返回描述性错误
+func Parse(line string) (Frame, error) {
+	var f Frame
+	if line == "" {
+		return f, ErrNotFrame
+	}
+
+	idLen := 0
+	switch line[0] {
+	case 't':
+		idLen = 3
+	case 'r':
+		idLen = 3
+		f.RTR = true
+	case 'T':
+		idLen = 8
+		f.Extended = true
+	case 'R':
+		idLen = 8
+		f.Extended = true
+		f.RTR = true
+	default:
+		return f, ErrNotFrame
+	}
+
+	// 类型 + ID + DLC
+	if len(line) < 1+idLen+1 {
+		return f, fmt.Errorf("frame too short: %q", line)
+	}
+
+	id, err := strconv.ParseUint(line[1:1+idLen], 16, 32)
+	if err != nil {
+		return f, fmt.Errorf("invalid id in %q", line)
+	}
+	if (f.Extended && id > MaxExtendedID) || (!f.Extended && id > MaxStandardID) {
+		return f, fmt.Errorf("id out of range in %q", line)
+	}
+	f.ID = uint32(id)
+
+	dlcChar := line[1+idLen]
+	if dlcChar < '0' || dlcChar > '8' {
+		return f, fmt.Errorf("invalid dlc in %q", line)
+	}
+	f.DLC = int(dlcChar - '0')
+
+	rest := line[2+idLen:]
+	if !f.RTR {
+		dataLen := f.DLC * 2
+		if len(rest) < dataLen {
+			return f, fmt.Errorf("data shorter than dlc in %q", line)
+		}
+		f.Data, err = hex.DecodeString(rest[:dataLen])
+		if err != nil {
+			return f, fmt.Errorf("invalid data in %q", line)
+		}
+		rest = rest[dataLen:]
+	}
+
+	// 可选的 4 位十六进制时间戳后缀
+	switch len(rest) {
+	case 0:
+	case 4:
+		ts, err := strconv.ParseUint(rest, 16, 16)
+		if err != nil {
+			return f, fmt.Errorf("invalid timestamp in %q", line)
+		}
+		f.Timestamp = int(ts)
+		f.HasTimestamp = true
+	default:
+		return f, fmt.Errorf("unexpected trailing data in %q", line)
+	}
+
+	return f, nil
+}
+
+// LineSplitter 将串口字节流按 \r 切分为 SLCAN 行
+type LineSplitter struct {
+	buf []byte
+}
+
+// maxLineLen 单行最大长度，防止异常数据导致缓冲区无限增长
+const maxLineLen = 64
+
+// Feed 输入一段数据，返回其中完整的行（不含 \r）
+// 单独出现的 BEL 字节作为 "\a" 行返回，便于调用方报告命令错误
+func (s *LineSplitter) Feed(data []byte) []string {
+	var lines []string
+	for _, b := range data {
+		switch b {
+		case '\r':
+			lines = append(lines, string(s.buf))
+			s.buf = s.buf[:0]
+		case BEL:
+			lines = append(lines, string(BEL))
+			s.buf = s.buf[:0]
+		case '\n':
+			// 部分适配器会附带 \n，忽略
+		default:
+			if len(s.buf) >= maxLineLen {
+				s.buf = s.buf[:0]
+			}
+			s.buf = append(s.buf, b)
+		}
+	}
+	return lines
+}
@@ -0,0 +1,145 @@
+package slcan
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParseFrames(t *testing.T) {
+	tests := []struct {
+		line     string
+		expected Frame
+	}{
+		{"t1232AABB", Frame{ID: 0x123, DLC: 2, Data: []byte{0xAA, 0xBB}}},
+		{"t7FF0", Frame{ID: 0x7FF, DLC: 0, Data: []byte{}}},
+		{"T1FFFFFFF81122334455667788", Frame{ID: 0x1FFFFFFF, Extended: true, DLC: 8, Data: []byte{0x11, 0x22, 0x33, 0x44, 0x55, 0x66, 0x77, 0x88}}},
+		{"r1004", Frame{ID: 0x100, RTR: true, DLC: 4}},
+		{"R000001002", Frame{ID: 0x100, Extended: true, RTR: true, DLC: 2}},
+		// 带时间戳后缀
+		{"t1232AABBEA5F", Frame{ID: 0x123, DLC: 2, Data: []byte{0xAA, 0xBB}, Timestamp: 0xEA5F, HasTimestamp: true}},
+		{"r10041234", Frame{ID: 0x100, RTR: true, DLC: 4, Timestamp: 0x1234, HasTimestamp: true}},
+	}
+
+	for _, tt := range tests {
+		f, err := Parse(tt.line)
+		if err != nil {
+			t.Errorf("Parse(%q) returned error: %v", tt.line, err)
+			continue
+		}
+		if !reflect.DeepEqual(f, tt.expected) {
+			t.Errorf("Parse(%q) = %+v, expected %+v", tt.line, f, tt.expected)
+		}
+	}
+}
+
+func TestParseMalformed(t *testing.T) {
+	malformed := []string{
+		"t12",           // 太短
+		"t12G1AA",       // 非法 ID
+		"t8001AA",       // 标准帧 ID 超出范围
+		"T3FFFFFFF0",    // 扩展帧 ID 超出范围
+		"t1239",         // DLC 超过 8
+		"t1232AA",       // 数据长度小于 DLC
+		"t1232AAZZ",     // 非法数据
+		"t1232AABB12",   // 非法尾部长度
+		"t1232AABBXYZW", // 非法时间戳
+	}
+	for _, line := range malformed {
+		if _, err := Parse(line); err == nil || errors.Is(err, ErrNotFrame) {
+			t.Errorf("Parse(%q) expected malformed-frame error, got %v", line, err)
+		}
+	}
+
+	for _, line := range []string{"", "z", "Z", "V1013", "\a"} {
+		if _, err := Parse(line); !errors.Is(err, ErrNotFrame) {
+			t.Errorf("Parse(%q) expected ErrNotFrame, got %v", line, err)
+		}
+	}
+}
+
+func TestEncode(t *testing.T) {
+	tests := []struct {
+		id       uint32
+		data     []byte
+		extended bool
+		rtr      bool
+		expected string
+	}{
+		{0x123, []byte{0xAA, 0xBB}, false, false, "t1232AABB\r"},
+		{0x1FFFFFFF, []byte{0x01}, true, false, "T1FFFFFFF101\r"},
+		{0x7FF, nil, false, false, "t7FF0\r"},
+		{0x100, nil, false, true, "r1000\r"},
+	}
+	for _, tt := range tests {
+		got, err := Encode(tt.id, tt.data, tt.extended, tt.rtr)
+		if err != nil {
+			t.Errorf("Encode(0x%X) returned error: %v", tt.id, err)
+			continue
+		}
+		if got != tt.expected {
+			t.Errorf("Encode(0x%X) = %q, expected %q", tt.id, got, tt.expected)
+		}
+	}
+
+	if _, err := Encode(0x800, nil, false, false); err == nil {
+		t.Error("Expected error for standard id > 0x7FF")
+	}
+	if _, err := Encode(0x20000000, nil, true, false); err == nil {
+		t.Error("Expected error for extended id > 0x1FFFFFFF")
+	}
+	if _, err := Encode(1, make([]byte, 9), false, false); err == nil {
+		t.Error("Expected error for data longer than 8 bytes")
+	}
+}
+
+func TestEncodeParseRoundTrip(t *testing.T) {
+	data := []byte{0xDE, 0xAD, 0xBE, 0xEF}
+	line, err := Encode(0x18FF50E5, data, true, false)
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	f, err := Parse(line[:len(line)-1])
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if f.ID != 0x18FF50E5 || !f.Extended || !bytes.Equal(f.Data, data) {
+		t.Errorf("Round trip mismatch: %+v", f)
+	}
+}
+
+func TestSetupCommands(t *testing.T) {
+	cmds, err := SetupCommands(6)
+	if err != nil {
+		t.Fatalf("SetupCommands(6) failed: %v", err)
+	}
+	expected := []string{"C\r", "S6\r", "O\r"}
+	if !reflect.DeepEqual(cmds, expected) {
+		t.Errorf("SetupCommands(6) = %q, expected %q", cmds, expected)
+	}
+	if _, err := SetupCommands(9); err == nil {
+		t.Error("Expected error for bitrate code 9")
+	}
+
+	cmds, _ = ListenOnlyCommands(6)
+	if expected := []string{"C\r", "S6\r", "L\r"}; !reflect.DeepEqual(cmds, expected) {
+		t.Errorf("ListenOnlyCommands(6) = %q, expected %q", cmds, expected)
+	}
+	if _, err := ListenOnlyCommands(-1); err == nil {
+		t.Error("Expected error for bitrate code -1")
+	}
+}
+
+func TestLineSplitter(t *testing.T) {
+	var s LineSplitter
+	lines := s.Feed([]byte("t1232AA"))
+	if len(lines) != 0 {
+		t.Errorf("Expected no complete lines, got %q", lines)
+	}
+	lines = s.Feed([]byte("BB\rz\r\n\at7FF0\r"))
+	expected := []string{"t1232AABB", "z", "\a", "t7FF0"}
+	if !reflect.DeepEqual(lines, expected) {
+		t.Errorf("Feed() = %q, expected %q", lines, expected)
+	}
+}
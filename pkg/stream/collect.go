@@ -0,0 +1,74 @@
+package stream
+
+import (
+	"bytes"
+	"sync"
+	"time"
+)
+
+// MaxCollect Collector 最多保存的字节数，超出部分被丢弃
+const MaxCollect = 1024 * 1024
+
+// Collector 收集数据直到出现结束符，用于一问一答式的请求
+type Collector struct {
+	term []byte
+
+	mu       sync.Mutex
+	buf      []byte
+	done     chan struct{}
+	finished bool
+}
+
+// NewCollector 创建收集器，terminator 为空时只按超时结束
+func NewCollector(terminator []byte) *Collector {
+	return &Collector{term: terminator, done: make(chan struct{})}
+}
+
+// Write 追加数据，可作为 Hub 的订阅回调
+func (c *Collector) Write(chunk []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.finished {
+		return
+	}
+	// 只需在新数据及其之前 len(term)-1 个字节中查找结束符
+	start := len(c.buf) - len(c.term) + 1
+	if start < 0 {
+		start = 0
+	}
+	room := MaxCollect - len(c.buf)
+	if len(chunk) > room {
+		chunk = chunk[:room]
+	}
+	c.buf = append(c.buf, chunk...)
+
+	if (len(c.term) > 0 && bytes.Contains(c.buf[start:], c.term)) || len(c.buf) >= MaxCollect {
+		c.finished = true
+		close(c.done)
+	}
+}
+
+// Done 返回出现结束符 (或达到 MaxCollect) 时关闭的通道，用于需要同时等待其他事件的场合
+func (c *Collector) Done() <-chan struct{} {
+	return c.done
+}
+
+// Wait 等待结束符出现或超时，返回收集到的数据；complete 表示在超时前出现了结束符
+func (c *Collector) Wait(timeout time.Duration) (data []byte, complete bool) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case <-c.done:
+		complete = true
+	case <-timer.C:
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.finished = true
+	complete = complete && len(c.term) > 0
+	return append([]byte(nil), c.buf...), complete
+}
@@ -0,0 +1,45 @@
+package stream
+
+import "sync"
+
+// Hub 将接收数据分发给临时订阅者 (例如 Transact、触发器)
+// 订阅回调在发布者的 goroutine 中同步调用，不会丢失数据，回调必须快速返回
+type Hub struct {
+	mu     sync.Mutex
+	nextID int
+	subs   map[int]func([]byte)
+}
+
+// NewHub 创建 Hub
+func NewHub() *Hub {
+	return &Hub{subs: make(map[int]func([]byte))}
+}
+
+// Subscribe 注册订阅回调，返回取消订阅的函数 (可重复调用)
+func (h *Hub) Subscribe(fn func([]byte)) (unsubscribe func()) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.nextID++
+	id := h.nextID
+	h.subs[id] = fn
+	return func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		delete(h.subs, id)
+	}
+}
+
+// Publish 将数据块发送给所有订阅者
+func (h *Hub) Publish(chunk []byte) {
+	h.mu.Lock()
+	subs := make([]func([]byte), 0, len(h.subs))
+	for _, fn := range h.subs {
+		subs = append(subs, fn)
+	}
+	h.mu.Unlock()
+
+	for _, fn := range subs {
+		fn(chunk)
+	}
+}
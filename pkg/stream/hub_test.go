@@ -0,0 +1,78 @@
+package stream
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHubSubscribeAndUnsubscribe(t *testing.T) {
+	h := NewHub()
+
+	var a, b []byte
+	unsubA := h.Subscribe(func(c []byte) { a = append(a, c...) })
+	h.Subscribe(func(c []byte) { b = append(b, c...) })
+
+	h.Publish([]byte("one"))
+	unsubA()
+	unsubA()
+	h.Publish([]byte("two"))
+
+	if string(a) != "one" {
+		t.Errorf("Unsubscribed callback should stop receiving, got %q", a)
+	}
+	if string(b) != "onetwo" {
+		t.Errorf("Expected all chunks, got %q", b)
+	}
+}
+
+func TestCollectorStopsAtTerminator(t *testing.T) {
+	c := NewCollector([]byte("\r\n"))
+	c.Write([]byte("+CSQ: 23"))
+	c.Write([]byte(",0\r"))
+	c.Write([]byte("\nOK"))
+	c.Write([]byte("late"))
+
+	data, complete := c.Wait(time.Second)
+	if !complete {
+		t.Error("Expected terminator split across chunks to be detected")
+	}
+	if string(data) != "+CSQ: 23,0\r\nOK" {
+		t.Errorf("Unexpected data %q", data)
+	}
+}
+
+func TestCollectorTimeout(t *testing.T) {
+	c := NewCollector(nil)
+	c.Write([]byte("partial"))
+
+	start := time.Now()
+	data, complete := c.Wait(30 * time.Millisecond)
+	if complete || string(data) != "partial" {
+		t.Errorf("Expected timeout with partial data, got %q, %v", data, complete)
+	}
+	if time.Since(start) < 30*time.Millisecond {
+		t.Error("Wait returned before the timeout")
+	}
+
+	c.Write([]byte("more"))
+	if data, _ := c.Wait(0); string(data) != "partial" {
+		t.Errorf("Collector should ignore data after Wait returned, got %q", data)
+	}
+}
+
+func TestCollectorDone(t *testing.T) {
+	c := NewCollector([]byte("> "))
+	c.Write([]byte("ok\r\n>"))
+	select {
+	case <-c.Done():
+		t.Fatal("Done closed before the prompt was complete")
+	default:
+	}
+
+	c.Write([]byte(" "))
+	select {
+	case <-c.Done():
+	case <-time.After(time.Second):
+		t.Fatal("Done not closed after the prompt arrived")
+	}
+}
@@ -0,0 +1,84 @@
+package stream
+
+import (
+	"bytes"
+	"sync"
+	"time"
+)
+
+// Matcher 在数据流中查找若干模式中最先出现的一个，只观察数据，不会截留
+// 模式可以跨越多次 Write，用于等待 ACK/NAK 之类的应答
+type Matcher struct {
+	patterns [][]byte
+	keep     int // 保留的尾部字节数，等于最长模式长度减一
+
+	mu      sync.Mutex
+	tail    []byte
+	matched int
+	done    chan struct{}
+}
+
+// NewMatcher 创建匹配器，空模式被忽略
+func NewMatcher(patterns ...[]byte) *Matcher {
+	m := &Matcher{matched: -1, done: make(chan struct{})}
+	for _, p := range patterns {
+		if len(p) > m.keep+1 {
+			m.keep = len(p) - 1
+		}
+		m.patterns = append(m.patterns, p)
+	}
+	return m
+}
+
+// Write 追加数据，可作为 Hub 的订阅回调；匹配成功后的数据被忽略
+func (m *Matcher) Write(chunk []byte) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.matched >= 0 {
+		return
+	}
+	buf := append(m.tail, chunk...)
+	first := -1
+	for i, p := range m.patterns {
+		if len(p) == 0 {
+			continue
+		}
+		if idx := bytes.Index(buf, p); idx >= 0 && (first < 0 || idx+len(p) < first) {
+			// 以模式结束位置判断先后，结束得更早的模式先被完整收到
+			first = idx + len(p)
+			m.matched = i
+		}
+	}
+	if m.matched >= 0 {
+		m.tail = nil
+		close(m.done)
+		return
+	}
+	if len(buf) > m.keep {
+		buf = buf[len(buf)-m.keep:]
+	}
+	m.tail = append([]byte(nil), buf...)
+}
+
+// Wait 等待任一模式出现，返回其在 NewMatcher 参数中的下标；超时返回 -1
+func (m *Matcher) Wait(timeout time.Duration) int {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case <-m.done:
+	case <-timer.C:
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.matched
+}
+
+// Matched 不等待，返回已匹配模式的下标，尚未匹配时返回 -1
+func (m *Matcher) Matched() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.matched
+}
@@ -0,0 +1,52 @@
+package stream
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMatcherAcrossChunks(t *testing.T) {
+	m := NewMatcher([]byte("ACK"), []byte("NAK"))
+	m.Write([]byte("noise A"))
+	m.Write([]byte("C"))
+	m.Write([]byte("K more"))
+	if got := m.Wait(time.Second); got != 0 {
+		t.Fatalf("Wait = %d, want 0", got)
+	}
+}
+
+func TestMatcherEarliestWins(t *testing.T) {
+	m := NewMatcher([]byte("ACK"), []byte("NAK"))
+	m.Write([]byte("xxNAKyyACK"))
+	if got := m.Wait(time.Second); got != 1 {
+		t.Fatalf("Wait = %d, want 1", got)
+	}
+	// 匹配后的数据不会改变结果
+	m.Write([]byte("ACK"))
+	if got := m.Wait(0); got != 1 {
+		t.Fatalf("Wait after match = %d, want 1", got)
+	}
+}
+
+func TestMatcherTimeout(t *testing.T) {
+	m := NewMatcher([]byte("ACK"), nil)
+	m.Write([]byte("AC"))
+	m.Write([]byte("X"))
+	m.Write([]byte("K"))
+	if got := m.Wait(10 * time.Millisecond); got != -1 {
+		t.Fatalf("Wait = %d, want -1", got)
+	}
+}
+
+func TestMatcherDoesNotRetainData(t *testing.T) {
+	m := NewMatcher([]byte{0x06})
+	chunk := make([]byte, 4096)
+	m.Write(chunk)
+	if len(m.tail) != 0 {
+		t.Errorf("tail keeps %d bytes for a single-byte pattern", len(m.tail))
+	}
+	m.Write([]byte{0x15, 0x06})
+	if got := m.Wait(time.Second); got != 0 {
+		t.Fatalf("Wait = %d, want 0", got)
+	}
+}
@@ -0,0 +1,167 @@
+package stream
+
+import (
+	"fmt"
+	"sync/atomic"
+)
+
+// Policy 接收队列已满时的背压策略
+type Policy string
+
+const (
+	// PolicyBlock 读取端等待处理端腾出空间，数据不丢失，但可能让操作系统缓冲区溢出
+	PolicyBlock Policy = "block"
+	// PolicyDrop 立即丢弃新数据块并计数，读取端永不等待
+	PolicyDrop Policy = "drop"
+)
+
+// ParsePolicy 解析背压策略名称，空字符串视为 PolicyBlock
+func ParsePolicy(name string) (Policy, error) {
+	switch Policy(name) {
+	case "", PolicyBlock:
+		return PolicyBlock, nil
+	case PolicyDrop:
+		return PolicyDrop, nil
+	default:
+		return "", fmt.Errorf("unknown backpressure policy %q (expected block or drop)", name)
+	}
+}
+
+// DefaultQueueDepth 未指定时接收队列可容纳的数据块数
+const DefaultQueueDepth = 256
+
+// MaxQueueDepth 接收队列深度上限
+const MaxQueueDepth = 65536
+
+// QueueConfig 接收队列参数
+type QueueConfig struct {
+	Depth  int    // 可容纳的数据块数，<= 0 时为 DefaultQueueDepth
+	Policy Policy // 为空时为 PolicyBlock
+	// Cancel 关闭后阻塞中的 Push 立即返回 false，通常为连接的停止通道
+	Cancel <-chan struct{}
+	// Pending 可选，累计队列中等待处理的字节数，可由多个队列共享 (例如统计全部连接的积压)
+	Pending *atomic.Int64
+}
+
+// queued 队列中的一个数据块及读取端附带的信息 (例如 UDP 数据报的来源地址)
+type queued struct {
+	data []byte
+	meta interface{}
+}
+
+// Queue 将读取与处理拆分到两个 goroutine：读取端只负责 Read 并把数据块 Push 进有界队列，
+// 处理 goroutine 按顺序对每个数据块调用 handle (分帧、过滤、格式化、发送事件)
+//
+// Push 与 Close 必须由同一个 goroutine (读取端) 调用
+type Queue struct {
+	ch      chan queued
+	policy  Policy
+	cancel  <-chan struct{}
+	pending *atomic.Int64
+	done    chan struct{}
+	closed  bool
+	abandon atomic.Bool
+
+	dropped   atomic.Int64 // 队列满时丢弃的数据块
+	abandoned atomic.Int64 // Close(false) 时未处理而丢弃的数据块
+}
+
+// NewQueue 创建队列并启动处理 goroutine，handle 收到 Push 时传入的 data 与 meta
+// Push 的数据块归队列所有，读取端不能再修改
+func NewQueue(cfg QueueConfig, handle func(data []byte, meta interface{})) *Queue {
+	depth := cfg.Depth
+	if depth <= 0 {
+		depth = DefaultQueueDepth
+	}
+	policy := cfg.Policy
+	if policy == "" {
+		policy = PolicyBlock
+	}
+	q := &Queue{
+		ch:      make(chan queued, depth),
+		policy:  policy,
+		cancel:  cfg.Cancel,
+		pending: cfg.Pending,
+		done:    make(chan struct{}),
+	}
+	go q.run(handle)
+	return q
+}
+
+func (q *Queue) run(handle func([]byte, interface{})) {
+	defer close(q.done)
+	for item := range q.ch {
+		if q.abandon.Load() {
+			q.abandoned.Add(1)
+		} else {
+			handle(item.data, item.meta)
+		}
+		if q.pending != nil {
+			q.pending.Add(-int64(len(item.data)))
+		}
+	}
+}
+
+// Push 将数据块交给处理 goroutine
+// PolicyBlock 下队列满时等待，Cancel 关闭时返回 false；PolicyDrop 下队列满时丢弃并返回 false
+func (q *Queue) Push(data []byte, meta interface{}) bool {
+	item := queued{data: data, meta: meta}
+	// 先计入再入队，避免处理 goroutine 先扣减
+	q.addPending(len(data))
+	select {
+	case q.ch <- item:
+		return true
+	default:
+	}
+	if q.policy == PolicyDrop {
+		q.dropped.Add(1)
+		q.addPending(-len(data))
+		return false
+	}
+	select {
+	case q.ch <- item:
+		return true
+	case <-q.cancel:
+		q.addPending(-len(data))
+		return false
+	}
+}
+
+func (q *Queue) addPending(n int) {
+	if q.pending != nil {
+		q.pending.Add(int64(n))
+	}
+}
+
+// Close 停止接收数据块 (可重复调用)，不等待处理 goroutine 退出
+// drain 为 true 时处理完队列中已有的数据块后退出；为 false 时在当前数据块处理完后
+// 丢弃剩余数据块 (计入 Abandoned) 并退出
+func (q *Queue) Close(drain bool) {
+	if !drain {
+		q.abandon.Store(true)
+	}
+	if !q.closed {
+		q.closed = true
+		close(q.ch)
+	}
+}
+
+// Done 返回在处理 goroutine 退出后关闭的通道
+func (q *Queue) Done() <-chan struct{} {
+	return q.done
+}
+
+// Len 返回等待处理的数据块数
+func (q *Queue) Len() int {
+	return len(q.ch)
+}
+
+// Dropped 返回因队列已满而丢弃的数据块数 (仅 PolicyDrop)
+func (q *Queue) Dropped() int64 {
+	return q.dropped.Load()
+}
+
+// Abandoned 返回 Close(false) 时未处理的数据块数
+func (q *Queue) Abandoned() int64 {
+	return q.abandoned.Load()
+}
@@ -0,0 +1,242 @@
+package stream
+
+import (
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestQueuePreservesOrder(t *testing.T) {
+	var got []string
+	q := NewQueue(QueueConfig{Depth: 2}, func(c []byte, _ interface{}) { got = append(got, string(c)) })
+	for _, s := range []string{"a", "b", "c", "d", "e"} {
+		if !q.Push([]byte(s), nil) {
+			t.Fatalf("Push(%s) failed", s)
+		}
+	}
+	q.Close(true)
+	<-q.Done()
+	if want := "abcde"; strings.Join(got, "") != want {
+		t.Errorf("handled %v, want %s", got, want)
+	}
+}
+
+func TestQueueDropPolicy(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{}, 1)
+	q := NewQueue(QueueConfig{Depth: 1, Policy: PolicyDrop}, func([]byte, interface{}) {
+		started <- struct{}{}
+		<-release
+	})
+	q.Push([]byte("1"), nil) // 被处理端取走并阻塞
+	<-started
+	q.Push([]byte("2"), nil) // 占满队列
+	if q.Push([]byte("3"), nil) {
+		t.Error("Push to a full drop queue should fail")
+	}
+	if q.Dropped() != 1 {
+		t.Errorf("Dropped = %d, want 1", q.Dropped())
+	}
+	close(release)
+	q.Close(true)
+	<-q.Done()
+}
+
+func TestQueueBlockPolicyCancel(t *testing.T) {
+	release := make(chan struct{})
+	cancel := make(chan struct{})
+	q := NewQueue(QueueConfig{Depth: 1, Cancel: cancel}, func([]byte, interface{}) { <-release })
+	q.Push([]byte("1"), nil)
+	q.Push([]byte("2"), nil)
+
+	result := make(chan bool)
+	go func() { result <- q.Push([]byte("3"), nil) }()
+	select {
+	case <-result:
+		t.Fatal("Push should block while the queue is full")
+	case <-time.After(20 * time.Millisecond):
+	}
+	close(cancel)
+	if <-result {
+		t.Error("cancelled Push should return false")
+	}
+	close(release)
+	q.Close(true)
+	<-q.Done()
+}
+
+func TestQueueCloseAbandon(t *testing.T) {
+	release := make(chan struct{})
+	var mu sync.Mutex
+	handled := 0
+	q := NewQueue(QueueConfig{Depth: 8}, func([]byte, interface{}) {
+		<-release
+		mu.Lock()
+		handled++
+		mu.Unlock()
+	})
+	for i := 0; i < 5; i++ {
+		q.Push([]byte{byte(i)}, nil)
+	}
+	q.Close(false)
+	q.Close(false) // 可重复调用
+	close(release)
+	<-q.Done()
+
+	// 关闭时正在处理的数据块会完成，其余全部丢弃
+	if handled > 1 || int64(handled)+q.Abandoned() != 5 {
+		t.Errorf("handled %d, abandoned %d", handled, q.Abandoned())
+	}
+}
+
+func TestQueuePendingBytes(t *testing.T) {
+	var pending atomic.Int64
+	release := make(chan struct{})
+	handle := func([]byte, interface{}) { <-release }
+	a := NewQueue(QueueConfig{Depth: 2, Policy: PolicyDrop, Pending: &pending}, handle)
+	b := NewQueue(QueueConfig{Depth: 2, Pending: &pending}, handle)
+	for i := 0; i < 4; i++ {
+		a.Push(make([]byte, 10), nil) // 队列满后丢弃的块不计入 (取决于处理端是否已取走第一块)
+	}
+	b.Push(make([]byte, 100), nil)
+	if got := pending.Load(); got < 120 || got > 130 {
+		t.Errorf("pending = %d with two queues backed up", got)
+	}
+	close(release)
+	a.Close(true)
+	b.Close(false)
+	<-a.Done()
+	<-b.Done()
+	if got := pending.Load(); got != 0 {
+		t.Errorf("pending = %d after both queues finished", got)
+	}
+}
+
+func TestParsePolicy(t *testing.T) {
+	for name, want := range map[string]Policy{"": PolicyBlock, "block": PolicyBlock, "drop": PolicyDrop} {
+		if got, err := ParsePolicy(name); err != nil || got != want {
+			t.Errorf("ParsePolicy(%q) = %q, %v", name, got, err)
+		}
+	}
+	if _, err := ParsePolicy("spill"); err == nil {
+		t.Error("ParsePolicy(spill) should fail")
+	}
+}
+
+// uartSource 模拟 3 Mbaud (8N1，300000 字节/秒) 串口：数据按时间均匀到达，
+// 操作系统接收缓冲区只有 osBuffer 字节，未及时读取的部分被覆盖丢失
+type uartSource struct {
+	start    time.Time
+	rate     float64 // 字节/秒
+	osBuffer int
+	total    int // 需要产生的总字节数
+	consumed int // 已读取或已丢失的字节数
+	lost     int
+}
+
+func newUARTSource(total int) *uartSource {
+	return &uartSource{start: time.Now(), rate: 3000000 / 10, osBuffer: 4096, total: total}
+}
+
+func (s *uartSource) arrived() int {
+	n := int(time.Since(s.start).Seconds() * s.rate)
+	if n > s.total {
+		n = s.total
+	}
+	return n
+}
+
+// Read 阻塞直到有数据可读，全部数据产生并读完后返回 0
+func (s *uartSource) Read(p []byte) int {
+	for {
+		avail := s.arrived() - s.consumed
+		if avail > s.osBuffer {
+			s.lost += avail - s.osBuffer
+			s.consumed += avail - s.osBuffer
+			avail = s.osBuffer
+		}
+		if avail > 0 {
+			if avail > len(p) {
+				avail = len(p)
+			}
+			s.consumed += avail
+			return avail
+		}
+		if s.consumed >= s.total {
+			return 0
+		}
+		time.Sleep(200 * time.Microsecond)
+	}
+}
+
+// slowHandler 模拟事件序列化与处理链的开销：大部分数据块很快处理完，
+// 但每 32 块会有一次 20ms 的停顿 (IPC 拥塞、GC 等)
+func slowHandler() func([]byte) {
+	n := 0
+	return func([]byte) {
+		n++
+		if n%32 == 0 {
+			time.Sleep(20 * time.Millisecond)
+		}
+	}
+}
+
+const benchTransfer = 48 * 1024
+
+func reportUART(b *testing.B, lost, delivered int, elapsed time.Duration) {
+	b.ReportMetric(float64(lost)/float64(b.N), "lost-B/op")
+	b.ReportMetric(float64(delivered)/elapsed.Seconds()/1024, "KB/s")
+}
+
+// BenchmarkSingleGoroutineLoop 读取与处理在同一个 goroutine 中 (拆分前的读取循环)
+func BenchmarkSingleGoroutineLoop(b *testing.B) {
+	var lost, delivered int
+	start := time.Now()
+	for i := 0; i < b.N; i++ {
+		src := newUARTSource(benchTransfer)
+		handle := slowHandler()
+		buff := make([]byte, 4096)
+		for {
+			n := src.Read(buff)
+			if n == 0 {
+				break
+			}
+			chunk := make([]byte, n)
+			copy(chunk, buff[:n])
+			handle(chunk)
+			delivered += n
+		}
+		lost += src.lost
+	}
+	reportUART(b, lost, delivered, time.Since(start))
+}
+
+// BenchmarkQueuedLoop 读取端只做 Read，处理在 Queue 的 goroutine 中进行
+func BenchmarkQueuedLoop(b *testing.B) {
+	var lost, delivered int
+	start := time.Now()
+	for i := 0; i < b.N; i++ {
+		src := newUARTSource(benchTransfer)
+		handle := slowHandler()
+		q := NewQueue(QueueConfig{}, func(c []byte, _ interface{}) {
+			handle(c)
+			delivered += len(c)
+		})
+		buff := make([]byte, 4096)
+		for {
+			n := src.Read(buff)
+			if n == 0 {
+				break
+			}
+			chunk := make([]byte, n)
+			copy(chunk, buff[:n])
+			q.Push(chunk, nil)
+		}
+		q.Close(true)
+		<-q.Done()
+		lost += src.lost
+	}
+	reportUART(b, lost, delivered, time.Since(start))
+}
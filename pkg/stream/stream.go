@@ -0,0 +1,94 @@
+// Package stream 将接收数据的处理拆分为可组合的阶段 (Stage)
+//
+// 读取循环只需把数据块推入连接的 Pipeline，并发送从末端流出的数据块；
+// 回显抑制、分帧、过滤等功能各自实现为独立的阶段，可以单独测试，
+// 并按连接配置不同的组合。
+package stream
+
+import "sync"
+
+// Stage 处理一个输入块，返回零个或多个输出块
+// 分帧类阶段可以在内部缓存不完整的数据，等后续输入补齐后再输出
+type Stage interface {
+	Process(chunk []byte) [][]byte
+}
+
+// StageFunc 将普通函数适配为 Stage
+type StageFunc func(chunk []byte) [][]byte
+
+// Process 实现 Stage
+func (f StageFunc) Process(chunk []byte) [][]byte {
+	return f(chunk)
+}
+
+// Pipeline 顺序执行的阶段链，线程安全
+// 同一条连接的数据块按到达顺序依次经过全部阶段
+type Pipeline struct {
+	mu     sync.Mutex
+	stages []Stage
+}
+
+// New 创建由 stages 组成的处理链，没有阶段时数据原样通过
+func New(stages ...Stage) *Pipeline {
+	return &Pipeline{stages: stages}
+}
+
+// Len 返回阶段数量
+func (p *Pipeline) Len() int {
+	return len(p.stages)
+}
+
+// Process 让 chunk 依次经过所有阶段，返回末端输出的数据块
+// 中间阶段输出的空块会被丢弃
+func (p *Pipeline) Process(chunk []byte) [][]byte {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	chunks := [][]byte{chunk}
+	for _, stage := range p.stages {
+		var next [][]byte
+		for _, c := range chunks {
+			for _, out := range stage.Process(c) {
+				if len(out) > 0 {
+					next = append(next, out)
+				}
+			}
+		}
+		if len(next) == 0 {
+			return nil
+		}
+		chunks = next
+	}
+	return chunks
+}
+
+// Map 一进一出的变换阶段，fn 返回空切片表示丢弃该块
+func Map(fn func([]byte) []byte) Stage {
+	return StageFunc(func(chunk []byte) [][]byte {
+		if out := fn(chunk); len(out) > 0 {
+			return [][]byte{out}
+		}
+		return nil
+	})
+}
+
+// Tap 旁路观察阶段：调用 fn 后原样输出数据块
+func Tap(fn func([]byte)) Stage {
+	return StageFunc(func(chunk []byte) [][]byte {
+		fn(chunk)
+		return [][]byte{chunk}
+	})
+}
+
+// Lines 将逐行分帧函数 (例如 slcan.LineSplitter.Feed) 适配为 Stage，每行输出一个块
+// 空行对应长度为 0 的块，会被 Pipeline 丢弃
+func Lines(feed func([]byte) []string) Stage {
+	return StageFunc(func(chunk []byte) [][]byte {
+		lines := feed(chunk)
+		out := make([][]byte, len(lines))
+		for i, line := range lines {
+			out[i] = []byte(line)
+		}
+		return out
+	})
+}
@@ -0,0 +1,104 @@
+package stream
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+
+	"serial-assistant/pkg/console"
+	"serial-assistant/pkg/slcan"
+)
+
+func strs(chunks [][]byte) []string {
+	out := make([]string, len(chunks))
+	for i, c := range chunks {
+		out[i] = string(c)
+	}
+	return out
+}
+
+func TestEmptyPipelinePassesThrough(t *testing.T) {
+	p := New()
+	if got := strs(p.Process([]byte("abc"))); !reflect.DeepEqual(got, []string{"abc"}) {
+		t.Errorf("Expected passthrough, got %v", got)
+	}
+}
+
+func TestStagesRunInOrder(t *testing.T) {
+	var seen []string
+	p := New(
+		Map(bytes.ToUpper),
+		Tap(func(c []byte) { seen = append(seen, string(c)) }),
+		Map(func(c []byte) []byte { return append(c, '!') }),
+	)
+
+	if got := strs(p.Process([]byte("hi"))); !reflect.DeepEqual(got, []string{"HI!"}) {
+		t.Errorf("Unexpected output %v", got)
+	}
+	if !reflect.DeepEqual(seen, []string{"HI"}) {
+		t.Errorf("Tap should observe the output of the previous stage, got %v", seen)
+	}
+}
+
+func TestMapCanDropChunks(t *testing.T) {
+	calls := 0
+	p := New(
+		Map(func([]byte) []byte { return nil }),
+		Tap(func([]byte) { calls++ }),
+	)
+	if out := p.Process([]byte("x")); out != nil {
+		t.Errorf("Expected dropped chunk, got %v", out)
+	}
+	if calls != 0 {
+		t.Errorf("Later stages should not run for dropped chunks")
+	}
+}
+
+func TestEchoSuppressionStage(t *testing.T) {
+	echo := console.NewEchoSuppressor(time.Second)
+	p := New(Map(echo.Filter))
+
+	echo.Expect([]byte("ls\r"))
+	if out := p.Process([]byte("ls\r")); out != nil {
+		t.Errorf("Echoed bytes should be dropped, got %v", strs(out))
+	}
+	if got := strs(p.Process([]byte("file.txt"))); !reflect.DeepEqual(got, []string{"file.txt"}) {
+		t.Errorf("Unexpected output %v", got)
+	}
+}
+
+func TestSlcanFramingStage(t *testing.T) {
+	var splitter slcan.LineSplitter
+	p := New(Lines(splitter.Feed))
+
+	if out := p.Process([]byte("t1232")); out != nil {
+		t.Errorf("Incomplete line should be buffered, got %v", strs(out))
+	}
+	got := strs(p.Process([]byte("AABB\r\rz\r")))
+	if !reflect.DeepEqual(got, []string{"t1232AABB", "z"}) {
+		t.Errorf("Unexpected lines %v (empty lines should be dropped)", got)
+	}
+}
+
+// BenchmarkFiveStageChain 5 个阶段的处理链，吞吐量需远高于 10 MB/s
+// (go test -bench FiveStage 输出中的 MB/s)
+func BenchmarkFiveStageChain(b *testing.B) {
+	echo := console.NewEchoSuppressor(time.Second)
+	var splitter slcan.LineSplitter
+	var total int
+	p := New(
+		Map(echo.Filter),
+		Tap(func(c []byte) { total += len(c) }),
+		Map(func(c []byte) []byte { return c }),
+		Lines(splitter.Feed),
+		Tap(func(c []byte) { total += len(c) }),
+	)
+
+	chunk := bytes.Repeat([]byte("t12380011223344556677\r"), 4096/22)
+	b.SetBytes(int64(len(chunk)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		p.Process(chunk)
+	}
+}
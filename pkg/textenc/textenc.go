@@ -0,0 +1,226 @@
+// Package textenc 将设备输出的 UTF-16 文本转换为 UTF-8，并可根据 BOM 或 NUL 字节分布自动识别
+package textenc
+
+import (
+	"fmt"
+	"sync"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// Mode 接收数据的编码
+type Mode string
+
+const (
+	// Raw 原样输出 (默认)
+	Raw Mode = "raw"
+	// UTF16LE 按 UTF-16 小端解码
+	UTF16LE Mode = "utf-16le"
+	// UTF16BE 按 UTF-16 大端解码
+	UTF16BE Mode = "utf-16be"
+	// Auto 在连接开始时检测 UTF-16，检测到后切换解码，否则保持原样输出
+	Auto Mode = "auto"
+)
+
+// ParseMode 解析编码名称，空字符串视为 Raw
+func ParseMode(name string) (Mode, error) {
+	switch Mode(name) {
+	case "", Raw:
+		return Raw, nil
+	case UTF16LE, UTF16BE, Auto:
+		return Mode(name), nil
+	default:
+		return "", fmt.Errorf("unknown RX encoding %q (expected raw, utf-16le, utf-16be or auto)", name)
+	}
+}
+
+// 自动检测参数
+const (
+	// DetectMinBytes 无 BOM 时至少收到这么多字节才做判断
+	DetectMinBytes = 16
+	// DetectWindow 连接开始后只在这么多字节内检测，之后保持原样输出
+	DetectWindow = 256
+	// nulRatio 一侧 (奇或偶位置) 字节中 NUL 的最低比例
+	nulRatio = 0.8
+	// textRatio 另一侧字节中可打印 ASCII 的最低比例
+	textRatio = 0.9
+)
+
+// Decoder 有状态的接收解码器，每条连接一个，线程安全
+// 跨数据块的奇数字节与被拆开的代理对在下一块到达时拼接
+type Decoder struct {
+	mu       sync.Mutex
+	mode     Mode // 当前生效的编码，Auto 表示尚未判定
+	onDetect func(Mode, string)
+
+	sample  []byte // 自动检测期间收到的数据
+	decided bool   // 自动检测已结束 (无论是否切换)
+
+	odd       []byte // 上一块末尾不足一个码元的字节
+	surrogate rune   // 上一块末尾未配对的高位代理，0 表示无
+}
+
+// NewDecoder 创建解码器，onDetect 在自动检测得出结论时调用 (参数为判定的编码与原因)，可为 nil
+func NewDecoder(mode Mode, onDetect func(Mode, string)) *Decoder {
+	d := &Decoder{onDetect: onDetect}
+	d.SetMode(mode)
+	return d
+}
+
+// SetMode 切换编码并清空解码状态；显式指定编码会覆盖自动检测的结果
+func (d *Decoder) SetMode(mode Mode) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.mode = mode
+	d.sample = nil
+	d.decided = mode != Auto
+	d.odd = nil
+	d.surrogate = 0
+}
+
+// Mode 返回当前生效的编码，自动检测尚未判定时返回 Auto
+func (d *Decoder) Mode() Mode {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.mode
+}
+
+// Decode 转换一个数据块，可直接用作 stream.Map 的函数
+func (d *Decoder) Decode(chunk []byte) []byte {
+	d.mu.Lock()
+	var reason string
+	if !d.decided {
+		offset := len(d.sample)
+		reason = d.detectLocked(chunk)
+		if d.mode == UTF16LE || d.mode == UTF16BE {
+			if bom := bomLen(d.sample); offset < bom {
+				// BOM 本身不输出
+				chunk = chunk[bom-offset:]
+			} else if offset%2 == 1 {
+				// 之前的数据已原样输出，从下一个码元边界开始解码
+				chunk = chunk[1:]
+			}
+		}
+		if d.decided {
+			d.sample = nil
+		}
+	}
+	mode := d.mode
+	var out []byte
+	switch mode {
+	case UTF16LE, UTF16BE:
+		out = d.decodeLocked(chunk, mode == UTF16BE)
+	default:
+		out = chunk
+	}
+	d.mu.Unlock()
+
+	if reason != "" && d.onDetect != nil {
+		d.onDetect(mode, reason)
+	}
+	return out
+}
+
+// detectLocked 累积样本并尝试判定，得出结论时返回原因
+func (d *Decoder) detectLocked(chunk []byte) string {
+	room := DetectWindow - len(d.sample)
+	if room > len(chunk) {
+		room = len(chunk)
+	}
+	d.sample = append(d.sample, chunk[:room]...)
+
+	mode, reason := Detect(d.sample)
+	switch {
+	case mode != Raw:
+		d.mode = mode
+	case len(d.sample) >= DetectWindow:
+		d.mode = Raw
+		reason = fmt.Sprintf("no UTF-16 text in the first %d bytes", DetectWindow)
+	default:
+		return ""
+	}
+	d.decided = true
+	return reason
+}
+
+// decodeLocked 解码 UTF-16 数据，不完整的码元与代理对留到下一块
+func (d *Decoder) decodeLocked(chunk []byte, bigEndian bool) []byte {
+	if len(d.odd) > 0 {
+		chunk = append(d.odd, chunk...)
+		d.odd = nil
+	}
+	out := make([]byte, 0, len(chunk))
+	i := 0
+	for ; i+1 < len(chunk); i += 2 {
+		var u rune
+		if bigEndian {
+			u = rune(chunk[i])<<8 | rune(chunk[i+1])
+		} else {
+			u = rune(chunk[i+1])<<8 | rune(chunk[i])
+		}
+
+		if d.surrogate != 0 {
+			r := utf16.DecodeRune(d.surrogate, u)
+			d.surrogate = 0
+			if r != utf8.RuneError {
+				out = utf8.AppendRune(out, r)
+				continue
+			}
+			// 高位代理后面不是低位代理：输出替换字符，当前码元重新处理
+			out = utf8.AppendRune(out, utf8.RuneError)
+		}
+		switch {
+		case u >= 0xD800 && u < 0xDC00:
+			d.surrogate = u
+		case utf16.IsSurrogate(u):
+			out = utf8.AppendRune(out, utf8.RuneError)
+		default:
+			out = utf8.AppendRune(out, u)
+		}
+	}
+	if i < len(chunk) {
+		d.odd = []byte{chunk[i]}
+	}
+	return out
+}
+
+// Detect 根据 BOM 或 NUL 字节的分布判断 data 是否为 UTF-16 文本
+// 返回 Raw 表示无法判定或不是 UTF-16
+func Detect(data []byte) (Mode, string) {
+	switch {
+	case len(data) >= 2 && data[0] == 0xFF && data[1] == 0xFE:
+		return UTF16LE, "UTF-16LE byte order mark"
+	case len(data) >= 2 && data[0] == 0xFE && data[1] == 0xFF:
+		return UTF16BE, "UTF-16BE byte order mark"
+	case len(data) < DetectMinBytes:
+		return Raw, ""
+	}
+
+	// 小端 ASCII 文本的奇数位置为 NUL，大端为偶数位置
+	var nul, text [2]int
+	n := len(data) &^ 1
+	for i := 0; i < n; i++ {
+		switch b := data[i]; {
+		case b == 0:
+			nul[i%2]++
+		case b >= 0x20 && b < 0x7F, b == '\r', b == '\n', b == '\t':
+			text[i%2]++
+		}
+	}
+	half := float64(n / 2)
+	if float64(nul[1]) >= nulRatio*half && float64(text[0]) >= textRatio*half {
+		return UTF16LE, "alternating NUL bytes (UTF-16LE)"
+	}
+	if float64(nul[0]) >= nulRatio*half && float64(text[1]) >= textRatio*half {
+		return UTF16BE, "alternating NUL bytes (UTF-16BE)"
+	}
+	return Raw, ""
+}
+
+func bomLen(data []byte) int {
+	if len(data) >= 2 && (data[0] == 0xFF && data[1] == 0xFE || data[0] == 0xFE && data[1] == 0xFF) {
+		return 2
+	}
+	return 0
+}
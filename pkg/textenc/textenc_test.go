@@ -0,0 +1,125 @@
+package textenc
+
+import (
+	"bytes"
+	"testing"
+	"unicode/utf16"
+)
+
+func encodeLE(s string) []byte {
+	var out []byte
+	for _, u := range utf16.Encode([]rune(s)) {
+		out = append(out, byte(u), byte(u>>8))
+	}
+	return out
+}
+
+func encodeBE(s string) []byte {
+	var out []byte
+	for _, u := range utf16.Encode([]rune(s)) {
+		out = append(out, byte(u>>8), byte(u))
+	}
+	return out
+}
+
+func TestExplicitDecodeAcrossChunks(t *testing.T) {
+	d := NewDecoder(UTF16LE, nil)
+	data := encodeLE("温度 23°C 😀 ok\r\n")
+	var out []byte
+	// 逐字节输入，代理对与码元都被拆开
+	for i := range data {
+		out = append(out, d.Decode(data[i:i+1])...)
+	}
+	if string(out) != "温度 23°C 😀 ok\r\n" {
+		t.Errorf("Unexpected output %q", out)
+	}
+
+	d.SetMode(UTF16BE)
+	if got := d.Decode(encodeBE("AB")); string(got) != "AB" {
+		t.Errorf("Unexpected BE output %q", got)
+	}
+}
+
+func TestUnpairedSurrogate(t *testing.T) {
+	d := NewDecoder(UTF16LE, nil)
+	// 高位代理后跟普通字符，以及孤立的低位代理
+	got := d.Decode([]byte{0x3D, 0xD8, 'A', 0, 0x00, 0xDC, 'B', 0})
+	if string(got) != "�A�B" {
+		t.Errorf("Unexpected output %q", got)
+	}
+}
+
+func TestAutoDetectBOM(t *testing.T) {
+	var detected Mode
+	d := NewDecoder(Auto, func(m Mode, reason string) { detected = m })
+	data := append([]byte{0xFE, 0xFF}, encodeBE("hi")...)
+	if got := d.Decode(data); string(got) != "hi" || detected != UTF16BE {
+		t.Errorf("Got %q, detected %q", got, detected)
+	}
+}
+
+func TestAutoDetectWithoutBOM(t *testing.T) {
+	var detected Mode
+	var reason string
+	d := NewDecoder(Auto, func(m Mode, r string) { detected, reason = m, r })
+	data := encodeLE("Sensor ready, firmware 1.2.3\r\nTemp=21.5\r\n")
+
+	// 第一块不足检测长度，原样输出；之后判定为 UTF-16LE
+	first := d.Decode(data[:6])
+	if !bytes.Equal(first, data[:6]) || d.Mode() != Auto {
+		t.Fatalf("Expected undecided passthrough, got %q mode %s", first, d.Mode())
+	}
+	rest := d.Decode(data[6:])
+	if detected != UTF16LE || reason == "" {
+		t.Fatalf("Expected UTF-16LE detection, got %q (%s)", detected, reason)
+	}
+	if string(rest) != "sor ready, firmware 1.2.3\r\nTemp=21.5\r\n" {
+		t.Errorf("Unexpected decoded output %q", rest)
+	}
+
+	// 从奇数偏移切换时对齐到码元边界
+	d = NewDecoder(Auto, nil)
+	d.Decode(data[:7])
+	if got := d.Decode(data[7:]); string(got) != "or ready, firmware 1.2.3\r\nTemp=21.5\r\n" {
+		t.Errorf("Unexpected output after odd offset %q", got)
+	}
+
+	// 显式设置覆盖检测结果
+	d.SetMode(Raw)
+	if got := d.Decode(data[:4]); !bytes.Equal(got, data[:4]) {
+		t.Errorf("Expected raw output after SetMode, got %q", got)
+	}
+}
+
+func TestAutoDetectIgnoresBinaryAndASCII(t *testing.T) {
+	streams := map[string][]byte{
+		"ascii": []byte("plain ASCII log line with enough bytes\r\n"),
+		// 二进制帧：零值字段很多，但非零一侧不是可打印文本
+		"binary": {0xAA, 0x00, 0x55, 0x00, 0x01, 0x00, 0x00, 0x00, 0x10, 0x00, 0xFF, 0x00, 0x80, 0x00, 0x02, 0x00, 0x03, 0x00, 0x90, 0x00},
+		// 文本与二进制混合
+		"mixed": append([]byte("OK\r\n"), 0x00, 0x01, 0x00, 0x00, 0x7E, 0x00, 0x00, 0x00, 0x05, 0x00, 'a', 0x00, 0x03, 0x00, 0xC3, 0x00),
+	}
+	for name, data := range streams {
+		var detected Mode
+		d := NewDecoder(Auto, func(m Mode, _ string) { detected = m })
+		var out []byte
+		for i := 0; i < DetectWindow; i += len(data) {
+			out = append(out, d.Decode(data)...)
+		}
+		if detected != Raw || d.Mode() != Raw {
+			t.Errorf("%s: expected detection to settle on raw, got %q", name, detected)
+		}
+		if !bytes.HasPrefix(out, data) {
+			t.Errorf("%s: data was modified", name)
+		}
+	}
+}
+
+func TestParseMode(t *testing.T) {
+	if m, err := ParseMode(""); err != nil || m != Raw {
+		t.Errorf("ParseMode(\"\") = %q, %v", m, err)
+	}
+	if _, err := ParseMode("utf-32"); err == nil {
+		t.Error("Expected error for unknown encoding")
+	}
+}
@@ -0,0 +1,272 @@
+// Package tmpl 在发送时展开数据模板中的占位符 (序号、时间戳、随机数)
+//
+// 支持的占位符：
+//
+//	{{seq}}       发送计数，每次展开加 1 (同一模板内的多个 {{seq}} 取相同的值)
+//	{{seq:04x}}   按格式输出计数，格式为 [0][宽度](d|x|X)
+//	{{ts_ms}}     Unix 毫秒时间戳
+//	{{ts_iso}}    ISO 8601 时间 (仅文本模式)
+//	{{rand:N}}    N 个随机字节 (1-256)，以十六进制输出
+//	{{env.KEY}}   会话环境变量 KEY 的值 (见 Expander.SetEnv)，按原样插入；未定义时返回 ErrUndefinedVar
+//
+// 十六进制模式下数字占位符按十六进制输出并补齐为偶数位，{{seq}} 与 {{ts_ms}} 默认格式分别为
+// 02x 与 x；展开结果必须是合法的十六进制字节，否则在发送前返回错误。
+package tmpl
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"serial-assistant/pkg/input"
+)
+
+// maxRandBytes {{rand:N}} 的上限
+const maxRandBytes = 256
+
+// 会话环境的限制
+const (
+	MaxEnvVars     = 256
+	MaxEnvKeyLen   = 64
+	MaxEnvValueLen = 4096
+)
+
+// envPrefix 环境变量占位符的前缀
+const envPrefix = "env."
+
+// ErrUndefinedVar 模板引用了未定义的环境变量
+var ErrUndefinedVar = errors.New("undefined variable")
+
+// Expander 模板展开器，持有一个连接的发送计数与会话环境，线程安全
+type Expander struct {
+	mu   sync.Mutex
+	seq  uint64
+	env  map[string]string
+	now  func() time.Time
+	rand io.Reader
+}
+
+// NewExpander 创建展开器，计数从 1 开始
+func NewExpander() *Expander {
+	return &Expander{now: time.Now, rand: rand.Reader}
+}
+
+// Reset 将计数重置为从 1 开始
+func (e *Expander) Reset() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.seq = 0
+}
+
+// ValidEnvKey 检查环境变量名：1-MaxEnvKeyLen 个字母、数字或下划线
+func ValidEnvKey(key string) error {
+	if key == "" || len(key) > MaxEnvKeyLen {
+		return fmt.Errorf("variable name must be 1-%d characters", MaxEnvKeyLen)
+	}
+	for _, ch := range key {
+		if !(ch == '_' || ch >= '0' && ch <= '9' || ch >= 'a' && ch <= 'z' || ch >= 'A' && ch <= 'Z') {
+			return fmt.Errorf("invalid variable name %q (use letters, digits and _)", key)
+		}
+	}
+	return nil
+}
+
+// SetEnv 设置会话环境变量，模板中以 {{env.KEY}} 引用；value 为空时删除该变量
+func (e *Expander) SetEnv(key, value string) error {
+	if err := ValidEnvKey(key); err != nil {
+		return err
+	}
+	if len(value) > MaxEnvValueLen {
+		return fmt.Errorf("value of %q is too long (%d bytes, max %d)", key, len(value), MaxEnvValueLen)
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if value == "" {
+		delete(e.env, key)
+		return nil
+	}
+	if _, ok := e.env[key]; !ok && len(e.env) >= MaxEnvVars {
+		return fmt.Errorf("too many variables (max %d)", MaxEnvVars)
+	}
+	if e.env == nil {
+		e.env = make(map[string]string)
+	}
+	e.env[key] = value
+	return nil
+}
+
+// Env 返回会话环境的副本
+func (e *Expander) Env() map[string]string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	out := make(map[string]string, len(e.env))
+	for k, v := range e.env {
+		out[k] = v
+	}
+	return out
+}
+
+// ClearEnv 清空会话环境
+func (e *Expander) ClearEnv() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.env = nil
+}
+
+// Expand 展开模板并返回要发送的字节
+// hexMode 时展开结果按十六进制 (允许空格分隔) 解码；出错时计数不变
+func (e *Expander) Expand(template string, hexMode bool) ([]byte, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	ctx := expandContext{seq: e.seq + 1, now: e.now(), rand: e.rand, hex: hexMode, env: e.env}
+	text, err := ctx.expand(template)
+	if err != nil {
+		return nil, err
+	}
+
+	payload := []byte(text)
+	if hexMode {
+		payload, err = input.ParseHex(text)
+		if err != nil {
+			return nil, fmt.Errorf("template does not expand to valid hex: %w", err)
+		}
+	}
+	e.seq = ctx.seq
+	return payload, nil
+}
+
+// ExpandText 以文本模式展开不用于发送的模板 (例如日志文件头)
+// vars 提供额外的占位符 ({{name}})，优先于内置占位符；此处没有发送计数，{{seq}} 会返回错误
+func ExpandText(template string, now time.Time, vars map[string]string) (string, error) {
+	ctx := expandContext{now: now, rand: rand.Reader, vars: vars, noSeq: true}
+	return ctx.expand(template)
+}
+
+type expandContext struct {
+	seq   uint64
+	now   time.Time
+	rand  io.Reader
+	hex   bool
+	vars  map[string]string
+	env   map[string]string
+	noSeq bool
+}
+
+func (c *expandContext) expand(template string) (string, error) {
+	var b strings.Builder
+	rest := template
+	for {
+		start := strings.Index(rest, "{{")
+		if start < 0 {
+			b.WriteString(rest)
+			return b.String(), nil
+		}
+		end := strings.Index(rest[start:], "}}")
+		if end < 0 {
+			return "", fmt.Errorf("unterminated placeholder at %q", rest[start:])
+		}
+		b.WriteString(rest[:start])
+
+		value, err := c.placeholder(strings.TrimSpace(rest[start+2 : start+end]))
+		if err != nil {
+			return "", err
+		}
+		b.WriteString(value)
+		rest = rest[start+end+2:]
+	}
+}
+
+func (c *expandContext) placeholder(p string) (string, error) {
+	name, arg, hasArg := strings.Cut(p, ":")
+	if value, ok := c.vars[name]; ok && !hasArg {
+		return value, nil
+	}
+	switch name {
+	case "seq":
+		if c.noSeq {
+			return "", fmt.Errorf("{{seq}} is only available in send templates")
+		}
+		format := "d"
+		if c.hex {
+			format = "02x"
+		}
+		if hasArg {
+			format = arg
+		}
+		return c.number(c.seq, format)
+	case "ts_ms":
+		if hasArg {
+			return "", fmt.Errorf("{{ts_ms}} does not take an argument")
+		}
+		format := "d"
+		if c.hex {
+			format = "x"
+		}
+		return c.number(uint64(c.now.UnixMilli()), format)
+	case "ts_iso":
+		if hasArg {
+			return "", fmt.Errorf("{{ts_iso}} does not take an argument")
+		}
+		if c.hex {
+			return "", fmt.Errorf("{{ts_iso}} cannot be used in hex mode")
+		}
+		return c.now.Format("2006-01-02T15:04:05.000Z07:00"), nil
+	case "rand":
+		n, err := strconv.Atoi(arg)
+		if !hasArg || err != nil || n < 1 || n > maxRandBytes {
+			return "", fmt.Errorf("{{rand:N}} requires N between 1 and %d, got %q", maxRandBytes, arg)
+		}
+		buf := make([]byte, n)
+		if _, err := io.ReadFull(c.rand, buf); err != nil {
+			return "", fmt.Errorf("failed to generate random bytes: %w", err)
+		}
+		return hex.EncodeToString(buf), nil
+	default:
+		// 环境变量在内置占位符之后解析
+		if key, ok := strings.CutPrefix(name, envPrefix); ok && !hasArg {
+			if value, ok := c.env[key]; ok {
+				return value, nil
+			}
+			return "", fmt.Errorf("%w {{%s}}", ErrUndefinedVar, p)
+		}
+		return "", fmt.Errorf("unknown placeholder {{%s}}", p)
+	}
+}
+
+// number 按 [0][宽度](d|x|X) 格式化数字；十六进制模式下只允许 x/X，并补齐为偶数位
+func (c *expandContext) number(v uint64, format string) (string, error) {
+	if format == "" {
+		return "", fmt.Errorf("empty number format")
+	}
+	verb := format[len(format)-1]
+	flags := format[:len(format)-1]
+	if verb != 'd' && verb != 'x' && verb != 'X' {
+		return "", fmt.Errorf("invalid number format %q (expected d, x or X)", format)
+	}
+	for _, ch := range flags {
+		if ch < '0' || ch > '9' {
+			return "", fmt.Errorf("invalid number format %q", format)
+		}
+	}
+	if c.hex && verb == 'd' {
+		return "", fmt.Errorf("number format %q is not hex; use x or X in hex mode", format)
+	}
+
+	s := fmt.Sprintf("%"+flags+string(verb), v)
+	if c.hex && len(s)%2 == 1 {
+		s = "0" + s
+	}
+	return s, nil
+}
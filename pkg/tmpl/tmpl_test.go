@@ -0,0 +1,181 @@
+package tmpl
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestExpander() *Expander {
+	e := NewExpander()
+	e.now = func() time.Time { return time.Date(2024, 3, 4, 5, 6, 7, 890e6, time.UTC) }
+	e.rand = bytes.NewReader(bytes.Repeat([]byte{0xAB}, 1024))
+	return e
+}
+
+func TestExpandText(t *testing.T) {
+	tests := []struct {
+		template string
+		expected string
+	}{
+		{"plain", "plain"},
+		{"#{{seq}}", "#1"},
+		{"{{seq:04x}}-{{seq:04X}}", "0001-0001"},
+		{"{{ seq:3d }}", "  1"},
+		{"{{ts_ms}}", "1709528767890"},
+		{"{{ts_iso}}", "2024-03-04T05:06:07.890Z"},
+		{"{{rand:2}}", "abab"},
+		{"a{{seq}}b{{seq}}c", "a1b1c"},
+	}
+	for _, tt := range tests {
+		e := newTestExpander()
+		got, err := e.Expand(tt.template, false)
+		if err != nil {
+			t.Errorf("Expand(%q) failed: %v", tt.template, err)
+			continue
+		}
+		if string(got) != tt.expected {
+			t.Errorf("Expand(%q) = %q, expected %q", tt.template, got, tt.expected)
+		}
+	}
+}
+
+func TestExpandHex(t *testing.T) {
+	tests := []struct {
+		template string
+		expected []byte
+	}{
+		{"AA {{seq}} 55", []byte{0xAA, 0x01, 0x55}},
+		{"{{seq:04x}}", []byte{0x00, 0x01}},
+		{"{{seq:x}}", []byte{0x01}}, // 补齐为偶数位
+		{"{{rand:2}} FF", []byte{0xAB, 0xAB, 0xFF}},
+		{"{{ts_ms}}", []byte{0x01, 0x8E, 0x07, 0xDA, 0xDD, 0x92}},
+	}
+	for _, tt := range tests {
+		e := newTestExpander()
+		got, err := e.Expand(tt.template, true)
+		if err != nil {
+			t.Errorf("Expand(%q) failed: %v", tt.template, err)
+			continue
+		}
+		if !bytes.Equal(got, tt.expected) {
+			t.Errorf("Expand(%q) = % X, expected % X", tt.template, got, tt.expected)
+		}
+	}
+}
+
+func TestExpandErrors(t *testing.T) {
+	tests := []struct {
+		template string
+		hex      bool
+		contains string
+	}{
+		{"{{seq", false, "unterminated"},
+		{"{{unknown}}", false, "unknown placeholder"},
+		{"{{seq:4q}}", false, "invalid number format"},
+		{"{{seq:-4d}}", false, "invalid number format"},
+		{"{{rand}}", false, "requires N"},
+		{"{{rand:0}}", false, "requires N"},
+		{"{{rand:999}}", false, "requires N"},
+		{"{{ts_ms:x}}", false, "does not take"},
+		{"{{ts_iso}}", true, "hex mode"},
+		{"{{seq:d}}", true, "not hex"},
+		{"AA ZZ {{seq}}", true, "valid hex"},
+	}
+	for _, tt := range tests {
+		e := newTestExpander()
+		_, err := e.Expand(tt.template, tt.hex)
+		if err == nil || !strings.Contains(err.Error(), tt.contains) {
+			t.Errorf("Expand(%q, hex=%v) error = %v, expected it to contain %q", tt.template, tt.hex, err, tt.contains)
+		}
+	}
+}
+
+func TestCounterAdvancesOnlyOnSuccess(t *testing.T) {
+	e := newTestExpander()
+
+	e.Expand("{{seq}}", false)
+	if _, err := e.Expand("{{seq}}{{bad}}", false); err == nil {
+		t.Fatal("Expected error")
+	}
+	got, _ := e.Expand("{{seq}}", false)
+	if string(got) != "2" {
+		t.Errorf("Failed expansion must not consume a sequence number, got %q", got)
+	}
+
+	e.Reset()
+	got, _ = e.Expand("{{seq}}", false)
+	if string(got) != "1" {
+		t.Errorf("Expected counter to restart at 1 after Reset, got %q", got)
+	}
+}
+
+func TestExpandTextVars(t *testing.T) {
+	now := time.Date(2024, 3, 4, 5, 6, 7, 890e6, time.UTC)
+	got, err := ExpandText("{{app}} {{version}} @ {{ts_iso}} ({{ts_ms}})", now, map[string]string{"app": "Serial Mate", "version": "v1.2.3"})
+	if err != nil {
+		t.Fatalf("ExpandText failed: %v", err)
+	}
+	if want := "Serial Mate v1.2.3 @ 2024-03-04T05:06:07.890Z (1709528767890)"; got != want {
+		t.Errorf("ExpandText = %q, want %q", got, want)
+	}
+
+	for _, template := range []string{"{{seq}}", "{{unknown}}", "{{app"} {
+		if _, err := ExpandText(template, now, map[string]string{"app": "x"}); err == nil {
+			t.Errorf("ExpandText(%q) should fail", template)
+		}
+	}
+}
+
+func TestExpandEnv(t *testing.T) {
+	e := newTestExpander()
+	if err := e.SetEnv("serial", "SN-{{seq}}"); err != nil {
+		t.Fatalf("SetEnv: %v", err)
+	}
+	e.SetEnv("addr", "0A")
+
+	// 变量的值按原样插入，不再展开其中的占位符
+	got, err := e.Expand("SET {{env.serial}} #{{seq}}", false)
+	if err != nil || string(got) != "SET SN-{{seq}} #1" {
+		t.Errorf("Expand = %q, %v", got, err)
+	}
+	got, err = e.Expand("AA {{ env.addr }} {{seq}}", true)
+	if err != nil || !bytes.Equal(got, []byte{0xAA, 0x0A, 0x02}) {
+		t.Errorf("Expand hex = % X, %v", got, err)
+	}
+
+	_, err = e.Expand("GET {{env.missing}}", false)
+	if !errors.Is(err, ErrUndefinedVar) || !strings.Contains(err.Error(), "env.missing") {
+		t.Errorf("Expected ErrUndefinedVar naming the variable, got %v", err)
+	}
+	if got, _ := e.Expand("{{seq}}", false); string(got) != "3" {
+		t.Errorf("Undefined variable must not consume a sequence number, got %q", got)
+	}
+
+	e.SetEnv("addr", "")
+	if env := e.Env(); len(env) != 1 || env["serial"] != "SN-{{seq}}" {
+		t.Errorf("Env after delete = %v", env)
+	}
+	e.Reset()
+	if len(e.Env()) != 1 {
+		t.Error("Reset must keep the environment")
+	}
+	e.ClearEnv()
+	if len(e.Env()) != 0 {
+		t.Error("ClearEnv must remove all variables")
+	}
+
+	for _, key := range []string{"", "a.b", "sp ace", strings.Repeat("k", MaxEnvKeyLen+1)} {
+		if err := e.SetEnv(key, "v"); err == nil {
+			t.Errorf("SetEnv(%q) should fail", key)
+		}
+	}
+	if err := e.SetEnv("big", strings.Repeat("v", MaxEnvValueLen+1)); err == nil {
+		t.Error("SetEnv with an oversized value should fail")
+	}
+	if _, err := ExpandText("{{env.serial}}", time.Now(), nil); !errors.Is(err, ErrUndefinedVar) {
+		t.Errorf("ExpandText has no environment, got %v", err)
+	}
+}
@@ -0,0 +1,124 @@
+package transport
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// MaxUDPPayload IPv4 下单个 UDP 数据报的最大负载 (65535 - 8 - 20)
+	MaxUDPPayload = 65507
+	// EthernetUDPPayload 以太网 MTU 1500 下不分片的 UDP 负载
+	EthernetUDPPayload = 1472
+
+	// DefaultLargeSendThreshold 超过该大小的串口/TCP 发送改为分块写入
+	DefaultLargeSendThreshold = 64 * 1024
+	// DefaultChunkSize 分块写入时每块的大小
+	DefaultChunkSize = 4096
+)
+
+// DatagramPolicy 负载超过数据报上限时的处理方式
+type DatagramPolicy string
+
+const (
+	// DatagramReject 拒绝发送
+	DatagramReject DatagramPolicy = "reject"
+	// DatagramSplit 拆分为多个数据报发送
+	DatagramSplit DatagramPolicy = "split"
+)
+
+// ParseDatagramPolicy 解析数据报策略，空字符串视为 DatagramReject
+func ParseDatagramPolicy(name string) (DatagramPolicy, error) {
+	switch DatagramPolicy(name) {
+	case "", DatagramReject:
+		return DatagramReject, nil
+	case DatagramSplit:
+		return DatagramSplit, nil
+	default:
+		return "", fmt.Errorf("unknown datagram policy %q (expected reject or split)", name)
+	}
+}
+
+// ErrDatagramTooLarge 负载超过数据报上限且策略为拒绝
+var ErrDatagramTooLarge = errors.New("payload exceeds datagram limit")
+
+// Datagrams 按 limit 将 p 划分为数据报，limit <= 0 或超过 MaxUDPPayload 时使用 MaxUDPPayload
+// 超限且策略为 DatagramReject 时返回 ErrDatagramTooLarge
+func Datagrams(p []byte, limit int, policy DatagramPolicy) ([][]byte, error) {
+	if limit <= 0 || limit > MaxUDPPayload {
+		limit = MaxUDPPayload
+	}
+	if len(p) <= limit {
+		return [][]byte{p}, nil
+	}
+	if policy != DatagramSplit {
+		return nil, fmt.Errorf("%w: %d bytes > %d bytes", ErrDatagramTooLarge, len(p), limit)
+	}
+	return Chunks(p, limit), nil
+}
+
+// Chunks 将 p 按 size 切分，返回的切片共享 p 的底层数组
+func Chunks(p []byte, size int) [][]byte {
+	if size <= 0 {
+		size = DefaultChunkSize
+	}
+	chunks := make([][]byte, 0, (len(p)+size-1)/size)
+	for len(p) > size {
+		chunks = append(chunks, p[:size])
+		p = p[size:]
+	}
+	if len(p) > 0 {
+		chunks = append(chunks, p)
+	}
+	return chunks
+}
+
+// TextChunks 同 Chunks，但不会把多字节 UTF-8 字符拆到两块中
+// 单个字符长于 size 时 (size < 4) 该字符独占一块；无效的 UTF-8 字节按单字节处理
+func TextChunks(p []byte, size int) [][]byte {
+	if size <= 0 {
+		size = DefaultChunkSize
+	}
+	chunks := make([][]byte, 0, (len(p)+size-1)/size)
+	for len(p) > size {
+		cut := size
+		// 向前回退到字符起始位置 (最多回退 utf8.UTFMax-1 个续字节)
+		for back := 0; back < utf8.UTFMax-1 && cut > 0 && !utf8.RuneStart(p[cut]); back++ {
+			cut--
+		}
+		if cut == 0 || !utf8.RuneStart(p[cut]) {
+			// 回退失败：size 小于字符长度或不是有效的 UTF-8，取完整的第一个字符
+			_, n := utf8.DecodeRune(p)
+			cut = size
+			if n > size {
+				cut = n
+			}
+		}
+		chunks = append(chunks, p[:cut])
+		p = p[cut:]
+	}
+	if len(p) > 0 {
+		chunks = append(chunks, p)
+	}
+	return chunks
+}
+
+// WriteChunked 按 chunk 大小分块写入 p，每块单独应用 timeout
+// 每块写完后调用 progress(已发送, 总数)，progress 可为 nil
+func WriteChunked(w io.Writer, p []byte, chunk int, timeout time.Duration, progress func(sent, total int)) (int, error) {
+	sent := 0
+	for _, c := range Chunks(p, chunk) {
+		n, err := Write(w, c, timeout)
+		sent += n
+		if err != nil {
+			return sent, err
+		}
+		if progress != nil {
+			progress(sent, len(p))
+		}
+	}
+	return sent, nil
+}
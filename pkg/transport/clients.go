@@ -0,0 +1,218 @@
+package transport
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"serial-assistant/pkg/apperr"
+)
+
+// Client TCP 服务端接入的一个客户端
+type Client struct {
+	Conn  net.Conn
+	Addr  string
+	Since time.Time
+
+	rx atomic.Uint64
+	tx atomic.Uint64
+}
+
+// CountRx 累加接收字节数
+func (c *Client) CountRx(n int) {
+	c.rx.Add(uint64(n))
+}
+
+// CountTx 累加发送字节数
+func (c *Client) CountTx(n int) {
+	c.tx.Add(uint64(n))
+}
+
+// ClientInfo 客户端状态快照
+type ClientInfo struct {
+	Addr        string `json:"addr"`
+	ConnectedAt int64  `json:"connectedAt"` // Unix 毫秒
+	RxBytes     uint64 `json:"rxBytes"`
+	TxBytes     uint64 `json:"txBytes"`
+}
+
+// Info 返回客户端状态快照
+func (c *Client) Info() ClientInfo {
+	return ClientInfo{
+		Addr:        c.Addr,
+		ConnectedAt: c.Since.UnixMilli(),
+		RxBytes:     c.rx.Load(),
+		TxBytes:     c.tx.Load(),
+	}
+}
+
+// ClientSet TCP 服务端当前接入的客户端集合，以远端地址为键，线程安全
+type ClientSet struct {
+	mu       sync.Mutex
+	clients  map[string]*Client
+	lastLeft time.Time // 最近一次 Remove 的时间
+}
+
+// NewClientSet 创建空的客户端集合
+func NewClientSet() *ClientSet {
+	return &ClientSet{clients: make(map[string]*Client)}
+}
+
+// Add 记录新接入的连接
+func (s *ClientSet) Add(conn net.Conn) *Client {
+	c := &Client{Conn: conn, Addr: conn.RemoteAddr().String(), Since: time.Now()}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.clients[c.Addr] = c
+	return c
+}
+
+// Remove 移除客户端 (不关闭连接)，返回客户端是否仍在集合中
+func (s *ClientSet) Remove(c *Client) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.clients[c.Addr] != c {
+		return false
+	}
+	delete(s.clients, c.Addr)
+	s.lastLeft = time.Now()
+	return true
+}
+
+// LastDisconnect 返回最近一个客户端断开 (Remove) 的时间，还没有客户端断开时为零值
+func (s *ClientSet) LastDisconnect() time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.lastLeft
+}
+
+// NoClientError 返回没有客户端接入时发送的错误 (NO_CLIENT)，说明距最近一个客户端断开已过去多久
+func (s *ClientSet) NoClientError(now time.Time) *apperr.Error {
+	left := s.LastDisconnect()
+	if left.IsZero() {
+		return apperr.New(apperr.NoClient, "no client connected; no client has connected yet")
+	}
+	return apperr.New(apperr.NoClient, "no client connected; last client disconnected %v ago", roundAgo(now.Sub(left)))
+}
+
+// roundAgo 一秒以内保留毫秒，其余取整到秒
+func roundAgo(d time.Duration) time.Duration {
+	if d < time.Second {
+		return d.Round(time.Millisecond)
+	}
+	return d.Round(time.Second)
+}
+
+// Get 按远端地址查找客户端
+func (s *ClientSet) Get(addr string) *Client {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.clients[addr]
+}
+
+// Clients 返回所有客户端，按接入时间排序
+func (s *ClientSet) Clients() []*Client {
+	s.mu.Lock()
+	out := make([]*Client, 0, len(s.clients))
+	for _, c := range s.clients {
+		out = append(out, c)
+	}
+	s.mu.Unlock()
+
+	sort.Slice(out, func(i, j int) bool {
+		if !out[i].Since.Equal(out[j].Since) {
+			return out[i].Since.Before(out[j].Since)
+		}
+		return out[i].Addr < out[j].Addr
+	})
+	return out
+}
+
+// List 返回所有客户端的状态快照，按接入时间排序
+func (s *ClientSet) List() []ClientInfo {
+	clients := s.Clients()
+	out := make([]ClientInfo, len(clients))
+	for i, c := range clients {
+		out[i] = c.Info()
+	}
+	return out
+}
+
+// Len 返回客户端数量
+func (s *ClientSet) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.clients)
+}
+
+// CloseAll 关闭并移除所有客户端
+func (s *ClientSet) CloseAll() {
+	s.mu.Lock()
+	clients := s.clients
+	s.clients = make(map[string]*Client)
+	s.mu.Unlock()
+
+	for _, c := range clients {
+		c.Conn.Close()
+	}
+}
+
+// ClientLostPolicy TCP 服务端最后一个客户端断开后的处理方式
+type ClientLostPolicy string
+
+const (
+	// ClientLostWait 继续监听，只输出提示 (默认)
+	ClientLostWait ClientLostPolicy = "wait"
+	// ClientLostNotify 继续监听，并发送 client-lost 事件
+	ClientLostNotify ClientLostPolicy = "notify"
+	// ClientLostClose 发送 client-lost 事件后关闭整个服务端
+	ClientLostClose ClientLostPolicy = "close"
+)
+
+// ParseClientLostPolicy 解析客户端断开策略，空字符串视为 ClientLostWait
+func ParseClientLostPolicy(name string) (ClientLostPolicy, error) {
+	switch ClientLostPolicy(name) {
+	case "", ClientLostWait:
+		return ClientLostWait, nil
+	case ClientLostNotify:
+		return ClientLostNotify, nil
+	case ClientLostClose:
+		return ClientLostClose, nil
+	default:
+		return "", fmt.Errorf("unknown client-lost policy %q (expected wait, notify or close)", name)
+	}
+}
+
+// ClientLost client-lost 事件的数据
+type ClientLost struct {
+	Addr        string           `json:"addr"`
+	Code        apperr.Code      `json:"code"`   // 断开原因的错误码 (例如 REMOTE_CLOSED、CONNECTION_RESET)
+	Reason      string           `json:"reason"` // 原始读取错误
+	ConnectedMs int64            `json:"connectedMs"`
+	Policy      ClientLostPolicy `json:"policy"`
+}
+
+// NewClientLost 由客户端读取循环结束时的错误生成 client-lost 事件
+func NewClientLost(c *Client, err error, now time.Time, policy ClientLostPolicy) ClientLost {
+	ev := TranslateError(err)
+	reason := ev.Message
+	if ev.Detail != "" {
+		reason = ev.Detail
+	}
+	return ClientLost{
+		Addr:        c.Addr,
+		Code:        ev.Code,
+		Reason:      reason,
+		ConnectedMs: now.Sub(c.Since).Milliseconds(),
+		Policy:      policy,
+	}
+}
@@ -0,0 +1,81 @@
+package transport
+
+import (
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"serial-assistant/pkg/apperr"
+)
+
+// pipeClient 以 net.Pipe 模拟一个接入的客户端，返回服务端一侧的连接与客户端一侧
+func pipeClient(t *testing.T) (server net.Conn, peer net.Conn) {
+	t.Helper()
+	server, peer = net.Pipe()
+	t.Cleanup(func() {
+		server.Close()
+		peer.Close()
+	})
+	return server, peer
+}
+
+func TestNoClientError(t *testing.T) {
+	s := NewClientSet()
+	now := time.Now()
+	err := s.NoClientError(now)
+	if apperr.CodeOf(err) != apperr.NoClient || !strings.Contains(err.Error(), "no client has connected yet") {
+		t.Errorf("before any client: %v", err)
+	}
+
+	server, _ := pipeClient(t)
+	c := s.Add(server)
+	if !s.Remove(c) || s.Remove(c) {
+		t.Fatal("Remove should report the client only once")
+	}
+	left := s.LastDisconnect()
+	if left.IsZero() {
+		t.Fatal("LastDisconnect not recorded")
+	}
+	err = s.NoClientError(left.Add(12*time.Second + 300*time.Millisecond))
+	if apperr.CodeOf(err) != apperr.NoClient || !strings.Contains(err.Error(), "last client disconnected 12s ago") {
+		t.Errorf("after disconnect: %v", err)
+	}
+	if err := s.NoClientError(left.Add(250 * time.Millisecond)); !strings.Contains(err.Error(), "250ms ago") {
+		t.Errorf("sub-second: %v", err)
+	}
+}
+
+func TestClientLostFromPipe(t *testing.T) {
+	s := NewClientSet()
+	server, peer := pipeClient(t)
+	c := s.Add(server)
+
+	// 客户端一侧关闭后服务端读取得到 io.EOF
+	peer.Close()
+	_, err := server.Read(make([]byte, 1))
+	if err != io.EOF {
+		t.Fatalf("Read after peer close: %v", err)
+	}
+	s.Remove(c)
+
+	ev := NewClientLost(c, err, c.Since.Add(1500*time.Millisecond), ClientLostNotify)
+	if ev.Addr != c.Addr || ev.Code != apperr.RemoteClosed || ev.Reason != "EOF" || ev.ConnectedMs != 1500 || ev.Policy != ClientLostNotify {
+		t.Errorf("Unexpected event: %+v", ev)
+	}
+	if s.Len() != 0 {
+		t.Errorf("%d clients left", s.Len())
+	}
+}
+
+func TestParseClientLostPolicy(t *testing.T) {
+	for name, want := range map[string]ClientLostPolicy{"": ClientLostWait, "wait": ClientLostWait, "notify": ClientLostNotify, "close": ClientLostClose} {
+		if got, err := ParseClientLostPolicy(name); err != nil || got != want {
+			t.Errorf("ParseClientLostPolicy(%q) = %q, %v", name, got, err)
+		}
+	}
+	if _, err := ParseClientLostPolicy("exit"); err == nil {
+		t.Error("Expected error for unknown policy")
+	}
+}
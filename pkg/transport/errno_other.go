@@ -0,0 +1,10 @@
+//go:build !windows
+
+package transport
+
+import "syscall"
+
+var (
+	resetErrnos       = []syscall.Errno{syscall.ECONNRESET, syscall.ECONNABORTED, syscall.EPIPE}
+	unreachableErrnos = []syscall.Errno{syscall.ENETUNREACH, syscall.EHOSTUNREACH, syscall.ENETDOWN}
+)
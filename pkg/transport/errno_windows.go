@@ -0,0 +1,21 @@
+package transport
+
+import (
+	"syscall"
+
+	"golang.org/x/sys/windows"
+)
+
+// Windows 套接字错误码 (WSA*) 与 POSIX 错误码不同，需要单独列出
+var (
+	resetErrnos = []syscall.Errno{
+		windows.WSAECONNRESET,
+		windows.WSAECONNABORTED,
+		windows.ERROR_NETNAME_DELETED,
+	}
+	unreachableErrnos = []syscall.Errno{
+		windows.WSAENETUNREACH,
+		windows.WSAEHOSTUNREACH,
+		windows.WSAENETDOWN,
+	}
+)
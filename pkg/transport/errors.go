@@ -0,0 +1,57 @@
+package transport
+
+import (
+	"errors"
+	"io"
+	"net"
+	"os"
+	"syscall"
+
+	"serial-assistant/pkg/apperr"
+)
+
+// TranslateError 将网络连接 (TCP/UDP) 的读写错误映射为带错误码与建议操作的事件
+func TranslateError(err error) apperr.Event {
+	detail := ""
+	if err != nil {
+		detail = err.Error()
+	}
+	var coded *apperr.Error
+	var errno syscall.Errno
+	switch {
+	case err == nil:
+		return apperr.NewEvent(apperr.IOError, detail)
+	case errors.As(err, &coded):
+		return apperr.NewEvent(coded.Code, detail)
+	case errors.Is(err, io.EOF):
+		return apperr.NewEvent(apperr.RemoteClosed, detail)
+	case errors.Is(err, ErrTimeout), errors.Is(err, os.ErrDeadlineExceeded):
+		return apperr.NewEvent(apperr.WriteTimeout, detail)
+	case errors.Is(err, os.ErrPermission):
+		return apperr.NewEvent(apperr.PermissionDenied, detail)
+	case errors.As(err, &errno) && containsErrno(resetErrnos, errno):
+		return apperr.NewEvent(apperr.ConnectionReset, detail)
+	case errors.As(err, &errno) && containsErrno(unreachableErrnos, errno):
+		return apperr.NewEvent(apperr.NetworkUnreachable, detail)
+	case errors.Is(err, net.ErrClosed):
+		// 本端已关闭的套接字，通常与 Close 竞争，按对端关闭处理
+		return apperr.NewEvent(apperr.RemoteClosed, detail)
+	default:
+		return apperr.NewEvent(apperr.IOError, detail)
+	}
+}
+
+// IsHalfClose 判断流式连接的读取错误是否为对端关闭写方向 (TCP FIN)：此时只是不会再收到数据，
+// 对端可能仍在读取，本端仍可发送；复位等硬错误返回 false
+func IsHalfClose(err error) bool {
+	return errors.Is(err, io.EOF)
+}
+
+func containsErrno(list []syscall.Errno, errno syscall.Errno) bool {
+	for _, e := range list {
+		if e == errno {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,98 @@
+package transport
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"serial-assistant/pkg/apperr"
+)
+
+func TestTranslateError(t *testing.T) {
+	opErr := func(err error) error {
+		return &net.OpError{Op: "read", Net: "tcp", Err: os.NewSyscallError("read", err)}
+	}
+	tests := []struct {
+		err  error
+		code apperr.Code
+	}{
+		{io.EOF, apperr.RemoteClosed},
+		{fmt.Errorf("read: %w", io.EOF), apperr.RemoteClosed},
+		{opErr(resetErrnos[0]), apperr.ConnectionReset},
+		{opErr(unreachableErrnos[0]), apperr.NetworkUnreachable},
+		{opErr(os.ErrPermission), apperr.PermissionDenied},
+		{ErrTimeout, apperr.WriteTimeout},
+		{&net.OpError{Op: "write", Net: "tcp", Err: os.ErrDeadlineExceeded}, apperr.WriteTimeout},
+		{&net.OpError{Op: "read", Net: "tcp", Err: net.ErrClosed}, apperr.RemoteClosed},
+		{apperr.New(apperr.ReadOnly, "read-only"), apperr.ReadOnly},
+		{errors.New("something odd"), apperr.IOError},
+	}
+	for _, tt := range tests {
+		ev := TranslateError(tt.err)
+		if ev.Code != tt.code {
+			t.Errorf("TranslateError(%v) = %s, want %s", tt.err, ev.Code, tt.code)
+		}
+		if ev.Detail != tt.err.Error() {
+			t.Errorf("TranslateError(%v) detail = %q", tt.err, ev.Detail)
+		}
+	}
+}
+
+// TestHalfClosedPeerKeepsReading 对端关闭写方向后仍在读取：本端读到 EOF，但之后的发送仍能到达对端
+func TestHalfClosedPeerKeepsReading(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	received := make(chan string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			received <- err.Error()
+			return
+		}
+		defer conn.Close()
+		conn.Write([]byte("bye"))
+		conn.(*net.TCPConn).CloseWrite()
+		data, _ := io.ReadAll(conn)
+		received <- string(data)
+	}()
+
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(io.LimitReader(conn, 3))
+	if err != nil || string(data) != "bye" {
+		t.Fatalf("read %q, %v", data, err)
+	}
+	_, err = conn.Read(make([]byte, 16))
+	if !IsHalfClose(err) {
+		t.Fatalf("read after FIN: %v, want a half-close", err)
+	}
+
+	if _, err := Write(conn, []byte("still here"), time.Second); err != nil {
+		t.Fatalf("write after half-close failed: %v", err)
+	}
+	conn.(*net.TCPConn).CloseWrite()
+	if got := <-received; got != "still here" {
+		t.Errorf("server received %q", got)
+	}
+	conn.Close()
+}
+
+func TestIsHalfClose(t *testing.T) {
+	if IsHalfClose(syscall.ECONNRESET) || IsHalfClose(nil) {
+		t.Error("hard errors are not a half-close")
+	}
+	if !IsHalfClose(fmt.Errorf("read: %w", io.EOF)) {
+		t.Error("wrapped EOF should be a half-close")
+	}
+}
@@ -0,0 +1,65 @@
+package transport
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// MaxPortSweep 一个端口规格最多包含的端口数
+const MaxPortSweep = 64
+
+// ParsePorts 解析端口规格：单个端口 ("5000")、范围 ("5000-5010") 或以逗号分隔的组合 ("5000,5002,6000-6001")
+// 重复的端口只保留第一次出现的位置；空字符串返回 nil，表示由系统分配端口
+func ParsePorts(spec string) ([]int, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return nil, nil
+	}
+
+	var ports []int
+	seen := make(map[int]bool)
+	add := func(p int) error {
+		if seen[p] {
+			return nil
+		}
+		if len(ports) == MaxPortSweep {
+			return fmt.Errorf("too many ports in %q (max %d)", spec, MaxPortSweep)
+		}
+		seen[p] = true
+		ports = append(ports, p)
+		return nil
+	}
+
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		lo, hi, isRange := strings.Cut(part, "-")
+		first, err := parsePort(lo)
+		if err != nil {
+			return nil, err
+		}
+		last := first
+		if isRange {
+			if last, err = parsePort(hi); err != nil {
+				return nil, err
+			}
+			if last < first {
+				return nil, fmt.Errorf("invalid port range %q", part)
+			}
+		}
+		for p := first; p <= last; p++ {
+			if err := add(p); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return ports, nil
+}
+
+func parsePort(s string) (int, error) {
+	p, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil || p < 0 || p > 65535 {
+		return 0, fmt.Errorf("invalid port %q", s)
+	}
+	return p, nil
+}
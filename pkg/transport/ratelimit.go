@@ -0,0 +1,145 @@
+package transport
+
+import (
+	"io"
+	"sync"
+	"time"
+)
+
+// burstFraction 令牌桶容量为每秒速率的 1/4，即最多允许 250ms 的突发
+const burstFraction = 4
+
+// Bucket 发送带宽的令牌桶，速率为 0 表示不限速
+// 令牌可以透支：Reserve 总是立即扣除，返回需要等待的时间，
+// 调用方在不持有 Bucket 锁的情况下等待，之后的预约在透支还清后才开始
+type Bucket struct {
+	mu     sync.Mutex
+	rate   float64 // 字节/秒
+	burst  float64
+	tokens float64
+	last   time.Time
+	now    func() time.Time
+
+	// 利用率统计：windowBytes 为当前窗口内预约的字节数，utilization 为上一个完整窗口的结果
+	windowStart time.Time
+	windowBytes int
+	utilization float64
+}
+
+// NewBucket 创建令牌桶，bytesPerSec <= 0 表示不限速
+func NewBucket(bytesPerSec int) *Bucket {
+	b := &Bucket{now: time.Now}
+	b.SetRate(bytesPerSec)
+	return b
+}
+
+// SetRate 修改速率，桶被重新填满
+func (b *Bucket) SetRate(bytesPerSec int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if bytesPerSec < 0 {
+		bytesPerSec = 0
+	}
+	b.rate = float64(bytesPerSec)
+	b.burst = b.rate / burstFraction
+	if b.burst < 1 && b.rate > 0 {
+		b.burst = 1
+	}
+	b.tokens = b.burst
+	b.last = b.now()
+	b.windowStart = b.last
+	b.windowBytes = 0
+	b.utilization = 0
+}
+
+// Rate 返回当前速率 (字节/秒)，0 表示不限速
+func (b *Bucket) Rate() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return int(b.rate)
+}
+
+// ChunkSize 平滑发送时建议的单次写入大小 (等于桶容量)，不限速时返回 0
+func (b *Bucket) ChunkSize() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return int(b.burst)
+}
+
+// Reserve 预约发送 n 字节，返回发送前需要等待的时间
+func (b *Bucket) Reserve(n int) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	b.rollWindowLocked(now)
+	b.windowBytes += n
+	if b.rate <= 0 {
+		return 0
+	}
+
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+	b.last = now
+	b.tokens -= float64(n)
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+// Utilization 返回最近一秒预约的字节数占速率上限的比例 (0-1，透支时可能略大于 1)，不限速时返回 0
+func (b *Bucket) Utilization() float64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.rollWindowLocked(b.now())
+	if b.rate <= 0 {
+		return 0
+	}
+	return b.utilization
+}
+
+func (b *Bucket) rollWindowLocked(now time.Time) {
+	elapsed := now.Sub(b.windowStart)
+	if elapsed < time.Second {
+		return
+	}
+	if elapsed >= 2*time.Second {
+		// 上一个窗口之后一直空闲
+		b.utilization = 0
+	} else if b.rate > 0 {
+		b.utilization = float64(b.windowBytes) / (b.rate * elapsed.Seconds())
+	}
+	b.windowStart = now
+	b.windowBytes = 0
+}
+
+// WritePaced 按 b 的速率平滑写入 p：拆分为桶容量大小的块，每块写入前等待令牌
+// b 不限速时等同于 Write；sleep 为 nil 时使用 time.Sleep
+func WritePaced(w io.Writer, p []byte, b *Bucket, timeout time.Duration, sleep func(time.Duration), progress func(sent, total int)) (int, error) {
+	chunk := b.ChunkSize()
+	if chunk <= 0 {
+		return Write(w, p, timeout)
+	}
+	if sleep == nil {
+		sleep = time.Sleep
+	}
+
+	sent := 0
+	for _, c := range Chunks(p, chunk) {
+		if d := b.Reserve(len(c)); d > 0 {
+			sleep(d)
+		}
+		n, err := Write(w, c, timeout)
+		sent += n
+		if err != nil {
+			return sent, err
+		}
+		if progress != nil {
+			progress(sent, len(p))
+		}
+	}
+	return sent, nil
+}
@@ -0,0 +1,81 @@
+// Package transport 提供带超时的写入辅助函数，避免卡死的设备使写操作永久阻塞
+package transport
+
+import (
+	"errors"
+	"io"
+	"net"
+	"os"
+	"time"
+)
+
+// ErrTimeout 写入超时
+var ErrTimeout = errors.New("write timed out")
+
+// deadlineWriter 支持写超时的连接（net.Conn 等）
+type deadlineWriter interface {
+	io.Writer
+	SetWriteDeadline(t time.Time) error
+}
+
+// Write 在 timeout 内将 p 写入 w，timeout <= 0 表示不限时
+// 支持 SetWriteDeadline 的连接直接使用写超时；其他 Writer（如串口）在单独的
+// goroutine 中写入，超时后立即返回 ErrTimeout，残留的写操作会在端口关闭时结束
+func Write(w io.Writer, p []byte, timeout time.Duration) (int, error) {
+	if timeout <= 0 {
+		return w.Write(p)
+	}
+
+	if dw, ok := w.(deadlineWriter); ok {
+		if err := dw.SetWriteDeadline(time.Now().Add(timeout)); err == nil {
+			n, err := dw.Write(p)
+			dw.SetWriteDeadline(time.Time{})
+			return n, classify(err)
+		}
+	}
+
+	type result struct {
+		n   int
+		err error
+	}
+	done := make(chan result, 1)
+	go func() {
+		n, err := w.Write(p)
+		done <- result{n, err}
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case r := <-done:
+		return r.n, r.err
+	case <-timer.C:
+		return 0, ErrTimeout
+	}
+}
+
+// WriteTo 在 timeout 内将 p 发送到 addr，timeout <= 0 表示不限时
+func WriteTo(pc net.PacketConn, p []byte, addr net.Addr, timeout time.Duration) (int, error) {
+	if timeout > 0 {
+		if err := pc.SetWriteDeadline(time.Now().Add(timeout)); err == nil {
+			defer pc.SetWriteDeadline(time.Time{})
+		}
+	}
+	n, err := pc.WriteTo(p, addr)
+	return n, classify(err)
+}
+
+// classify 将各种超时错误统一为 ErrTimeout
+func classify(err error) error {
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, os.ErrDeadlineExceeded) {
+		return ErrTimeout
+	}
+	var nerr net.Error
+	if errors.As(err, &nerr) && nerr.Timeout() {
+		return ErrTimeout
+	}
+	return err
+}
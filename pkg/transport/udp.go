@@ -0,0 +1,146 @@
+package transport
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+// udpReadPoll 读取循环检查停止信号的间隔
+const udpReadPoll = 500 * time.Millisecond
+
+// UDPSocket 打开的 UDP 套接字
+type UDPSocket struct {
+	Conns    []net.PacketConn // 绑定的全部套接字，第一个用于发送
+	Failures []string         // 端口扫描时未能绑定的端口及原因
+	Dialed   bool             // connected 模式 (net.DialUDP)，只接收来自远端地址的数据报
+}
+
+// OpenUDP 按端口规格 (见 ParsePorts) 打开 UDP 套接字
+// connected 为 true 时使用 connected 套接字，由内核过滤其他地址的数据报，此时必须指定 remote 且只能绑定一个端口；
+// 否则绑定一个或多个端口监听，端口扫描中部分端口绑定失败时记录到 Failures，全部失败才返回错误
+func OpenUDP(localPort string, remote *net.UDPAddr, connected bool) (*UDPSocket, error) {
+	ports, err := ParsePorts(localPort)
+	if err != nil {
+		return nil, fmt.Errorf("invalid local port: %w", err)
+	}
+
+	if connected {
+		if remote == nil {
+			return nil, errors.New("connected UDP mode requires a remote address")
+		}
+		if len(ports) > 1 {
+			return nil, errors.New("connected UDP mode requires a single local port")
+		}
+		lAddr, err := net.ResolveUDPAddr("udp", ":"+localPort)
+		if err != nil {
+			return nil, fmt.Errorf("invalid local address: %w", err)
+		}
+		conn, err := net.DialUDP("udp", lAddr, remote)
+		if err != nil {
+			return nil, fmt.Errorf("dial failed: %w", err)
+		}
+		return &UDPSocket{Conns: []net.PacketConn{conn}, Dialed: true}, nil
+	}
+
+	if len(ports) <= 1 {
+		conn, err := net.ListenPacket("udp", ":"+localPort)
+		if err != nil {
+			return nil, fmt.Errorf("listen failed: %w", err)
+		}
+		return &UDPSocket{Conns: []net.PacketConn{conn}}, nil
+	}
+
+	s := &UDPSocket{}
+	for _, p := range ports {
+		conn, err := net.ListenPacket("udp", fmt.Sprintf(":%d", p))
+		if err != nil {
+			s.Failures = append(s.Failures, fmt.Sprintf("%d (%v)", p, err))
+			continue
+		}
+		s.Conns = append(s.Conns, conn)
+	}
+	if len(s.Conns) == 0 {
+		return nil, fmt.Errorf("listen failed: no port could be bound: %s", strings.Join(s.Failures, "; "))
+	}
+	return s, nil
+}
+
+// Close 关闭全部套接字，返回第一个错误
+func (s *UDPSocket) Close() error {
+	var err error
+	for _, c := range s.Conns {
+		if cerr := c.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+// LocalPort 返回套接字绑定的本地端口
+func LocalPort(conn net.PacketConn) int {
+	if addr, ok := conn.LocalAddr().(*net.UDPAddr); ok {
+		return addr.Port
+	}
+	return 0
+}
+
+// UDPPeer UDP 的发送目标，不是线程安全的
+// 指定了远端或 connected 模式时目标固定；否则以第一个发送数据报的地址作为回复地址，
+// 并从收到该数据报的套接字回复，使对端看到的源端口一致
+type UDPPeer struct {
+	Remote net.Addr       // 发送目标，nil 表示尚未确定
+	Reply  net.PacketConn // 收到当前对端数据报的套接字，nil 时使用第一个套接字
+	Dialed bool           // connected 模式，远端固定
+}
+
+// Observe 记录经由 conn 收到来自 addr 的数据报，确定了回复地址时返回 true
+func (p *UDPPeer) Observe(conn net.PacketConn, addr net.Addr) bool {
+	if p.Remote != nil || p.Dialed {
+		return false
+	}
+	p.Remote, p.Reply = addr, conn
+	return true
+}
+
+// Ready 是否已有发送目标
+func (p *UDPPeer) Ready() bool {
+	return p.Dialed || p.Remote != nil
+}
+
+// Datagram 收到的一个 UDP 数据报
+type Datagram struct {
+	Data      []byte
+	Source    net.Addr
+	LocalPort int // 接收数据报的本地端口，只在绑定了多个端口时设置
+}
+
+// ReadDatagrams 读取 conn 直到 stop 关闭 (返回 nil) 或读取出错 (返回该错误)，对每个数据报调用 fn；
+// sweep 为 true 时 (绑定了多个端口) 数据报附带本地端口。Data 在回调返回后仍然有效
+func ReadDatagrams(conn net.PacketConn, stop <-chan struct{}, sweep bool, fn func(Datagram)) error {
+	localPort := 0
+	if sweep {
+		localPort = LocalPort(conn)
+	}
+	buff := make([]byte, 4096)
+	for {
+		select {
+		case <-stop:
+			return nil
+		default:
+		}
+		conn.SetReadDeadline(time.Now().Add(udpReadPoll))
+		n, addr, err := conn.ReadFrom(buff)
+		if err != nil {
+			if nerr, ok := err.(net.Error); ok && nerr.Timeout() {
+				continue
+			}
+			return err
+		}
+		data := make([]byte, n)
+		copy(data, buff[:n])
+		fn(Datagram{Data: data, Source: addr, LocalPort: localPort})
+	}
+}
@@ -0,0 +1,144 @@
+package transport
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+// listenLoopback 在回环地址上打开一个 UDP 套接字，作为对端或第三方
+func listenLoopback(t *testing.T) net.PacketConn {
+	t.Helper()
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("UDP loopback unavailable: %v", err)
+	}
+	t.Cleanup(func() { pc.Close() })
+	return pc
+}
+
+// readAll 读取 conn 直到 wait 内没有新的数据报
+func readAll(t *testing.T, conn net.PacketConn, sweep bool, wait time.Duration) []Datagram {
+	t.Helper()
+	stop := make(chan struct{})
+	got := make(chan Datagram, 16)
+	done := make(chan error, 1)
+	go func() { done <- ReadDatagrams(conn, stop, sweep, func(d Datagram) { got <- d }) }()
+
+	var out []Datagram
+	for {
+		select {
+		case d := <-got:
+			out = append(out, d)
+			continue
+		case <-time.After(wait):
+		}
+		break
+	}
+	close(stop)
+	if err := <-done; err != nil {
+		t.Errorf("ReadDatagrams: %v", err)
+	}
+	return out
+}
+
+func loopbackTo(conn net.PacketConn) *net.UDPAddr {
+	return &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: LocalPort(conn)}
+}
+
+func TestConnectedModeDropsOtherSenders(t *testing.T) {
+	peer := listenLoopback(t)
+	stranger := listenLoopback(t)
+
+	sock, err := OpenUDP("", peer.LocalAddr().(*net.UDPAddr), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sock.Close()
+	if !sock.Dialed || len(sock.Conns) != 1 {
+		t.Fatalf("Socket = %+v", sock)
+	}
+
+	local := loopbackTo(sock.Conns[0])
+	stranger.WriteTo([]byte("from stranger"), local)
+	peer.WriteTo([]byte("from peer"), local)
+
+	got := readAll(t, sock.Conns[0], false, 200*time.Millisecond)
+	if len(got) != 1 || string(got[0].Data) != "from peer" {
+		t.Fatalf("Expected only the peer's datagram, got %+v", got)
+	}
+	if got[0].Source.String() != peer.LocalAddr().String() {
+		t.Errorf("Source = %v, expected %v", got[0].Source, peer.LocalAddr())
+	}
+	if got[0].LocalPort != 0 {
+		t.Errorf("LocalPort = %d without a port sweep", got[0].LocalPort)
+	}
+}
+
+func TestListenModeTracksFirstSender(t *testing.T) {
+	first := listenLoopback(t)
+	second := listenLoopback(t)
+
+	sock, err := OpenUDP("", nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sock.Close()
+	if sock.Dialed {
+		t.Fatal("Listen mode must not dial")
+	}
+
+	local := loopbackTo(sock.Conns[0])
+	first.WriteTo([]byte("one"), local)
+	time.Sleep(20 * time.Millisecond)
+	second.WriteTo([]byte("two"), local)
+
+	// 每个数据报都附带来源；回复地址只取第一个发送者
+	var peer UDPPeer
+	got := readAll(t, sock.Conns[0], true, 200*time.Millisecond)
+	if len(got) != 2 {
+		t.Fatalf("Expected 2 datagrams, got %+v", got)
+	}
+	if got[0].Source.String() != first.LocalAddr().String() || got[1].Source.String() != second.LocalAddr().String() {
+		t.Errorf("Sources = %v, %v", got[0].Source, got[1].Source)
+	}
+	if got[0].LocalPort != local.Port {
+		t.Errorf("LocalPort = %d, expected %d in sweep mode", got[0].LocalPort, local.Port)
+	}
+	if peer.Ready() {
+		t.Error("Peer should not be ready before any datagram")
+	}
+	for _, d := range got {
+		peer.Observe(sock.Conns[0], d.Source)
+	}
+	if !peer.Ready() || peer.Remote.String() != first.LocalAddr().String() || peer.Reply != sock.Conns[0] {
+		t.Errorf("Peer = %+v, expected the first sender", peer)
+	}
+}
+
+func TestUDPPeerFixedRemote(t *testing.T) {
+	remote := &net.UDPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 5000}
+	other := &net.UDPAddr{IP: net.IPv4(10, 0, 0, 2), Port: 5000}
+
+	p := UDPPeer{Remote: remote}
+	if p.Observe(nil, other) || p.Remote != remote {
+		t.Error("A configured remote must not be replaced")
+	}
+	p = UDPPeer{Dialed: true}
+	if p.Observe(nil, other) || p.Remote != nil || !p.Ready() {
+		t.Errorf("Connected mode must not track senders: %+v", p)
+	}
+}
+
+func TestOpenUDPErrors(t *testing.T) {
+	remote := &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 9}
+	if _, err := OpenUDP("", nil, true); err == nil {
+		t.Error("Connected mode without a remote should fail")
+	}
+	if _, err := OpenUDP("5000-5001", remote, true); err == nil {
+		t.Error("Connected mode with a port sweep should fail")
+	}
+	if _, err := OpenUDP("x", nil, false); err == nil {
+		t.Error("Invalid port should fail")
+	}
+}
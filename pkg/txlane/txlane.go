@@ -0,0 +1,164 @@
+// Package txlane 为发送路径提供两级优先级：交互发送 (SendData、快捷指令、宏、控制字符) 与
+// 批量发送 (文件发送、定时发送)
+//
+// 发送方仍各自持有连接锁写入；Gate 只决定批量发送何时可以开始下一帧：有交互发送在等待时，
+// 批量发送在帧边界让出，交互发送在当前的批量帧写完后立即执行。为避免批量发送饿死，
+// 批量帧连续让出 Burst 次交互发送或等待超过 MaxDelay 后强制发出一帧。
+// 批量发送按申请顺序逐帧执行 (同一时刻只有一个批量帧在写入)；交互发送之间的顺序由连接锁决定。
+package txlane
+
+import (
+	"sync"
+	"time"
+)
+
+// Lane 发送优先级
+type Lane string
+
+const (
+	Interactive Lane = "interactive"
+	Bulk        Lane = "bulk"
+)
+
+const (
+	// DefaultBurst Burst 为 0 时批量帧最多连续让出的交互发送数
+	DefaultBurst = 8
+	// DefaultMaxDelay MaxDelay 为 0 时批量帧最长的等待时间
+	DefaultMaxDelay = 500 * time.Millisecond
+)
+
+// Config 饥饿保护设置
+type Config struct {
+	Burst    int
+	MaxDelay time.Duration
+}
+
+// Depth 两个优先级的排队情况
+type Depth struct {
+	Interactive int `json:"interactive"` // 正在等待或写入的交互发送数
+	Bulk        int `json:"bulk"`        // 正在等待或写入的批量帧数
+	// Yielded 批量帧因交互发送而推迟的次数，Forced 其中由饥饿保护强制发出的次数
+	Yielded uint64 `json:"yielded"`
+	Forced  uint64 `json:"forced"`
+}
+
+// Gate 发送优先级的调度，线程安全
+type Gate struct {
+	cfg Config
+
+	mu          sync.Mutex
+	interactive int
+	bulk        []chan struct{} // 批量帧的排队顺序，队首为正在等待或写入的帧
+	served      int             // 队首批量帧等待期间完成的交互发送数
+	changed     chan struct{}   // 状态变化时关闭并替换
+	yielded     uint64
+	forced      uint64
+}
+
+// New 创建调度器
+func New(cfg Config) *Gate {
+	if cfg.Burst <= 0 {
+		cfg.Burst = DefaultBurst
+	}
+	if cfg.MaxDelay <= 0 {
+		cfg.MaxDelay = DefaultMaxDelay
+	}
+	return &Gate{cfg: cfg, changed: make(chan struct{})}
+}
+
+// Interactive 登记一次交互发送，返回发送结束时调用的函数 (可重复调用)。
+// 应在获取连接锁之前调用，使正在等待的批量帧让出；可以嵌套
+func (g *Gate) Interactive() (done func()) {
+	g.mu.Lock()
+	g.interactive++
+	g.notifyLocked()
+	g.mu.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			g.mu.Lock()
+			g.interactive--
+			g.served++
+			g.notifyLocked()
+			g.mu.Unlock()
+		})
+	}
+}
+
+// Bulk 等待发送下一个批量帧的时机，返回帧写入后调用的函数 (可重复调用)；
+// stop 关闭时放弃等待并返回 ok 为 false。调用方不得持有连接锁
+func (g *Gate) Bulk(stop <-chan struct{}) (done func(), ok bool) {
+	ticket := make(chan struct{})
+	g.mu.Lock()
+	g.bulk = append(g.bulk, ticket)
+	if len(g.bulk) == 1 {
+		g.served = 0
+	}
+	g.mu.Unlock()
+
+	var once sync.Once
+	done = func() {
+		once.Do(func() {
+			g.mu.Lock()
+			for i, t := range g.bulk {
+				if t == ticket {
+					g.bulk = append(g.bulk[:i], g.bulk[i+1:]...)
+					break
+				}
+			}
+			g.served = 0
+			g.notifyLocked()
+			g.mu.Unlock()
+		})
+	}
+
+	var deadline <-chan time.Time
+	yielded := false
+	for {
+		g.mu.Lock()
+		head := g.bulk[0] == ticket
+		switch {
+		case head && g.interactive == 0:
+			g.mu.Unlock()
+			return done, true
+		case head && g.served >= g.cfg.Burst:
+			g.forced++
+			g.mu.Unlock()
+			return done, true
+		case head && !yielded:
+			// 开始为交互发送让出，计时饥饿保护
+			yielded = true
+			g.yielded++
+			timer := time.NewTimer(g.cfg.MaxDelay)
+			defer timer.Stop()
+			deadline = timer.C
+		}
+		changed := g.changed
+		g.mu.Unlock()
+
+		select {
+		case <-changed:
+		case <-deadline:
+			g.mu.Lock()
+			g.forced++
+			g.mu.Unlock()
+			return done, true
+		case <-stop:
+			done()
+			return nil, false
+		}
+	}
+}
+
+// Depth 返回两个优先级的排队情况
+func (g *Gate) Depth() Depth {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return Depth{Interactive: g.interactive, Bulk: len(g.bulk), Yielded: g.yielded, Forced: g.forced}
+}
+
+func (g *Gate) notifyLocked() {
+	close(g.changed)
+	g.changed = make(chan struct{})
+}
@@ -0,0 +1,237 @@
+package txlane
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// slowWriter 模拟连接：写入持有连接锁并耗时 delay，按顺序记录写入的帧
+type slowWriter struct {
+	conn  sync.Mutex // 相当于 App.mutex
+	delay time.Duration
+
+	mu     sync.Mutex
+	frames []string
+}
+
+func (w *slowWriter) write(frame string) {
+	w.conn.Lock()
+	defer w.conn.Unlock()
+	time.Sleep(w.delay)
+	w.mu.Lock()
+	w.frames = append(w.frames, frame)
+	w.mu.Unlock()
+}
+
+func (w *slowWriter) written() []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return append([]string(nil), w.frames...)
+}
+
+// sendBulk 按批量优先级逐帧发送
+func sendBulk(g *Gate, w *slowWriter, prefix string, n int, stop <-chan struct{}) int {
+	for i := 0; i < n; i++ {
+		done, ok := g.Bulk(stop)
+		if !ok {
+			return i
+		}
+		w.write(fmt.Sprintf("%s%d", prefix, i))
+		done()
+	}
+	return n
+}
+
+func sendInteractive(g *Gate, w *slowWriter, frame string) {
+	done := g.Interactive()
+	defer done()
+	w.write(frame)
+}
+
+func indexOf(frames []string, frame string) int {
+	for i, f := range frames {
+		if f == frame {
+			return i
+		}
+	}
+	return -1
+}
+
+func TestInteractiveJumpsAheadOfBulk(t *testing.T) {
+	g := New(Config{})
+	w := &slowWriter{delay: 5 * time.Millisecond}
+
+	finished := make(chan struct{})
+	go func() {
+		sendBulk(g, w, "chunk", 40, nil)
+		close(finished)
+	}()
+
+	// 传输进行中按下 "abort"
+	for len(w.written()) < 5 {
+		time.Sleep(time.Millisecond)
+	}
+	before := len(w.written())
+	sendInteractive(g, w, "abort")
+	<-finished
+
+	frames := w.written()
+	pos := indexOf(frames, "abort")
+	// 最多等待发送 abort 时正在写入的那一个批量帧
+	if pos < 0 || pos > before+1 {
+		t.Fatalf("abort written at position %d, expected at most %d: %v", pos, before+1, frames)
+	}
+	// 批量帧的顺序不变且没有丢失
+	n := 0
+	for _, f := range frames {
+		if f == "abort" {
+			continue
+		}
+		if f != fmt.Sprintf("chunk%d", n) {
+			t.Fatalf("bulk order broken at %q, expected chunk%d", f, n)
+		}
+		n++
+	}
+	if n != 40 {
+		t.Errorf("Expected 40 bulk frames, got %d", n)
+	}
+	if d := g.Depth(); d.Interactive != 0 || d.Bulk != 0 {
+		t.Errorf("Depth after completion = %+v", d)
+	}
+}
+
+func TestInteractiveOrderPreserved(t *testing.T) {
+	g := New(Config{})
+	w := &slowWriter{delay: 2 * time.Millisecond}
+	go sendBulk(g, w, "chunk", 20, nil)
+	for len(w.written()) < 2 {
+		time.Sleep(time.Millisecond)
+	}
+	// 同一发送方的交互帧保持顺序
+	for i := 0; i < 5; i++ {
+		sendInteractive(g, w, fmt.Sprintf("key%d", i))
+	}
+	frames := w.written()
+	last := -1
+	for i := 0; i < 5; i++ {
+		pos := indexOf(frames, fmt.Sprintf("key%d", i))
+		if pos <= last {
+			t.Fatalf("interactive order broken: %v", frames)
+		}
+		last = pos
+	}
+}
+
+func TestBulkSendersFIFO(t *testing.T) {
+	g := New(Config{})
+	w := &slowWriter{delay: time.Millisecond}
+
+	// 持有一个交互发送，使批量帧全部排队
+	release := g.Interactive()
+	var wg sync.WaitGroup
+	for i, name := range []string{"a", "b", "c"} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			done, _ := g.Bulk(nil)
+			w.write(name)
+			done()
+		}()
+		// 确保按顺序排队
+		for g.Depth().Bulk < i+1 {
+			time.Sleep(time.Millisecond)
+		}
+	}
+	if d := g.Depth(); d.Bulk != 3 || d.Interactive != 1 {
+		t.Fatalf("Depth = %+v", d)
+	}
+	release()
+	wg.Wait()
+	if got := fmt.Sprint(w.written()); got != "[a b c]" {
+		t.Errorf("Bulk frames written as %s, expected [a b c]", got)
+	}
+}
+
+func TestStarvationGuard(t *testing.T) {
+	t.Run("burst", func(t *testing.T) {
+		g := New(Config{Burst: 3, MaxDelay: time.Hour})
+		w := &slowWriter{delay: time.Millisecond}
+
+		// 交互发送连续不断，批量发送仍应前进
+		stopFlood := make(chan struct{})
+		var wg sync.WaitGroup
+		for i := 0; i < 2; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for {
+					select {
+					case <-stopFlood:
+						return
+					default:
+					}
+					sendInteractive(g, w, "i")
+				}
+			}()
+		}
+		sent := make(chan int)
+		go func() { sent <- sendBulk(g, w, "b", 5, nil) }()
+		select {
+		case n := <-sent:
+			if n != 5 {
+				t.Errorf("Expected 5 bulk frames, sent %d", n)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("bulk transfer starved")
+		}
+		close(stopFlood)
+		wg.Wait()
+		if d := g.Depth(); d.Forced == 0 {
+			t.Errorf("Expected forced bulk frames, depth %+v", d)
+		}
+	})
+
+	t.Run("max delay", func(t *testing.T) {
+		g := New(Config{Burst: 1000, MaxDelay: 20 * time.Millisecond})
+		// 一个长时间的交互发送 (例如分块粘贴) 不应无限期阻塞批量发送
+		release := g.Interactive()
+		defer release()
+		start := time.Now()
+		done, ok := g.Bulk(nil)
+		if !ok {
+			t.Fatal("Bulk failed")
+		}
+		done()
+		if elapsed := time.Since(start); elapsed < 20*time.Millisecond || elapsed > time.Second {
+			t.Errorf("Bulk waited %v, expected about 20ms", elapsed)
+		}
+		if d := g.Depth(); d.Forced != 1 || d.Yielded != 1 {
+			t.Errorf("Depth = %+v", d)
+		}
+	})
+}
+
+func TestBulkStop(t *testing.T) {
+	g := New(Config{MaxDelay: time.Hour})
+	release := g.Interactive()
+	defer release()
+
+	stop := make(chan struct{})
+	result := make(chan bool)
+	go func() {
+		_, ok := g.Bulk(stop)
+		result <- ok
+	}()
+	for g.Depth().Bulk != 1 {
+		time.Sleep(time.Millisecond)
+	}
+	close(stop)
+	if <-result {
+		t.Error("Bulk should fail when stopped")
+	}
+	if d := g.Depth(); d.Bulk != 0 {
+		t.Errorf("Stopped bulk frame must leave the queue, depth %+v", d)
+	}
+}
@@ -0,0 +1,119 @@
+package updater
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	// MaxReleaseNotes caps the aggregated release notes shown in the update dialog
+	MaxReleaseNotes = 16 * 1024
+	// releasesPerPage is the page size requested from the releases API (GitHub maximum)
+	releasesPerPage = 100
+	// maxReleasePages bounds pagination in case the Link header never ends
+	maxReleasePages = 20
+)
+
+// linkNext extracts the rel="next" URL from a GitHub Link header
+var linkNext = regexp.MustCompile(`<([^>]+)>;\s*rel="next"`)
+
+// ChangelogURL returns the public page listing all releases
+func ChangelogURL() string {
+	return fmt.Sprintf("https://github.com/%s/releases", GitHubRepo)
+}
+
+// fetchReleases downloads the full release list, following Link pagination
+func fetchReleases(client *http.Client) ([]Release, error) {
+	url := fmt.Sprintf("%s/repos/%s/releases?per_page=%d", apiBaseURL, GitHubRepo, releasesPerPage)
+
+	var all []Release
+	for page := 0; url != "" && page < maxReleasePages; page++ {
+		req, err := http.NewRequest("GET", url, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+		req.Header.Set("User-Agent", "serial-mate-updater")
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch releases: %w", err)
+		}
+
+		var releases []Release
+		if resp.StatusCode != http.StatusOK {
+			err = fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		} else if derr := json.NewDecoder(resp.Body).Decode(&releases); derr != nil {
+			err = fmt.Errorf("failed to decode releases: %w", derr)
+		}
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		all = append(all, releases...)
+		url = ""
+		if m := linkNext.FindStringSubmatch(resp.Header.Get("Link")); m != nil {
+			url = m[1]
+		}
+	}
+	return all, nil
+}
+
+// AggregateReleaseNotes concatenates the notes of all published releases newer than
+// currentVersion, newest first. Drafts and pre-releases are skipped. The result is
+// capped at MaxReleaseNotes, dropping the oldest releases first, and ends with a link
+// to the full changelog when anything was left out.
+func AggregateReleaseNotes(releases []Release, currentVersion string) string {
+	var newer []Release
+	for _, r := range releases {
+		if r.Draft || r.Prerelease || compareVersions(r.TagName, currentVersion) <= 0 {
+			continue
+		}
+		newer = append(newer, r)
+	}
+	sort.SliceStable(newer, func(i, j int) bool {
+		return compareVersions(newer[i].TagName, newer[j].TagName) > 0
+	})
+
+	const separator = "\n\n---\n\n"
+	footer := fmt.Sprintf("%s_Notes truncated. See the full changelog: %s_", separator, ChangelogURL())
+
+	var sb strings.Builder
+	for i, r := range newer {
+		section := formatReleaseSection(r)
+		if i > 0 {
+			section = separator + section
+		}
+		if sb.Len()+len(section) > MaxReleaseNotes-len(footer) {
+			if i == 0 {
+				// Even the newest release alone is too long: cut it on a rune boundary
+				sb.WriteString(strings.ToValidUTF8(section[:MaxReleaseNotes-len(footer)], ""))
+			}
+			sb.WriteString(footer)
+			break
+		}
+		sb.WriteString(section)
+	}
+	return sb.String()
+}
+
+// formatReleaseSection renders one release as a heading line followed by its body
+func formatReleaseSection(r Release) string {
+	heading := "## " + r.TagName
+	if r.Name != "" && r.Name != r.TagName {
+		heading += " - " + r.Name
+	}
+	if !r.PublishedAt.IsZero() {
+		heading += " (" + r.PublishedAt.Format("2006-01-02") + ")"
+	}
+
+	body := strings.TrimSpace(r.Body)
+	if body == "" {
+		body = "_No release notes._"
+	}
+	return heading + "\n\n" + body
+}
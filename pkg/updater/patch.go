@@ -0,0 +1,350 @@
+package updater
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// Patch file layout (little endian):
+//
+//	offset  size  content
+//	0       8     PatchMagic
+//	8       8     source size
+//	16      32    source SHA-256
+//	48      8     target size
+//	56      32    target SHA-256
+//	88      ...   deflate stream of operations
+//
+// Each operation starts with a byte: opCopy is followed by a uvarint source offset and a uvarint
+// length, opInsert by a uvarint length and that many literal bytes; opEnd terminates the stream.
+const PatchMagic = "SMPATCH1"
+
+const (
+	patchHeaderLen = 8 + 8 + sha256.Size + 8 + sha256.Size
+
+	opEnd    = 0
+	opCopy   = 1
+	opInsert = 2
+
+	// patchBlock is the match granularity of MakePatch
+	patchBlock = 32
+	// maxPatchTarget bounds the size a patch may claim to produce
+	maxPatchTarget = 1 << 30
+)
+
+// Patch failures; ApplyPatch wraps them with details
+var (
+	// ErrPatchSource the running executable is not the binary the patch was made from
+	ErrPatchSource = errors.New("current executable does not match the patch source")
+	// ErrCorruptPatch the patch file is malformed or produced a result that does not match its own header
+	ErrCorruptPatch = errors.New("patch file is corrupt")
+	// ErrChecksumMismatch the rebuilt binary does not match the release checksum
+	ErrChecksumMismatch = errors.New("patched file does not match the release checksum")
+)
+
+// MakePatch builds a patch that turns source into target. It is used by the release tooling;
+// matching is block based, so unchanged regions cost a few bytes and changed regions are stored literally
+func MakePatch(source, target []byte) []byte {
+	srcSum, dstSum := sha256.Sum256(source), sha256.Sum256(target)
+	out := []byte(PatchMagic)
+	out = binary.LittleEndian.AppendUint64(out, uint64(len(source)))
+	out = append(out, srcSum[:]...)
+	out = binary.LittleEndian.AppendUint64(out, uint64(len(target)))
+	out = append(out, dstSum[:]...)
+
+	index := make(map[string]int, len(source)/patchBlock)
+	for i := 0; i+patchBlock <= len(source); i += patchBlock {
+		if _, ok := index[string(source[i:i+patchBlock])]; !ok {
+			index[string(source[i:i+patchBlock])] = i
+		}
+	}
+
+	var ops bytes.Buffer
+	var literal []byte
+	flush := func() {
+		if len(literal) > 0 {
+			ops.WriteByte(opInsert)
+			ops.Write(binary.AppendUvarint(nil, uint64(len(literal))))
+			ops.Write(literal)
+			literal = literal[:0]
+		}
+	}
+	for i := 0; i < len(target); {
+		if i+patchBlock <= len(target) {
+			if off, ok := index[string(target[i:i+patchBlock])]; ok {
+				n := patchBlock
+				for off+n < len(source) && i+n < len(target) && source[off+n] == target[i+n] {
+					n++
+				}
+				flush()
+				ops.WriteByte(opCopy)
+				ops.Write(binary.AppendUvarint(nil, uint64(off)))
+				ops.Write(binary.AppendUvarint(nil, uint64(n)))
+				i += n
+				continue
+			}
+		}
+		literal = append(literal, target[i])
+		i++
+	}
+	flush()
+	ops.WriteByte(opEnd)
+
+	buf := bytes.NewBuffer(out)
+	zw, _ := flate.NewWriter(buf, flate.BestCompression)
+	zw.Write(ops.Bytes())
+	zw.Close()
+	return buf.Bytes()
+}
+
+// patchHeader is the fixed part of a patch file
+type patchHeader struct {
+	srcSize, dstSize uint64
+	srcSum, dstSum   [sha256.Size]byte
+}
+
+func parsePatchHeader(patch []byte) (patchHeader, error) {
+	var h patchHeader
+	if len(patch) < patchHeaderLen || !bytes.HasPrefix(patch, []byte(PatchMagic)) {
+		return h, fmt.Errorf("%w: missing %s header", ErrCorruptPatch, PatchMagic)
+	}
+	p := patch[len(PatchMagic):]
+	h.srcSize = binary.LittleEndian.Uint64(p)
+	copy(h.srcSum[:], p[8:])
+	h.dstSize = binary.LittleEndian.Uint64(p[8+sha256.Size:])
+	copy(h.dstSum[:], p[16+sha256.Size:])
+	if h.dstSize > maxPatchTarget {
+		return h, fmt.Errorf("%w: target size %d is too large", ErrCorruptPatch, h.dstSize)
+	}
+	return h, nil
+}
+
+// applyPatch rebuilds the target from source; the result is checked against the patch header
+func applyPatch(source, patch []byte) ([]byte, error) {
+	h, err := parsePatchHeader(patch)
+	if err != nil {
+		return nil, err
+	}
+	if uint64(len(source)) != h.srcSize || sha256.Sum256(source) != h.srcSum {
+		return nil, fmt.Errorf("%w (patch expects %d bytes with SHA-256 %s…)", ErrPatchSource, h.srcSize, hex.EncodeToString(h.srcSum[:4]))
+	}
+
+	r := bufio.NewReader(flate.NewReader(bytes.NewReader(patch[patchHeaderLen:])))
+	out := make([]byte, 0, h.dstSize)
+	corrupt := func(format string, args ...interface{}) ([]byte, error) {
+		return nil, fmt.Errorf("%w: %s", ErrCorruptPatch, fmt.Sprintf(format, args...))
+	}
+	for {
+		op, err := r.ReadByte()
+		if err != nil {
+			return corrupt("truncated operation stream: %v", err)
+		}
+		switch op {
+		case opEnd:
+			if uint64(len(out)) != h.dstSize || sha256.Sum256(out) != h.dstSum {
+				return corrupt("result does not match the patch header")
+			}
+			return out, nil
+		case opCopy:
+			off, err1 := binary.ReadUvarint(r)
+			n, err2 := binary.ReadUvarint(r)
+			if err1 != nil || err2 != nil {
+				return corrupt("truncated copy operation")
+			}
+			if off > uint64(len(source)) || n > uint64(len(source))-off || n > h.dstSize-uint64(len(out)) {
+				return corrupt("copy of %d bytes at %d is out of range", n, off)
+			}
+			out = append(out, source[off:off+n]...)
+		case opInsert:
+			n, err := binary.ReadUvarint(r)
+			if err != nil {
+				return corrupt("truncated insert operation")
+			}
+			if n > h.dstSize-uint64(len(out)) {
+				return corrupt("insert of %d bytes overruns the target", n)
+			}
+			start := len(out)
+			out = append(out, make([]byte, n)...)
+			if _, err := io.ReadFull(r, out[start:]); err != nil {
+				return corrupt("truncated insert data")
+			}
+		default:
+			return corrupt("unknown operation %d", op)
+		}
+	}
+}
+
+// ApplyPatch rebuilds the full update from the running executable and a downloaded patch,
+// writing it next to the patch under the full asset name. The result must match checksum
+// (hex SHA-256 of the full asset, UpdateInfo.SHA256) before it is handed to InstallUpdate.
+// Errors wrap ErrPatchSource, ErrCorruptPatch or ErrChecksumMismatch; callers fall back to the full download
+func ApplyPatch(patchFile, checksum string) (string, error) {
+	exePath, err := executablePath()
+	if err != nil {
+		return "", err
+	}
+	outFile := filepath.Join(filepath.Dir(patchFile), getAssetName())
+	if err := applyPatchFile(exePath, patchFile, checksum, outFile); err != nil {
+		return "", err
+	}
+	return outFile, nil
+}
+
+// applyPatchFile applies patchFile to sourcePath and writes the verified result to outFile
+func applyPatchFile(sourcePath, patchFile, checksum, outFile string) error {
+	want, err := hex.DecodeString(strings.TrimSpace(checksum))
+	if err != nil || len(want) != sha256.Size {
+		return fmt.Errorf("invalid release checksum %q", checksum)
+	}
+	source, err := os.ReadFile(sourcePath)
+	if err != nil {
+		return fmt.Errorf("failed to read current executable: %w", err)
+	}
+	patch, err := os.ReadFile(patchFile)
+	if err != nil {
+		return fmt.Errorf("failed to read patch: %w", err)
+	}
+	target, err := applyPatch(source, patch)
+	if err != nil {
+		return err
+	}
+	if got := sha256.Sum256(target); !bytes.Equal(got[:], want) {
+		return fmt.Errorf("%w: got %s, want %s", ErrChecksumMismatch, hex.EncodeToString(got[:]), hex.EncodeToString(want))
+	}
+	if err := os.WriteFile(outFile, target, 0o644); err != nil {
+		os.Remove(outFile)
+		return fmt.Errorf("failed to write patched file: %w", err)
+	}
+	return nil
+}
+
+// patchAssetName returns the name of the patch asset from currentVersion for this platform,
+// e.g. serial-mate-windows-amd64-from-v1.2.3.patch; empty where the asset is not a plain executable
+func patchAssetName(currentVersion string) string {
+	if runtime.GOOS == "darwin" {
+		return ""
+	}
+	base := strings.TrimSuffix(getAssetName(), ".exe")
+	if base == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s-from-v%s.patch", base, strings.TrimPrefix(currentVersion, "v"))
+}
+
+// checksumAssetName returns the name of the asset holding the SHA-256 of the full asset
+func checksumAssetName() string {
+	return getAssetName() + ".sha256"
+}
+
+// parseChecksum reads the first field of a sha256sum style file
+func parseChecksum(data []byte) (string, error) {
+	fields := strings.Fields(string(data))
+	if len(fields) == 0 {
+		return "", errors.New("empty checksum file")
+	}
+	sum := strings.ToLower(fields[0])
+	if b, err := hex.DecodeString(sum); err != nil || len(b) != sha256.Size {
+		return "", fmt.Errorf("invalid checksum %q", fields[0])
+	}
+	return sum, nil
+}
+
+// FetchUpdate downloads the update described by info and returns the path of the full asset.
+// When info offers a patch it is downloaded and applied first; any patch failure is passed to
+// onFallback (may be nil) and the full asset is downloaded instead
+func FetchUpdate(info UpdateInfo, progressCallback func(downloaded, total int64), onFallback func(error)) (string, error) {
+	exePath, err := executablePath()
+	if err != nil {
+		// Without the running executable only the full download is possible
+		info.PatchURL = ""
+	}
+	return fetchUpdate(info, exePath, os.TempDir(), progressCallback, onFallback)
+}
+
+// fetchUpdate is FetchUpdate with the executable and download directory given
+func fetchUpdate(info UpdateInfo, exePath, dir string, progressCallback func(downloaded, total int64), onFallback func(error)) (string, error) {
+	if info.PatchURL != "" {
+		path, err := fetchPatched(info, exePath, dir, progressCallback)
+		if err == nil {
+			return path, nil
+		}
+		if onFallback != nil {
+			onFallback(err)
+		}
+	}
+	return DownloadUpdateTo(info.DownloadURL, dir, info.AssetSize, progressCallback)
+}
+
+// fetchPatched downloads the patch into dir and applies it to exePath
+func fetchPatched(info UpdateInfo, exePath, dir string, progressCallback func(downloaded, total int64)) (string, error) {
+	patchFile, err := DownloadUpdateTo(info.PatchURL, dir, info.PatchSize, progressCallback)
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(patchFile)
+	outFile := filepath.Join(dir, getAssetName())
+	if err := applyPatchFile(exePath, patchFile, info.SHA256, outFile); err != nil {
+		return "", err
+	}
+	return outFile, nil
+}
+
+// findPatch fills the patch fields of info from release; a missing patch or checksum leaves them empty
+func findPatch(client *http.Client, release *Release, info *UpdateInfo) {
+	patchName, sumName := patchAssetName(info.CurrentVersion), checksumAssetName()
+	if patchName == "" {
+		return
+	}
+	var patchURL, sumURL string
+	var patchSize int64
+	for _, asset := range release.Assets {
+		switch asset.Name {
+		case patchName:
+			patchURL, patchSize = asset.BrowserDownloadURL, asset.Size
+		case sumName:
+			sumURL = asset.BrowserDownloadURL
+		}
+	}
+	if patchURL == "" || sumURL == "" {
+		return
+	}
+	sum, err := fetchChecksum(client, sumURL)
+	if err != nil {
+		return
+	}
+	info.PatchURL, info.PatchSize, info.SHA256 = patchURL, patchSize, sum
+}
+
+// fetchChecksum downloads and parses a checksum asset
+func fetchChecksum(client *http.Client, url string) (string, error) {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("User-Agent", "serial-mate-updater")
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if err != nil {
+		return "", err
+	}
+	return parseChecksum(data)
+}
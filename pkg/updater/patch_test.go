@@ -0,0 +1,325 @@
+package updater
+
+import (
+	"bytes"
+	"compress/flate"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testBinaries returns an old and a new "executable" sharing most of their content,
+// with inserted, removed and modified regions like a rebuilt binary
+func testBinaries(size int) (old, new []byte) {
+	rng := rand.New(rand.NewSource(1))
+	old = make([]byte, size)
+	rng.Read(old)
+	copy(old, "\x7fELF")
+
+	new = append([]byte(nil), old[:size/4]...)
+	new = append(new, []byte("inserted section of the new release")...)
+	new = append(new, old[size/4+100:size/2]...)
+	for i := 0; i < 64; i++ {
+		new = append(new, byte(rng.Intn(256)))
+	}
+	new = append(new, old[size/2:]...)
+	new[size/3] ^= 0xFF
+	return old, new
+}
+
+func sumHex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func TestMakeAndApplyPatch(t *testing.T) {
+	old, new := testBinaries(200 << 10)
+	tests := []struct {
+		name        string
+		source      []byte
+		target      []byte
+		maxPatchLen int
+	}{
+		{"rebuilt binary", old, new, 4 << 10},
+		{"identical", old, old, 1 << 10},
+		{"empty source", nil, []byte("hello"), 1 << 10},
+		{"empty target", old, nil, 1 << 10},
+		{"unrelated", []byte("aaaa"), []byte("bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb"), 1 << 10},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			patch := MakePatch(tt.source, tt.target)
+			if len(patch) > tt.maxPatchLen {
+				t.Errorf("patch is %d bytes, expected at most %d", len(patch), tt.maxPatchLen)
+			}
+			got, err := applyPatch(tt.source, patch)
+			if err != nil {
+				t.Fatalf("applyPatch: %v", err)
+			}
+			if !bytes.Equal(got, tt.target) {
+				t.Error("patched result differs from the target")
+			}
+		})
+	}
+}
+
+func TestApplyPatchWrongSource(t *testing.T) {
+	old, new := testBinaries(64 << 10)
+	patch := MakePatch(old, new)
+
+	// Same size with one byte changed (e.g. a locally modified build), and a different version altogether
+	modified := append([]byte(nil), old...)
+	modified[1000] ^= 1
+	other, _ := testBinaries(50 << 10)
+	for name, source := range map[string][]byte{"modified": modified, "other version": other, "empty": nil} {
+		if _, err := applyPatch(source, patch); !errors.Is(err, ErrPatchSource) {
+			t.Errorf("%s: expected ErrPatchSource, got %v", name, err)
+		}
+	}
+}
+
+func TestApplyPatchCorrupt(t *testing.T) {
+	old, new := testBinaries(64 << 10)
+	patch := MakePatch(old, new)
+
+	// Operation streams built by hand, behind a valid header for old and new
+	header := patch[:patchHeaderLen]
+	withOps := func(ops ...[]byte) []byte {
+		var buf bytes.Buffer
+		for _, op := range ops {
+			buf.Write(op)
+		}
+		return append(append([]byte(nil), header...), deflate(buf.Bytes())...)
+	}
+	uv := func(v uint64) []byte { return binary.AppendUvarint(nil, v) }
+
+	flipped := append([]byte(nil), patch...)
+	flipped[len(flipped)-10] ^= 0x55
+	badMagic := append([]byte(nil), patch...)
+	copy(badMagic, "SMPATCH0")
+	huge := append([]byte(nil), patch...)
+	binary.LittleEndian.PutUint64(huge[8+8+sha256.Size:], 1<<40)
+
+	tests := map[string][]byte{
+		"empty":                nil,
+		"bad magic":            badMagic,
+		"header only":          header,
+		"truncated":            patch[:len(patch)-20],
+		"bit flip":             flipped,
+		"huge target":          huge,
+		"no end":               withOps([]byte{opCopy}, uv(0), uv(10)),
+		"copy past source":     withOps([]byte{opCopy}, uv(uint64(len(old))-5), uv(10), []byte{opEnd}),
+		"copy offset overflow": withOps([]byte{opCopy}, uv(1<<63), uv(1<<63), []byte{opEnd}),
+		"insert overruns":      withOps([]byte{opInsert}, uv(uint64(len(new))+1), []byte{opEnd}),
+		"insert truncated":     withOps([]byte{opInsert}, uv(100), []byte("short")),
+		"unknown op":           withOps([]byte{9, opEnd}),
+		"short result":         withOps([]byte{opCopy}, uv(0), uv(10), []byte{opEnd}),
+		"wrong content":        withOps([]byte{opCopy}, uv(0), uv(uint64(len(new))), []byte{opEnd}),
+	}
+	for name, p := range tests {
+		if _, err := applyPatch(old, p); !errors.Is(err, ErrCorruptPatch) {
+			t.Errorf("%s: expected ErrCorruptPatch, got %v", name, err)
+		}
+	}
+}
+
+func deflate(data []byte) []byte {
+	var buf bytes.Buffer
+	zw, _ := flate.NewWriter(&buf, flate.DefaultCompression)
+	zw.Write(data)
+	zw.Close()
+	return buf.Bytes()
+}
+
+func TestApplyPatchFile(t *testing.T) {
+	dir := t.TempDir()
+	old, new := testBinaries(64 << 10)
+	exe := filepath.Join(dir, "serial-mate")
+	patchFile := filepath.Join(dir, "update.patch")
+	os.WriteFile(exe, old, 0o755)
+	os.WriteFile(patchFile, MakePatch(old, new), 0o644)
+
+	out := filepath.Join(dir, "out")
+	if err := applyPatchFile(exe, patchFile, strings.ToUpper(sumHex(new)), out); err != nil {
+		t.Fatalf("applyPatchFile: %v", err)
+	}
+	if data, _ := os.ReadFile(out); !bytes.Equal(data, new) {
+		t.Error("written file differs from the target")
+	}
+
+	// A valid patch whose result is not the published asset
+	os.Remove(out)
+	if err := applyPatchFile(exe, patchFile, sumHex(old), out); !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("Expected ErrChecksumMismatch, got %v", err)
+	}
+	if _, err := os.Stat(out); !os.IsNotExist(err) {
+		t.Error("Output file must not be written when the checksum does not match")
+	}
+	if err := applyPatchFile(exe, patchFile, "not-a-checksum", out); err == nil {
+		t.Error("Expected an error for an invalid checksum")
+	}
+
+	// The running executable is not the patch source
+	os.WriteFile(exe, new, 0o755)
+	if err := applyPatchFile(exe, patchFile, sumHex(new), out); !errors.Is(err, ErrPatchSource) {
+		t.Errorf("Expected ErrPatchSource, got %v", err)
+	}
+}
+
+func TestParseChecksum(t *testing.T) {
+	sum := sumHex([]byte("x"))
+	for _, in := range []string{sum, sum + "\n", strings.ToUpper(sum) + "  serial-mate-linux-amd64\n"} {
+		if got, err := parseChecksum([]byte(in)); err != nil || got != sum {
+			t.Errorf("parseChecksum(%q) = %q, %v", in, got, err)
+		}
+	}
+	for _, in := range []string{"", "abc", "<html>", sum[:60]} {
+		if _, err := parseChecksum([]byte(in)); err == nil {
+			t.Errorf("parseChecksum(%q): expected an error", in)
+		}
+	}
+}
+
+// serveRelease serves a latest release with the full asset, optionally a patch from v1.2.3
+// and a checksum; requests are counted by path
+func serveRelease(t *testing.T, full, patch []byte, checksum string, hits map[string]int) *httptest.Server {
+	t.Helper()
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits[r.URL.Path]++
+		switch r.URL.Path {
+		case "/repos/" + GitHubRepo + "/releases/latest":
+			assets := []string{fmt.Sprintf(`{"name": %q, "browser_download_url": "%s/dl/%s", "size": %d}`, getAssetName(), server.URL, getAssetName(), len(full))}
+			if patch != nil {
+				name := patchAssetName("v1.2.3")
+				assets = append(assets, fmt.Sprintf(`{"name": %q, "browser_download_url": "%s/dl/%s", "size": %d}`, name, server.URL, name, len(patch)))
+			}
+			if checksum != "" {
+				name := checksumAssetName()
+				assets = append(assets, fmt.Sprintf(`{"name": %q, "browser_download_url": "%s/dl/%s", "size": 100}`, name, server.URL, name))
+			}
+			fmt.Fprintf(w, `{"tag_name": "v1.3.0", "body": "notes", "assets": [%s]}`, strings.Join(assets, ","))
+		case "/dl/" + getAssetName():
+			w.Write(full)
+		case "/dl/" + patchAssetName("v1.2.3"):
+			w.Write(patch)
+		case "/dl/" + checksumAssetName():
+			fmt.Fprintf(w, "%s  %s\n", checksum, getAssetName())
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	old := apiBaseURL
+	apiBaseURL = server.URL
+	t.Cleanup(func() { apiBaseURL = old })
+	return server
+}
+
+func TestCheckForUpdatesPrefersPatch(t *testing.T) {
+	if patchAssetName("v1.2.3") == "" {
+		t.Skip("no patch updates on this platform")
+	}
+	old, new := testBinaries(64 << 10)
+	patch := MakePatch(old, new)
+
+	tests := []struct {
+		name      string
+		patch     []byte
+		checksum  string
+		current   string
+		wantPatch bool
+	}{
+		{"patch and checksum", patch, sumHex(new), "v1.2.3", true},
+		{"version without v", patch, sumHex(new), "1.2.3", true},
+		{"patch from another version", patch, sumHex(new), "v1.2.2", false},
+		{"no checksum", patch, "", "v1.2.3", false},
+		{"bad checksum", patch, "zz", "v1.2.3", false},
+		{"no patch", nil, sumHex(new), "v1.2.3", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			serveRelease(t, new, tt.patch, tt.checksum, map[string]int{})
+			info, err := CheckForUpdates(tt.current)
+			if err != nil {
+				t.Fatalf("CheckForUpdates: %v", err)
+			}
+			if info.DownloadURL == "" {
+				t.Error("Full download URL must always be set")
+			}
+			if got := info.PatchURL != ""; got != tt.wantPatch {
+				t.Fatalf("PatchURL = %q, want patch: %v", info.PatchURL, tt.wantPatch)
+			}
+			if tt.wantPatch && (info.SHA256 != sumHex(new) || info.PatchSize != int64(len(patch))) {
+				t.Errorf("SHA256 = %q, PatchSize = %d", info.SHA256, info.PatchSize)
+			}
+		})
+	}
+}
+
+func TestFetchUpdatePatchAndFallback(t *testing.T) {
+	if patchAssetName("v1.2.3") == "" {
+		t.Skip("no patch updates on this platform")
+	}
+	old, new := testBinaries(MinAssetSize + 4096)
+	patch := MakePatch(old, new)
+	wrongSource, _ := testBinaries(MinAssetSize + 1000)
+
+	tests := []struct {
+		name         string
+		exe          []byte
+		patch        []byte
+		checksum     string
+		wantFallback error
+	}{
+		{"patch applies", old, patch, sumHex(new), nil},
+		{"wrong source binary", wrongSource, patch, sumHex(new), ErrPatchSource},
+		{"corrupt patch", old, append(append([]byte(nil), patch[:patchHeaderLen]...), make([]byte, 200)...), sumHex(new), ErrCorruptPatch},
+		{"patch is an error page", old, []byte("<html><body>404</body></html>"), sumHex(new), ErrHTMLResponse},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hits := map[string]int{}
+			serveRelease(t, new, tt.patch, tt.checksum, hits)
+			info, err := CheckForUpdates("v1.2.3")
+			if err != nil || info.PatchURL == "" {
+				t.Fatalf("CheckForUpdates: %+v, %v", info, err)
+			}
+
+			dir := t.TempDir()
+			exe := filepath.Join(dir, "current")
+			os.WriteFile(exe, tt.exe, 0o755)
+			downloads := t.TempDir()
+
+			var fallback error
+			path, err := fetchUpdate(*info, exe, downloads, nil, func(err error) { fallback = err })
+			if err != nil {
+				t.Fatalf("fetchUpdate: %v", err)
+			}
+			if data, _ := os.ReadFile(path); !bytes.Equal(data, new) {
+				t.Error("Result differs from the full asset")
+			}
+			fullHits := hits["/dl/"+getAssetName()]
+			if tt.wantFallback == nil {
+				if fallback != nil || fullHits != 0 {
+					t.Errorf("Expected the patch to be used, fallback %v, full downloads %d", fallback, fullHits)
+				}
+			} else if !errors.Is(fallback, tt.wantFallback) || fullHits != 1 {
+				t.Errorf("Expected fallback with %v and one full download, got %v and %d", tt.wantFallback, fallback, fullHits)
+			}
+			if entries, _ := os.ReadDir(downloads); len(entries) != 1 {
+				t.Errorf("Expected only the update in the download directory, got %d files", len(entries))
+			}
+		})
+	}
+}
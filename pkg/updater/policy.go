@@ -0,0 +1,150 @@
+package updater
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// Policy controls which parts of the updater the app may use
+type Policy string
+
+const (
+	// PolicyFull allows checking, downloading and installing updates (the default)
+	PolicyFull Policy = "full"
+	// PolicyNotifyOnly allows checking for new versions but not installing them,
+	// for managed installs where IT deploys upgrades
+	PolicyNotifyOnly Policy = "notify-only"
+	// PolicyDisabled turns off all update checks
+	PolicyDisabled Policy = "disabled"
+)
+
+// PolicyEnvVar forces the policy for the current process and overrides the user setting
+const PolicyEnvVar = "SERIAL_MATE_UPDATE_POLICY"
+
+// Policy sources, from highest to lowest precedence
+const (
+	SourceSystem  = "system"  // machine-wide policy file written by the administrator
+	SourceEnv     = "env"     // PolicyEnvVar
+	SourceUser    = "user"    // the user's settings
+	SourceDefault = "default" // nothing configured
+)
+
+// ParsePolicy parses a policy name; the empty string means PolicyFull
+func ParsePolicy(name string) (Policy, error) {
+	switch p := Policy(strings.ToLower(strings.TrimSpace(name))); p {
+	case "":
+		return PolicyFull, nil
+	case PolicyFull, PolicyNotifyOnly, PolicyDisabled:
+		return p, nil
+	default:
+		return "", fmt.Errorf("unknown update policy %q (expected full, notify-only or disabled)", name)
+	}
+}
+
+// AllowsCheck reports whether the app may look for new versions
+func (p Policy) AllowsCheck() bool {
+	return p != PolicyDisabled
+}
+
+// AllowsInstall reports whether the app may download and install updates
+func (p Policy) AllowsInstall() bool {
+	return p == PolicyFull
+}
+
+// EffectivePolicy is the policy in force and where it came from
+type EffectivePolicy struct {
+	Policy  Policy `json:"policy"`
+	Source  string `json:"source"`            // one of the Source* constants
+	Forced  bool   `json:"forced"`            // set by the system file or the environment; the user cannot change it
+	Warning string `json:"warning,omitempty"` // an administrator-supplied value that could not be used
+}
+
+// PolicyInputs holds the raw policy values from each source; empty means not set
+type PolicyInputs struct {
+	System    string // value from the system policy file
+	SystemErr error  // the system policy file exists but could not be read
+	Env       string
+	User      string
+}
+
+// ResolvePolicy applies the precedence rules: system file, then environment, then user setting, then PolicyFull.
+//
+// The system file wins over the environment because users can set environment variables themselves.
+// An invalid value from a forced source fails closed to PolicyDisabled instead of falling through to
+// a less restrictive source, so a typo in a managed deployment never re-enables self-update.
+// An invalid user setting is ignored.
+func ResolvePolicy(in PolicyInputs) EffectivePolicy {
+	if in.SystemErr != nil {
+		return EffectivePolicy{Policy: PolicyDisabled, Source: SourceSystem, Forced: true,
+			Warning: fmt.Sprintf("system update policy could not be read, updates disabled: %v", in.SystemErr)}
+	}
+	forced := []struct{ source, value string }{{SourceSystem, in.System}, {SourceEnv, in.Env}}
+	for _, f := range forced {
+		if strings.TrimSpace(f.value) == "" {
+			continue
+		}
+		p, err := ParsePolicy(f.value)
+		if err != nil {
+			return EffectivePolicy{Policy: PolicyDisabled, Source: f.source, Forced: true,
+				Warning: fmt.Sprintf("%v; updates disabled", err)}
+		}
+		return EffectivePolicy{Policy: p, Source: f.source, Forced: true}
+	}
+	if strings.TrimSpace(in.User) != "" {
+		if p, err := ParsePolicy(in.User); err == nil {
+			return EffectivePolicy{Policy: p, Source: SourceUser}
+		}
+	}
+	return EffectivePolicy{Policy: PolicyFull, Source: SourceDefault}
+}
+
+// SystemPolicyPath returns the machine-wide policy file location:
+// %ProgramData%\serial-mate\policy.json on Windows, /Library/Application Support/serial-mate/policy.json
+// on macOS and /etc/serial-mate/policy.json elsewhere
+func SystemPolicyPath() string {
+	switch runtime.GOOS {
+	case "windows":
+		base := os.Getenv("ProgramData")
+		if base == "" {
+			base = `C:\ProgramData`
+		}
+		return filepath.Join(base, "serial-mate", "policy.json")
+	case "darwin":
+		return "/Library/Application Support/serial-mate/policy.json"
+	default:
+		return "/etc/serial-mate/policy.json"
+	}
+}
+
+// systemPolicyFile is the format of the system policy file, e.g. {"updatePolicy": "notify-only"}
+type systemPolicyFile struct {
+	UpdatePolicy string `json:"updatePolicy"`
+}
+
+// LoadSystemPolicy reads the update policy from the system policy file at path.
+// A missing file is not an error and yields an empty value
+func LoadSystemPolicy(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	var f systemPolicyFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return "", fmt.Errorf("%s: %w", path, err)
+	}
+	return f.UpdatePolicy, nil
+}
+
+// LoadPolicy resolves the effective policy from the system file at systemPath, the environment and user
+func LoadPolicy(systemPath, user string) EffectivePolicy {
+	system, err := LoadSystemPolicy(systemPath)
+	return ResolvePolicy(PolicyInputs{System: system, SystemErr: err, Env: os.Getenv(PolicyEnvVar), User: user})
+}
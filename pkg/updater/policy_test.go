@@ -0,0 +1,99 @@
+package updater
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestResolvePolicyPrecedence(t *testing.T) {
+	tests := []struct {
+		name   string
+		in     PolicyInputs
+		want   Policy
+		source string
+		forced bool
+		warn   bool
+	}{
+		{"nothing set", PolicyInputs{}, PolicyFull, SourceDefault, false, false},
+		{"user only", PolicyInputs{User: "notify-only"}, PolicyNotifyOnly, SourceUser, false, false},
+		{"env only", PolicyInputs{Env: "disabled"}, PolicyDisabled, SourceEnv, true, false},
+		{"system only", PolicyInputs{System: "notify-only"}, PolicyNotifyOnly, SourceSystem, true, false},
+		{"env beats user", PolicyInputs{Env: "notify-only", User: "full"}, PolicyNotifyOnly, SourceEnv, true, false},
+		{"env can loosen user", PolicyInputs{Env: "full", User: "disabled"}, PolicyFull, SourceEnv, true, false},
+		{"system beats user", PolicyInputs{System: "disabled", User: "full"}, PolicyDisabled, SourceSystem, true, false},
+		{"system beats env", PolicyInputs{System: "notify-only", Env: "full"}, PolicyNotifyOnly, SourceSystem, true, false},
+		{"system beats env and user", PolicyInputs{System: "full", Env: "disabled", User: "notify-only"}, PolicyFull, SourceSystem, true, false},
+		{"values are case-insensitive", PolicyInputs{Env: " Notify-Only "}, PolicyNotifyOnly, SourceEnv, true, false},
+		{"blank env is unset", PolicyInputs{Env: "  ", User: "disabled"}, PolicyDisabled, SourceUser, false, false},
+		{"invalid user falls back to default", PolicyInputs{User: "sometimes"}, PolicyFull, SourceDefault, false, false},
+		{"invalid env fails closed", PolicyInputs{Env: "notify_only", User: "full"}, PolicyDisabled, SourceEnv, true, true},
+		{"invalid system fails closed", PolicyInputs{System: "yes", Env: "full"}, PolicyDisabled, SourceSystem, true, true},
+		{"unreadable system file fails closed", PolicyInputs{SystemErr: errors.New("permission denied"), Env: "full"}, PolicyDisabled, SourceSystem, true, true},
+	}
+	for _, tt := range tests {
+		got := ResolvePolicy(tt.in)
+		if got.Policy != tt.want || got.Source != tt.source || got.Forced != tt.forced {
+			t.Errorf("%s: got %+v, want %s from %s (forced %v)", tt.name, got, tt.want, tt.source, tt.forced)
+		}
+		if (got.Warning != "") != tt.warn {
+			t.Errorf("%s: unexpected warning %q", tt.name, got.Warning)
+		}
+	}
+}
+
+func TestPolicyPermissions(t *testing.T) {
+	tests := []struct {
+		p              Policy
+		check, install bool
+	}{
+		{PolicyFull, true, true},
+		{PolicyNotifyOnly, true, false},
+		{PolicyDisabled, false, false},
+	}
+	for _, tt := range tests {
+		if tt.p.AllowsCheck() != tt.check || tt.p.AllowsInstall() != tt.install {
+			t.Errorf("%s: AllowsCheck %v, AllowsInstall %v", tt.p, tt.p.AllowsCheck(), tt.p.AllowsInstall())
+		}
+	}
+}
+
+func TestLoadSystemPolicy(t *testing.T) {
+	dir := t.TempDir()
+
+	if v, err := LoadSystemPolicy(filepath.Join(dir, "missing.json")); v != "" || err != nil {
+		t.Errorf("missing file: %q, %v", v, err)
+	}
+
+	path := filepath.Join(dir, "policy.json")
+	os.WriteFile(path, []byte(`{"updatePolicy": "notify-only"}`), 0o644)
+	if v, err := LoadSystemPolicy(path); v != "notify-only" || err != nil {
+		t.Errorf("valid file: %q, %v", v, err)
+	}
+
+	os.WriteFile(path, []byte(`{"updatePolicy": `), 0o644)
+	if _, err := LoadSystemPolicy(path); err == nil {
+		t.Error("malformed file should be an error")
+	}
+}
+
+func TestLoadPolicy(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "policy.json")
+
+	t.Setenv(PolicyEnvVar, "notify-only")
+	if got := LoadPolicy(path, "full"); got.Policy != PolicyNotifyOnly || got.Source != SourceEnv {
+		t.Errorf("env over user: %+v", got)
+	}
+
+	os.WriteFile(path, []byte(`{"updatePolicy": "disabled"}`), 0o644)
+	if got := LoadPolicy(path, "full"); got.Policy != PolicyDisabled || got.Source != SourceSystem {
+		t.Errorf("system over env: %+v", got)
+	}
+
+	os.WriteFile(path, []byte(`not json`), 0o644)
+	if got := LoadPolicy(path, "full"); got.Policy != PolicyDisabled || got.Warning == "" {
+		t.Errorf("malformed system file: %+v", got)
+	}
+}
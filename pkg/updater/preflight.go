@@ -0,0 +1,66 @@
+package updater
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// NotWritableError reports that the executable cannot be replaced in place, e.g. when it runs
+// from a read-only network share or an install directory owned by another user.
+// It is returned before anything is renamed or downloaded
+type NotWritableError struct {
+	Dir    string // directory containing the executable
+	Reason string
+	Err    error // underlying error, may be nil
+}
+
+func (e *NotWritableError) Error() string {
+	return fmt.Sprintf("install directory %s is not writable: %s", e.Dir, e.Reason)
+}
+
+func (e *NotWritableError) Unwrap() error {
+	return e.Err
+}
+
+// executablePath returns the resolved path of the running executable
+func executablePath() (string, error) {
+	exePath, err := os.Executable()
+	if err != nil {
+		return "", fmt.Errorf("failed to get executable path: %w", err)
+	}
+	exePath, err = filepath.EvalSymlinks(exePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve symlinks: %w", err)
+	}
+	return exePath, nil
+}
+
+// CheckInstallable verifies that the running executable can be replaced by InstallUpdate.
+// Failures are reported as *NotWritableError
+func CheckInstallable() error {
+	exePath, err := executablePath()
+	if err != nil {
+		return err
+	}
+	return checkInstallDir(exePath)
+}
+
+// checkInstallDir checks that exePath belongs to the current user and that its directory accepts
+// new files, by creating and removing a probe file next to it
+func checkInstallDir(exePath string) error {
+	dir := filepath.Dir(exePath)
+	if err := checkOwner(exePath); err != nil {
+		return &NotWritableError{Dir: dir, Reason: err.Error()}
+	}
+	probe, err := os.CreateTemp(dir, ".serial-mate-probe-*")
+	if err != nil {
+		return &NotWritableError{Dir: dir, Reason: "cannot create files", Err: err}
+	}
+	name := probe.Name()
+	probe.Close()
+	if err := os.Remove(name); err != nil {
+		return &NotWritableError{Dir: dir, Reason: "cannot remove files", Err: err}
+	}
+	return nil
+}
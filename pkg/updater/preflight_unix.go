@@ -0,0 +1,24 @@
+//go:build !windows
+
+package updater
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// checkOwner rejects executables owned by another user: even in a writable directory
+// (e.g. a shared tools folder with the sticky bit) the rename would fail. root may replace any file
+func checkOwner(exePath string) error {
+	info, err := os.Stat(exePath)
+	if err != nil {
+		return err
+	}
+	st, ok := info.Sys().(*syscall.Stat_t)
+	uid := os.Geteuid()
+	if !ok || uid == 0 || int(st.Uid) == uid {
+		return nil
+	}
+	return fmt.Errorf("the executable is owned by uid %d, not the current user (uid %d)", st.Uid, uid)
+}
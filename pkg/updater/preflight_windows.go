@@ -0,0 +1,8 @@
+//go:build windows
+
+package updater
+
+// checkOwner is a no-op on Windows, where access is governed by ACLs; the probe file covers it
+func checkOwner(exePath string) error {
+	return nil
+}
@@ -1,9 +1,12 @@
 package updater
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"mime"
 	"net/http"
 	"os"
 	"os/exec"
@@ -11,6 +14,7 @@ import (
 	"runtime"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -21,12 +25,37 @@ const (
 	OldExeCleanupDelay = 5 * time.Second
 )
 
+// apiBaseURL is the GitHub API endpoint; tests point it at a local server
+var apiBaseURL = "https://api.github.com"
+
+var (
+	transportMu sync.Mutex
+	transport   http.RoundTripper
+)
+
+// SetTransport sets the transport used for all update requests (proxy and CA settings);
+// nil restores http.DefaultTransport
+func SetTransport(rt http.RoundTripper) {
+	transportMu.Lock()
+	defer transportMu.Unlock()
+	transport = rt
+}
+
+// newClient returns an HTTP client using the configured transport
+func newClient(timeout time.Duration) *http.Client {
+	transportMu.Lock()
+	defer transportMu.Unlock()
+	return &http.Client{Timeout: timeout, Transport: transport}
+}
+
 // Release represents a GitHub release
 type Release struct {
-	TagName string `json:"tag_name"`
-	Name    string `json:"name"`
-	Body    string `json:"body"`
-	Assets  []struct {
+	TagName    string `json:"tag_name"`
+	Name       string `json:"name"`
+	Body       string `json:"body"`
+	Draft      bool   `json:"draft"`
+	Prerelease bool   `json:"prerelease"`
+	Assets     []struct {
 		Name               string `json:"name"`
 		BrowserDownloadURL string `json:"browser_download_url"`
 		Size               int64  `json:"size"`
@@ -42,13 +71,18 @@ type UpdateInfo struct {
 	ReleaseNotes   string `json:"releaseNotes"`
 	DownloadURL    string `json:"downloadUrl"`
 	AssetSize      int64  `json:"assetSize"`
+	// PatchURL is set when the release has a patch from the current version and a checksum
+	// for the full asset; see FetchUpdate
+	PatchURL  string `json:"patchUrl,omitempty"`
+	PatchSize int64  `json:"patchSize,omitempty"`
+	SHA256    string `json:"sha256,omitempty"` // hex SHA-256 of the full asset
 }
 
 // CheckForUpdates checks if a new version is available on GitHub
 func CheckForUpdates(currentVersion string) (*UpdateInfo, error) {
-	url := fmt.Sprintf("https://api.github.com/repos/%s/releases/latest", GitHubRepo)
+	url := fmt.Sprintf("%s/repos/%s/releases/latest", apiBaseURL, GitHubRepo)
 
-	client := &http.Client{Timeout: CheckTimeout}
+	client := newClient(CheckTimeout)
 	req, err := http.NewRequest("GET", url, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
@@ -95,14 +129,92 @@ func CheckForUpdates(currentVersion string) (*UpdateInfo, error) {
 		if info.DownloadURL == "" {
 			return nil, fmt.Errorf("no compatible asset found for platform")
 		}
+
+		// Offer a patch only when the result can be verified against the full asset's checksum
+		findPatch(client, &release, info)
+
+		// Include notes from every skipped release; keep the latest notes if the list is unavailable
+		if releases, err := fetchReleases(client); err == nil {
+			if notes := AggregateReleaseNotes(releases, currentVersion); notes != "" {
+				info.ReleaseNotes = notes
+			}
+		}
 	}
 
 	return info, nil
 }
 
-// DownloadUpdate downloads the update file
-func DownloadUpdate(downloadURL string, progressCallback func(downloaded, total int64)) (string, error) {
-	client := &http.Client{Timeout: 5 * time.Minute}
+// MinAssetSize is the smallest download accepted when the release does not report the asset size;
+// every published build is several megabytes, anything smaller is an error page or a truncated file
+const MinAssetSize = 1 << 20
+
+// sniffLen is the number of leading bytes inspected before the download is written to disk
+const sniffLen = 512
+
+// Rejections of a downloaded file; each is wrapped with details by DownloadUpdate
+var (
+	// ErrHTMLResponse the server answered 200 with a web page (typically a CDN or proxy error page)
+	ErrHTMLResponse = errors.New("server returned an HTML page instead of the update file")
+	// ErrUnexpectedContent the file does not start with the signature expected for the asset type
+	ErrUnexpectedContent = errors.New("downloaded file does not look like the update asset")
+	// ErrTooSmall the download is smaller than the asset size reported by the release
+	ErrTooSmall = errors.New("downloaded file is smaller than expected")
+)
+
+// assetMagic returns the leading bytes expected for an asset, judged by its file name;
+// nil when the type is unknown and only the HTML check applies
+func assetMagic(name string) []byte {
+	lower := strings.ToLower(name)
+	switch {
+	case strings.HasSuffix(lower, ".exe"):
+		return []byte("MZ")
+	case strings.HasSuffix(lower, ".zip"):
+		return []byte("PK\x03\x04")
+	case strings.HasSuffix(lower, ".patch"):
+		return []byte(PatchMagic)
+	case strings.HasSuffix(lower, ".gz"):
+		return []byte{0x1f, 0x8b}
+	case filepath.Ext(lower) == "" || strings.HasSuffix(lower, ".appimage"):
+		return []byte("\x7fELF")
+	default:
+		return nil
+	}
+}
+
+// checkHead rejects responses whose Content-Type or first bytes show they are not the asset
+func checkHead(contentType, name string, head []byte) error {
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil && mediaType == "text/html" {
+		return fmt.Errorf("%w (Content-Type %s)", ErrHTMLResponse, contentType)
+	}
+	if strings.HasPrefix(http.DetectContentType(head), "text/html") {
+		return ErrHTMLResponse
+	}
+	if magic := assetMagic(name); magic != nil && !bytes.HasPrefix(head, magic) {
+		return fmt.Errorf("%w: %s should start with %q, got %q", ErrUnexpectedContent, name, magic, head[:min(len(head), len(magic))])
+	}
+	return nil
+}
+
+// DownloadUpdate downloads the update file to the temp directory and returns its path.
+// expectedSize is UpdateInfo.AssetSize (0 when unknown); the download must be at least that large,
+// or MinAssetSize when it is unknown. progressCallback receives total = -1 when the server does not
+// send Content-Length. Rejected downloads are removed and reported with ErrHTMLResponse,
+// ErrUnexpectedContent or ErrTooSmall.
+func DownloadUpdate(downloadURL string, expectedSize int64, progressCallback func(downloaded, total int64)) (string, error) {
+	return DownloadUpdateTo(downloadURL, os.TempDir(), expectedSize, progressCallback)
+}
+
+// DownloadUpdateTo is DownloadUpdate with a chosen directory, for installs that cannot update
+// themselves (see NotWritableError); the user replaces the executable by hand.
+// An existing file with the asset name in dir is overwritten
+func DownloadUpdateTo(downloadURL, dir string, expectedSize int64, progressCallback func(downloaded, total int64)) (string, error) {
+	if info, err := os.Stat(dir); err != nil {
+		return "", fmt.Errorf("invalid download directory: %w", err)
+	} else if !info.IsDir() {
+		return "", fmt.Errorf("invalid download directory: %s is not a directory", dir)
+	}
+
+	client := newClient(5 * time.Minute)
 	req, err := http.NewRequest("GET", downloadURL, nil)
 	if err != nil {
 		return "", fmt.Errorf("failed to create download request: %w", err)
@@ -118,30 +230,57 @@ func DownloadUpdate(downloadURL string, progressCallback func(downloaded, total
 		return "", fmt.Errorf("download failed with status: %d", resp.StatusCode)
 	}
 
-	// Create temporary file
-	tmpDir := os.TempDir()
-	tmpFile := filepath.Join(tmpDir, filepath.Base(downloadURL))
+	// Inspect the first bytes before anything is written; the asset type comes from the release URL,
+	// since redirect targets on the CDN have no file extension
+	head := make([]byte, sniffLen)
+	n, err := io.ReadFull(resp.Body, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return "", fmt.Errorf("failed to read response: %w", err)
+	}
+	head = head[:n]
+	if err := checkHead(resp.Header.Get("Content-Type"), filepath.Base(downloadURL), head); err != nil {
+		return "", err
+	}
 
-	out, err := os.Create(tmpFile)
+	// Create the output file
+	outFile := filepath.Join(dir, filepath.Base(downloadURL))
+
+	out, err := os.Create(outFile)
 	if err != nil {
-		return "", fmt.Errorf("failed to create temp file: %w", err)
+		return "", fmt.Errorf("failed to create download file: %w", err)
 	}
-	defer out.Close()
+	ok := false
+	defer func() {
+		out.Close()
+		if !ok {
+			os.Remove(outFile)
+		}
+	}()
 
-	// Download with progress
+	// Download with progress; ContentLength is -1 for chunked responses
 	totalSize := resp.ContentLength
 	downloaded := int64(0)
+	write := func(p []byte) error {
+		if _, err := out.Write(p); err != nil {
+			return fmt.Errorf("failed to write to file: %w", err)
+		}
+		downloaded += int64(len(p))
+		if progressCallback != nil {
+			progressCallback(downloaded, totalSize)
+		}
+		return nil
+	}
+	if len(head) > 0 {
+		if err := write(head); err != nil {
+			return "", err
+		}
+	}
 	buffer := make([]byte, 32*1024)
-
 	for {
 		n, err := resp.Body.Read(buffer)
 		if n > 0 {
-			if _, writeErr := out.Write(buffer[:n]); writeErr != nil {
-				return "", fmt.Errorf("failed to write to file: %w", writeErr)
-			}
-			downloaded += int64(n)
-			if progressCallback != nil {
-				progressCallback(downloaded, totalSize)
+			if writeErr := write(buffer[:n]); writeErr != nil {
+				return "", writeErr
 			}
 		}
 		if err == io.EOF {
@@ -152,21 +291,35 @@ func DownloadUpdate(downloadURL string, progressCallback func(downloaded, total
 		}
 	}
 
-	return tmpFile, nil
+	minSize := expectedSize
+	if minSize <= 0 {
+		minSize = MinAssetSize
+	}
+	if downloaded < minSize {
+		return "", fmt.Errorf("%w: got %d bytes, need at least %d", ErrTooSmall, downloaded, minSize)
+	}
+	if err := out.Close(); err != nil {
+		return "", fmt.Errorf("failed to write to file: %w", err)
+	}
+	ok = true
+	return outFile, nil
 }
 
-// InstallUpdate installs the downloaded update
+// InstallUpdate installs the downloaded update. The install directory is checked first
+// (see CheckInstallable), so a read-only location fails with *NotWritableError before the
+// executable is touched
 func InstallUpdate(updateFile string) error {
-	// Get current executable path
-	exePath, err := os.Executable()
+	exePath, err := executablePath()
 	if err != nil {
-		return fmt.Errorf("failed to get executable path: %w", err)
+		return err
 	}
+	return installAt(exePath, updateFile)
+}
 
-	// Resolve symlinks
-	exePath, err = filepath.EvalSymlinks(exePath)
-	if err != nil {
-		return fmt.Errorf("failed to resolve symlinks: %w", err)
+// installAt replaces exePath with updateFile, keeping a backup until the copy succeeds
+func installAt(exePath, updateFile string) error {
+	if err := checkInstallDir(exePath); err != nil {
+		return err
 	}
 
 	// For both Windows and Unix, we use copy + remove to handle cross-device moves
@@ -1,10 +1,19 @@
 package updater
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"runtime"
+	"strconv"
+	"strings"
 	"testing"
+	"unicode/utf8"
 )
 
 func TestCompareVersions(t *testing.T) {
@@ -250,3 +259,279 @@ func TestEscapeWindowsPath(t *testing.T) {
 		}
 	}
 }
+
+// releasesJSON is a fabricated /releases payload in GitHub's (unsorted) format
+const releasesJSON = `[
+	{"tag_name": "v1.3.0", "name": "Scripting", "body": "BREAKING: macro syntax changed", "published_at": "2024-03-01T10:00:00Z"},
+	{"tag_name": "v1.6.0-rc1", "name": "RC", "body": "pre-release", "prerelease": true, "published_at": "2024-06-01T10:00:00Z"},
+	{"tag_name": "v1.5.0", "name": "v1.5.0", "body": "Faster plots\r\n", "published_at": "2024-05-01T10:00:00Z"},
+	{"tag_name": "v1.7.0", "name": "Draft", "body": "unpublished", "draft": true},
+	{"tag_name": "v1.2.0", "name": "Current", "body": "already installed", "published_at": "2024-02-01T10:00:00Z"},
+	{"tag_name": "v1.4.0", "name": "", "body": "", "published_at": "2024-04-01T10:00:00Z"},
+	{"tag_name": "v1.1.0", "name": "Old", "body": "old", "published_at": "2024-01-01T10:00:00Z"}
+]`
+
+func TestAggregateReleaseNotes(t *testing.T) {
+	var releases []Release
+	if err := json.Unmarshal([]byte(releasesJSON), &releases); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+
+	expected := "## v1.5.0 (2024-05-01)\n\nFaster plots" +
+		"\n\n---\n\n## v1.4.0 (2024-04-01)\n\n_No release notes._" +
+		"\n\n---\n\n## v1.3.0 - Scripting (2024-03-01)\n\nBREAKING: macro syntax changed"
+	if got := AggregateReleaseNotes(releases, "v1.2.0"); got != expected {
+		t.Errorf("Unexpected notes:\n%s\nexpected:\n%s", got, expected)
+	}
+
+	if got := AggregateReleaseNotes(releases, "v1.5.0"); got != "" {
+		t.Errorf("Expected no notes when up to date, got %q", got)
+	}
+}
+
+func TestAggregateReleaseNotesTruncates(t *testing.T) {
+	var releases []Release
+	for i := 1; i <= 20; i++ {
+		releases = append(releases, Release{
+			TagName: fmt.Sprintf("v2.%d.0", i),
+			Body:    strings.Repeat("x", 2000),
+		})
+	}
+
+	notes := AggregateReleaseNotes(releases, "v1.0.0")
+	if len(notes) > MaxReleaseNotes {
+		t.Errorf("Notes exceed the cap: %d bytes", len(notes))
+	}
+	if !strings.HasPrefix(notes, "## v2.20.0") || !strings.Contains(notes, ChangelogURL()) {
+		t.Errorf("Expected newest release first and a changelog link, got %q...", notes[:40])
+	}
+	if strings.Contains(notes, "## v2.1.0\n") {
+		t.Error("Expected the oldest release to be dropped")
+	}
+
+	// A single oversized release is cut rather than omitted
+	huge := []Release{{TagName: "v3.0.0", Body: strings.Repeat("中", MaxReleaseNotes)}}
+	notes = AggregateReleaseNotes(huge, "v1.0.0")
+	if len(notes) > MaxReleaseNotes || !utf8.ValidString(notes) || !strings.HasPrefix(notes, "## v3.0.0") {
+		t.Errorf("Unexpected truncation of a single release: %d bytes", len(notes))
+	}
+}
+
+func TestCheckForUpdatesAggregatesPaginatedReleases(t *testing.T) {
+	var all []Release
+	if err := json.Unmarshal([]byte(releasesJSON), &all); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/repos/"+GitHubRepo+"/releases/latest":
+			fmt.Fprintf(w, `{"tag_name": "v1.5.0", "body": "Faster plots", "assets": [{"name": %q, "browser_download_url": "https://example.invalid/a", "size": 1}]}`, getAssetName())
+		case r.URL.Path == "/repos/"+GitHubRepo+"/releases":
+			// Serve the releases three per page, linked with rel="next"
+			page := 0
+			fmt.Sscanf(r.URL.Query().Get("page"), "%d", &page)
+			start, end := page*3, page*3+3
+			if end >= len(all) {
+				end = len(all)
+			} else {
+				w.Header().Set("Link", fmt.Sprintf(`<%s%s?per_page=3&page=%d>; rel="next", <%s>; rel="last"`, server.URL, r.URL.Path, page+1, server.URL))
+			}
+			json.NewEncoder(w).Encode(all[start:end])
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	old := apiBaseURL
+	apiBaseURL = server.URL
+	defer func() { apiBaseURL = old }()
+
+	info, err := CheckForUpdates("v1.2.0")
+	if err != nil {
+		t.Fatalf("CheckForUpdates: %v", err)
+	}
+	if !info.Available || !strings.Contains(info.ReleaseNotes, "BREAKING") || !strings.Contains(info.ReleaseNotes, "## v1.4.0") {
+		t.Errorf("Expected notes from all skipped releases, got %q", info.ReleaseNotes)
+	}
+}
+
+// serveAsset serves body at any path; chunked omits Content-Length
+func serveAsset(t *testing.T, contentType string, body []byte, chunked bool) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if contentType != "" {
+			w.Header().Set("Content-Type", contentType)
+		}
+		if chunked {
+			w.WriteHeader(http.StatusOK)
+			for len(body) > 0 {
+				n := min(len(body), 1000)
+				w.Write(body[:n])
+				w.(http.Flusher).Flush()
+				body = body[n:]
+			}
+			return
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+		w.Write(body)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func elfBody(size int) []byte {
+	body := bytes.Repeat([]byte{0x90}, size)
+	copy(body, "\x7fELF")
+	return body
+}
+
+func TestDownloadUpdateWithoutContentLength(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+	body := elfBody(5000)
+	server := serveAsset(t, "application/octet-stream", body, true)
+
+	var totals []int64
+	var last int64
+	path, err := DownloadUpdate(server.URL+"/serial-mate-linux-amd64", int64(len(body)), func(downloaded, total int64) {
+		totals = append(totals, total)
+		last = downloaded
+	})
+	if err != nil {
+		t.Fatalf("DownloadUpdate: %v", err)
+	}
+	for _, total := range totals {
+		if total != -1 {
+			t.Fatalf("Expected unknown total (-1) for a chunked response, got %d", total)
+		}
+	}
+	if last != int64(len(body)) {
+		t.Errorf("Expected %d bytes reported, got %d", len(body), last)
+	}
+	if data, _ := os.ReadFile(path); !bytes.Equal(data, body) {
+		t.Error("Downloaded file differs from the served body")
+	}
+}
+
+func TestDownloadUpdateRejections(t *testing.T) {
+	page := []byte("<!DOCTYPE html><html><body><h1>502 Bad Gateway</h1></body></html>")
+	tests := []struct {
+		name        string
+		contentType string
+		body        []byte
+		asset       string
+		expected    int64
+		want        error
+	}{
+		{"html content type", "text/html; charset=utf-8", page, "serial-mate-linux-amd64", 0, ErrHTMLResponse},
+		{"html sniffed", "application/octet-stream", page, "serial-mate-windows-amd64.exe", 0, ErrHTMLResponse},
+		{"wrong signature", "application/octet-stream", elfBody(4096), "serial-mate-windows-amd64.exe", 4096, ErrUnexpectedContent},
+		{"truncated", "application/octet-stream", elfBody(4096), "serial-mate-linux-amd64", 8192, ErrTooSmall},
+		{"below minimum", "", elfBody(4096), "serial-mate-linux-amd64", 0, ErrTooSmall},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			t.Setenv("TMPDIR", dir)
+			server := serveAsset(t, tt.contentType, tt.body, false)
+			_, err := DownloadUpdate(server.URL+"/"+tt.asset, tt.expected, nil)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("Expected %v, got %v", tt.want, err)
+			}
+			if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+				t.Errorf("Rejected download left %d files behind", len(entries))
+			}
+		})
+	}
+
+	// Each rejection reads differently
+	seen := map[string]bool{}
+	for _, err := range []error{ErrHTMLResponse, ErrUnexpectedContent, ErrTooSmall} {
+		if seen[err.Error()] {
+			t.Errorf("Duplicate message %q", err)
+		}
+		seen[err.Error()] = true
+	}
+}
+
+func TestInstallPreflightReadOnlyDir(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Directory permission bits are not enforced on Windows")
+	}
+	if os.Geteuid() == 0 {
+		t.Skip("root bypasses directory permissions")
+	}
+
+	dir := t.TempDir()
+	exePath := filepath.Join(dir, "serial-mate")
+	original := []byte("original executable content")
+	if err := os.WriteFile(exePath, original, 0755); err != nil {
+		t.Fatal(err)
+	}
+	updatePath := filepath.Join(t.TempDir(), "update-file")
+	if err := os.WriteFile(updatePath, []byte("updated executable content"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(dir, 0555); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chmod(dir, 0755) })
+
+	err := installAt(exePath, updatePath)
+	var nw *NotWritableError
+	if !errors.As(err, &nw) {
+		t.Fatalf("Expected NotWritableError, got %v", err)
+	}
+	if nw.Dir != dir || !strings.Contains(err.Error(), dir) {
+		t.Errorf("Error does not name the directory: %v", err)
+	}
+
+	// Nothing was renamed: the executable is intact and there is no backup
+	if data, _ := os.ReadFile(exePath); !bytes.Equal(data, original) {
+		t.Error("Executable was modified")
+	}
+	if _, err := os.Stat(exePath + ".old"); !os.IsNotExist(err) {
+		t.Errorf("Backup exists after failed preflight: %v", err)
+	}
+	if _, err := os.Stat(updatePath); err != nil {
+		t.Errorf("Update file was removed: %v", err)
+	}
+}
+
+func TestInstallPreflightWritableDir(t *testing.T) {
+	dir := t.TempDir()
+	exePath := filepath.Join(dir, "serial-mate")
+	if err := os.WriteFile(exePath, []byte("exe"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := checkInstallDir(exePath); err != nil {
+		t.Fatalf("checkInstallDir: %v", err)
+	}
+	// The probe file is removed again
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("Expected only the executable in %s, found %d entries", dir, len(entries))
+	}
+}
+
+func TestDownloadUpdateTo(t *testing.T) {
+	dir := t.TempDir()
+	body := elfBody(5000)
+	server := serveAsset(t, "application/octet-stream", body, false)
+
+	path, err := DownloadUpdateTo(server.URL+"/serial-mate-linux-amd64", dir, int64(len(body)), nil)
+	if err != nil {
+		t.Fatalf("DownloadUpdateTo: %v", err)
+	}
+	if path != filepath.Join(dir, "serial-mate-linux-amd64") {
+		t.Errorf("Downloaded to %s", path)
+	}
+	if data, _ := os.ReadFile(path); !bytes.Equal(data, body) {
+		t.Error("Downloaded file differs from the served body")
+	}
+
+	if _, err := DownloadUpdateTo(server.URL+"/serial-mate-linux-amd64", filepath.Join(dir, "missing"), 0, nil); err == nil {
+		t.Error("Expected error for a missing directory")
+	}
+}
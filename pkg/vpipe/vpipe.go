@@ -0,0 +1,81 @@
+// Package vpipe 提供进程内背靠背连接的一对端点，用于在没有硬件时调试协议
+// 可选的有损模式可以模拟丢包、延迟与带宽限制
+package vpipe
+
+import (
+	"fmt"
+	"math/rand"
+	"net"
+	"sync"
+	"time"
+)
+
+// Options 有损模式参数，零值表示理想链路
+type Options struct {
+	DropPercent float64       // 每次 Write 被整体丢弃的概率 (0-100)
+	Latency     time.Duration // 每次 Write 额外增加的延迟
+	BytesPerSec int           // 带宽上限，0 表示不限
+}
+
+// Validate 检查参数范围
+func (o Options) Validate() error {
+	if o.DropPercent < 0 || o.DropPercent > 100 {
+		return fmt.Errorf("drop percent must be between 0 and 100, got %v", o.DropPercent)
+	}
+	if o.Latency < 0 {
+		return fmt.Errorf("latency must not be negative")
+	}
+	if o.BytesPerSec < 0 {
+		return fmt.Errorf("bandwidth must not be negative")
+	}
+	return nil
+}
+
+// Pair 创建一对相连的端点：写入 a 的数据从 b 读出，反之亦然
+// 关闭任意一端，另一端的读写都会返回 io.EOF / io.ErrClosedPipe
+func Pair(opts Options) (net.Conn, net.Conn) {
+	a, b := net.Pipe()
+	if opts == (Options{}) {
+		return a, b
+	}
+	rng := &lockedRand{r: rand.New(rand.NewSource(time.Now().UnixNano()))}
+	return &lossyConn{Conn: a, opts: opts, rng: rng}, &lossyConn{Conn: b, opts: opts, rng: rng}
+}
+
+// 便于测试替换
+var sleep = time.Sleep
+
+// lockedRand 两端共享的随机源
+type lockedRand struct {
+	mu sync.Mutex
+	r  *rand.Rand
+}
+
+func (l *lockedRand) Float64() float64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.r.Float64()
+}
+
+// lossyConn 在 Write 时施加丢包、延迟与带宽限制
+type lossyConn struct {
+	net.Conn
+	opts Options
+	rng  *lockedRand
+}
+
+func (c *lossyConn) Write(p []byte) (int, error) {
+	if c.opts.DropPercent > 0 && c.rng.Float64()*100 < c.opts.DropPercent {
+		// 对发送方而言写入成功，数据在"线路"上丢失
+		return len(p), nil
+	}
+
+	delay := c.opts.Latency
+	if c.opts.BytesPerSec > 0 {
+		delay += time.Duration(len(p)) * time.Second / time.Duration(c.opts.BytesPerSec)
+	}
+	if delay > 0 {
+		sleep(delay)
+	}
+	return c.Conn.Write(p)
+}
@@ -0,0 +1,92 @@
+package vpipe
+
+import (
+	"io"
+	"math/rand"
+	"net"
+	"testing"
+	"time"
+)
+
+func readAsync(c net.Conn) <-chan []byte {
+	ch := make(chan []byte, 16)
+	go func() {
+		defer close(ch)
+		buf := make([]byte, 64)
+		for {
+			n, err := c.Read(buf)
+			if err != nil {
+				return
+			}
+			ch <- append([]byte(nil), buf[:n]...)
+		}
+	}()
+	return ch
+}
+
+func TestPairIsBidirectional(t *testing.T) {
+	a, b := Pair(Options{})
+	defer a.Close()
+
+	fromA := readAsync(b)
+	fromB := readAsync(a)
+
+	a.Write([]byte("ping"))
+	if got := string(<-fromA); got != "ping" {
+		t.Errorf("B received %q, expected ping", got)
+	}
+	b.Write([]byte("pong"))
+	if got := string(<-fromB); got != "pong" {
+		t.Errorf("A received %q, expected pong", got)
+	}
+}
+
+func TestClosingOneSideClosesBoth(t *testing.T) {
+	a, b := Pair(Options{})
+	a.Close()
+
+	if _, err := b.Read(make([]byte, 1)); err != io.EOF {
+		t.Errorf("Expected EOF on peer read, got %v", err)
+	}
+	if _, err := b.Write([]byte{1}); err == nil {
+		t.Error("Expected error writing to closed pair")
+	}
+}
+
+func TestLossyDropAndDelay(t *testing.T) {
+	var slept time.Duration
+	sleep = func(d time.Duration) { slept += d }
+	defer func() { sleep = time.Sleep }()
+
+	x, y := net.Pipe()
+	defer x.Close()
+	rng := &lockedRand{r: rand.New(rand.NewSource(1))}
+
+	drop := &lossyConn{Conn: x, opts: Options{DropPercent: 100}, rng: rng}
+	if n, err := drop.Write([]byte("lost")); n != 4 || err != nil {
+		t.Errorf("Dropped write should report success, got %d, %v", n, err)
+	}
+	if slept != 0 {
+		t.Errorf("Dropped write should not be delayed")
+	}
+
+	slow := &lossyConn{Conn: x, opts: Options{Latency: 10 * time.Millisecond, BytesPerSec: 100}, rng: rng}
+	received := readAsync(y)
+	slow.Write(make([]byte, 50))
+	<-received
+	if expected := 10*time.Millisecond + 500*time.Millisecond; slept != expected {
+		t.Errorf("Expected delay %v, got %v", expected, slept)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	bad := []Options{{DropPercent: 101}, {DropPercent: -1}, {Latency: -1}, {BytesPerSec: -1}}
+	for _, o := range bad {
+		if o.Validate() == nil {
+			t.Errorf("Expected %+v to be invalid", o)
+		}
+	}
+	if err := (Options{DropPercent: 5, Latency: time.Millisecond}).Validate(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,112 @@
+// Package watchdog 检测接收静默：超过设定时间没有收到任何数据时触发回调
+package watchdog
+
+import (
+	"sync"
+	"time"
+)
+
+// Watchdog 接收静默看门狗，线程安全
+// 每次收到数据调用 Kick；静默达到 timeout 时调用 onSilent，
+// 之后每再静默一个 timeout 周期触发一次，直到再次收到数据
+type Watchdog struct {
+	timeout  time.Duration
+	onSilent func(silence time.Duration)
+	now      func() time.Time
+
+	mu       sync.Mutex
+	lastRx   time.Time
+	nextFire time.Time
+	stop     chan struct{}
+}
+
+// New 创建看门狗，需调用 Start 开始计时
+func New(timeout time.Duration, onSilent func(silence time.Duration)) *Watchdog {
+	return &Watchdog{timeout: timeout, onSilent: onSilent, now: time.Now}
+}
+
+// Start 开始计时，静默时间从此刻算起
+func (w *Watchdog) Start() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.stop != nil {
+		return
+	}
+	w.resetLocked(w.now())
+	w.stop = make(chan struct{})
+	go w.run(w.stop)
+}
+
+// Stop 停止计时，可重复调用
+func (w *Watchdog) Stop() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.stop != nil {
+		close(w.stop)
+		w.stop = nil
+	}
+}
+
+// Kick 记录一次接收
+func (w *Watchdog) Kick() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.resetLocked(w.now())
+}
+
+// Silence 返回距最近一次接收 (或 Start) 的时间
+func (w *Watchdog) Silence() time.Duration {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.now().Sub(w.lastRx)
+}
+
+func (w *Watchdog) resetLocked(now time.Time) {
+	w.lastRx = now
+	w.nextFire = now.Add(w.timeout)
+}
+
+// checkInterval 检查间隔：timeout 的 1/10，限制在 10ms 到 1s 之间
+func (w *Watchdog) checkInterval() time.Duration {
+	d := w.timeout / 10
+	if d < 10*time.Millisecond {
+		d = 10 * time.Millisecond
+	}
+	if d > time.Second {
+		d = time.Second
+	}
+	return d
+}
+
+func (w *Watchdog) run(stop chan struct{}) {
+	ticker := time.NewTicker(w.checkInterval())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			w.check()
+		}
+	}
+}
+
+// check 静默达到下一个触发点时调用回调 (在锁外调用)
+func (w *Watchdog) check() {
+	w.mu.Lock()
+	now := w.now()
+	if now.Before(w.nextFire) {
+		w.mu.Unlock()
+		return
+	}
+	silence := now.Sub(w.lastRx)
+	w.nextFire = now.Add(w.timeout)
+	w.mu.Unlock()
+
+	w.onSilent(silence)
+}
@@ -0,0 +1,83 @@
+package watchdog
+
+import (
+	"testing"
+	"time"
+)
+
+type fakeClock struct{ t time.Time }
+
+func (c *fakeClock) now() time.Time          { return c.t }
+func (c *fakeClock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+func newTestWatchdog(timeout time.Duration) (*Watchdog, *fakeClock, *[]time.Duration) {
+	clock := &fakeClock{t: time.Unix(1000, 0)}
+	var fired []time.Duration
+	w := New(timeout, func(d time.Duration) { fired = append(fired, d) })
+	w.now = clock.now
+	w.resetLocked(clock.now())
+	return w, clock, &fired
+}
+
+func TestFiresAfterSilence(t *testing.T) {
+	w, clock, fired := newTestWatchdog(10 * time.Second)
+
+	clock.advance(9 * time.Second)
+	w.check()
+	if len(*fired) != 0 {
+		t.Fatalf("Should not fire before timeout")
+	}
+
+	clock.advance(time.Second)
+	w.check()
+	if len(*fired) != 1 || (*fired)[0] != 10*time.Second {
+		t.Fatalf("Expected one firing at 10s, got %v", *fired)
+	}
+
+	// 同一静默周期内不重复触发，下一个周期再触发
+	clock.advance(5 * time.Second)
+	w.check()
+	clock.advance(5 * time.Second)
+	w.check()
+	if len(*fired) != 2 || (*fired)[1] != 20*time.Second {
+		t.Errorf("Expected second firing at 20s of silence, got %v", *fired)
+	}
+}
+
+func TestKickResetsTimer(t *testing.T) {
+	w, clock, fired := newTestWatchdog(10 * time.Second)
+
+	clock.advance(8 * time.Second)
+	w.Kick()
+	clock.advance(8 * time.Second)
+	w.check()
+	if len(*fired) != 0 {
+		t.Errorf("Kick should reset the timer, got %v", *fired)
+	}
+	if s := w.Silence(); s != 8*time.Second {
+		t.Errorf("Expected silence 8s, got %v", s)
+	}
+}
+
+func TestStartStop(t *testing.T) {
+	fired := make(chan time.Duration, 10)
+	w := New(20*time.Millisecond, func(d time.Duration) { fired <- d })
+	w.Start()
+
+	select {
+	case <-fired:
+	case <-time.After(time.Second):
+		t.Fatal("Watchdog did not fire")
+	}
+
+	w.Stop()
+	w.Stop()
+	time.Sleep(30 * time.Millisecond) // 等待可能正在进行的检查结束
+	for len(fired) > 0 {
+		<-fired
+	}
+	time.Sleep(60 * time.Millisecond)
+	if len(fired) != 0 {
+		t.Error("Stopped watchdog should not fire")
+	}
+}
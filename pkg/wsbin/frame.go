@@ -0,0 +1,75 @@
+package wsbin
+
+import (
+	"encoding/binary"
+	"errors"
+	"time"
+)
+
+// Version 帧格式版本
+const Version = 1
+
+// HeaderLen 固定头部长度，之后依次为连接 ID 与数据
+//
+//	偏移 长度 字段
+//	0    1    版本 (Version)
+//	1    1    标志 (FlagReplay)
+//	2    2    连接 ID 长度，小端
+//	4    8    序号 (与 serial-data 元信息中的 seq 相同)，小端
+//	12   8    接收时间，Unix 微秒，小端
+//	20   n    连接 ID (事件通道 ID，UTF-8)
+//	20+n ...  数据
+const HeaderLen = 20
+
+// 标志位
+const (
+	FlagReplay = 1 << 0 // 由 RequestReplay 或重连补发的数据
+)
+
+// Frame 一个数据帧
+type Frame struct {
+	Channel string
+	Seq     uint64
+	Time    time.Time
+	Replay  bool
+	Data    []byte
+}
+
+// AppendFrame 把帧编码追加到 dst
+func AppendFrame(dst []byte, f Frame) []byte {
+	var hdr [HeaderLen]byte
+	hdr[0] = Version
+	if f.Replay {
+		hdr[1] |= FlagReplay
+	}
+	binary.LittleEndian.PutUint16(hdr[2:], uint16(len(f.Channel)))
+	binary.LittleEndian.PutUint64(hdr[4:], f.Seq)
+	binary.LittleEndian.PutUint64(hdr[12:], uint64(f.Time.UnixMicro()))
+	dst = append(dst, hdr[:]...)
+	dst = append(dst, f.Channel...)
+	return append(dst, f.Data...)
+}
+
+// ErrShortFrame 数据不足一个完整的头部
+var ErrShortFrame = errors.New("binary frame too short")
+
+// ParseFrame 解码一个帧，Data 引用 b 的内存
+func ParseFrame(b []byte) (Frame, error) {
+	if len(b) < HeaderLen {
+		return Frame{}, ErrShortFrame
+	}
+	if b[0] != Version {
+		return Frame{}, errors.New("unsupported binary frame version")
+	}
+	n := int(binary.LittleEndian.Uint16(b[2:]))
+	if len(b) < HeaderLen+n {
+		return Frame{}, ErrShortFrame
+	}
+	return Frame{
+		Channel: string(b[HeaderLen : HeaderLen+n]),
+		Seq:     binary.LittleEndian.Uint64(b[4:]),
+		Time:    time.UnixMicro(int64(binary.LittleEndian.Uint64(b[12:]))),
+		Replay:  b[1]&FlagReplay != 0,
+		Data:    b[HeaderLen+n:],
+	}, nil
+}
@@ -0,0 +1,251 @@
+// Package wsbin 通过本机回环 WebSocket 以二进制帧发送接收数据
+//
+// 高吞吐 (每秒数 MB) 时，经 Wails 事件发送的数据需要 JSON 编码 (字节数组按 base64)，前端再解码，
+// 两端都消耗大量 CPU。开启后数据块改为经本包的 WebSocket 以二进制消息发送 (格式见 HeaderLen)，
+// 事件通道只传元信息与控制事件。
+//
+// 服务只监听 127.0.0.1 的随机端口，连接 URL 中带有随机令牌，其他本机进程无法猜到。
+// 客户端重连时在 URL 中附加 from=<最后收到的序号+1>，服务端先补发历史缓冲区中从该序号开始的数据
+// 再继续发送实时数据，按序号去重，重连期间的数据既不丢失也不重复 (前提是仍在历史缓冲区内)。
+// 客户端读取太慢导致发送队列满时连接被断开，客户端以同样的方式重连补齐。
+package wsbin
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// 默认参数
+const (
+	// QueueDepth 每个客户端的发送队列长度 (帧数)，超过后断开该客户端
+	QueueDepth = 1024
+	// WriteTimeout 单条消息的写超时
+	WriteTimeout = 5 * time.Second
+	// Path WebSocket 的路径
+	Path = "/data"
+)
+
+// ReplayFunc 返回从 fromSeq 开始的历史数据，用于客户端重连补发
+type ReplayFunc func(fromSeq uint64) []Frame
+
+// Stats 发送统计
+type Stats struct {
+	Clients  int    `json:"clients"`
+	Frames   uint64 `json:"frames"`   // 已写出的帧数 (含补发)
+	Bytes    uint64 `json:"bytes"`    // 已写出的数据字节数 (不含头部)
+	Replayed uint64 `json:"replayed"` // 重连时补发的帧数
+	Kicked   uint64 `json:"kicked"`   // 因发送队列满被断开的次数
+}
+
+type item struct {
+	channel string
+	seq     uint64
+	n       int // 数据字节数
+	msg     []byte
+}
+
+type client struct {
+	conn      *websocket.Conn
+	queue     chan item
+	kick      chan struct{}
+	closeOnce sync.Once
+	last      map[string]uint64 // 各连接已发送的最大序号，仅由写 goroutine 访问
+}
+
+// close 断开客户端，同时关闭底层连接使阻塞中的写入立即返回
+func (c *client) close() {
+	c.closeOnce.Do(func() {
+		close(c.kick)
+		c.conn.Close()
+	})
+}
+
+// Server 二进制数据服务
+type Server struct {
+	ln     net.Listener
+	srv    *http.Server
+	token  string
+	replay ReplayFunc
+
+	upgrader websocket.Upgrader
+
+	mu      sync.Mutex
+	clients map[*client]struct{}
+	closed  bool
+
+	frames   atomic.Uint64
+	bytes    atomic.Uint64
+	replayed atomic.Uint64
+	kicked   atomic.Uint64
+}
+
+// Start 在 127.0.0.1 的随机端口上启动服务，replay 可为 nil (不补发)
+func Start(replay ReplayFunc) (*Server, error) {
+	var tok [16]byte
+	if _, err := rand.Read(tok[:]); err != nil {
+		return nil, err
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	s := &Server{
+		ln:      ln,
+		token:   hex.EncodeToString(tok[:]),
+		replay:  replay,
+		clients: make(map[*client]struct{}),
+		upgrader: websocket.Upgrader{
+			ReadBufferSize:  1024,
+			WriteBufferSize: 64 << 10,
+			// 前端页面的来源因平台而异 (wails://、http://wails.localhost)，由令牌保证只有本程序能连接
+			CheckOrigin: func(*http.Request) bool { return true },
+		},
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc(Path, s.handle)
+	s.srv = &http.Server{Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+	go s.srv.Serve(ln)
+	return s, nil
+}
+
+// URL 返回客户端连接的地址 (不含 from 参数)
+func (s *Server) URL() string {
+	return "ws://" + s.ln.Addr().String() + Path + "?token=" + s.token
+}
+
+// Send 把一帧放入每个客户端的发送队列，不会阻塞；没有客户端时直接丢弃 (客户端连接时按序号补发)
+func (s *Server) Send(f Frame) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.clients) == 0 {
+		return
+	}
+	it := item{channel: f.Channel, seq: f.Seq, n: len(f.Data), msg: AppendFrame(make([]byte, 0, HeaderLen+len(f.Channel)+len(f.Data)), f)}
+	for c := range s.clients {
+		select {
+		case c.queue <- it:
+		default:
+			s.kicked.Add(1)
+			c.close()
+		}
+	}
+}
+
+// Stats 返回发送统计
+func (s *Server) Stats() Stats {
+	s.mu.Lock()
+	n := len(s.clients)
+	s.mu.Unlock()
+	return Stats{
+		Clients:  n,
+		Frames:   s.frames.Load(),
+		Bytes:    s.bytes.Load(),
+		Replayed: s.replayed.Load(),
+		Kicked:   s.kicked.Load(),
+	}
+}
+
+// Close 停止服务并断开所有客户端
+func (s *Server) Close() error {
+	s.mu.Lock()
+	s.closed = true
+	for c := range s.clients {
+		c.close()
+	}
+	s.mu.Unlock()
+	return s.srv.Close()
+}
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	if subtle.ConstantTimeCompare([]byte(r.URL.Query().Get("token")), []byte(s.token)) != 1 {
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+	var from uint64
+	if v := r.URL.Query().Get("from"); v != "" {
+		n, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			http.Error(w, "invalid from", http.StatusBadRequest)
+			return
+		}
+		from = n
+	}
+	conn, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+
+	c := &client{conn: conn, queue: make(chan item, QueueDepth), kick: make(chan struct{}), last: make(map[string]uint64)}
+	// 先注册再补发：补发期间到达的实时数据进入队列，按序号跳过已补发的部分
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		conn.Close()
+		return
+	}
+	s.clients[c] = struct{}{}
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		delete(s.clients, c)
+		s.mu.Unlock()
+		c.close()
+	}()
+
+	// 读取并丢弃客户端消息以处理控制帧，连接断开时结束
+	go func() {
+		for {
+			if _, _, err := conn.NextReader(); err != nil {
+				c.close()
+				return
+			}
+		}
+	}()
+
+	if from > 0 && s.replay != nil {
+		for _, f := range s.replay(from) {
+			f.Replay = true
+			if !s.write(c, item{channel: f.Channel, seq: f.Seq, n: len(f.Data), msg: AppendFrame(nil, f)}) {
+				return
+			}
+			s.replayed.Add(1)
+		}
+	}
+	for {
+		select {
+		case <-c.kick:
+			return
+		case it := <-c.queue:
+			if it.seq <= c.last[it.channel] {
+				continue
+			}
+			if !s.write(c, it) {
+				return
+			}
+		}
+	}
+}
+
+// write 写出一帧并记录序号，失败时返回 false
+func (s *Server) write(c *client, it item) bool {
+	c.conn.SetWriteDeadline(time.Now().Add(WriteTimeout))
+	if err := c.conn.WriteMessage(websocket.BinaryMessage, it.msg); err != nil {
+		return false
+	}
+	if it.seq > c.last[it.channel] {
+		c.last[it.channel] = it.seq
+	}
+	s.frames.Add(1)
+	s.bytes.Add(uint64(it.n))
+	return true
+}
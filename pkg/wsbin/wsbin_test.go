@@ -0,0 +1,249 @@
+package wsbin
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestFrameRoundTrip(t *testing.T) {
+	at := time.UnixMicro(1700000000123456)
+	f := Frame{Channel: "conn-2", Seq: 42, Time: at, Replay: true, Data: []byte{0, 1, 0xFF}}
+	got, err := ParseFrame(AppendFrame(nil, f))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Channel != f.Channel || got.Seq != 42 || !got.Time.Equal(at) || !got.Replay || !bytes.Equal(got.Data, f.Data) {
+		t.Errorf("got %+v", got)
+	}
+	if _, err := ParseFrame([]byte{Version, 0, 9, 0}); err == nil {
+		t.Error("expected an error for a short frame")
+	}
+}
+
+// history 模拟应用的历史缓冲区
+type history struct {
+	mu     sync.Mutex
+	frames []Frame
+}
+
+func (h *history) add(s *Server, seq uint64) {
+	f := Frame{Channel: "c", Seq: seq, Time: time.Now(), Data: []byte(fmt.Sprint(seq))}
+	h.mu.Lock()
+	h.frames = append(h.frames, f)
+	h.mu.Unlock()
+	s.Send(f)
+}
+
+func (h *history) from(seq uint64) []Frame {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	var out []Frame
+	for _, f := range h.frames {
+		if f.Seq >= seq {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+func dial(t testing.TB, s *Server, from uint64) *websocket.Conn {
+	t.Helper()
+	url := s.URL()
+	if from > 0 {
+		url += fmt.Sprintf("&from=%d", from)
+	}
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return conn
+}
+
+func waitClients(t *testing.T, s *Server, n int) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for s.Stats().Clients != n {
+		if time.Now().After(deadline) {
+			t.Fatalf("clients = %d, want %d", s.Stats().Clients, n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func read(t *testing.T, conn *websocket.Conn) Frame {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, msg, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := ParseFrame(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+func TestRejectsWrongToken(t *testing.T) {
+	s, err := Start(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	url := "ws://" + s.ln.Addr().String() + Path + "?token=guess"
+	_, resp, err := websocket.DefaultDialer.Dial(url, nil)
+	if err == nil || resp == nil || resp.StatusCode != http.StatusForbidden {
+		t.Errorf("dial with a wrong token: %v", err)
+	}
+}
+
+func TestReconnectReplaysWithoutLossOrDuplicates(t *testing.T) {
+	h := &history{}
+	s, err := Start(h.from)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	conn := dial(t, s, 0)
+	waitClients(t, s, 1)
+	for seq := uint64(1); seq <= 3; seq++ {
+		h.add(s, seq)
+	}
+	var last uint64
+	for i := 0; i < 3; i++ {
+		f := read(t, conn)
+		if f.Seq != last+1 || f.Replay {
+			t.Fatalf("frame %+v after seq %d", f, last)
+		}
+		last = f.Seq
+	}
+	conn.Close()
+	waitClients(t, s, 0)
+
+	// 断开期间的数据在重连时补发，之后继续接收实时数据
+	for seq := uint64(4); seq <= 6; seq++ {
+		h.add(s, seq)
+	}
+	conn = dial(t, s, last+1)
+	defer conn.Close()
+	waitClients(t, s, 1)
+	h.add(s, 7)
+	for last < 7 {
+		f := read(t, conn)
+		if f.Seq != last+1 {
+			t.Fatalf("got seq %d after %d", f.Seq, last)
+		}
+		if want := f.Seq <= 6; f.Replay != want {
+			t.Errorf("seq %d replay = %v", f.Seq, f.Replay)
+		}
+		last = f.Seq
+	}
+	if st := s.Stats(); st.Replayed != 3 {
+		t.Errorf("replayed = %d", st.Replayed)
+	}
+}
+
+func TestSlowClientIsDisconnected(t *testing.T) {
+	s, err := Start(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	conn := dial(t, s, 0)
+	defer conn.Close()
+	waitClients(t, s, 1)
+
+	// 客户端不读取，队列与套接字缓冲区填满后被断开
+	data := make([]byte, 64<<10)
+	deadline := time.Now().Add(5 * time.Second)
+	for seq := uint64(1); s.Stats().Kicked == 0; seq++ {
+		if time.Now().After(deadline) {
+			t.Fatal("slow client was never disconnected")
+		}
+		s.Send(Frame{Channel: "c", Seq: seq, Data: data})
+	}
+	waitClients(t, s, 0)
+}
+
+// 以 5 MB/s 的模拟数据源 (每次读取 4 KiB，每秒 1280 块) 比较两种传输方式的 CPU 开销，
+// cpu% 为按每块耗时折算的单核占用。参考结果 (x86-64 Xeon)：
+// JSON 事件的编码与解码约 3.8%，二进制帧 (含回环 WebSocket 的写入与读取) 约 1.3%，约为前者的三分之一。
+// JSON 一侧还未计入 Wails 把消息交给 WebView 与 JS 解析 base64 的开销，实际差距更大
+const (
+	benchChunk = 4096
+	benchRate  = 5 << 20
+)
+
+func reportCPU(b *testing.B) {
+	perChunk := float64(b.Elapsed().Nanoseconds()) / float64(b.N)
+	b.ReportMetric(perChunk*benchRate/benchChunk/1e9*100, "cpu%@5MB/s")
+}
+
+// BenchmarkJSONEvent Wails 事件的编码方式：参数数组整体 JSON 序列化，[]byte 编码为 base64
+func BenchmarkJSONEvent(b *testing.B) {
+	chunk := bytes.Repeat([]byte("0123456789abcdef"), benchChunk/16)
+	meta := map[string]interface{}{"seq": uint64(1)}
+	b.SetBytes(benchChunk)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		msg, err := json.Marshal(map[string]interface{}{"name": "serial-data", "data": []interface{}{chunk, meta}})
+		if err != nil {
+			b.Fatal(err)
+		}
+		// 前端对应的解码
+		var ev struct {
+			Data []json.RawMessage `json:"data"`
+		}
+		var data []byte
+		if err := json.Unmarshal(msg, &ev); err != nil || json.Unmarshal(ev.Data[0], &data) != nil {
+			b.Fatal("decode failed")
+		}
+	}
+	reportCPU(b)
+}
+
+// BenchmarkBinaryFrame 经回环 WebSocket 发送并由客户端读取
+func BenchmarkBinaryFrame(b *testing.B) {
+	s, err := Start(nil)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer s.Close()
+	conn := dial(b, s, 0)
+	defer conn.Close()
+	for s.Stats().Clients == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	chunk := bytes.Repeat([]byte("0123456789abcdef"), benchChunk/16)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for n := 0; n < b.N; n++ {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+	b.SetBytes(benchChunk)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		s.Send(Frame{Channel: "c", Seq: uint64(i + 1), Time: time.Now(), Data: chunk})
+		if i%(QueueDepth/2) == 0 {
+			// 避免发送快于读取时被判定为慢客户端
+			for s.Stats().Frames+QueueDepth/2 < uint64(i) {
+				time.Sleep(10 * time.Microsecond)
+			}
+		}
+	}
+	<-done
+	reportCPU(b)
+}
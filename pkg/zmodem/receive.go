@@ -0,0 +1,482 @@
+package zmodem
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// 接收限制
+const (
+	// MaxSubpacket 单个数据子包的最大长度 (协议规定不超过 1024，部分实现使用 8192)
+	MaxSubpacket = 8192
+	// MaxErrors 连续校验错误次数上限，超过后放弃传输
+	MaxErrors = 10
+	// PartSuffix 传输中的文件后缀，传输完成后重命名
+	PartSuffix = ".part"
+)
+
+var (
+	// ErrAborted 发送端取消了传输 (ZCAN、ZABORT 或连续的 CAN 字符)
+	ErrAborted = errors.New("transfer cancelled by sender")
+	// ErrTooManyErrors 连续校验错误过多
+	ErrTooManyErrors = errors.New("too many consecutive CRC errors")
+
+	errBadCRC    = errors.New("bad CRC")
+	errBadEscape = errors.New("bad ZDLE escape")
+	errTooLong   = errors.New("subpacket too long")
+)
+
+// Progress 传输进度，Total 未知时为 -1
+type Progress struct {
+	File     string `json:"file"`
+	Received int64  `json:"received"`
+	Total    int64  `json:"total"`
+}
+
+// Receive 从 r 接收文件并写入 dir，应答写入 w，返回已完成的文件路径
+// 调用方需要在超时或连接关闭时关闭 r 以结束接收；出错时删除未完成的文件
+func Receive(r io.Reader, w io.Writer, dir string, onProgress func(Progress)) ([]string, error) {
+	rx := &receiver{r: bufio.NewReader(r), w: w, dir: dir, onProgress: onProgress}
+	err := rx.run()
+	if rx.file != nil {
+		rx.file.Close()
+		os.Remove(rx.file.Name())
+	}
+	return rx.files, err
+}
+
+type receiver struct {
+	r          *bufio.Reader
+	w          io.Writer
+	dir        string
+	onProgress func(Progress)
+
+	crc32  bool // 最近的帧头为 ZBIN32，数据子包使用 CRC32
+	errors int
+
+	file   *os.File
+	name   string // 最终文件路径
+	offset uint32
+	total  int64
+	files  []string
+}
+
+func (rx *receiver) send(h Header) error {
+	_, err := rx.w.Write(EncodeHexHeader(h))
+	return err
+}
+
+func (rx *receiver) sendInit() error {
+	return rx.send(FlagsHeader(ZRINIT, CANFDX|CANOVIO|CANFC32))
+}
+
+// recover 请求发送端从当前位置重发
+func (rx *receiver) recover() error {
+	rx.errors++
+	if rx.errors > MaxErrors {
+		return ErrTooManyErrors
+	}
+	return rx.send(PosHeader(ZRPOS, rx.offset))
+}
+
+func (rx *receiver) run() error {
+	if err := rx.sendInit(); err != nil {
+		return err
+	}
+
+	for {
+		h, err := rx.readHeader()
+		if err == errBadCRC || err == errBadEscape {
+			rx.errors++
+			if rx.errors > MaxErrors {
+				return ErrTooManyErrors
+			}
+			if err := rx.send(Header{Type: ZNAK}); err != nil {
+				return err
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		switch h.Type {
+		case ZRQINIT:
+			err = rx.sendInit()
+		case ZSINIT:
+			if _, _, err = rx.readSubpacket(); err == nil {
+				err = rx.send(PosHeader(ZACK, 0))
+			} else if err == errBadCRC || err == errBadEscape || err == errTooLong {
+				err = rx.send(Header{Type: ZNAK})
+			}
+		case ZFILE:
+			err = rx.handleFile()
+		case ZDATA:
+			err = rx.handleData(h)
+		case ZEOF:
+			err = rx.handleEOF(h)
+		case ZFIN:
+			if err := rx.send(Header{Type: ZFIN}); err != nil {
+				return err
+			}
+			if rx.file != nil {
+				return fmt.Errorf("session finished before %s was complete", filepath.Base(rx.name))
+			}
+			// 发送端以 "OO" 结束会话，读不到也不影响结果
+			return nil
+		case ZCAN, ZABORT:
+			return ErrAborted
+		case ZFERR:
+			return fmt.Errorf("sender reported a file error")
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (rx *receiver) handleFile() error {
+	info, _, err := rx.readSubpacket()
+	if err == errBadCRC || err == errBadEscape || err == errTooLong {
+		rx.errors++
+		if rx.errors > MaxErrors {
+			return ErrTooManyErrors
+		}
+		return rx.send(Header{Type: ZNAK})
+	}
+	if err != nil {
+		return err
+	}
+
+	name, total, err := parseFileInfo(info)
+	if err != nil {
+		return err
+	}
+	if rx.file != nil {
+		// 上一个文件未收到 ZEOF
+		rx.file.Close()
+		os.Remove(rx.file.Name())
+		rx.file = nil
+	}
+
+	if err := os.MkdirAll(rx.dir, 0755); err != nil {
+		return fmt.Errorf("cannot create download directory: %w", err)
+	}
+	path := uniquePath(filepath.Join(rx.dir, name))
+	f, err := os.OpenFile(path+PartSuffix, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return fmt.Errorf("cannot create %s: %w", path+PartSuffix, err)
+	}
+	rx.file, rx.name, rx.offset, rx.total, rx.errors = f, path, 0, total, 0
+	rx.progress()
+	return rx.send(PosHeader(ZRPOS, 0))
+}
+
+func (rx *receiver) handleData(h Header) error {
+	if rx.file == nil {
+		// 尚未收到 ZFILE，要求发送端重新开始
+		return rx.sendInit()
+	}
+	if h.Pos() != rx.offset {
+		return rx.recover()
+	}
+
+	for {
+		data, end, err := rx.readSubpacket()
+		if err == errBadCRC || err == errBadEscape || err == errTooLong {
+			return rx.recover()
+		}
+		if err != nil {
+			return err
+		}
+
+		if _, err := rx.file.Write(data); err != nil {
+			return fmt.Errorf("write failed: %w", err)
+		}
+		rx.offset += uint32(len(data))
+		rx.errors = 0
+		rx.progress()
+
+		switch end {
+		case ZCRCW:
+			return rx.send(PosHeader(ZACK, rx.offset))
+		case ZCRCQ:
+			if err := rx.send(PosHeader(ZACK, rx.offset)); err != nil {
+				return err
+			}
+		case ZCRCE:
+			return nil
+		}
+	}
+}
+
+func (rx *receiver) handleEOF(h Header) error {
+	if rx.file == nil {
+		return rx.sendInit()
+	}
+	if h.Pos() != rx.offset {
+		// 仍有数据未到达，按协议忽略该 ZEOF
+		return nil
+	}
+	if rx.total >= 0 && int64(rx.offset) != rx.total {
+		return fmt.Errorf("%s: received %d bytes, expected %d", filepath.Base(rx.name), rx.offset, rx.total)
+	}
+
+	part := rx.file.Name()
+	if err := rx.file.Close(); err != nil {
+		rx.file = nil
+		os.Remove(part)
+		return fmt.Errorf("write failed: %w", err)
+	}
+	rx.file = nil
+	if err := os.Rename(part, rx.name); err != nil {
+		os.Remove(part)
+		return fmt.Errorf("cannot rename %s: %w", part, err)
+	}
+	rx.files = append(rx.files, rx.name)
+	return rx.sendInit()
+}
+
+func (rx *receiver) progress() {
+	if rx.onProgress != nil {
+		rx.onProgress(Progress{File: filepath.Base(rx.name), Received: int64(rx.offset), Total: rx.total})
+	}
+}
+
+// readHeader 跳过非帧头数据，读取下一个帧头
+func (rx *receiver) readHeader() (Header, error) {
+	cans := 0
+	for {
+		c, err := rx.r.ReadByte()
+		if err != nil {
+			return Header{}, unexpected(err)
+		}
+		if c == ZDLE {
+			if cans++; cans >= 5 {
+				return Header{}, ErrAborted
+			}
+			continue
+		}
+		cans = 0
+		if c != ZPAD {
+			continue
+		}
+
+		// 跳过多余的 ZPAD
+		for c == ZPAD {
+			if c, err = rx.r.ReadByte(); err != nil {
+				return Header{}, unexpected(err)
+			}
+		}
+		if c != ZDLE {
+			continue
+		}
+		if c, err = rx.r.ReadByte(); err != nil {
+			return Header{}, unexpected(err)
+		}
+
+		switch c {
+		case ZHEX:
+			return rx.readHexHeader()
+		case ZBIN:
+			rx.crc32 = false
+			return rx.readBinHeader(false)
+		case ZBIN32:
+			rx.crc32 = true
+			return rx.readBinHeader(true)
+		}
+	}
+}
+
+func (rx *receiver) readHexHeader() (Header, error) {
+	digits := make([]byte, 14)
+	if _, err := io.ReadFull(rx.r, digits); err != nil {
+		return Header{}, unexpected(err)
+	}
+	raw := make([]byte, 7)
+	if _, err := hex.Decode(raw, bytes.ToLower(digits)); err != nil {
+		return Header{}, errBadCRC
+	}
+	if crc16(0, raw[:5]) != binary.BigEndian.Uint16(raw[5:]) {
+		return Header{}, errBadCRC
+	}
+	// 十六进制帧头之后的数据子包使用 CRC16
+	rx.crc32 = false
+	return Header{Type: raw[0], P: [4]byte{raw[1], raw[2], raw[3], raw[4]}}, nil
+}
+
+func (rx *receiver) readBinHeader(use32 bool) (Header, error) {
+	n := 7
+	if use32 {
+		n = 9
+	}
+	raw := make([]byte, n)
+	for i := range raw {
+		b, end, err := rx.readEscaped()
+		if err != nil {
+			return Header{}, err
+		}
+		if end != 0 {
+			return Header{}, errBadEscape
+		}
+		raw[i] = b
+	}
+
+	if use32 {
+		if crc32.ChecksumIEEE(raw[:5]) != binary.LittleEndian.Uint32(raw[5:]) {
+			return Header{}, errBadCRC
+		}
+	} else if crc16(0, raw[:5]) != binary.BigEndian.Uint16(raw[5:]) {
+		return Header{}, errBadCRC
+	}
+	return Header{Type: raw[0], P: [4]byte{raw[1], raw[2], raw[3], raw[4]}}, nil
+}
+
+// readSubpacket 读取一个数据子包并校验，返回数据与结束标记
+func (rx *receiver) readSubpacket() ([]byte, byte, error) {
+	var data []byte
+	for {
+		b, end, err := rx.readEscaped()
+		if err != nil {
+			return nil, 0, err
+		}
+		if end != 0 {
+			return rx.checkSubpacket(data, end)
+		}
+		if len(data) >= MaxSubpacket {
+			return nil, 0, errTooLong
+		}
+		data = append(data, b)
+	}
+}
+
+func (rx *receiver) checkSubpacket(data []byte, end byte) ([]byte, byte, error) {
+	n := 2
+	if rx.crc32 {
+		n = 4
+	}
+	crc := make([]byte, n)
+	for i := range crc {
+		b, e, err := rx.readEscaped()
+		if err != nil {
+			return nil, 0, err
+		}
+		if e != 0 {
+			return nil, 0, errBadEscape
+		}
+		crc[i] = b
+	}
+
+	if rx.crc32 {
+		sum := crc32.Update(crc32.ChecksumIEEE(data), crc32.IEEETable, []byte{end})
+		if sum != binary.LittleEndian.Uint32(crc) {
+			return nil, 0, errBadCRC
+		}
+	} else if crc16(crc16(0, data), []byte{end}) != binary.BigEndian.Uint16(crc) {
+		return nil, 0, errBadCRC
+	}
+	return data, end, nil
+}
+
+// readEscaped 读取一个经 ZDLE 转义的字节；遇到子包结束标记时返回 end != 0
+func (rx *receiver) readEscaped() (b byte, end byte, err error) {
+	for {
+		c, err := rx.r.ReadByte()
+		if err != nil {
+			return 0, 0, unexpected(err)
+		}
+		switch c {
+		case xon, xon | 0x80, 0x13, 0x93:
+			// 未转义的流控字符
+			continue
+		case ZDLE:
+		default:
+			return c, 0, nil
+		}
+
+		cans := 1
+		for {
+			if c, err = rx.r.ReadByte(); err != nil {
+				return 0, 0, unexpected(err)
+			}
+			if c != ZDLE {
+				break
+			}
+			if cans++; cans >= 5 {
+				return 0, 0, ErrAborted
+			}
+		}
+
+		switch c {
+		case ZCRCE, ZCRCG, ZCRCQ, ZCRCW:
+			return 0, c, nil
+		case ZRUB0:
+			return 0x7F, 0, nil
+		case ZRUB1:
+			return 0xFF, 0, nil
+		case xon, xon | 0x80, 0x13, 0x93:
+			continue
+		}
+		if c&0x60 != 0x40 {
+			return 0, 0, errBadEscape
+		}
+		return c ^ 0x40, 0, nil
+	}
+}
+
+// parseFileInfo 解析 ZFILE 数据子包 ("文件名\0长度 修改时间 模式 ...")
+func parseFileInfo(info []byte) (string, int64, error) {
+	parts := bytes.SplitN(info, []byte{0}, 2)
+	name := sanitizeName(string(parts[0]))
+	if name == "" {
+		return "", 0, fmt.Errorf("invalid file name %q", parts[0])
+	}
+
+	total := int64(-1)
+	if len(parts) == 2 {
+		fields := strings.Fields(string(bytes.TrimRight(parts[1], "\x00")))
+		if len(fields) > 0 {
+			if n, err := strconv.ParseInt(fields[0], 10, 64); err == nil && n >= 0 {
+				total = n
+			}
+		}
+	}
+	return name, total, nil
+}
+
+// sanitizeName 只保留文件名部分，防止写到下载目录之外
+func sanitizeName(name string) string {
+	name = filepath.Base(strings.ReplaceAll(name, "\\", "/"))
+	if name == "." || name == ".." || name == "/" {
+		return ""
+	}
+	return name
+}
+
+// uniquePath 文件已存在时追加 .1、.2 等后缀
+func uniquePath(path string) string {
+	candidate := path
+	for i := 1; ; i++ {
+		if _, err := os.Stat(candidate); os.IsNotExist(err) {
+			return candidate
+		}
+		candidate = fmt.Sprintf("%s.%d", path, i)
+	}
+}
+
+func unexpected(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
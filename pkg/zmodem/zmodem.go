@@ -0,0 +1,153 @@
+// Package zmodem 实现 ZMODEM 接收端 (对应设备上的 sz 命令)
+//
+// 只实现接收所需的部分：十六进制/二进制 (CRC16、CRC32) 帧头解析、ZDLE 转义、
+// 数据子包校验以及 ZRPOS 错误恢复。协议细节参考 Chuck Forsberg 的 ZMODEM 文档。
+package zmodem
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+)
+
+// 控制字符
+const (
+	ZPAD   = '*'
+	ZDLE   = 0x18 // 同时也是 CAN
+	ZBIN   = 'A'
+	ZHEX   = 'B'
+	ZBIN32 = 'C'
+
+	// 数据子包结束标记 (ZDLE 之后)
+	ZCRCE = 'h' // 帧结束，不需要应答
+	ZCRCG = 'i' // 帧继续，不需要应答
+	ZCRCQ = 'j' // 帧继续，需要 ZACK
+	ZCRCW = 'k' // 帧结束，需要 ZACK
+	ZRUB0 = 'l' // 0x7F
+	ZRUB1 = 'm' // 0xFF
+
+	xon = 0x11
+)
+
+// 帧类型
+const (
+	ZRQINIT    = 0
+	ZRINIT     = 1
+	ZSINIT     = 2
+	ZACK       = 3
+	ZFILE      = 4
+	ZSKIP      = 5
+	ZNAK       = 6
+	ZABORT     = 7
+	ZFIN       = 8
+	ZRPOS      = 9
+	ZDATA      = 10
+	ZEOF       = 11
+	ZFERR      = 12
+	ZCRC       = 13
+	ZCHALLENGE = 14
+	ZCOMPL     = 15
+	ZCAN       = 16
+)
+
+// ZRINIT 能力标志 (ZF0)
+const (
+	CANFDX  = 0x01 // 全双工
+	CANOVIO = 0x02 // 可在磁盘写入时继续接收
+	CANFC32 = 0x20 // 支持 CRC32
+)
+
+// Header ZMODEM 帧头，P 为 ZP0-ZP3 (位置字段为小端，标志字段 ZF0 = P[3])
+type Header struct {
+	Type byte
+	P    [4]byte
+}
+
+// Pos 返回帧头中的文件位置
+func (h Header) Pos() uint32 {
+	return binary.LittleEndian.Uint32(h.P[:])
+}
+
+// PosHeader 构造携带文件位置的帧头 (ZRPOS、ZACK、ZDATA、ZEOF 等)
+func PosHeader(t byte, pos uint32) Header {
+	h := Header{Type: t}
+	binary.LittleEndian.PutUint32(h.P[:], pos)
+	return h
+}
+
+// FlagsHeader 构造携带标志的帧头，f0 为 ZF0
+func FlagsHeader(t byte, f0 byte) Header {
+	h := Header{Type: t}
+	h.P[3] = f0
+	return h
+}
+
+// EncodeHexHeader 编码十六进制帧头 (接收端发出的帧头均使用该格式)
+func EncodeHexHeader(h Header) []byte {
+	raw := []byte{h.Type, h.P[0], h.P[1], h.P[2], h.P[3]}
+	crc := crc16(0, raw)
+	raw = append(raw, byte(crc>>8), byte(crc))
+
+	var buf bytes.Buffer
+	buf.Write([]byte{ZPAD, ZPAD, ZDLE, ZHEX})
+	buf.WriteString(hex.EncodeToString(raw))
+	buf.Write([]byte{'\r', '\n' | 0x80})
+	if h.Type != ZACK && h.Type != ZFIN {
+		buf.WriteByte(xon)
+	}
+	return buf.Bytes()
+}
+
+// crc16 CRC-16/XMODEM (多项式 0x1021)
+func crc16(crc uint16, data []byte) uint16 {
+	for _, b := range data {
+		crc ^= uint16(b) << 8
+		for i := 0; i < 8; i++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
+
+// zrqinit 发送端 ZRQINIT 十六进制帧头的特征 ("*" ZDLE "B" "00")
+var zrqinit = []byte{ZPAD, ZDLE, ZHEX, '0', '0'}
+
+// Detector 在接收数据流中检测 ZRQINIT，支持特征跨数据块
+type Detector struct {
+	tail []byte
+}
+
+// Feed 检查 data，返回 ZRQINIT 在 data 中的起始位置，未检测到时返回 -1
+// 特征起始于之前的数据块时返回 0
+func (d *Detector) Feed(data []byte) int {
+	buf := append(d.tail, data...)
+	if i := bytes.Index(buf, zrqinit); i >= 0 {
+		d.tail = nil
+		if i -= len(buf) - len(data); i < 0 {
+			i = 0
+		}
+		return i
+	}
+
+	keep := len(zrqinit) - 1
+	if len(buf) < keep {
+		keep = len(buf)
+	}
+	d.tail = append([]byte(nil), buf[len(buf)-keep:]...)
+	return -1
+}
+
+// Reset 清除跨数据块的状态
+func (d *Detector) Reset() {
+	d.tail = nil
+}
+
+// CancelSequence 接收端放弃传输时发送给发送端的取消序列 (8 个 CAN 加 8 个退格)
+var CancelSequence = []byte{
+	ZDLE, ZDLE, ZDLE, ZDLE, ZDLE, ZDLE, ZDLE, ZDLE,
+	0x08, 0x08, 0x08, 0x08, 0x08, 0x08, 0x08, 0x08,
+}
@@ -0,0 +1,323 @@
+package zmodem
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// 以下辅助函数按 sz 的输出格式构造发送端数据流，用作录制的传输轨迹
+
+// escape 对发送数据做 ZDLE 转义 (与 lrzsz 默认设置一致)
+func escape(data []byte) []byte {
+	var out []byte
+	for _, b := range data {
+		switch b {
+		case ZDLE, 0x10, 0x90, 0x11, 0x91, 0x13, 0x93:
+			out = append(out, ZDLE, b^0x40)
+		case 0x7F:
+			out = append(out, ZDLE, ZRUB0)
+		case 0xFF:
+			out = append(out, ZDLE, ZRUB1)
+		default:
+			out = append(out, b)
+		}
+	}
+	return out
+}
+
+func bin32Header(h Header) []byte {
+	raw := []byte{h.Type, h.P[0], h.P[1], h.P[2], h.P[3]}
+	raw = binary.LittleEndian.AppendUint32(raw, crc32.ChecksumIEEE(raw))
+	return append([]byte{ZPAD, ZDLE, ZBIN32}, escape(raw)...)
+}
+
+func bin16Header(h Header) []byte {
+	raw := []byte{h.Type, h.P[0], h.P[1], h.P[2], h.P[3]}
+	raw = binary.BigEndian.AppendUint16(raw, crc16(0, raw))
+	return append([]byte{ZPAD, ZDLE, ZBIN}, escape(raw)...)
+}
+
+func subpacket32(data []byte, end byte) []byte {
+	sum := crc32.Update(crc32.ChecksumIEEE(data), crc32.IEEETable, []byte{end})
+	out := append(escape(data), ZDLE, end)
+	return append(out, escape(binary.LittleEndian.AppendUint32(nil, sum))...)
+}
+
+func subpacket16(data []byte, end byte) []byte {
+	sum := crc16(crc16(0, data), []byte{end})
+	out := append(escape(data), ZDLE, end)
+	return append(out, escape(binary.BigEndian.AppendUint16(nil, sum))...)
+}
+
+func fileInfo(name string, size int) []byte {
+	return []byte(name + "\x00" + strconv.Itoa(size) + " 14547400001 100644 0 1 " + strconv.Itoa(size) + "\x00")
+}
+
+// testPayload 包含所有需要转义的字节
+func testPayload(n int) []byte {
+	p := make([]byte, n)
+	for i := range p {
+		p[i] = byte(i * 7)
+	}
+	return p
+}
+
+// trace 构造完整的单文件传输：ZRQINIT、ZFILE、ZDATA (多个子包)、ZEOF、ZFIN、OO
+func trace(name string, payload []byte, chunk int) []byte {
+	var t bytes.Buffer
+	t.WriteString("rz\r")
+	t.Write(EncodeHexHeader(Header{Type: ZRQINIT}))
+	t.Write(bin32Header(Header{Type: ZFILE}))
+	t.Write(subpacket32(fileInfo(name, len(payload)), ZCRCW))
+	t.Write(bin32Header(PosHeader(ZDATA, 0)))
+	for off := 0; off < len(payload); off += chunk {
+		end := off + chunk
+		frameEnd := byte(ZCRCG)
+		if end >= len(payload) {
+			end, frameEnd = len(payload), ZCRCE
+		}
+		t.Write(subpacket32(payload[off:end], frameEnd))
+	}
+	t.Write(bin32Header(PosHeader(ZEOF, uint32(len(payload)))))
+	t.Write(EncodeHexHeader(Header{Type: ZFIN}))
+	t.WriteString("OO")
+	return t.Bytes()
+}
+
+// replies 解析接收端发出的十六进制帧头
+func replies(t *testing.T, out []byte) []Header {
+	t.Helper()
+	rx := &receiver{r: bufio.NewReader(bytes.NewReader(out))}
+	var hs []Header
+	for {
+		h, err := rx.readHeader()
+		if err != nil {
+			return hs
+		}
+		hs = append(hs, h)
+	}
+}
+
+func TestHexHeaderRoundTrip(t *testing.T) {
+	h := PosHeader(ZRPOS, 0x12345678)
+	enc := EncodeHexHeader(h)
+	if !bytes.HasPrefix(enc, []byte("**\x18B09")) {
+		t.Fatalf("Unexpected encoding %q", enc)
+	}
+	if enc[len(enc)-1] != xon {
+		t.Error("Expected trailing XON on ZRPOS")
+	}
+	if got := replies(t, enc); len(got) != 1 || got[0] != h {
+		t.Errorf("Round trip = %+v; expected %+v", got, h)
+	}
+	if enc := EncodeHexHeader(Header{Type: ZFIN}); enc[len(enc)-1] == xon {
+		t.Error("ZFIN must not be followed by XON")
+	}
+}
+
+func TestDetector(t *testing.T) {
+	var d Detector
+	if i := d.Feed([]byte("hello world")); i != -1 {
+		t.Errorf("Unexpected detection at %d", i)
+	}
+	if i := d.Feed([]byte("rz\r**\x18B0000000000000000\r\x8a\x11")); i != 4 {
+		t.Errorf("Expected detection at 4, got %d", i)
+	}
+
+	// 特征跨数据块
+	d.Reset()
+	if i := d.Feed([]byte("rz\r**\x18B")); i != -1 {
+		t.Errorf("Unexpected early detection at %d", i)
+	}
+	if i := d.Feed([]byte("0000000000")); i != 0 {
+		t.Errorf("Expected detection at 0 for split sequence, got %d", i)
+	}
+}
+
+func TestReceiveSingleFile(t *testing.T) {
+	dir := t.TempDir()
+	payload := testPayload(5000)
+	var out bytes.Buffer
+	var progress []Progress
+
+	files, err := Receive(bytes.NewReader(trace("firmware.bin", payload, 1024)), &out, dir, func(p Progress) {
+		progress = append(progress, p)
+	})
+	if err != nil {
+		t.Fatalf("Receive failed: %v", err)
+	}
+	if len(files) != 1 || filepath.Base(files[0]) != "firmware.bin" {
+		t.Fatalf("Unexpected files: %v", files)
+	}
+	got, _ := os.ReadFile(files[0])
+	if !bytes.Equal(got, payload) {
+		t.Error("Received file content mismatch")
+	}
+	if last := progress[len(progress)-1]; last.Received != 5000 || last.Total != 5000 || last.File != "firmware.bin" {
+		t.Errorf("Unexpected final progress %+v", last)
+	}
+
+	types := []byte{}
+	for _, h := range replies(t, out.Bytes()) {
+		types = append(types, h.Type)
+	}
+	// ZRINIT (启动)、ZRINIT (应答 ZRQINIT)、ZRPOS(0)、ZRINIT (文件完成)、ZFIN
+	expected := []byte{ZRINIT, ZRINIT, ZRPOS, ZRINIT, ZFIN}
+	if !bytes.Equal(types, expected) {
+		t.Errorf("Replies = %v; expected %v", types, expected)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("Expected only the final file in %s, got %d entries", dir, len(entries))
+	}
+}
+
+func TestReceiveCRC16AndPathTraversal(t *testing.T) {
+	dir := t.TempDir()
+	payload := testPayload(300)
+
+	var tr bytes.Buffer
+	tr.Write(EncodeHexHeader(Header{Type: ZRQINIT}))
+	tr.Write(bin16Header(Header{Type: ZFILE}))
+	tr.Write(subpacket16(fileInfo("../../etc/passwd", len(payload)), ZCRCW))
+	tr.Write(bin16Header(PosHeader(ZDATA, 0)))
+	tr.Write(subpacket16(payload[:100], ZCRCQ))
+	tr.Write(subpacket16(payload[100:], ZCRCW))
+	tr.Write(bin16Header(PosHeader(ZEOF, 300)))
+	tr.Write(EncodeHexHeader(Header{Type: ZFIN}))
+
+	var out bytes.Buffer
+	files, err := Receive(&tr, &out, dir, nil)
+	if err != nil {
+		t.Fatalf("Receive failed: %v", err)
+	}
+	if len(files) != 1 || files[0] != filepath.Join(dir, "passwd") {
+		t.Fatalf("Expected file confined to download dir, got %v", files)
+	}
+
+	var acks []uint32
+	for _, h := range replies(t, out.Bytes()) {
+		if h.Type == ZACK {
+			acks = append(acks, h.Pos())
+		}
+	}
+	if len(acks) != 2 || acks[0] != 100 || acks[1] != 300 {
+		t.Errorf("Expected ZACK at 100 and 300, got %v", acks)
+	}
+}
+
+// TestReceiveRecoversFromCorruption 损坏的子包触发 ZRPOS，发送端从该位置重发后继续
+func TestReceiveRecoversFromCorruption(t *testing.T) {
+	dir := t.TempDir()
+	payload := testPayload(2048)
+
+	bad := subpacket32(payload[1024:], ZCRCE)
+	bad[10] ^= 0x01 // 破坏数据
+
+	var tr bytes.Buffer
+	tr.Write(EncodeHexHeader(Header{Type: ZRQINIT}))
+	tr.Write(bin32Header(Header{Type: ZFILE}))
+	tr.Write(subpacket32(fileInfo("log.txt", len(payload)), ZCRCW))
+	tr.Write(bin32Header(PosHeader(ZDATA, 0)))
+	tr.Write(subpacket32(payload[:1024], ZCRCG))
+	tr.Write(bad)
+	// 收到 ZRPOS 之前发送端已经发出的数据 (应被忽略)
+	tr.Write(bin32Header(PosHeader(ZEOF, 2048)))
+	// 发送端按 ZRPOS(1024) 重发
+	tr.Write(bin32Header(PosHeader(ZDATA, 1024)))
+	tr.Write(subpacket32(payload[1024:], ZCRCE))
+	tr.Write(bin32Header(PosHeader(ZEOF, 2048)))
+	tr.Write(EncodeHexHeader(Header{Type: ZFIN}))
+
+	var out bytes.Buffer
+	files, err := Receive(&tr, &out, dir, nil)
+	if err != nil {
+		t.Fatalf("Receive failed: %v", err)
+	}
+	got, _ := os.ReadFile(files[0])
+	if !bytes.Equal(got, payload) {
+		t.Error("Recovered file content mismatch")
+	}
+
+	found := false
+	for _, h := range replies(t, out.Bytes()) {
+		if h.Type == ZRPOS && h.Pos() == 1024 {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("Expected ZRPOS(1024) after corrupt subpacket")
+	}
+}
+
+func TestReceiveAbortRemovesPartialFile(t *testing.T) {
+	dir := t.TempDir()
+	payload := testPayload(4096)
+
+	var tr bytes.Buffer
+	tr.Write(EncodeHexHeader(Header{Type: ZRQINIT}))
+	tr.Write(bin32Header(Header{Type: ZFILE}))
+	tr.Write(subpacket32(fileInfo("big.bin", len(payload)), ZCRCW))
+	tr.Write(bin32Header(PosHeader(ZDATA, 0)))
+	tr.Write(subpacket32(payload[:1024], ZCRCG))
+	tr.Write(bytes.Repeat([]byte{ZDLE}, 8)) // 用户在设备端按下 Ctrl-X
+	tr.Write(bytes.Repeat([]byte{0x08}, 8))
+
+	_, err := Receive(&tr, io.Discard, dir, nil)
+	if !errors.Is(err, ErrAborted) {
+		t.Fatalf("Expected ErrAborted, got %v", err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("Expected partial file to be removed, found %d entries", len(entries))
+	}
+}
+
+func TestReceiveTruncatedStream(t *testing.T) {
+	dir := t.TempDir()
+	full := trace("cut.bin", testPayload(3000), 1024)
+
+	_, err := Receive(bytes.NewReader(full[:len(full)/2]), io.Discard, dir, nil)
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("Expected ErrUnexpectedEOF, got %v", err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("Expected partial file to be removed, found %d entries", len(entries))
+	}
+}
+
+func TestReceiveSizeMismatch(t *testing.T) {
+	dir := t.TempDir()
+	payload := testPayload(100)
+
+	var tr bytes.Buffer
+	tr.Write(bin32Header(Header{Type: ZFILE}))
+	tr.Write(subpacket32(fileInfo("short.bin", 200), ZCRCW))
+	tr.Write(bin32Header(PosHeader(ZDATA, 0)))
+	tr.Write(subpacket32(payload, ZCRCE))
+	tr.Write(bin32Header(PosHeader(ZEOF, 100)))
+
+	_, err := Receive(&tr, io.Discard, dir, nil)
+	if err == nil || !strings.Contains(err.Error(), "expected 200") {
+		t.Fatalf("Expected size mismatch error, got %v", err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("Expected partial file to be removed, found %d entries", len(entries))
+	}
+}
+
+func TestUniquePath(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "a.bin")
+	os.WriteFile(path, nil, 0644)
+	if got := uniquePath(path); got != path+".1" {
+		t.Errorf("uniquePath = %q; expected %q", got, path+".1")
+	}
+}